@@ -12,6 +12,9 @@ import (
 func Run() {
 	root.Cmd.Version(version.Version)
 	_, err := root.Cmd.Parse(os.Args[1:])
+	if closeErr := root.Close(); closeErr != nil {
+		log.WithError(closeErr).Error("failed to close the database")
+	}
 	if err != nil {
 		log.WithError(err).Error("failure in main command")
 		os.Exit(2)
@@ -0,0 +1,25 @@
+package backup
+
+import (
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+)
+
+func init() {
+	cmd := root.Command("backup", "Backup the results and the measurements")
+	backupDir := cmd.Arg("dir", "the directory where to write the backup, which must not exist").Required().String()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if err := ctx.DB().Backup(ctx.Home(), *backupDir); err != nil {
+			log.WithError(err).Error("failed to backup")
+			return err
+		}
+		log.Infof("Wrote backup into %s", *backupDir)
+		return nil
+	})
+}
@@ -0,0 +1,53 @@
+package completion
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+)
+
+// The scripts ask ooniprobe itself for the completions using the hidden
+// --completion-bash flag, such that they follow the command tree and the
+// dynamic hints (e.g., the IDs of the results) of the running version.
+
+const bashScript = `_ooniprobe_completion() {
+    local cur opts
+    COMPREPLY=()
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    opts=$( ${COMP_WORDS[0]} --completion-bash ${COMP_WORDS[@]:1:$COMP_CWORD} 2>/dev/null )
+    COMPREPLY=( $(compgen -W "${opts}" -- ${cur}) )
+    return 0
+}
+complete -F _ooniprobe_completion ooniprobe
+`
+
+const zshScript = `#compdef ooniprobe
+autoload -U compinit && compinit
+autoload -U bashcompinit && bashcompinit
+
+` + bashScript
+
+const fishScript = `function __ooniprobe_completion
+    set -l args (commandline -opc)
+    ooniprobe --completion-bash $args[2..-1] (commandline -ct) 2>/dev/null
+end
+complete -c ooniprobe -f -a '(__ooniprobe_completion)'
+`
+
+var scripts = map[string]string{
+	"bash": bashScript,
+	"fish": fishScript,
+	"zsh":  zshScript,
+}
+
+func init() {
+	cmd := root.Command("completion", "Generate the shell completion script (e.g., `source <(ooniprobe completion bash)`)")
+	shell := cmd.Arg("shell", "the shell (one of: bash, fish, zsh)").Required().Enum("bash", "fish", "zsh")
+
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		_, err := fmt.Fprint(os.Stdout, scripts[*shell])
+		return err
+	})
+}
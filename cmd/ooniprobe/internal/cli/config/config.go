@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+)
+
+func init() {
+	cmd := root.Command("config", "Inspect the config file")
+	checkCmd := cmd.Command("check", "Check the config file and show the effective settings")
+	checkCmd.Action(func(_ *kingpin.ParseContext) error {
+		// We don't call root.Init because it fails when the
+		// config file is invalid, which is what we want to check.
+		path, err := root.ConfigPath()
+		if err != nil {
+			log.WithError(err).Error("failed to get the config file path")
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.WithError(err).Error("failed to read the config file")
+			return err
+		}
+		result := config.Check(data)
+		output.SectionTitle("Problems in " + path)
+		for _, problem := range result.Problems {
+			output.ConfigProblem(problem)
+		}
+		if errs := result.Errors(); len(errs) > 0 {
+			return fmt.Errorf("%w: found %d errors", config.ErrInvalidConfig, len(errs))
+		}
+		if len(result.Problems) <= 0 {
+			log.Info("The config file is valid")
+		}
+		output.SectionTitle("Effective settings")
+		for _, value := range result.Values {
+			output.ConfigValue(value)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/onboard"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/daemon"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/nettests"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("daemon", "Run test groups on the schedule set in the config file")
+	linkType := cmd.Flag(
+		"link-type", "Type of the link we're using (one of: wifi, mobile, wired)",
+	).Enum(database.LinkTypeWifi, database.LinkTypeMobile, database.LinkTypeWired)
+	metricsAddress := cmd.Flag(
+		"metrics-address", "Serve the Prometheus metrics at /metrics on this address (e.g., 127.0.0.1:9464)",
+	).String()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probe, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if err = onboard.MaybeOnboarding(probe); err != nil {
+			log.WithError(err).Error("failed to perform onboarding")
+			return err
+		}
+		settings := probe.Config().Schedule
+		for groupName := range settings.Groups {
+			if _, found := nettests.All[groupName]; !found {
+				return fmt.Errorf("schedule: no test group named %s", groupName)
+			}
+		}
+		probe.SetLinkType(*linkType)
+		d, err := daemon.New(daemon.Config{
+			Schedules:           settings.Groups,
+			OnlyWhenCharging:    settings.OnlyWhenCharging,
+			SkipMeteredNetworks: settings.SkipMeteredNetworks,
+			LinkType:            *linkType,
+			DB:                  probe.DB(),
+			RunGroup: func(groupName string) error {
+				return nettests.RunGroup(nettests.RunGroupConfig{
+					GroupName: groupName,
+					Probe:     probe,
+					RunType:   model.RunTypeTimed,
+				})
+			},
+			IsTerminated:   probe.IsTerminated,
+			MetricsAddress: *metricsAddress,
+		})
+		if err != nil {
+			log.WithError(err).Error("invalid schedule settings in the config file")
+			return err
+		}
+		// We install the handlers once here rather than relying on
+		// RunGroup, since the daemon may wait before the first run.
+		probe.ListenForSignals()
+		probe.MaybeListenForStdinClosed()
+		return d.Run()
+	})
+}
@@ -0,0 +1,68 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/doctor"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// newResolvers returns resolvers using different DNS transports.
+func newResolvers(logger model.Logger) []doctor.Resolver {
+	dialer := netxlite.NewDialerWithoutResolver(logger)
+	tlsDialer := netxlite.NewTLSDialer(dialer, netxlite.NewTLSHandshakerStdlib(logger))
+	return []doctor.Resolver{{
+		Name:     "system",
+		Resolver: netxlite.NewResolverStdlib(logger),
+	}, {
+		Name:     "udp://8.8.8.8:53",
+		Resolver: netxlite.NewResolverUDP(logger, dialer, "8.8.8.8:53"),
+	}, {
+		Name:     "dot://1.1.1.1:853",
+		Resolver: netxlite.NewSerialResolver(netxlite.NewDNSOverTLS(tlsDialer.DialTLSContext, "1.1.1.1:853")),
+	}, {
+		Name: "https://dns.google/dns-query",
+		Resolver: netxlite.NewSerialResolver(netxlite.NewDNSOverHTTPSTransport(
+			netxlite.NewHTTPClientStdlib(logger), "https://dns.google/dns-query")),
+	}}
+}
+
+func init() {
+	cmd := root.Command("doctor", "Diagnose the problems preventing ooniprobe from measuring")
+
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probe, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		sess, err := probe.NewSession(context.Background(), model.RunTypeManual)
+		if err != nil {
+			log.WithError(err).Error("failed to create a measurement session")
+			return err
+		}
+		defer sess.Close()
+		findings := doctor.New(doctor.Config{
+			Session:   sess,
+			DB:        probe.DB(),
+			Home:      probe.Home(),
+			Resolvers: newResolvers(log.Log),
+		}).Run(context.Background())
+		var failed bool
+		for _, finding := range findings {
+			output.DoctorFinding(finding)
+			failed = failed || finding.Severity == doctor.SeverityError
+		}
+		if failed {
+			return errors.New("doctor found problems")
+		}
+		log.Info("No problems found")
+		return nil
+	})
+}
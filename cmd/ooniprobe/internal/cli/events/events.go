@@ -0,0 +1,45 @@
+package events
+
+import (
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+)
+
+func init() {
+	cmd := root.Command("events", "Show the timeline of what the probe did")
+	since := cmd.Flag("since", "Only show the events in this time window (e.g., 24h)").Duration()
+	kind := cmd.Flag("kind", "Only show the events of this kind (e.g., check_in)").Enum(
+		database.EventRunStarted,
+		database.EventRunStopped,
+		database.EventCheckIn,
+		database.EventLocationChanged,
+		database.EventUploadBatch,
+	)
+	limit := cmd.Flag("limit", "Maximum number of recent events to show").Default("100").Int()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probeCLI, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		filter := &database.EventFilter{Kind: *kind, Limit: *limit}
+		if *since > 0 {
+			filter.Since = time.Now().Add(-*since)
+		}
+		events, err := probeCLI.DB().ListEvents(filter)
+		if err != nil {
+			log.WithError(err).Error("failed to list events")
+			return err
+		}
+		output.SectionTitle("Events")
+		for _, event := range events {
+			output.EventItem(event)
+		}
+		return nil
+	})
+}
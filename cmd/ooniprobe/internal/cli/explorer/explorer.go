@@ -0,0 +1,72 @@
+package explorer
+
+import (
+	"errors"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/explorer"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+)
+
+// selectMeasurements returns the measurements with the given IDs
+// followed by the measurements of the given result, if any.
+func selectMeasurements(actions database.Actions, msmtIDs []int64, resultID int64) ([]database.MeasurementURLNetwork, error) {
+	var measurements []database.MeasurementURLNetwork
+	for _, msmtID := range msmtIDs {
+		msmt, err := actions.GetMeasurement(msmtID)
+		if err != nil {
+			log.WithError(err).Errorf("failed to get measurement #%d", msmtID)
+			return nil, err
+		}
+		measurements = append(measurements, *msmt)
+	}
+	if resultID > 0 {
+		msmts, err := actions.ListMeasurements(resultID)
+		if err != nil {
+			log.WithError(err).Errorf("failed to list the measurements of result #%d", resultID)
+			return nil, err
+		}
+		measurements = append(measurements, msmts...)
+	}
+	return measurements, nil
+}
+
+func init() {
+	cmd := root.Command("explorer", "Show the OONI Explorer links of uploaded measurements")
+	msmtIDs := cmd.Arg("id", "the ids of the measurements").Int64List()
+	resultID := cmd.Flag("result", "Show the links of all the measurements of the given result").
+		HintAction(root.ResultIDHints).Int64()
+	open := cmd.Flag("open", "Open the links using the default browser").Bool()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		if len(*msmtIDs) <= 0 && *resultID <= 0 {
+			return errors.New("specify the measurements or use --result")
+		}
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		measurements, err := selectMeasurements(ctx.DB(), *msmtIDs, *resultID)
+		if err != nil {
+			return err
+		}
+		for idx := range measurements {
+			msmt := &measurements[idx]
+			URL, err := explorer.ForMeasurement(msmt)
+			if err != nil {
+				log.Warnf("measurement #%d is not in OONI Explorer because it was not uploaded", msmt.Measurement.ID)
+				continue
+			}
+			output.ExplorerLink(msmt.Measurement.ID, msmt.Measurement.TestName, URL)
+			if *open {
+				if err := explorer.Open(URL); err != nil {
+					log.WithError(err).Warnf("failed to open %s", URL)
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+// exportCSVFile writes the CSV file at the given path using fn.
+func exportCSVFile(path string, fn func(io.Writer) error) error {
+	filep, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(filep); err != nil {
+		filep.Close()
+		return err
+	}
+	if err := filep.Close(); err != nil {
+		return err
+	}
+	log.Infof("Exported %s", path)
+	return nil
+}
+
+// exportCSV writes results.csv and measurements.csv inside outputDir.
+func exportCSV(actions database.Actions, outputDir string) error {
+	if err := os.MkdirAll(outputDir, 0700); err != nil {
+		return err
+	}
+	err := exportCSVFile(filepath.Join(outputDir, "results.csv"), actions.ExportResultsCSV)
+	if err != nil {
+		return err
+	}
+	return exportCSVFile(filepath.Join(outputDir, "measurements.csv"), actions.ExportMeasurementsCSV)
+}
+
+// exportJSONL writes measurements.jsonl inside outputDir.
+func exportJSONL(actions database.Actions, outputDir string, filter *database.MeasurementFilter) error {
+	if err := os.MkdirAll(outputDir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(outputDir, "measurements.jsonl")
+	filep, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	count, err := actions.ExportMeasurementsJSONL(filter, filep)
+	if err != nil {
+		filep.Close()
+		return err
+	}
+	if err := filep.Close(); err != nil {
+		return err
+	}
+	log.Infof("Exported %d measurements to %s", count, path)
+	return nil
+}
+
+func init() {
+	cmd := root.Command("export", "Export results and measurements")
+	format := cmd.Flag("format", "Set the export format (one of: csv, jsonl)").Default("csv").Enum("csv", "jsonl")
+	outputDir := cmd.Flag("output", "Set the directory where to write exported files").Short('o').Default(".").String()
+	resultID := cmd.Flag("result-id", "Only export measurements of the given result (jsonl only)").Int64()
+	since := cmd.Flag("since", "Only export measurements started on or after YYYY-MM-DD (jsonl only)").String()
+	until := cmd.Flag("until", "Only export measurements started before YYYY-MM-DD (jsonl only)").String()
+	testName := cmd.Flag("test-name", "Only export measurements of the given test (jsonl only)").HintAction(root.ExperimentNameHints).String()
+	annotations := cmd.Flag(
+		"annotation", "Only export measurements with the given key=value annotation (jsonl only, can be repeated)",
+	).StringMap()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probeCLI, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		switch *format {
+		case "jsonl":
+			filter := &database.MeasurementFilter{
+				ResultID:    *resultID,
+				TestName:    *testName,
+				Annotations: *annotations,
+			}
+			if filter.Since, err = root.ParseDate(*since); err != nil {
+				log.WithError(err).Error("invalid --since date")
+				return err
+			}
+			if filter.Until, err = root.ParseDate(*until); err != nil {
+				log.WithError(err).Error("invalid --until date")
+				return err
+			}
+			err = exportJSONL(probeCLI.DB(), *outputDir, filter)
+		default:
+			err = exportCSV(probeCLI.DB(), *outputDir)
+		}
+		if err != nil {
+			log.WithError(err).Error("failed to export")
+			return err
+		}
+		return nil
+	})
+}
@@ -2,6 +2,7 @@ package geoip
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/apex/log"
@@ -43,17 +44,37 @@ func dogeoip(config dogeoipconfig) error {
 	}
 	defer engine.Close()
 
-	err = engine.MaybeLookupLocation()
+	err = engine.CrossCheckLocation()
 	if err != nil {
 		return err
 	}
 
+	// We report all the services we used, including the ones that
+	// failed, since they help to understand the results.
+	var services []string
+	for _, lookup := range engine.ProbeIPLookups() {
+		if lookup.Failure != "" {
+			services = append(services, fmt.Sprintf("%s (%s)", lookup.Service, lookup.Failure))
+			continue
+		}
+		services = append(services, lookup.Service)
+	}
+	disagree := engine.ProbeIPLookupsDisagree()
+	if disagree {
+		config.Logger.Warn("The IP lookup services returned different IPs: " +
+			"maybe you are using a proxy or a VPN that only applies to some traffic")
+	}
 	config.Logger.WithFields(log.Fields{
-		"type":         "table",
-		"asn":          engine.ProbeASNString(),
-		"network_name": engine.ProbeNetworkName(),
-		"country_code": engine.ProbeCC(),
-		"ip":           engine.ProbeIP(),
+		"type":                  "table",
+		"asn":                   engine.ProbeASNString(),
+		"network_name":          engine.ProbeNetworkName(),
+		"country_code":          engine.ProbeCC(),
+		"ip":                    engine.ProbeIP(),
+		"resolver_asn":          engine.ResolverASNString(),
+		"resolver_ip":           engine.ResolverIP(),
+		"resolver_network_name": engine.ResolverNetworkName(),
+		"ip_lookup_services":    services,
+		"ip_lookups_disagree":   disagree,
 	}).Info("Looked up your location")
 
 	return nil
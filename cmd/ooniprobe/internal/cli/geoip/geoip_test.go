@@ -7,6 +7,7 @@ import (
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+	"github.com/ooni/probe-cli/v3/internal/engine/geolocate"
 )
 
 func TestNewProbeCLIFailed(t *testing.T) {
@@ -52,11 +53,11 @@ func TestNewProbeEngineFailed(t *testing.T) {
 	}
 }
 
-func TestMaybeLookupLocationFailed(t *testing.T) {
+func TestCrossCheckLocationFailed(t *testing.T) {
 	fo := &oonitest.FakeOutput{}
 	expected := errors.New("mocked error")
 	engine := &oonitest.FakeProbeEngine{
-		FakeMaybeLookupLocation: expected,
+		FakeCrossCheckLocation: expected,
 	}
 	cli := &oonitest.FakeProbeCLI{
 		FakeProbeEnginePtr: engine,
@@ -78,7 +79,7 @@ func TestMaybeLookupLocationFailed(t *testing.T) {
 	}
 }
 
-func TestMaybeLookupLocationSuccess(t *testing.T) {
+func TestCrossCheckLocationSuccess(t *testing.T) {
 	fo := &oonitest.FakeOutput{}
 	engine := &oonitest.FakeProbeEngine{
 		FakeProbeASNString:   "AS30722",
@@ -132,3 +133,47 @@ func TestMaybeLookupLocationSuccess(t *testing.T) {
 		t.Fatal("invalid ip")
 	}
 }
+
+func TestCrossCheckLocationDisagree(t *testing.T) {
+	engine := &oonitest.FakeProbeEngine{
+		FakeProbeIP: "130.25.90.216",
+		FakeProbeIPLookups: []geolocate.IPLookup{
+			{Service: "avast", IP: "130.25.90.216"},
+			{Service: "ipinfo", Failure: "generic_timeout_error"},
+			{Service: "ubuntu", IP: "93.184.216.34"},
+		},
+		FakeProbeIPLookupsDisagree: true,
+		FakeResolverIP:             "8.8.8.8",
+	}
+	cli := &oonitest.FakeProbeCLI{
+		FakeProbeEnginePtr: engine,
+	}
+	handler := &oonitest.FakeLoggerHandler{}
+	err := dogeoip(dogeoipconfig{
+		SectionTitle: (&oonitest.FakeOutput{}).SectionTitle,
+		NewProbeCLI: func() (ooni.ProbeCLI, error) {
+			return cli, nil
+		},
+		Logger: &log.Logger{
+			Handler: handler,
+			Level:   log.DebugLevel,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handler.FakeEntries) != 2 || handler.FakeEntries[0].Level != log.WarnLevel {
+		t.Fatal("expected to warn about the disagreement")
+	}
+	entry := handler.FakeEntries[1]
+	if entry.Fields["resolver_ip"].(string) != "8.8.8.8" {
+		t.Fatal("invalid resolver ip")
+	}
+	services := entry.Fields["ip_lookup_services"].([]string)
+	if len(services) != 3 || services[1] != "ipinfo (generic_timeout_error)" {
+		t.Fatal("invalid lookup services", services)
+	}
+	if !entry.Fields["ip_lookups_disagree"].(bool) {
+		t.Fatal("expected the lookups to disagree")
+	}
+}
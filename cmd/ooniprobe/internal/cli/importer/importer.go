@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("import", "Import measurements previously published to the OONI API")
+	reportID := cmd.Flag("report-id", "Import the measurements of the given report").String()
+	probeCC := cmd.Flag("probe-cc", "Import the measurements of the given country code (requires --probe-asn)").String()
+	probeASN := cmd.Flag("probe-asn", "Import the measurements of the given ASN, e.g., AS30722 (requires --probe-cc)").String()
+	since := cmd.Flag("since", "Only import measurements started on or after YYYY-MM-DD").String()
+	limit := cmd.Flag("limit", "Set the maximum number of measurements to import").Default("1000").Int()
+	apiURL := cmd.Flag("api-url", "Set the base URL of the OONI API").Hidden().String()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probeCLI, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		config := probeservices.MeasurementListConfig{
+			ReportID: *reportID,
+			ProbeCC:  *probeCC,
+			ProbeASN: *probeASN,
+			Limit:    *limit,
+		}
+		if *since != "" {
+			if config.Since, err = time.Parse("2006-01-02", *since); err != nil {
+				log.WithError(err).Error("invalid --since date")
+				return err
+			}
+		}
+		sess, err := probeCLI.NewSession(context.Background(), model.RunTypeManual)
+		if err != nil {
+			log.WithError(err).Error("failed to create a measurement session")
+			return err
+		}
+		defer sess.Close()
+		client, err := sess.NewProbeServicesClient(context.Background())
+		if err != nil {
+			log.WithError(err).Error("failed to create a probe services client")
+			return err
+		}
+		if *apiURL != "" {
+			client.BaseURL = *apiURL
+		}
+		measurements, err := client.ListMeasurements(context.Background(), config)
+		if err != nil {
+			log.WithError(err).Error("failed to fetch measurements")
+			return err
+		}
+		count, err := probeCLI.DB().ImportMeasurements(probeCLI.Home(), measurements)
+		if err != nil {
+			log.WithError(err).Error("failed to import measurements")
+			return err
+		}
+		log.Infof("Imported %d of %d measurements", count, len(measurements))
+		return nil
+	})
+}
@@ -1,7 +1,7 @@
 package list
 
 import (
-	"strings"
+	"encoding/json"
 
 	"github.com/alecthomas/kingpin"
 	"github.com/apex/log"
@@ -12,7 +12,13 @@ import (
 
 func init() {
 	cmd := root.Command("list", "List results")
-	resultID := cmd.Arg("id", "the id of the result to list measurements for").Int64()
+	resultID := cmd.Arg("id", "the id of the result to list measurements for").HintAction(root.ResultIDHints).Int64()
+	testGroup := cmd.Flag("test-group", "Only list results of the given test group").String()
+	anomalies := cmd.Flag("anomalies", "Only list results with anomalous measurements").Bool()
+	since := cmd.Flag("since", "Only list results started on or after YYYY-MM-DD").String()
+	until := cmd.Flag("until", "Only list results started before YYYY-MM-DD").String()
+	asn := cmd.Flag("asn", "Only list results of the given ASN").Uint()
+	countryCode := cmd.Flag("country-code", "Only list results of the given country code").String()
 	cmd.Action(func(_ *kingpin.ParseContext) error {
 		probeCLI, err := root.Init()
 		if err != nil {
@@ -20,7 +26,7 @@ func init() {
 			return err
 		}
 		if *resultID > 0 {
-			measurements, err := database.ListMeasurements(probeCLI.DB(), *resultID)
+			measurements, err := probeCLI.DB().ListMeasurements(*resultID)
 			if err != nil {
 				log.WithError(err).Error("failed to list measurements")
 				return err
@@ -63,13 +69,39 @@ func init() {
 			}
 			output.MeasurementSummary(msmtSummary)
 		} else {
-			doneResults, incompleteResults, err := database.ListResults(probeCLI.DB())
+			filter := &database.ResultFilter{
+				TestGroupName: *testGroup,
+				AnomalyOnly:   *anomalies,
+				ASN:           *asn,
+				CountryCode:   *countryCode,
+			}
+			if filter.Since, err = root.ParseDate(*since); err != nil {
+				log.WithError(err).Error("invalid --since date")
+				return err
+			}
+			if filter.Until, err = root.ParseDate(*until); err != nil {
+				log.WithError(err).Error("invalid --until date")
+				return err
+			}
+			doneResults, _, err := probeCLI.DB().ListResultsMatching(filter)
 			if err != nil {
 				log.WithError(err).Error("failed to list results")
 				return err
 			}
+			// We only remind the user about the incomplete results
+			// when listing all the results.
+			var incompleteResults []database.IncompleteResult
+			if filter.IsZero() {
+				incompleteResults, err = probeCLI.DB().ListIncompleteResults()
+				if err != nil {
+					log.WithError(err).Error("failed to list incomplete results")
+					return err
+				}
+			}
 			if len(incompleteResults) > 0 {
 				output.SectionTitle("Incomplete results")
+				output.Paragraph("Use `ooniprobe resume <id>` to resume an incomplete result " +
+					"or `ooniprobe resume --mark-failed <id>` to give up on it.")
 			}
 			for idx, result := range incompleteResults {
 				output.ResultItem(output.ResultItemData{
@@ -81,9 +113,9 @@ func init() {
 					NetworkName:             result.Network.NetworkName,
 					Country:                 result.Network.CountryCode,
 					ASN:                     result.Network.ASN,
-					MeasurementCount:        0,
+					MeasurementCount:        result.TotalCount,
 					MeasurementAnomalyCount: 0,
-					TestKeys:                "{}", // FIXME this used to be Summary we probably need to use a list now
+					TestKeys:                "{}",
 					Done:                    result.IsDone,
 					IsUploaded:              result.IsUploaded,
 					DataUsageUp:             result.DataUsageUp,
@@ -96,19 +128,14 @@ func init() {
 			for idx, result := range doneResults {
 				testKeys := "{}"
 
-				// We only care to expose in the testKeys the value of the ndt test result
+				// We only care to expose in the testKeys the performance summary
 				if result.TestGroupName == "performance" {
-					// The test_keys column are concanetated with the "|" character as a separator.
-					// We consider this to be safe since we only really care about values of the
-					// performance test_keys where the values are all numbers and none of the keys
-					// contain the "|" character.
-					for _, e := range strings.Split(result.TestKeys, "|") {
-						// We use the presence of the "download" key to indicate we have found the
-						// ndt test_keys, since the dash result does not contain it.
-						if strings.Contains(e, "download") {
-							testKeys = e
-						}
+					data, err := json.Marshal(result.PerformanceTestKeys())
+					if err != nil {
+						log.WithError(err).Error("failed to serialize the performance summary")
+						return err
 					}
+					testKeys = string(data)
 				}
 
 				output.ResultItem(output.ResultItemData{
@@ -124,6 +151,7 @@ func init() {
 					MeasurementCount:        result.TotalCount,
 					MeasurementAnomalyCount: result.AnomalyCount,
 					Done:                    result.IsDone,
+					IsPartial:               result.IsPartial,
 					DataUsageUp:             result.DataUsageUp,
 					DataUsageDown:           result.DataUsageDown,
 				})
@@ -133,6 +161,11 @@ func init() {
 				resultSummary.TotalDataUsageDown += result.DataUsageDown
 			}
 			resultSummary.TotalNetworks = int64(len(netCount))
+			resultSummary.PendingUploads, err = probeCLI.DB().CountPendingUploads()
+			if err != nil {
+				log.WithError(err).Error("failed to count pending uploads")
+				return err
+			}
 			output.ResultSummary(resultSummary)
 		}
 		return nil
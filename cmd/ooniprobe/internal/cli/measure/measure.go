@@ -0,0 +1,71 @@
+package measure
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/onboard"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/measure"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("measure", "Measure a single URL right away")
+	experiment := cmd.Flag(
+		"experiment", "Experiment to use (one of: "+strings.Join(measure.Experiments, ", ")+")",
+	).Default(measure.Experiments[0]).Enum(measure.Experiments...)
+	submit := cmd.Flag("submit", "Submit the measurement to the OONI collector").Bool()
+	annotations := cmd.Flag(
+		"annotation", "Add the given key=value annotation to the measurement (can be repeated)",
+	).Short('A').StringMap()
+	URL := cmd.Arg("url", "the URL to measure").Required().String()
+
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		if err := measure.CheckURL(*URL); err != nil {
+			log.WithError(err).Error("invalid command line")
+			return err
+		}
+		probe, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if err := onboard.MaybeOnboarding(probe); err != nil {
+			log.WithError(err).Error("failed to perform onboarding")
+			return err
+		}
+		probe.ListenForSignals()
+		sess, err := probe.NewSession(context.Background(), model.RunTypeManual)
+		if err != nil {
+			log.WithError(err).Error("failed to create a measurement session")
+			return err
+		}
+		defer sess.Close()
+		if err := sess.MaybeLookupLocation(); err != nil {
+			log.WithError(err).Error("failed to lookup the location of the probe")
+			return err
+		}
+		if err := sess.MaybeLookupBackends(); err != nil {
+			log.WithError(err).Error("failed to discover OONI backends")
+			return err
+		}
+		report, err := measure.Run(context.Background(), measure.Config{
+			Session:        sess,
+			ExperimentName: *experiment,
+			Home:           probe.Home(),
+			Submit:         *submit,
+			Annotations:    *annotations,
+		}, *URL)
+		if err != nil {
+			log.WithError(err).Errorf("failed to measure %s", *URL)
+			return err
+		}
+		output.MeasureVerdict(*experiment, *URL, report.Verdict.Anomaly,
+			report.Verdict.Message, report.Path, report.ExplorerURL)
+		return nil
+	})
+}
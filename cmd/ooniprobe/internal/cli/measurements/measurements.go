@@ -0,0 +1,58 @@
+package measurements
+
+import (
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+)
+
+func init() {
+	cmd := root.Command("measurements", "List the measurements of all results one page at a time")
+	testName := cmd.Flag("test-name", "Only list measurements of the given test").HintAction(root.ExperimentNameHints).String()
+	anomalies := cmd.Flag("anomalies", "Only list anomalous measurements").Bool()
+	since := cmd.Flag("since", "Only list measurements started on or after YYYY-MM-DD").String()
+	until := cmd.Flag("until", "Only list measurements started before YYYY-MM-DD").String()
+	asn := cmd.Flag("asn", "Only list measurements of the given ASN").Uint()
+	countryCode := cmd.Flag("country-code", "Only list measurements of the given country code").String()
+	annotations := cmd.Flag("annotation", "Only list measurements with the given key=value annotation (can be repeated)").StringMap()
+	limit := cmd.Flag("limit", "Maximum number of measurements to list").Default("50").Int()
+	cursor := cmd.Flag("cursor", "Cursor of the page to list, as printed by the previous page").String()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probeCLI, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		filter := &database.MeasurementFilter{
+			TestName:    *testName,
+			AnomalyOnly: *anomalies,
+			ASN:         *asn,
+			CountryCode: *countryCode,
+			Annotations: *annotations,
+		}
+		if filter.Since, err = root.ParseDate(*since); err != nil {
+			log.WithError(err).Error("invalid --since date")
+			return err
+		}
+		if filter.Until, err = root.ParseDate(*until); err != nil {
+			log.WithError(err).Error("invalid --until date")
+			return err
+		}
+		page, err := probeCLI.DB().ListMeasurementsPage(filter, *cursor, *limit)
+		if err != nil {
+			log.WithError(err).Error("failed to list measurements")
+			return err
+		}
+		for idx, msmt := range page.Measurements {
+			output.MeasurementItem(msmt, idx == 0, idx == len(page.Measurements)-1)
+		}
+		output.MeasurementPage(output.MeasurementPageData{
+			Count:      len(page.Measurements),
+			TotalCount: page.TotalCount,
+			NextCursor: page.NextCursor,
+		})
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package networks
+
+import (
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+)
+
+func init() {
+	cmd := root.Command("networks", "Show the networks we have been measuring")
+	days := cmd.Flag("days", "Number of days of recent runs to compute the anomaly trend").Default("30").Int()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probeCLI, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		recentSince := time.Now().UTC().Add(-time.Duration(*days) * 24 * time.Hour)
+		histories, err := probeCLI.DB().ListNetworkHistories(recentSince)
+		if err != nil {
+			log.WithError(err).Error("failed to list networks")
+			return err
+		}
+		output.SectionTitle("Networks")
+		for _, h := range histories {
+			output.NetworkHistoryItem(output.NetworkHistoryItemData{
+				ASN:          h.ASN,
+				CountryCode:  h.CountryCode,
+				NetworkName:  h.NetworkName,
+				FirstSeen:    h.FirstSeen,
+				LastSeen:     h.LastSeen,
+				RunCount:     h.RunCount,
+				TotalCount:   h.TotalCount,
+				AnomalyCount: h.AnomalyCount,
+				AnomalyRate:  h.AnomalyRate(),
+				Trend:        h.Trend(),
+			})
+		}
+		return nil
+	})
+}
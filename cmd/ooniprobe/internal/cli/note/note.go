@@ -0,0 +1,67 @@
+package note
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/upper/db/v4"
+)
+
+func init() {
+	cmd := root.Command("note", "Manage the notes of results")
+
+	addCmd := cmd.Command("add", "Add a note to a result")
+	addResultID := addCmd.Arg("id", "the id of the result to annotate").HintAction(root.ResultIDHints).Required().Int64()
+	addText := addCmd.Arg("text", "the text of the note (e.g., \"power outage\")").Required().Strings()
+	addCmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		note, err := ctx.DB().AddResultNote(*addResultID, strings.Join(*addText, " "))
+		if err != nil {
+			log.WithError(err).Error("failed to add note")
+			return err
+		}
+		log.Infof("Added note #%d", note.ID)
+		return nil
+	})
+
+	rmCmd := cmd.Command("rm", "Delete a note")
+	rmNoteID := rmCmd.Arg("id", "the id of the note to delete").Required().Int64()
+	rmCmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		err = ctx.DB().DeleteResultNote(*rmNoteID)
+		if err == db.ErrNoMoreRows {
+			return errors.New("note not found")
+		}
+		return err
+	})
+
+	listCmd := cmd.Command("list", "List the notes of a result")
+	listResultID := listCmd.Arg("id", "the id of the result").HintAction(root.ResultIDHints).Required().Int64()
+	listCmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		notes, err := ctx.DB().ListResultNotes(*listResultID)
+		if err != nil {
+			log.WithError(err).Error("failed to list notes")
+			return err
+		}
+		for _, note := range notes {
+			log.Infof("#%d [%s] %s", note.ID, note.CreatedAt.Format("2006-01-02 15:04"), note.Text)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package repair
+
+import (
+	"errors"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+)
+
+func init() {
+	cmd := root.Command("repair", "Remove rows and files left behind by crashes")
+	yes := cmd.Flag("yes", "Skip interactive prompt").Bool()
+	dryRun := cmd.Flag("dry-run", "Only show what would be removed").Bool()
+
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		orphans, err := ctx.DB().FindOrphans(ctx.Home())
+		if err != nil {
+			log.WithError(err).Error("failed to find orphans")
+			return err
+		}
+		if orphans.Empty() {
+			log.Info("Nothing to repair")
+			return nil
+		}
+		log.Infof("Found %d orphaned measurements", len(orphans.MeasurementIDs))
+		log.Infof("Found %d orphaned results", len(orphans.ResultIDs))
+		log.Infof("Found %d orphaned networks", len(orphans.NetworkIDs))
+		for _, path := range orphans.Paths {
+			log.Infof("Found orphaned file %s", path)
+		}
+		if *dryRun == true {
+			return nil
+		}
+		if *yes == false {
+			answer := ""
+			confirm := &survey.Select{
+				Message: "Are you sure you wish to remove the orphans",
+				Options: []string{"true", "false"},
+				Default: "false",
+			}
+			survey.AskOne(confirm, &answer, nil)
+			if answer == "false" {
+				return errors.New("canceled by user")
+			}
+		}
+		if err := ctx.DB().RemoveOrphans(orphans); err != nil {
+			log.WithError(err).Error("failed to remove orphans")
+			return err
+		}
+		log.Info("Repaired the database")
+		return nil
+	})
+}
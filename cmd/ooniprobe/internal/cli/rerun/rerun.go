@@ -0,0 +1,146 @@
+package rerun
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/fatih/color"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/onboard"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/nettests"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/upper/db/v4"
+)
+
+// anomalyString describes the outcome of a measurement.
+func anomalyString(msmt *database.Measurement) string {
+	switch {
+	case msmt.IsFailed:
+		return color.RedString("failed")
+	case !msmt.IsAnomaly.Valid:
+		return "unknown"
+	case msmt.IsAnomaly.Bool:
+		return color.RedString("anomaly")
+	default:
+		return color.GreenString("ok")
+	}
+}
+
+// selectMeasurements returns the measurements with the given IDs
+// followed by the ones selected by the filter, if any.
+func selectMeasurements(
+	probe *ooni.Probe, msmtIDs []int64, filter *database.MeasurementFilter) ([]database.MeasurementURLNetwork, error) {
+	var measurements []database.MeasurementURLNetwork
+	for _, msmtID := range msmtIDs {
+		msmt, err := probe.DB().GetMeasurement(msmtID)
+		if err == db.ErrNoMoreRows {
+			return nil, fmt.Errorf("measurement #%d not found", msmtID)
+		}
+		if err != nil {
+			log.WithError(err).Error("failed to get the measurement")
+			return nil, err
+		}
+		measurements = append(measurements, *msmt)
+	}
+	if filter == nil {
+		return measurements, nil
+	}
+	selected, err := probe.DB().ListMeasurementsMatching(filter)
+	if err != nil {
+		log.WithError(err).Error("failed to list the measurements")
+		return nil, err
+	}
+	return append(measurements, selected...), nil
+}
+
+// Rerun re-runs the tests of the given measurements with the same inputs
+// into a new result for each test group. The new measurements are re-runs
+// of the original ones.
+func Rerun(probe *ooni.Probe, measurements []database.MeasurementURLNetwork) error {
+	byGroup := nettests.RerunsByGroup(measurements)
+	var groupNames []string
+	for groupName := range byGroup {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
+	for _, groupName := range groupNames {
+		if probe.IsTerminated() {
+			break
+		}
+		reruns := byGroup[groupName]
+		log.Infof("Re-running %d %s measurements", len(reruns), color.BlueString(groupName))
+		err := nettests.RunGroup(nettests.RunGroupConfig{
+			GroupName: groupName,
+			Probe:     probe,
+			RunType:   model.RunTypeManual,
+			Reruns:    reruns,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	cmd := root.Command("rerun", "Re-run the tests of measurements to compare the outcomes")
+	msmtIDs := cmd.Arg("id", "the ids of the measurements to re-run").Int64List()
+	failed := cmd.Flag("failed", "Re-run the failed measurements").Bool()
+	anomalous := cmd.Flag("anomalous", "Re-run the anomalous measurements").Bool()
+	resultID := cmd.Flag(
+		"result", "Only re-run the measurements of this result (all of them, unless using --failed or --anomalous)",
+	).HintAction(root.ResultIDHints).Int64()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		var filter *database.MeasurementFilter
+		if *failed || *anomalous || *resultID > 0 {
+			filter = &database.MeasurementFilter{
+				ResultID:    *resultID,
+				AnomalyOnly: *anomalous,
+				FailedOnly:  *failed,
+			}
+		} else if len(*msmtIDs) <= 0 {
+			return errors.New("specify the measurements to re-run")
+		}
+		probe, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if err = onboard.MaybeOnboarding(probe); err != nil {
+			log.WithError(err).Error("failed to perform onboarding")
+			return err
+		}
+		measurements, err := selectMeasurements(probe, *msmtIDs, filter)
+		if err != nil {
+			return err
+		}
+		if len(measurements) <= 0 {
+			log.Info("No measurements to re-run")
+			return nil
+		}
+		probe.ListenForSignals()
+		if err := Rerun(probe, measurements); err != nil {
+			return err
+		}
+		for _, original := range measurements {
+			reruns, err := probe.DB().ListMeasurementReruns(original.Measurement.ID)
+			if err != nil {
+				log.WithError(err).Error("failed to list the re-runs")
+				return err
+			}
+			if len(reruns) < 1 {
+				log.Warnf("Could not re-run measurement #%d", original.Measurement.ID)
+				continue
+			}
+			rerun := &reruns[len(reruns)-1]
+			log.Infof("Measurement #%d: %s, re-run #%d: %s", original.Measurement.ID,
+				anomalyString(&original.Measurement), rerun.ID, anomalyString(rerun))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,41 @@
+package restore
+
+import (
+	"errors"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+)
+
+func init() {
+	cmd := root.Command("restore", "Replace the results and the measurements with a backup")
+	backupDir := cmd.Arg("dir", "the directory containing the backup").Required().String()
+	yes := cmd.Flag("yes", "Skip interactive prompt").Bool()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if *yes == false {
+			answer := ""
+			confirm := &survey.Select{
+				Message: "Are you sure you wish to replace your results with the backup",
+				Options: []string{"true", "false"},
+				Default: "false",
+			}
+			survey.AskOne(confirm, &answer, nil)
+			if answer == "false" {
+				return errors.New("canceled by user")
+			}
+		}
+		if err := ctx.DB().Restore(ctx.Home(), *backupDir); err != nil {
+			log.WithError(err).Error("failed to restore")
+			return err
+		}
+		log.Infof("Restored backup from %s", *backupDir)
+		return nil
+	})
+}
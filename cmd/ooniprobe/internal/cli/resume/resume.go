@@ -0,0 +1,65 @@
+package resume
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/fatih/color"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/onboard"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/nettests"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/upper/db/v4"
+)
+
+func init() {
+	cmd := root.Command("resume", "Resume a result left incomplete by a crashed or interrupted run")
+	resultID := cmd.Arg("id", "the id of the incomplete result").HintAction(root.ResultIDHints).Required().Int64()
+	markFailed := cmd.Flag("mark-failed", "Mark the result as failed instead of resuming it").Bool()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probe, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if *markFailed {
+			err = probe.DB().FailResult(*resultID, "abandoned by the user")
+			if err == db.ErrNoMoreRows {
+				return errors.New("result not found")
+			}
+			if err != nil {
+				log.WithError(err).Error("failed to mark the result as failed")
+				return err
+			}
+			log.Infof("Marked result #%d as failed", *resultID)
+			return nil
+		}
+		results, err := probe.DB().ListIncompleteResults()
+		if err != nil {
+			log.WithError(err).Error("failed to list incomplete results")
+			return err
+		}
+		groupName := ""
+		for _, result := range results {
+			if result.Result.ID == *resultID {
+				groupName = result.TestGroupName
+			}
+		}
+		if groupName == "" {
+			return fmt.Errorf("no incomplete result with id #%d", *resultID)
+		}
+		if err = onboard.MaybeOnboarding(probe); err != nil {
+			log.WithError(err).Error("failed to perform onboarding")
+			return err
+		}
+		log.Infof("Resuming %s tests", color.BlueString(groupName))
+		return nettests.RunGroup(nettests.RunGroupConfig{
+			GroupName:      groupName,
+			Probe:          probe,
+			RunType:        model.RunTypeManual,
+			ResumeResultID: *resultID,
+		})
+	})
+}
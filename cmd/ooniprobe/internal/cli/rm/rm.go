@@ -12,49 +12,82 @@ import (
 	"github.com/upper/db/v4"
 )
 
-func deleteAll(sess db.Session, skipInteractive bool) error {
-	if skipInteractive == false {
-		answer := ""
-		confirm := &survey.Select{
-			Message: fmt.Sprintf("Are you sure you wish to delete ALL results"),
-			Options: []string{"true", "false"},
-			Default: "false",
-		}
-		survey.AskOne(confirm, &answer, nil)
-		if answer == "false" {
-			return errors.New("canceled by user")
-		}
+// confirm asks the user to confirm the deletion.
+func confirm(message string) error {
+	answer := ""
+	confirm := &survey.Select{
+		Message: message,
+		Options: []string{"true", "false"},
+		Default: "false",
 	}
-	doneResults, incompleteResults, err := database.ListResults(sess)
+	survey.AskOne(confirm, &answer, nil)
+	if answer == "false" {
+		return errors.New("canceled by user")
+	}
+	return nil
+}
+
+// selectResults returns the IDs of the results selected by filter.
+func selectResults(actions database.Actions, filter *database.ResultFilter) ([]int64, error) {
+	doneResults, incompleteResults, err := actions.ListResultsMatching(filter)
 	if err != nil {
 		log.WithError(err).Error("failed to list results")
-		return err
+		return nil, err
+	}
+	var resultIDs []int64
+	for _, results := range [][]database.ResultNetwork{incompleteResults, doneResults} {
+		for _, result := range results {
+			resultIDs = append(resultIDs, result.Result.ID)
+		}
 	}
-	cnt := 0
-	for _, result := range incompleteResults {
-		err = database.DeleteResult(sess, result.Result.ID)
-		if err == db.ErrNoMoreRows {
-			log.WithError(err).Errorf("failed to delete result #%d", result.Result.ID)
+	return resultIDs, nil
+}
+
+// deleteResults deletes either all the given results or none of them.
+func deleteResults(actions database.Actions, resultIDs []int64, skipInteractive bool) error {
+	// The results selected by date may also be given by ID.
+	unique := make(map[int64]bool)
+	var ids []int64
+	for _, resultID := range resultIDs {
+		if !unique[resultID] {
+			unique[resultID] = true
+			ids = append(ids, resultID)
 		}
-		cnt++
 	}
-	for _, result := range doneResults {
-		err = database.DeleteResult(sess, result.Result.ID)
-		if err == db.ErrNoMoreRows {
-			log.WithError(err).Errorf("failed to delete result #%d", result.Result.ID)
+	resultIDs = ids
+	if len(resultIDs) <= 0 {
+		log.Info("No results to delete")
+		return nil
+	}
+	if skipInteractive == false {
+		message := fmt.Sprintf("Are you sure you wish to delete %d results", len(resultIDs))
+		if len(resultIDs) == 1 {
+			message = fmt.Sprintf("Are you sure you wish to delete the result #%d", resultIDs[0])
+		}
+		if err := confirm(message); err != nil {
+			return err
 		}
-		cnt++
 	}
-	log.Infof("Deleted #%d measurements", cnt)
+	err := actions.DeleteResults(resultIDs)
+	if err == db.ErrNoMoreRows {
+		return errors.New("result not found")
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("Deleted %d results", len(resultIDs))
 	return nil
 }
 
 func init() {
-	cmd := root.Command("rm", "Delete a result")
+	cmd := root.Command("rm", "Delete results")
 	yes := cmd.Flag("yes", "Skip interactive prompt").Bool()
 	all := cmd.Flag("all", "Delete all measurements").Bool()
+	since := cmd.Flag("since", "Delete the results started on or after YYYY-MM-DD").String()
+	until := cmd.Flag("until", "Delete the results started before YYYY-MM-DD").String()
+	allBefore := cmd.Flag("all-before", "Delete all the results started before YYYY-MM-DD").String()
 
-	resultID := cmd.Arg("id", "the id of the result to delete").Int64()
+	resultIDs := cmd.Arg("id", "the ids of the results to delete").HintAction(root.ResultIDHints).Int64List()
 
 	cmd.Action(func(_ *kingpin.ParseContext) error {
 		ctx, err := root.Init()
@@ -64,30 +97,36 @@ func init() {
 		}
 
 		if *all == true {
-			return deleteAll(ctx.DB(), *yes)
+			ids, err := selectResults(ctx.DB(), &database.ResultFilter{})
+			if err != nil {
+				return err
+			}
+			return deleteResults(ctx.DB(), ids, *yes)
 		}
 
-		if *yes == true {
-			err = database.DeleteResult(ctx.DB(), *resultID)
-			if err == db.ErrNoMoreRows {
-				return errors.New("result not found")
+		if *allBefore != "" {
+			if *until != "" {
+				return errors.New("cannot use --all-before along with --until")
 			}
-			return err
+			*until = *allBefore
 		}
-		answer := ""
-		confirm := &survey.Select{
-			Message: fmt.Sprintf("Are you sure you wish to delete the result #%d", *resultID),
-			Options: []string{"true", "false"},
-			Default: "false",
+		filter := &database.ResultFilter{}
+		if filter.Since, err = root.ParseDate(*since); err != nil {
+			return fmt.Errorf("invalid --since date: %w", err)
 		}
-		survey.AskOne(confirm, &answer, nil)
-		if answer == "false" {
-			return errors.New("canceled by user")
+		if filter.Until, err = root.ParseDate(*until); err != nil {
+			return fmt.Errorf("invalid --until date: %w", err)
 		}
-		err = database.DeleteResult(ctx.DB(), *resultID)
-		if err == db.ErrNoMoreRows {
-			return errors.New("result not found")
+		ids := *resultIDs
+		if !filter.IsZero() {
+			selected, err := selectResults(ctx.DB(), filter)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, selected...)
+		} else if len(ids) <= 0 {
+			return errors.New("specify the results to delete")
 		}
-		return err
+		return deleteResults(ctx.DB(), ids, *yes)
 	})
 }
@@ -0,0 +1,12 @@
+package root
+
+import "time"
+
+// ParseDate parses the YYYY-MM-DD date of the --since and --until flags
+// of the subcommands, returning the zero time when the date is empty.
+func ParseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
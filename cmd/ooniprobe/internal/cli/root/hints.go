@@ -0,0 +1,50 @@
+package root
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/discard"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/version"
+)
+
+// The hints below provide the dynamic shell completions (see `ooniprobe
+// completion`). Since the shell invokes us while the user is typing, they
+// must not prompt, log, or create the OONI home, and they return no hints
+// rather than failing.
+
+// ExperimentNameHints returns the names of the experiments.
+func ExperimentNameHints() []string {
+	return engine.AllExperiments()
+}
+
+// ResultIDHints returns the IDs of the results in the database.
+func ResultIDHints() []string {
+	homePath, err := utils.GetOONIHome()
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(utils.DBDir(homePath, "main")); err != nil {
+		return nil
+	}
+	log.SetHandler(discard.Default)
+	p := ooni.NewProbe("", homePath)
+	if err := p.Init(ooni.DefaultSoftwareName, version.Version); err != nil {
+		return nil
+	}
+	defer os.RemoveAll(p.TempDir())
+	defer p.Close()
+	doneResults, incompleteResults, err := p.DB().ListResults()
+	if err != nil {
+		return nil
+	}
+	var hints []string
+	for _, result := range append(incompleteResults, doneResults...) {
+		hints = append(hints, strconv.FormatInt(result.Result.ID, 10))
+	}
+	return hints
+}
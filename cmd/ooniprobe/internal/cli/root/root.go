@@ -1,13 +1,24 @@
 package root
 
 import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/alecthomas/kingpin"
 	"github.com/apex/log"
+	"github.com/fatih/color"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/crash"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/log/handlers/batch"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/log/handlers/cli"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/log/handlers/json"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/log/handlers/level"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/log/handlers/syslog"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/debugserver"
 	"github.com/ooni/probe-cli/v3/internal/version"
 )
 
@@ -20,6 +31,60 @@ var Command = Cmd.Command
 // Init should be called by all subcommand that care to have a ooni.Context instance
 var Init func() (*ooni.Probe, error)
 
+// ConfigPath returns the path of the config file without loading it.
+var ConfigPath func() (string, error)
+
+// probe is the probe created by Init, if any.
+var probe *ooni.Probe
+
+// crashRecorder records the last log events for the crash dumps.
+var crashRecorder *crash.Recorder
+
+// debugServer is the server started by --debug-address, if any.
+var debugServer *debugserver.Server
+
+// Close closes the probe created by Init, if any, and stops the
+// debug server. The main function should call Close before exiting.
+func Close() error {
+	if debugServer != nil {
+		debugServer.Close()
+	}
+	if probe == nil {
+		return nil
+	}
+	return probe.Close()
+}
+
+// askDatabasePassphrase asks the user for the database passphrase.
+func askDatabasePassphrase() (string, error) {
+	var passphrase string
+	err := survey.AskOne(&survey.Password{Message: "Database passphrase:"}, &passphrase)
+	return passphrase, err
+}
+
+// parseProxyURL parses the value of the --proxy flag, returning
+// nil when the user did not configure a proxy.
+func parseProxyURL(value string) (*url.URL, error) {
+	if value == "" {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "psiphon", "tor":
+		return proxyURL, nil
+	case "socks5", "http":
+		if proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid --proxy URL: missing host in %s", value)
+		}
+		return proxyURL, nil
+	default:
+		return nil, fmt.Errorf("invalid --proxy URL: unsupported scheme in %s", value)
+	}
+}
+
 // NewProbeCLI is like Init but returns a ooni.ProbeCLI instead.
 func NewProbeCLI() (ooni.ProbeCLI, error) {
 	probeCLI, err := Init()
@@ -32,11 +97,20 @@ func NewProbeCLI() (ooni.ProbeCLI, error) {
 func init() {
 	configPath := Cmd.Flag("config", "Set a custom config file path").Short('c').String()
 
-	isVerbose := Cmd.Flag("verbose", "Enable verbose log output.").Short('v').Bool()
+	verbosity := Cmd.Flag(
+		"verbose", "Enable verbose log output (repeat, e.g., -vv, to include the debug logs of the engine).",
+	).Short('v').Counter()
 	isBatch := Cmd.Flag("batch", "Enable batch command line usage.").Bool()
+	isJSON := Cmd.Flag("json", "Emit the output of commands as JSON on the standard output.").Bool()
 	logHandler := Cmd.Flag(
-		"log-handler", "Set the desired log handler (one of: batch, cli, syslog)",
+		"log-handler", "Set the desired log handler (one of: batch, cli, json, syslog)",
 	).String()
+	logFormat := Cmd.Flag(
+		"log-format", "Set the format of the logs (one of: text, json)",
+	).Default("text").Enum("text", "json")
+	progress := Cmd.Flag(
+		"progress", "Set how to emit the progress of runs (one of: cli, json)",
+	).Default("cli").Enum("cli", "json")
 
 	softwareName := Cmd.Flag(
 		"software-name", "Override application name",
@@ -45,28 +119,88 @@ func init() {
 		"software-version", "Override the application version",
 	).Default(version.Version).String()
 
+	proxy := Cmd.Flag(
+		"proxy", "Use a proxy to talk to the OONI backend (e.g., socks5://127.0.0.1:9050/, http://127.0.0.1:8080/, tor:///, psiphon:///)",
+	).String()
+
+	debugAddress := Cmd.Flag(
+		"debug-address", "Serve pprof profiles and runtime metrics at this address (e.g., 127.0.0.1:6060)",
+	).String()
+
 	Cmd.PreAction(func(ctx *kingpin.ParseContext) error {
 		// TODO(bassosimone): we need to properly deprecate --batch
 		// in favour of more granular command line flags.
 		if *isBatch && *logHandler != "" {
 			log.Fatal("cannot specify --batch and --log-handler together")
 		}
+		if *isJSON && (*isBatch || *logHandler != "") {
+			log.Fatal("cannot specify --json together with --batch or --log-handler")
+		}
 		if *isBatch {
 			*logHandler = "batch"
 		}
+		if *logFormat == "json" {
+			// Both the batch and the json handler emit JSON logs.
+			switch *logHandler {
+			case "":
+				*logHandler = "batch"
+			case "cli", "syslog":
+				log.Fatalf("cannot specify --log-format json together with --log-handler %s", *logHandler)
+			}
+		}
+		if *isJSON {
+			// Like --batch, --json implies we should not ask questions,
+			// because the user is most likely a script.
+			*isBatch = true
+			*logHandler = "json"
+		}
+		// With --progress=json, the standard output only contains the
+		// progress events, hence the logs go to the standard error.
+		logOutput := os.Stdout
+		if *progress == "json" {
+			output.EnableProgressEvents(os.Stdout)
+			output.Stdout = os.Stderr
+			logOutput = os.Stderr
+		}
+		var handler log.Handler
 		switch *logHandler {
+		case "json":
+			handler = json.New(logOutput, os.Stderr)
+			output.Stdout = os.Stderr
+			color.NoColor = true
 		case "batch":
-			log.SetHandler(batch.Default)
+			handler = batch.New(logOutput)
 		case "cli", "":
-			log.SetHandler(cli.Default)
+			handler = cli.New(logOutput)
 		case "syslog":
-			log.SetHandler(syslog.Default)
+			handler = syslog.Default
 		default:
 			log.Fatalf("unknown --log-handler: %s", *logHandler)
 		}
-		if *isVerbose {
-			log.SetLevel(log.DebugLevel)
-			log.Debugf("ooni version %s", version.Version)
+		logLevel, engineLogLevel := level.FromVerbosity(*verbosity)
+		crashRecorder = crash.NewRecorder(
+			level.New(handler, logLevel, engineLogLevel), crash.DefaultEvents)
+		log.SetHandler(crashRecorder)
+		log.SetLevel(logLevel)
+		log.Debugf("ooni version %s", version.Version)
+
+		if *debugAddress != "" {
+			server, err := debugserver.Start(*debugAddress, log.Log)
+			if err != nil {
+				log.WithError(err).Fatal("failed to start the debug server")
+			}
+			debugServer = server
+		}
+
+		ConfigPath = func() (string, error) {
+			if *configPath != "" {
+				return *configPath, nil
+			}
+			homePath, err := utils.GetOONIHome()
+			if err != nil {
+				return "", err
+			}
+			return utils.ConfigPath(homePath), nil
 		}
 
 		Init = func() (*ooni.Probe, error) {
@@ -77,16 +211,27 @@ func init() {
 				return nil, err
 			}
 
-			probe := ooni.NewProbe(*configPath, homePath)
-			err = probe.Init(*softwareName, *softwareVersion)
+			proxyURL, err := parseProxyURL(*proxy)
+			if err != nil {
+				return nil, err
+			}
+
+			p := ooni.NewProbe(*configPath, homePath)
+			p.SetProxyURL(proxyURL)
+			p.SetCrashRecorder(crashRecorder)
+			if !*isBatch {
+				p.SetAskDatabasePassphrase(askDatabasePassphrase)
+			}
+			err = p.Init(*softwareName, *softwareVersion)
 			if err != nil {
 				return nil, err
 			}
 			if *isBatch {
-				probe.SetIsBatch(true)
+				p.SetIsBatch(true)
 			}
 
-			return probe, nil
+			probe = p
+			return p, nil
 		}
 
 		return nil
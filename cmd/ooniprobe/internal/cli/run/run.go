@@ -1,19 +1,66 @@
 package run
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/alecthomas/kingpin"
 	"github.com/apex/log"
 	"github.com/fatih/color"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/onboard"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/nettests"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
+// categoryCodeRegexp matches the category codes of the test lists.
+var categoryCodeRegexp = regexp.MustCompile(`^[A-Z]+$`)
+
+// parseCategoryCodes parses a comma separated list of category codes
+// (e.g., "NEWS,HUMR"), returning nil when the list is empty.
+func parseCategoryCodes(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var codes []string
+	for _, code := range strings.Split(value, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !categoryCodeRegexp.MatchString(code) {
+			return nil, fmt.Errorf("invalid category code %q", code)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
 func init() {
 	cmd := root.Command("run", "Run a test group or OONI Run link")
 	noCollector := cmd.Flag("no-collector", "Disable uploading measurements to a collector").Bool()
+	collectors := cmd.Flag(
+		"collector", "Submit measurements to this collector URL if the default one fails (can be repeated)",
+	).Strings()
+	replaceDefaultCollector := cmd.Flag(
+		"replace-default-collector", "Only submit measurements to the collectors specified using --collector",
+	).Bool()
+	linkType := cmd.Flag(
+		"link-type", "Type of the link we're using (one of: wifi, mobile, wired)",
+	).Enum(database.LinkTypeWifi, database.LinkTypeMobile, database.LinkTypeWired)
+	annotations := cmd.Flag(
+		"annotation", "Add the given key=value annotation to each measurement (can be repeated)",
+	).Short('A').StringMap()
+	dryRun := cmd.Flag(
+		"dry-run", "Only print which experiments and inputs we would measure",
+	).Bool()
+	maxRuntime := cmd.Flag(
+		"max-runtime", "Stop each test group after this time (e.g., 10m), finishing the current measurement",
+	).Duration()
+	parallelism := cmd.Flag(
+		"parallelism", fmt.Sprintf("Number of inputs (e.g., URLs) to test concurrently (at most %d)", nettests.MaxParallelism),
+	).Default("1").Int()
 
 	var probe *ooni.Probe
 	cmd.Action(func(_ *kingpin.ParseContext) error {
@@ -30,6 +77,27 @@ func init() {
 		if *noCollector {
 			probe.Config().Sharing.UploadResults = false
 		}
+		if *replaceDefaultCollector && len(*collectors) <= 0 {
+			err = errors.New("--replace-default-collector requires at least one --collector")
+			log.WithError(err).Error("invalid command line")
+			return err
+		}
+		if *maxRuntime < 0 {
+			err = errors.New("--max-runtime must not be negative")
+			log.WithError(err).Error("invalid command line")
+			return err
+		}
+		if *parallelism < 1 || *parallelism > nettests.MaxParallelism {
+			err = fmt.Errorf("--parallelism must be between 1 and %d", nettests.MaxParallelism)
+			log.WithError(err).Error("invalid command line")
+			return err
+		}
+		if *parallelism > 1 {
+			log.Warnf("Testing %d inputs concurrently: this uses more bandwidth and data, "+
+				"which may matter on metered connections, and may affect the results", *parallelism)
+		}
+		probe.SetCollectors(*collectors, *replaceDefaultCollector)
+		probe.SetLinkType(*linkType)
 		return nil
 	})
 
@@ -40,9 +108,13 @@ func init() {
 			}
 			log.Infof("Running %s tests", color.BlueString(name))
 			conf := nettests.RunGroupConfig{
-				GroupName: name,
-				Probe:     probe,
-				RunType:   runType,
+				GroupName:   name,
+				Probe:       probe,
+				RunType:     runType,
+				Annotations: *annotations,
+				DryRun:      *dryRun,
+				MaxRuntime:  *maxRuntime,
+				Parallelism: *parallelism,
 			}
 			if err := nettests.RunGroup(conf); err != nil {
 				log.WithError(err).Errorf("failed to run %s", name)
@@ -62,14 +134,40 @@ func init() {
 	websitesCmd := cmd.Command("websites", "")
 	inputFile := websitesCmd.Flag("input-file", "File containing input URLs").Strings()
 	input := websitesCmd.Flag("input", "Test the specified URL").Strings()
+	withTestList := websitesCmd.Flag(
+		"with-test-list", "Test the URLs of the test list besides the ones given using --input and --input-file",
+	).Bool()
+	categoryCodes := websitesCmd.Flag(
+		"category-codes", "Only test URLs with these comma separated category codes (e.g., NEWS,HUMR)",
+	).String()
+	excludeCategoryCodes := websitesCmd.Flag(
+		"exclude-category-codes", "Do not test URLs with these comma separated category codes",
+	).String()
 	websitesCmd.Action(func(_ *kingpin.ParseContext) error {
+		include, err := parseCategoryCodes(*categoryCodes)
+		if err != nil {
+			log.WithError(err).Error("invalid --category-codes")
+			return err
+		}
+		exclude, err := parseCategoryCodes(*excludeCategoryCodes)
+		if err != nil {
+			log.WithError(err).Error("invalid --exclude-category-codes")
+			return err
+		}
 		log.Infof("Running %s tests", color.BlueString("websites"))
 		return nettests.RunGroup(nettests.RunGroupConfig{
-			GroupName:  "websites",
-			Probe:      probe,
-			InputFiles: *inputFile,
-			Inputs:     *input,
-			RunType:    model.RunTypeManual,
+			GroupName:            "websites",
+			Probe:                probe,
+			InputFiles:           *inputFile,
+			Inputs:               *input,
+			RunType:              model.RunTypeManual,
+			Annotations:          *annotations,
+			DryRun:               *dryRun,
+			CategoryCodes:        include,
+			ExcludeCategoryCodes: exclude,
+			MergeWithTestList:    *withTestList,
+			MaxRuntime:           *maxRuntime,
+			Parallelism:          *parallelism,
 		})
 	})
 
@@ -80,9 +178,16 @@ func init() {
 	}
 
 	unattendedCmd := cmd.Command("unattended", "")
+	maxJitter := unattendedCmd.Flag(
+		"max-jitter", "Wait for a random delay up to this time before running (use 0s to start immediately)",
+	).Default("2m").Duration()
 	unattendedCmd.Action(func(_ *kingpin.ParseContext) error {
-		return functionalRun(model.RunTypeTimed, func(name string, gr nettests.Group) bool {
-			return gr.UnattendedOK
+		return nettests.RunUnattended(nettests.UnattendedConfig{
+			Probe:       probe,
+			Annotations: *annotations,
+			MaxJitter:   *maxJitter,
+			DryRun:      *dryRun,
+			MaxRuntime:  *maxRuntime,
 		})
 	})
 
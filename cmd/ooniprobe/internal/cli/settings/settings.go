@@ -0,0 +1,164 @@
+package settings
+
+import (
+	"errors"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/settings"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// getPaths returns the paths of the settings. We don't call root.Init
+// because we may be provisioning a probe that has never run.
+func getPaths() (settings.Paths, error) {
+	configPath, err := root.ConfigPath()
+	if err != nil {
+		return settings.Paths{}, err
+	}
+	return settings.Paths{ConfigPath: configPath}, nil
+}
+
+// getKVStore returns the engine's kvstore, which contains the orchestra
+// credentials. Unlike getPaths, this function initializes the probe.
+func getKVStore() (model.KeyValueStore, error) {
+	probe, err := root.Init()
+	if err != nil {
+		return nil, err
+	}
+	return probe.KVStore()
+}
+
+// getPassphrase returns the passphrase of the bundle, asking the user
+// unless settings.PassphraseEnv is set. When confirm is true, the user
+// must type the passphrase twice.
+func getPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(settings.PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	var passphrase string
+	if err := survey.AskOne(&survey.Password{Message: "Bundle passphrase:"}, &passphrase); err != nil {
+		return "", err
+	}
+	if !confirm {
+		return passphrase, nil
+	}
+	var again string
+	if err := survey.AskOne(&survey.Password{Message: "Confirm the bundle passphrase:"}, &again); err != nil {
+		return "", err
+	}
+	if again != passphrase {
+		return "", errors.New("the passphrases do not match")
+	}
+	return passphrase, nil
+}
+
+func init() {
+	cmd := root.Command("settings", "Export or import the settings of the probe")
+
+	exportCmd := cmd.Command("export", "Export the config, consent, and optionally the orchestra credentials to an encrypted bundle")
+	exportPath := exportCmd.Arg("file", "the file where to write the bundle, which must not exist").Required().String()
+	includeCredentials := exportCmd.Flag(
+		"include-credentials",
+		"Include the orchestra credentials, which identify the probe, to move it to another machine",
+	).Bool()
+	exportCmd.Action(func(_ *kingpin.ParseContext) error {
+		if utils.FileExists(*exportPath) {
+			return errors.New("the bundle file already exists")
+		}
+		paths, err := getPaths()
+		if err != nil {
+			log.WithError(err).Error("failed to get the settings paths")
+			return err
+		}
+		var kvs model.KeyValueStore
+		if *includeCredentials {
+			if kvs, err = getKVStore(); err != nil {
+				log.WithError(err).Error("failed to open the engine's kvstore")
+				return err
+			}
+		}
+		passphrase, err := getPassphrase(true)
+		if err != nil {
+			return err
+		}
+		data, err := settings.Export(paths, passphrase, kvs)
+		if err != nil {
+			log.WithError(err).Error("failed to export the settings")
+			return err
+		}
+		if err := os.WriteFile(*exportPath, data, 0600); err != nil {
+			log.WithError(err).Error("failed to write the bundle")
+			return err
+		}
+		log.Infof("Wrote the settings into %s", *exportPath)
+		log.Warn("The bundle contains the config secrets: keep it and its passphrase secret")
+		if *includeCredentials {
+			log.Warn("The bundle contains the orchestra credentials: only import it on a single probe")
+		}
+		return nil
+	})
+
+	importCmd := cmd.Command("import", "Import the settings from an encrypted bundle")
+	importPath := importCmd.Arg("file", "the bundle to import").Required().ExistingFile()
+	yes := importCmd.Flag("yes", "Skip interactive prompt").Bool()
+	importCmd.Action(func(_ *kingpin.ParseContext) error {
+		paths, err := getPaths()
+		if err != nil {
+			log.WithError(err).Error("failed to get the settings paths")
+			return err
+		}
+		data, err := os.ReadFile(*importPath)
+		if err != nil {
+			log.WithError(err).Error("failed to read the bundle")
+			return err
+		}
+		passphrase, err := getPassphrase(false)
+		if err != nil {
+			return err
+		}
+		bundle, err := settings.Decrypt(passphrase, data)
+		if err != nil {
+			log.WithError(err).Error("failed to decrypt the bundle")
+			return err
+		}
+		if *yes == false && utils.FileExists(paths.ConfigPath) {
+			answer := ""
+			confirm := &survey.Select{
+				Message: "Are you sure you wish to replace the current settings",
+				Options: []string{"true", "false"},
+				Default: "false",
+			}
+			survey.AskOne(confirm, &answer, nil)
+			if answer == "false" {
+				return errors.New("canceled by user")
+			}
+		}
+		if err := settings.Import(paths, bundle); err != nil {
+			log.WithError(err).Error("failed to import the settings")
+			return err
+		}
+		if bundle.HasCredentials() {
+			// We open the kvstore after writing the config, which
+			// tells us whether and how to open the database.
+			kvs, err := getKVStore()
+			if err != nil {
+				log.WithError(err).Error("failed to open the engine's kvstore")
+				return err
+			}
+			if err := settings.ImportCredentials(kvs, bundle); err != nil {
+				log.WithError(err).Error("failed to import the orchestra credentials")
+				return err
+			}
+		}
+		log.Infof("Imported the settings from %s", *importPath)
+		if !bundle.InformedConsent() {
+			log.Info("The bundle does not contain the informed consent: run `ooniprobe onboard`")
+		}
+		return nil
+	})
+}
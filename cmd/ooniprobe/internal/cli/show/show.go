@@ -1,28 +1,69 @@
 package nettest
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/alecthomas/kingpin"
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/explorer"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/report"
 )
 
+// writeHTMLReport writes the HTML report of the given result to path.
+func writeHTMLReport(actions database.Actions, resultID int64, path string) error {
+	r, err := report.New(actions, resultID)
+	if err != nil {
+		return err
+	}
+	filep, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.WriteHTML(filep); err != nil {
+		filep.Close()
+		return err
+	}
+	return filep.Close()
+}
+
 func init() {
 	cmd := root.Command("show", "Show a specific measurement")
-	msmtID := cmd.Arg("id", "the id of the measurement to show").Required().Int64()
+	msmtID := cmd.Arg("id", "the id of the measurement to show (or of the result with --html)").Required().Int64()
+	html := cmd.Flag("html", "Write a standalone HTML report of the given result").Bool()
+	outputPath := cmd.Flag("output", "Set the path of the HTML report (default: result-<id>.html)").Short('o').String()
 	cmd.Action(func(_ *kingpin.ParseContext) error {
 		ctx, err := root.Init()
 		if err != nil {
 			log.WithError(err).Error("failed to initialize root context")
 			return err
 		}
-		msmt, err := database.GetMeasurementJSON(ctx.DB(), *msmtID)
+		if *html {
+			path := *outputPath
+			if path == "" {
+				path = fmt.Sprintf("result-%d.html", *msmtID)
+			}
+			if err := writeHTMLReport(ctx.DB(), *msmtID, path); err != nil {
+				log.WithError(err).Error("failed to write the HTML report")
+				return err
+			}
+			log.Infof("Written the HTML report of result #%d to %s", *msmtID, path)
+			return nil
+		}
+		msmt, err := ctx.DB().GetMeasurementJSON(*msmtID)
 		if err != nil {
 			log.Errorf("error: %v", err)
 			return err
 		}
 		output.MeasurementJSON(msmt)
+		if msmt, err := ctx.DB().GetMeasurement(*msmtID); err == nil {
+			if URL, err := explorer.ForMeasurement(msmt); err == nil {
+				output.ExplorerLink(msmt.Measurement.ID, msmt.Measurement.TestName, URL)
+			}
+		}
 		return nil
 	})
 }
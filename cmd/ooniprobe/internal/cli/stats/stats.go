@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+)
+
+func init() {
+	cmd := root.Command("stats", "Show anomaly statistics")
+	days := cmd.Flag("days", "Number of days to include in the stats over time").Default("30").Int()
+	window := cmd.Flag("window", "Duration of each time window (e.g., 24h)").Default("24h").Duration()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		if *days <= 0 {
+			return errors.New("--days must be positive")
+		}
+		if *window <= 0 {
+			return errors.New("--window must be positive")
+		}
+		probeCLI, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		actions := probeCLI.DB()
+
+		networks, err := actions.StatsByNetwork()
+		if err != nil {
+			log.WithError(err).Error("failed to compute stats by network")
+			return err
+		}
+		output.SectionTitle("Anomalies by network")
+		for _, s := range networks {
+			output.StatsItem(output.StatsItemData{
+				GroupBy:      "network",
+				Key:          fmt.Sprintf("AS%d (%s, %s)", s.ASN, s.NetworkName, s.CountryCode),
+				TotalCount:   s.TotalCount,
+				AnomalyCount: s.AnomalyCount,
+				AnomalyRate:  s.AnomalyRate(),
+			})
+		}
+
+		groups, err := actions.StatsByTestGroup()
+		if err != nil {
+			log.WithError(err).Error("failed to compute stats by test group")
+			return err
+		}
+		output.SectionTitle("Anomalies by test group")
+		for _, s := range groups {
+			output.StatsItem(output.StatsItemData{
+				GroupBy:      "test_group",
+				Key:          s.TestGroupName,
+				TotalCount:   s.TotalCount,
+				AnomalyCount: s.AnomalyCount,
+				AnomalyRate:  s.AnomalyRate(),
+			})
+		}
+
+		until := time.Now().UTC()
+		since := until.Add(-time.Duration(*days) * 24 * time.Hour)
+		windows, err := actions.StatsOverTime(since, until, *window)
+		if err != nil {
+			log.WithError(err).Error("failed to compute stats over time")
+			return err
+		}
+		output.SectionTitle("Anomalies over time")
+		for _, s := range windows {
+			output.StatsItem(output.StatsItemData{
+				GroupBy:      "time_window",
+				Key:          s.Start.Format(time.RFC3339),
+				TotalCount:   s.TotalCount,
+				AnomalyCount: s.AnomalyCount,
+				AnomalyRate:  s.AnomalyRate(),
+			})
+		}
+		return nil
+	})
+}
@@ -0,0 +1,93 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/crash"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/nettests"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/status"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// bugReportURL is where users should file bug reports.
+const bugReportURL = "https://github.com/ooni/probe/issues/new"
+
+// printCrashReport prints the most recent crash dump, if any, such
+// that the user can attach it to a bug report.
+func printCrashReport(dumps []string) error {
+	if len(dumps) <= 0 {
+		log.Info("No crash dumps to report")
+		return nil
+	}
+	dump, err := crash.Read(dumps[0])
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(output.Stdout, string(data))
+	log.Infof("Please attach the crash report above to a bug report at %s", bugReportURL)
+	return nil
+}
+
+func init() {
+	cmd := root.Command("status", "Summarize the state of ooniprobe")
+	offline := cmd.Flag("offline", "Do not check whether we can reach the OONI backend").Bool()
+	crashReport := cmd.Flag(
+		"crash-report", "Print the most recent crash dump to attach it to a bug report",
+	).Bool()
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probe, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		var groupNames []string
+		for groupName := range nettests.All {
+			groupNames = append(groupNames, groupName)
+		}
+		schedules := probe.Config().Schedule.Groups
+		config := status.Config{
+			DB:         probe.DB(),
+			Home:       probe.Home(),
+			CrashDir:   utils.CrashDir(probe.Home()),
+			GroupNames: groupNames,
+			Schedules:  schedules,
+		}
+		if !*offline {
+			sess, err := probe.NewSession(context.Background(), model.RunTypeManual)
+			if err != nil {
+				log.WithError(err).Error("failed to create a measurement session")
+				return err
+			}
+			defer sess.Close()
+			config.Session = sess
+		}
+		st, err := status.Collect(context.Background(), config)
+		if err != nil {
+			log.WithError(err).Error("failed to collect the status")
+			return err
+		}
+		output.SectionTitle("Test groups")
+		for _, gs := range st.Groups {
+			output.StatusGroup(gs)
+		}
+		output.StatusSummary(st, len(schedules) > 0)
+		if *crashReport {
+			if err := printCrashReport(st.CrashDumps); err != nil {
+				log.WithError(err).Error("failed to print the crash report")
+				return err
+			}
+		}
+		return nil
+	})
+}
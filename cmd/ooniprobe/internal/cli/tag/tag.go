@@ -0,0 +1,79 @@
+package tag
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/upper/db/v4"
+)
+
+func init() {
+	cmd := root.Command("tag", "Manage the tags of measurements")
+
+	addCmd := cmd.Command("add", "Add a tag to a measurement")
+	addMsmtID := addCmd.Arg("id", "the id of the measurement to tag").Required().Int64()
+	addTag := addCmd.Arg("tag", "the tag to add (e.g., \"mobile hotspot\")").Required().String()
+	addCmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if err := ctx.DB().AddMeasurementTag(*addMsmtID, *addTag); err != nil {
+			log.WithError(err).Error("failed to add tag")
+			return err
+		}
+		return nil
+	})
+
+	rmCmd := cmd.Command("rm", "Remove a tag from a measurement")
+	rmMsmtID := rmCmd.Arg("id", "the id of the measurement").Required().Int64()
+	rmTag := rmCmd.Arg("tag", "the tag to remove").Required().String()
+	rmCmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		err = ctx.DB().RemoveMeasurementTag(*rmMsmtID, *rmTag)
+		if err == db.ErrNoMoreRows {
+			return errors.New("tag not found")
+		}
+		return err
+	})
+
+	listCmd := cmd.Command("list", "List the tags of a measurement or the measurements with a tag")
+	listMsmtID := listCmd.Flag("id", "the id of the measurement").Int64()
+	listTag := listCmd.Flag("tag", "the tag").String()
+	listCmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if *listTag != "" {
+			ids, err := ctx.DB().ListTaggedMeasurements(*listTag)
+			if err != nil {
+				log.WithError(err).Error("failed to list tagged measurements")
+				return err
+			}
+			for _, id := range ids {
+				log.Infof("#%d", id)
+			}
+			return nil
+		}
+		if *listMsmtID <= 0 {
+			return errors.New("please specify either --id or --tag")
+		}
+		tags, err := ctx.DB().ListMeasurementTags(*listMsmtID)
+		if err != nil {
+			log.WithError(err).Error("failed to list tags")
+			return err
+		}
+		log.Infof("#%d: %s", *listMsmtID, strings.Join(tags, ", "))
+		return nil
+	})
+}
@@ -0,0 +1,67 @@
+package tui
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/onboard"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/rerun"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/upload"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/nettests"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/tui"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func init() {
+	cmd := root.Command("tui", "Show a dashboard with the current run and the recent results")
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		probe, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		if probe.IsBatch() {
+			return errors.New("the dashboard is not available in batch mode")
+		}
+		if err = onboard.MaybeOnboarding(probe); err != nil {
+			log.WithError(err).Error("failed to perform onboarding")
+			return err
+		}
+		d := tui.New(tui.Config{
+			DB: probe.DB(),
+			RunGroup: func(groupName string) error {
+				return nettests.RunGroup(nettests.RunGroupConfig{
+					GroupName: groupName,
+					Probe:     probe,
+					RunType:   model.RunTypeManual,
+				})
+			},
+			Rerun: func(measurementID int64) error {
+				msmt, err := probe.DB().GetMeasurement(measurementID)
+				if err != nil {
+					return err
+				}
+				return rerun.Rerun(probe, []database.MeasurementURLNetwork{*msmt})
+			},
+			UploadPending: func() error {
+				_, err := upload.UploadPending(probe, time.Second)
+				return err
+			},
+			Input:        os.Stdin,
+			Output:       os.Stdout,
+			IsTerminated: probe.IsTerminated,
+		})
+		// The dashboard shows the log messages, such that
+		// they do not mess up the layout.
+		handler := log.Log.(*log.Logger).Handler
+		log.SetHandler(d)
+		defer log.SetHandler(handler)
+		probe.ListenForSignals()
+		return d.Run()
+	})
+}
@@ -0,0 +1,12 @@
+package update
+
+import (
+	_ "embed" // because we embed a file
+)
+
+// releaseSigningKey is the armored GPG public key signing the binaries
+// (see script/sign-ooniprobe.bash). When it is empty, `ooniprobe update`
+// can only check for new releases.
+//
+//go:embed release-signing-key.asc
+var releaseSigningKey string
@@ -0,0 +1,48 @@
+package update
+
+import (
+	"context"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/selfupdate"
+	"github.com/ooni/probe-cli/v3/internal/version"
+)
+
+func init() {
+	cmd := root.Command("update", "Update ooniprobe to the latest release")
+	checkOnly := cmd.Flag("check-only", "Only check whether there is a new release").Bool()
+
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		updater := selfupdate.New(selfupdate.Config{
+			SigningKey: releaseSigningKey,
+		})
+		release, err := updater.Check(context.Background())
+		if err != nil {
+			log.WithError(err).Error("failed to check for new releases")
+			return err
+		}
+		if !release.IsNewerThan(version.Version) {
+			log.Infof("You are running the latest release of ooniprobe (%s)", version.Version)
+			return nil
+		}
+		log.Infof("ooniprobe %s is available (you are running %s)", release.Version, version.Version)
+		log.Infof("Release notes: %s", release.URL)
+		if *checkOnly {
+			return nil
+		}
+		if !updater.CanUpdate() {
+			log.Info("This build of ooniprobe cannot update itself: please use your " +
+				"package manager or download the new release from the above URL")
+			return nil
+		}
+		log.Infof("Downloading and verifying ooniprobe %s...", release.Version)
+		if err := updater.Update(context.Background(), release); err != nil {
+			log.WithError(err).Error("failed to update ooniprobe")
+			return err
+		}
+		log.Infof("Updated ooniprobe to %s", release.Version)
+		return nil
+	})
+}
@@ -1,17 +1,76 @@
 package upload
 
 import (
+	"context"
+	"time"
+
 	"github.com/alecthomas/kingpin"
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/uploader"
+	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
+// UploadPending uploads the measurements we failed to upload, waiting
+// at least the given interval between two uploads.
+func UploadPending(probe *ooni.Probe, interval time.Duration) (*uploader.Summary, error) {
+	sess, err := probe.NewSession(context.Background(), model.RunTypeManual)
+	if err != nil {
+		log.WithError(err).Error("failed to create a measurement session")
+		return nil, err
+	}
+	defer sess.Close()
+	if err := sess.MaybeLookupBackends(); err != nil {
+		log.WithError(err).Warn("failed to discover OONI backends")
+		return nil, err
+	}
+	submitter, err := sess.NewSubmitter(context.Background())
+	if err != nil {
+		log.WithError(err).Error("failed to create a submitter")
+		return nil, err
+	}
+	summary, err := uploader.New(uploader.Config{
+		DB:           probe.DB(),
+		Submitter:    submitter,
+		Interval:     interval,
+		IsTerminated: probe.IsTerminated,
+	}).Run(context.Background())
+	if err != nil {
+		log.WithError(err).Error("failed to upload the pending measurements")
+		return nil, err
+	}
+	return summary, nil
+}
+
 func init() {
 	cmd := root.Command("upload", "Upload a specific measurement")
+	pending := cmd.Flag("pending", "Upload all the measurements we failed to upload").Bool()
+	interval := cmd.Flag("interval", "Minimum time between two uploads").Default("1s").Duration()
 
 	cmd.Action(func(_ *kingpin.ParseContext) error {
-		log.Info("Uploading")
-		log.Error("this function is not implemented")
+		if !*pending {
+			log.Info("Uploading")
+			log.Error("this function is not implemented")
+			log.Info("use `ooniprobe upload --pending` to upload the measurements we failed to upload")
+			return nil
+		}
+		probe, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		probe.ListenForSignals()
+		summary, err := UploadPending(probe, *interval)
+		if err != nil {
+			return err
+		}
+		output.UploadSummary(output.UploadSummaryData{
+			Uploaded:  summary.Uploaded,
+			Failed:    summary.Failed,
+			Remaining: summary.Remaining,
+		})
 		return nil
 	})
 }
@@ -0,0 +1,32 @@
+package vacuum
+
+import (
+	"github.com/alecthomas/kingpin"
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/root"
+)
+
+func init() {
+	cmd := root.Command("vacuum", "Compress old measurement files and reclaim the unused space in the database")
+	cmd.Action(func(_ *kingpin.ParseContext) error {
+		ctx, err := root.Init()
+		if err != nil {
+			log.WithError(err).Error("failed to initialize root context")
+			return err
+		}
+		// Older releases stored uncompressed measurement files. Failing to
+		// compress them is not fatal, since we can still read them.
+		if n, err := ctx.DB().CompressMeasurementFiles(); err != nil {
+			log.WithError(err).Warn("failed to compress measurement files")
+		} else {
+			log.Infof("Compressed %d measurement files", n)
+		}
+		stats, err := ctx.DB().Vacuum()
+		if err != nil {
+			log.WithError(err).Error("failed to vacuum the database")
+			return err
+		}
+		log.Infof("Reclaimed %d bytes (database size: %d bytes)", stats.Reclaimed(), stats.SizeAfter)
+		return nil
+	})
+}
@@ -0,0 +1,9 @@
+// Package conditions checks the conditions of the device that affect
+// whether and what we should measure, e.g., whether we're on battery.
+package conditions
+
+import "errors"
+
+// ErrUnsupported indicates that we cannot check a
+// condition on this platform.
+var ErrUnsupported = errors.New("conditions: not supported on this platform")
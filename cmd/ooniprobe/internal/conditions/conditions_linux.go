@@ -0,0 +1,70 @@
+package conditions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/execabs"
+)
+
+// Supported indicates whether we know how to check whether
+// we are running on battery or using a metered network.
+const Supported = true
+
+// powerSupplyDir is where Linux describes the power supplies.
+const powerSupplyDir = "/sys/class/power_supply"
+
+// readPowerSupply reads an attribute of a power supply.
+func readPowerSupply(name, attribute string) string {
+	data, err := os.ReadFile(filepath.Join(powerSupplyDir, name, attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// OnBattery returns whether we are running on battery, which is false
+// when there are no batteries, e.g., on desktops and servers.
+func OnBattery() (bool, error) {
+	entries, err := os.ReadDir(powerSupplyDir)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	discharging := false
+	for _, entry := range entries {
+		switch readPowerSupply(entry.Name(), "type") {
+		case "Mains":
+			if readPowerSupply(entry.Name(), "online") == "1" {
+				return false, nil
+			}
+		case "Battery":
+			if readPowerSupply(entry.Name(), "status") == "Discharging" {
+				discharging = true
+			}
+		}
+	}
+	return discharging, nil
+}
+
+// OnMeteredNetwork returns whether NetworkManager considers any of the
+// devices metered, which is false when NetworkManager is not available.
+func OnMeteredNetwork() (bool, error) {
+	if _, err := execabs.LookPath("nmcli"); err != nil {
+		return false, nil
+	}
+	output, err := execabs.Command("nmcli", "-t", "-f", "GENERAL.METERED", "device", "show").Output()
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		// For example, "GENERAL.METERED:yes (guessed)".
+		if strings.HasPrefix(strings.TrimPrefix(line, "GENERAL.METERED:"), "yes") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
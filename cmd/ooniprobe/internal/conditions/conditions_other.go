@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package conditions
+
+// Supported indicates whether we know how to check whether
+// we are running on battery or using a metered network.
+const Supported = false
+
+// OnBattery returns whether we are running on battery. We don't
+// know how to check this on this platform, hence we fail.
+func OnBattery() (bool, error) {
+	return false, ErrUnsupported
+}
+
+// OnMeteredNetwork returns whether we are using a metered network. We
+// don't know how to check this on this platform, hence we fail.
+func OnMeteredNetwork() (bool, error) {
+	return false, ErrUnsupported
+}
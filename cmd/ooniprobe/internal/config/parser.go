@@ -2,7 +2,9 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
@@ -21,7 +23,7 @@ func ReadConfig(path string) (*Config, error) {
 
 	c, err := ParseConfig(b)
 	if err != nil {
-		return nil, errors.Wrap(err, "parsing config")
+		return nil, errors.Wrapf(err, "parsing config %s", path)
 	}
 	c.path = path
 	return c, err
@@ -29,8 +31,15 @@ func ReadConfig(path string) (*Config, error) {
 
 // ParseConfig returns config from JSON bytes.
 func ParseConfig(b []byte) (*Config, error) {
-	var c Config
+	if problems := Check(b).Errors(); len(problems) > 0 {
+		var messages []string
+		for _, problem := range problems {
+			messages = append(messages, problem.String())
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(messages, "; "))
+	}
 
+	var c Config
 	if err := json.Unmarshal(b, &c); err != nil {
 		return nil, errors.Wrap(err, "parsing json")
 	}
@@ -54,6 +63,9 @@ type Config struct {
 	Sharing  Sharing  `json:"sharing"`
 	Nettests Nettests `json:"nettests"`
 	Advanced Advanced `json:"advanced"`
+	Schedule Schedule `json:"schedule"`
+
+	Notifications Notifications `json:"notifications"`
 
 	mutex sync.Mutex
 	path  string
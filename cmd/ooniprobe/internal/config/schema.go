@@ -0,0 +1,420 @@
+package config
+
+//
+// Config schema.
+//
+// We derive the schema of the config file from the Config struct and we
+// walk the JSON tokens of the config file to check it against the schema,
+// such that we can report each problem along with its line and column.
+//
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidConfig indicates that the config file does not match its schema.
+var ErrInvalidConfig = errors.New("config: invalid config")
+
+// These are the severities of the problems of a config file.
+const (
+	// SeverityError indicates that we cannot load the config file.
+	SeverityError = "error"
+
+	// SeverityWarning indicates that we ignore part of the config file.
+	SeverityWarning = "warning"
+)
+
+// Problem is a problem of a config file.
+type Problem struct {
+	// Line and Column are the position of the problem, starting from one.
+	Line, Column int
+
+	// Key is the dotted key of the setting (e.g., "sharing.upload_results").
+	Key string
+
+	// Severity is either SeverityError or SeverityWarning.
+	Severity string
+
+	// Message describes the problem.
+	Message string
+}
+
+// String returns a description of the problem including its position.
+func (p Problem) String() string {
+	if p.Key == "" {
+		return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", p.Line, p.Column, p.Key, p.Message)
+}
+
+// Value is the effective value of a setting.
+type Value struct {
+	// Key is the dotted key of the setting.
+	Key string
+
+	// Value is the JSON encoding of the value.
+	Value string
+
+	// IsDefault indicates that the config file does not contain the
+	// setting, hence we are using its default value.
+	IsDefault bool
+}
+
+// CheckResult is the outcome of checking a config file.
+type CheckResult struct {
+	// Problems contains the problems in the order in which they
+	// appear in the config file.
+	Problems []Problem
+
+	// Values contains the effective values of the settings, sorted
+	// by key, which is empty when the config file has errors.
+	Values []Value
+}
+
+// Errors returns the problems with severity SeverityError.
+func (r *CheckResult) Errors() []Problem {
+	var out []Problem
+	for _, p := range r.Problems {
+		if p.Severity == SeverityError {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// deprecatedKeys maps the deprecated settings to how to replace them.
+var deprecatedKeys = map[string]string{
+	"nettests.websites_url_limit": "use nettests.websites_max_runtime instead",
+}
+
+// categoryCodeRegexp matches the category codes of the test lists.
+var categoryCodeRegexp = regexp.MustCompile(`^[A-Z]+$`)
+
+// validators checks the values of some settings, returning an empty
+// string when the value is valid. The values inside lists and maps use
+// the key of the list or map itself.
+var validators = map[string]func(value interface{}) string{
+	"advanced.database_url": func(value interface{}) string {
+		if value.(string) == "" {
+			return ""
+		}
+		URL, err := url.Parse(value.(string))
+		if err != nil || (URL.Scheme != "postgres" && URL.Scheme != "postgresql") || URL.Host == "" {
+			return "must be a postgres or postgresql URL"
+		}
+		return ""
+	},
+	"nettests.websites_max_runtime":            nonNegative,
+	"nettests.websites_url_limit":              nonNegative,
+	"nettests.max_runtime":                     nonNegative,
+	"nettests.watchdog_timeout":                nonNegative,
+	"nettests.websites_enabled_category_codes": categoryCode,
+	"nettests.experiments.ndt.server": func(value interface{}) string {
+		if strings.Contains(value.(string), "/") {
+			return "must be a hostname, not a URL"
+		}
+		return ""
+	},
+	"nettests.experiments.web_connectivity.category_codes": categoryCode,
+	"notifications.webhook_url": func(value interface{}) string {
+		if value.(string) == "" {
+			return ""
+		}
+		URL, err := url.Parse(value.(string))
+		if err != nil || (URL.Scheme != "http" && URL.Scheme != "https") || URL.Host == "" {
+			return "must be an http or https URL"
+		}
+		return ""
+	},
+	"sharing.scrub_strings": func(value interface{}) string {
+		if value.(string) == "" {
+			return "empty string"
+		}
+		return ""
+	},
+	"schedule.groups": func(value interface{}) string {
+		if strings.TrimSpace(value.(string)) == "" {
+			return "empty schedule"
+		}
+		return ""
+	},
+}
+
+// nonNegative checks that an integer is not negative.
+func nonNegative(value interface{}) string {
+	if value.(int64) < 0 {
+		return "must not be negative"
+	}
+	return ""
+}
+
+// categoryCode checks that a string is a category code.
+func categoryCode(value interface{}) string {
+	if !categoryCodeRegexp.MatchString(value.(string)) {
+		return fmt.Sprintf("invalid category code %q", value)
+	}
+	return ""
+}
+
+// checker checks a config file against the schema.
+type checker struct {
+	data     []byte
+	dec      *json.Decoder
+	problems []Problem
+	keys     map[string]bool
+}
+
+// nextToken returns the offset of the token following the given
+// offset, which json.Decoder.InputOffset returns, skipping separators.
+func (c *checker) nextToken(offset int64) int64 {
+	for offset < int64(len(c.data)) && strings.ContainsRune(" \t\r\n,:", rune(c.data[offset])) {
+		offset++
+	}
+	return offset
+}
+
+// position returns the line and column of the given offset.
+func (c *checker) position(offset int64) (int, int) {
+	before := c.data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	return line, int(offset) - bytes.LastIndexByte(before, '\n')
+}
+
+// add adds a problem at the given offset.
+func (c *checker) add(offset int64, key, severity, format string, v ...interface{}) {
+	line, column := c.position(offset)
+	c.problems = append(c.problems, Problem{
+		Line:     line,
+		Column:   column,
+		Key:      key,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, v...),
+	})
+}
+
+// skip skips the rest of the value starting with the given token.
+func (c *checker) skip(tok json.Token) error {
+	if delim, ok := tok.(json.Delim); !ok || (delim != '{' && delim != '[') {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := c.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// typeName returns the name of the JSON type of the given Go type.
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		return typeName(t.Elem())
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	default:
+		return "an integer"
+	}
+}
+
+// fields returns the fields of a struct indexed by their JSON name.
+func fields(t reflect.Type) map[string]reflect.StructField {
+	out := make(map[string]reflect.StructField)
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "" || name == "-" {
+			continue // unexported or not serialized
+		}
+		out[name] = field
+	}
+	return out
+}
+
+// joinKey returns the key of a setting inside the given parent.
+func joinKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// value checks the next value, whose key is key, against t. The vkey
+// argument is the key we use to find the validator of the value.
+func (c *checker) value(key, vkey string, t reflect.Type) error {
+	offset := c.nextToken(c.dec.InputOffset())
+	tok, err := c.dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil // like json.Unmarshal, we accept null
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem() // optional setting
+	}
+	var value interface{}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		if tok != json.Delim('{') {
+			break
+		}
+		schema := map[string]reflect.StructField{}
+		if t.Kind() == reflect.Struct {
+			schema = fields(t)
+		}
+		for c.dec.More() {
+			keyOffset := c.nextToken(c.dec.InputOffset())
+			tok, err := c.dec.Token()
+			if err != nil {
+				return err
+			}
+			name := tok.(string) // object keys are always strings
+			childKey := joinKey(key, name)
+			childType, childVKey := t, vkey
+			if t.Kind() == reflect.Struct {
+				field, found := schema[name]
+				if !found {
+					c.add(keyOffset, childKey, SeverityWarning, "unknown setting")
+					tok, err := c.dec.Token()
+					if err != nil {
+						return err
+					}
+					if err := c.skip(tok); err != nil {
+						return err
+					}
+					continue
+				}
+				c.keys[childKey] = true
+				if replacement, found := deprecatedKeys[childKey]; found {
+					c.add(keyOffset, childKey, SeverityWarning, "deprecated setting, %s", replacement)
+				}
+				childType, childVKey = field.Type, childKey
+			}
+			if t.Kind() == reflect.Map {
+				childType = t.Elem()
+			}
+			if err := c.value(childKey, childVKey, childType); err != nil {
+				return err
+			}
+		}
+		_, err := c.dec.Token() // consume '}'
+		return err
+	case reflect.Slice:
+		if tok != json.Delim('[') {
+			break
+		}
+		for idx := 0; c.dec.More(); idx++ {
+			if err := c.value(fmt.Sprintf("%s[%d]", key, idx), vkey, t.Elem()); err != nil {
+				return err
+			}
+		}
+		_, err := c.dec.Token() // consume ']'
+		return err
+	case reflect.Bool:
+		if b, ok := tok.(bool); ok {
+			value = b
+		}
+	case reflect.String:
+		if s, ok := tok.(string); ok {
+			value = s
+		}
+	default:
+		if n, ok := tok.(json.Number); ok {
+			if v, err := n.Int64(); err == nil {
+				value = v
+			}
+		}
+	}
+	if value == nil {
+		c.add(offset, key, SeverityError, "expected %s", typeName(t))
+		return c.skip(tok)
+	}
+	if validate, found := validators[vkey]; found {
+		if message := validate(value); message != "" {
+			c.add(offset, key, SeverityError, "%s", message)
+		}
+	}
+	return nil
+}
+
+// Check checks the given config file against the schema.
+func Check(data []byte) *CheckResult {
+	c := &checker{
+		data: data,
+		dec:  json.NewDecoder(bytes.NewReader(data)),
+		keys: make(map[string]bool),
+	}
+	c.dec.UseNumber()
+	err := c.value("", "", reflect.TypeOf(Config{}))
+	if err == nil {
+		if _, err = c.dec.Token(); err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = errors.New("trailing data after the config")
+		}
+	}
+	if err != nil {
+		offset := c.dec.InputOffset()
+		var syntaxError *json.SyntaxError
+		if errors.As(err, &syntaxError) {
+			offset = syntaxError.Offset - 1
+		}
+		c.add(offset, "", SeverityError, "%s", err.Error())
+	}
+	result := &CheckResult{Problems: c.problems}
+	if len(result.Errors()) <= 0 {
+		var config Config
+		if err := json.Unmarshal(data, &config); err == nil {
+			result.Values = values(&config, c.keys)
+		}
+	}
+	return result
+}
+
+// values returns the effective values of the settings of config, where
+// keys contains the keys of the settings in the config file.
+func values(config *Config, keys map[string]bool) []Value {
+	var out []Value
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		for name, field := range fields(v.Type()) {
+			key := joinKey(prefix, name)
+			fv := v.FieldByIndex(field.Index)
+			if field.Type.Kind() == reflect.Struct {
+				walk(key, fv)
+				continue
+			}
+			data, _ := json.Marshal(fv.Interface())
+			out = append(out, Value{Key: key, Value: string(data), IsDefault: !keys[key]})
+		}
+	}
+	walk("", reflect.ValueOf(config).Elem())
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheck(t *testing.T) {
+	t.Run("with a valid config", func(t *testing.T) {
+		data, err := os.ReadFile("testdata/valid-config.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := Check(data)
+		if len(result.Problems) != 0 {
+			t.Fatal("unexpected problems", result.Problems)
+		}
+		var found bool
+		for _, value := range result.Values {
+			switch value.Key {
+			case "sharing.upload_results":
+				found = true
+				if value.Value != "true" || value.IsDefault {
+					t.Fatal("unexpected value", value)
+				}
+			case "advanced.encrypt_database":
+				if value.Value != "false" || !value.IsDefault {
+					t.Fatal("unexpected value", value)
+				}
+			}
+		}
+		if !found {
+			t.Fatal("missing value of sharing.upload_results")
+		}
+	})
+
+	t.Run("with a legacy config", func(t *testing.T) {
+		data, err := os.ReadFile("testdata/config-v0.json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := Check(data)
+		if len(result.Errors()) != 0 {
+			t.Fatal("unexpected errors", result.Errors())
+		}
+		expected := Problem{
+			Line: 4, Column: 3, Key: "_is_beta", Severity: SeverityWarning, Message: "unknown setting",
+		}
+		if diff := cmp.Diff(expected, result.Problems[0]); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with an invalid config", func(t *testing.T) {
+		data := []byte(`{
+  "sharing": {"upload_results": "yes", "include_ip": true, "scrub_strings": [""]},
+  "nettests": {
+    "websites_max_runtime": -1,
+    "websites_url_limit": 10,
+    "websites_enabled_category_codes": ["NEWS", "news"]
+  },
+  "schedule": {"groups": {"websites": ""}, "only_when_charging": [true]},
+  "notifications": {"webhook_url": "ftp://example.com/"},
+  "advanced": {"database_url": "sqlite3:///tmp/main.sqlite3"}
+}`)
+		expected := []string{
+			"2:33: sharing.upload_results: expected a boolean",
+			"2:40: sharing.include_ip: unknown setting",
+			"2:78: sharing.scrub_strings[0]: empty string",
+			"4:29: nettests.websites_max_runtime: must not be negative",
+			"5:5: nettests.websites_url_limit: deprecated setting, use nettests.websites_max_runtime instead",
+			"6:49: nettests.websites_enabled_category_codes[1]: invalid category code \"news\"",
+			"8:39: schedule.groups.websites: empty schedule",
+			"8:66: schedule.only_when_charging: expected a boolean",
+			"9:36: notifications.webhook_url: must be an http or https URL",
+			"10:32: advanced.database_url: must be a postgres or postgresql URL",
+		}
+		result := Check(data)
+		var problems []string
+		for _, problem := range result.Problems {
+			problems = append(problems, problem.String())
+		}
+		if diff := cmp.Diff(expected, problems); diff != "" {
+			t.Fatal(diff)
+		}
+		if len(result.Values) != 0 {
+			t.Fatal("expected no values")
+		}
+		if _, err := ParseConfig(data); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with per-experiment options", func(t *testing.T) {
+		data := []byte(`{
+  "nettests": {
+    "experiments": {
+      "telegram": {"enabled": false},
+      "ndt": {"enabled": "no", "server": "https://ndt.example.com/"},
+      "web_connectivity": {"category_codes": ["NEWS", "news"]},
+      "nonexistent": {}
+    }
+  }
+}`)
+		expected := []string{
+			"5:26: nettests.experiments.ndt.enabled: expected a boolean",
+			"5:42: nettests.experiments.ndt.server: must be a hostname, not a URL",
+			"6:55: nettests.experiments.web_connectivity.category_codes[1]: invalid category code \"news\"",
+			"7:7: nettests.experiments.nonexistent: unknown setting",
+		}
+		var problems []string
+		for _, problem := range Check(data).Problems {
+			problems = append(problems, problem.String())
+		}
+		if diff := cmp.Diff(expected, problems); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a syntax error", func(t *testing.T) {
+		result := Check([]byte("{\n  \"sharing\": {,\n}"))
+		errs := result.Errors()
+		if len(errs) != 1 || errs[0].Line != 2 {
+			t.Fatal("unexpected errors", errs)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestExperimentsIsEnabled(t *testing.T) {
+	config, err := ParseConfig([]byte(`{
+  "nettests": {
+    "experiments": {
+      "telegram": {"enabled": false},
+      "ndt": {"enabled": true, "server": "ndt.example.com"}
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	experiments := &config.Nettests.Experiments
+	for name, expected := range map[string]bool{
+		"telegram":         false,
+		"ndt":              true,
+		"web_connectivity": true,
+		"nonexistent":      true,
+	} {
+		if experiments.IsEnabled(name) != expected {
+			t.Fatalf("unexpected IsEnabled(%q)", name)
+		}
+	}
+	if experiments.NDT.Server != "ndt.example.com" {
+		t.Fatal("unexpected ndt server", experiments.NDT.Server)
+	}
+}
@@ -0,0 +1,186 @@
+// Package crash captures the panics of the goroutines measuring and
+// running nettests and writes crash dumps into the OONI home, such that
+// users can attach them to bug reports (see `ooniprobe status`).
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/platform"
+	"github.com/ooni/probe-cli/v3/internal/scrubber"
+	"github.com/ooni/probe-cli/v3/internal/version"
+)
+
+// dumpTimestamp is a windows friendly timestamp for the dump names.
+const dumpTimestamp = "2006-01-02T150405.999999999Z0700"
+
+// Dump is a crash dump. We redact the IP addresses and the user's
+// home directory from the panic value, the stack, and the events.
+type Dump struct {
+	Time            time.Time `json:"time"`
+	Name            string    `json:"name"`
+	Panic           string    `json:"panic"`
+	Stack           string    `json:"stack"`
+	SoftwareName    string    `json:"software_name"`
+	SoftwareVersion string    `json:"software_version"`
+	EngineVersion   string    `json:"engine_version"`
+	Platform        string    `json:"platform"`
+	GoVersion       string    `json:"go_version"`
+
+	// Events contains the last log events before the panic.
+	Events []string `json:"events"`
+}
+
+// PanicError is the error returned by Reporter.Guard when the
+// function it runs panics.
+type PanicError struct {
+	// Name is the name of what panicked.
+	Name string
+
+	// Value is the redacted value passed to panic.
+	Value string
+
+	// DumpPath is the path of the crash dump, which is empty
+	// when we could not write the crash dump.
+	DumpPath string
+}
+
+// Error implements error.Error.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("crash: panic in %s: %s", e.Name, e.Value)
+}
+
+// Reporter writes crash dumps when the functions it guards panic.
+type Reporter struct {
+	// Dir is the directory where we write the crash dumps.
+	Dir string
+
+	// Events is the OPTIONAL recorder of the last log events.
+	Events *Recorder
+
+	// SoftwareName is the name of the software that crashed.
+	SoftwareName string
+
+	// SoftwareVersion is the version of the software that crashed.
+	SoftwareVersion string
+}
+
+// Guard runs fn and returns its error. When fn panics, Guard writes a
+// crash dump and returns a *PanicError rather than crashing, such that
+// the caller can continue with the next measurement or nettest.
+func (r *Reporter) Guard(name string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = r.capture(name, v, debug.Stack())
+		}
+	}()
+	return fn()
+}
+
+// capture writes the crash dump of a panic and returns the corresponding error.
+func (r *Reporter) capture(name string, v interface{}, stack []byte) *PanicError {
+	dump := r.newDump(name, v, stack)
+	perr := &PanicError{Name: name, Value: dump.Panic}
+	path, err := r.write(dump)
+	if err != nil {
+		log.WithError(err).Warn("crash: cannot write the crash dump")
+		return perr
+	}
+	log.Warnf("crash: %s panicked; written crash dump to %s", name, path)
+	perr.DumpPath = path
+	return perr
+}
+
+// newDump creates a new redacted Dump.
+func (r *Reporter) newDump(name string, v interface{}, stack []byte) *Dump {
+	dump := &Dump{
+		Time:            time.Now().UTC(),
+		Name:            name,
+		Panic:           redact(fmt.Sprintf("%v", v)),
+		Stack:           redact(string(stack)),
+		SoftwareName:    r.SoftwareName,
+		SoftwareVersion: r.SoftwareVersion,
+		EngineVersion:   version.Version,
+		Platform:        platform.Name(),
+		GoVersion:       runtime.Version(),
+	}
+	if r.Events != nil {
+		for _, ev := range r.Events.Events() {
+			dump.Events = append(dump.Events, redact(ev))
+		}
+	}
+	return dump
+}
+
+// write writes the dump into r.Dir and returns its path.
+func (r *Reporter) write(dump *Dump) (string, error) {
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(r.Dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(r.Dir, fmt.Sprintf("crash-%s.json", dump.Time.Format(dumpTimestamp)))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// userHomeDir returns the home directory of the user.
+var userHomeDir = os.UserHomeDir
+
+// redact removes the IP addresses and the user's home directory, which
+// may contain the user name, from the given string.
+func redact(s string) string {
+	s = scrubber.Scrub(s)
+	if home, err := userHomeDir(); err == nil && home != "" {
+		s = strings.ReplaceAll(s, home, "~")
+	}
+	return s
+}
+
+// List returns the paths of the crash dumps inside dir, the most recent
+// first. It returns no paths and no error when dir does not exist.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, "crash-") && strings.HasSuffix(name, ".json") {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	// The timestamp in the name sorts lexicographically.
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths, nil
+}
+
+// Read reads the crash dump at path.
+func Read(path string) (*Dump, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var dump Dump
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, err
+	}
+	return &dump, nil
+}
@@ -0,0 +1,131 @@
+package crash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apex/log"
+)
+
+// nullHandler is a log.Handler discarding all the entries.
+type nullHandler struct{}
+
+func (nullHandler) HandleLog(e *log.Entry) error {
+	return nil
+}
+
+func TestGuard(t *testing.T) {
+	t.Run("without panic", func(t *testing.T) {
+		r := &Reporter{Dir: t.TempDir()}
+		expected := errors.New("mocked error")
+		if err := r.Guard("example", func() error { return expected }); err != expected {
+			t.Fatal("unexpected err", err)
+		}
+		paths, err := List(r.Dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) != 0 {
+			t.Fatal("expected no crash dumps", paths)
+		}
+	})
+
+	t.Run("with panic", func(t *testing.T) {
+		saved := userHomeDir
+		defer func() { userHomeDir = saved }()
+		userHomeDir = func() (string, error) {
+			return "/home/alice", nil
+		}
+		recorder := NewRecorder(nullHandler{}, DefaultEvents)
+		savedLog := log.Log
+		defer func() { log.Log = savedLog }()
+		log.Log = &log.Logger{Handler: recorder, Level: log.DebugLevel}
+		log.Info("connecting to 130.192.91.211")
+		r := &Reporter{
+			Dir:             filepath.Join(t.TempDir(), "crashes"),
+			Events:          recorder,
+			SoftwareName:    "ooniprobe-cli",
+			SoftwareVersion: "3.16.0",
+		}
+		err := r.Guard("example", func() error {
+			panic("cannot open /home/alice/.ooniprobe/config.json")
+		})
+		var perr *PanicError
+		if !errors.As(err, &perr) {
+			t.Fatal("unexpected err", err)
+		}
+		if perr.Value != "cannot open ~/.ooniprobe/config.json" {
+			t.Fatal("unexpected panic value", perr.Value)
+		}
+		paths, err := List(r.Dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) != 1 || paths[0] != perr.DumpPath {
+			t.Fatal("unexpected crash dumps", paths)
+		}
+		dump, err := Read(paths[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dump.Name != "example" || dump.Panic != perr.Value || dump.SoftwareName != "ooniprobe-cli" {
+			t.Fatal("unexpected dump", dump)
+		}
+		if !strings.Contains(dump.Stack, "TestGuard") {
+			t.Fatal("unexpected stack", dump.Stack)
+		}
+		if len(dump.Events) != 1 || strings.Contains(dump.Events[0], "130.192.91.211") {
+			t.Fatal("unexpected events", dump.Events)
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	t.Run("with missing directory", func(t *testing.T) {
+		paths, err := List(filepath.Join(t.TempDir(), "nonexistent"))
+		if err != nil || paths != nil {
+			t.Fatal("unexpected result", paths, err)
+		}
+	})
+
+	t.Run("with dumps", func(t *testing.T) {
+		dir := t.TempDir()
+		names := []string{
+			"crash-2022-10-06T090000Z.json",
+			"crash-2022-10-07T090000Z.json",
+			"README.txt",
+		}
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		paths, err := List(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) != 2 || filepath.Base(paths[0]) != names[1] || filepath.Base(paths[1]) != names[0] {
+			t.Fatal("unexpected paths", paths)
+		}
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(nullHandler{}, 2)
+	for _, message := range []string{"a", "b", "c"} {
+		r.HandleLog(&log.Entry{
+			Level:     log.InfoLevel,
+			Message:   message,
+			Fields:    log.Fields{"type": "test"},
+			Timestamp: time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC),
+		})
+	}
+	events := r.Events()
+	if len(events) != 2 || events[0] != "09:00:00.000 info b type=test" || events[1] != "09:00:00.000 info c type=test" {
+		t.Fatal("unexpected events", events)
+	}
+}
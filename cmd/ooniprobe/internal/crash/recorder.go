@@ -0,0 +1,70 @@
+package crash
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/apex/log"
+)
+
+// DefaultEvents is the default number of events kept by a Recorder.
+const DefaultEvents = 64
+
+// Recorder is a log.Handler that keeps the last log events, which we
+// include into the crash dumps, and forwards them to another handler.
+type Recorder struct {
+	events  []string
+	handler log.Handler
+	mu      sync.Mutex
+	next    int
+	size    int
+}
+
+var _ log.Handler = &Recorder{}
+
+// NewRecorder creates a new Recorder keeping the last size
+// events and forwarding all the events to handler.
+func NewRecorder(handler log.Handler, size int) *Recorder {
+	return &Recorder{handler: handler, size: size}
+}
+
+// HandleLog implements log.Handler.HandleLog.
+func (r *Recorder) HandleLog(e *log.Entry) error {
+	r.add(formatEntry(e))
+	return r.handler.HandleLog(e)
+}
+
+// add adds an event, replacing the oldest one when we are full.
+func (r *Recorder) add(ev string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size <= 0 {
+		return
+	}
+	if len(r.events) < r.size {
+		r.events = append(r.events, ev)
+		return
+	}
+	r.events[r.next] = ev
+	r.next = (r.next + 1) % r.size
+}
+
+// Events returns the recorded events, the oldest first.
+func (r *Recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, 0, len(r.events))
+	out = append(out, r.events[r.next:]...)
+	return append(out, r.events[:r.next]...)
+}
+
+// formatEntry formats a log entry as a single line of text.
+func formatEntry(e *log.Entry) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s", e.Timestamp.UTC().Format("15:04:05.000"), e.Level, e.Message)
+	for _, name := range e.Fields.Names() {
+		fmt.Fprintf(&sb, " %s=%v", name, e.Fields.Get(name))
+	}
+	return sb.String()
+}
@@ -0,0 +1,228 @@
+// Package daemon runs test groups on a schedule (see `ooniprobe daemon`).
+package daemon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/conditions"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+// These constants control the main loop of the daemon.
+const (
+	// pollInterval is how often we check whether we should stop
+	// while waiting for the next run.
+	pollInterval = time.Second
+
+	// postponeDelay is how long we postpone a run that we cannot
+	// perform because of the constraints (e.g., on battery).
+	postponeDelay = 15 * time.Minute
+)
+
+// conditionsSupported allows to mock conditions.Supported in tests.
+var conditionsSupported = conditions.Supported
+
+// Config contains the settings of the daemon.
+type Config struct {
+	// Schedules maps the name of each test group to its
+	// schedule (see ParseSchedule).
+	Schedules map[string]string
+
+	// OnlyWhenCharging indicates whether to postpone runs on battery.
+	OnlyWhenCharging bool
+
+	// SkipMeteredNetworks indicates whether to postpone runs
+	// on metered networks.
+	SkipMeteredNetworks bool
+
+	// LinkType is the OPTIONAL type of the link set by the user,
+	// where we consider database.LinkTypeMobile metered.
+	LinkType string
+
+	// DB is the database where we persist the schedule runs.
+	DB database.Actions
+
+	// RunGroup runs the given test group.
+	RunGroup func(groupName string) error
+
+	// IsTerminated returns whether we should stop.
+	IsTerminated func() bool
+
+	// MetricsAddress is the OPTIONAL address on which we serve
+	// the metrics in the Prometheus format at /metrics.
+	MetricsAddress string
+}
+
+// Daemon runs test groups on a schedule.
+type Daemon struct {
+	config    Config
+	schedules map[string]Schedule
+
+	// metrics is nil unless we serve the metrics.
+	metrics *metrics
+
+	// These fields allow to mock the environment in tests.
+	timeNow          func() time.Time
+	sleep            func(time.Duration)
+	onBattery        func() (bool, error)
+	onMeteredNetwork func() (bool, error)
+}
+
+// New creates a new daemon, failing if any schedule is invalid or if we
+// cannot check the conditions for running the test groups on this platform,
+// where we can still skip metered networks when the user sets the link type.
+func New(config Config) (*Daemon, error) {
+	if len(config.Schedules) <= 0 {
+		return nil, fmt.Errorf("%w: no scheduled test groups", ErrInvalidSchedule)
+	}
+	if !conditionsSupported && config.OnlyWhenCharging {
+		return nil, fmt.Errorf("%w: only_when_charging", conditions.ErrUnsupported)
+	}
+	if !conditionsSupported && config.SkipMeteredNetworks && config.LinkType == "" {
+		return nil, fmt.Errorf("%w: skip_metered_networks without --link-type", conditions.ErrUnsupported)
+	}
+	schedules := make(map[string]Schedule)
+	for groupName, spec := range config.Schedules {
+		schedule, err := ParseSchedule(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", groupName, err)
+		}
+		schedules[groupName] = schedule
+	}
+	return &Daemon{
+		config:           config,
+		schedules:        schedules,
+		timeNow:          time.Now,
+		sleep:            time.Sleep,
+		onBattery:        conditions.OnBattery,
+		onMeteredNetwork: conditions.OnMeteredNetwork,
+	}, nil
+}
+
+// loadRuns returns the runs of the scheduled test groups. We keep the
+// persisted runs unless the user changed their schedule. We run new
+// test groups with an interval right away, and the others when their
+// cron expression first matches.
+func (d *Daemon) loadRuns() ([]*database.ScheduleRun, error) {
+	saved, err := d.config.DB.ListScheduleRuns()
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]database.ScheduleRun)
+	for _, run := range saved {
+		index[run.GroupName] = run
+	}
+	now := d.timeNow()
+	var runs []*database.ScheduleRun
+	for groupName, spec := range d.config.Schedules {
+		run, found := index[groupName]
+		if !found || run.ScheduleSpec != spec {
+			run.GroupName = groupName
+			run.ScheduleSpec = spec
+			run.NextRunTime = now
+			if _, interval := d.schedules[groupName].(intervalSchedule); !interval {
+				run.NextRunTime = d.schedules[groupName].Next(now)
+			}
+			if err := d.config.DB.SaveScheduleRun(&run); err != nil {
+				return nil, err
+			}
+		}
+		runs = append(runs, &run)
+	}
+	return runs, nil
+}
+
+// postponeReason returns why we should postpone a run, if we should.
+func (d *Daemon) postponeReason() string {
+	if d.config.OnlyWhenCharging {
+		battery, err := d.onBattery()
+		if err != nil {
+			log.WithError(err).Warn("daemon: cannot check whether we're on battery")
+		}
+		if battery {
+			return "running on battery"
+		}
+	}
+	if d.config.SkipMeteredNetworks {
+		if d.config.LinkType == database.LinkTypeMobile {
+			return "using a mobile link"
+		}
+		if d.config.LinkType != "" && !conditionsSupported {
+			return ""
+		}
+		metered, err := d.onMeteredNetwork()
+		if err != nil {
+			log.WithError(err).Warn("daemon: cannot check whether the network is metered")
+		}
+		if metered {
+			return "using a metered network"
+		}
+	}
+	return ""
+}
+
+// Run runs the test groups on schedule until IsTerminated returns true.
+func (d *Daemon) Run() error {
+	runs, err := d.loadRuns()
+	if err != nil {
+		return err
+	}
+	if d.config.MetricsAddress != "" {
+		d.metrics = newMetrics()
+		for _, run := range runs {
+			d.metrics.setScheduleRun(*run)
+		}
+		d.updateUploadQueue()
+		server, err := serveMetrics(d.config.MetricsAddress, d.metrics)
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+	}
+	for _, run := range runs {
+		log.Infof("daemon: next %s run at %s", run.GroupName, run.NextRunTime.Local().Format(time.RFC1123))
+	}
+	for !d.config.IsTerminated() {
+		sort.SliceStable(runs, func(i, j int) bool {
+			return runs[i].NextRunTime.Before(runs[j].NextRunTime)
+		})
+		run, now := runs[0], d.timeNow()
+		if wait := run.NextRunTime.Sub(now); wait > 0 {
+			if wait > pollInterval {
+				wait = pollInterval
+			}
+			d.sleep(wait)
+			continue
+		}
+		schedule := d.schedules[run.GroupName]
+		if reason := d.postponeReason(); reason != "" {
+			run.NextRunTime = now.Add(postponeDelay)
+			if next := schedule.Next(now); next.Before(run.NextRunTime) {
+				run.NextRunTime = next
+			}
+			log.Infof("daemon: postponing %s until %s: %s", run.GroupName,
+				run.NextRunTime.Local().Format(time.RFC1123), reason)
+		} else {
+			log.Infof("daemon: running %s", run.GroupName)
+			if err := d.config.RunGroup(run.GroupName); err != nil {
+				log.WithError(err).Warnf("daemon: failed to run %s", run.GroupName)
+			}
+			d.observeRun(run.GroupName, now)
+			now = d.timeNow()
+			run.LastRunTime.Time, run.LastRunTime.Valid = now, true
+			run.NextRunTime = schedule.Next(now)
+			log.Infof("daemon: next %s run at %s", run.GroupName, run.NextRunTime.Local().Format(time.RFC1123))
+		}
+		if err := d.config.DB.SaveScheduleRun(run); err != nil {
+			return err
+		}
+		if d.metrics != nil {
+			d.metrics.setScheduleRun(*run)
+		}
+	}
+	log.Info("daemon: stopped")
+	return nil
+}
@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/conditions"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+)
+
+// fakeEnv is a fake environment for the daemon, where time only
+// passes when the daemon sleeps or runs a test group.
+type fakeEnv struct {
+	now     time.Time
+	ran     []string
+	ranAt   []time.Time
+	maxRuns int
+	battery bool
+}
+
+func (env *fakeEnv) newDaemon(t *testing.T, db *oonitest.FakeDB, config Config) *Daemon {
+	config.DB = db
+	config.RunGroup = func(groupName string) error {
+		env.ran = append(env.ran, groupName)
+		env.ranAt = append(env.ranAt, env.now)
+		env.now = env.now.Add(10 * time.Minute)
+		return errors.New("mocked error") // should not stop the daemon
+	}
+	config.IsTerminated = func() bool {
+		return len(env.ran) >= env.maxRuns
+	}
+	d, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.timeNow = func() time.Time { return env.now }
+	d.sleep = func(delay time.Duration) { env.now = env.now.Add(delay) }
+	d.onBattery = func() (bool, error) { return env.battery, nil }
+	d.onMeteredNetwork = func() (bool, error) { return false, nil }
+	return d
+}
+
+func TestDaemon(t *testing.T) {
+	start := time.Date(2022, 3, 7, 10, 17, 30, 0, time.UTC)
+
+	t.Run("runs the groups on schedule", func(t *testing.T) {
+		db := &oonitest.FakeDB{}
+		env := &fakeEnv{now: start, maxRuns: 4}
+		d := env.newDaemon(t, db, Config{Schedules: map[string]string{
+			"websites": "6h",
+			"im":       "0 12 * * *",
+		}})
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"websites", "im", "websites", "websites"}
+		if len(env.ran) != len(expected) {
+			t.Fatal("unexpected runs", env.ran)
+		}
+		for idx := range expected {
+			if env.ran[idx] != expected[idx] {
+				t.Fatal("unexpected runs", env.ran)
+			}
+		}
+		if !env.ranAt[1].Equal(time.Date(2022, 3, 7, 12, 0, 0, 0, time.UTC)) {
+			t.Fatal("unexpected run time", env.ranAt[1])
+		}
+		if !env.ranAt[2].Equal(start.Add(10*time.Minute + 6*time.Hour)) {
+			t.Fatal("unexpected run time", env.ranAt[2])
+		}
+		run := db.FakeScheduleRuns["im"]
+		if !run.LastRunTime.Valid || !run.NextRunTime.Equal(time.Date(2022, 3, 8, 12, 0, 0, 0, time.UTC)) {
+			t.Fatal("unexpected saved run", run)
+		}
+	})
+
+	t.Run("keeps the persisted runs", func(t *testing.T) {
+		next := start.Add(time.Hour)
+		db := &oonitest.FakeDB{FakeScheduleRuns: map[string]database.ScheduleRun{
+			"websites": {GroupName: "websites", ScheduleSpec: "6h", NextRunTime: next},
+			"im":       {GroupName: "im", ScheduleSpec: "3h", NextRunTime: next},
+		}}
+		env := &fakeEnv{now: start, maxRuns: 1}
+		d := env.newDaemon(t, db, Config{Schedules: map[string]string{
+			"websites": "6h",
+			"im":       "1h", // changed, hence we should run it now
+		}})
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if len(env.ran) != 1 || env.ran[0] != "im" || !env.ranAt[0].Equal(start) {
+			t.Fatal("unexpected runs", env.ran, env.ranAt)
+		}
+		if !db.FakeScheduleRuns["websites"].NextRunTime.Equal(next) {
+			t.Fatal("unexpected saved run", db.FakeScheduleRuns["websites"])
+		}
+	})
+
+	t.Run("postpones the runs on battery", func(t *testing.T) {
+		db := &oonitest.FakeDB{}
+		env := &fakeEnv{now: start, maxRuns: 1, battery: true}
+		d := env.newDaemon(t, db, Config{
+			Schedules:        map[string]string{"websites": "6h"},
+			OnlyWhenCharging: true,
+		})
+		d.sleep = func(delay time.Duration) {
+			env.now = env.now.Add(delay)
+			env.battery = env.now.Before(start.Add(time.Hour))
+		}
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if len(env.ran) != 1 || !env.ranAt[0].Equal(start.Add(4*postponeDelay)) {
+			t.Fatal("unexpected runs", env.ranAt)
+		}
+	})
+
+	t.Run("postpones the runs on mobile links", func(t *testing.T) {
+		db := &oonitest.FakeDB{}
+		env := &fakeEnv{now: start, maxRuns: 1}
+		d := env.newDaemon(t, db, Config{
+			Schedules:           map[string]string{"websites": "6h"},
+			SkipMeteredNetworks: true,
+			LinkType:            database.LinkTypeMobile,
+		})
+		if reason := d.postponeReason(); reason == "" {
+			t.Fatal("expected to postpone")
+		}
+	})
+
+	t.Run("fails with invalid schedules", func(t *testing.T) {
+		for _, schedules := range []map[string]string{nil, {"websites": "1s"}} {
+			if _, err := New(Config{Schedules: schedules}); !errors.Is(err, ErrInvalidSchedule) {
+				t.Fatal("unexpected error", err)
+			}
+		}
+	})
+
+	t.Run("fails with unsupported conditions", func(t *testing.T) {
+		supported := conditionsSupported
+		conditionsSupported = false
+		defer func() { conditionsSupported = supported }()
+		schedules := map[string]string{"websites": "6h"}
+		for _, config := range []Config{{
+			Schedules:        schedules,
+			OnlyWhenCharging: true,
+		}, {
+			Schedules:           schedules,
+			SkipMeteredNetworks: true,
+		}} {
+			if _, err := New(config); !errors.Is(err, conditions.ErrUnsupported) {
+				t.Fatal("unexpected error", err)
+			}
+		}
+		d, err := New(Config{
+			Schedules:           schedules,
+			SkipMeteredNetworks: true,
+			LinkType:            database.LinkTypeWired,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.onMeteredNetwork = func() (bool, error) {
+			t.Fatal("should not check whether the network is metered")
+			return false, nil
+		}
+		if reason := d.postponeReason(); reason != "" {
+			t.Fatal("unexpected reason", reason)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package daemon
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+// metricsTimeout is the timeout for serving the metrics.
+const metricsTimeout = 30 * time.Second
+
+// metricFamily is a metric in the Prometheus text format.
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string // either "counter" or "gauge"
+	samples []metricSample
+}
+
+// metricSample is a sample of a metric.
+type metricSample struct {
+	labels string // e.g., `{test_group="websites"}`
+	value  float64
+}
+
+// metricLabels formats the given label names and values.
+func metricLabels(namesAndValues ...string) string {
+	var pairs []string
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for i := 0; i+1 < len(namesAndValues); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, namesAndValues[i], escaper.Replace(namesAndValues[i+1])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// groupStats contains the stats of the runs of a test group
+// completed since the daemon started.
+type groupStats struct {
+	runs          uint64
+	measurements  uint64
+	anomalies     uint64
+	dataUsageUp   float64 // KiB
+	dataUsageDown float64 // KiB
+}
+
+// metrics contains the metrics of the daemon. We update the counters
+// when a run completes and the gauges when they change, such that
+// serving the metrics does not need to query the database.
+type metrics struct {
+	mu           sync.Mutex
+	groups       map[string]*groupStats
+	scheduleRuns map[string]database.ScheduleRun
+	uploadQueue  uint64
+}
+
+// newMetrics creates new metrics.
+func newMetrics() *metrics {
+	return &metrics{
+		groups:       make(map[string]*groupStats),
+		scheduleRuns: make(map[string]database.ScheduleRun),
+	}
+}
+
+// observeRun updates the counters after a run of groupName, which
+// created the given done results.
+func (m *metrics) observeRun(groupName string, results []database.ResultNetwork) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	group, found := m.groups[groupName]
+	if !found {
+		group = &groupStats{}
+		m.groups[groupName] = group
+	}
+	group.runs++
+	for _, result := range results {
+		group.measurements += result.TotalCount
+		group.anomalies += result.AnomalyCount
+		group.dataUsageUp += result.DataUsageUp
+		group.dataUsageDown += result.DataUsageDown
+	}
+}
+
+// setScheduleRun updates the last and next run times of a test group.
+func (m *metrics) setScheduleRun(run database.ScheduleRun) {
+	m.mu.Lock()
+	m.scheduleRuns[run.GroupName] = run
+	m.mu.Unlock()
+}
+
+// setUploadQueue updates the number of measurements to upload again.
+func (m *metrics) setUploadQueue(pending uint64) {
+	m.mu.Lock()
+	m.uploadQueue = pending
+	m.mu.Unlock()
+}
+
+// observeRun updates the metrics, if we serve them, after a run of
+// groupName started at the given time. We only query the results of
+// this run and the number of measurements to upload again.
+func (d *Daemon) observeRun(groupName string, started time.Time) {
+	if d.metrics == nil {
+		return
+	}
+	results, _, err := d.config.DB.ListResultsMatching(&database.ResultFilter{
+		Since:         started.Truncate(time.Second),
+		TestGroupName: groupName,
+	})
+	if err != nil {
+		log.WithError(err).Warn("daemon: cannot list the results of the run")
+	}
+	d.metrics.observeRun(groupName, results)
+	d.updateUploadQueue()
+}
+
+// updateUploadQueue updates the number of measurements to upload again.
+func (d *Daemon) updateUploadQueue() {
+	pending, err := d.config.DB.CountPendingUploads()
+	if err != nil {
+		log.WithError(err).Warn("daemon: cannot count the pending uploads")
+		return
+	}
+	d.metrics.setUploadQueue(pending)
+}
+
+// families returns the metric families.
+func (m *metrics) families() []metricFamily {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var groupNames []string
+	for groupName := range m.groups {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
+	runs := metricFamily{
+		name: "ooniprobe_runs_total",
+		help: "Number of runs of each test group completed by the daemon.",
+		kind: "counter",
+	}
+	measurements := metricFamily{
+		name: "ooniprobe_measurements_total",
+		help: "Number of measurements of the runs of each test group.",
+		kind: "counter",
+	}
+	anomalies := metricFamily{
+		name: "ooniprobe_anomalies_total",
+		help: "Number of anomalous measurements of the runs of each test group.",
+		kind: "counter",
+	}
+	dataUsage := metricFamily{
+		name: "ooniprobe_data_usage_bytes_total",
+		help: "Bytes sent and received by the runs of each test group.",
+		kind: "counter",
+	}
+	for _, groupName := range groupNames {
+		group := m.groups[groupName]
+		labels := metricLabels("test_group", groupName)
+		runs.samples = append(runs.samples, metricSample{labels, float64(group.runs)})
+		measurements.samples = append(measurements.samples, metricSample{labels, float64(group.measurements)})
+		anomalies.samples = append(anomalies.samples, metricSample{labels, float64(group.anomalies)})
+		dataUsage.samples = append(dataUsage.samples,
+			metricSample{metricLabels("test_group", groupName, "direction", "up"), group.dataUsageUp * 1024},
+			metricSample{metricLabels("test_group", groupName, "direction", "down"), group.dataUsageDown * 1024})
+	}
+	uploadQueue := metricFamily{
+		name:    "ooniprobe_upload_queue_depth",
+		help:    "Number of measurements we failed to upload and will upload again.",
+		kind:    "gauge",
+		samples: []metricSample{{"", float64(m.uploadQueue)}},
+	}
+	var scheduleRuns []database.ScheduleRun
+	for _, run := range m.scheduleRuns {
+		scheduleRuns = append(scheduleRuns, run)
+	}
+	sort.Slice(scheduleRuns, func(i, j int) bool {
+		return scheduleRuns[i].GroupName < scheduleRuns[j].GroupName
+	})
+	lastRun := metricFamily{
+		name: "ooniprobe_last_run_timestamp_seconds",
+		help: "When the daemon last ran each scheduled test group.",
+		kind: "gauge",
+	}
+	nextRun := metricFamily{
+		name: "ooniprobe_next_run_timestamp_seconds",
+		help: "When the daemon will next run each scheduled test group.",
+		kind: "gauge",
+	}
+	for _, run := range scheduleRuns {
+		labels := metricLabels("test_group", run.GroupName)
+		if run.LastRunTime.Valid {
+			lastRun.samples = append(lastRun.samples, metricSample{labels, float64(run.LastRunTime.Time.Unix())})
+		}
+		nextRun.samples = append(nextRun.samples, metricSample{labels, float64(run.NextRunTime.Unix())})
+	}
+	return []metricFamily{runs, measurements, anomalies, dataUsage, uploadQueue, lastRun, nextRun}
+}
+
+// writeMetrics writes the metrics in the Prometheus text format.
+func writeMetrics(w io.Writer, metrics []metricFamily) error {
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", metric.name, metric.kind)
+		for _, sample := range metric.samples {
+			if _, err := fmt.Fprintf(w, "%s%s %s\n", metric.name, sample.labels,
+				strconv.FormatFloat(sample.value, 'f', -1, 64)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body bytes.Buffer
+	writeMetrics(&body, m.families())
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(body.Bytes())
+}
+
+// serveMetrics serves the given metrics at /metrics on the given
+// address until we close the returned server.
+func serveMetrics(address string, m *metrics) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	server := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  metricsTimeout,
+		WriteTimeout: metricsTimeout,
+	}
+	go server.Serve(listener)
+	log.Infof("daemon: serving the metrics at http://%s/metrics", listener.Addr())
+	return server, nil
+}
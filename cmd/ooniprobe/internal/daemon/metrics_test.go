@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"database/sql"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+)
+
+func newResultNetwork(groupName string, total, anomalies uint64, up, down float64) database.ResultNetwork {
+	var result database.ResultNetwork
+	result.TestGroupName = groupName
+	result.TotalCount = total
+	result.AnomalyCount = anomalies
+	result.DataUsageUp = up
+	result.DataUsageDown = down
+	return result
+}
+
+func TestMetrics(t *testing.T) {
+	last := time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC)
+	db := &oonitest.FakeDB{
+		FakeResults: []database.ResultNetwork{
+			newResultNetwork("websites", 10, 2, 1, 4),
+			newResultNetwork("im", 4, 0, 0.5, 0.5),
+		},
+		FakePendingUploads: 3,
+	}
+	d := &Daemon{config: Config{DB: db}, metrics: newMetrics()}
+	d.observeRun("websites", last.Add(-time.Hour+500*time.Millisecond))
+	db.FakeResults[0] = newResultNetwork("websites", 5, 1, 1, 2)
+	d.observeRun("websites", last)
+	d.observeRun("im", last)
+	if len(db.FakeFilters) != 3 || !db.FakeFilters[0].Since.Equal(last.Add(-time.Hour)) {
+		t.Fatal("unexpected filters", db.FakeFilters)
+	}
+	d.metrics.setScheduleRun(database.ScheduleRun{
+		GroupName:   "websites",
+		LastRunTime: sql.NullTime{Time: last, Valid: true},
+		NextRunTime: last.Add(time.Hour),
+	})
+	d.metrics.setScheduleRun(database.ScheduleRun{
+		GroupName:   "im",
+		NextRunTime: last.Add(2 * time.Hour),
+	})
+	w := httptest.NewRecorder()
+	d.metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 200 {
+		t.Fatal("unexpected status code", w.Code)
+	}
+	data, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(data)
+	expectations := []string{
+		"# TYPE ooniprobe_runs_total counter\n",
+		`ooniprobe_runs_total{test_group="im"} 1` + "\n",
+		`ooniprobe_runs_total{test_group="websites"} 2` + "\n",
+		`ooniprobe_measurements_total{test_group="websites"} 15` + "\n",
+		`ooniprobe_anomalies_total{test_group="websites"} 3` + "\n",
+		`ooniprobe_data_usage_bytes_total{test_group="websites",direction="down"} 6144` + "\n",
+		`ooniprobe_data_usage_bytes_total{test_group="im",direction="up"} 512` + "\n",
+		"ooniprobe_upload_queue_depth 3\n",
+		`ooniprobe_last_run_timestamp_seconds{test_group="websites"} 1665046800` + "\n",
+		`ooniprobe_next_run_timestamp_seconds{test_group="im"} 1665054000` + "\n",
+	}
+	for _, expected := range expectations {
+		if !strings.Contains(body, expected) {
+			t.Fatal("missing", expected, "in", body)
+		}
+	}
+	if strings.Contains(body, `ooniprobe_last_run_timestamp_seconds{test_group="im"}`) {
+		t.Fatal("unexpected last run of a group that never ran")
+	}
+}
+
+func TestObserveRunWithoutMetrics(t *testing.T) {
+	d := &Daemon{} // must not use the database
+	d.observeRun("websites", time.Now())
+}
+
+func TestMetricLabels(t *testing.T) {
+	labels := metricLabels("test_group", `a"b\c`, "direction", "up")
+	if labels != `{test_group="a\"b\\c",direction="up"}` {
+		t.Fatal("unexpected labels", labels)
+	}
+}
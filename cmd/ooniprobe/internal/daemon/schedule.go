@@ -0,0 +1,199 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSchedule indicates that we cannot parse a schedule.
+var ErrInvalidSchedule = errors.New("daemon: invalid schedule")
+
+// minInterval is the minimum interval between runs, which prevents
+// schedules such as "1s" from hammering the backends.
+const minInterval = 15 * time.Minute
+
+// Schedule computes when to run a test group.
+type Schedule interface {
+	// Next returns the first time strictly after the given time
+	// at which we should run the test group.
+	Next(after time.Time) time.Time
+}
+
+// ParseSchedule parses a schedule, which is either an interval using the
+// syntax of time.ParseDuration (e.g., "6h") or a cron expression with
+// five fields (minute, hour, day of month, month, day of week), where each
+// field is "*" or a comma separated list of values (e.g., "5") or ranges
+// (e.g., "1-5"), optionally followed by a step (e.g., "*/2"). We evaluate
+// cron expressions in the local time zone and, like cron, we run when
+// either the day of month or the day of week matches, if both are not "*".
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if interval, err := time.ParseDuration(spec); err == nil {
+		if interval < minInterval {
+			return nil, fmt.Errorf("%w: %s is shorter than %s", ErrInvalidSchedule, spec, minInterval)
+		}
+		return intervalSchedule(interval), nil
+	}
+	schedule, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w: %q never matches", ErrInvalidSchedule, spec)
+	}
+	return schedule, nil
+}
+
+// intervalSchedule runs at fixed intervals.
+type intervalSchedule time.Duration
+
+// Next implements Schedule.Next.
+func (s intervalSchedule) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(s))
+}
+
+// cronSchedule runs when the time matches a cron expression. Each
+// field contains the set of values for which it matches.
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+
+	// anyDay and anyWeekday indicate whether the days and weekdays
+	// fields are "*", which affects how we match days.
+	anyDay, anyWeekday bool
+}
+
+// cronFields describes the fields of a cron expression.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // both 0 and 7 are Sunday
+}
+
+// parseCron parses a cron expression with five fields.
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%w: %q is neither an interval nor a cron expression", ErrInvalidSchedule, spec)
+	}
+	var sets []map[int]bool
+	for idx, field := range fields {
+		set, err := parseCronField(field, cronFields[idx].min, cronFields[idx].max)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s field of %q: %s", ErrInvalidSchedule, cronFields[idx].name, spec, err)
+		}
+		sets = append(sets, set)
+	}
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+	return &cronSchedule{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   sets[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField parses a field of a cron expression whose values
+// are between min and max, inclusive.
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, item := range strings.Split(field, ",") {
+		rangeSpec, step := item, 1
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[idx+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", item)
+			}
+			rangeSpec = item[:idx]
+		}
+		first, last := min, max
+		switch idx := strings.Index(rangeSpec, "-"); {
+		case rangeSpec == "*":
+		case idx >= 0:
+			var err1, err2 error
+			first, err1 = strconv.Atoi(rangeSpec[:idx])
+			last, err2 = strconv.Atoi(rangeSpec[idx+1:])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid range %q", rangeSpec)
+			}
+		default:
+			value, err := strconv.Atoi(rangeSpec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", rangeSpec)
+			}
+			first, last = value, value
+			if step > 1 {
+				// Like cron, "5/10" means "5-max/10".
+				last = max
+			}
+		}
+		if first < min || last > max || first > last {
+			return nil, fmt.Errorf("%q is out of the %d-%d range", item, min, max)
+		}
+		for value := first; value <= last; value += step {
+			set[value] = true
+		}
+	}
+	return set, nil
+}
+
+// matchesDay returns whether the given day matches the schedule.
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	dayMatches := s.days[t.Day()]
+	weekdayMatches := s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekdayMatches
+	case s.anyWeekday:
+		return dayMatches
+	default:
+		return dayMatches || weekdayMatches
+	}
+}
+
+// cronSearchLimit bounds the search of the next time matching a cron
+// expression, such that expressions that never match (e.g., February
+// 30th) do not cause an infinite loop.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// Next implements Schedule.Next. We skip non matching months, days, and
+// hours at once, such that we only check a few times. When the cron
+// expression never matches, we return the zero time.
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(cronSearchLimit)
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
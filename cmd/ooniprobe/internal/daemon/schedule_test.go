@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// Monday, March 7th, 2022 at 10:17:30 UTC.
+	now := time.Date(2022, 3, 7, 10, 17, 30, 0, time.UTC)
+	expectations := []struct {
+		spec string
+		next time.Time
+	}{{
+		spec: "6h",
+		next: now.Add(6 * time.Hour),
+	}, {
+		spec: "* * * * *",
+		next: time.Date(2022, 3, 7, 10, 18, 0, 0, time.UTC),
+	}, {
+		spec: "30 */6 * * *",
+		next: time.Date(2022, 3, 7, 12, 30, 0, 0, time.UTC),
+	}, {
+		spec: "0,15 10 * * *",
+		next: time.Date(2022, 3, 8, 10, 0, 0, 0, time.UTC),
+	}, {
+		spec: "0 9-17/4 * * 1-5",
+		next: time.Date(2022, 3, 7, 13, 0, 0, 0, time.UTC),
+	}, {
+		spec: "0 3 * * 0",
+		next: time.Date(2022, 3, 13, 3, 0, 0, 0, time.UTC),
+	}, {
+		spec: "0 3 * * 7",
+		next: time.Date(2022, 3, 13, 3, 0, 0, 0, time.UTC),
+	}, {
+		spec: "0 0 1 * *",
+		next: time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC),
+	}, {
+		// Like cron, either the day of month or the day of week.
+		spec: "0 0 15 * 3",
+		next: time.Date(2022, 3, 9, 0, 0, 0, 0, time.UTC),
+	}, {
+		spec: "0 0 29 2 *",
+		next: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+	}}
+	for _, e := range expectations {
+		t.Run(e.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(e.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := schedule.Next(now); !next.Equal(e.next) {
+				t.Fatal("unexpected next run", next)
+			}
+		})
+	}
+
+	for _, spec := range []string{
+		"", "1m", "-6h", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *",
+		"0 0 30 2 *",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseSchedule(spec); !errors.Is(err, ErrInvalidSchedule) {
+				t.Fatal("unexpected error", err)
+			}
+		})
+	}
+}
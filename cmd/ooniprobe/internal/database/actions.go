@@ -8,12 +8,12 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
-	"reflect"
 	"time"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/enginex"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils/encryption"
 	"github.com/pkg/errors"
 	"github.com/upper/db/v4"
 )
@@ -36,11 +36,18 @@ func ListMeasurements(sess db.Session, resultID int64) ([]MeasurementURLNetwork,
 		log.Errorf("failed to run query %s: %v", req.String(), err)
 		return measurements, err
 	}
+	if err := loadMeasurementAnnotations(sess, measurements); err != nil {
+		return measurements, err
+	}
+	if err := loadRerunOriginals(sess, measurements); err != nil {
+		return measurements, err
+	}
 	return measurements, nil
 }
 
-// GetMeasurementJSON returns a map[string]interface{} given a database and a measurementID
-func GetMeasurementJSON(sess db.Session, measurementID int64) (map[string]interface{}, error) {
+// GetMeasurementJSON returns a map[string]interface{} given a database and a measurementID.
+// We use keyring to read encrypted measurement files (see ReadMeasurementFile).
+func GetMeasurementJSON(sess db.Session, measurementID int64, keyring *encryption.Keyring) (map[string]interface{}, error) {
 	var (
 		measurement MeasurementURLNetwork
 		msmtJSON    map[string]interface{}
@@ -90,7 +97,7 @@ func GetMeasurementJSON(sess db.Session, measurementID int64) (map[string]interf
 		return nil, errors.New("cannot access measurement file")
 	}
 	measurementFilePath := measurement.Measurement.MeasurementFilePath.String
-	b, err := os.ReadFile(measurementFilePath)
+	b, err := ReadMeasurementFile(measurementFilePath, keyring)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +111,63 @@ func GetMeasurementJSON(sess db.Session, measurementID int64) (map[string]interf
 
 // ListResults return the list of results
 func ListResults(sess db.Session) ([]ResultNetwork, []ResultNetwork, error) {
+	return ListResultsMatching(sess, &ResultFilter{})
+}
+
+// ResultFilter selects results. The zero value selects all the results.
+type ResultFilter struct {
+	// Since OPTIONALLY selects the results started
+	// at or after the given time.
+	Since time.Time
+
+	// Until OPTIONALLY selects the results started
+	// before the given time.
+	Until time.Time
+
+	// TestGroupName is the OPTIONAL name of the test group.
+	TestGroupName string
+
+	// AnomalyOnly OPTIONALLY selects the results having
+	// at least an anomalous measurement.
+	AnomalyOnly bool
+
+	// ASN is the OPTIONAL ASN of the network.
+	ASN uint
+
+	// CountryCode is the OPTIONAL country code of the network.
+	CountryCode string
+}
+
+// IsZero returns whether the filter selects all the results.
+func (f *ResultFilter) IsZero() bool {
+	return len(f.cond()) <= 0 && !f.AnomalyOnly
+}
+
+// cond returns the conditions selecting the results, except
+// for AnomalyOnly, which requires a subquery.
+func (f *ResultFilter) cond() db.Cond {
+	cond := db.Cond{}
+	if !f.Since.IsZero() {
+		cond["results.result_start_time >="] = f.Since.UTC()
+	}
+	if !f.Until.IsZero() {
+		cond["results.result_start_time <"] = f.Until.UTC()
+	}
+	if f.TestGroupName != "" {
+		cond["results.test_group_name"] = f.TestGroupName
+	}
+	if f.ASN > 0 {
+		cond["networks.asn"] = f.ASN
+	}
+	if f.CountryCode != "" {
+		cond["networks.network_country_code"] = f.CountryCode
+	}
+	return cond
+}
+
+// ListResultsMatching is like ListResults but only returns
+// the results selected by the filter.
+func ListResultsMatching(sess db.Session, filter *ResultFilter) ([]ResultNetwork, []ResultNetwork, error) {
 	doneResults := []ResultNetwork{}
 	incompleteResults := []ResultNetwork{}
 	req := sess.SQL().Select(
@@ -124,17 +188,28 @@ func ListResults(sess db.Session) ([]ResultNetwork, []ResultNetwork, error) {
 		db.Raw("results.result_data_usage_up"),
 		db.Raw("results.result_data_usage_down"),
 		db.Raw("results.measurement_dir"),
+		db.Raw("results.result_link_type"),
+		db.Raw("results.result_downlink_kbps"),
+		db.Raw("results.result_software_name"),
+		db.Raw("results.result_software_version"),
+		db.Raw("results.result_engine_version"),
+		db.Raw("results.result_platform"),
+		db.Raw("results.result_is_failed"),
+		db.Raw("results.result_failure_msg"),
+		db.Raw("results.result_is_partial"),
 
 		db.Raw("COUNT(CASE WHEN measurements.is_anomaly = TRUE THEN 1 END) as anomaly_count"),
-		db.Raw("COUNT() as total_count"),
-		// The test_keys column are concanetated with the "|" character as a separator.
-		// We consider this to be safe since we only really care about values of the
-		// performance test_keys where the values are all numbers and none of the keys
-		// contain the "|" character.
-		db.Raw("group_concat(test_keys, '|') as test_keys"),
+		db.Raw("COUNT(*) as total_count"),
+		// Each measurement has at most one performance summary, hence
+		// MAX returns the value of the ndt or dash measurement.
+		db.Raw("MAX(performance_summaries.upload) as upload"),
+		db.Raw("MAX(performance_summaries.download) as download"),
+		db.Raw("MAX(performance_summaries.ping) as ping"),
+		db.Raw("MAX(performance_summaries.median_bitrate) as median_bitrate"),
 	).From("results").
 		Join("networks").On("results.network_id = networks.network_id").
 		Join("measurements").On("measurements.result_id = results.result_id").
+		LeftJoin("performance_summaries").On("performance_summaries.measurement_id = measurements.measurement_id").
 		OrderBy("results.result_start_time").
 		GroupBy(
 			db.Raw("networks.network_name"),
@@ -154,44 +229,117 @@ func ListResults(sess db.Session) ([]ResultNetwork, []ResultNetwork, error) {
 			db.Raw("results.result_data_usage_up"),
 			db.Raw("results.result_data_usage_down"),
 			db.Raw("results.measurement_dir"),
+			db.Raw("results.result_link_type"),
+			db.Raw("results.result_downlink_kbps"),
+			db.Raw("results.result_software_name"),
+			db.Raw("results.result_software_version"),
+			db.Raw("results.result_engine_version"),
+			db.Raw("results.result_platform"),
+			db.Raw("results.result_is_failed"),
+			db.Raw("results.result_failure_msg"),
+			db.Raw("results.result_is_partial"),
 		)
-	if err := req.Where("result_is_done = true").All(&doneResults); err != nil {
+	if cond := filter.cond(); len(cond) > 0 {
+		req = req.Where(cond)
+	}
+	if filter.AnomalyOnly {
+		req = req.And(`results.result_id IN (SELECT result_id
+			FROM measurements WHERE is_anomaly = TRUE)`)
+	}
+	if err := req.And("result_is_done = true").All(&doneResults); err != nil {
 		return doneResults, incompleteResults, errors.Wrap(err, "failed to get result done list")
 	}
-	if err := req.Where("result_is_done = false").All(&incompleteResults); err != nil {
+	if err := req.And("result_is_done = false").All(&incompleteResults); err != nil {
 		return doneResults, incompleteResults, errors.Wrap(err, "failed to get result done list")
 	}
 	return doneResults, incompleteResults, nil
 }
 
-// DeleteResult will delete a particular result and the relative measurement on
-// disk.
+// DeleteResult will delete a particular result together with its
+// measurements, their report files, and the measurement dir on disk.
 func DeleteResult(sess db.Session, resultID int64) error {
-	var result Result
-	res := sess.Collection("results").Find("result_id", resultID)
-	if err := res.One(&result); err != nil {
-		if err == db.ErrNoMoreRows {
-			return err
+	return DeleteResults(sess, []int64{resultID})
+}
+
+// DeleteResults deletes the given results together with their measurements
+// within a single transaction, such that we either delete all the results
+// or none of them, and then removes their report files. This function fails
+// with db.ErrNoMoreRows when one of the results does not exist.
+func DeleteResults(sess db.Session, resultIDs []int64) error {
+	var (
+		results      []Result
+		measurements []Measurement
+	)
+	err := sess.Tx(func(tx db.Session) error {
+		for _, resultID := range resultIDs {
+			var result Result
+			res := tx.Collection("results").Find("result_id", resultID)
+			if err := res.One(&result); err != nil {
+				return err
+			}
+			var msmts []Measurement
+			all := tx.Collection("measurements").Find("result_id", resultID)
+			if err := all.All(&msmts); err != nil {
+				return errors.Wrap(err, "listing the result measurements")
+			}
+			// The re-runs of these measurements may belong to other results.
+			_, err := tx.SQL().Update("measurements").
+				Set("rerun_of_measurement_id", nil).
+				Where("rerun_of_measurement_id IN (SELECT measurement_id FROM measurements WHERE result_id = ?)", resultID).
+				Exec()
+			if err != nil {
+				return errors.Wrap(err, "unlinking the re-runs of the result measurements")
+			}
+			// We don't rely on ON DELETE CASCADE because databases created
+			// before we enabled foreign keys may not enforce it.
+			if err := all.Delete(); err != nil {
+				return errors.Wrap(err, "deleting the result measurements")
+			}
+			if err := res.Delete(); err != nil {
+				return err
+			}
+			results = append(results, result)
+			measurements = append(measurements, msmts...)
+		}
+		return nil
+	})
+	if err != nil {
+		if err != db.ErrNoMoreRows {
+			log.WithError(err).Error("failed to delete the results")
 		}
-		log.WithError(err).Error("error in obtaining the result")
 		return err
 	}
-	if err := res.Delete(); err != nil {
-		log.WithError(err).Error("failed to delete the result directory")
-		return err
+	for _, msmt := range measurements {
+		removeMeasurementFiles(&msmt)
+	}
+	for _, result := range results {
+		if err := os.RemoveAll(result.MeasurementDir); err != nil {
+			log.WithError(err).Warnf("failed to remove %s", result.MeasurementDir)
+		}
 	}
-
-	os.RemoveAll(result.MeasurementDir)
 	return nil
 }
 
+// removeMeasurementFiles removes the report files of a measurement, which
+// usually live inside the measurement dir, but may also live elsewhere.
+func removeMeasurementFiles(msmt *Measurement) {
+	for _, path := range []sql.NullString{msmt.MeasurementFilePath, msmt.ReportFilePath} {
+		if !path.Valid || path.String == "" {
+			continue
+		}
+		if err := os.Remove(path.String); err != nil && !os.IsNotExist(err) {
+			log.WithError(err).Warnf("failed to remove %s", path.String)
+		}
+	}
+}
+
 // UpdateUploadedStatus will check if all the measurements inside of a given result set have been uploaded and if so will set the is_uploaded flag to true
 func UpdateUploadedStatus(sess db.Session, result *Result) error {
 	err := sess.Tx(func(tx db.Session) error {
 		uploadedTotal := UploadedTotalCount{}
 		req := tx.SQL().Select(
-			db.Raw("SUM(measurements.measurement_is_uploaded)"),
-			db.Raw("COUNT(*)"),
+			db.Raw("COUNT(CASE WHEN measurements.measurement_is_uploaded = TRUE THEN 1 END) AS uploaded_count"),
+			db.Raw("COUNT(*) AS total_count"),
 		).From("results").
 			Join("measurements").On("measurements.result_id = results.result_id").
 			Where("results.result_id = ?", result.ID)
@@ -227,7 +375,7 @@ func CreateMeasurement(sess db.Session, reportID sql.NullString, testName string
 	// TODO we should look into generating this file path in a more robust way.
 	// If there are two identical test_names in the same test group there is
 	// going to be a clash of test_name
-	msmtFilePath := filepath.Join(measurementDir, fmt.Sprintf("msmt-%s-%d.json", testName, idx))
+	msmtFilePath := filepath.Join(measurementDir, fmt.Sprintf("msmt-%s-%d.json.gz", testName, idx))
 	msmt := Measurement{
 		ReportID:            reportID,
 		TestName:            testName,
@@ -269,6 +417,8 @@ func CreateResult(sess db.Session, homePath string, testGroupName string, networ
 
 	newID, err := sess.Collection("results").Insert(result)
 	if err != nil {
+		// Do not leave behind a directory no result refers to.
+		os.Remove(p)
 		return nil, errors.Wrap(err, "creating result")
 	}
 	result.ID = newID.ID().(int64)
@@ -296,9 +446,10 @@ func CreateNetwork(sess db.Session, loc enginex.LocationProvider) (*Network, err
 
 // CreateOrUpdateURL will create a new URL entry to the urls table if it doesn't
 // exists, otherwise it will update the category code of the one already in
-// there.
+// there. We store the URL in the form returned by NormalizeURL.
 func CreateOrUpdateURL(sess db.Session, urlStr string, categoryCode string, countryCode string) (int64, error) {
 	var url URL
+	urlStr = NormalizeURL(urlStr)
 
 	err := sess.Tx(func(tx db.Session) error {
 		res := tx.Collection("urls").Find(
@@ -336,32 +487,85 @@ func CreateOrUpdateURL(sess db.Session, urlStr string, categoryCode string, coun
 	return url.ID.Int64, nil
 }
 
-// AddTestKeys writes the summary to the measurement
+// AddTestKeys validates and writes the summary to the measurement. In case
+// the summary does not match the measurement's experiment, the error type is
+// such that errors.Is(err, ErrInvalidTestKeys).
 func AddTestKeys(sess db.Session, msmt *Measurement, tk interface{}) error {
-	var (
-		isAnomaly      bool
-		isAnomalyValid bool
-	)
-	tkBytes, err := json.Marshal(tk)
-	if err != nil {
+	if err := msmt.SetTestKeys(tk); err != nil {
 		log.WithError(err).Error("failed to serialize summary")
+		return err
 	}
-
-	// This is necessary so that we can extract from the the opaque testKeys just
-	// the IsAnomaly field of bool type.
-	// Maybe generics are not so bad after-all, heh golang?
-	isAnomalyValue := reflect.ValueOf(tk).FieldByName("IsAnomaly")
-	if isAnomalyValue.IsValid() == true && isAnomalyValue.Kind() == reflect.Bool {
-		isAnomaly = isAnomalyValue.Bool()
-		isAnomalyValid = true
+	err := sess.Tx(func(tx db.Session) error {
+		return writeTestKeys(tx, msmt)
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to update measurement")
+		return err
 	}
-	msmt.TestKeys = string(tkBytes)
-	msmt.IsAnomaly = sql.NullBool{Bool: isAnomaly, Valid: isAnomalyValid}
+	return nil
+}
 
-	err = sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt)
+// writeTestKeys writes the measurement, whose test keys are already
+// set, along with its summaries, using the given transaction.
+func writeTestKeys(tx db.Session, msmt *Measurement) error {
+	err := tx.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt)
 	if err != nil {
-		log.WithError(err).Error("failed to update measurement")
 		return errors.Wrap(err, "updating measurement")
 	}
+	if err := updateMeasurementSummary(tx, msmt); err != nil {
+		return err
+	}
+	return updateGroupSummary(tx, msmt)
+}
+
+// CompleteMeasurement marks the measurement as done and writes its summary
+// and its annotations in a single transaction, such that a crash cannot
+// leave a done measurement without them. When data is not nil, we first
+// write it into the measurement file, and we remove the file if we cannot
+// update the database. We skip the summary when tk is nil or does not match
+// the measurement's experiment, because we have data but no summary. We
+// write the measurement file using keyring (see WriteMeasurementFile).
+func CompleteMeasurement(sess db.Session, msmt *Measurement, data []byte, tk interface{},
+	annotations map[string]string, keyring *encryption.Keyring) error {
+	if err := validateAnnotations(annotations); err != nil {
+		return err
+	}
+	if data != nil {
+		if err := WriteMeasurementFile(msmt.MeasurementFilePath.String, data, keyring); err != nil {
+			return errors.Wrap(err, "writing measurement file")
+		}
+	}
+	// We modify a copy, such that msmt is unchanged when we fail.
+	completed := *msmt
+	completed.Runtime = time.Now().UTC().Sub(completed.StartTime).Seconds()
+	completed.IsDone = true
+	hasTestKeys := tk != nil
+	if hasTestKeys {
+		err := completed.SetTestKeys(tk)
+		if err != nil && !errors.Is(err, ErrInvalidTestKeys) {
+			return err
+		}
+		hasTestKeys = err == nil
+	}
+	err := sess.Tx(func(tx db.Session) error {
+		if hasTestKeys {
+			if err := writeTestKeys(tx, &completed); err != nil {
+				return err
+			}
+		} else {
+			err := tx.Collection("measurements").Find("measurement_id", completed.ID).Update(&completed)
+			if err != nil {
+				return errors.Wrap(err, "updating measurement")
+			}
+		}
+		return setMeasurementAnnotations(tx, completed.ID, annotations)
+	})
+	if err != nil {
+		if data != nil {
+			os.Remove(msmt.MeasurementFilePath.String)
+		}
+		return err
+	}
+	*msmt = completed
 	return nil
 }
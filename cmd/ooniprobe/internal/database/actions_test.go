@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/telegram"
 	"github.com/upper/db/v4"
 )
 
@@ -229,6 +233,95 @@ func TestDeleteResult(t *testing.T) {
 	}
 }
 
+func TestDeleteResults(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resultIDs []int64
+	for idx := 0; idx < 3; idx++ {
+		result, err := CreateResult(sess, tmpdir, "im", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = CreateMeasurement(sess, sql.NullString{}, "telegram",
+			result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resultIDs = append(resultIDs, result.ID)
+	}
+	count := func() uint64 {
+		count, err := sess.Collection("results").Find().Count()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	if err := DeleteResults(sess, []int64{resultIDs[0], 1234}); err != db.ErrNoMoreRows {
+		t.Fatal("unexpected error", err)
+	}
+	if count() != 3 {
+		t.Fatal("we should not delete any result")
+	}
+
+	if err := DeleteResults(sess, resultIDs[:2]); err != nil {
+		t.Fatal(err)
+	}
+	if count() != 1 {
+		t.Fatal("we should only keep the last result")
+	}
+	measurements, err := sess.Collection("measurements").Find().Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if measurements != 1 {
+		t.Fatal("we should only keep the measurements of the last result")
+	}
+}
+
+func TestPartialResult(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CreateMeasurement(sess, sql.NullString{}, "web_connectivity",
+		result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.IsPartial = true
+	if err := result.Finished(sess); err != nil {
+		t.Fatal(err)
+	}
+	doneResults, _, err := ListResults(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doneResults) != 1 || !doneResults[0].IsPartial {
+		t.Fatal("expected a partial result", doneResults)
+	}
+}
+
 func TestNetworkCreate(t *testing.T) {
 	tmpfile, err := ioutil.TempFile("", "dbtest")
 	if err != nil {
@@ -387,7 +480,7 @@ func TestGetMeasurementJSON(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tk, err := GetMeasurementJSON(sess, msmt.ID)
+	tk, err := GetMeasurementJSON(sess, msmt.ID, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -395,3 +488,186 @@ func TestGetMeasurementJSON(t *testing.T) {
 		t.Error("inconsistent measurement downloaded")
 	}
 }
+
+func TestCompleteMeasurement(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("CreateResult with a missing network", func(t *testing.T) {
+		if _, err := CreateResult(sess, tmpdir, "im", network.ID+1); err == nil {
+			t.Fatal("expected an error here")
+		}
+		entries, err := os.ReadDir(filepath.Join(tmpdir, "msmts"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Fatal("expected no measurement dirs", entries)
+		}
+	})
+
+	result, err := CreateResult(sess, tmpdir, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("{\"test_name\": \"telegram\"}\n")
+	tk := &telegram.SummaryKeys{TCPBlocking: true}
+
+	t.Run("on success", func(t *testing.T) {
+		msmt, err := CreateMeasurement(sess, sql.NullString{}, "telegram",
+			result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations := map[string]string{"site": "home"}
+		if err := CompleteMeasurement(sess, msmt, data, tk, annotations, nil); err != nil {
+			t.Fatal(err)
+		}
+		var stored Measurement
+		if err := sess.Collection("measurements").Find("measurement_id", msmt.ID).One(&stored); err != nil {
+			t.Fatal(err)
+		}
+		if !stored.IsDone || stored.TestKeys == "" {
+			t.Fatal("unexpected measurement", stored)
+		}
+		out, err := ReadMeasurementFile(msmt.MeasurementFilePath.String, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data) {
+			t.Fatal("unexpected measurement file", string(out))
+		}
+		got, err := ListMeasurementAnnotations(sess, msmt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(annotations, got); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("on failure", func(t *testing.T) {
+		msmt, err := CreateMeasurement(sess, sql.NullString{}, "telegram",
+			result.MeasurementDir, 1, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A missing measurement causes the annotations to violate
+		// the foreign key constraint and the transaction to fail.
+		missing := *msmt
+		missing.ID += 1000
+		if err := CompleteMeasurement(sess, &missing, data, tk, map[string]string{"site": "home"}, nil); err == nil {
+			t.Fatal("expected an error here")
+		}
+		if missing.IsDone || missing.TestKeys != "" {
+			t.Fatal("expected the measurement to be unchanged", missing)
+		}
+		if _, err := os.Stat(msmt.MeasurementFilePath.String); !os.IsNotExist(err) {
+			t.Fatal("expected the measurement file to be removed", err)
+		}
+	})
+}
+
+func TestListResultsMatching(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	italy, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	germany, err := CreateNetwork(sess, &locationInfo{asn: 3320, countryCode: "DE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startTime := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	newResult := func(group string, networkID int64, day int, anomaly bool) int64 {
+		result, err := CreateResult(sess, tmpdir, group, networkID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msmt, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity",
+			result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msmt.IsAnomaly = sql.NullBool{Valid: true, Bool: anomaly}
+		if err := sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt); err != nil {
+			t.Fatal(err)
+		}
+		result.StartTime = startTime.AddDate(0, 0, day)
+		if err := result.Finished(sess); err != nil {
+			t.Fatal(err)
+		}
+		return result.ID
+	}
+	r1 := newResult("websites", italy.ID, 0, false)
+	r2 := newResult("websites", germany.ID, 1, true)
+	r3 := newResult("im", italy.ID, 2, true)
+	r4 := newResult("websites", italy.ID, 3, true)
+
+	expectations := []struct {
+		name     string
+		filter   *ResultFilter
+		expected []int64
+	}{{
+		name:     "with the zero filter",
+		filter:   &ResultFilter{},
+		expected: []int64{r1, r2, r3, r4},
+	}, {
+		name:     "by test group",
+		filter:   &ResultFilter{TestGroupName: "websites"},
+		expected: []int64{r1, r2, r4},
+	}, {
+		name:     "by network",
+		filter:   &ResultFilter{ASN: 30722, CountryCode: "IT"},
+		expected: []int64{r1, r3, r4},
+	}, {
+		name:     "by country",
+		filter:   &ResultFilter{CountryCode: "DE"},
+		expected: []int64{r2},
+	}, {
+		name:     "by anomaly",
+		filter:   &ResultFilter{AnomalyOnly: true, TestGroupName: "websites"},
+		expected: []int64{r2, r4},
+	}, {
+		name:     "by date",
+		filter:   &ResultFilter{Since: startTime.AddDate(0, 0, 1), Until: startTime.AddDate(0, 0, 3)},
+		expected: []int64{r2, r3},
+	}}
+	for _, e := range expectations {
+		t.Run(e.name, func(t *testing.T) {
+			done, incomplete, err := ListResultsMatching(sess, e.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(incomplete) != 0 {
+				t.Fatal("unexpected incomplete results", incomplete)
+			}
+			var ids []int64
+			for _, result := range done {
+				ids = append(ids, result.Result.ID)
+			}
+			if diff := cmp.Diff(e.expected, ids); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	if !(&ResultFilter{}).IsZero() || (&ResultFilter{AnomalyOnly: true}).IsZero() {
+		t.Fatal("unexpected IsZero result")
+	}
+}
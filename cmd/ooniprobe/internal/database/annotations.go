@@ -0,0 +1,234 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// maxTagLength is the maximum length of a measurement tag.
+const maxTagLength = 64
+
+var (
+	// ErrInvalidTag indicates that a measurement tag is empty or too long.
+	ErrInvalidTag = errors.New("database: invalid tag")
+
+	// ErrEmptyNote indicates that a result note is empty.
+	ErrEmptyNote = errors.New("database: empty note")
+)
+
+// normalizeTag returns the normalized version of the tag.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || len(tag) > maxTagLength {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+	}
+	return tag, nil
+}
+
+// AddMeasurementTag tags the given measurement. Adding a
+// tag that the measurement already has is not an error.
+func AddMeasurementTag(sess db.Session, measurementID int64, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	_, err = sess.SQL().Exec(`INSERT INTO measurement_tags (measurement_id, tag)
+		VALUES (?, ?) ON CONFLICT DO NOTHING`, measurementID, tag)
+	if err != nil {
+		return errors.Wrap(err, "adding measurement tag")
+	}
+	return nil
+}
+
+// RemoveMeasurementTag removes the tag from the given measurement. This
+// function returns db.ErrNoMoreRows if the measurement does not have the tag.
+func RemoveMeasurementTag(sess db.Session, measurementID int64, tag string) error {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	res := sess.Collection("measurement_tags").Find(db.Cond{
+		"measurement_id": measurementID,
+		"tag":            tag,
+	})
+	var entry MeasurementTag
+	if err := res.One(&entry); err != nil {
+		return err
+	}
+	if err := res.Delete(); err != nil {
+		return errors.Wrap(err, "removing measurement tag")
+	}
+	return nil
+}
+
+// ListMeasurementTags returns the sorted tags of the given measurement.
+func ListMeasurementTags(sess db.Session, measurementID int64) ([]string, error) {
+	entries := []MeasurementTag{}
+	err := sess.Collection("measurement_tags").Find("measurement_id", measurementID).
+		OrderBy("tag").All(&entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing measurement tags")
+	}
+	tags := []string{}
+	for _, entry := range entries {
+		tags = append(tags, entry.Tag)
+	}
+	return tags, nil
+}
+
+// ListTaggedMeasurements returns the IDs of the measurements having the given tag.
+func ListTaggedMeasurements(sess db.Session, tag string) ([]int64, error) {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	entries := []MeasurementTag{}
+	err = sess.Collection("measurement_tags").Find("tag", tag).
+		OrderBy("measurement_id").All(&entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing tagged measurements")
+	}
+	ids := []int64{}
+	for _, entry := range entries {
+		ids = append(ids, entry.MeasurementID)
+	}
+	return ids, nil
+}
+
+// maxAnnotationKeyLength is the maximum length of an annotation key.
+const maxAnnotationKeyLength = 64
+
+// ErrInvalidAnnotation indicates that an annotation key is empty or too long.
+var ErrInvalidAnnotation = errors.New("database: invalid annotation")
+
+// validateAnnotations returns an error if an annotation key is not valid.
+func validateAnnotations(annotations map[string]string) error {
+	for key := range annotations {
+		if key == "" || len(key) > maxAnnotationKeyLength {
+			return fmt.Errorf("%w: %q", ErrInvalidAnnotation, key)
+		}
+	}
+	return nil
+}
+
+// SetMeasurementAnnotations stores the given annotations of a measurement,
+// replacing the existing annotations with the same keys.
+func SetMeasurementAnnotations(sess db.Session, measurementID int64, annotations map[string]string) error {
+	if err := validateAnnotations(annotations); err != nil {
+		return err
+	}
+	return sess.Tx(func(tx db.Session) error {
+		return setMeasurementAnnotations(tx, measurementID, annotations)
+	})
+}
+
+// setMeasurementAnnotations is like SetMeasurementAnnotations but uses
+// the given transaction and does not validate the annotations.
+func setMeasurementAnnotations(tx db.Session, measurementID int64, annotations map[string]string) error {
+	for key, value := range annotations {
+		_, err := tx.SQL().Exec(`INSERT INTO measurement_annotations
+			(measurement_id, annotation_key, annotation_value) VALUES (?, ?, ?)
+			ON CONFLICT (measurement_id, annotation_key) DO UPDATE SET
+			annotation_value = excluded.annotation_value`,
+			measurementID, key, value)
+		if err != nil {
+			return errors.Wrap(err, "setting measurement annotation")
+		}
+	}
+	return nil
+}
+
+// ListMeasurementAnnotations returns the annotations of the given measurement.
+func ListMeasurementAnnotations(sess db.Session, measurementID int64) (map[string]string, error) {
+	entries := []MeasurementAnnotation{}
+	err := sess.Collection("measurement_annotations").Find("measurement_id", measurementID).All(&entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing measurement annotations")
+	}
+	annotations := make(map[string]string)
+	for _, entry := range entries {
+		annotations[entry.Key] = entry.Value
+	}
+	return annotations, nil
+}
+
+// loadBatchSize is the number of measurements whose details (e.g., the
+// annotations) we load with a single query, which we bound to stay well
+// below the maximum number of SQLite query parameters.
+const loadBatchSize = 500
+
+// loadMeasurementAnnotations sets the annotations of the given measurements.
+func loadMeasurementAnnotations(sess db.Session, measurements []MeasurementURLNetwork) error {
+	index := make(map[int64]*MeasurementURLNetwork)
+	var ids []int64
+	for idx := range measurements {
+		m := &measurements[idx]
+		m.Annotations = make(map[string]string)
+		index[m.Measurement.ID] = m
+		ids = append(ids, m.Measurement.ID)
+	}
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > loadBatchSize {
+			batch = batch[:loadBatchSize]
+		}
+		ids = ids[len(batch):]
+		entries := []MeasurementAnnotation{}
+		err := sess.Collection("measurement_annotations").Find(db.Cond{"measurement_id IN": batch}).All(&entries)
+		if err != nil {
+			return errors.Wrap(err, "loading measurement annotations")
+		}
+		for _, entry := range entries {
+			index[entry.MeasurementID].Annotations[entry.Key] = entry.Value
+		}
+	}
+	return nil
+}
+
+// AddResultNote adds a note to the given result.
+func AddResultNote(sess db.Session, resultID int64, text string) (*ResultNote, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrEmptyNote
+	}
+	note := ResultNote{
+		ResultID:  resultID,
+		Text:      text,
+		CreatedAt: time.Now().UTC(),
+	}
+	newID, err := sess.Collection("result_notes").Insert(note)
+	if err != nil {
+		return nil, errors.Wrap(err, "adding result note")
+	}
+	note.ID = newID.ID().(int64)
+	return &note, nil
+}
+
+// ListResultNotes returns the notes of the given result from the oldest.
+func ListResultNotes(sess db.Session, resultID int64) ([]ResultNote, error) {
+	notes := []ResultNote{}
+	err := sess.Collection("result_notes").Find("result_id", resultID).
+		OrderBy("note_created_at", "note_id").All(&notes)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing result notes")
+	}
+	return notes, nil
+}
+
+// DeleteResultNote deletes the given note. This function
+// returns db.ErrNoMoreRows if the note does not exist.
+func DeleteResultNote(sess db.Session, noteID int64) error {
+	res := sess.Collection("result_notes").Find("note_id", noteID)
+	var note ResultNote
+	if err := res.One(&note); err != nil {
+		return err
+	}
+	if err := res.Delete(); err != nil {
+		return errors.Wrap(err, "deleting result note")
+	}
+	return nil
+}
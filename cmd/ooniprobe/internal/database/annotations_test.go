@@ -0,0 +1,179 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/upper/db/v4"
+)
+
+func TestAnnotations(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location := locationInfo{
+		asn:         0,
+		countryCode: "IT",
+		networkName: "Unknown",
+	}
+	network, err := CreateNetwork(sess, &location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msmt, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity", tmpdir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("measurement tags", func(t *testing.T) {
+		for _, tag := range []string{"mobile hotspot", " power outage ", "mobile hotspot"} {
+			if err := AddMeasurementTag(sess, msmt.ID, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tags, err := ListMeasurementTags(sess, msmt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]string{"mobile hotspot", "power outage"}, tags); diff != "" {
+			t.Fatal(diff)
+		}
+		ids, err := ListTaggedMeasurements(sess, "power outage")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff([]int64{msmt.ID}, ids); diff != "" {
+			t.Fatal(diff)
+		}
+		if err := RemoveMeasurementTag(sess, msmt.ID, "power outage"); err != nil {
+			t.Fatal(err)
+		}
+		if err := RemoveMeasurementTag(sess, msmt.ID, "power outage"); err != db.ErrNoMoreRows {
+			t.Fatal("not the error we expected", err)
+		}
+		for _, tag := range []string{"", "  ", strings.Repeat("x", maxTagLength+1)} {
+			if err := AddMeasurementTag(sess, msmt.ID, tag); !errors.Is(err, ErrInvalidTag) {
+				t.Fatal("not the error we expected", err)
+			}
+		}
+		if err := AddMeasurementTag(sess, msmt.ID+1, "antani"); err == nil {
+			t.Fatal("expected an error for a nonexistent measurement")
+		}
+	})
+
+	t.Run("result notes", func(t *testing.T) {
+		first, err := AddResultNote(sess, result.ID, "power outage")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := AddResultNote(sess, result.ID, "using a mobile hotspot"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := AddResultNote(sess, result.ID, " "); !errors.Is(err, ErrEmptyNote) {
+			t.Fatal("not the error we expected", err)
+		}
+		notes, err := ListResultNotes(sess, result.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 2 || notes[0].Text != "power outage" {
+			t.Fatal("unexpected notes", notes)
+		}
+		if err := DeleteResultNote(sess, first.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := DeleteResultNote(sess, first.ID); err != db.ErrNoMoreRows {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("measurement annotations", func(t *testing.T) {
+		err := SetMeasurementAnnotations(sess, msmt.ID, map[string]string{"platform": "linux", "site": "home"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := SetMeasurementAnnotations(sess, msmt.ID, map[string]string{"site": "office"}); err != nil {
+			t.Fatal(err)
+		}
+		err = SetMeasurementAnnotations(sess, msmt.ID, map[string]string{"": "antani"})
+		if !errors.Is(err, ErrInvalidAnnotation) {
+			t.Fatal("not the error we expected", err)
+		}
+		annotations, err := ListMeasurementAnnotations(sess, msmt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]string{"platform": "linux", "site": "office"}
+		if diff := cmp.Diff(expected, annotations); diff != "" {
+			t.Fatal(diff)
+		}
+		for _, entry := range []struct {
+			annotations map[string]string
+			count       int
+		}{
+			{map[string]string{"site": "office"}, 1},
+			{map[string]string{"site": "office", "platform": "linux"}, 1},
+			{map[string]string{"site": "home"}, 0},
+		} {
+			measurements, err := ListMeasurementsMatching(sess, &MeasurementFilter{Annotations: entry.annotations})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(measurements) != entry.count {
+				t.Fatal("unexpected measurements", entry.annotations, measurements)
+			}
+			if entry.count > 0 {
+				if diff := cmp.Diff(expected, measurements[0].Annotations); diff != "" {
+					t.Fatal(diff)
+				}
+			}
+		}
+	})
+
+	t.Run("deleting the result deletes the annotations", func(t *testing.T) {
+		if err := AddMeasurementTag(sess, msmt.ID, "antani"); err != nil {
+			t.Fatal(err)
+		}
+		if err := DeleteResult(sess, result.ID); err != nil {
+			t.Fatal(err)
+		}
+		notes, err := ListResultNotes(sess, result.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tags, err := ListMeasurementTags(sess, msmt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations, err := ListMeasurementAnnotations(sess, msmt.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 0 || len(tags) != 0 || len(annotations) != 0 {
+			t.Fatal("expected no annotations", notes, tags, annotations)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package database
+
+//
+// Compressed measurement files.
+//
+// We store the raw JSON of each measurement gzip compressed, because
+// websites runs generate hundreds of MB of JSON. Older releases stored
+// it uncompressed, hence the read path detects whether a file is
+// compressed and CompressMeasurementFiles compresses existing files.
+//
+// When the database is encrypted (see OpenEncrypted), we also encrypt
+// the measurement files, because they contain the same data.
+//
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils/encryption"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// compressedSuffix is the suffix of compressed measurement files.
+const compressedSuffix = ".gz"
+
+// gzipMagic is the magic number at the beginning of gzip files.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// compressData returns the gzip compressed data.
+func compressData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressData returns data decompressed if data is gzip
+// compressed and otherwise returns data unmodified.
+func decompressData(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// WriteMeasurementFile writes the compressed raw JSON of a measurement
+// into the given measurement file. When keyring is not nil, we also
+// encrypt the compressed raw JSON using keyring.
+func WriteMeasurementFile(path string, data []byte, keyring *encryption.Keyring) error {
+	compressed, err := compressData(data)
+	if err != nil {
+		return errors.Wrap(err, "compressing measurement file")
+	}
+	if keyring != nil {
+		compressed, err = keyring.Encrypt(encryptedMeasurementMagic, compressed)
+		if err != nil {
+			return errors.Wrap(err, "encrypting measurement file")
+		}
+	}
+	return writeFileAtomic(path, compressed)
+}
+
+// ReadMeasurementFile returns the raw JSON of a measurement stored in
+// the given measurement file, which may or may not be compressed. When
+// the file is encrypted, we decrypt it using keyring and we fail with
+// ErrEncryptedMeasurementFile if keyring is nil.
+func ReadMeasurementFile(path string, keyring *encryption.Keyring) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, encryptedMeasurementMagic) {
+		if keyring == nil {
+			return nil, ErrEncryptedMeasurementFile
+		}
+		data, err = keyring.Decrypt(encryptedMeasurementMagic, data)
+		if err != nil {
+			return nil, ErrWrongPassphrase
+		}
+	}
+	data, err = decompressData(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decompressing %s", path)
+	}
+	return data, nil
+}
+
+// CompressMeasurementFiles compresses the measurement files written by
+// older releases and returns the number of files it compressed. We skip
+// the measurement files that do not exist, e.g., because the user removed
+// them, such that their path continues to reference the missing file.
+// We ignore the measurements that are still running. We write the compressed
+// files using keyring (see WriteMeasurementFile).
+func CompressMeasurementFiles(sess db.Session, keyring *encryption.Keyring) (int, error) {
+	var measurements []Measurement
+	err := sess.Collection("measurements").Find(db.Cond{
+		"measurement_file_path NOT LIKE": "%" + compressedSuffix,
+		"measurement_is_done":            true,
+	}).All(&measurements)
+	if err != nil {
+		return 0, errors.Wrap(err, "listing uncompressed measurements")
+	}
+	var count int
+	for _, msmt := range measurements {
+		path := msmt.MeasurementFilePath.String
+		data, err := ReadMeasurementFile(path, keyring)
+		if os.IsNotExist(err) {
+			log.Debugf("skipping missing measurement file %s", path)
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		if err := WriteMeasurementFile(path+compressedSuffix, data, keyring); err != nil {
+			return count, err
+		}
+		msmt.MeasurementFilePath.String = path + compressedSuffix
+		err = sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt)
+		if err != nil {
+			return count, errors.Wrap(err, "updating measurement file path")
+		}
+		count++
+		// We remove the uncompressed file only after updating the
+		// database, such that we do not lose data if we're interrupted.
+		if err := os.Remove(path); err != nil {
+			log.WithError(err).Warnf("failed to remove %s", path)
+		}
+	}
+	return count, nil
+}
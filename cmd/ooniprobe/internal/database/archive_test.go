@@ -0,0 +1,143 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMeasurementFiles(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	data := []byte("{\"test_name\": \"web_connectivity\"}\n")
+
+	t.Run("with a compressed file", func(t *testing.T) {
+		path := filepath.Join(tmpdir, "msmt-web_connectivity-0.json.gz")
+		if err := WriteMeasurementFile(path, data, nil); err != nil {
+			t.Fatal(err)
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(raw, gzipMagic) {
+			t.Fatal("expected a compressed file")
+		}
+		out, err := ReadMeasurementFile(path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, out) {
+			t.Fatal("unexpected data", string(out))
+		}
+	})
+
+	t.Run("with an uncompressed file", func(t *testing.T) {
+		path := filepath.Join(tmpdir, "msmt-web_connectivity-1.json")
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		out, err := ReadMeasurementFile(path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, out) {
+			t.Fatal("unexpected data", string(out))
+		}
+	})
+}
+
+func TestCompressMeasurementFiles(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("{\"test_name\": \"telegram\"}\n")
+	var legacy []*Measurement
+	for idx, testName := range []string{"telegram", "signal", "whatsapp"} {
+		msmt, err := CreateMeasurement(sess, sql.NullString{}, testName,
+			result.MeasurementDir, idx, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testName == "whatsapp" {
+			continue // still running, hence not done
+		}
+		// emulate a measurement created by an older release
+		msmt.MeasurementFilePath.String = strings.TrimSuffix(
+			msmt.MeasurementFilePath.String, compressedSuffix)
+		if err := msmt.Done(sess); err != nil {
+			t.Fatal(err)
+		}
+		if testName == "telegram" {
+			if err := os.WriteFile(msmt.MeasurementFilePath.String, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		legacy = append(legacy, msmt)
+	}
+
+	count, err := CompressMeasurementFiles(sess, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatal("unexpected number of compressed files", count)
+	}
+	for idx, msmt := range legacy {
+		var stored Measurement
+		if err := sess.Collection("measurements").Find("measurement_id", msmt.ID).One(&stored); err != nil {
+			t.Fatal(err)
+		}
+		expected := msmt.MeasurementFilePath.String
+		if idx == 0 {
+			expected += compressedSuffix // only telegram's file exists
+		}
+		if stored.MeasurementFilePath.String != expected {
+			t.Fatal("unexpected measurement file path", stored.MeasurementFilePath.String)
+		}
+		if _, err := os.Stat(msmt.MeasurementFilePath.String); !os.IsNotExist(err) {
+			t.Fatal("expected the uncompressed file to not exist", err)
+		}
+	}
+	out, err := ReadMeasurementFile(legacy[0].MeasurementFilePath.String+compressedSuffix, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, out) {
+		t.Fatal("unexpected data", string(out))
+	}
+
+	count, err = CompressMeasurementFiles(sess, nil)
+	if err != nil || count != 0 {
+		t.Fatal("unexpected result", count, err)
+	}
+}
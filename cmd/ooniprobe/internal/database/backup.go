@@ -0,0 +1,302 @@
+package database
+
+//
+// Backup and restore.
+//
+// A backup is a directory containing a copy of the database, which we
+// make using SQLite's online backup API, such that we do not need to stop
+// other users of the database, a copy of the measurements directory, and
+// a manifest. Because the database contains absolute paths, the manifest
+// records the OONI home of the backup and restoring rewrites these paths
+// such that a backup can be restored into another OONI home.
+//
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// These are the names of the entries of a backup directory.
+const (
+	backupDatabaseName = "main.sqlite3"
+	backupManifestName = "manifest.json"
+	backupMsmtsName    = "msmts"
+)
+
+// ErrInvalidBackup indicates that a directory is not a valid backup.
+var ErrInvalidBackup = errors.New("database: invalid backup")
+
+// backupManifest describes a backup.
+type backupManifest struct {
+	// HomePath is the OONI home of the backup.
+	HomePath string `json:"home_path"`
+
+	// SchemaVersion is the schema version of the database.
+	SchemaVersion int `json:"schema_version"`
+
+	// CreatedAt is when we created the backup.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Encrypted indicates that the database of the backup is encrypted
+	// and lives at EncryptedPath(backupDatabaseName).
+	Encrypted bool `json:"encrypted,omitempty"`
+}
+
+// copyDatabase copies the main database of src into the main database
+// of dst using SQLite's online backup API.
+func copyDatabase(dst, src *sql.DB) error {
+	ctx := context.Background()
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	return dstConn.Raw(func(dstDriverConn interface{}) error {
+		return srcConn.Raw(func(srcDriverConn interface{}) error {
+			dstSQLite, ok := dstDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("database: unexpected driver connection %T", dstDriverConn)
+			}
+			srcSQLite, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("database: unexpected driver connection %T", srcDriverConn)
+			}
+			backup, err := dstSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// copyTree copies the regular files and the directories inside src into
+// dst, which we create if needed. A missing src is an empty tree.
+func copyTree(dst, src string) error {
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(target, path)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// copyFile copies the src file into the dst file.
+func copyFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Backup writes a backup of the database and of the measurements inside
+// the given OONI home into backupDir, which must not exist. When passphrase
+// is not empty, we encrypt the database of the backup with it. This function
+// returns ErrNotSupported with PostgreSQL, which has its own backup tools.
+func Backup(sess db.Session, homePath string, backupDir string, passphrase string) error {
+	if isPostgreSQL(sess) {
+		return ErrNotSupported
+	}
+	if err := os.MkdirAll(filepath.Dir(backupDir), 0700); err != nil {
+		return err
+	}
+	if err := os.Mkdir(backupDir, 0700); err != nil {
+		return errors.Wrap(err, "creating backup directory")
+	}
+	if err := writeBackup(sess, homePath, backupDir, passphrase); err != nil {
+		os.RemoveAll(backupDir)
+		return err
+	}
+	return nil
+}
+
+// writeBackup writes the content of the backup into backupDir.
+func writeBackup(sess db.Session, homePath string, backupDir string, passphrase string) error {
+	src := sess.Driver().(*sql.DB)
+	version, err := SchemaVersion(src)
+	if err != nil {
+		return err
+	}
+	dbPath := filepath.Join(backupDir, backupDatabaseName)
+	dst, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return err
+	}
+	if err := copyDatabase(dst, src); err != nil {
+		dst.Close()
+		return errors.Wrap(err, "copying database")
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if passphrase != "" {
+		if err := encryptDatabaseFile(dbPath, passphrase); err != nil {
+			return errors.Wrap(err, "encrypting database")
+		}
+	}
+	err = copyTree(filepath.Join(backupDir, backupMsmtsName), filepath.Join(homePath, backupMsmtsName))
+	if err != nil {
+		return errors.Wrap(err, "copying measurements")
+	}
+	manifest := &backupManifest{
+		HomePath:      homePath,
+		SchemaVersion: version,
+		CreatedAt:     time.Now().UTC(),
+		Encrypted:     passphrase != "",
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(backupDir, backupManifestName), data, 0600)
+}
+
+// readBackupManifest reads the manifest of the backup in backupDir.
+func readBackupManifest(backupDir string) (*backupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(backupDir, backupManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBackup, err.Error())
+	}
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil || manifest.HomePath == "" {
+		return nil, fmt.Errorf("%w: invalid manifest", ErrInvalidBackup)
+	}
+	return &manifest, nil
+}
+
+// Restore replaces the database with the one in the backup in backupDir
+// and copies the measurements of the backup into the given OONI home. We
+// migrate the restored database, if needed, and we rewrite its paths
+// such that they refer to the given OONI home. We use passphrase to
+// decrypt the database of the backup, when it is encrypted. This function
+// returns ErrNotSupported with PostgreSQL.
+func Restore(sess db.Session, homePath string, backupDir string, passphrase string) error {
+	if isPostgreSQL(sess) {
+		return ErrNotSupported
+	}
+	manifest, err := readBackupManifest(backupDir)
+	if err != nil {
+		return err
+	}
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+	if manifest.SchemaVersion > latest {
+		return fmt.Errorf("%w: %d", ErrSchemaTooNew, manifest.SchemaVersion)
+	}
+	dbPath := filepath.Join(backupDir, backupDatabaseName)
+	if manifest.Encrypted {
+		if passphrase == "" {
+			return ErrEncryptedDatabase
+		}
+		data, err := os.ReadFile(EncryptedPath(dbPath))
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidBackup, err.Error())
+		}
+		plaintext, err := decryptData(passphrase, data)
+		if err != nil {
+			return err
+		}
+		tmpdir, err := os.MkdirTemp("", "ooniprobe-restore")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpdir)
+		dbPath = filepath.Join(tmpdir, backupDatabaseName)
+		if err := os.WriteFile(dbPath, plaintext, 0600); err != nil {
+			return err
+		}
+	}
+	err = copyTree(filepath.Join(homePath, backupMsmtsName), filepath.Join(backupDir, backupMsmtsName))
+	if err != nil {
+		return errors.Wrap(err, "copying measurements")
+	}
+	src, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst := sess.Driver().(*sql.DB)
+	if err := copyDatabase(dst, src); err != nil {
+		return errors.Wrap(err, "copying database")
+	}
+	if err := RunMigrations(dst); err != nil {
+		return err
+	}
+	return rewriteHomePath(sess, manifest.HomePath, homePath)
+}
+
+// rewriteHomePath replaces the oldHome prefix of the paths inside
+// the database with newHome.
+func rewriteHomePath(sess db.Session, oldHome, newHome string) error {
+	oldPrefix := filepath.Clean(oldHome) + string(filepath.Separator)
+	newPrefix := filepath.Clean(newHome) + string(filepath.Separator)
+	if oldPrefix == newPrefix {
+		return nil
+	}
+	return sess.Tx(func(tx db.Session) error {
+		for _, column := range []struct {
+			table string
+			name  string
+		}{
+			{"results", "measurement_dir"},
+			{"measurements", "measurement_file_path"},
+			{"measurements", "report_file_path"},
+		} {
+			query := fmt.Sprintf(`UPDATE %s SET %s = ? || substr(%s, length(?) + 1)
+				WHERE substr(%s, 1, length(?)) = ?`,
+				column.table, column.name, column.name, column.name)
+			_, err := tx.SQL().Exec(query, newPrefix, oldPrefix, oldPrefix, oldPrefix)
+			if err != nil {
+				return errors.Wrapf(err, "rewriting %s.%s", column.table, column.name)
+			}
+		}
+		return nil
+	})
+}
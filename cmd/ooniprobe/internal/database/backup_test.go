@@ -0,0 +1,165 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcHome := filepath.Join(tmpdir, "src")
+	srcSess, err := Connect(filepath.Join(tmpdir, "src.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcSess.Close()
+	network, err := CreateNetwork(srcSess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(srcSess, srcHome, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msmt, err := CreateMeasurement(srcSess, sql.NullString{}, "telegram",
+		result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("{\"test_name\": \"telegram\"}\n")
+	if err := WriteMeasurementFile(msmt.MeasurementFilePath.String, data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Finished(srcSess); err != nil {
+		t.Fatal(err)
+	}
+
+	backupDir := filepath.Join(tmpdir, "backups", "first")
+	if err := Backup(srcSess, srcHome, backupDir, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("into an existing directory", func(t *testing.T) {
+		if err := Backup(srcSess, srcHome, backupDir, ""); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+
+	dstHome := filepath.Join(tmpdir, "dst")
+	dstSess, err := Connect(filepath.Join(tmpdir, "dst.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dstSess.Close()
+
+	t.Run("from an invalid backup", func(t *testing.T) {
+		err := Restore(dstSess, dstHome, filepath.Join(tmpdir, "backups"), "")
+		if !errors.Is(err, ErrInvalidBackup) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("from a valid backup", func(t *testing.T) {
+		if err := Restore(dstSess, dstHome, backupDir, ""); err != nil {
+			t.Fatal(err)
+		}
+		done, incomplete, err := ListResults(dstSess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(done) != 1 || len(incomplete) != 0 {
+			t.Fatal("unexpected results", done, incomplete)
+		}
+		if !strings.HasPrefix(done[0].MeasurementDir, dstHome+string(filepath.Separator)) {
+			t.Fatal("unexpected measurement dir", done[0].MeasurementDir)
+		}
+		measurements, err := ListMeasurements(dstSess, done[0].Result.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(measurements) != 1 {
+			t.Fatal("unexpected measurements", measurements)
+		}
+		out, err := ReadMeasurementFile(measurements[0].MeasurementFilePath.String, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != string(data) {
+			t.Fatal("unexpected measurement file", string(out))
+		}
+	})
+}
+
+func TestEncryptedBackupAndRestore(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcHome := filepath.Join(tmpdir, "src")
+	srcSess, err := Connect(filepath.Join(tmpdir, "src.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srcSess.Close()
+	network, err := CreateNetwork(srcSess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateResult(srcSess, srcHome, "im", network.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	backupDir := filepath.Join(tmpdir, "backup")
+	if err := Backup(srcSess, srcHome, backupDir, "antani"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, backupDatabaseName)); !os.IsNotExist(err) {
+		t.Fatal("the backup contains the plaintext database", err)
+	}
+
+	dstHome := filepath.Join(tmpdir, "dst")
+	dstSess, err := Connect(filepath.Join(tmpdir, "dst.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dstSess.Close()
+
+	t.Run("without a passphrase", func(t *testing.T) {
+		err := Restore(dstSess, dstHome, backupDir, "")
+		if !errors.Is(err, ErrEncryptedDatabase) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with the wrong passphrase", func(t *testing.T) {
+		err := Restore(dstSess, dstHome, backupDir, "mascetti")
+		if !errors.Is(err, ErrWrongPassphrase) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with the right passphrase", func(t *testing.T) {
+		if err := Restore(dstSess, dstHome, backupDir, "antani"); err != nil {
+			t.Fatal(err)
+		}
+		count, err := dstSess.Collection("results").Find().Count()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatal("unexpected number of results", count)
+		}
+	})
+}
@@ -0,0 +1,371 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/mattn/go-sqlite3"
+	"github.com/upper/db/v4"
+)
+
+// These constants control how we retry operations when the database is busy.
+const (
+	// busyMaxAttempts is the maximum number of attempts.
+	busyMaxAttempts = 5
+
+	// busyInitialDelay is the delay after the first failure, which
+	// doubles after each subsequent failure.
+	busyInitialDelay = 50 * time.Millisecond
+)
+
+// IsBusy returns whether err indicates that the database is locked
+// by another connection, i.e., "database is locked".
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// RetryIfBusy calls fn and calls it again, with exponential backoff,
+// as long as it fails because the database is busy. The busy timeout
+// we configure in Connect makes this condition rare, yet it may still
+// happen when another process holds the write lock for long.
+func RetryIfBusy(fn func() error) error {
+	delay := busyInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if !IsBusy(err) || attempt >= busyMaxAttempts {
+			return err
+		}
+		log.Debugf("database is busy; retrying in %s", delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// concurrentSession is the db.Session returned by NewConcurrentSession.
+type concurrentSession struct {
+	db.Session
+
+	// mu serializes transactions.
+	mu *sync.Mutex
+}
+
+// NewConcurrentSession wraps a session such that it can be used by several
+// goroutines, e.g., a background submitter and a foreground run. The wrapper
+// serializes transactions, such that goroutines do not compete for the write
+// lock, and retries them using RetryIfBusy when another process is holding
+// the write lock. Note that fn may therefore be called more than once.
+func NewConcurrentSession(sess db.Session) db.Session {
+	if cs, ok := sess.(*concurrentSession); ok {
+		return cs
+	}
+	return &concurrentSession{Session: sess, mu: &sync.Mutex{}}
+}
+
+// Tx implements db.Session.Tx.
+func (cs *concurrentSession) Tx(fn func(sess db.Session) error) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return RetryIfBusy(func() error {
+		return cs.Session.Tx(fn)
+	})
+}
+
+// Collection implements db.Session.Collection. The returned collection
+// writes inside a transaction, including when updating or deleting the
+// results of Find. Outside of a transaction, the SQLite adapter begins its
+// own transaction for each statement and does not roll it back when the
+// statement fails (e.g., because of a foreign key), which leaks a connection
+// holding the write lock and makes the database busy for everyone else. For
+// the same reason, SQL executes statements inside a transaction.
+func (cs *concurrentSession) Collection(name string) db.Collection {
+	return &concurrentCollection{Collection: cs.Session.Collection(name), cs: cs}
+}
+
+// concurrentCollection is the db.Collection returned by concurrentSession.
+type concurrentCollection struct {
+	db.Collection
+	cs *concurrentSession
+}
+
+// Insert implements db.Collection.Insert.
+func (cc *concurrentCollection) Insert(item interface{}) (res db.InsertResult, err error) {
+	err = cc.cs.Tx(func(tx db.Session) error {
+		res, err = tx.Collection(cc.Name()).Insert(item)
+		return err
+	})
+	return
+}
+
+// InsertReturning implements db.Collection.InsertReturning.
+func (cc *concurrentCollection) InsertReturning(item interface{}) error {
+	return cc.cs.Tx(func(tx db.Session) error {
+		return tx.Collection(cc.Name()).InsertReturning(item)
+	})
+}
+
+// Find implements db.Collection.Find.
+func (cc *concurrentCollection) Find(conds ...interface{}) db.Result {
+	return &concurrentResult{
+		Result: cc.Collection.Find(conds...),
+		cs:     cc.cs,
+		replay: func(tx db.Session) db.Result {
+			return tx.Collection(cc.Name()).Find(conds...)
+		},
+	}
+}
+
+// concurrentResult is the db.Result returned by concurrentCollection. We
+// read using the wrapped result. To write, we replay the calls that built
+// the result using the transaction's session.
+type concurrentResult struct {
+	db.Result
+	cs     *concurrentSession
+	replay func(tx db.Session) db.Result
+}
+
+// chain returns the concurrentResult obtained by applying fn.
+func (cr *concurrentResult) chain(fn func(res db.Result) db.Result) db.Result {
+	return &concurrentResult{
+		Result: fn(cr.Result),
+		cs:     cr.cs,
+		replay: func(tx db.Session) db.Result {
+			return fn(cr.replay(tx))
+		},
+	}
+}
+
+// Limit implements db.Result.Limit.
+func (cr *concurrentResult) Limit(n int) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.Limit(n) })
+}
+
+// Offset implements db.Result.Offset.
+func (cr *concurrentResult) Offset(n int) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.Offset(n) })
+}
+
+// OrderBy implements db.Result.OrderBy.
+func (cr *concurrentResult) OrderBy(v ...interface{}) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.OrderBy(v...) })
+}
+
+// Select implements db.Result.Select.
+func (cr *concurrentResult) Select(v ...interface{}) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.Select(v...) })
+}
+
+// And implements db.Result.And.
+func (cr *concurrentResult) And(v ...interface{}) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.And(v...) })
+}
+
+// GroupBy implements db.Result.GroupBy.
+func (cr *concurrentResult) GroupBy(v ...interface{}) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.GroupBy(v...) })
+}
+
+// Paginate implements db.Result.Paginate.
+func (cr *concurrentResult) Paginate(pageSize uint) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.Paginate(pageSize) })
+}
+
+// Page implements db.Result.Page.
+func (cr *concurrentResult) Page(pageNumber uint) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.Page(pageNumber) })
+}
+
+// Cursor implements db.Result.Cursor.
+func (cr *concurrentResult) Cursor(cursorColumn string) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.Cursor(cursorColumn) })
+}
+
+// NextPage implements db.Result.NextPage.
+func (cr *concurrentResult) NextPage(cursorValue interface{}) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.NextPage(cursorValue) })
+}
+
+// PrevPage implements db.Result.PrevPage.
+func (cr *concurrentResult) PrevPage(cursorValue interface{}) db.Result {
+	return cr.chain(func(res db.Result) db.Result { return res.PrevPage(cursorValue) })
+}
+
+// Update implements db.Result.Update.
+func (cr *concurrentResult) Update(record interface{}) error {
+	return cr.cs.Tx(func(tx db.Session) error {
+		return cr.replay(tx).Update(record)
+	})
+}
+
+// Delete implements db.Result.Delete.
+func (cr *concurrentResult) Delete() error {
+	return cr.cs.Tx(func(tx db.Session) error {
+		return cr.replay(tx).Delete()
+	})
+}
+
+// SQL implements db.Session.SQL.
+func (cs *concurrentSession) SQL() db.SQL {
+	return &concurrentSQL{SQL: cs.Session.SQL(), cs: cs}
+}
+
+// concurrentSQL is the db.SQL returned by concurrentSession.
+type concurrentSQL struct {
+	db.SQL
+	cs *concurrentSession
+}
+
+// Exec implements db.SQL.Exec.
+func (cq *concurrentSQL) Exec(query interface{}, args ...interface{}) (res sql.Result, err error) {
+	return cq.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext implements db.SQL.ExecContext.
+func (cq *concurrentSQL) ExecContext(
+	ctx context.Context, query interface{}, args ...interface{}) (res sql.Result, err error) {
+	err = cq.cs.Tx(func(tx db.Session) error {
+		res, err = tx.SQL().ExecContext(ctx, query, args...)
+		return err
+	})
+	return
+}
+
+// Update implements db.SQL.Update.
+func (cq *concurrentSQL) Update(table string) db.Updater {
+	return &concurrentUpdater{
+		Updater: cq.SQL.Update(table),
+		cs:      cq.cs,
+		replay: func(tx db.Session) db.Updater {
+			return tx.SQL().Update(table)
+		},
+	}
+}
+
+// concurrentUpdater is the db.Updater returned by concurrentSQL. Like
+// concurrentResult, it replays the calls that built the statement using
+// the transaction's session when executing the statement.
+type concurrentUpdater struct {
+	db.Updater
+	cs     *concurrentSession
+	replay func(tx db.Session) db.Updater
+}
+
+// chain returns the concurrentUpdater obtained by applying fn.
+func (cu *concurrentUpdater) chain(fn func(up db.Updater) db.Updater) db.Updater {
+	return &concurrentUpdater{
+		Updater: fn(cu.Updater),
+		cs:      cu.cs,
+		replay: func(tx db.Session) db.Updater {
+			return fn(cu.replay(tx))
+		},
+	}
+}
+
+// Set implements db.Updater.Set.
+func (cu *concurrentUpdater) Set(v ...interface{}) db.Updater {
+	return cu.chain(func(up db.Updater) db.Updater { return up.Set(v...) })
+}
+
+// Where implements db.Updater.Where.
+func (cu *concurrentUpdater) Where(v ...interface{}) db.Updater {
+	return cu.chain(func(up db.Updater) db.Updater { return up.Where(v...) })
+}
+
+// And implements db.Updater.And.
+func (cu *concurrentUpdater) And(v ...interface{}) db.Updater {
+	return cu.chain(func(up db.Updater) db.Updater { return up.And(v...) })
+}
+
+// Limit implements db.Updater.Limit.
+func (cu *concurrentUpdater) Limit(n int) db.Updater {
+	return cu.chain(func(up db.Updater) db.Updater { return up.Limit(n) })
+}
+
+// Amend implements db.Updater.Amend.
+func (cu *concurrentUpdater) Amend(fn func(queryIn string) (queryOut string)) db.Updater {
+	return cu.chain(func(up db.Updater) db.Updater { return up.Amend(fn) })
+}
+
+// Exec implements db.Updater.Exec.
+func (cu *concurrentUpdater) Exec() (sql.Result, error) {
+	return cu.ExecContext(context.Background())
+}
+
+// ExecContext implements db.Updater.ExecContext.
+func (cu *concurrentUpdater) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	err = cu.cs.Tx(func(tx db.Session) error {
+		res, err = cu.replay(tx).ExecContext(ctx)
+		return err
+	})
+	return
+}
+
+// DeleteFrom implements db.SQL.DeleteFrom.
+func (cq *concurrentSQL) DeleteFrom(table string) db.Deleter {
+	return &concurrentDeleter{
+		Deleter: cq.SQL.DeleteFrom(table),
+		cs:      cq.cs,
+		replay: func(tx db.Session) db.Deleter {
+			return tx.SQL().DeleteFrom(table)
+		},
+	}
+}
+
+// concurrentDeleter is the db.Deleter returned by concurrentSQL.
+type concurrentDeleter struct {
+	db.Deleter
+	cs     *concurrentSession
+	replay func(tx db.Session) db.Deleter
+}
+
+// chain returns the concurrentDeleter obtained by applying fn.
+func (cd *concurrentDeleter) chain(fn func(del db.Deleter) db.Deleter) db.Deleter {
+	return &concurrentDeleter{
+		Deleter: fn(cd.Deleter),
+		cs:      cd.cs,
+		replay: func(tx db.Session) db.Deleter {
+			return fn(cd.replay(tx))
+		},
+	}
+}
+
+// Where implements db.Deleter.Where.
+func (cd *concurrentDeleter) Where(v ...interface{}) db.Deleter {
+	return cd.chain(func(del db.Deleter) db.Deleter { return del.Where(v...) })
+}
+
+// And implements db.Deleter.And.
+func (cd *concurrentDeleter) And(v ...interface{}) db.Deleter {
+	return cd.chain(func(del db.Deleter) db.Deleter { return del.And(v...) })
+}
+
+// Limit implements db.Deleter.Limit.
+func (cd *concurrentDeleter) Limit(n int) db.Deleter {
+	return cd.chain(func(del db.Deleter) db.Deleter { return del.Limit(n) })
+}
+
+// Amend implements db.Deleter.Amend.
+func (cd *concurrentDeleter) Amend(fn func(queryIn string) (queryOut string)) db.Deleter {
+	return cd.chain(func(del db.Deleter) db.Deleter { return del.Amend(fn) })
+}
+
+// Exec implements db.Deleter.Exec.
+func (cd *concurrentDeleter) Exec() (sql.Result, error) {
+	return cd.ExecContext(context.Background())
+}
+
+// ExecContext implements db.Deleter.ExecContext.
+func (cd *concurrentDeleter) ExecContext(ctx context.Context) (res sql.Result, err error) {
+	err = cd.cs.Tx(func(tx db.Session) error {
+		res, err = cd.replay(tx).ExecContext(ctx)
+		return err
+	})
+	return
+}
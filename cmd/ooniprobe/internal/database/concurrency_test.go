@@ -0,0 +1,241 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/upper/db/v4"
+)
+
+func TestIsBusy(t *testing.T) {
+	if !IsBusy(fmt.Errorf("wrapped: %w", sqlite3.Error{Code: sqlite3.ErrBusy})) {
+		t.Fatal("expected busy")
+	}
+	if !IsBusy(sqlite3.Error{Code: sqlite3.ErrLocked}) {
+		t.Fatal("expected busy")
+	}
+	if IsBusy(sqlite3.Error{Code: sqlite3.ErrConstraint}) || IsBusy(errors.New("antani")) || IsBusy(nil) {
+		t.Fatal("expected not busy")
+	}
+}
+
+func TestRetryIfBusy(t *testing.T) {
+	t.Run("when the database becomes available", func(t *testing.T) {
+		var count int
+		err := RetryIfBusy(func() error {
+			if count++; count < 3 {
+				return sqlite3.Error{Code: sqlite3.ErrBusy}
+			}
+			return nil
+		})
+		if err != nil || count != 3 {
+			t.Fatal("unexpected result", err, count)
+		}
+	})
+
+	t.Run("when the database is always busy", func(t *testing.T) {
+		var count int
+		err := RetryIfBusy(func() error {
+			count++
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		})
+		if !IsBusy(err) || count != busyMaxAttempts {
+			t.Fatal("unexpected result", err, count)
+		}
+	})
+
+	t.Run("with other errors", func(t *testing.T) {
+		var count int
+		expected := errors.New("mocked error")
+		err := RetryIfBusy(func() error {
+			count++
+			return expected
+		})
+		if !errors.Is(err, expected) || count != 1 {
+			t.Fatal("unexpected result", err, count)
+		}
+	})
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	// Emulate a background submitter and a foreground run,
+	// each one with its own connection to the database.
+	var sessions []db.Session
+	for i := 0; i < 2; i++ {
+		sess, err := Connect(tmpfile.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sess.Close()
+		sessions = append(sessions, sess)
+	}
+
+	var journalMode string
+	err = sessions[0].Driver().(*sql.DB).QueryRow("PRAGMA journal_mode").Scan(&journalMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if journalMode != "wal" {
+		t.Fatal("unexpected journal mode", journalMode)
+	}
+
+	const writes = 50
+	errch := make(chan error, 4*writes)
+	wg := &sync.WaitGroup{}
+	for idx, sess := range sessions {
+		for goroutine := 0; goroutine < 2; goroutine++ {
+			wg.Add(1)
+			go func(sess db.Session, prefix string) {
+				defer wg.Done()
+				for i := 0; i < writes; i++ {
+					loc := &locationInfo{networkName: fmt.Sprintf("%s-%d", prefix, i)}
+					_, err := CreateNetwork(sess, loc)
+					errch <- err
+				}
+			}(sess, fmt.Sprintf("%d-%d", idx, goroutine))
+		}
+	}
+	wg.Wait()
+	close(errch)
+	for err := range errch {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	count, err := sessions[1].Collection("networks").Find().Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4*writes {
+		t.Fatal("unexpected number of networks", count)
+	}
+}
+
+func TestConcurrentSessionFailedInsert(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := Result{TestGroupName: "im", NetworkID: network.ID + 1}
+	if _, err := sess.Collection("results").Insert(result); err == nil {
+		t.Fatal("expected a foreign key error")
+	}
+	_, err = sess.SQL().Exec(`INSERT INTO results (test_group_name, network_id)
+		VALUES (?, ?)`, "im", network.ID+1)
+	if err == nil {
+		t.Fatal("expected a foreign key error")
+	}
+	// Failed statements must not leave the database locked.
+	result.NetworkID = network.ID
+	if _, err := sess.Collection("results").Insert(result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentUpdatesAndDeletes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.sqlite3")
+	var sessions []db.Session
+	for i := 0; i < 2; i++ {
+		sess, err := Connect(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sess.Close()
+		sessions = append(sessions, sess)
+	}
+	network, err := CreateNetwork(sessions[0], &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each goroutine owns four results, which it updates and then
+	// deletes using both the collection and the SQL builder APIs.
+	const goroutines = 8
+	var results [goroutines][4]Result
+	for g := 0; g < goroutines; g++ {
+		for idx := range results[g] {
+			result := &results[g][idx]
+			result.TestGroupName = "websites"
+			result.NetworkID = network.ID
+			result.StartTime = time.Now().UTC()
+			if err := sessions[0].Collection("results").InsertReturning(result); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	errch := make(chan error, 10*goroutines)
+	wg := &sync.WaitGroup{}
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(sess db.Session, owned *[4]Result) {
+			defer wg.Done()
+			for idx := range owned {
+				owned[idx].IsViewed = true
+				errch <- sess.Collection("results").Find("result_id", owned[idx].ID).Update(owned[idx])
+				_, err := sess.SQL().Update("results").Set("result_is_done", true).
+					Where("result_id = ?", owned[idx].ID).Exec()
+				errch <- err
+			}
+			errch <- sess.Collection("results").Find("result_id", owned[0].ID).Delete()
+			_, err := sess.SQL().DeleteFrom("results").Where("result_id = ?", owned[1].ID).Exec()
+			errch <- err
+		}(sessions[g%2], &results[g])
+	}
+	wg.Wait()
+	close(errch)
+	for err := range errch {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	count, err := sessions[1].Collection("results").Find(
+		"result_is_viewed", true).And("result_is_done", true).Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2*goroutines {
+		t.Fatal("unexpected number of results", count)
+	}
+}
+
+func TestConcurrentSessionFailedUpdate(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := Result{TestGroupName: "im", NetworkID: network.ID}
+	if err := sess.Collection("results").InsertReturning(&result); err != nil {
+		t.Fatal(err)
+	}
+	result.NetworkID = network.ID + 1
+	if err := sess.Collection("results").Find("result_id", result.ID).Update(result); err == nil {
+		t.Fatal("expected a foreign key error")
+	}
+	_, err = sess.SQL().Update("results").Set("network_id", network.ID+1).
+		Where("result_id = ?", result.ID).Exec()
+	if err == nil {
+		t.Fatal("expected a foreign key error")
+	}
+	// Failed statements must not leave the database locked.
+	if err := sess.Collection("results").Find("result_id", result.ID).Delete(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,49 +1,25 @@
 package database
 
 import (
-	"context"
 	"database/sql"
 	"embed"
 
 	"github.com/apex/log"
-	"github.com/ooni/probe-cli/v3/internal/netxlite"
-	migrate "github.com/rubenv/sql-migrate"
 	"github.com/upper/db/v4"
 	"github.com/upper/db/v4/adapter/sqlite"
 )
 
-//go:embed migrations/*.sql
+//go:embed migrations/*.sql migrations/postgresql/*.sql
 var efs embed.FS
 
-func readAsset(path string) ([]byte, error) {
-	filep, err := efs.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	return netxlite.ReadAllContext(context.Background(), filep)
-}
-
-func readAssetDir(path string) ([]string, error) {
-	var out []string
-	lst, err := efs.ReadDir(path)
-	if err != nil {
-		return nil, err
-	}
-	for _, e := range lst {
-		out = append(out, e.Name())
-	}
-	return out, nil
-}
-
-// RunMigrations runs the database migrations
+// RunMigrations migrates the database to the latest schema version
 func RunMigrations(sess *sql.DB) error {
 	log.Debugf("running migrations")
-	migrations := &migrate.AssetMigrationSource{
-		Asset:    readAsset,
-		AssetDir: readAssetDir,
-		Dir:      "migrations",
+	migrations, err := loadEmbeddedMigrations(dialectOf(sess))
+	if err != nil {
+		return err
 	}
-	n, err := migrate.Exec(sess, "sqlite3", migrations, migrate.Up)
+	n, err := migrateTo(sess, migrations, len(migrations))
 	if err != nil {
 		return err
 	}
@@ -51,22 +27,57 @@ func RunMigrations(sess *sql.DB) error {
 	return nil
 }
 
-// Connect to the database
+// busyTimeout is the number of milliseconds SQLite waits for a
+// lock held by another connection before failing.
+const busyTimeout = "5000"
+
+// We use github.com/upper/db/v4, the maintained successor of upper.io/db.v3,
+// with its sqlite adapter, which is backed by github.com/mattn/go-sqlite3 and
+// hence requires CGO. We have not moved to database/sql with a pure Go driver
+// such as modernc.org/sqlite because every query in this package uses the
+// upper/db query builder and NewConcurrentSession wraps its interfaces, so
+// such a move would be a rewrite of the package rather than a driver swap.
+
+// Connect to the database. We use WAL journaling, such that readers do
+// not block writers, a busy timeout, such that concurrent writers wait for
+// each other, and immediate transactions, such that transactions acquire
+// the write lock when they begin rather than failing when they try to
+// upgrade a read lock. The returned session also serializes and retries
+// transactions as documented in NewConcurrentSession.
 func Connect(path string) (sess db.Session, err error) {
+	return connect(path, false)
+}
+
+// connect is like Connect but, when exclusive is true, uses a single
+// connection holding an exclusive lock on the database until we close
+// the session, such that other processes cannot use the database.
+func connect(path string, exclusive bool) (sess db.Session, err error) {
 	settings := sqlite.ConnectionURL{
 		Database: path,
-		Options:  map[string]string{"_foreign_keys": "1"},
+		Options: map[string]string{
+			"_busy_timeout": busyTimeout,
+			"_foreign_keys": "1",
+			"_journal_mode": "WAL",
+			"_txlock":       "immediate",
+		},
+	}
+	if exclusive {
+		settings.Options["_locking_mode"] = "EXCLUSIVE"
 	}
 	sess, err = sqlite.Open(settings)
 	if err != nil {
 		log.WithError(err).Error("failed to open the DB")
 		return nil, err
 	}
+	if exclusive {
+		sess.SetMaxOpenConns(1)
+	}
 
 	err = RunMigrations(sess.Driver().(*sql.DB))
 	if err != nil {
 		log.WithError(err).Error("failed to run DB migration")
+		sess.Close()
 		return nil, err
 	}
-	return sess, err
+	return NewConcurrentSession(sess), nil
 }
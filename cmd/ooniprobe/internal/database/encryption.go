@@ -0,0 +1,218 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils/encryption"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+var (
+	// ErrWrongPassphrase indicates that we could not decrypt the
+	// database, most likely because the passphrase is wrong.
+	ErrWrongPassphrase = errors.New("database: wrong passphrase or corrupt file")
+
+	// ErrEmptyPassphrase indicates that the passphrase is empty.
+	ErrEmptyPassphrase = errors.New("database: empty passphrase")
+
+	// ErrEncryptedDatabase indicates that the database is encrypted
+	// but we are trying to open it without a passphrase.
+	ErrEncryptedDatabase = errors.New("database: the database is encrypted")
+
+	// ErrEncryptedMeasurementFile indicates that a measurement file is
+	// encrypted but we are trying to read it without a passphrase.
+	ErrEncryptedMeasurementFile = errors.New("database: the measurement file is encrypted")
+)
+
+// encryptedMagic is the header of encrypted database files.
+var encryptedMagic = []byte("OONIDBE1")
+
+// encryptedMeasurementMagic is the header of encrypted measurement files.
+var encryptedMeasurementMagic = []byte("OONIMSE1")
+
+// EncryptedPath returns the path of the encrypted version of
+// the database whose plaintext path is path.
+func EncryptedPath(path string) string {
+	return path + ".enc"
+}
+
+// encryptData returns the encrypted file content (see encryption.Encrypt).
+func encryptData(passphrase string, data []byte) ([]byte, error) {
+	return encryption.Encrypt(encryptedMagic, passphrase, data)
+}
+
+// decryptData is the inverse of encryptData.
+func decryptData(passphrase string, data []byte) ([]byte, error) {
+	plaintext, err := encryption.Decrypt(encryptedMagic, passphrase, data)
+	if errors.Is(err, encryption.ErrDecrypt) {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, err
+}
+
+// writeFileAtomic writes data into a temporary file and then
+// renames the temporary file to path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// encryptDatabaseFile encrypts the database at path and then removes
+// the plaintext database. We refuse to do that if there is a WAL file,
+// because it means that the database has not been checkpointed.
+func encryptDatabaseFile(path string, passphrase string) error {
+	if utils.FileExists(path + "-wal") {
+		return fmt.Errorf("database: %s-wal exists; not encrypting", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	encrypted, err := encryptData(passphrase, data)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(EncryptedPath(path), encrypted); err != nil {
+		return err
+	}
+	os.Remove(path + "-shm")
+	return os.Remove(path)
+}
+
+// decryptDatabaseFile decrypts the encrypted database into path.
+func decryptDatabaseFile(path string, passphrase string) error {
+	data, err := os.ReadFile(EncryptedPath(path))
+	if err != nil {
+		return err
+	}
+	plaintext, err := decryptData(passphrase, data)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, plaintext)
+}
+
+// encryptMeasurementFiles encrypts the measurement files that are not
+// encrypted, e.g., the ones written before enabling encryption, and returns
+// the number of files it encrypted. We skip the missing files.
+func encryptMeasurementFiles(sess db.Session, keyring *encryption.Keyring) (int, error) {
+	var measurements []Measurement
+	err := sess.Collection("measurements").Find(db.Cond{
+		"measurement_file_path IS NOT": nil,
+		"measurement_is_done":          true,
+	}).All(&measurements)
+	if err != nil {
+		return 0, errors.Wrap(err, "listing measurements")
+	}
+	var count int
+	for _, msmt := range measurements {
+		path := msmt.MeasurementFilePath.String
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		if bytes.HasPrefix(data, encryptedMeasurementMagic) {
+			continue
+		}
+		if data, err = decompressData(data); err != nil {
+			return count, errors.Wrapf(err, "decompressing %s", path)
+		}
+		if err := WriteMeasurementFile(path, data, keyring); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// recoverPlaintextDatabase encrypts the plaintext database at path, which
+// exists because a previous run crashed or was killed before encrypting it,
+// or because the database was not encrypted before. In the latter case, we
+// also encrypt the measurement files before encrypting the database, such
+// that we encrypt them again if we are interrupted. We refuse to encrypt
+// the plaintext database when passphrase does not decrypt the encrypted
+// database, if any, or when another process is using the database.
+func recoverPlaintextDatabase(path string, passphrase string, keyring *encryption.Keyring) error {
+	encrypted, err := os.ReadFile(EncryptedPath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if _, err := decryptData(passphrase, encrypted); err != nil {
+			return err
+		}
+	}
+	// Because of the exclusive lock, connecting fails if another process
+	// is using the database, and closing checkpoints the WAL file.
+	sess, err := connect(path, true)
+	if err != nil {
+		return err
+	}
+	if encrypted == nil {
+		if _, err := encryptMeasurementFiles(sess, keyring); err != nil {
+			sess.Close()
+			return err
+		}
+	}
+	if err := sess.Close(); err != nil {
+		return err
+	}
+	return encryptDatabaseFile(path, passphrase)
+}
+
+// OpenEncrypted is like Open but keeps the database encrypted with a key
+// derived from passphrase while we are not using it. We decrypt the file
+// at EncryptedPath(path) into path, use path, and, when the database is
+// closed, we encrypt path again and remove it. We also encrypt the files
+// containing the measurements (see WriteMeasurementFile).
+//
+// Because the plaintext database exists on disk while ooniprobe is running,
+// this protects the database at rest, e.g., when the device is seized while
+// ooniprobe is not running, and not against a running attacker. To prevent
+// another process from removing the plaintext database while we are using
+// it, we hold an exclusive lock on it.
+//
+// When the plaintext database already exists, either because a previous run
+// crashed or was killed, or because the database was not encrypted before, we
+// encrypt it before doing anything else (see recoverPlaintextDatabase), such
+// that the plaintext database does not outlive the next run.
+func OpenEncrypted(path string, passphrase string) (*Database, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	keyring := encryption.NewKeyring(passphrase)
+	if utils.FileExists(path) {
+		log.Warnf("database: found plaintext database at %s; encrypting it", path)
+		if err := recoverPlaintextDatabase(path, passphrase, keyring); err != nil {
+			return nil, err
+		}
+	}
+	if utils.FileExists(EncryptedPath(path)) {
+		if err := decryptDatabaseFile(path, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	sess, err := connect(path, true)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	d := NewDatabase(sess)
+	d.passphrase = passphrase
+	d.keyring = keyring
+	d.afterClose = func() error {
+		return encryptDatabaseFile(path, passphrase)
+	}
+	return d, nil
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+)
+
+func TestEncryptData(t *testing.T) {
+	plaintext := []byte("SQLite format 3")
+	data, err := encryptData("antani", plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, plaintext) {
+		t.Fatal("expected the data to be encrypted")
+	}
+	decrypted, err := decryptData("antani", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatal("unexpected plaintext", decrypted)
+	}
+	if _, err := decryptData("mascetti", data); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatal("not the error we expected", err)
+	}
+	data[len(data)-1] ^= 1
+	if _, err := decryptData("antani", data); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatal("not the error we expected", err)
+	}
+	if _, err := decryptData("antani", plaintext); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestOpenEncrypted(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	path := filepath.Join(tmpdir, "main.sqlite3")
+
+	if _, err := OpenEncrypted(path, ""); !errors.Is(err, ErrEmptyPassphrase) {
+		t.Fatal("not the error we expected", err)
+	}
+
+	d, err := OpenEncrypted(path, "antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := d.CreateNetwork(&locationInfo{asn: 30722, countryCode: "IT", networkName: "Vodafone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.CreateResult(tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.CreateMeasurement(sql.NullString{}, "web_connectivity", tmpdir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process cannot use the database while we're using it.
+	if _, err := OpenEncrypted(path, "antani"); err == nil {
+		t.Fatal("expected an error here")
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if utils.FileExists(path) || !utils.FileExists(EncryptedPath(path)) {
+		t.Fatal("expected only the encrypted database to exist")
+	}
+	if _, err := Open(path); !errors.Is(err, ErrEncryptedDatabase) {
+		t.Fatal("not the error we expected", err)
+	}
+	if _, err := OpenEncrypted(path, "mascetti"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatal("not the error we expected", err)
+	}
+	if utils.FileExists(path) {
+		t.Fatal("expected no plaintext database")
+	}
+
+	d, err = OpenEncrypted(path, "antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, incomplete, err := d.ListResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done)+len(incomplete) != 1 || incomplete[0].Network.ASN != 30722 {
+		t.Fatal("unexpected results", done, incomplete)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenEncryptedAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.sqlite3")
+	d, err := OpenEncrypted(path, "antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	d, err = OpenEncrypted(path, "antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.CreateNetwork(&locationInfo{asn: 30722, countryCode: "IT"}); err != nil {
+		t.Fatal(err)
+	}
+	// Emulate a crash, which leaves the plaintext database on disk.
+	d.afterClose = nil
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.FileExists(path) {
+		t.Fatal("expected the plaintext database to exist")
+	}
+
+	// We must not encrypt the plaintext database with the wrong passphrase.
+	if _, err := OpenEncrypted(path, "mascetti"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatal("not the error we expected", err)
+	}
+	if !utils.FileExists(path) {
+		t.Fatal("expected the plaintext database to exist")
+	}
+
+	d, err = OpenEncrypted(path, "antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var networks []Network
+	if err := d.sess.Collection("networks").Find().All(&networks); err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 1 || networks[0].ASN != 30722 {
+		t.Fatal("expected to keep the data written before the crash", networks)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if utils.FileExists(path) || !utils.FileExists(EncryptedPath(path)) {
+		t.Fatal("expected only the encrypted database to exist")
+	}
+}
+
+func TestOpenEncryptedMeasurementFiles(t *testing.T) {
+	tmpdir := t.TempDir()
+	path := filepath.Join(tmpdir, "main.sqlite3")
+	data := []byte(`{"test_name":"web_connectivity"}` + "\n")
+	createMeasurement := func(d *Database) *Measurement {
+		network, err := d.CreateNetwork(&locationInfo{asn: 30722, countryCode: "IT"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := d.CreateResult(tmpdir, "websites", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msmt, err := d.CreateMeasurement(sql.NullString{}, "web_connectivity",
+			result.MeasurementDir, int(result.ID), result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.CompleteMeasurement(msmt, data, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		return msmt
+	}
+
+	// Create a measurement before enabling encryption.
+	d, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := createMeasurement(d)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadMeasurementFile(legacy.MeasurementFilePath.String, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = OpenEncrypted(path, "antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for _, msmt := range []*Measurement{legacy, createMeasurement(d)} {
+		name := msmt.MeasurementFilePath.String
+		raw, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(raw, encryptedMeasurementMagic) {
+			t.Fatal("expected an encrypted measurement file", name)
+		}
+		if _, err := ReadMeasurementFile(name, nil); !errors.Is(err, ErrEncryptedMeasurementFile) {
+			t.Fatal("not the error we expected", err)
+		}
+		out, err := d.ReadMeasurementFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Fatal("unexpected measurement file content", out)
+		}
+	}
+}
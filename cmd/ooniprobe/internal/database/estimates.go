@@ -0,0 +1,52 @@
+package database
+
+import (
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// DataUsageEstimate estimates the data usage of the measurements of a
+// test group using the data usage of the previous results of the group.
+type DataUsageEstimate struct {
+	// ResultCount is the number of previous results we used.
+	ResultCount uint64
+
+	// MeasurementCount is the number of measurements of these results.
+	MeasurementCount uint64
+
+	// KibiBytesPerMeasurement is the average data usage, both upload
+	// and download, of a measurement. It is zero when ResultCount is zero.
+	KibiBytesPerMeasurement float64
+}
+
+// EstimateDataUsage estimates the data usage of the measurements of the
+// given test group, using the done results of the group with measurements.
+func EstimateDataUsage(sess db.Session, groupName string) (*DataUsageEstimate, error) {
+	var results []struct {
+		DataUsage        float64 `db:"data_usage"`
+		MeasurementCount uint64  `db:"measurement_count"`
+	}
+	err := sess.SQL().Select(
+		db.Raw("results.result_data_usage_up + results.result_data_usage_down AS data_usage"),
+		db.Raw("COUNT(measurements.measurement_id) AS measurement_count"),
+	).From("results").
+		Join("measurements").On("measurements.result_id = results.result_id").
+		Where("results.test_group_name = ?", groupName).
+		And("results.result_is_done = true").
+		GroupBy(db.Raw("results.result_id")).
+		All(&results)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the results data usage")
+	}
+	estimate := &DataUsageEstimate{}
+	var total float64
+	for _, r := range results {
+		estimate.ResultCount++
+		estimate.MeasurementCount += r.MeasurementCount
+		total += r.DataUsage
+	}
+	if estimate.MeasurementCount > 0 {
+		estimate.KibiBytesPerMeasurement = total / float64(estimate.MeasurementCount)
+	}
+	return estimate, nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestEstimateDataUsage(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newResult := func(group string, measurements int, dataUsage float64, done bool) {
+		result, err := CreateResult(sess, tmpdir, group, network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for idx := 0; idx < measurements; idx++ {
+			_, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity",
+				result.MeasurementDir, idx, result.ID, sql.NullInt64{})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		result.DataUsageUp = dataUsage / 4
+		result.DataUsageDown = dataUsage * 3 / 4
+		if done {
+			if err := result.Finished(sess); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := sess.Collection("results").Find("result_id", result.ID).Update(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	estimate, err := EstimateDataUsage(sess, "websites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.ResultCount != 0 || estimate.KibiBytesPerMeasurement != 0 {
+		t.Fatal("unexpected estimate", estimate)
+	}
+
+	newResult("websites", 2, 100, true)
+	newResult("websites", 3, 400, true)
+	newResult("websites", 0, 1000, true) // no measurements
+	newResult("websites", 10, 10000, false)
+	newResult("im", 4, 1000, true)
+	estimate, err = EstimateDataUsage(sess, "websites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.ResultCount != 2 || estimate.MeasurementCount != 5 {
+		t.Fatal("unexpected estimate", estimate)
+	}
+	if estimate.KibiBytesPerMeasurement != 100 {
+		t.Fatal("unexpected data usage", estimate.KibiBytesPerMeasurement)
+	}
+}
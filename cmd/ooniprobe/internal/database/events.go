@@ -0,0 +1,143 @@
+package database
+
+//
+// Lifecycle events.
+//
+// The events table is a timeline of what the probe did (e.g., when it
+// started and stopped running, how check-ins went), which is useful to
+// audit a probe running in unattended mode. Each event has a JSON object
+// with details depending on its kind.
+//
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// The kinds of events.
+const (
+	// EventRunStarted is the start of the run of a test group.
+	EventRunStarted = "run_started"
+
+	// EventRunStopped is the end of the run of a test group.
+	EventRunStopped = "run_stopped"
+
+	// EventCheckIn is the outcome of a check-in with the backend.
+	EventCheckIn = "check_in"
+
+	// EventLocationChanged is a change of the probe ASN or country.
+	EventLocationChanged = "location_changed"
+
+	// EventUploadBatch is the outcome of uploading the measurements
+	// of a test, which we only record when uploading is enabled.
+	EventUploadBatch = "upload_batch"
+)
+
+// RecordEvent records an event of the given kind with the given details,
+// which must serialize to a JSON object, or be nil.
+func RecordEvent(sess db.Session, kind string, resultID sql.NullInt64, details interface{}) (*Event, error) {
+	data := []byte("{}")
+	if details != nil {
+		var err error
+		if data, err = json.Marshal(details); err != nil {
+			return nil, errors.Wrap(err, "serializing event details")
+		}
+	}
+	event := Event{
+		Time:     time.Now().UTC(),
+		Kind:     kind,
+		ResultID: resultID,
+		Details:  string(data),
+	}
+	newID, err := sess.Collection("events").Insert(event)
+	if err != nil {
+		return nil, errors.Wrap(err, "recording event")
+	}
+	event.ID = newID.ID().(int64)
+	return &event, nil
+}
+
+// LocationDetails contains the details of an EventLocationChanged event.
+type LocationDetails struct {
+	ASN         uint   `json:"asn"`
+	CountryCode string `json:"country_code"`
+	NetworkName string `json:"network_name"`
+}
+
+// RecordLocation records an EventLocationChanged event when the ASN or the
+// country code of the given network differ from the ones of the previous
+// location event, or when there is no previous location event. It returns
+// whether it recorded an event.
+func RecordLocation(sess db.Session, network *Network, resultID sql.NullInt64) (bool, error) {
+	current := LocationDetails{
+		ASN:         network.ASN,
+		CountryCode: network.CountryCode,
+		NetworkName: network.NetworkName,
+	}
+	changed := false
+	err := sess.Tx(func(tx db.Session) error {
+		var previous Event
+		err := tx.Collection("events").Find("event_kind", EventLocationChanged).
+			OrderBy("-event_time", "-event_id").One(&previous)
+		if err != nil && err != db.ErrNoMoreRows {
+			return errors.Wrap(err, "finding the previous location")
+		}
+		if err == nil {
+			var details LocationDetails
+			if err := json.Unmarshal([]byte(previous.Details), &details); err != nil {
+				return errors.Wrap(err, "parsing the previous location")
+			}
+			if details.ASN == current.ASN && details.CountryCode == current.CountryCode {
+				return nil
+			}
+		}
+		changed = true
+		_, err = RecordEvent(tx, EventLocationChanged, resultID, current)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return changed, nil
+}
+
+// EventFilter selects the events to list. The zero value selects
+// all the events.
+type EventFilter struct {
+	// Since OPTIONALLY selects the events at or after this time.
+	Since time.Time
+
+	// Kind OPTIONALLY selects the events of this kind.
+	Kind string
+
+	// Limit OPTIONALLY selects at most this number of events,
+	// starting from the most recent ones.
+	Limit int
+}
+
+// ListEvents returns the events matching the given filter from the oldest.
+func ListEvents(sess db.Session, filter *EventFilter) ([]Event, error) {
+	cond := db.Cond{}
+	if !filter.Since.IsZero() {
+		cond["event_time >="] = filter.Since.UTC()
+	}
+	if filter.Kind != "" {
+		cond["event_kind"] = filter.Kind
+	}
+	events := []Event{}
+	res := sess.Collection("events").Find(cond).OrderBy("-event_time", "-event_id")
+	if filter.Limit > 0 {
+		res = res.Limit(filter.Limit)
+	}
+	if err := res.All(&events); err != nil {
+		return events, errors.Wrap(err, "failed to list events")
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+
+	t.Run("RecordEvent", func(t *testing.T) {
+		event, err := RecordEvent(sess, EventRunStarted, sql.NullInt64{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.ID <= 0 || event.Details != "{}" {
+			t.Fatal("unexpected event", event)
+		}
+		network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := CreateResult(sess, t.TempDir(), "im", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		details := map[string]int{"uploaded": 3}
+		event, err = RecordEvent(sess, EventUploadBatch, sql.NullInt64{Int64: result.ID, Valid: true}, details)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Details != `{"uploaded":3}` {
+			t.Fatal("unexpected details", event.Details)
+		}
+		if _, err := RecordEvent(sess, EventCheckIn, sql.NullInt64{}, make(chan int)); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("RecordLocation", func(t *testing.T) {
+		milan := &Network{ASN: 30722, CountryCode: "IT", NetworkName: "Vodafone"}
+		expectations := []struct {
+			network *Network
+			changed bool
+		}{
+			{network: milan, changed: true},
+			{network: milan, changed: false},
+			{network: &Network{ASN: 30722, CountryCode: "IT", NetworkName: "Renamed"}, changed: false},
+			{network: &Network{ASN: 3269, CountryCode: "IT"}, changed: true},
+			{network: milan, changed: true},
+		}
+		for idx, e := range expectations {
+			changed, err := RecordLocation(sess, e.network, sql.NullInt64{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed != e.changed {
+				t.Fatal("unexpected changed for", idx, changed)
+			}
+		}
+		events, err := ListEvents(sess, &EventFilter{Kind: EventLocationChanged})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 3 {
+			t.Fatal("unexpected events", events)
+		}
+		var details LocationDetails
+		if err := json.Unmarshal([]byte(events[1].Details), &details); err != nil {
+			t.Fatal(err)
+		}
+		if details.ASN != 3269 || details.CountryCode != "IT" {
+			t.Fatal("unexpected details", details)
+		}
+	})
+
+	t.Run("ListEvents", func(t *testing.T) {
+		events, err := ListEvents(sess, &EventFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 5 {
+			t.Fatal("unexpected events", events)
+		}
+		if events[0].Kind != EventRunStarted || events[4].Kind != EventLocationChanged {
+			t.Fatal("unexpected order", events)
+		}
+		events, err = ListEvents(sess, &EventFilter{Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 2 || events[0].ID >= events[1].ID || events[1].Kind != EventLocationChanged {
+			t.Fatal("unexpected events", events)
+		}
+		events, err = ListEvents(sess, &EventFilter{Since: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 0 {
+			t.Fatal("unexpected events", events)
+		}
+	})
+}
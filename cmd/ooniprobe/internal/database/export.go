@@ -0,0 +1,380 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils/encryption"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// ResultsCSVHeader is the header of the CSV produced by ExportResultsCSV.
+var ResultsCSVHeader = []string{
+	"result_id",
+	"test_group_name",
+	"result_start_time",
+	"result_runtime",
+	"result_is_done",
+	"result_is_uploaded",
+	"result_data_usage_up",
+	"result_data_usage_down",
+	"network_name",
+	"network_type",
+	"asn",
+	"network_country_code",
+	"anomaly_count",
+	"total_count",
+	"result_link_type",
+	"result_downlink_kbps",
+	"result_software_name",
+	"result_software_version",
+	"result_engine_version",
+	"result_platform",
+}
+
+// MeasurementsCSVHeader is the header of the CSV produced
+// by ExportMeasurementsCSV.
+var MeasurementsCSVHeader = []string{
+	"measurement_id",
+	"result_id",
+	"test_group_name",
+	"test_name",
+	"measurement_start_time",
+	"measurement_runtime",
+	"url",
+	"category_code",
+	"url_country_code",
+	"network_name",
+	"network_type",
+	"asn",
+	"network_country_code",
+	"is_anomaly",
+	"measurement_is_failed",
+	"measurement_failure_msg",
+	"measurement_is_uploaded",
+	"report_id",
+	"collector_address",
+	"test_keys",
+	"annotations",
+}
+
+// MeasurementFilter selects measurements. The zero value
+// selects all the measurements.
+type MeasurementFilter struct {
+	// ResultID is the OPTIONAL ID of the result
+	// containing the measurements.
+	ResultID int64
+
+	// Since OPTIONALLY selects the measurements
+	// started at or after the given time.
+	Since time.Time
+
+	// Until OPTIONALLY selects the measurements
+	// started before the given time.
+	Until time.Time
+
+	// TestName is the OPTIONAL name of the test.
+	TestName string
+
+	// AnomalyOnly OPTIONALLY selects the anomalous measurements.
+	AnomalyOnly bool
+
+	// FailedOnly OPTIONALLY selects the failed measurements. When
+	// AnomalyOnly is also set, we select the measurements that are
+	// either anomalous or failed.
+	FailedOnly bool
+
+	// ASN is the OPTIONAL ASN of the network.
+	ASN uint
+
+	// CountryCode is the OPTIONAL country code of the network.
+	CountryCode string
+
+	// Annotations OPTIONALLY selects the measurements having
+	// all the given key-value annotations.
+	Annotations map[string]string
+}
+
+// cond returns the conditions selecting the measurements.
+func (f *MeasurementFilter) cond() db.Cond {
+	cond := db.Cond{}
+	if f.ResultID > 0 {
+		cond["results.result_id"] = f.ResultID
+	}
+	if !f.Since.IsZero() {
+		cond["measurements.measurement_start_time >="] = f.Since.UTC()
+	}
+	if !f.Until.IsZero() {
+		cond["measurements.measurement_start_time <"] = f.Until.UTC()
+	}
+	if f.TestName != "" {
+		cond["measurements.test_name"] = f.TestName
+	}
+	if f.AnomalyOnly && !f.FailedOnly {
+		cond["measurements.is_anomaly"] = true
+	}
+	if f.FailedOnly && !f.AnomalyOnly {
+		cond["measurements.measurement_is_failed"] = true
+	}
+	if f.ASN > 0 {
+		cond["networks.asn"] = f.ASN
+	}
+	if f.CountryCode != "" {
+		cond["networks.network_country_code"] = f.CountryCode
+	}
+	return cond
+}
+
+// selectMeasurements returns a query selecting the given columns of the
+// measurements selected by the filter joined with their result, network,
+// and URL.
+func selectMeasurements(sess db.Session, filter *MeasurementFilter, columns ...interface{}) db.Selector {
+	req := sess.SQL().Select(columns...).From("results").
+		Join("measurements").On("results.result_id = measurements.result_id").
+		Join("networks").On("results.network_id = networks.network_id").
+		LeftJoin("urls").On("urls.url_id = measurements.url_id")
+	if cond := filter.cond(); len(cond) > 0 {
+		req = req.Where(cond)
+	}
+	if filter.AnomalyOnly && filter.FailedOnly {
+		req = req.And(db.Or(
+			db.Cond{"measurements.is_anomaly": true},
+			db.Cond{"measurements.measurement_is_failed": true},
+		))
+	}
+	keys := make([]string, 0, len(filter.Annotations))
+	for key := range filter.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // make the query deterministic
+	for _, key := range keys {
+		req = req.And(`measurements.measurement_id IN (SELECT measurement_id
+			FROM measurement_annotations WHERE annotation_key = ? AND annotation_value = ?)`,
+			key, filter.Annotations[key])
+	}
+	return req
+}
+
+// ListMeasurementsMatching returns the measurements selected by the
+// filter joined with their result, network, and URL.
+func ListMeasurementsMatching(sess db.Session, filter *MeasurementFilter) ([]MeasurementURLNetwork, error) {
+	measurements := []MeasurementURLNetwork{}
+	req := selectMeasurements(sess, filter,
+		db.Raw("networks.*"),
+		db.Raw("urls.*"),
+		db.Raw("measurements.*"),
+		db.Raw("results.*"),
+	).OrderBy("measurements.measurement_start_time")
+	if err := req.All(&measurements); err != nil {
+		return measurements, errors.Wrap(err, "failed to list measurements")
+	}
+	if err := loadMeasurementAnnotations(sess, measurements); err != nil {
+		return measurements, err
+	}
+	if err := loadRerunOriginals(sess, measurements); err != nil {
+		return measurements, err
+	}
+	return measurements, nil
+}
+
+// ListAllMeasurements returns the measurements of all the results
+// joined with their result, network, and URL.
+func ListAllMeasurements(sess db.Session) ([]MeasurementURLNetwork, error) {
+	return ListMeasurementsMatching(sess, &MeasurementFilter{})
+}
+
+// formatCSVTime formats a time for inclusion into a CSV file.
+func formatCSVTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatCSVFloat formats a float for inclusion into a CSV file.
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatCSVNullBool formats a nullable bool for inclusion into a
+// CSV file using an empty string when the value is NULL.
+func formatCSVNullBool(v bool, valid bool) string {
+	if !valid {
+		return ""
+	}
+	return strconv.FormatBool(v)
+}
+
+// formatCSVNullFloat formats a nullable float for inclusion into a
+// CSV file using an empty string when the value is NULL.
+func formatCSVNullFloat(v float64, valid bool) string {
+	if !valid {
+		return ""
+	}
+	return formatCSVFloat(v)
+}
+
+// ExportResultsCSV writes into w a CSV file containing a row for each
+// result, including its network and a summary of its measurements.
+func ExportResultsCSV(sess db.Session, w io.Writer) error {
+	doneResults, incompleteResults, err := ListResults(sess)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ResultsCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range append(doneResults, incompleteResults...) {
+		err := writer.Write([]string{
+			strconv.FormatInt(r.Result.ID, 10),
+			r.TestGroupName,
+			formatCSVTime(r.Result.StartTime),
+			formatCSVFloat(r.Result.Runtime),
+			strconv.FormatBool(r.IsDone),
+			strconv.FormatBool(r.Result.IsUploaded),
+			formatCSVFloat(r.DataUsageUp),
+			formatCSVFloat(r.DataUsageDown),
+			r.NetworkName,
+			r.NetworkType,
+			strconv.FormatUint(uint64(r.ASN), 10),
+			r.Network.CountryCode,
+			strconv.FormatUint(r.AnomalyCount, 10),
+			strconv.FormatUint(r.TotalCount, 10),
+			r.LinkType.String,
+			formatCSVNullFloat(r.DownlinkKbps.Float64, r.DownlinkKbps.Valid),
+			r.SoftwareName.String,
+			r.SoftwareVersion.String,
+			r.EngineVersion.String,
+			r.Platform.String,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatCSVAnnotations formats annotations for inclusion into a CSV
+// file as a JSON object, or as the empty string when there are none.
+func formatCSVAnnotations(annotations map[string]string) (string, error) {
+	if len(annotations) <= 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(annotations)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ExportMeasurementsCSV writes into w a CSV file containing a row for
+// each measurement, including its result, network, URL, and summary.
+func ExportMeasurementsCSV(sess db.Session, w io.Writer) error {
+	measurements, err := ListAllMeasurements(sess)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(MeasurementsCSVHeader); err != nil {
+		return err
+	}
+	for _, m := range measurements {
+		annotations, err := formatCSVAnnotations(m.Annotations)
+		if err != nil {
+			return err
+		}
+		err = writer.Write([]string{
+			strconv.FormatInt(m.Measurement.ID, 10),
+			strconv.FormatInt(m.Measurement.ResultID, 10),
+			m.TestGroupName,
+			m.TestName,
+			formatCSVTime(m.Measurement.StartTime),
+			formatCSVFloat(m.Measurement.Runtime),
+			m.URL.URL.String,
+			m.CategoryCode.String,
+			m.URL.CountryCode.String,
+			m.NetworkName,
+			m.NetworkType,
+			strconv.FormatUint(uint64(m.ASN), 10),
+			m.Network.CountryCode,
+			formatCSVNullBool(m.IsAnomaly.Bool, m.IsAnomaly.Valid),
+			strconv.FormatBool(m.Measurement.IsFailed),
+			m.Measurement.FailureMsg.String,
+			strconv.FormatBool(m.Measurement.IsUploaded),
+			m.ReportID.String,
+			m.CollectorAddress.String,
+			m.TestKeys,
+			annotations,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportMeasurementsJSONL writes into w the raw JSON of each measurement
+// selected by the filter, one measurement per line, and returns the
+// number of measurements it wrote. We skip the measurements whose raw
+// JSON is not available on disk, e.g., because they failed. We use keyring
+// to read encrypted measurement files (see ReadMeasurementFile).
+func ExportMeasurementsJSONL(sess db.Session, filter *MeasurementFilter, w io.Writer, keyring *encryption.Keyring) (int, error) {
+	measurements, err := ListMeasurementsMatching(sess, filter)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	for _, m := range measurements {
+		if !m.MeasurementFilePath.Valid {
+			log.Warnf("measurement #%d has no measurement file", m.Measurement.ID)
+			continue
+		}
+		data, err := ReadMeasurementFile(m.MeasurementFilePath.String, keyring)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Warnf("measurement #%d: %s", m.Measurement.ID, err.Error())
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		n, err := writeJSONL(w, data)
+		if err != nil {
+			return count, errors.Wrapf(err, "measurement #%d", m.Measurement.ID)
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// writeJSONL writes each JSON document in data on its own line and
+// returns the number of documents it wrote.
+func writeJSONL(w io.Writer, data []byte) (int, error) {
+	var count int
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) <= 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, line); err != nil {
+			return count, err
+		}
+		buf.WriteByte('\n')
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, scanner.Err()
+}
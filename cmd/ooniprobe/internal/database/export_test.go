@@ -0,0 +1,216 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportCSV(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location := locationInfo{
+		asn:         30722,
+		countryCode: "IT",
+		networkName: "Vodafone Italia",
+	}
+	network, err := CreateNetwork(sess, &location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	urlID, err := CreateOrUpdateURL(sess, "https://example.com/", "MISC", "XX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msmt, err := CreateMeasurement(sess, sql.NullString{String: "_id", Valid: true},
+		"web_connectivity", tmpdir, 0, result.ID, sql.NullInt64{Int64: urlID, Valid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msmt.IsAnomaly = sql.NullBool{Valid: true, Bool: true}
+	if err := sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("results", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportResultsCSV(sess, &buf); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 {
+			t.Fatal("expected header and one result", len(records))
+		}
+		if diff := cmp.Diff(ResultsCSVHeader, records[0]); diff != "" {
+			t.Fatal(diff)
+		}
+		row := records[1]
+		if row[1] != "websites" || row[8] != "Vodafone Italia" || row[10] != "30722" {
+			t.Fatal("unexpected row", row)
+		}
+		if row[12] != "1" || row[13] != "1" {
+			t.Fatal("unexpected counts", row)
+		}
+	})
+
+	t.Run("measurements", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := ExportMeasurementsCSV(sess, &buf); err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 {
+			t.Fatal("expected header and one measurement", len(records))
+		}
+		if diff := cmp.Diff(MeasurementsCSVHeader, records[0]); diff != "" {
+			t.Fatal(diff)
+		}
+		row := records[1]
+		if row[3] != "web_connectivity" || row[6] != "https://example.com/" || row[7] != "MISC" {
+			t.Fatal("unexpected row", row)
+		}
+		if row[13] != "true" || row[17] != "_id" {
+			t.Fatal("unexpected row", row)
+		}
+	})
+}
+
+func TestExportMeasurementsJSONL(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location := locationInfo{
+		asn:         0,
+		countryCode: "IT",
+		networkName: "Unknown",
+	}
+	network, err := CreateNetwork(sess, &location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, testName := range []string{"telegram", "signal", "whatsapp"} {
+		msmt, err := CreateMeasurement(sess, sql.NullString{}, testName,
+			result.MeasurementDir, idx, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if testName == "whatsapp" {
+			continue // emulate a measurement without file
+		}
+		data := "{ \"test_name\": \"" + testName + "\" }\n"
+		if err := os.WriteFile(msmt.MeasurementFilePath.String, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("without filter", func(t *testing.T) {
+		var buf bytes.Buffer
+		count, err := ExportMeasurementsJSONL(sess, &MeasurementFilter{}, &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Fatal("unexpected number of measurements", count)
+		}
+		expected := "{\"test_name\":\"telegram\"}\n{\"test_name\":\"signal\"}\n"
+		if diff := cmp.Diff(expected, buf.String()); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with test name", func(t *testing.T) {
+		var buf bytes.Buffer
+		count, err := ExportMeasurementsJSONL(sess, &MeasurementFilter{TestName: "signal"}, &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 || !strings.Contains(buf.String(), "signal") {
+			t.Fatal("unexpected export", count, buf.String())
+		}
+	})
+
+	t.Run("with date range", func(t *testing.T) {
+		var buf bytes.Buffer
+		filter := &MeasurementFilter{Until: time.Now().Add(-24 * time.Hour)}
+		count, err := ExportMeasurementsJSONL(sess, filter, &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 || buf.Len() != 0 {
+			t.Fatal("unexpected export", count, buf.String())
+		}
+	})
+
+	t.Run("with result ID", func(t *testing.T) {
+		var buf bytes.Buffer
+		count, err := ExportMeasurementsJSONL(sess, &MeasurementFilter{ResultID: result.ID + 1}, &buf, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatal("unexpected number of measurements", count)
+		}
+	})
+
+	t.Run("with invalid JSON", func(t *testing.T) {
+		err := os.WriteFile(filepath.Join(result.MeasurementDir, "msmt-telegram-0.json.gz"), []byte("{"), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := ExportMeasurementsJSONL(sess, &MeasurementFilter{}, &buf, nil); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package database
+
+//
+// Importing measurements from the OONI API.
+//
+// A reinstalled probe has an empty database. To give the user some
+// historical context, we fetch the metadata of the measurements they
+// previously published from the OONI API (see the ListMeasurements
+// method of probeservices.Client) and store it locally. We do
+// not download the raw measurements, because GetMeasurementJSON already
+// fetches them from the OONI API for uploaded measurements.
+//
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// importTestGroups maps test names to the test groups in nettests.All.
+var importTestGroups = map[string]string{
+	"web_connectivity":               "websites",
+	"dash":                           "performance",
+	"ndt":                            "performance",
+	"http_invalid_request_line":      "middlebox",
+	"http_header_field_manipulation": "middlebox",
+	"facebook_messenger":             "im",
+	"telegram":                       "im",
+	"whatsapp":                       "im",
+	"signal":                         "im",
+	"psiphon":                        "circumvention",
+	"tor":                            "circumvention",
+	"dnscheck":                       "experimental",
+	"stunreachability":               "experimental",
+	"torsf":                          "experimental",
+	"vanilla_tor":                    "experimental",
+}
+
+// apiLocation implements enginex.LocationProvider for imported measurements.
+type apiLocation struct {
+	asn         uint
+	countryCode string
+}
+
+func (loc *apiLocation) ProbeASN() uint           { return loc.asn }
+func (loc *apiLocation) ProbeASNString() string   { return fmt.Sprintf("AS%d", loc.asn) }
+func (loc *apiLocation) ProbeCC() string          { return loc.countryCode }
+func (loc *apiLocation) ProbeIP() string          { return "" }
+func (loc *apiLocation) ProbeNetworkName() string { return "" }
+func (loc *apiLocation) ResolverIP() string       { return "" }
+
+// parseAPITime parses the times returned by the OONI API.
+func parseAPITime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse("2006-01-02 15:04:05", s)
+	}
+	return t.UTC(), err
+}
+
+// isImported returns whether we already have the given measurement.
+func isImported(sess db.Session, m *probeservices.MeasurementListEntry) (bool, error) {
+	var entry struct {
+		Count int64 `db:"count"`
+	}
+	err := sess.SQL().Select(db.Raw("COUNT(*) AS count")).
+		From("measurements").
+		LeftJoin("urls").On("urls.url_id = measurements.url_id").
+		Where("measurements.report_id = ?", m.ReportID).
+		And("measurements.test_name = ?", m.TestName).
+		And("COALESCE(urls.url, '') = ?", NormalizeURL(m.Input)).
+		One(&entry)
+	if err != nil {
+		return false, errors.Wrap(err, "checking whether the measurement exists")
+	}
+	return entry.Count > 0, nil
+}
+
+// findOrCreateNetwork returns a network with the given ASN and country
+// code, which we create if we do not have one yet.
+func findOrCreateNetwork(sess db.Session, loc *apiLocation) (*Network, error) {
+	var network Network
+	err := sess.Collection("networks").Find(db.Cond{
+		"asn":                  loc.asn,
+		"network_country_code": loc.countryCode,
+	}).OrderBy("network_id").One(&network)
+	if err == db.ErrNoMoreRows {
+		return CreateNetwork(sess, loc)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "finding network")
+	}
+	return &network, nil
+}
+
+// ImportMeasurements stores the given measurements, which we usually
+// obtain using probeservices.Client.ListMeasurements, and returns the number of measurements
+// it stored. We create a result for each report and we skip the
+// measurements we already have and those of unknown nettests.
+func ImportMeasurements(sess db.Session, homePath string, measurements []*probeservices.MeasurementListEntry) (int, error) {
+	var reportIDs []string
+	reports := make(map[string][]*probeservices.MeasurementListEntry)
+	for _, m := range measurements {
+		if _, found := importTestGroups[m.TestName]; !found {
+			log.Warnf("skipping measurement of unknown nettest: %s", m.TestName)
+			continue
+		}
+		exists, err := isImported(sess, m)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			continue
+		}
+		if _, found := reports[m.ReportID]; !found {
+			reportIDs = append(reportIDs, m.ReportID)
+		}
+		reports[m.ReportID] = append(reports[m.ReportID], m)
+	}
+	var count int
+	for _, reportID := range reportIDs {
+		n, err := importReport(sess, homePath, reports[reportID])
+		count += n
+		if err != nil {
+			return count, errors.Wrapf(err, "importing report %s", reportID)
+		}
+	}
+	return count, nil
+}
+
+// importReport stores the measurements of a single report into a new
+// result and returns the number of measurements it stored.
+func importReport(sess db.Session, homePath string, measurements []*probeservices.MeasurementListEntry) (int, error) {
+	first := measurements[0]
+	asn, err := strconv.ParseUint(strings.TrimPrefix(first.ProbeASN, "AS"), 10, 32)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing ASN %s", first.ProbeASN)
+	}
+	network, err := findOrCreateNetwork(sess, &apiLocation{asn: uint(asn), countryCode: first.ProbeCC})
+	if err != nil {
+		return 0, err
+	}
+	result, err := CreateResult(sess, homePath, importTestGroups[first.TestName], network.ID)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	for idx, m := range measurements {
+		startTime, err := parseAPITime(m.MeasurementStartTime)
+		if err != nil {
+			return count, errors.Wrap(err, "parsing measurement start time")
+		}
+		var urlID sql.NullInt64
+		if m.Input != "" {
+			id, err := CreateOrUpdateURL(sess, m.Input, "MISC", m.ProbeCC)
+			if err != nil {
+				return count, err
+			}
+			urlID = sql.NullInt64{Int64: id, Valid: true}
+		}
+		reportID := sql.NullString{String: m.ReportID, Valid: true}
+		msmt, err := CreateMeasurement(sess, reportID, m.TestName, result.MeasurementDir, idx, result.ID, urlID)
+		if err != nil {
+			return count, err
+		}
+		msmt.StartTime = startTime
+		msmt.IsDone = true
+		msmt.IsUploaded = true
+		msmt.IsFailed = m.Failure
+		msmt.IsAnomaly = sql.NullBool{Bool: m.Anomaly || m.Confirmed, Valid: !m.Failure}
+		err = sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt)
+		if err != nil {
+			return count, errors.Wrap(err, "updating measurement")
+		}
+		if idx == 0 || startTime.Before(result.StartTime) {
+			result.StartTime = startTime
+		}
+		count++
+	}
+	result.IsDone = true
+	result.IsUploaded = true
+	result.IsViewed = true
+	if err := sess.Collection("results").Find("result_id", result.ID).Update(result); err != nil {
+		return count, errors.Wrap(err, "updating result")
+	}
+	return count, nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+)
+
+func TestImportMeasurements(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	measurements := []*probeservices.MeasurementListEntry{{
+		Anomaly:              true,
+		Input:                "https://example.com/",
+		MeasurementStartTime: "2022-05-10T12:00:00Z",
+		ProbeASN:             "AS30722",
+		ProbeCC:              "IT",
+		ReportID:             "20220510T120000Z_webconnectivity_IT_30722_n1_antani",
+		TestName:             "web_connectivity",
+	}, {
+		Input:                "https://example.org/",
+		MeasurementStartTime: "2022-05-10T12:00:05Z",
+		ProbeASN:             "AS30722",
+		ProbeCC:              "IT",
+		ReportID:             "20220510T120000Z_webconnectivity_IT_30722_n1_antani",
+		TestName:             "web_connectivity",
+	}, {
+		MeasurementStartTime: "2022-05-10T13:00:00Z",
+		ProbeASN:             "AS30722",
+		ProbeCC:              "IT",
+		ReportID:             "20220510T130000Z_telegram_IT_30722_n1_antani",
+		TestName:             "telegram",
+	}, {
+		MeasurementStartTime: "2022-05-10T14:00:00Z",
+		ProbeASN:             "AS30722",
+		ProbeCC:              "IT",
+		ReportID:             "20220510T140000Z_antani_IT_30722_n1_antani",
+		TestName:             "antani",
+	}}
+
+	t.Run("with new measurements", func(t *testing.T) {
+		count, err := ImportMeasurements(sess, tmpdir, measurements)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 {
+			t.Fatal("unexpected number of imported measurements", count)
+		}
+		done, incomplete, err := ListResults(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(done) != 2 || len(incomplete) != 0 {
+			t.Fatal("unexpected results", done, incomplete)
+		}
+		for _, result := range done {
+			if !result.IsUploaded || result.Network.ASN != 30722 {
+				t.Fatal("unexpected result", result)
+			}
+			if result.TestGroupName == "websites" && result.AnomalyCount != 1 {
+				t.Fatal("unexpected anomaly count", result.AnomalyCount)
+			}
+		}
+	})
+
+	t.Run("with already imported measurements", func(t *testing.T) {
+		count, err := ImportMeasurements(sess, tmpdir, measurements)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Fatal("unexpected number of imported measurements", count)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package database
+
+//
+// Incomplete results.
+//
+// A result is incomplete when the run that produced it crashed or was
+// interrupted before calling Result.Finished. We can either give up on
+// such a result (see FailResult) or resume it (see ResumeResult), in which
+// case the runner only runs the tests the result did not complete.
+//
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// ErrResultDone indicates that a result is not incomplete.
+var ErrResultDone = errors.New("database: result is done")
+
+// ErrNetworkChanged indicates that we cannot resume a result because
+// we are now running on a different network.
+var ErrNetworkChanged = errors.New("database: network changed")
+
+// interruptedFailure is the failure of the measurements that were
+// running when the run that produced their result stopped.
+const interruptedFailure = "interrupted"
+
+// IncompleteResult is a result that is not done, along with its network
+// and the count of its measurements.
+type IncompleteResult struct {
+	Result     `db:",inline"`
+	Network    `db:",inline"`
+	DoneCount  uint64 `db:"done_count"`
+	TotalCount uint64 `db:"total_count"`
+}
+
+// ListIncompleteResults returns the results that are not done, including
+// the ones without measurements, ordered by start time. Note that the
+// results of a run that is still in progress are also incomplete.
+func ListIncompleteResults(sess db.Session) ([]IncompleteResult, error) {
+	results := []IncompleteResult{}
+	req := sess.SQL().Select(
+		db.Raw("networks.*"),
+		db.Raw("results.*"),
+		db.Raw("COUNT(CASE WHEN measurements.measurement_is_done = TRUE THEN 1 END) as done_count"),
+		db.Raw("COUNT(measurements.measurement_id) as total_count"),
+	).From("results").
+		Join("networks").On("results.network_id = networks.network_id").
+		LeftJoin("measurements").On("measurements.result_id = results.result_id").
+		Where("results.result_is_done = false").
+		GroupBy(db.Raw("results.result_id"), db.Raw("networks.network_id")).
+		OrderBy("results.result_start_time", "results.result_id")
+	if err := req.All(&results); err != nil {
+		return results, errors.Wrap(err, "failed to list incomplete results")
+	}
+	return results, nil
+}
+
+// findIncompleteResult returns the given result, or ErrResultDone when
+// the result is done.
+func findIncompleteResult(sess db.Session, resultID int64) (*Result, error) {
+	var result Result
+	if err := sess.Collection("results").Find("result_id", resultID).One(&result); err != nil {
+		return nil, err
+	}
+	if result.IsDone {
+		return nil, fmt.Errorf("%w: #%d", ErrResultDone, resultID)
+	}
+	return &result, nil
+}
+
+// failUnfinishedMeasurements marks the measurements of the given result
+// that are neither done nor failed as failed.
+func failUnfinishedMeasurements(sess db.Session, resultID int64, failure string) error {
+	_, err := sess.SQL().Update("measurements").
+		Set("measurement_is_failed", true).
+		Set("measurement_failure_msg", failure).
+		Where("result_id = ?", resultID).
+		And("measurement_is_done = false").
+		And("measurement_is_failed = false").
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "failing the unfinished measurements")
+	}
+	return nil
+}
+
+// FailResult gives up on an incomplete result: it marks the result as done
+// and failed with the given reason, and it marks the measurements that
+// were running when the run stopped as failed.
+func FailResult(sess db.Session, resultID int64, reason string) error {
+	return sess.Tx(func(tx db.Session) error {
+		result, err := findIncompleteResult(tx, resultID)
+		if err != nil {
+			return err
+		}
+		if err := failUnfinishedMeasurements(tx, resultID, interruptedFailure); err != nil {
+			return err
+		}
+		result.IsDone = true
+		result.IsFailed = true
+		result.FailureMsg = sql.NullString{String: reason, Valid: true}
+		if err := tx.Collection("results").Find("result_id", resultID).Update(result); err != nil {
+			return errors.Wrap(err, "updating failed result")
+		}
+		return nil
+	})
+}
+
+// ResumedResult is an incomplete result that the runner is resuming.
+type ResumedResult struct {
+	*Result
+
+	// CompletedTests contains the names of the tests with measurements
+	// that all completed, either successfully or not. The runner should
+	// not run these tests again.
+	CompletedTests map[string]bool
+}
+
+// ResumeResult prepares the given incomplete result for resumption on the
+// given network, which must have the same ASN and country code as the
+// network of the result. The measurements that were running when the
+// run stopped are marked as failed, such that the runner runs their tests
+// again.
+func ResumeResult(sess db.Session, resultID int64, network *Network) (*ResumedResult, error) {
+	resumed := &ResumedResult{CompletedTests: make(map[string]bool)}
+	err := sess.Tx(func(tx db.Session) error {
+		result, err := findIncompleteResult(tx, resultID)
+		if err != nil {
+			return err
+		}
+		var previous Network
+		if err := tx.Collection("networks").Find("network_id", result.NetworkID).One(&previous); err != nil {
+			return errors.Wrap(err, "finding the result network")
+		}
+		if previous.ASN != network.ASN || previous.CountryCode != network.CountryCode {
+			return fmt.Errorf("%w: AS%d (%s) instead of AS%d (%s)", ErrNetworkChanged,
+				network.ASN, network.CountryCode, previous.ASN, previous.CountryCode)
+		}
+		var tests []struct {
+			TestName        string `db:"test_name"`
+			UnfinishedCount int64  `db:"unfinished_count"`
+		}
+		err = tx.SQL().Select(
+			"test_name",
+			db.Raw(`COUNT(CASE WHEN measurement_is_done = FALSE
+				AND measurement_is_failed = FALSE THEN 1 END) as unfinished_count`),
+		).From("measurements").
+			Where("result_id = ?", resultID).
+			GroupBy("test_name").
+			All(&tests)
+		if err != nil {
+			return errors.Wrap(err, "listing the result tests")
+		}
+		for _, test := range tests {
+			if test.UnfinishedCount == 0 {
+				resumed.CompletedTests[test.TestName] = true
+			}
+		}
+		resumed.Result = result
+		return failUnfinishedMeasurements(tx, resultID, interruptedFailure)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resumed, nil
+}
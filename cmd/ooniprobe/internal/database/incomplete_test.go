@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestIncompleteResults(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// newInterruptedResult emulates a run that stopped while running whatsapp.
+	newInterruptedResult := func() (*Result, *Measurement) {
+		result, err := CreateResult(sess, tmpdir, "im", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		telegram, err := CreateMeasurement(sess, sql.NullString{}, "telegram",
+			result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := telegram.Done(sess); err != nil {
+			t.Fatal(err)
+		}
+		whatsapp, err := CreateMeasurement(sess, sql.NullString{}, "whatsapp",
+			result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, whatsapp
+	}
+	failed, _ := newInterruptedResult()
+	resumable, whatsapp := newInterruptedResult()
+	empty, err := CreateResult(sess, tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := CreateResult(sess, tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := done.Finished(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("ListIncompleteResults", func(t *testing.T) {
+		results, err := ListIncompleteResults(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 3 {
+			t.Fatal("unexpected results", results)
+		}
+		if results[0].Result.ID != failed.ID || results[0].DoneCount != 1 || results[0].TotalCount != 2 {
+			t.Fatal("unexpected result", results[0])
+		}
+		if results[2].Result.ID != empty.ID || results[2].TotalCount != 0 {
+			t.Fatal("unexpected result", results[2])
+		}
+	})
+
+	t.Run("FailResult", func(t *testing.T) {
+		if err := FailResult(sess, failed.ID, "abandoned"); err != nil {
+			t.Fatal(err)
+		}
+		var result Result
+		if err := sess.Collection("results").Find("result_id", failed.ID).One(&result); err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsDone || !result.IsFailed || result.FailureMsg.String != "abandoned" {
+			t.Fatal("unexpected result", result)
+		}
+		err := FailResult(sess, done.ID, "abandoned")
+		if !errors.Is(err, ErrResultDone) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("ResumeResult on another network", func(t *testing.T) {
+		_, err := ResumeResult(sess, resumable.ID, &Network{ASN: 3269, CountryCode: "IT"})
+		if !errors.Is(err, ErrNetworkChanged) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("ResumeResult", func(t *testing.T) {
+		resumed, err := ResumeResult(sess, resumable.ID, network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resumed.Result.ID != resumable.ID {
+			t.Fatal("unexpected result", resumed.Result)
+		}
+		if len(resumed.CompletedTests) != 1 || !resumed.CompletedTests["telegram"] {
+			t.Fatal("unexpected completed tests", resumed.CompletedTests)
+		}
+		var msmt Measurement
+		if err := sess.Collection("measurements").Find("measurement_id", whatsapp.ID).One(&msmt); err != nil {
+			t.Fatal(err)
+		}
+		if !msmt.IsFailed || msmt.FailureMsg.String != interruptedFailure {
+			t.Fatal("unexpected measurement", msmt)
+		}
+	})
+}
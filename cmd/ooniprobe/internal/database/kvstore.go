@@ -0,0 +1,181 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// KVStore is a key-value store backed by the kvstore table. Entries
+// may optionally expire, in which case Get behaves as if the key did
+// not exist and Compact removes them from the database.
+type KVStore struct {
+	sess db.Session
+
+	// write runs the given function, which writes into the
+	// database, inside a transaction.
+	write func(fn func(sess db.Session) error) error
+
+	// timeNow allows to mock time.Now in tests.
+	timeNow func() time.Time
+}
+
+var _ model.KeyValueStore = &KVStore{}
+
+// NewKVStore creates a new KVStore using the given database session.
+func NewKVStore(sess db.Session) *KVStore {
+	return &KVStore{sess: sess, write: sess.Tx, timeNow: time.Now}
+}
+
+// KVStore returns a KVStore using the database, which writes using
+// the write worker, such that it is safe to use the returned KVStore
+// concurrently with the other Database operations.
+func (d *Database) KVStore() *KVStore {
+	return &KVStore{
+		sess: d.sess,
+		write: func(fn func(sess db.Session) error) error {
+			return d.write(func(sess db.Session) error {
+				return sess.Tx(fn)
+			})
+		},
+		timeNow: time.Now,
+	}
+}
+
+// lookup returns the non-expired entry for the given key. In case of
+// error, the error type is such that errors.Is(err, kvstore.ErrNoSuchKey)
+// if the entry does not exist or has expired.
+func (kvs *KVStore) lookup(sess db.Session, key string) (*KeyValue, error) {
+	var entry KeyValue
+	err := sess.Collection("kvstore").Find("kv_key", key).One(&entry)
+	if err == db.ErrNoMoreRows {
+		return nil, fmt.Errorf("%w: %s", kvstore.ErrNoSuchKey, key)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading key")
+	}
+	if entry.ExpiresAt.Valid && entry.ExpiresAt.Int64 <= kvs.timeNow().Unix() {
+		return nil, fmt.Errorf("%w: %s has expired", kvstore.ErrNoSuchKey, key)
+	}
+	return &entry, nil
+}
+
+// upsert creates or replaces the value of the given key.
+func (kvs *KVStore) upsert(sess db.Session, key string, value []byte, ttl time.Duration) error {
+	entry := KeyValue{Key: key, Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = sql.NullInt64{Int64: kvs.timeNow().Add(ttl).Unix(), Valid: true}
+	}
+	res := sess.Collection("kvstore").Find("kv_key", key)
+	exists, err := res.Exists()
+	if err != nil {
+		return errors.Wrap(err, "checking whether key exists")
+	}
+	if exists {
+		if err := res.Update(entry); err != nil {
+			return errors.Wrap(err, "updating key")
+		}
+		return nil
+	}
+	if _, err := sess.Collection("kvstore").Insert(entry); err != nil {
+		return errors.Wrap(err, "inserting key")
+	}
+	return nil
+}
+
+// Get returns the specified key's value. In case of error, the
+// error type is such that errors.Is(err, kvstore.ErrNoSuchKey).
+func (kvs *KVStore) Get(key string) ([]byte, error) {
+	entry, err := kvs.lookup(kvs.sess, key)
+	if err != nil {
+		return nil, err
+	}
+	return entry.Value, nil
+}
+
+// Set sets the value of a specific key. The key does not expire.
+func (kvs *KVStore) Set(key string, value []byte) error {
+	return kvs.SetWithTTL(key, value, 0)
+}
+
+// SetWithTTL is like Set but the key expires after the given ttl. A
+// zero or negative ttl means that the key does not expire.
+func (kvs *KVStore) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return kvs.write(func(tx db.Session) error {
+		return kvs.upsert(tx, key, value, ttl)
+	})
+}
+
+// GetOrSet atomically returns the value of the given key, if it exists
+// and has not expired, or otherwise sets the key to the value returned
+// by create, which expires after the given ttl (see SetWithTTL).
+// We call create while holding the write lock, hence create should
+// not block for long, because it blocks the other writers.
+func (kvs *KVStore) GetOrSet(
+	key string, ttl time.Duration, create func() ([]byte, error)) ([]byte, error) {
+	var value []byte
+	err := kvs.write(func(tx db.Session) error {
+		entry, err := kvs.lookup(tx, key)
+		if err == nil {
+			value = entry.Value
+			return nil
+		}
+		if !errors.Is(err, kvstore.ErrNoSuchKey) {
+			return err
+		}
+		if value, err = create(); err != nil {
+			return err
+		}
+		return kvs.upsert(tx, key, value, ttl)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Delete removes a specific key.
+func (kvs *KVStore) Delete(key string) error {
+	return kvs.write(func(tx db.Session) error {
+		if err := tx.Collection("kvstore").Find("kv_key", key).Delete(); err != nil {
+			return errors.Wrap(err, "deleting key")
+		}
+		return nil
+	})
+}
+
+// ListKeys returns the keys that have not expired.
+func (kvs *KVStore) ListKeys() ([]string, error) {
+	var entries []KeyValue
+	err := kvs.sess.Collection("kvstore").Find(db.Or(
+		db.Cond{"kv_expires_at IS": nil},
+		db.Cond{"kv_expires_at >": kvs.timeNow().Unix()},
+	)).OrderBy("kv_key").All(&entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing keys")
+	}
+	var keys []string
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys, nil
+}
+
+// Compact removes the expired keys from the database. The engine
+// session calls this function periodically.
+func (kvs *KVStore) Compact() error {
+	return kvs.write(func(tx db.Session) error {
+		err := tx.Collection("kvstore").Find(db.Cond{
+			"kv_expires_at <=": kvs.timeNow().Unix(),
+		}).Delete()
+		if err != nil {
+			return errors.Wrap(err, "deleting expired keys")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+)
+
+func newKVStoreForTesting(t *testing.T) *KVStore {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(tmpfile.Name())
+	})
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sess.Close()
+	})
+	return NewKVStore(sess)
+}
+
+func TestKVStoreGetSet(t *testing.T) {
+	kvs := newKVStoreForTesting(t)
+	if _, err := kvs.Get("antani"); !errors.Is(err, kvstore.ErrNoSuchKey) {
+		t.Fatal("not the error we expected", err)
+	}
+	for _, value := range [][]byte{[]byte("foo"), []byte("bar")} {
+		if err := kvs.Set("antani", value); err != nil {
+			t.Fatal(err)
+		}
+		ovalue, err := kvs.Get("antani")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ovalue, value) {
+			t.Fatal("invalid value")
+		}
+	}
+}
+
+func TestKVStoreTTL(t *testing.T) {
+	kvs := newKVStoreForTesting(t)
+	now := time.Now()
+	kvs.timeNow = func() time.Time {
+		return now
+	}
+	if err := kvs.SetWithTTL("antani", []byte("foo"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvs.Set("mascetti", []byte("bar")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kvs.Get("antani"); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := kvs.Get("antani"); !errors.Is(err, kvstore.ErrNoSuchKey) {
+		t.Fatal("not the error we expected", err)
+	}
+	if err := kvs.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	count, err := kvs.sess.Collection("kvstore").Find().Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatal("unexpected number of keys", count)
+	}
+	if _, err := kvs.Get("mascetti"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKVStoreGetOrSet(t *testing.T) {
+	kvs := newKVStoreForTesting(t)
+	var calls int
+	create := func() ([]byte, error) {
+		calls++
+		return []byte("foo"), nil
+	}
+	for idx := 0; idx < 2; idx++ {
+		value, err := kvs.GetOrSet("antani", 0, create)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != "foo" {
+			t.Fatal("invalid value")
+		}
+	}
+	if calls != 1 {
+		t.Fatal("unexpected number of calls", calls)
+	}
+}
+
+func TestKVStoreGetOrSetFailure(t *testing.T) {
+	kvs := newKVStoreForTesting(t)
+	expected := errors.New("mocked error")
+	value, err := kvs.GetOrSet("antani", 0, func() ([]byte, error) {
+		return nil, expected
+	})
+	if !errors.Is(err, expected) {
+		t.Fatal("not the error we expected", err)
+	}
+	if value != nil {
+		t.Fatal("expected nil value")
+	}
+	if _, err := kvs.Get("antani"); !errors.Is(err, kvstore.ErrNoSuchKey) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestKVStoreDeleteAndListKeys(t *testing.T) {
+	kvs := newKVStoreForTesting(t)
+	now := time.Now()
+	kvs.timeNow = func() time.Time {
+		return now
+	}
+	if err := kvs.Delete("nonexistent"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"b", "a", "c"} {
+		if err := kvs.Set(key, []byte("foo")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kvs.SetWithTTL("d", []byte("foo"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvs.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute) // "d" expires
+	keys, err := kvs.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fatal("unexpected keys", keys)
+	}
+}
+
+func TestDatabaseKVStoreGetOrSetIsAtomic(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(tmpfile.Name())
+	})
+	database, err := Open(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	kvs := database.KVStore()
+	var (
+		calls int32
+		wg    sync.WaitGroup
+	)
+	errch := make(chan error, 8)
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := kvs.GetOrSet("antani", time.Minute, func() ([]byte, error) {
+				atomic.AddInt32(&calls, 1)
+				return []byte("foo"), nil
+			})
+			errch <- err
+		}()
+	}
+	wg.Wait()
+	close(errch)
+	for err := range errch {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatal("unexpected number of calls", calls)
+	}
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvs.Set("antani", []byte("bar")); !errors.Is(err, ErrClosed) {
+		t.Fatal("not the error we expected", err)
+	}
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// These are the link types.
+const (
+	LinkTypeWifi   = "wifi"
+	LinkTypeMobile = "mobile"
+	LinkTypeWired  = "wired"
+)
+
+// ErrInvalidLinkType indicates that the link type is not valid.
+var ErrInvalidLinkType = errors.New("database: invalid link type")
+
+// LinkContext describes the link used by a result, such that we can
+// interpret its anomalies alongside the quality of the network.
+type LinkContext struct {
+	// Type is the OPTIONAL link type (e.g., LinkTypeWifi).
+	Type string
+
+	// DownlinkKbps is the OPTIONAL downlink estimate in kbit/s.
+	DownlinkKbps sql.NullFloat64
+}
+
+// LastDownlinkEstimate returns the download speed in kbit/s measured by the
+// most recent performance measurement on a network with the same ASN and
+// country code of the given network. The return value is not valid when
+// there is no such measurement.
+func LastDownlinkEstimate(sess db.Session, network *Network) (sql.NullFloat64, error) {
+	var entry struct {
+		Downlink sql.NullFloat64 `db:"downlink"`
+	}
+	downlink := "COALESCE(measurement_summaries.download, measurement_summaries.median_bitrate)"
+	err := sess.SQL().Select(db.Raw(downlink+" AS downlink")).
+		From("measurement_summaries").
+		Join("measurements").On("measurements.measurement_id = measurement_summaries.measurement_id").
+		Join("results").On("results.result_id = measurements.result_id").
+		Join("networks").On("networks.network_id = results.network_id").
+		Where(downlink+" IS NOT NULL").
+		And("networks.asn = ?", network.ASN).
+		And("networks.network_country_code = ?", network.CountryCode).
+		OrderBy("-measurements.measurement_start_time").
+		Limit(1).
+		One(&entry)
+	if err == db.ErrNoMoreRows {
+		return sql.NullFloat64{}, nil
+	}
+	if err != nil {
+		return sql.NullFloat64{}, errors.Wrap(err, "querying the last downlink estimate")
+	}
+	return entry.Downlink, nil
+}
+
+// SetResultLinkContext writes the link context of the given result.
+func SetResultLinkContext(sess db.Session, result *Result, lc *LinkContext) error {
+	switch lc.Type {
+	case "", LinkTypeWifi, LinkTypeMobile, LinkTypeWired:
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidLinkType, lc.Type)
+	}
+	result.LinkType = sql.NullString{String: lc.Type, Valid: lc.Type != ""}
+	result.DownlinkKbps = lc.DownlinkKbps
+	err := sess.Collection("results").Find("result_id", result.ID).Update(result)
+	if err != nil {
+		return errors.Wrap(err, "updating the result link context")
+	}
+	return nil
+}
+
+// UpdateResultLinkContext sets the link context of a result created on
+// the given network, using the given link type, which may be empty, and
+// the downlink estimate returned by LastDownlinkEstimate.
+func UpdateResultLinkContext(sess db.Session, result *Result, network *Network, linkType string) error {
+	downlink, err := LastDownlinkEstimate(sess, network)
+	if err != nil {
+		return err
+	}
+	return SetResultLinkContext(sess, result, &LinkContext{Type: linkType, DownlinkKbps: downlink})
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/ndt7"
+)
+
+func TestResultLinkContext(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// newResult creates a result on a new network with the given ASN.
+	newResult := func(asn uint, group string) (*Result, *Network) {
+		network, err := CreateNetwork(sess, &locationInfo{asn: asn, countryCode: "IT"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := CreateResult(sess, tmpdir, group, network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, network
+	}
+
+	performance, _ := newResult(30722, "performance")
+	for idx, download := range []float64{1000, 2000} {
+		msmt, err := CreateMeasurement(sess, sql.NullString{}, "ndt", tmpdir, idx, performance.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := AddTestKeys(sess, msmt, &ndt7.SummaryKeys{Download: download}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("on a network with a previous performance run", func(t *testing.T) {
+		result, network := newResult(30722, "websites")
+		if err := UpdateResultLinkContext(sess, result, network, LinkTypeWired); err != nil {
+			t.Fatal(err)
+		}
+		var stored Result
+		if err := sess.Collection("results").Find("result_id", result.ID).One(&stored); err != nil {
+			t.Fatal(err)
+		}
+		if stored.LinkType.String != LinkTypeWired || stored.DownlinkKbps.Float64 != 2000 {
+			t.Fatal("unexpected link context", stored.LinkType, stored.DownlinkKbps)
+		}
+	})
+
+	t.Run("on a network without performance runs", func(t *testing.T) {
+		result, network := newResult(3269, "websites")
+		if err := UpdateResultLinkContext(sess, result, network, ""); err != nil {
+			t.Fatal(err)
+		}
+		if result.LinkType.Valid || result.DownlinkKbps.Valid {
+			t.Fatal("unexpected link context", result.LinkType, result.DownlinkKbps)
+		}
+	})
+
+	t.Run("with an invalid link type", func(t *testing.T) {
+		result, network := newResult(30722, "websites")
+		err := UpdateResultLinkContext(sess, result, network, "carrier pigeon")
+		if !errors.Is(err, ErrInvalidLinkType) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
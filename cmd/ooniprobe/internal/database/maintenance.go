@@ -0,0 +1,230 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// orphanGracePeriod is how old an incomplete result or a file must be for
+// us to consider it orphaned. This prevents us from considering orphaned
+// the result and the files of a run that has just started.
+const orphanGracePeriod = 24 * time.Hour
+
+// Orphans contains the rows and the files left behind by crashes and by
+// old versions of ooniprobe, which did not enforce foreign keys.
+type Orphans struct {
+	// MeasurementIDs contains the measurements whose result does not exist.
+	MeasurementIDs []int64
+
+	// ResultIDs contains the incomplete results without measurements.
+	ResultIDs []int64
+
+	// NetworkIDs contains the networks not used by any result.
+	NetworkIDs []int64
+
+	// Paths contains the files and directories inside the measurements
+	// directory not belonging to any result or measurement.
+	Paths []string
+}
+
+// Empty returns whether we did not find any orphan.
+func (o *Orphans) Empty() bool {
+	return len(o.MeasurementIDs) <= 0 && len(o.ResultIDs) <= 0 &&
+		len(o.NetworkIDs) <= 0 && len(o.Paths) <= 0
+}
+
+// selectIDs runs a query returning a single integer column.
+func selectIDs(req db.Selector) ([]int64, error) {
+	var rows []struct {
+		ID int64 `db:"id"`
+	}
+	if err := req.All(&rows); err != nil {
+		return nil, err
+	}
+	ids := []int64{}
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	return ids, nil
+}
+
+// FindOrphans returns the orphaned rows in the database and the orphaned
+// files inside the measurements directory of the given OONI home.
+func FindOrphans(sess db.Session, homePath string) (*Orphans, error) {
+	var (
+		orphans Orphans
+		err     error
+	)
+	threshold := time.Now().UTC().Add(-orphanGracePeriod)
+	orphans.MeasurementIDs, err = selectIDs(sess.SQL().
+		Select(db.Raw("measurements.measurement_id AS id")).
+		From("measurements").
+		LeftJoin("results").On("results.result_id = measurements.result_id").
+		Where("results.result_id IS NULL"))
+	if err != nil {
+		return nil, errors.Wrap(err, "finding orphaned measurements")
+	}
+	orphans.ResultIDs, err = selectIDs(sess.SQL().
+		Select(db.Raw("results.result_id AS id")).
+		From("results").
+		LeftJoin("measurements").On("measurements.result_id = results.result_id").
+		Where("measurements.measurement_id IS NULL").
+		And("results.result_is_done = false").
+		And("results.result_start_time < ?", threshold))
+	if err != nil {
+		return nil, errors.Wrap(err, "finding orphaned results")
+	}
+	orphans.NetworkIDs, err = selectIDs(sess.SQL().
+		Select(db.Raw("networks.network_id AS id")).
+		From("networks").
+		LeftJoin("results").On("results.network_id = networks.network_id").
+		Where("results.result_id IS NULL"))
+	if err != nil {
+		return nil, errors.Wrap(err, "finding orphaned networks")
+	}
+	orphans.Paths, err = findOrphanedPaths(sess, filepath.Join(homePath, "msmts"), threshold)
+	if err != nil {
+		return nil, err
+	}
+	return &orphans, nil
+}
+
+// findOrphanedPaths returns the entries of msmtsDir that do not belong to any
+// result or measurement and the files inside the results directories that do
+// not belong to any measurement, where a measurement owns both its measurement
+// file and its report file. We ignore the entries modified after threshold.
+func findOrphanedPaths(sess db.Session, msmtsDir string, threshold time.Time) ([]string, error) {
+	var results []Result
+	if err := sess.Collection("results").Find().All(&results); err != nil {
+		return nil, errors.Wrap(err, "listing results")
+	}
+	dirs := make(map[string]bool)
+	for _, result := range results {
+		dirs[filepath.Clean(result.MeasurementDir)] = true
+	}
+	var measurements []Measurement
+	if err := sess.Collection("measurements").Find().All(&measurements); err != nil {
+		return nil, errors.Wrap(err, "listing measurements")
+	}
+	files := make(map[string]bool)
+	for _, msmt := range measurements {
+		for _, path := range []sql.NullString{msmt.MeasurementFilePath, msmt.ReportFilePath} {
+			if path.Valid && path.String != "" {
+				files[filepath.Clean(path.String)] = true
+			}
+		}
+	}
+	// orphanedEntries returns the entries of dir modified before threshold
+	// for which isOrphaned returns true. A missing dir has no entries.
+	orphanedEntries := func(dir string, isOrphaned func(path string, info os.FileInfo) bool) ([]string, error) {
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var paths []string
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				continue // the entry may have been removed meanwhile
+			}
+			path := filepath.Join(dir, entry.Name())
+			if info.ModTime().Before(threshold) && isOrphaned(path, info) {
+				paths = append(paths, path)
+			}
+		}
+		return paths, nil
+	}
+	paths, err := orphanedEntries(msmtsDir, func(path string, info os.FileInfo) bool {
+		if info.IsDir() {
+			return !dirs[path]
+		}
+		return !files[path]
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing the measurements directory")
+	}
+	for dir := range dirs {
+		more, err := orphanedEntries(dir, func(path string, info os.FileInfo) bool {
+			return !info.IsDir() && !files[path]
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing %s", dir)
+		}
+		paths = append(paths, more...)
+	}
+	return paths, nil
+}
+
+// CheckIntegrity checks the integrity of the database file, returning
+// the problems that SQLite finds, which is empty when it is fine. With
+// PostgreSQL, this function returns no problems, because there is no
+// database file for us to check and the server checks its own storage.
+func CheckIntegrity(sess db.Session) ([]string, error) {
+	if isPostgreSQL(sess) {
+		return []string{}, nil
+	}
+	rows, err := sess.Driver().(*sql.DB).Query("PRAGMA integrity_check")
+	if err != nil {
+		return nil, errors.Wrap(err, "checking integrity")
+	}
+	defer rows.Close()
+	problems := []string{}
+	for rows.Next() {
+		var problem string
+		if err := rows.Scan(&problem); err != nil {
+			return nil, errors.Wrap(err, "checking integrity")
+		}
+		if problem != "ok" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// RemoveOrphans removes the orphans returned by FindOrphans. We only
+// remove networks that are still not used by any result.
+func RemoveOrphans(sess db.Session, orphans *Orphans) error {
+	for _, resultID := range orphans.ResultIDs {
+		if err := DeleteResult(sess, resultID); err != nil && err != db.ErrNoMoreRows {
+			return err
+		}
+	}
+	err := sess.Tx(func(tx db.Session) error {
+		if len(orphans.MeasurementIDs) > 0 {
+			err := tx.Collection("measurements").Find(db.Cond{
+				"measurement_id IN": orphans.MeasurementIDs,
+			}).Delete()
+			if err != nil {
+				return errors.Wrap(err, "deleting orphaned measurements")
+			}
+		}
+		if len(orphans.NetworkIDs) > 0 {
+			err := tx.Collection("networks").Find(db.Cond{
+				"network_id IN": orphans.NetworkIDs,
+			}).And(db.Raw("network_id NOT IN (SELECT network_id FROM results)")).Delete()
+			if err != nil {
+				return errors.Wrap(err, "deleting orphaned networks")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.WithError(err).Error("failed to remove orphaned rows")
+		return err
+	}
+	for _, path := range orphans.Paths {
+		if err := os.RemoveAll(path); err != nil {
+			return errors.Wrapf(err, "removing %s", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestOrphans(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	home, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * orphanGracePeriod)
+
+	// A complete result with a measurement saved to disk.
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, home, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msmt, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity",
+		result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(msmt.MeasurementFilePath.String, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reportFile := filepath.Join(result.MeasurementDir, "report-web_connectivity-0.jsonl")
+	if err := os.WriteFile(reportFile, []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(reportFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+	msmt.ReportFilePath = sql.NullString{String: reportFile, Valid: true}
+	if err := sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt); err != nil {
+		t.Fatal(err)
+	}
+	strayFile := filepath.Join(result.MeasurementDir, "msmt-antani-1.json")
+	if err := os.WriteFile(strayFile, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(strayFile, old, old); err != nil {
+		t.Fatal(err)
+	}
+	recentFile := filepath.Join(result.MeasurementDir, "msmt-antani-2.json")
+	if err := os.WriteFile(recentFile, []byte("{}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A network without results.
+	strayNetwork, err := CreateNetwork(sess, &locationInfo{asn: 3269, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An old incomplete result without measurements.
+	incomplete, err := CreateResult(sess, home, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	incomplete.StartTime = old.UTC()
+	if err := sess.Collection("results").Find("result_id", incomplete.ID).Update(incomplete); err != nil {
+		t.Fatal(err)
+	}
+
+	// A measurement whose result was deleted while foreign keys were disabled.
+	deleted, err := CreateResult(sess, home, "performance", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	strayMsmt, err := CreateMeasurement(sess, sql.NullString{}, "ndt",
+		deleted.MeasurementDir, 0, deleted.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := sess.Driver().(*sql.DB).Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		"PRAGMA foreign_keys = OFF",
+		"DELETE FROM results WHERE result_id = ?",
+		"PRAGMA foreign_keys = ON",
+	} {
+		var args []interface{}
+		if query[0] == 'D' {
+			args = append(args, deleted.ID)
+		}
+		if _, err := conn.ExecContext(context.Background(), query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Close()
+	if err := os.Chtimes(deleted.MeasurementDir, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	orphans, err := FindOrphans(sess, home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Orphans{
+		MeasurementIDs: []int64{strayMsmt.ID},
+		ResultIDs:      []int64{incomplete.ID},
+		NetworkIDs:     []int64{strayNetwork.ID},
+		Paths:          []string{deleted.MeasurementDir, strayFile},
+	}
+	sortStrings := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+	if diff := cmp.Diff(expected, orphans, sortStrings); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if err := RemoveOrphans(sess, orphans); err != nil {
+		t.Fatal(err)
+	}
+	orphans, err = FindOrphans(sess, home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !orphans.Empty() {
+		t.Fatal("expected no orphans", orphans)
+	}
+	for _, path := range []string{deleted.MeasurementDir, incomplete.MeasurementDir, strayFile} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatal("expected the path to be removed", path)
+		}
+	}
+	for _, path := range []string{msmt.MeasurementFilePath.String, reportFile, recentFile} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done, incompleteResults, err := ListResults(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done)+len(incompleteResults) != 1 {
+		t.Fatal("unexpected results", done, incompleteResults)
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := CheckIntegrity(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Fatal("unexpected problems", problems)
+	}
+}
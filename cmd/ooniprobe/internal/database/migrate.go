@@ -0,0 +1,352 @@
+package database
+
+//
+// Versioned schema migrations.
+//
+// Each file in the migrations directory is named `<version>_<name>.sql`
+// and contains a `-- +migrate Up` and a `-- +migrate Down` section. The
+// schema_version table records the migrations we have applied. When we
+// open a database that was migrated by older releases, which used the
+// gorp_migrations table, we import its content into schema_version.
+// A migration may also have a Go hook transforming the data before we
+// execute its Up section (see migrationHooks).
+//
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidMigration indicates that a migration file is malformed.
+	ErrInvalidMigration = errors.New("database: invalid migration")
+
+	// ErrSchemaTooNew indicates that the database has been migrated by
+	// a more recent release and we don't know how to use it.
+	ErrSchemaTooNew = errors.New("database: schema version is newer than supported")
+
+	// ErrNoSuchSchemaVersion indicates that we don't know
+	// how to migrate to the requested schema version.
+	ErrNoSuchSchemaVersion = errors.New("database: no such schema version")
+)
+
+// migration is a versioned schema migration.
+type migration struct {
+	// Version is the migration version.
+	Version int
+
+	// Name is the name of the file containing the migration.
+	Name string
+
+	// Up contains the statements applying the migration.
+	Up string
+
+	// Down contains the statements reverting the migration.
+	Down string
+
+	// BeforeUp is the OPTIONAL function transforming the data, which
+	// we call in the same transaction before executing Up.
+	BeforeUp func(tx *sql.Tx) error
+}
+
+// migrationHooks contains the BeforeUp functions of the embedded
+// migrations, indexed by version, for data transformations that we
+// cannot express in SQL. We only use them with SQLite, because the
+// PostgreSQL backend is more recent than these migrations, hence
+// it has no data to transform when it applies them.
+var migrationHooks = map[int]func(tx *sql.Tx) error{
+	9: mergeDuplicateURLs,
+}
+
+// These are the markers delimiting the sections of a migration file. We
+// ignore the StatementBegin and StatementEnd markers, which we previously
+// needed, because we execute each section as a whole.
+const (
+	migrationUpMarker             = "-- +migrate Up"
+	migrationDownMarker           = "-- +migrate Down"
+	migrationStatementBeginMarker = "-- +migrate StatementBegin"
+	migrationStatementEndMarker   = "-- +migrate StatementEnd"
+)
+
+// parseMigration parses the migration file with the given name and content.
+func parseMigration(name string, data []byte) (*migration, error) {
+	prefix, _, found := strings.Cut(name, "_")
+	if !found || !strings.HasSuffix(name, ".sql") {
+		return nil, fmt.Errorf("%w: %s: invalid file name", ErrInvalidMigration, name)
+	}
+	version, err := strconv.Atoi(prefix)
+	if err != nil || version <= 0 {
+		return nil, fmt.Errorf("%w: %s: invalid version", ErrInvalidMigration, name)
+	}
+	var up, down strings.Builder
+	var section *strings.Builder
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		switch strings.TrimSpace(line) {
+		case migrationUpMarker:
+			section = &up
+		case migrationDownMarker:
+			section = &down
+		case migrationStatementBeginMarker, migrationStatementEndMarker:
+			// nothing
+		default:
+			if section == nil {
+				if strings.TrimSpace(line) != "" {
+					return nil, fmt.Errorf("%w: %s: statement outside of sections",
+						ErrInvalidMigration, name)
+				}
+				continue
+			}
+			section.WriteString(line)
+		}
+	}
+	if strings.TrimSpace(up.String()) == "" || strings.TrimSpace(down.String()) == "" {
+		return nil, fmt.Errorf("%w: %s: missing up or down section", ErrInvalidMigration, name)
+	}
+	return &migration{Version: version, Name: name, Up: up.String(), Down: down.String()}, nil
+}
+
+// loadMigrations loads the migrations inside dir and returns them sorted
+// by version. Versions must be consecutive and start from one.
+func loadMigrations(fsys fs.FS, dir string) ([]*migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []*migration
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, err := parseMigration(entry.Name(), data)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Version < out[j].Version
+	})
+	for idx, m := range out {
+		if m.Version != idx+1 {
+			return nil, fmt.Errorf("%w: %s: expected version %d", ErrInvalidMigration, m.Name, idx+1)
+		}
+	}
+	return out, nil
+}
+
+// createSchemaVersionTable creates the schema_version table and imports
+// into it the migrations recorded by older releases, if any.
+func createSchemaVersionTable(sess *sql.DB) error {
+	d := dialectOf(sess)
+	tx, err := sess.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := d.lockMigrations(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(d.translate(`CREATE TABLE IF NOT EXISTS schema_version (
+		"version" INTEGER PRIMARY KEY NOT NULL,
+		"name" VARCHAR(255) NOT NULL,
+		"applied_at" DATETIME NOT NULL
+	)`)); err != nil {
+		return err
+	}
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&count); err != nil {
+		return err
+	}
+	if count <= 0 && d == dialectSQLite {
+		if err := importLegacyMigrations(tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// importLegacyMigrations copies the content of the gorp_migrations
+// table, if it exists, into the schema_version table.
+func importLegacyMigrations(tx *sql.Tx) error {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name = 'gorp_migrations'`).Scan(&count)
+	if err != nil || count <= 0 {
+		return err
+	}
+	rows, err := tx.Query(`SELECT id FROM gorp_migrations`)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("%w: %s: invalid legacy migration", ErrInvalidMigration, name)
+		}
+		_, err = tx.Exec(`INSERT INTO schema_version (version, name, applied_at)
+			VALUES (?, ?, ?)`, version, name, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the database schema, which is
+// zero when we have not applied any migration yet.
+func SchemaVersion(sess *sql.DB) (int, error) {
+	if err := createSchemaVersionTable(sess); err != nil {
+		return 0, err
+	}
+	var version int
+	err := sess.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// applyMigration applies the given migration in a transaction, unless
+// another process using the database has already applied it.
+func applyMigration(sess *sql.DB, m *migration) error {
+	d := dialectOf(sess)
+	tx, err := sess.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := d.lockMigrations(tx); err != nil {
+		return err
+	}
+	var count int
+	err = tx.QueryRow(d.rebind(`SELECT COUNT(*) FROM schema_version WHERE version = ?`),
+		m.Version).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+	if m.BeforeUp != nil {
+		if err := m.BeforeUp(tx); err != nil {
+			return fmt.Errorf("database: cannot apply %s: %w", m.Name, err)
+		}
+	}
+	if _, err := tx.Exec(m.Up); err != nil {
+		return fmt.Errorf("database: cannot apply %s: %w", m.Name, err)
+	}
+	_, err = tx.Exec(d.rebind(`INSERT INTO schema_version (version, name, applied_at)
+		VALUES (?, ?, ?)`), m.Version, m.Name, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// revertMigration reverts the given migration in a transaction.
+func revertMigration(sess *sql.DB, m *migration) error {
+	d := dialectOf(sess)
+	tx, err := sess.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := d.lockMigrations(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(m.Down); err != nil {
+		return fmt.Errorf("database: cannot revert %s: %w", m.Name, err)
+	}
+	if _, err := tx.Exec(d.rebind(`DELETE FROM schema_version WHERE version = ?`), m.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrateTo migrates the database to the given schema version using the
+// given migrations and returns the number of migrations it performed.
+func migrateTo(sess *sql.DB, migrations []*migration, version int) (int, error) {
+	if version < 0 || version > len(migrations) {
+		return 0, fmt.Errorf("%w: %d", ErrNoSuchSchemaVersion, version)
+	}
+	current, err := SchemaVersion(sess)
+	if err != nil {
+		return 0, err
+	}
+	if current > len(migrations) {
+		return 0, fmt.Errorf("%w: %d", ErrSchemaTooNew, current)
+	}
+	var count int
+	for ; current < version; current++ {
+		if err := applyMigration(sess, migrations[current]); err != nil {
+			return count, err
+		}
+		count++
+	}
+	for ; current > version; current-- {
+		if err := revertMigration(sess, migrations[current-1]); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// loadEmbeddedMigrations loads the migrations embedded in the binary
+// for the given dialect along with their hooks.
+func loadEmbeddedMigrations(d dialect) ([]*migration, error) {
+	migrations, err := loadMigrations(efs, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range migrations {
+		if d == dialectPostgreSQL {
+			if err := translateMigration(efs, m); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		m.BeforeUp = migrationHooks[m.Version]
+	}
+	return migrations, nil
+}
+
+// LatestSchemaVersion returns the schema version that RunMigrations
+// migrates the database to.
+func LatestSchemaVersion() (int, error) {
+	migrations, err := loadEmbeddedMigrations(dialectSQLite)
+	if err != nil {
+		return 0, err
+	}
+	return len(migrations), nil
+}
+
+// MigrateTo migrates the database up or down to the given schema version
+// using the migrations embedded in the binary and returns the number of
+// migrations it performed.
+func MigrateTo(sess *sql.DB, version int) (int, error) {
+	migrations, err := loadEmbeddedMigrations(dialectOf(sess))
+	if err != nil {
+		return 0, err
+	}
+	return migrateTo(sess, migrations, version)
+}
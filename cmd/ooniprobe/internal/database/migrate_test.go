@@ -0,0 +1,168 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/upper/db/v4"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("with the embedded migrations", func(t *testing.T) {
+		migrations, err := loadMigrations(efs, "migrations")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(migrations) < 5 {
+			t.Fatal("expected at least five migrations")
+		}
+		if migrations[3].Name != "4_measurement_collector.sql" {
+			t.Fatal("unexpected migration name", migrations[3].Name)
+		}
+	})
+
+	t.Run("with a gap between versions", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/1_a.sql": {Data: []byte("-- +migrate Up\nSELECT 1;\n-- +migrate Down\nSELECT 1;\n")},
+			"m/3_b.sql": {Data: []byte("-- +migrate Up\nSELECT 1;\n-- +migrate Down\nSELECT 1;\n")},
+		}
+		_, err := loadMigrations(fsys, "m")
+		if !errors.Is(err, ErrInvalidMigration) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with an invalid file name", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/antani.sql": {Data: []byte("-- +migrate Up\nSELECT 1;\n-- +migrate Down\nSELECT 1;\n")},
+		}
+		_, err := loadMigrations(fsys, "m")
+		if !errors.Is(err, ErrInvalidMigration) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("without a down section", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/1_a.sql": {Data: []byte("-- +migrate Up\nSELECT 1;\n")},
+		}
+		_, err := loadMigrations(fsys, "m")
+		if !errors.Is(err, ErrInvalidMigration) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with statements outside of sections", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/1_a.sql": {Data: []byte("SELECT 1;\n-- +migrate Up\nSELECT 1;\n-- +migrate Down\nSELECT 1;\n")},
+		}
+		_, err := loadMigrations(fsys, "m")
+		if !errors.Is(err, ErrInvalidMigration) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
+
+// newMigrationsTestDB returns a connected test database along with
+// the function to call for removing it.
+func newMigrationsTestDB(t *testing.T) (db.Session, *sql.DB, func()) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess, sess.Driver().(*sql.DB), func() {
+		sess.Close()
+		os.Remove(tmpfile.Name())
+	}
+}
+
+func TestMigrateTo(t *testing.T) {
+	sess, sqldb, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := SchemaVersion(sqldb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != latest {
+		t.Fatal("expected the latest schema version", version, latest)
+	}
+	count, err := MigrateTo(sqldb, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != latest {
+		t.Fatal("unexpected number of migrations", count)
+	}
+	if exists, _ := sess.Collection("results").Exists(); exists {
+		t.Fatal("expected the results table to be gone")
+	}
+	if err := RunMigrations(sqldb); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := sess.Collection("results").Exists(); !exists {
+		t.Fatal("expected the results table to exist")
+	}
+	if _, err := MigrateTo(sqldb, latest+1); !errors.Is(err, ErrNoSuchSchemaVersion) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestMigrationsWithSchemaTooNew(t *testing.T) {
+	_, sqldb, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	_, err := sqldb.Exec(`INSERT INTO schema_version (version, name, applied_at)
+		VALUES (1000, '1000_future.sql', CURRENT_TIMESTAMP)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigrations(sqldb); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestMigrationsImportLegacyTable(t *testing.T) {
+	_, sqldb, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	// Emulate a database migrated to version 3 by an older release.
+	if _, err := MigrateTo(sqldb, 3); err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		`DROP TABLE schema_version`,
+		`CREATE TABLE gorp_migrations (id VARCHAR(255) PRIMARY KEY, applied_at DATETIME)`,
+		`INSERT INTO gorp_migrations (id, applied_at) VALUES
+			('1_create_msmt_results.sql', CURRENT_TIMESTAMP),
+			('2_single_msmt_file.sql', CURRENT_TIMESTAMP),
+			('3_results_is_uploaded.sql', CURRENT_TIMESTAMP)`,
+	}
+	for _, stmt := range statements {
+		if _, err := sqldb.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version, err := SchemaVersion(sqldb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 {
+		t.Fatal("expected to import the legacy migrations", version)
+	}
+	if err := RunMigrations(sqldb); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqldb.Exec(`SELECT collector_address FROM measurements`); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,36 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE `results`
+DROP COLUMN result_platform;
+
+ALTER TABLE `results`
+DROP COLUMN result_engine_version;
+
+ALTER TABLE `results`
+DROP COLUMN result_software_version;
+
+ALTER TABLE `results`
+DROP COLUMN result_software_name;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The software that produced the result, such that users can correlate
+-- behavior changes with upgrades. These columns are NULL for the results
+-- produced by older releases.
+ALTER TABLE `results`
+ADD COLUMN result_software_name VARCHAR(64);
+
+ALTER TABLE `results`
+ADD COLUMN result_software_version VARCHAR(64);
+
+ALTER TABLE `results`
+ADD COLUMN result_engine_version VARCHAR(64);
+
+ALTER TABLE `results`
+ADD COLUMN result_platform VARCHAR(32);
+
+-- +migrate StatementEnd
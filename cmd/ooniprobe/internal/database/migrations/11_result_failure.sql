@@ -0,0 +1,23 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE `results`
+DROP COLUMN result_failure_msg;
+
+ALTER TABLE `results`
+DROP COLUMN result_is_failed;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Whether we gave up on a result that a crashed or interrupted run left
+-- incomplete rather than resuming it, and the reason why.
+ALTER TABLE `results`
+ADD COLUMN result_is_failed TINYINT(1) NOT NULL DEFAULT 0;
+
+ALTER TABLE `results`
+ADD COLUMN result_failure_msg VARCHAR(255);
+
+-- +migrate StatementEnd
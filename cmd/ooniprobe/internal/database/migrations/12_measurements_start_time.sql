@@ -0,0 +1,16 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP INDEX `measurements_start_time`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- We paginate measurements by start time and ID (see ListMeasurementsPage)
+-- and this index allows us to seek to any page without scanning.
+CREATE INDEX `measurements_start_time`
+    ON `measurements`(`measurement_start_time`, `measurement_id`);
+
+-- +migrate StatementEnd
@@ -0,0 +1,27 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `measurement_annotations`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The key-value annotations of measurements, which come from the engine
+-- (e.g., "platform") or from the user (e.g., `ooniprobe run -A key=value`).
+CREATE TABLE `measurement_annotations` (
+    `measurement_id` INTEGER NOT NULL,
+    `annotation_key` VARCHAR(64) NOT NULL,
+    `annotation_value` TEXT NOT NULL,
+    PRIMARY KEY (`measurement_id`, `annotation_key`),
+    CONSTRAINT `fk_measurement_id`
+      FOREIGN KEY (`measurement_id`)
+      REFERENCES `measurements`(`measurement_id`)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX `measurement_annotations_key_value`
+    ON `measurement_annotations`(`annotation_key`, `annotation_value`);
+
+-- +migrate StatementEnd
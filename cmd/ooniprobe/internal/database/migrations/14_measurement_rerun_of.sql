@@ -0,0 +1,25 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP INDEX `measurements_rerun_of`;
+
+ALTER TABLE `measurements`
+DROP COLUMN rerun_of_measurement_id;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The original measurement of a re-run measurement (i.e., of a measurement
+-- where measurement_is_rerun is true), such that we can compare them. We do
+-- not declare a foreign key constraint, because SQLite cannot drop columns
+-- having one, hence DeleteResult clears the references to the measurements
+-- it deletes.
+ALTER TABLE `measurements`
+ADD COLUMN rerun_of_measurement_id INTEGER;
+
+CREATE INDEX `measurements_rerun_of`
+    ON `measurements`(`rerun_of_measurement_id`);
+
+-- +migrate StatementEnd
@@ -0,0 +1,31 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `events`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The lifecycle events of the probe (e.g., "run_started", "check_in"), which
+-- give users a timeline of what the probe did in unattended mode. We keep
+-- the events of deleted results, hence ON DELETE SET NULL.
+CREATE TABLE `events` (
+    `event_id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `event_time` DATETIME NOT NULL,
+    `event_kind` VARCHAR(64) NOT NULL,
+    `result_id` INTEGER,
+    `event_details` TEXT NOT NULL, -- JSON object
+    CONSTRAINT `fk_result_id`
+      FOREIGN KEY (`result_id`)
+      REFERENCES `results`(`result_id`)
+      ON DELETE SET NULL
+);
+
+CREATE INDEX `events_time`
+    ON `events`(`event_time`);
+CREATE INDEX `events_kind_time`
+    ON `events`(`event_kind`, `event_time`);
+
+-- +migrate StatementEnd
@@ -0,0 +1,21 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `schedule_runs`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- When `ooniprobe daemon` last ran each test group and when it should run
+-- it next, such that restarting the daemon does not reset the schedule. We
+-- also store the schedule, to notice when the user changes it.
+CREATE TABLE `schedule_runs` (
+    `test_group_name` VARCHAR(64) PRIMARY KEY NOT NULL,
+    `schedule_spec` VARCHAR(255) NOT NULL,
+    `last_run_time` DATETIME,
+    `next_run_time` DATETIME NOT NULL
+);
+
+-- +migrate StatementEnd
@@ -0,0 +1,18 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE `results`
+DROP COLUMN result_is_partial;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Whether the run stopped before measuring all the inputs because it
+-- reached its maximum runtime, such that the result is done but it only
+-- covers some of the inputs.
+ALTER TABLE `results`
+ADD COLUMN result_is_partial TINYINT(1) NOT NULL DEFAULT 0;
+
+-- +migrate StatementEnd
@@ -0,0 +1,17 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `kvstore`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+CREATE TABLE `kvstore` (
+    `kv_key` VARCHAR(255) PRIMARY KEY NOT NULL,
+    `kv_value` BLOB NOT NULL,
+    `kv_expires_at` INTEGER
+);
+
+-- +migrate StatementEnd
@@ -0,0 +1,153 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `websites_summaries`;
+DROP TABLE `im_summaries`;
+DROP TABLE `performance_summaries`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- These tables contain the normalized test keys of the measurements of
+-- the performance, im, and websites groups, such that we do not need to
+-- parse JSON when listing results and we can use indexed queries such as
+-- "all the results with upload < 1 Mbit/s". Unlike measurement_summaries,
+-- they contain all the test keys of each group and the result_id.
+CREATE TABLE `performance_summaries` (
+    `measurement_id` INTEGER PRIMARY KEY NOT NULL,
+    `result_id` INTEGER NOT NULL,
+    `test_name` VARCHAR(64) NOT NULL,
+    `upload` REAL, -- ndt only; kbit/s
+    `download` REAL, -- ndt only; kbit/s
+    `ping` REAL, -- ndt only; milliseconds
+    `median_bitrate` REAL, -- dash only; kbit/s
+    `min_playout_delay` REAL, -- dash only; seconds
+    CONSTRAINT `fk_measurement_id`
+      FOREIGN KEY (`measurement_id`)
+      REFERENCES `measurements`(`measurement_id`)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX `performance_summaries_result_id`
+    ON `performance_summaries`(`result_id`);
+CREATE INDEX `performance_summaries_upload`
+    ON `performance_summaries`(`upload`);
+CREATE INDEX `performance_summaries_download`
+    ON `performance_summaries`(`download`);
+
+CREATE TABLE `im_summaries` (
+    `measurement_id` INTEGER PRIMARY KEY NOT NULL,
+    `result_id` INTEGER NOT NULL,
+    `test_name` VARCHAR(64) NOT NULL,
+    `is_anomaly` TINYINT(1),
+    `dns_blocking` TINYINT(1), -- facebook_messenger only
+    `tcp_blocking` TINYINT(1), -- facebook_messenger and telegram only
+    `http_blocking` TINYINT(1), -- telegram only
+    `web_blocking` TINYINT(1), -- telegram and whatsapp only
+    `endpoints_blocking` TINYINT(1), -- whatsapp only
+    `registration_server_blocking` TINYINT(1), -- whatsapp only
+    `backend_status` VARCHAR(64), -- signal only
+    CONSTRAINT `fk_measurement_id`
+      FOREIGN KEY (`measurement_id`)
+      REFERENCES `measurements`(`measurement_id`)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX `im_summaries_result_id`
+    ON `im_summaries`(`result_id`);
+CREATE INDEX `im_summaries_anomaly`
+    ON `im_summaries`(`is_anomaly`, `test_name`);
+
+CREATE TABLE `websites_summaries` (
+    `measurement_id` INTEGER PRIMARY KEY NOT NULL,
+    `result_id` INTEGER NOT NULL,
+    `url_id` INTEGER,
+    `is_anomaly` TINYINT(1),
+    `accessible` TINYINT(1),
+    `blocking` VARCHAR(64),
+    CONSTRAINT `fk_measurement_id`
+      FOREIGN KEY (`measurement_id`)
+      REFERENCES `measurements`(`measurement_id`)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX `websites_summaries_result_id`
+    ON `websites_summaries`(`result_id`);
+CREATE INDEX `websites_summaries_url_id`
+    ON `websites_summaries`(`url_id`);
+CREATE INDEX `websites_summaries_blocking`
+    ON `websites_summaries`(`blocking`);
+
+INSERT INTO `performance_summaries` (
+    `measurement_id`,
+    `result_id`,
+    `test_name`,
+    `upload`,
+    `download`,
+    `ping`,
+    `median_bitrate`,
+    `min_playout_delay`
+) SELECT
+    `measurement_id`,
+    `result_id`,
+    `test_name`,
+    CASE WHEN `test_name` = 'ndt' THEN json_extract(`test_keys`, '$.upload') END,
+    CASE WHEN `test_name` = 'ndt' THEN json_extract(`test_keys`, '$.download') END,
+    CASE WHEN `test_name` = 'ndt' THEN json_extract(`test_keys`, '$.ping') END,
+    CASE WHEN `test_name` = 'dash' THEN json_extract(`test_keys`, '$.median_bitrate') END,
+    CASE WHEN `test_name` = 'dash' THEN json_extract(`test_keys`, '$.min_playout_delay') END
+  FROM `measurements`
+  WHERE `test_name` IN ('ndt', 'dash')
+    AND json_valid(`test_keys`) AND json_type(`test_keys`) = 'object';
+
+INSERT INTO `im_summaries` (
+    `measurement_id`,
+    `result_id`,
+    `test_name`,
+    `is_anomaly`,
+    `dns_blocking`,
+    `tcp_blocking`,
+    `http_blocking`,
+    `web_blocking`,
+    `endpoints_blocking`,
+    `registration_server_blocking`,
+    `backend_status`
+) SELECT
+    `measurement_id`,
+    `result_id`,
+    `test_name`,
+    `is_anomaly`,
+    json_extract(`test_keys`, '$.facebook_dns_blocking'),
+    COALESCE(json_extract(`test_keys`, '$.facebook_tcp_blocking'),
+        json_extract(`test_keys`, '$.telegram_tcp_blocking')),
+    json_extract(`test_keys`, '$.telegram_http_blocking'),
+    COALESCE(json_extract(`test_keys`, '$.telegram_web_blocking'),
+        json_extract(`test_keys`, '$.whatsapp_web_blocking')),
+    json_extract(`test_keys`, '$.whatsapp_endpoints_blocking'),
+    json_extract(`test_keys`, '$.registration_server_blocking'),
+    json_extract(`test_keys`, '$.signal_backend_status')
+  FROM `measurements`
+  WHERE `test_name` IN ('facebook_messenger', 'telegram', 'whatsapp', 'signal')
+    AND json_valid(`test_keys`) AND json_type(`test_keys`) = 'object';
+
+INSERT INTO `websites_summaries` (
+    `measurement_id`,
+    `result_id`,
+    `url_id`,
+    `is_anomaly`,
+    `accessible`,
+    `blocking`
+) SELECT
+    `measurement_id`,
+    `result_id`,
+    `url_id`,
+    `is_anomaly`,
+    json_extract(`test_keys`, '$.accessible'),
+    json_extract(`test_keys`, '$.blocking')
+  FROM `measurements`
+  WHERE `test_name` = 'web_connectivity'
+    AND json_valid(`test_keys`) AND json_type(`test_keys`) = 'object';
+
+-- +migrate StatementEnd
@@ -0,0 +1,31 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP INDEX `networks_asn_country_code`;
+DROP INDEX `measurements_result_anomaly`;
+DROP INDEX `results_network_id`;
+DROP INDEX `results_group_start_time`;
+DROP INDEX `results_done_start_time`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- ListResultsMatching selects the done or incomplete results, optionally
+-- by start time, test group, and network, ordered by start time.
+CREATE INDEX `results_done_start_time`
+    ON `results`(`result_is_done`, `result_start_time`);
+CREATE INDEX `results_group_start_time`
+    ON `results`(`test_group_name`, `result_start_time`);
+CREATE INDEX `results_network_id`
+    ON `results`(`network_id`);
+CREATE INDEX `networks_asn_country_code`
+    ON `networks`(`asn`, `network_country_code`);
+
+-- It also joins the measurements of each result to count the anomalies,
+-- and it selects the results having an anomaly with a subquery.
+CREATE INDEX `measurements_result_anomaly`
+    ON `measurements`(`result_id`, `is_anomaly`);
+
+-- +migrate StatementEnd
@@ -0,0 +1,15 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE `measurements`
+DROP COLUMN collector_address;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+ALTER TABLE `measurements`
+ADD COLUMN collector_address VARCHAR(255);
+
+-- +migrate StatementEnd
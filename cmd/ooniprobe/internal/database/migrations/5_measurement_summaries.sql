@@ -0,0 +1,57 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `measurement_summaries`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The measurement_summaries table contains the normalized content of
+-- the measurements test_keys column, which we cannot efficiently query.
+CREATE TABLE `measurement_summaries` (
+    `measurement_id` INTEGER PRIMARY KEY NOT NULL,
+    `test_name` VARCHAR(64) NOT NULL,
+    `is_anomaly` TINYINT(1),
+    `accessible` TINYINT(1), -- web_connectivity only
+    `blocking` VARCHAR(64), -- web_connectivity only
+    `upload` REAL, -- ndt only
+    `download` REAL, -- ndt only
+    `ping` REAL, -- ndt only
+    `median_bitrate` REAL, -- dash only
+    CONSTRAINT `fk_measurement_id`
+      FOREIGN KEY (`measurement_id`)
+      REFERENCES `measurements`(`measurement_id`)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX `measurement_summaries_anomaly`
+    ON `measurement_summaries`(`is_anomaly`, `test_name`);
+
+INSERT INTO `measurement_summaries` (
+    `measurement_id`,
+    `test_name`,
+    `is_anomaly`,
+    `accessible`,
+    `blocking`,
+    `upload`,
+    `download`,
+    `ping`,
+    `median_bitrate`
+) SELECT
+    `measurement_id`,
+    `test_name`,
+    `is_anomaly`,
+    CASE WHEN `test_name` = 'web_connectivity'
+        THEN json_extract(`test_keys`, '$.accessible') END,
+    CASE WHEN `test_name` = 'web_connectivity'
+        THEN json_extract(`test_keys`, '$.blocking') END,
+    CASE WHEN `test_name` = 'ndt' THEN json_extract(`test_keys`, '$.upload') END,
+    CASE WHEN `test_name` = 'ndt' THEN json_extract(`test_keys`, '$.download') END,
+    CASE WHEN `test_name` = 'ndt' THEN json_extract(`test_keys`, '$.ping') END,
+    CASE WHEN `test_name` = 'dash' THEN json_extract(`test_keys`, '$.median_bitrate') END
+  FROM `measurements`
+  WHERE json_valid(`test_keys`) AND json_type(`test_keys`) = 'object';
+
+-- +migrate StatementEnd
@@ -0,0 +1,37 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `result_notes`;
+DROP TABLE `measurement_tags`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- User-defined tags annotating measurements (e.g., "mobile hotspot").
+CREATE TABLE `measurement_tags` (
+    `measurement_id` INTEGER NOT NULL,
+    `tag` VARCHAR(64) NOT NULL,
+    PRIMARY KEY (`measurement_id`, `tag`),
+    CONSTRAINT `fk_measurement_id`
+      FOREIGN KEY (`measurement_id`)
+      REFERENCES `measurements`(`measurement_id`)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX `measurement_tags_tag` ON `measurement_tags`(`tag`);
+
+-- Free-form notes annotating results (e.g., "power outage").
+CREATE TABLE `result_notes` (
+    `note_id` INTEGER PRIMARY KEY AUTOINCREMENT,
+    `result_id` INTEGER NOT NULL,
+    `note_text` TEXT NOT NULL,
+    `note_created_at` DATETIME NOT NULL,
+    CONSTRAINT `fk_result_id`
+      FOREIGN KEY (`result_id`)
+      REFERENCES `results`(`result_id`)
+      ON DELETE CASCADE
+);
+
+-- +migrate StatementEnd
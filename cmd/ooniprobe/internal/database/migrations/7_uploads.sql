@@ -0,0 +1,47 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE `uploads`;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The uploads table tracks the submission of each measurement that we
+-- failed to upload at least once. It supersedes the measurements
+-- is_upload_failed column, which we keep for compatibility.
+CREATE TABLE `uploads` (
+    `measurement_id` INTEGER PRIMARY KEY NOT NULL,
+    `upload_attempts` INTEGER NOT NULL DEFAULT 0,
+    `upload_last_error` TEXT,
+    `upload_last_attempt_at` DATETIME,
+    `upload_next_retry_at` DATETIME,
+    `upload_is_done` TINYINT(1) NOT NULL DEFAULT 0,
+    CONSTRAINT `fk_measurement_id`
+      FOREIGN KEY (`measurement_id`)
+      REFERENCES `measurements`(`measurement_id`)
+      ON DELETE CASCADE
+);
+
+CREATE INDEX `uploads_pending`
+    ON `uploads`(`upload_is_done`, `upload_next_retry_at`);
+
+INSERT INTO `uploads` (
+    `measurement_id`,
+    `upload_attempts`,
+    `upload_last_error`,
+    `upload_next_retry_at`,
+    `upload_is_done`
+) SELECT
+    `measurement_id`,
+    1,
+    `measurement_upload_failure_msg`,
+    datetime('now'),
+    0
+  FROM `measurements`
+  WHERE `measurement_is_uploaded` = 0
+    AND (`measurement_is_upload_failed` = 1
+      OR `measurement_upload_failure_msg` IS NOT NULL);
+
+-- +migrate StatementEnd
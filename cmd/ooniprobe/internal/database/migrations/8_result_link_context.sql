@@ -0,0 +1,24 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE `results`
+DROP COLUMN result_downlink_kbps;
+
+ALTER TABLE `results`
+DROP COLUMN result_link_type;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The link type is one of wifi, mobile, wired, or NULL when unknown.
+ALTER TABLE `results`
+ADD COLUMN result_link_type VARCHAR(16);
+
+-- The downlink estimate in kbit/s from the most recent performance
+-- measurement on the same network, or NULL when unknown.
+ALTER TABLE `results`
+ADD COLUMN result_downlink_kbps REAL;
+
+-- +migrate StatementEnd
@@ -0,0 +1,16 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP INDEX urls_url_country_code_idx;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Before creating this index, the mergeDuplicateURLs hook normalizes
+-- the URLs and merges the duplicates, repointing their measurements.
+CREATE UNIQUE INDEX urls_url_country_code_idx
+ON `urls`(`url`, `url_country_code`);
+
+-- +migrate StatementEnd
@@ -0,0 +1,19 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE "kvstore";
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Unlike SQLite, PostgreSQL INTEGER columns have 32 bits, which are not
+-- enough for the Unix time of the expiration.
+CREATE TABLE "kvstore" (
+    "kv_key" VARCHAR(255) PRIMARY KEY NOT NULL,
+    "kv_value" BYTEA NOT NULL,
+    "kv_expires_at" BIGINT
+);
+
+-- +migrate StatementEnd
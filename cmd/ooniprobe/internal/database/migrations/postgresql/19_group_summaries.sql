@@ -0,0 +1,151 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE "websites_summaries";
+DROP TABLE "im_summaries";
+DROP TABLE "performance_summaries";
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- See the SQLite migration with the same name. We use the PostgreSQL
+-- JSON operators to fill these tables from the existing measurements,
+-- whose test keys are valid JSON, since SetTestKeys validates them.
+CREATE TABLE "performance_summaries" (
+    "measurement_id" INTEGER PRIMARY KEY NOT NULL,
+    "result_id" INTEGER NOT NULL,
+    "test_name" VARCHAR(64) NOT NULL,
+    "upload" DOUBLE PRECISION, -- ndt only; kbit/s
+    "download" DOUBLE PRECISION, -- ndt only; kbit/s
+    "ping" DOUBLE PRECISION, -- ndt only; milliseconds
+    "median_bitrate" DOUBLE PRECISION, -- dash only; kbit/s
+    "min_playout_delay" DOUBLE PRECISION, -- dash only; seconds
+    CONSTRAINT "fk_measurement_id"
+      FOREIGN KEY ("measurement_id")
+      REFERENCES "measurements"("measurement_id")
+      ON DELETE CASCADE
+);
+
+CREATE INDEX "performance_summaries_result_id"
+    ON "performance_summaries"("result_id");
+CREATE INDEX "performance_summaries_upload"
+    ON "performance_summaries"("upload");
+CREATE INDEX "performance_summaries_download"
+    ON "performance_summaries"("download");
+
+CREATE TABLE "im_summaries" (
+    "measurement_id" INTEGER PRIMARY KEY NOT NULL,
+    "result_id" INTEGER NOT NULL,
+    "test_name" VARCHAR(64) NOT NULL,
+    "is_anomaly" BOOLEAN,
+    "dns_blocking" BOOLEAN, -- facebook_messenger only
+    "tcp_blocking" BOOLEAN, -- facebook_messenger and telegram only
+    "http_blocking" BOOLEAN, -- telegram only
+    "web_blocking" BOOLEAN, -- telegram and whatsapp only
+    "endpoints_blocking" BOOLEAN, -- whatsapp only
+    "registration_server_blocking" BOOLEAN, -- whatsapp only
+    "backend_status" VARCHAR(64), -- signal only
+    CONSTRAINT "fk_measurement_id"
+      FOREIGN KEY ("measurement_id")
+      REFERENCES "measurements"("measurement_id")
+      ON DELETE CASCADE
+);
+
+CREATE INDEX "im_summaries_result_id"
+    ON "im_summaries"("result_id");
+CREATE INDEX "im_summaries_anomaly"
+    ON "im_summaries"("is_anomaly", "test_name");
+
+CREATE TABLE "websites_summaries" (
+    "measurement_id" INTEGER PRIMARY KEY NOT NULL,
+    "result_id" INTEGER NOT NULL,
+    "url_id" INTEGER,
+    "is_anomaly" BOOLEAN,
+    "accessible" BOOLEAN,
+    "blocking" VARCHAR(64),
+    CONSTRAINT "fk_measurement_id"
+      FOREIGN KEY ("measurement_id")
+      REFERENCES "measurements"("measurement_id")
+      ON DELETE CASCADE
+);
+
+CREATE INDEX "websites_summaries_result_id"
+    ON "websites_summaries"("result_id");
+CREATE INDEX "websites_summaries_url_id"
+    ON "websites_summaries"("url_id");
+CREATE INDEX "websites_summaries_blocking"
+    ON "websites_summaries"("blocking");
+
+INSERT INTO "performance_summaries" (
+    "measurement_id",
+    "result_id",
+    "test_name",
+    "upload",
+    "download",
+    "ping",
+    "median_bitrate",
+    "min_playout_delay"
+) SELECT
+    "measurement_id",
+    "result_id",
+    "test_name",
+    CASE WHEN "test_name" = 'ndt' THEN ("test_keys"::jsonb ->> 'upload')::DOUBLE PRECISION END,
+    CASE WHEN "test_name" = 'ndt' THEN ("test_keys"::jsonb ->> 'download')::DOUBLE PRECISION END,
+    CASE WHEN "test_name" = 'ndt' THEN ("test_keys"::jsonb ->> 'ping')::DOUBLE PRECISION END,
+    CASE WHEN "test_name" = 'dash' THEN ("test_keys"::jsonb ->> 'median_bitrate')::DOUBLE PRECISION END,
+    CASE WHEN "test_name" = 'dash' THEN ("test_keys"::jsonb ->> 'min_playout_delay')::DOUBLE PRECISION END
+  FROM "measurements"
+  WHERE "test_name" IN ('ndt', 'dash')
+    AND jsonb_typeof("test_keys"::jsonb) = 'object';
+
+INSERT INTO "im_summaries" (
+    "measurement_id",
+    "result_id",
+    "test_name",
+    "is_anomaly",
+    "dns_blocking",
+    "tcp_blocking",
+    "http_blocking",
+    "web_blocking",
+    "endpoints_blocking",
+    "registration_server_blocking",
+    "backend_status"
+) SELECT
+    "measurement_id",
+    "result_id",
+    "test_name",
+    "is_anomaly",
+    ("test_keys"::jsonb ->> 'facebook_dns_blocking')::BOOLEAN,
+    COALESCE("test_keys"::jsonb ->> 'facebook_tcp_blocking',
+        "test_keys"::jsonb ->> 'telegram_tcp_blocking')::BOOLEAN,
+    ("test_keys"::jsonb ->> 'telegram_http_blocking')::BOOLEAN,
+    COALESCE("test_keys"::jsonb ->> 'telegram_web_blocking',
+        "test_keys"::jsonb ->> 'whatsapp_web_blocking')::BOOLEAN,
+    ("test_keys"::jsonb ->> 'whatsapp_endpoints_blocking')::BOOLEAN,
+    ("test_keys"::jsonb ->> 'registration_server_blocking')::BOOLEAN,
+    "test_keys"::jsonb ->> 'signal_backend_status'
+  FROM "measurements"
+  WHERE "test_name" IN ('facebook_messenger', 'telegram', 'whatsapp', 'signal')
+    AND jsonb_typeof("test_keys"::jsonb) = 'object';
+
+INSERT INTO "websites_summaries" (
+    "measurement_id",
+    "result_id",
+    "url_id",
+    "is_anomaly",
+    "accessible",
+    "blocking"
+) SELECT
+    "measurement_id",
+    "result_id",
+    "url_id",
+    "is_anomaly",
+    ("test_keys"::jsonb ->> 'accessible')::BOOLEAN,
+    "test_keys"::jsonb ->> 'blocking'
+  FROM "measurements"
+  WHERE "test_name" = 'web_connectivity'
+    AND jsonb_typeof("test_keys"::jsonb) = 'object';
+
+-- +migrate StatementEnd
@@ -0,0 +1,24 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+ALTER TABLE "measurements"
+DROP COLUMN "measurement_file_path";
+
+ALTER TABLE "measurements"
+ALTER COLUMN "report_file_path" SET NOT NULL;
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- Unlike SQLite, PostgreSQL can drop constraints and add columns, hence
+-- we do not need to re-create the table and copy the data over.
+
+ALTER TABLE "measurements"
+ALTER COLUMN "report_file_path" DROP NOT NULL;
+
+ALTER TABLE "measurements"
+ADD COLUMN "measurement_file_path" TEXT;
+
+-- +migrate StatementEnd
@@ -0,0 +1,34 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE "measurement_summaries";
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The measurement_summaries table contains the normalized content of
+-- the measurements test_keys column, which we cannot efficiently query.
+-- Unlike SQLite, we do not fill it from the existing measurements,
+-- because there are none when we create a PostgreSQL database.
+CREATE TABLE "measurement_summaries" (
+    "measurement_id" INTEGER PRIMARY KEY NOT NULL,
+    "test_name" VARCHAR(64) NOT NULL,
+    "is_anomaly" BOOLEAN,
+    "accessible" BOOLEAN, -- web_connectivity only
+    "blocking" VARCHAR(64), -- web_connectivity only
+    "upload" DOUBLE PRECISION, -- ndt only
+    "download" DOUBLE PRECISION, -- ndt only
+    "ping" DOUBLE PRECISION, -- ndt only
+    "median_bitrate" DOUBLE PRECISION, -- dash only
+    CONSTRAINT "fk_measurement_id"
+      FOREIGN KEY ("measurement_id")
+      REFERENCES "measurements"("measurement_id")
+      ON DELETE CASCADE
+);
+
+CREATE INDEX "measurement_summaries_anomaly"
+    ON "measurement_summaries"("is_anomaly", "test_name");
+
+-- +migrate StatementEnd
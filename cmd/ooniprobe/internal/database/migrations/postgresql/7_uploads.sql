@@ -0,0 +1,31 @@
+-- +migrate Down
+-- +migrate StatementBegin
+
+DROP TABLE "uploads";
+
+-- +migrate StatementEnd
+
+-- +migrate Up
+-- +migrate StatementBegin
+
+-- The uploads table tracks the submission of each measurement that we
+-- failed to upload at least once. Unlike SQLite, we do not fill it from
+-- the existing measurements, because there are none when we create a
+-- PostgreSQL database.
+CREATE TABLE "uploads" (
+    "measurement_id" INTEGER PRIMARY KEY NOT NULL,
+    "upload_attempts" INTEGER NOT NULL DEFAULT 0,
+    "upload_last_error" TEXT,
+    "upload_last_attempt_at" TIMESTAMPTZ,
+    "upload_next_retry_at" TIMESTAMPTZ,
+    "upload_is_done" BOOLEAN NOT NULL DEFAULT FALSE,
+    CONSTRAINT "fk_measurement_id"
+      FOREIGN KEY ("measurement_id")
+      REFERENCES "measurements"("measurement_id")
+      ON DELETE CASCADE
+);
+
+CREATE INDEX "uploads_pending"
+    ON "uploads"("upload_is_done", "upload_next_retry_at");
+
+-- +migrate StatementEnd
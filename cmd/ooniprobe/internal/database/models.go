@@ -15,13 +15,29 @@ type ResultNetwork struct {
 	Network      `db:",inline"`
 	AnomalyCount uint64 `db:"anomaly_count"`
 	TotalCount   uint64 `db:"total_count"`
-	TestKeys     string `db:"test_keys"`
+
+	// These fields come from the performance_summaries table and
+	// they are only valid for the results of the performance group.
+	Upload        sql.NullFloat64 `db:"upload"`
+	Download      sql.NullFloat64 `db:"download"`
+	Ping          sql.NullFloat64 `db:"ping"`
+	MedianBitrate sql.NullFloat64 `db:"median_bitrate"`
+}
+
+// PerformanceTestKeys returns the performance summary of the result.
+func (r *ResultNetwork) PerformanceTestKeys() *PerformanceTestKeys {
+	return &PerformanceTestKeys{
+		Upload:   r.Upload.Float64,
+		Download: r.Download.Float64,
+		Ping:     r.Ping.Float64,
+		Bitrate:  r.MedianBitrate.Float64,
+	}
 }
 
 // UploadedTotalCount is the count of the measurements which have been uploaded vs the total measurements in a given result set
 type UploadedTotalCount struct {
-	UploadedCount int64 `db:",inline"`
-	TotalCount    int64 `db:",inline"`
+	UploadedCount int64 `db:"uploaded_count"`
+	TotalCount    int64 `db:"total_count"`
 }
 
 // MeasurementURLNetwork is used for the JOIN between Measurement and URL
@@ -30,6 +46,14 @@ type MeasurementURLNetwork struct {
 	Network     `db:",inline"`
 	Result      `db:",inline"`
 	URL         `db:",inline"`
+
+	// Annotations contains the annotations of the measurement, which
+	// we load separately (see loadMeasurementAnnotations).
+	Annotations map[string]string `db:"-"`
+
+	// RerunOfMeasurement is the original measurement of a re-run
+	// measurement, which we load separately (see loadRerunOriginals).
+	RerunOfMeasurement *Measurement `db:"-"`
 }
 
 // Network represents a network tested by the user
@@ -60,18 +84,20 @@ type Measurement struct {
 	IsUploaded       bool           `db:"measurement_is_uploaded"`
 	IsFailed         bool           `db:"measurement_is_failed"`
 	FailureMsg       sql.NullString `db:"measurement_failure_msg,omitempty"`
-	IsUploadFailed   bool           `db:"measurement_is_upload_failed"`
+	IsUploadFailed   bool           `db:"measurement_is_upload_failed"` // Superseded by the uploads table
 	UploadFailureMsg sql.NullString `db:"measurement_upload_failure_msg,omitempty"`
 	IsRerun          bool           `db:"measurement_is_rerun"`
+	RerunOf          sql.NullInt64  `db:"rerun_of_measurement_id,omitempty"` // Original of a re-run measurement
 	ReportID         sql.NullString `db:"report_id,omitempty"`
 	URLID            sql.NullInt64  `db:"url_id,omitempty"` // Used to reference URL
 	MeasurementID    sql.NullInt64  `db:"collector_measurement_id,omitempty"`
 	IsAnomaly        sql.NullBool   `db:"is_anomaly,omitempty"`
-	// FIXME we likely want to support JSON. See: https://github.com/upper/db/issues/462
+	// Use DecodeTestKeys and SetTestKeys to access the JSON summary in TestKeys.
 	TestKeys            string         `db:"test_keys"`
 	ResultID            int64          `db:"result_id"`
 	ReportFilePath      sql.NullString `db:"report_file_path,omitempty"`
 	MeasurementFilePath sql.NullString `db:"measurement_file_path,omitempty"`
+	CollectorAddress    sql.NullString `db:"collector_address,omitempty"` // Collector that accepted the report
 }
 
 // Result model
@@ -87,6 +113,82 @@ type Result struct {
 	DataUsageUp    float64   `db:"result_data_usage_up"`
 	DataUsageDown  float64   `db:"result_data_usage_down"`
 	MeasurementDir string    `db:"measurement_dir"`
+
+	// LinkType and DownlinkKbps describe the link we were using and
+	// they are not valid when we don't know (see LinkContext).
+	LinkType     sql.NullString  `db:"result_link_type,omitempty"`
+	DownlinkKbps sql.NullFloat64 `db:"result_downlink_kbps,omitempty"`
+
+	// These fields describe the software that produced the result and
+	// they are not valid for results produced by older releases.
+	SoftwareName    sql.NullString `db:"result_software_name,omitempty"`
+	SoftwareVersion sql.NullString `db:"result_software_version,omitempty"`
+	EngineVersion   sql.NullString `db:"result_engine_version,omitempty"`
+	Platform        sql.NullString `db:"result_platform,omitempty"`
+
+	// IsFailed and FailureMsg indicate that we gave up on a result
+	// that was left incomplete (see FailResult).
+	IsFailed   bool           `db:"result_is_failed"`
+	FailureMsg sql.NullString `db:"result_failure_msg,omitempty"`
+
+	// IsPartial indicates that the run stopped before measuring all
+	// the inputs because it reached its maximum runtime.
+	IsPartial bool `db:"result_is_partial"`
+}
+
+// KeyValue is an entry of the key-value store
+type KeyValue struct {
+	Key       string        `db:"kv_key"`
+	Value     []byte        `db:"kv_value"`
+	ExpiresAt sql.NullInt64 `db:"kv_expires_at"` // Unix time; not valid means no expiry
+}
+
+// MeasurementTag is a user-defined tag annotating a measurement
+type MeasurementTag struct {
+	MeasurementID int64  `db:"measurement_id"`
+	Tag           string `db:"tag"`
+}
+
+// MeasurementAnnotation is a key-value annotation of a measurement
+type MeasurementAnnotation struct {
+	MeasurementID int64  `db:"measurement_id"`
+	Key           string `db:"annotation_key"`
+	Value         string `db:"annotation_value"`
+}
+
+// ResultNote is a free-form note annotating a result
+type ResultNote struct {
+	ID        int64     `db:"note_id,omitempty"`
+	ResultID  int64     `db:"result_id"`
+	Text      string    `db:"note_text"`
+	CreatedAt time.Time `db:"note_created_at"`
+}
+
+// Upload tracks the submission of a measurement we failed to upload
+type Upload struct {
+	MeasurementID int64          `db:"measurement_id"`
+	Attempts      int64          `db:"upload_attempts"`
+	LastError     sql.NullString `db:"upload_last_error"`
+	LastAttemptAt sql.NullTime   `db:"upload_last_attempt_at"`
+	NextRetryAt   sql.NullTime   `db:"upload_next_retry_at"` // Not valid when IsDone
+	IsDone        bool           `db:"upload_is_done"`
+}
+
+// Event is an entry of the timeline of what the probe did
+type Event struct {
+	ID       int64         `db:"event_id,omitempty"`
+	Time     time.Time     `db:"event_time"`
+	Kind     string        `db:"event_kind"`
+	ResultID sql.NullInt64 `db:"result_id,omitempty"`
+	Details  string        `db:"event_details"` // JSON object
+}
+
+// ScheduleRun tracks the runs of a test group by `ooniprobe daemon`
+type ScheduleRun struct {
+	GroupName    string       `db:"test_group_name"`
+	ScheduleSpec string       `db:"schedule_spec"`
+	LastRunTime  sql.NullTime `db:"last_run_time"`
+	NextRunTime  time.Time    `db:"next_run_time"`
 }
 
 // PerformanceTestKeys is the result summary for a performance test
@@ -137,24 +239,33 @@ func (m *Measurement) Done(sess db.Session) error {
 }
 
 // UploadFailed writes the error string for the upload failure to the measurement
+// and schedules another upload attempt in the uploads table
 func (m *Measurement) UploadFailed(sess db.Session, failure string) error {
 	m.UploadFailureMsg = sql.NullString{String: failure, Valid: true}
 	m.IsUploaded = false
+	m.IsUploadFailed = true
 
-	err := sess.Collection("measurements").Find("measurement_id", m.ID).Update(m)
-	if err != nil {
-		return errors.Wrap(err, "updating measurement")
-	}
-	return nil
+	return sess.Tx(func(tx db.Session) error {
+		err := tx.Collection("measurements").Find("measurement_id", m.ID).Update(m)
+		if err != nil {
+			return errors.Wrap(err, "updating measurement")
+		}
+		_, err = recordUploadFailure(tx, m.ID, failure, time.Now().UTC())
+		return err
+	})
 }
 
-// UploadSucceeded writes the error string for the upload failure to the measurement
-func (m *Measurement) UploadSucceeded(sess db.Session) error {
+// UploadSucceeded marks the measurement as uploaded to the given collector
+func (m *Measurement) UploadSucceeded(sess db.Session, collectorAddress string) error {
 	m.IsUploaded = true
+	m.IsUploadFailed = false
+	m.CollectorAddress = sql.NullString{String: collectorAddress, Valid: collectorAddress != ""}
 
-	err := sess.Collection("measurements").Find("measurement_id", m.ID).Update(m)
-	if err != nil {
-		return errors.Wrap(err, "updating measurement")
-	}
-	return nil
+	return sess.Tx(func(tx db.Session) error {
+		err := tx.Collection("measurements").Find("measurement_id", m.ID).Update(m)
+		if err != nil {
+			return errors.Wrap(err, "updating measurement")
+		}
+		return recordUploadSuccess(tx, m.ID, time.Now().UTC())
+	})
 }
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// NetworkHistory summarizes the runs on a network, which we identify
+// by ASN and country code, regardless of its name.
+type NetworkHistory struct {
+	// AnomalyStats contains the anomaly stats of all the runs.
+	AnomalyStats
+
+	// ASN is the network ASN.
+	ASN uint
+
+	// CountryCode is the network country code.
+	CountryCode string
+
+	// NetworkName is the network name of the most recent run.
+	NetworkName string
+
+	// FirstSeen is the start time of the first run.
+	FirstSeen time.Time
+
+	// LastSeen is the start time of the most recent run.
+	LastSeen time.Time
+
+	// RunCount is the number of runs, i.e., of results.
+	RunCount uint64
+
+	// RecentStats contains the anomaly stats of the runs started
+	// at or after the recentSince argument of ListNetworkHistories.
+	RecentStats AnomalyStats
+}
+
+// Trend returns the difference between the anomaly rate of the recent
+// runs and the anomaly rate of the previous runs, thus a positive trend
+// means that we are seeing more anomalies. The trend is zero when there
+// are no recent runs or no previous runs.
+func (h *NetworkHistory) Trend() float64 {
+	previous := AnomalyStats{
+		TotalCount:   h.TotalCount - h.RecentStats.TotalCount,
+		AnomalyCount: h.AnomalyCount - h.RecentStats.AnomalyCount,
+	}
+	if previous.TotalCount <= 0 || h.RecentStats.TotalCount <= 0 {
+		return 0
+	}
+	return h.RecentStats.AnomalyRate() - previous.AnomalyRate()
+}
+
+// ListNetworkHistories returns the history of each network, starting
+// from the most recently seen one. The recentSince argument is the
+// time after which we consider runs as recent to compute the trend.
+func ListNetworkHistories(sess db.Session, recentSince time.Time) ([]NetworkHistory, error) {
+	var runs []struct {
+		AnomalyStats `db:",inline"`
+		StartTime    time.Time `db:"result_start_time"`
+		ASN          uint      `db:"asn"`
+		NetworkName  string    `db:"network_name"`
+		CountryCode  string    `db:"network_country_code"`
+	}
+	err := sess.SQL().Select(
+		db.Raw("results.result_start_time"),
+		db.Raw("networks.asn"),
+		db.Raw("networks.network_name"),
+		db.Raw("networks.network_country_code"),
+		// We only count measurements for which we know whether
+		// there's an anomaly, like AnomalyStats does.
+		db.Raw("COUNT(measurements.is_anomaly) AS total_count"),
+		statsAnomalyCount,
+	).From("results").
+		Join("networks").On("networks.network_id = results.network_id").
+		LeftJoin("measurements").On("measurements.result_id = results.result_id").
+		GroupBy(db.Raw("results.result_id"), db.Raw("networks.network_id")).
+		OrderBy("results.result_start_time").
+		All(&runs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list network runs")
+	}
+	type networkKey struct {
+		asn         uint
+		countryCode string
+	}
+	index := make(map[networkKey]*NetworkHistory)
+	var histories []*NetworkHistory
+	for _, run := range runs {
+		key := networkKey{asn: run.ASN, countryCode: run.CountryCode}
+		h, found := index[key]
+		if !found {
+			h = &NetworkHistory{ASN: run.ASN, CountryCode: run.CountryCode, FirstSeen: run.StartTime}
+			index[key] = h
+			histories = append(histories, h)
+		}
+		// The runs are sorted, hence this is the most recent run so far.
+		h.NetworkName = run.NetworkName
+		h.LastSeen = run.StartTime
+		h.RunCount++
+		h.TotalCount += run.TotalCount
+		h.AnomalyCount += run.AnomalyCount
+		if !run.StartTime.Before(recentSince) {
+			h.RecentStats.TotalCount += run.TotalCount
+			h.RecentStats.AnomalyCount += run.AnomalyCount
+		}
+	}
+	out := []NetworkHistory{}
+	for _, h := range histories {
+		out = append(out, *h)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].LastSeen.After(out[j].LastSeen)
+	})
+	return out, nil
+}
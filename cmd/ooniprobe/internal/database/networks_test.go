@@ -0,0 +1,86 @@
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNetworkHistoryTrend(t *testing.T) {
+	h := &NetworkHistory{
+		AnomalyStats: AnomalyStats{TotalCount: 10, AnomalyCount: 3},
+		RecentStats:  AnomalyStats{TotalCount: 2, AnomalyCount: 1},
+	}
+	if trend := h.Trend(); trend != 0.25 {
+		t.Fatal("unexpected trend", trend)
+	}
+	h.RecentStats = AnomalyStats{}
+	if trend := h.Trend(); trend != 0 {
+		t.Fatal("unexpected trend", trend)
+	}
+}
+
+func TestListNetworkHistories(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	now := time.Now().UTC()
+
+	// createRun creates a run on the given network started at the
+	// given time with measurements with the given anomaly flags.
+	createRun := func(asn uint, startTime time.Time, anomalies ...bool) {
+		network, err := CreateNetwork(sess, &locationInfo{asn: asn, countryCode: "IT"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := CreateResult(sess, tmpdir, "im", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result.StartTime = startTime
+		if err := sess.Collection("results").Find("result_id", result.ID).Update(result); err != nil {
+			t.Fatal(err)
+		}
+		for idx, anomaly := range anomalies {
+			msmt, err := CreateMeasurement(sess, sql.NullString{}, "telegram",
+				result.MeasurementDir, idx, result.ID, sql.NullInt64{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msmt.IsAnomaly = sql.NullBool{Bool: anomaly, Valid: true}
+			if err := msmt.Done(sess); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	createRun(30722, now.Add(-60*24*time.Hour), false, false)
+	createRun(3269, now.Add(-40*24*time.Hour), true)
+	createRun(30722, now.Add(-24*time.Hour), true, false)
+
+	histories, err := ListNetworkHistories(sess, now.Add(-30*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(histories) != 2 {
+		t.Fatal("unexpected histories", histories)
+	}
+	h := histories[0]
+	if h.ASN != 30722 || h.RunCount != 2 || h.TotalCount != 4 || h.AnomalyCount != 1 {
+		t.Fatal("unexpected history", h)
+	}
+	if !h.FirstSeen.Equal(now.Add(-60*24*time.Hour)) || !h.LastSeen.Equal(now.Add(-24*time.Hour)) {
+		t.Fatal("unexpected first or last seen", h.FirstSeen, h.LastSeen)
+	}
+	if h.Trend() != 0.5 {
+		t.Fatal("unexpected trend", h.Trend())
+	}
+	if histories[1].ASN != 3269 || histories[1].RunCount != 1 || histories[1].Trend() != 0 {
+		t.Fatal("unexpected history", histories[1])
+	}
+}
@@ -0,0 +1,126 @@
+package database
+
+//
+// Paginated measurement listing.
+//
+// We use cursor-based (aka keyset) pagination ordered by start time and
+// ID, such that each page is an indexed query regardless of how deep we
+// are into the history and such that concurrently adding measurements does
+// not shift the pages. The cursor is opaque to the caller.
+//
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// DefaultPageSize is the page size we use when the caller does not
+// specify a valid page size.
+const DefaultPageSize = 50
+
+// ErrInvalidCursor indicates that a page cursor is not valid.
+var ErrInvalidCursor = errors.New("database: invalid cursor")
+
+// MeasurementPage is a page of measurements.
+type MeasurementPage struct {
+	// Measurements contains the measurements of the page.
+	Measurements []MeasurementURLNetwork
+
+	// TotalCount is the number of measurements selected by the
+	// filter across all the pages.
+	TotalCount uint64
+
+	// NextCursor is the cursor of the next page or the empty
+	// string when this is the last page.
+	NextCursor string
+}
+
+// pageCursor is the position after which a page starts.
+type pageCursor struct {
+	StartTime time.Time
+	ID        int64
+}
+
+// encodePageCursor returns the opaque cursor of the page starting
+// after the given measurement.
+func encodePageCursor(m *Measurement) string {
+	value := fmt.Sprintf("%s/%d", m.StartTime.UTC().Format(time.RFC3339Nano), m.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+// decodePageCursor parses a cursor returned by encodePageCursor.
+func decodePageCursor(cursor string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err.Error())
+	}
+	v := strings.SplitN(string(data), "/", 2)
+	if len(v) != 2 {
+		return nil, fmt.Errorf("%w: missing separator", ErrInvalidCursor)
+	}
+	startTime, err := time.Parse(time.RFC3339Nano, v[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err.Error())
+	}
+	id, err := strconv.ParseInt(v[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err.Error())
+	}
+	return &pageCursor{StartTime: startTime.UTC(), ID: id}, nil
+}
+
+// ListMeasurementsPage returns the page of the measurements selected by the
+// filter that starts at the given cursor, or the first page when the cursor
+// is empty. The page contains at most limit measurements, or DefaultPageSize
+// measurements when limit is not positive.
+func ListMeasurementsPage(sess db.Session, filter *MeasurementFilter, cursor string, limit int) (*MeasurementPage, error) {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	page := &MeasurementPage{Measurements: []MeasurementURLNetwork{}}
+	var count struct {
+		TotalCount uint64 `db:"total_count"`
+	}
+	err := selectMeasurements(sess, filter, db.Raw("COUNT(*) AS total_count")).One(&count)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count measurements")
+	}
+	page.TotalCount = count.TotalCount
+	req := selectMeasurements(sess, filter,
+		db.Raw("networks.*"),
+		db.Raw("urls.*"),
+		db.Raw("measurements.*"),
+		db.Raw("results.*"),
+	)
+	if cursor != "" {
+		after, err := decodePageCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		req = req.And(`(measurements.measurement_start_time > ? OR
+			(measurements.measurement_start_time = ? AND measurements.measurement_id > ?))`,
+			after.StartTime, after.StartTime, after.ID)
+	}
+	// We fetch an extra measurement to know whether there is a next page.
+	req = req.OrderBy("measurements.measurement_start_time", "measurements.measurement_id").Limit(limit + 1)
+	if err := req.All(&page.Measurements); err != nil {
+		return nil, errors.Wrap(err, "failed to list measurements")
+	}
+	if len(page.Measurements) > limit {
+		page.Measurements = page.Measurements[:limit]
+		page.NextCursor = encodePageCursor(&page.Measurements[limit-1].Measurement)
+	}
+	if err := loadMeasurementAnnotations(sess, page.Measurements); err != nil {
+		return nil, err
+	}
+	if err := loadRerunOriginals(sess, page.Measurements); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestListMeasurementsPage(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	for _, loc := range []*locationInfo{
+		{asn: 30722, countryCode: "IT"},
+		{asn: 3269, countryCode: "IT"},
+	} {
+		network, err := CreateNetwork(sess, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := CreateResult(sess, tmpdir, "im", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for idx, testName := range []string{"telegram", "whatsapp", "signal"} {
+			msmt, err := CreateMeasurement(sess, sql.NullString{}, testName,
+				result.MeasurementDir, idx, result.ID, sql.NullInt64{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msmt.IsAnomaly = sql.NullBool{Bool: testName == "whatsapp", Valid: true}
+			if err := msmt.Done(sess); err != nil {
+				t.Fatal(err)
+			}
+			if testName == "signal" && loc.asn == 3269 {
+				if err := msmt.Failed(sess, "generic_timeout_error"); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	t.Run("with cursors", func(t *testing.T) {
+		var (
+			cursor string
+			ids    []int64
+		)
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatal("too many pages")
+			}
+			page, err := ListMeasurementsPage(sess, &MeasurementFilter{}, cursor, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.TotalCount != 6 {
+				t.Fatal("unexpected total count", page.TotalCount)
+			}
+			for _, msmt := range page.Measurements {
+				ids = append(ids, msmt.Measurement.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		if len(ids) != 6 {
+			t.Fatal("unexpected measurements", ids)
+		}
+		for idx := 1; idx < len(ids); idx++ {
+			if ids[idx] <= ids[idx-1] {
+				t.Fatal("unexpected order", ids)
+			}
+		}
+	})
+
+	t.Run("with filters", func(t *testing.T) {
+		filter := &MeasurementFilter{AnomalyOnly: true, ASN: 3269, CountryCode: "IT"}
+		page, err := ListMeasurementsPage(sess, filter, "", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.TotalCount != 1 || len(page.Measurements) != 1 || page.NextCursor != "" {
+			t.Fatal("unexpected page", page)
+		}
+		if page.Measurements[0].TestName != "whatsapp" || page.Measurements[0].ASN != 3269 {
+			t.Fatal("unexpected measurement", page.Measurements[0])
+		}
+	})
+
+	t.Run("with failed or anomalous", func(t *testing.T) {
+		failed, err := ListMeasurementsMatching(sess, &MeasurementFilter{FailedOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(failed) != 1 || failed[0].TestName != "signal" {
+			t.Fatal("unexpected measurements", failed)
+		}
+		either, err := ListMeasurementsMatching(sess, &MeasurementFilter{FailedOnly: true, AnomalyOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(either) != 3 {
+			t.Fatal("unexpected measurements", either)
+		}
+	})
+
+	t.Run("with an invalid cursor", func(t *testing.T) {
+		_, err := ListMeasurementsPage(sess, &MeasurementFilter{}, "invalid", 4)
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
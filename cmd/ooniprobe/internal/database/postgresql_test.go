@@ -0,0 +1,186 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestDialectRebind(t *testing.T) {
+	query := `UPDATE urls SET url = ?, category_code = ? WHERE url_id = ?`
+	if out := dialectSQLite.rebind(query); out != query {
+		t.Fatal("unexpected query", out)
+	}
+	expected := `UPDATE urls SET url = $1, category_code = $2 WHERE url_id = $3`
+	if out := dialectPostgreSQL.rebind(query); out != expected {
+		t.Fatal("unexpected query", out)
+	}
+}
+
+func TestDialectTranslate(t *testing.T) {
+	query := "PRAGMA foreign_keys=off;\n" +
+		"CREATE TABLE `results` (\n" +
+		"    `result_id` INTEGER PRIMARY KEY AUTOINCREMENT,\n" +
+		"    `result_start_time` DATETIME NOT NULL,\n" +
+		"    `result_runtime` REAL,\n" +
+		"    `result_is_done` TINYINT(1) NOT NULL DEFAULT 0,\n" +
+		"    `asn` INT(4) NOT NULL,\n" +
+		"    `test_keys` JSON NOT NULL\n" +
+		");\n" +
+		"ALTER TABLE `results`\n" +
+		"ADD COLUMN result_is_uploaded TINYINT(1) DEFAULT 1 NOT NULL;\n"
+	if out := dialectSQLite.translate(query); out != query {
+		t.Fatal("unexpected query", out)
+	}
+	expected := "CREATE TABLE \"results\" (\n" +
+		"    \"result_id\" SERIAL PRIMARY KEY,\n" +
+		"    \"result_start_time\" TIMESTAMPTZ NOT NULL,\n" +
+		"    \"result_runtime\" DOUBLE PRECISION,\n" +
+		"    \"result_is_done\" BOOLEAN NOT NULL DEFAULT FALSE,\n" +
+		"    \"asn\" INTEGER NOT NULL,\n" +
+		"    \"test_keys\" TEXT NOT NULL\n" +
+		");\n" +
+		"ALTER TABLE \"results\"\n" +
+		"ADD COLUMN result_is_uploaded BOOLEAN DEFAULT TRUE NOT NULL;\n"
+	if out := dialectPostgreSQL.translate(query); out != expected {
+		t.Fatal("unexpected query", out)
+	}
+}
+
+func TestTranslateMigration(t *testing.T) {
+	t.Run("with the embedded migrations", func(t *testing.T) {
+		migrations, err := loadEmbeddedMigrations(dialectPostgreSQL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range migrations {
+			if m.BeforeUp != nil {
+				t.Fatal("unexpected hook", m.Name)
+			}
+			for _, s := range []string{"`", "PRAGMA", "AUTOINCREMENT", "TINYINT", "DATETIME",
+				"json_extract", "datetime("} {
+				if strings.Contains(m.Up, s) || strings.Contains(m.Down, s) {
+					t.Fatal("untranslated SQLite syntax", m.Name, s)
+				}
+			}
+		}
+	})
+
+	t.Run("with an override", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/postgresql/1_a.sql": {
+				Data: []byte("-- +migrate Up\nSELECT 2;\n-- +migrate Down\nSELECT 3;\n"),
+			},
+		}
+		m := &migration{Name: "1_a.sql", Up: "SELECT 0;", Down: "SELECT 1;"}
+		if err := translateMigration(fsys, m); err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(m.Up) != "SELECT 2;" || strings.TrimSpace(m.Down) != "SELECT 3;" {
+			t.Fatal("unexpected migration", m.Up, m.Down)
+		}
+	})
+}
+
+func TestDialectOf(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+	if dialectOf(sess.Driver().(*sql.DB)) != dialectSQLite {
+		t.Fatal("expected SQLite")
+	}
+	if isPostgreSQL(sess) {
+		t.Fatal("expected SQLite")
+	}
+}
+
+// postgreSQLTestEnv is the environment variable containing the URL of
+// the PostgreSQL database used by TestPostgreSQL, which we skip when the
+// variable is not set. The test drops all the tables of the database.
+const postgreSQLTestEnv = "OONIPROBE_TEST_POSTGRESQL_URL"
+
+func TestPostgreSQL(t *testing.T) {
+	URL := os.Getenv(postgreSQLTestEnv)
+	if URL == "" {
+		t.Skip("set " + postgreSQLTestEnv + " to run this test")
+	}
+	sess, err := ConnectPostgreSQL(URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := MigrateTo(sess.Driver().(*sql.DB), 0); err != nil {
+			t.Fatal(err)
+		}
+		sess.Close()
+	}()
+
+	// Connecting again must not apply the migrations again.
+	other, err := ConnectPostgreSQL(URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT", networkName: "Vodafone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msmt, err := CreateMeasurement(sess, sql.NullString{}, "telegram", tmpdir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := AddMeasurementTag(sess, msmt.ID, "hotspot"); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetMeasurementAnnotations(sess, msmt.ID, map[string]string{"run": "x"}); err != nil {
+			t.Fatal(err)
+		}
+		run := &ScheduleRun{GroupName: "im", ScheduleSpec: "@daily", NextRunTime: time.Now()}
+		if err := SaveScheduleRun(sess, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := UpdateUploadedStatus(sess, result); err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Finished(sess); err != nil {
+		t.Fatal(err)
+	}
+	done, _, err := ListResults(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 || done[0].TotalCount != 1 {
+		t.Fatal("unexpected results", done)
+	}
+	if problems, err := CheckIntegrity(sess); err != nil || len(problems) != 0 {
+		t.Fatal("unexpected problems", problems, err)
+	}
+	if _, err := Vacuum(sess); !errors.Is(err, ErrNotSupported) {
+		t.Fatal("not the error we expected", err)
+	}
+}
@@ -0,0 +1,132 @@
+package database
+
+//
+// Re-run measurements.
+//
+// A re-run measurement (i.e., a measurement where IsRerun is true) points
+// to the measurement it re-tests, such that users can compare the anomaly
+// of the original measurement with the outcome of the re-test.
+//
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// ErrInvalidRerun indicates that a measurement cannot be a re-run of
+// the given original measurement.
+var ErrInvalidRerun = errors.New("database: invalid re-run")
+
+// GetMeasurement returns the given measurement along with its network,
+// result, URL, annotations, and original measurement, if any.
+func GetMeasurement(sess db.Session, measurementID int64) (*MeasurementURLNetwork, error) {
+	measurements := []MeasurementURLNetwork{}
+	req := sess.SQL().Select(
+		db.Raw("networks.*"),
+		db.Raw("urls.*"),
+		db.Raw("measurements.*"),
+		db.Raw("results.*"),
+	).From("measurements").
+		Join("results").On("results.result_id = measurements.result_id").
+		Join("networks").On("results.network_id = networks.network_id").
+		LeftJoin("urls").On("urls.url_id = measurements.url_id").
+		Where("measurements.measurement_id = ?", measurementID)
+	if err := req.All(&measurements); err != nil {
+		return nil, errors.Wrap(err, "failed to get measurement")
+	}
+	if len(measurements) < 1 {
+		return nil, db.ErrNoMoreRows
+	}
+	if err := loadMeasurementAnnotations(sess, measurements); err != nil {
+		return nil, err
+	}
+	if err := loadRerunOriginals(sess, measurements); err != nil {
+		return nil, err
+	}
+	return &measurements[0], nil
+}
+
+// SetMeasurementRerunOf marks the given measurement as a re-run of the
+// original measurement, which must exist, have the same test name, and
+// not be the measurement itself.
+func SetMeasurementRerunOf(sess db.Session, msmt *Measurement, originalID int64) error {
+	if originalID == msmt.ID {
+		return fmt.Errorf("%w: #%d cannot be a re-run of itself", ErrInvalidRerun, msmt.ID)
+	}
+	var original Measurement
+	err := sess.Collection("measurements").Find("measurement_id", originalID).One(&original)
+	if err == db.ErrNoMoreRows {
+		return fmt.Errorf("%w: no measurement #%d", ErrInvalidRerun, originalID)
+	}
+	if err != nil {
+		return errors.Wrap(err, "finding the original measurement")
+	}
+	if original.TestName != msmt.TestName {
+		return fmt.Errorf("%w: #%d is a %s measurement, not a %s one",
+			ErrInvalidRerun, originalID, original.TestName, msmt.TestName)
+	}
+	msmt.IsRerun = true
+	msmt.RerunOf = sql.NullInt64{Int64: originalID, Valid: true}
+	_, err = sess.SQL().Update("measurements").
+		Set("measurement_is_rerun", true).
+		Set("rerun_of_measurement_id", originalID).
+		Where("measurement_id = ?", msmt.ID).
+		Exec()
+	if err != nil {
+		return errors.Wrap(err, "updating the re-run measurement")
+	}
+	return nil
+}
+
+// ListMeasurementReruns returns the re-runs of the given measurement
+// from the oldest.
+func ListMeasurementReruns(sess db.Session, measurementID int64) ([]Measurement, error) {
+	reruns := []Measurement{}
+	err := sess.Collection("measurements").
+		Find("rerun_of_measurement_id", measurementID).
+		OrderBy("measurement_start_time", "measurement_id").
+		All(&reruns)
+	if err != nil {
+		return reruns, errors.Wrap(err, "failed to list measurement re-runs")
+	}
+	return reruns, nil
+}
+
+// loadRerunOriginals sets the original measurement of the given re-run
+// measurements. We leave the original unset when it has been deleted.
+func loadRerunOriginals(sess db.Session, measurements []MeasurementURLNetwork) error {
+	index := make(map[int64][]*MeasurementURLNetwork)
+	var ids []int64
+	for idx := range measurements {
+		m := &measurements[idx]
+		if !m.Measurement.RerunOf.Valid {
+			continue
+		}
+		id := m.Measurement.RerunOf.Int64
+		if _, found := index[id]; !found {
+			ids = append(ids, id)
+		}
+		index[id] = append(index[id], m)
+	}
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > loadBatchSize {
+			batch = batch[:loadBatchSize]
+		}
+		ids = ids[len(batch):]
+		originals := []Measurement{}
+		err := sess.Collection("measurements").Find(db.Cond{"measurement_id IN": batch}).All(&originals)
+		if err != nil {
+			return errors.Wrap(err, "loading the original measurements")
+		}
+		for idx := range originals {
+			for _, m := range index[originals[idx].ID] {
+				m.RerunOfMeasurement = &originals[idx]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMeasurementReruns(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMeasurement := func(testName string) (*Result, *Measurement) {
+		result, err := CreateResult(sess, tmpdir, "im", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msmt, err := CreateMeasurement(sess, sql.NullString{}, testName,
+			result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, msmt
+	}
+	originalResult, original := newMeasurement("telegram")
+	original.IsAnomaly = sql.NullBool{Bool: true, Valid: true}
+	if err := sess.Collection("measurements").Find("measurement_id", original.ID).Update(original); err != nil {
+		t.Fatal(err)
+	}
+	rerunResult, rerun := newMeasurement("telegram")
+	_, other := newMeasurement("whatsapp")
+
+	t.Run("SetMeasurementRerunOf", func(t *testing.T) {
+		if err := SetMeasurementRerunOf(sess, rerun, original.ID); err != nil {
+			t.Fatal(err)
+		}
+		if !rerun.IsRerun || rerun.RerunOf.Int64 != original.ID {
+			t.Fatal("unexpected measurement", rerun)
+		}
+		for _, originalID := range []int64{other.ID, other.ID + 100} {
+			err := SetMeasurementRerunOf(sess, rerun, originalID)
+			if !errors.Is(err, ErrInvalidRerun) {
+				t.Fatal("unexpected error", err)
+			}
+		}
+		if err := SetMeasurementRerunOf(sess, other, other.ID); !errors.Is(err, ErrInvalidRerun) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("ListMeasurementReruns", func(t *testing.T) {
+		reruns, err := ListMeasurementReruns(sess, original.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reruns) != 1 || reruns[0].ID != rerun.ID || !reruns[0].IsRerun {
+			t.Fatal("unexpected reruns", reruns)
+		}
+	})
+
+	t.Run("ListMeasurements", func(t *testing.T) {
+		measurements, err := ListMeasurements(sess, rerunResult.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(measurements) != 1 {
+			t.Fatal("unexpected measurements", measurements)
+		}
+		m := measurements[0]
+		if m.RerunOfMeasurement == nil || m.RerunOfMeasurement.ID != original.ID {
+			t.Fatal("unexpected original", m.RerunOfMeasurement)
+		}
+		if !m.RerunOfMeasurement.IsAnomaly.Bool {
+			t.Fatal("expected the original anomaly")
+		}
+	})
+
+	t.Run("GetMeasurement", func(t *testing.T) {
+		m, err := GetMeasurement(sess, rerun.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Result.ID != rerunResult.ID || m.Network.ASN != 30722 || m.RerunOfMeasurement == nil {
+			t.Fatal("unexpected measurement", m)
+		}
+		if _, err := GetMeasurement(sess, rerun.ID+100); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("DeleteResult unlinks the re-runs", func(t *testing.T) {
+		if err := DeleteResult(sess, originalResult.ID); err != nil {
+			t.Fatal(err)
+		}
+		m, err := GetMeasurement(sess, rerun.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !m.IsRerun || m.Measurement.RerunOf.Valid || m.RerunOfMeasurement != nil {
+			t.Fatal("unexpected measurement", m)
+		}
+	})
+}
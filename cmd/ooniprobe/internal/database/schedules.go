@@ -0,0 +1,33 @@
+package database
+
+import (
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// ListScheduleRuns returns the runs of the test groups scheduled by
+// `ooniprobe daemon`, sorted by test group name.
+func ListScheduleRuns(sess db.Session) ([]ScheduleRun, error) {
+	runs := []ScheduleRun{}
+	err := sess.Collection("schedule_runs").Find().OrderBy("test_group_name").All(&runs)
+	if err != nil {
+		return runs, errors.Wrap(err, "failed to list schedule runs")
+	}
+	return runs, nil
+}
+
+// SaveScheduleRun creates or replaces the run of a scheduled test group.
+func SaveScheduleRun(sess db.Session, run *ScheduleRun) error {
+	_, err := sess.SQL().Exec(`INSERT INTO schedule_runs (
+		test_group_name, schedule_spec, last_run_time, next_run_time
+	) VALUES (?, ?, ?, ?)
+	ON CONFLICT (test_group_name) DO UPDATE SET
+		schedule_spec = excluded.schedule_spec,
+		last_run_time = excluded.last_run_time,
+		next_run_time = excluded.next_run_time`,
+		run.GroupName, run.ScheduleSpec, run.LastRunTime, run.NextRunTime.UTC())
+	if err != nil {
+		return errors.Wrap(err, "saving schedule run")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestScheduleRuns(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	next := time.Date(2022, 3, 1, 12, 30, 0, 0, time.UTC)
+	runs := []*ScheduleRun{{
+		GroupName:    "websites",
+		ScheduleSpec: "6h",
+		NextRunTime:  next,
+	}, {
+		GroupName:    "im",
+		ScheduleSpec: "0 * * * *",
+		NextRunTime:  next,
+	}}
+	for _, run := range runs {
+		if err := SaveScheduleRun(sess, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs[0].LastRunTime = sql.NullTime{Time: next, Valid: true}
+	runs[0].NextRunTime = next.Add(6 * time.Hour)
+	if err := SaveScheduleRun(sess, runs[0]); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ListScheduleRuns(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 || saved[0].GroupName != "im" || saved[1].GroupName != "websites" {
+		t.Fatal("unexpected runs", saved)
+	}
+	if saved[0].LastRunTime.Valid || !saved[0].NextRunTime.Equal(next) {
+		t.Fatal("unexpected run", saved[0])
+	}
+	if !saved[1].LastRunTime.Time.Equal(next) || !saved[1].NextRunTime.Equal(next.Add(6*time.Hour)) {
+		t.Fatal("unexpected run", saved[1])
+	}
+}
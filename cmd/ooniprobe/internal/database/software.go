@@ -0,0 +1,39 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// SoftwareInfo describes the software that produced a result.
+type SoftwareInfo struct {
+	// Name is the software name (e.g., "ooniprobe-cli").
+	Name string
+
+	// Version is the software version.
+	Version string
+
+	// EngineVersion is the version of the measurement engine.
+	EngineVersion string
+
+	// Platform is the platform name (e.g., "linux").
+	Platform string
+}
+
+// SetResultSoftware writes the software that produced the given result.
+func SetResultSoftware(sess db.Session, result *Result, info *SoftwareInfo) error {
+	optional := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	result.SoftwareName = optional(info.Name)
+	result.SoftwareVersion = optional(info.Version)
+	result.EngineVersion = optional(info.EngineVersion)
+	result.Platform = optional(info.Platform)
+	err := sess.Collection("results").Find("result_id", result.ID).Update(result)
+	if err != nil {
+		return errors.Wrap(err, "updating the result software")
+	}
+	return nil
+}
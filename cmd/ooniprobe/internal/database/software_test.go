@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSetResultSoftware(t *testing.T) {
+	sess, _, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ListResults only lists the results having measurements.
+	_, err = CreateMeasurement(sess, sql.NullString{}, "telegram",
+		result.MeasurementDir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &SoftwareInfo{Name: "ooniprobe-cli", Version: "3.10.0", EngineVersion: "3.10.0"}
+	if err := SetResultSoftware(sess, result, info); err != nil {
+		t.Fatal(err)
+	}
+	if err := result.Finished(sess); err != nil {
+		t.Fatal(err)
+	}
+	done, _, err := ListResults(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 1 {
+		t.Fatal("unexpected results", done)
+	}
+	if done[0].SoftwareName.String != "ooniprobe-cli" || done[0].EngineVersion.String != "3.10.0" {
+		t.Fatal("unexpected software", done[0].Result)
+	}
+	if done[0].Platform.Valid {
+		t.Fatal("expected a NULL platform", done[0].Platform)
+	}
+}
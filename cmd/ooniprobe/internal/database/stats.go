@@ -0,0 +1,133 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// AnomalyStats counts the measurements and the anomalies. We only
+// count measurements for which we know whether there's an anomaly.
+type AnomalyStats struct {
+	TotalCount   uint64 `db:"total_count"`
+	AnomalyCount uint64 `db:"anomaly_count"`
+}
+
+// AnomalyRate returns the fraction of measurements with anomalies.
+func (s AnomalyStats) AnomalyRate() float64 {
+	if s.TotalCount <= 0 {
+		return 0
+	}
+	return float64(s.AnomalyCount) / float64(s.TotalCount)
+}
+
+// NetworkStats contains the anomaly stats of a network.
+type NetworkStats struct {
+	AnomalyStats `db:",inline"`
+	ASN          uint   `db:"asn"`
+	NetworkName  string `db:"network_name"`
+	CountryCode  string `db:"network_country_code"`
+}
+
+// TestGroupStats contains the anomaly stats of a test group.
+type TestGroupStats struct {
+	AnomalyStats  `db:",inline"`
+	TestGroupName string `db:"test_group_name"`
+}
+
+// TimeWindowStats contains the anomaly stats of a time window.
+type TimeWindowStats struct {
+	AnomalyStats
+	Start time.Time
+	End   time.Time
+}
+
+// These are the columns computing AnomalyStats.
+var (
+	statsTotalCount   = db.Raw("COUNT(*) AS total_count")
+	statsAnomalyCount = db.Raw("COUNT(CASE WHEN measurements.is_anomaly = TRUE THEN 1 END) AS anomaly_count")
+)
+
+// StatsByNetwork returns the anomaly stats of each network,
+// starting from the ones with more measurements.
+func StatsByNetwork(sess db.Session) ([]NetworkStats, error) {
+	stats := []NetworkStats{}
+	req := sess.SQL().Select(
+		db.Raw("networks.asn"),
+		db.Raw("networks.network_name"),
+		db.Raw("networks.network_country_code"),
+		statsTotalCount,
+		statsAnomalyCount,
+	).From("measurements").
+		Join("results").On("results.result_id = measurements.result_id").
+		Join("networks").On("networks.network_id = results.network_id").
+		Where("measurements.is_anomaly IS NOT NULL").
+		GroupBy(
+			db.Raw("networks.asn"),
+			db.Raw("networks.network_name"),
+			db.Raw("networks.network_country_code"),
+		).
+		OrderBy("-total_count", "networks.asn")
+	if err := req.All(&stats); err != nil {
+		return nil, errors.Wrap(err, "failed to compute stats by network")
+	}
+	return stats, nil
+}
+
+// StatsByTestGroup returns the anomaly stats of each test group.
+func StatsByTestGroup(sess db.Session) ([]TestGroupStats, error) {
+	stats := []TestGroupStats{}
+	req := sess.SQL().Select(
+		db.Raw("results.test_group_name"),
+		statsTotalCount,
+		statsAnomalyCount,
+	).From("measurements").
+		Join("results").On("results.result_id = measurements.result_id").
+		Where("measurements.is_anomaly IS NOT NULL").
+		GroupBy(db.Raw("results.test_group_name")).
+		OrderBy("results.test_group_name")
+	if err := req.All(&stats); err != nil {
+		return nil, errors.Wrap(err, "failed to compute stats by test group")
+	}
+	return stats, nil
+}
+
+// StatsOverTime returns the anomaly stats of consecutive time windows
+// with the given duration covering the [since, until) interval. Windows
+// without measurements have zero counts, so the return value is suitable
+// for plotting a time series.
+func StatsOverTime(sess db.Session, since, until time.Time, window time.Duration) ([]TimeWindowStats, error) {
+	if window <= 0 || !since.Before(until) {
+		return nil, errors.New("invalid time range or window")
+	}
+	since, until = since.UTC(), until.UTC()
+	var entries []struct {
+		StartTime time.Time `db:"measurement_start_time"`
+		IsAnomaly bool      `db:"is_anomaly"`
+	}
+	err := sess.SQL().Select("measurement_start_time", "is_anomaly").
+		From("measurements").
+		Where("is_anomaly IS NOT NULL").
+		And("measurement_start_time >= ?", since).
+		And("measurement_start_time < ?", until).
+		All(&entries)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to compute stats over time")
+	}
+	stats := []TimeWindowStats{}
+	for start := since; start.Before(until); start = start.Add(window) {
+		stats = append(stats, TimeWindowStats{Start: start, End: start.Add(window)})
+	}
+	for _, entry := range entries {
+		idx := int(entry.StartTime.Sub(since) / window)
+		if idx < 0 || idx >= len(stats) {
+			continue // be defensive
+		}
+		stats[idx].TotalCount++
+		if entry.IsAnomaly {
+			stats[idx].AnomalyCount++
+		}
+	}
+	return stats, nil
+}
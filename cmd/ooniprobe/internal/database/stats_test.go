@@ -0,0 +1,118 @@
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAnomalyStatsAnomalyRate(t *testing.T) {
+	if rate := (AnomalyStats{}).AnomalyRate(); rate != 0 {
+		t.Fatal("unexpected rate", rate)
+	}
+	if rate := (AnomalyStats{TotalCount: 4, AnomalyCount: 1}).AnomalyRate(); rate != 0.25 {
+		t.Fatal("unexpected rate", rate)
+	}
+}
+
+func TestStats(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// createMeasurements creates measurements with the given anomaly
+	// flags inside a new result of the given network and test group.
+	createMeasurements := func(asn uint, group string, anomalies ...bool) {
+		network, err := CreateNetwork(sess, &locationInfo{asn: asn, countryCode: "IT"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := CreateResult(sess, tmpdir, group, network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for idx, anomaly := range anomalies {
+			msmt, err := CreateMeasurement(sess, sql.NullString{}, "antani", tmpdir, idx, result.ID, sql.NullInt64{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msmt.IsAnomaly = sql.NullBool{Bool: anomaly, Valid: true}
+			if err := sess.Collection("measurements").Find("measurement_id", msmt.ID).Update(msmt); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	createMeasurements(30722, "websites", true, false, false, false)
+	createMeasurements(30722, "im", true)
+	createMeasurements(3269, "websites", false, false)
+
+	t.Run("by network", func(t *testing.T) {
+		stats, err := StatsByNetwork(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 2 {
+			t.Fatal("unexpected number of networks", len(stats))
+		}
+		if stats[0].ASN != 30722 || stats[0].TotalCount != 5 || stats[0].AnomalyCount != 2 {
+			t.Fatal("unexpected stats", stats[0])
+		}
+		if stats[1].ASN != 3269 || stats[1].TotalCount != 2 || stats[1].AnomalyCount != 0 {
+			t.Fatal("unexpected stats", stats[1])
+		}
+	})
+
+	t.Run("by test group", func(t *testing.T) {
+		stats, err := StatsByTestGroup(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 2 {
+			t.Fatal("unexpected number of test groups", len(stats))
+		}
+		if stats[0].TestGroupName != "im" || stats[0].AnomalyRate() != 1 {
+			t.Fatal("unexpected stats", stats[0])
+		}
+		if stats[1].TestGroupName != "websites" || stats[1].TotalCount != 6 || stats[1].AnomalyCount != 1 {
+			t.Fatal("unexpected stats", stats[1])
+		}
+	})
+
+	t.Run("over time", func(t *testing.T) {
+		now := time.Now()
+		stats, err := StatsOverTime(sess, now.Add(-3*time.Hour), now.Add(time.Hour), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 4 {
+			t.Fatal("unexpected number of windows", len(stats))
+		}
+		// All the measurements were created during the last hour.
+		for _, idx := range []int{0, 1, 3} {
+			if stats[idx].TotalCount != 0 {
+				t.Fatal("expected empty window", stats[idx])
+			}
+		}
+		if stats[2].TotalCount != 7 || stats[2].AnomalyCount != 2 {
+			t.Fatal("unexpected stats", stats[2])
+		}
+		if _, err := StatsOverTime(sess, now, now, time.Hour); err == nil {
+			t.Fatal("expected an error here")
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package database
+
+//
+// Experiment-specific summary tables.
+//
+// The performance_summaries, im_summaries, and websites_summaries tables
+// contain the normalized test keys of the measurements of the corresponding
+// test groups. We populate them when a measurement finishes (see
+// AddTestKeys), such that we can use indexed queries on them. Unlike the
+// measurement_summaries table, they contain all the summary keys of each
+// group, including IM, along with the result of the measurement.
+//
+
+import (
+	"database/sql"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/dash"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/fbmessenger"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/ndt7"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/signal"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/telegram"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/webconnectivity"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/whatsapp"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// PerformanceSummary is an entry of the performance_summaries table.
+type PerformanceSummary struct {
+	MeasurementID   int64           `db:"measurement_id"`
+	ResultID        int64           `db:"result_id"`
+	TestName        string          `db:"test_name"`
+	Upload          sql.NullFloat64 `db:"upload"`            // ndt only
+	Download        sql.NullFloat64 `db:"download"`          // ndt only
+	Ping            sql.NullFloat64 `db:"ping"`              // ndt only
+	MedianBitrate   sql.NullFloat64 `db:"median_bitrate"`    // dash only
+	MinPlayoutDelay sql.NullFloat64 `db:"min_playout_delay"` // dash only
+}
+
+// IMSummary is an entry of the im_summaries table.
+type IMSummary struct {
+	MeasurementID              int64          `db:"measurement_id"`
+	ResultID                   int64          `db:"result_id"`
+	TestName                   string         `db:"test_name"`
+	IsAnomaly                  sql.NullBool   `db:"is_anomaly"`
+	DNSBlocking                sql.NullBool   `db:"dns_blocking"`                 // facebook_messenger only
+	TCPBlocking                sql.NullBool   `db:"tcp_blocking"`                 // facebook_messenger and telegram only
+	HTTPBlocking               sql.NullBool   `db:"http_blocking"`                // telegram only
+	WebBlocking                sql.NullBool   `db:"web_blocking"`                 // telegram and whatsapp only
+	EndpointsBlocking          sql.NullBool   `db:"endpoints_blocking"`           // whatsapp only
+	RegistrationServerBlocking sql.NullBool   `db:"registration_server_blocking"` // whatsapp only
+	BackendStatus              sql.NullString `db:"backend_status"`               // signal only
+}
+
+// WebsitesSummary is an entry of the websites_summaries table.
+type WebsitesSummary struct {
+	MeasurementID int64          `db:"measurement_id"`
+	ResultID      int64          `db:"result_id"`
+	URLID         sql.NullInt64  `db:"url_id"`
+	IsAnomaly     sql.NullBool   `db:"is_anomaly"`
+	Accessible    sql.NullBool   `db:"accessible"`
+	Blocking      sql.NullString `db:"blocking"`
+}
+
+// newGroupSummary returns the name of the summary table of the given
+// measurement along with its entry, or an empty name when the measurement
+// does not belong to any of the summary tables.
+func newGroupSummary(m *Measurement) (string, interface{}, error) {
+	tk, err := m.DecodeTestKeys()
+	if err != nil {
+		return "", nil, err
+	}
+	valid := func(v bool) sql.NullBool {
+		return sql.NullBool{Bool: v, Valid: true}
+	}
+	newIMSummary := func() *IMSummary {
+		return &IMSummary{MeasurementID: m.ID, ResultID: m.ResultID, TestName: m.TestName, IsAnomaly: m.IsAnomaly}
+	}
+	switch v := tk.(type) {
+	case *ndt7.SummaryKeys:
+		return "performance_summaries", &PerformanceSummary{
+			MeasurementID: m.ID,
+			ResultID:      m.ResultID,
+			TestName:      m.TestName,
+			Upload:        sql.NullFloat64{Float64: v.Upload, Valid: true},
+			Download:      sql.NullFloat64{Float64: v.Download, Valid: true},
+			Ping:          sql.NullFloat64{Float64: v.Ping, Valid: true},
+		}, nil
+	case *dash.SummaryKeys:
+		return "performance_summaries", &PerformanceSummary{
+			MeasurementID:   m.ID,
+			ResultID:        m.ResultID,
+			TestName:        m.TestName,
+			MedianBitrate:   sql.NullFloat64{Float64: v.Bitrate, Valid: true},
+			MinPlayoutDelay: sql.NullFloat64{Float64: v.Delay, Valid: true},
+		}, nil
+	case *fbmessenger.SummaryKeys:
+		im := newIMSummary()
+		im.DNSBlocking = valid(v.DNSBlocking)
+		im.TCPBlocking = valid(v.TCPBlocking)
+		return "im_summaries", im, nil
+	case *telegram.SummaryKeys:
+		im := newIMSummary()
+		im.TCPBlocking = valid(v.TCPBlocking)
+		im.HTTPBlocking = valid(v.HTTPBlocking)
+		im.WebBlocking = valid(v.WebBlocking)
+		return "im_summaries", im, nil
+	case *whatsapp.SummaryKeys:
+		im := newIMSummary()
+		im.WebBlocking = valid(v.WebBlocking)
+		im.EndpointsBlocking = valid(v.EndpointsBlocking)
+		im.RegistrationServerBlocking = valid(v.RegistrationServerBlocking)
+		return "im_summaries", im, nil
+	case *signal.SummaryKeys:
+		im := newIMSummary()
+		im.BackendStatus = sql.NullString{String: v.SignalBackendStatus, Valid: true}
+		return "im_summaries", im, nil
+	case *webconnectivity.SummaryKeys:
+		return "websites_summaries", &WebsitesSummary{
+			MeasurementID: m.ID,
+			ResultID:      m.ResultID,
+			URLID:         m.URLID,
+			IsAnomaly:     m.IsAnomaly,
+			Accessible:    valid(v.Accessible),
+			Blocking:      sql.NullString{String: v.Blocking, Valid: true},
+		}, nil
+	}
+	return "", nil, nil
+}
+
+// updateGroupSummary creates or replaces the entry of the measurement
+// in the summary table of its test group, if any.
+func updateGroupSummary(sess db.Session, m *Measurement) error {
+	table, summary, err := newGroupSummary(m)
+	if err != nil || table == "" {
+		return err
+	}
+	if _, err := sess.SQL().DeleteFrom(table).Where("measurement_id = ?", m.ID).Exec(); err != nil {
+		return errors.Wrapf(err, "deleting from %s", table)
+	}
+	if _, err := sess.Collection(table).Insert(summary); err != nil {
+		return errors.Wrapf(err, "inserting into %s", table)
+	}
+	return nil
+}
+
+// PerformanceFilter selects performance summaries by speed. The zero
+// value of each field means that we do not filter by that field.
+type PerformanceFilter struct {
+	// MaxUpload is the maximum upload speed in kbit/s.
+	MaxUpload float64
+
+	// MaxDownload is the maximum download speed in kbit/s.
+	MaxDownload float64
+}
+
+// ListPerformanceSummaries returns the ndt summaries whose speed is below
+// the thresholds of the given filter, ordered by measurement.
+func ListPerformanceSummaries(sess db.Session, filter *PerformanceFilter) ([]PerformanceSummary, error) {
+	summaries := []PerformanceSummary{}
+	cond := db.Cond{"test_name": "ndt"}
+	if filter.MaxUpload > 0 {
+		cond["upload <"] = filter.MaxUpload
+	}
+	if filter.MaxDownload > 0 {
+		cond["download <"] = filter.MaxDownload
+	}
+	err := sess.Collection("performance_summaries").Find(cond).OrderBy("measurement_id").All(&summaries)
+	if err != nil {
+		return summaries, errors.Wrap(err, "failed to list performance summaries")
+	}
+	return summaries, nil
+}
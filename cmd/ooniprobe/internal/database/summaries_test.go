@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/dash"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/ndt7"
+)
+
+func TestGroupSummaries(t *testing.T) {
+	sess, sqldb, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	im, err := CreateResult(sess, tmpdir, "im", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Emulate a measurement written before we had the summary tables.
+	if _, err := MigrateTo(sqldb, 18); err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := CreateMeasurement(sess, sql.NullString{}, "telegram", tmpdir, 0, im.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.TestKeys = `{"telegram_http_blocking":false,"telegram_tcp_blocking":true,"telegram_web_blocking":false}`
+	if err := sess.Collection("measurements").Find("measurement_id", legacy.ID).Update(legacy); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigrations(sqldb); err != nil {
+		t.Fatal(err)
+	}
+	var summary IMSummary
+	if err := sess.Collection("im_summaries").Find("measurement_id", legacy.ID).One(&summary); err != nil {
+		t.Fatal(err)
+	}
+	if !summary.TCPBlocking.Bool || summary.HTTPBlocking.Bool || summary.DNSBlocking.Valid {
+		t.Fatal("unexpected summary", summary)
+	}
+
+	// newPerformanceResult creates a performance result with the given speeds.
+	newPerformanceResult := func(upload, download float64) *Result {
+		result, err := CreateResult(sess, tmpdir, "performance", network.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ndtMsmt, err := CreateMeasurement(sess, sql.NullString{}, "ndt", tmpdir, 0, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := AddTestKeys(sess, ndtMsmt, &ndt7.SummaryKeys{Upload: upload, Download: download, Ping: 10}); err != nil {
+			t.Fatal(err)
+		}
+		dashMsmt, err := CreateMeasurement(sess, sql.NullString{}, "dash", tmpdir, 1, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := AddTestKeys(sess, dashMsmt, &dash.SummaryKeys{Bitrate: download / 2}); err != nil {
+			t.Fatal(err)
+		}
+		if err := result.Finished(sess); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	slow := newPerformanceResult(500, 2000)
+	newPerformanceResult(5000, 20000)
+
+	t.Run("ListPerformanceSummaries", func(t *testing.T) {
+		summaries, err := ListPerformanceSummaries(sess, &PerformanceFilter{MaxUpload: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(summaries) != 1 || summaries[0].ResultID != slow.ID {
+			t.Fatal("unexpected summaries", summaries)
+		}
+		summaries, err = ListPerformanceSummaries(sess, &PerformanceFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(summaries) != 2 {
+			t.Fatal("unexpected summaries", summaries)
+		}
+	})
+
+	t.Run("ListResults", func(t *testing.T) {
+		done, _, err := ListResults(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(done) != 2 {
+			t.Fatal("unexpected results", done)
+		}
+		tk := done[0].PerformanceTestKeys()
+		if tk.Upload != 500 || tk.Download != 2000 || tk.Ping != 10 || tk.Bitrate != 1000 {
+			t.Fatal("unexpected performance test keys", tk)
+		}
+	})
+
+	t.Run("with the result indexes", func(t *testing.T) {
+		for _, name := range []string{
+			"results_done_start_time",
+			"results_group_start_time",
+			"results_network_id",
+			"networks_asn_country_code",
+			"measurements_result_anomaly",
+		} {
+			var count int
+			err := sqldb.QueryRow(`SELECT COUNT(*) FROM sqlite_master
+				WHERE type = 'index' AND name = ?`, name).Scan(&count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 {
+				t.Fatal("missing index", name)
+			}
+		}
+	})
+}
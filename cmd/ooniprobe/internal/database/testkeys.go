@@ -0,0 +1,194 @@
+package database
+
+//
+// Typed access to the measurements test_keys column, which contains
+// the JSON summary produced by each experiment's GetSummaryKeys.
+//
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/dash"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/fbmessenger"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/ndt7"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/psiphon"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/signal"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/telegram"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/webconnectivity"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/whatsapp"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// ErrInvalidTestKeys indicates that the test keys of a measurement
+// do not match the SummaryKeys struct of the measurement's experiment.
+var ErrInvalidTestKeys = errors.New("database: invalid test keys")
+
+// testKeysFactories maps the name of an experiment to the function
+// returning a new instance of its SummaryKeys struct.
+var testKeysFactories = map[string]func() interface{}{
+	"dash": func() interface{} {
+		return &dash.SummaryKeys{}
+	},
+	"facebook_messenger": func() interface{} {
+		return &fbmessenger.SummaryKeys{}
+	},
+	"ndt": func() interface{} {
+		return &ndt7.SummaryKeys{}
+	},
+	"psiphon": func() interface{} {
+		return &psiphon.SummaryKeys{}
+	},
+	"signal": func() interface{} {
+		return &signal.SummaryKeys{}
+	},
+	"telegram": func() interface{} {
+		return &telegram.SummaryKeys{}
+	},
+	"web_connectivity": func() interface{} {
+		return &webconnectivity.SummaryKeys{}
+	},
+	"whatsapp": func() interface{} {
+		return &whatsapp.SummaryKeys{}
+	},
+}
+
+// decodeTestKeys decodes data into the SummaryKeys struct of the given
+// experiment, or into a map when we don't have such a struct for it.
+func decodeTestKeys(testName string, data []byte) (interface{}, error) {
+	var out interface{} = &map[string]interface{}{}
+	if factory, found := testKeysFactories[testName]; found {
+		out = factory()
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(out); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidTestKeys, testName, err.Error())
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("%w: %s: trailing data", ErrInvalidTestKeys, testName)
+	}
+	return out, nil
+}
+
+// DecodeTestKeys returns the test keys of the measurement. The return value
+// is a pointer to the SummaryKeys struct of the measurement's experiment (e.g.,
+// *webconnectivity.SummaryKeys) or a *map[string]interface{} for experiments
+// for which we don't have such a struct.
+func (m *Measurement) DecodeTestKeys() (interface{}, error) {
+	return decodeTestKeys(m.TestName, []byte(m.TestKeys))
+}
+
+// SetTestKeys validates and sets the test keys of the measurement along
+// with the anomaly flag, which we obtain from the IsAnomaly field of tk,
+// if tk is a struct with such a field. This function does not write the
+// measurement into the database.
+func (m *Measurement) SetTestKeys(tk interface{}) error {
+	data, err := json.Marshal(tk)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidTestKeys, err.Error())
+	}
+	if _, err := decodeTestKeys(m.TestName, data); err != nil {
+		return err
+	}
+	var (
+		isAnomaly      bool
+		isAnomalyValid bool
+	)
+	// This is necessary so that we can extract from the opaque testKeys just
+	// the IsAnomaly field of bool type.
+	value := reflect.Indirect(reflect.ValueOf(tk))
+	if value.Kind() == reflect.Struct {
+		isAnomalyValue := value.FieldByName("IsAnomaly")
+		if isAnomalyValue.IsValid() && isAnomalyValue.Kind() == reflect.Bool {
+			isAnomaly = isAnomalyValue.Bool()
+			isAnomalyValid = true
+		}
+	}
+	m.TestKeys = string(data)
+	m.IsAnomaly = sql.NullBool{Bool: isAnomaly, Valid: isAnomalyValid}
+	return nil
+}
+
+// MeasurementSummary is an entry of the measurement_summaries table, which
+// contains the normalized content of each measurement's test keys.
+type MeasurementSummary struct {
+	MeasurementID int64           `db:"measurement_id"`
+	TestName      string          `db:"test_name"`
+	IsAnomaly     sql.NullBool    `db:"is_anomaly"`
+	Accessible    sql.NullBool    `db:"accessible"`     // web_connectivity only
+	Blocking      sql.NullString  `db:"blocking"`       // web_connectivity only
+	Upload        sql.NullFloat64 `db:"upload"`         // ndt only
+	Download      sql.NullFloat64 `db:"download"`       // ndt only
+	Ping          sql.NullFloat64 `db:"ping"`           // ndt only
+	MedianBitrate sql.NullFloat64 `db:"median_bitrate"` // dash only
+}
+
+// newMeasurementSummary creates the summary of the given measurement.
+func newMeasurementSummary(m *Measurement) (*MeasurementSummary, error) {
+	tk, err := m.DecodeTestKeys()
+	if err != nil {
+		return nil, err
+	}
+	summary := &MeasurementSummary{
+		MeasurementID: m.ID,
+		TestName:      m.TestName,
+		IsAnomaly:     m.IsAnomaly,
+	}
+	switch v := tk.(type) {
+	case *webconnectivity.SummaryKeys:
+		summary.Accessible = sql.NullBool{Bool: v.Accessible, Valid: true}
+		summary.Blocking = sql.NullString{String: v.Blocking, Valid: true}
+	case *ndt7.SummaryKeys:
+		summary.Upload = sql.NullFloat64{Float64: v.Upload, Valid: true}
+		summary.Download = sql.NullFloat64{Float64: v.Download, Valid: true}
+		summary.Ping = sql.NullFloat64{Float64: v.Ping, Valid: true}
+	case *dash.SummaryKeys:
+		summary.MedianBitrate = sql.NullFloat64{Float64: v.Bitrate, Valid: true}
+	}
+	return summary, nil
+}
+
+// updateMeasurementSummary creates or replaces the summary of the measurement.
+func updateMeasurementSummary(sess db.Session, m *Measurement) error {
+	summary, err := newMeasurementSummary(m)
+	if err != nil {
+		return err
+	}
+	_, err = sess.SQL().Exec(`INSERT INTO measurement_summaries (
+		measurement_id, test_name, is_anomaly, accessible, blocking,
+		upload, download, ping, median_bitrate
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (measurement_id) DO UPDATE SET
+		test_name = excluded.test_name,
+		is_anomaly = excluded.is_anomaly,
+		accessible = excluded.accessible,
+		blocking = excluded.blocking,
+		upload = excluded.upload,
+		download = excluded.download,
+		ping = excluded.ping,
+		median_bitrate = excluded.median_bitrate`,
+		summary.MeasurementID, summary.TestName, summary.IsAnomaly,
+		summary.Accessible, summary.Blocking, summary.Upload,
+		summary.Download, summary.Ping, summary.MedianBitrate)
+	return err
+}
+
+// ListAnomalies returns the summaries of the measurements with anomalies,
+// optionally restricting the search to the given test name.
+func ListAnomalies(sess db.Session, testName string) ([]MeasurementSummary, error) {
+	summaries := []MeasurementSummary{}
+	cond := db.Cond{"is_anomaly": true}
+	if testName != "" {
+		cond["test_name"] = testName
+	}
+	err := sess.Collection("measurement_summaries").Find(cond).OrderBy("measurement_id").All(&summaries)
+	if err != nil {
+		return summaries, errors.Wrap(err, "failed to list anomalies")
+	}
+	return summaries, nil
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/telegram"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/webconnectivity"
+)
+
+func TestMeasurementSetTestKeys(t *testing.T) {
+	t.Run("with valid summary", func(t *testing.T) {
+		msmt := &Measurement{TestName: "web_connectivity"}
+		err := msmt.SetTestKeys(&webconnectivity.SummaryKeys{Blocking: "dns", IsAnomaly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !msmt.IsAnomaly.Valid || !msmt.IsAnomaly.Bool {
+			t.Fatal("unexpected is_anomaly", msmt.IsAnomaly)
+		}
+		tk, err := msmt.DecodeTestKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := &webconnectivity.SummaryKeys{Blocking: "dns"}
+		if diff := cmp.Diff(expected, tk); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with summary not matching the experiment", func(t *testing.T) {
+		msmt := &Measurement{TestName: "web_connectivity"}
+		err := msmt.SetTestKeys(&telegram.SummaryKeys{})
+		if !errors.Is(err, ErrInvalidTestKeys) {
+			t.Fatal("not the error we expected", err)
+		}
+		if msmt.TestKeys != "" {
+			t.Fatal("should not have set the test keys")
+		}
+	})
+
+	t.Run("with experiment without summary struct", func(t *testing.T) {
+		msmt := &Measurement{TestName: "antani"}
+		if err := msmt.SetTestKeys(map[string]int{"mascetti": 17}); err != nil {
+			t.Fatal(err)
+		}
+		if msmt.IsAnomaly.Valid {
+			t.Fatal("is_anomaly should not be valid")
+		}
+		tk, err := msmt.DecodeTestKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := &map[string]interface{}{"mascetti": 17.0}
+		if diff := cmp.Diff(expected, tk); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with summary that is not a JSON object", func(t *testing.T) {
+		msmt := &Measurement{TestName: "antani"}
+		err := msmt.SetTestKeys([]int{1, 2, 3})
+		if !errors.Is(err, ErrInvalidTestKeys) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
+
+func TestMeasurementSummaries(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqldb := sess.Driver().(*sql.DB)
+
+	location := locationInfo{
+		asn:         0,
+		countryCode: "IT",
+		networkName: "Unknown",
+	}
+	network, err := CreateNetwork(sess, &location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Emulate a measurement written before we had the summaries table.
+	if _, err := MigrateTo(sqldb, 4); err != nil {
+		t.Fatal(err)
+	}
+	m1, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity", tmpdir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1.TestKeys = `{"accessible":false,"blocking":"dns"}`
+	m1.IsAnomaly = sql.NullBool{Bool: true, Valid: true}
+	if err := sess.Collection("measurements").Find("measurement_id", m1.ID).Update(m1); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigrations(sqldb); err != nil {
+		t.Fatal(err)
+	}
+
+	m2, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity", tmpdir, 1, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := &webconnectivity.SummaryKeys{Accessible: true}
+	if err := AddTestKeys(sess, m2, tk); err != nil {
+		t.Fatal(err)
+	}
+	m3, err := CreateMeasurement(sess, sql.NullString{}, "ndt", tmpdir, 2, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AddTestKeys(sess, m3, &webconnectivity.SummaryKeys{}); !errors.Is(err, ErrInvalidTestKeys) {
+		t.Fatal("not the error we expected", err)
+	}
+
+	anomalies, err := ListAnomalies(sess, "web_connectivity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anomalies) != 1 || anomalies[0].MeasurementID != m1.ID {
+		t.Fatal("unexpected anomalies", anomalies)
+	}
+	if anomalies[0].Blocking.String != "dns" || !anomalies[0].Accessible.Valid {
+		t.Fatal("unexpected summary", anomalies[0])
+	}
+	count, err := sess.Collection("measurement_summaries").Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatal("unexpected number of summaries", count)
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// These constants control the exponential backoff between upload attempts.
+const (
+	// uploadRetryMinDelay is the delay after the first failure.
+	uploadRetryMinDelay = 5 * time.Minute
+
+	// uploadRetryMaxDelay is the maximum delay between attempts.
+	uploadRetryMaxDelay = 24 * time.Hour
+)
+
+// uploadRetryDelay returns how long to wait before the next attempt
+// to upload a measurement that failed the given number of times.
+func uploadRetryDelay(attempts int64) time.Duration {
+	delay := uploadRetryMinDelay
+	for i := int64(1); i < attempts && delay < uploadRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > uploadRetryMaxDelay {
+		delay = uploadRetryMaxDelay
+	}
+	return delay
+}
+
+// getUpload returns the upload of the given measurement or
+// db.ErrNoMoreRows if we have never failed to upload it.
+func getUpload(sess db.Session, measurementID int64) (*Upload, error) {
+	var upload Upload
+	if err := sess.Collection("uploads").Find("measurement_id", measurementID).One(&upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// saveUpload creates or replaces the given upload.
+func saveUpload(sess db.Session, upload *Upload) error {
+	_, err := sess.SQL().Exec(`INSERT INTO uploads (
+		measurement_id, upload_attempts, upload_last_error,
+		upload_last_attempt_at, upload_next_retry_at, upload_is_done
+	) VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT (measurement_id) DO UPDATE SET
+		upload_attempts = excluded.upload_attempts,
+		upload_last_error = excluded.upload_last_error,
+		upload_last_attempt_at = excluded.upload_last_attempt_at,
+		upload_next_retry_at = excluded.upload_next_retry_at,
+		upload_is_done = excluded.upload_is_done`,
+		upload.MeasurementID, upload.Attempts, upload.LastError,
+		upload.LastAttemptAt, upload.NextRetryAt, upload.IsDone)
+	if err != nil {
+		return errors.Wrap(err, "saving upload")
+	}
+	return nil
+}
+
+// recordUploadFailure records a failed attempt to upload the
+// given measurement and schedules the next attempt.
+func recordUploadFailure(sess db.Session, measurementID int64, failure string, now time.Time) (*Upload, error) {
+	upload, err := getUpload(sess, measurementID)
+	if err == db.ErrNoMoreRows {
+		upload, err = &Upload{MeasurementID: measurementID}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "getting upload")
+	}
+	upload.Attempts++
+	upload.LastError = sql.NullString{String: failure, Valid: true}
+	upload.LastAttemptAt = sql.NullTime{Time: now, Valid: true}
+	upload.NextRetryAt = sql.NullTime{Time: now.Add(uploadRetryDelay(upload.Attempts)), Valid: true}
+	upload.IsDone = false
+	if err := saveUpload(sess, upload); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// recordUploadSuccess records that we have uploaded the given measurement. We
+// only need to do that when we have previously failed to upload it.
+func recordUploadSuccess(sess db.Session, measurementID int64, now time.Time) error {
+	upload, err := getUpload(sess, measurementID)
+	if err == db.ErrNoMoreRows {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "getting upload")
+	}
+	upload.Attempts++
+	upload.LastAttemptAt = sql.NullTime{Time: now, Valid: true}
+	upload.NextRetryAt = sql.NullTime{}
+	upload.IsDone = true
+	return saveUpload(sess, upload)
+}
+
+// CountPendingUploads returns the number of measurements we failed
+// to upload and we have not uploaded successfully since then.
+func CountPendingUploads(sess db.Session) (uint64, error) {
+	count, err := sess.Collection("uploads").Find("upload_is_done", false).Count()
+	if err != nil {
+		return 0, errors.Wrap(err, "counting pending uploads")
+	}
+	return count, nil
+}
+
+// ListDueUploads returns the pending uploads whose next attempt is
+// due at the given time, starting from the ones due earlier.
+func ListDueUploads(sess db.Session, now time.Time) ([]Upload, error) {
+	uploads := []Upload{}
+	err := sess.Collection("uploads").Find(db.Cond{
+		"upload_is_done":          false,
+		"upload_next_retry_at <=": now.UTC(),
+	}).OrderBy("upload_next_retry_at").All(&uploads)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing due uploads")
+	}
+	return uploads, nil
+}
+
+// ListPendingUploads returns all the pending uploads regardless of
+// when their next attempt is due, starting from the ones due earlier.
+func ListPendingUploads(sess db.Session) ([]Upload, error) {
+	uploads := []Upload{}
+	err := sess.Collection("uploads").Find("upload_is_done", false).
+		OrderBy("upload_next_retry_at").All(&uploads)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing pending uploads")
+	}
+	return uploads, nil
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUploadRetryDelay(t *testing.T) {
+	expectations := map[int64]time.Duration{
+		0:  5 * time.Minute,
+		1:  5 * time.Minute,
+		2:  10 * time.Minute,
+		3:  20 * time.Minute,
+		9:  21*time.Hour + 20*time.Minute,
+		10: 24 * time.Hour,
+		64: 24 * time.Hour,
+	}
+	for attempts, expected := range expectations {
+		if delay := uploadRetryDelay(attempts); delay != expected {
+			t.Fatal("unexpected delay", attempts, delay)
+		}
+	}
+}
+
+func TestUploadsWorkflow(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location := locationInfo{
+		asn:         0,
+		countryCode: "IT",
+		networkName: "Unknown",
+	}
+	network, err := CreateNetwork(sess, &location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Emulate a failed upload recorded before we had the uploads table.
+	if _, err := MigrateTo(sess.Driver().(*sql.DB), 6); err != nil {
+		t.Fatal(err)
+	}
+	m1, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity", tmpdir, 0, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1.UploadFailureMsg = sql.NullString{String: "generic_timeout_error", Valid: true}
+	if err := sess.Collection("measurements").Find("measurement_id", m1.ID).Update(m1); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunMigrations(sess.Driver().(*sql.DB)); err != nil {
+		t.Fatal(err)
+	}
+	checkPending := func(t *testing.T, expected uint64) {
+		count, err := CountPendingUploads(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != expected {
+			t.Fatal("unexpected number of pending uploads", count)
+		}
+	}
+	checkPending(t, 1)
+
+	m2, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity", tmpdir, 1, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := m2.UploadFailed(sess, "connection_refused"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPending(t, 2)
+	upload, err := getUpload(sess, m2.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upload.Attempts != 2 || upload.LastError.String != "connection_refused" || upload.IsDone {
+		t.Fatal("unexpected upload", upload)
+	}
+	if !m2.IsUploadFailed {
+		t.Fatal("expected the measurement to be marked as failed")
+	}
+
+	due, err := ListDueUploads(sess, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].MeasurementID != m1.ID {
+		t.Fatal("unexpected due uploads", due)
+	}
+	due, err = ListDueUploads(sess, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 2 {
+		t.Fatal("unexpected due uploads", due)
+	}
+	pending, err := ListPendingUploads(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[1].MeasurementID != m2.ID {
+		t.Fatal("unexpected pending uploads", pending)
+	}
+
+	if err := m2.UploadSucceeded(sess, "https://ams-pg.ooni.org"); err != nil {
+		t.Fatal(err)
+	}
+	checkPending(t, 1)
+	upload, err = getUpload(sess, m2.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upload.Attempts != 3 || !upload.IsDone || upload.NextRetryAt.Valid {
+		t.Fatal("unexpected upload", upload)
+	}
+
+	// We don't track measurements we upload at the first attempt.
+	m3, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity", tmpdir, 2, result.ID, sql.NullInt64{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m3.UploadSucceeded(sess, "https://ams-pg.ooni.org"); err != nil {
+		t.Fatal(err)
+	}
+	checkPending(t, 1)
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// defaultPorts maps the schemes to the ports we strip from URLs.
+var defaultPorts = map[string]string{
+	"http":  ":80",
+	"https": ":443",
+}
+
+// NormalizeURL returns the normalized form of the given URL, which
+// we use to avoid storing the same URL more than once: we lowercase the
+// scheme and the host and strip the default port. We do not modify the
+// path, the query, and the fragment, where case matters. We return
+// unmodified the strings that are not absolute URLs.
+func NormalizeURL(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return urlStr
+	}
+	schemeEnd := strings.Index(urlStr, "://")
+	if schemeEnd < 0 {
+		return urlStr
+	}
+	scheme := strings.ToLower(urlStr[:schemeEnd])
+	rest := urlStr[schemeEnd+len("://"):]
+	authorityEnd := strings.IndexAny(rest, "/?#")
+	if authorityEnd < 0 {
+		authorityEnd = len(rest)
+	}
+	authority, tail := rest[:authorityEnd], rest[authorityEnd:]
+	var userinfo string
+	if idx := strings.LastIndex(authority, "@"); idx >= 0 {
+		userinfo, authority = authority[:idx+1], authority[idx+1:]
+	}
+	host := strings.TrimSuffix(strings.ToLower(authority), defaultPorts[scheme])
+	return scheme + "://" + userinfo + host + tail
+}
+
+// mergeDuplicateURLs normalizes the URLs in the urls table and merges
+// the URLs that have the same normalized form and country code, such that
+// their measurements point to the oldest of them, which takes the
+// category code of the most recent one.
+func mergeDuplicateURLs(tx *sql.Tx) error {
+	type urlEntry struct {
+		id           int64
+		url          string
+		categoryCode string
+		countryCode  string
+	}
+	rows, err := tx.Query(`SELECT url_id, url, category_code, url_country_code
+		FROM urls ORDER BY url_id`)
+	if err != nil {
+		return errors.Wrap(err, "listing urls")
+	}
+	var entries []*urlEntry
+	for rows.Next() {
+		var e urlEntry
+		if err := rows.Scan(&e.id, &e.url, &e.categoryCode, &e.countryCode); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, &e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	canonical := make(map[string]*urlEntry)
+	var keys []string
+	for _, e := range entries {
+		e.url = NormalizeURL(e.url)
+		key := e.countryCode + " " + e.url
+		first, found := canonical[key]
+		if !found {
+			canonical[key] = e
+			keys = append(keys, key)
+			continue
+		}
+		first.categoryCode = e.categoryCode
+		if _, err := tx.Exec(`UPDATE measurements SET url_id = ? WHERE url_id = ?`,
+			first.id, e.id); err != nil {
+			return errors.Wrap(err, "repointing measurements")
+		}
+		if _, err := tx.Exec(`DELETE FROM urls WHERE url_id = ?`, e.id); err != nil {
+			return errors.Wrap(err, "deleting duplicate url")
+		}
+	}
+	for _, key := range keys {
+		e := canonical[key]
+		_, err := tx.Exec(`UPDATE urls SET url = ?, category_code = ? WHERE url_id = ?`,
+			e.url, e.categoryCode, e.id)
+		if err != nil {
+			return errors.Wrap(err, "normalizing url")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected string
+	}{
+		{"https://example.com/", "https://example.com/"},
+		{"HTTPS://Example.COM/Path?Q=A#F", "https://example.com/Path?Q=A#F"},
+		{"http://example.com:80/", "http://example.com/"},
+		{"https://example.com:443", "https://example.com"},
+		{"http://example.com:443/", "http://example.com:443/"},
+		{"https://example.com:8443/", "https://example.com:8443/"},
+		{"http://[::1]:80/", "http://[::1]/"},
+		{"http://User@Example.com/", "http://User@example.com/"},
+		{"example.com", "example.com"},
+		{"", ""},
+	} {
+		if got := NormalizeURL(tc.input); got != tc.expected {
+			t.Fatalf("NormalizeURL(%q): expected %q, got %q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestMergeDuplicateURLs(t *testing.T) {
+	sess, sqldb, cleanup := newMigrationsTestDB(t)
+	defer cleanup()
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	network, err := CreateNetwork(sess, &locationInfo{asn: 30722, countryCode: "IT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := CreateResult(sess, tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Emulate the URLs written by older releases.
+	urls := []struct {
+		url          string
+		categoryCode string
+		countryCode  string
+	}{
+		{"https://example.com/", "NEWS", "IT"},
+		{"HTTPS://EXAMPLE.COM:443/", "MISC", "IT"},
+		{"https://example.com/", "NEWS", "XX"},
+		{"https://example.org/", "GRP", "IT"},
+	}
+	// We create the measurements using the latest schema and then we go back
+	// to the schema before the URLs were unique to add the older URLs.
+	var measurementIDs []int64
+	for idx := range urls {
+		msmt, err := CreateMeasurement(sess, sql.NullString{}, "web_connectivity",
+			result.MeasurementDir, idx, result.ID, sql.NullInt64{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		measurementIDs = append(measurementIDs, msmt.ID)
+	}
+	if _, err := MigrateTo(sqldb, 8); err != nil {
+		t.Fatal(err)
+	}
+	for idx, entry := range urls {
+		res, err := sqldb.Exec(`INSERT INTO urls (url, category_code, url_country_code)
+			VALUES (?, ?, ?)`, entry.url, entry.categoryCode, entry.countryCode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urlID, err := res.LastInsertId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = sqldb.Exec(`UPDATE measurements SET url_id = ? WHERE measurement_id = ?`,
+			urlID, measurementIDs[idx])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RunMigrations(sqldb); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := sqldb.QueryRow(`SELECT COUNT(*) FROM urls`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatal("unexpected number of urls", count)
+	}
+	var url, categoryCode string
+	err = sqldb.QueryRow(`SELECT url, category_code FROM urls
+		JOIN measurements ON measurements.url_id = urls.url_id
+		WHERE measurement_id = ?`, measurementIDs[1]).Scan(&url, &categoryCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://example.com/" || categoryCode != "MISC" {
+		t.Fatal("unexpected url", url, categoryCode)
+	}
+
+	urlID, err := CreateOrUpdateURL(sess, "https://EXAMPLE.com:443/", "NEWS", "IT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msmt Measurement
+	if err := sess.Collection("measurements").Find("measurement_id", measurementIDs[0]).One(&msmt); err != nil {
+		t.Fatal(err)
+	}
+	if msmt.URLID.Int64 != urlID {
+		t.Fatal("expected to reuse the existing url", msmt.URLID.Int64, urlID)
+	}
+	if _, err := sqldb.Exec(`INSERT INTO urls (url, category_code, url_country_code)
+		VALUES ('https://example.org/', 'GRP', 'IT')`); err == nil {
+		t.Fatal("expected the uniqueness constraint to fail")
+	}
+}
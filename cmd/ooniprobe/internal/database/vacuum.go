@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/apex/log"
+	"github.com/pkg/errors"
+	"github.com/upper/db/v4"
+)
+
+// These constants control when MaybeVacuum reclaims space.
+const (
+	// autoVacuumMinBytes is the minimum amount of free space.
+	autoVacuumMinBytes = 8 << 20
+
+	// autoVacuumMinRatio is the minimum ratio between the free
+	// space and the size of the database.
+	autoVacuumMinRatio = 0.25
+)
+
+// autoVacuumIncremental is the auto_vacuum value enabling incremental vacuum.
+const autoVacuumIncremental = 2
+
+// VacuumStats contains the size of the database before and after vacuuming.
+type VacuumStats struct {
+	SizeBefore int64
+	SizeAfter  int64
+}
+
+// Reclaimed returns the number of bytes we reclaimed.
+func (s *VacuumStats) Reclaimed() int64 {
+	return s.SizeBefore - s.SizeAfter
+}
+
+// pageStats contains the page counters of the database.
+type pageStats struct {
+	pageSize      int64
+	pageCount     int64
+	freelistCount int64
+	autoVacuum    int64
+}
+
+// size returns the size of the database in bytes.
+func (s *pageStats) size() int64 {
+	return s.pageSize * s.pageCount
+}
+
+// free returns the size of the unused pages in bytes.
+func (s *pageStats) free() int64 {
+	return s.pageSize * s.freelistCount
+}
+
+// queryRower is the common interface of *sql.DB and *sql.Conn we need.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// getPageStats returns the page counters of the database.
+func getPageStats(sess queryRower) (*pageStats, error) {
+	var stats pageStats
+	for pragma, value := range map[string]*int64{
+		"page_size":      &stats.pageSize,
+		"page_count":     &stats.pageCount,
+		"freelist_count": &stats.freelistCount,
+		"auto_vacuum":    &stats.autoVacuum,
+	} {
+		err := sess.QueryRowContext(context.Background(), "PRAGMA "+pragma).Scan(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", pragma)
+		}
+	}
+	return &stats, nil
+}
+
+// Vacuum rebuilds the database, which reclaims all the unused space. We
+// also switch the database to incremental auto vacuum, such that we can
+// later use the cheaper incremental vacuum to reclaim space. This function
+// returns ErrNotSupported with PostgreSQL.
+func Vacuum(sess db.Session) (*VacuumStats, error) {
+	if isPostgreSQL(sess) {
+		return nil, ErrNotSupported
+	}
+	ctx := context.Background()
+	// Changing auto_vacuum only takes effect when followed by VACUUM
+	// on the same connection, hence we use a dedicated connection.
+	conn, err := sess.Driver().(*sql.DB).Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	before, err := getPageStats(conn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return nil, errors.Wrap(err, "enabling incremental auto vacuum")
+	}
+	if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+		return nil, errors.Wrap(err, "running VACUUM")
+	}
+	after, err := getPageStats(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &VacuumStats{SizeBefore: before.size(), SizeAfter: after.size()}, nil
+}
+
+// MaybeVacuum reclaims the unused space when there is enough of it, e.g.,
+// after deleting many results. We use incremental vacuum when possible and
+// otherwise we use Vacuum. This function returns nil stats and no error
+// when there is not enough unused space to reclaim, which is always the
+// case with PostgreSQL, whose autovacuum daemon reclaims space for us.
+func MaybeVacuum(sess db.Session) (*VacuumStats, error) {
+	if isPostgreSQL(sess) {
+		return nil, nil
+	}
+	sqlDB := sess.Driver().(*sql.DB)
+	before, err := getPageStats(sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	if before.free() < autoVacuumMinBytes ||
+		float64(before.free()) < autoVacuumMinRatio*float64(before.size()) {
+		return nil, nil
+	}
+	if before.autoVacuum != autoVacuumIncremental {
+		return Vacuum(sess)
+	}
+	if err := incrementalVacuum(sqlDB); err != nil {
+		return nil, errors.Wrap(err, "running incremental vacuum")
+	}
+	after, err := getPageStats(sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	return &VacuumStats{SizeBefore: before.size(), SizeAfter: after.size()}, nil
+}
+
+// incrementalVacuum frees all the unused pages. SQLite frees the pages while
+// we step through the statement, so we must consume all its rows.
+func incrementalVacuum(sess *sql.DB) error {
+	rows, err := sess.Query("PRAGMA incremental_vacuum")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		// nothing
+	}
+	return rows.Err()
+}
+
+// maybeVacuumAfterPrune is like MaybeVacuum but only logs the outcome,
+// since failing to reclaim space should not fail the prune.
+func maybeVacuumAfterPrune(sess db.Session) {
+	stats, err := MaybeVacuum(sess)
+	if err != nil {
+		log.WithError(err).Warn("failed to reclaim unused database space")
+		return
+	}
+	if stats != nil {
+		log.Debugf("reclaimed %d bytes of unused database space", stats.Reclaimed())
+	}
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestVacuum(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	sess, err := Connect(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	// prune writes and then deletes enough data to trigger MaybeVacuum.
+	prune := func(t *testing.T) {
+		name := string(bytes.Repeat([]byte("x"), 1<<20))
+		for i := 0; i < 2*autoVacuumMinBytes>>20; i++ {
+			_, err := CreateNetwork(sess, &locationInfo{networkName: fmt.Sprintf("%s-%d", name, i)})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sess.Collection("networks").Truncate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("with little unused space", func(t *testing.T) {
+		stats, err := MaybeVacuum(sess)
+		if err != nil || stats != nil {
+			t.Fatal("unexpected result", stats, err)
+		}
+	})
+
+	t.Run("with full vacuum", func(t *testing.T) {
+		prune(t)
+		stats, err := MaybeVacuum(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats == nil || stats.Reclaimed() < autoVacuumMinBytes {
+			t.Fatal("unexpected stats", stats)
+		}
+		pages, err := getPageStats(sess.Driver().(*sql.DB))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pages.autoVacuum != autoVacuumIncremental {
+			t.Fatal("expected incremental auto vacuum", pages.autoVacuum)
+		}
+	})
+
+	t.Run("with incremental vacuum", func(t *testing.T) {
+		prune(t)
+		stats, err := MaybeVacuum(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats == nil || stats.Reclaimed() < autoVacuumMinBytes {
+			t.Fatal("unexpected stats", stats)
+		}
+	})
+
+	t.Run("with explicit vacuum", func(t *testing.T) {
+		stats, err := Vacuum(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.SizeAfter <= 0 || stats.Reclaimed() < 0 {
+			t.Fatal("unexpected stats", stats)
+		}
+	})
+}
@@ -0,0 +1,742 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/enginex"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils/encryption"
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/upper/db/v4"
+)
+
+// ErrClosed indicates that the database has been closed.
+var ErrClosed = errors.New("database: closed")
+
+// Actions contains the database operations used by ooniprobe. The
+// implementation must be safe to use from several goroutines, e.g.,
+// the experiments, the submitter, and the code listing results.
+type Actions interface {
+	// ListMeasurements lists the measurements of a result.
+	ListMeasurements(resultID int64) ([]MeasurementURLNetwork, error)
+
+	// GetMeasurementJSON returns the JSON of a measurement.
+	GetMeasurementJSON(measurementID int64) (map[string]interface{}, error)
+
+	// GetMeasurement returns a measurement with its result and network.
+	GetMeasurement(measurementID int64) (*MeasurementURLNetwork, error)
+
+	// ListMeasurementReruns lists the re-runs of a measurement.
+	ListMeasurementReruns(measurementID int64) ([]Measurement, error)
+
+	// ListResults returns the done and the incomplete results.
+	ListResults() ([]ResultNetwork, []ResultNetwork, error)
+
+	// ListResultsMatching returns the done and the incomplete
+	// results selected by the filter.
+	ListResultsMatching(filter *ResultFilter) ([]ResultNetwork, []ResultNetwork, error)
+
+	// ListIncompleteResults returns the results that are not done.
+	ListIncompleteResults() ([]IncompleteResult, error)
+
+	// ListPerformanceSummaries returns the ndt summaries below the given speeds.
+	ListPerformanceSummaries(filter *PerformanceFilter) ([]PerformanceSummary, error)
+
+	// ExportResultsCSV writes all the results as CSV.
+	ExportResultsCSV(w io.Writer) error
+
+	// ExportMeasurementsCSV writes all the measurements as CSV.
+	ExportMeasurementsCSV(w io.Writer) error
+
+	// ExportMeasurementsJSONL writes the matching measurements as JSONL.
+	ExportMeasurementsJSONL(filter *MeasurementFilter, w io.Writer) (int, error)
+
+	// ListMeasurementsMatching returns the measurements selected by a filter.
+	ListMeasurementsMatching(filter *MeasurementFilter) ([]MeasurementURLNetwork, error)
+
+	// ListMeasurementsPage returns a page of the measurements selected by a filter.
+	ListMeasurementsPage(filter *MeasurementFilter, cursor string, limit int) (*MeasurementPage, error)
+
+	// StatsByNetwork returns the anomaly stats of each network.
+	StatsByNetwork() ([]NetworkStats, error)
+
+	// ListNetworkHistories returns the history of each network.
+	ListNetworkHistories(recentSince time.Time) ([]NetworkHistory, error)
+
+	// StatsByTestGroup returns the anomaly stats of each test group.
+	StatsByTestGroup() ([]TestGroupStats, error)
+
+	// StatsOverTime returns the anomaly stats of consecutive time windows.
+	StatsOverTime(since, until time.Time, window time.Duration) ([]TimeWindowStats, error)
+
+	// ListMeasurementTags returns the tags of a measurement.
+	ListMeasurementTags(measurementID int64) ([]string, error)
+
+	// ListTaggedMeasurements returns the IDs of the measurements with a tag.
+	ListTaggedMeasurements(tag string) ([]int64, error)
+
+	// ListMeasurementAnnotations returns the annotations of a measurement.
+	ListMeasurementAnnotations(measurementID int64) (map[string]string, error)
+
+	// ListResultNotes returns the notes of a result.
+	ListResultNotes(resultID int64) ([]ResultNote, error)
+
+	// CountPendingUploads returns the number of measurements to upload.
+	CountPendingUploads() (uint64, error)
+
+	// ListPendingUploads returns the measurements to upload.
+	ListPendingUploads() ([]Upload, error)
+
+	// FindOrphans returns the orphaned rows and files.
+	FindOrphans(homePath string) (*Orphans, error)
+
+	// CheckIntegrity returns the problems of the database file.
+	CheckIntegrity() ([]string, error)
+
+	// CreateNetwork creates a new network.
+	CreateNetwork(loc enginex.LocationProvider) (*Network, error)
+
+	// CreateResult creates a new result.
+	CreateResult(homePath string, testGroupName string, networkID int64) (*Result, error)
+
+	// CreateMeasurement creates a new measurement.
+	CreateMeasurement(reportID sql.NullString, testName string, measurementDir string,
+		idx int, resultID int64, urlID sql.NullInt64) (*Measurement, error)
+
+	// CreateOrUpdateURL creates or updates an URL and returns its ID.
+	CreateOrUpdateURL(urlStr string, categoryCode string, countryCode string) (int64, error)
+
+	// AddTestKeys writes the summary of a measurement.
+	AddTestKeys(msmt *Measurement, tk interface{}) error
+
+	// DeleteResult deletes a result and its measurements.
+	DeleteResult(resultID int64) error
+
+	// DeleteResults deletes either all the given results and
+	// their measurements or none of them.
+	DeleteResults(resultIDs []int64) error
+
+	// UpdateUploadedStatus updates whether a result has been fully uploaded.
+	UpdateUploadedStatus(result *Result) error
+
+	// ResultFinished marks a result as done.
+	ResultFinished(result *Result) error
+
+	// UpdateResultLinkContext sets the link context of a result.
+	UpdateResultLinkContext(result *Result, network *Network, linkType string) error
+
+	// SetResultSoftware sets the software that produced a result.
+	SetResultSoftware(result *Result, info *SoftwareInfo) error
+
+	// FailResult gives up on an incomplete result.
+	FailResult(resultID int64, reason string) error
+
+	// ResumeResult prepares an incomplete result for resumption.
+	ResumeResult(resultID int64, network *Network) (*ResumedResult, error)
+
+	// MeasurementFailed marks a measurement as failed.
+	MeasurementFailed(msmt *Measurement, failure string) error
+
+	// MeasurementDone marks a measurement as done.
+	MeasurementDone(msmt *Measurement) error
+
+	// CompleteMeasurement marks a measurement as done and writes its
+	// measurement file, summary, and annotations.
+	CompleteMeasurement(msmt *Measurement, data []byte, tk interface{}, annotations map[string]string) error
+
+	// SetMeasurementRerunOf marks a measurement as a re-run of another.
+	SetMeasurementRerunOf(msmt *Measurement, originalID int64) error
+
+	// MeasurementUploadFailed records a failed upload.
+	MeasurementUploadFailed(msmt *Measurement, failure string) error
+
+	// MeasurementUploadSucceeded records a successful upload.
+	MeasurementUploadSucceeded(msmt *Measurement, collectorAddress string) error
+
+	// AddMeasurementTag tags a measurement.
+	AddMeasurementTag(measurementID int64, tag string) error
+
+	// RemoveMeasurementTag removes a tag from a measurement.
+	RemoveMeasurementTag(measurementID int64, tag string) error
+
+	// SetMeasurementAnnotations stores the annotations of a measurement.
+	SetMeasurementAnnotations(measurementID int64, annotations map[string]string) error
+
+	// AddResultNote adds a note to a result.
+	AddResultNote(resultID int64, text string) (*ResultNote, error)
+
+	// DeleteResultNote deletes a note.
+	DeleteResultNote(noteID int64) error
+
+	// RecordEvent records a lifecycle event.
+	RecordEvent(kind string, resultID sql.NullInt64, details interface{}) error
+
+	// RecordLocation records a location event if the location changed.
+	RecordLocation(network *Network, resultID sql.NullInt64) (bool, error)
+
+	// ListEvents lists the lifecycle events.
+	ListEvents(filter *EventFilter) ([]Event, error)
+
+	// EstimateDataUsage estimates the data usage of a test group.
+	EstimateDataUsage(groupName string) (*DataUsageEstimate, error)
+
+	// ListScheduleRuns lists the runs of the scheduled test groups.
+	ListScheduleRuns() ([]ScheduleRun, error)
+
+	// SaveScheduleRun saves the run of a scheduled test group.
+	SaveScheduleRun(run *ScheduleRun) error
+
+	// RemoveOrphans removes the orphans returned by FindOrphans.
+	RemoveOrphans(orphans *Orphans) error
+
+	// Vacuum reclaims the unused space.
+	Vacuum() (*VacuumStats, error)
+
+	// CompressMeasurementFiles compresses the uncompressed measurement files.
+	CompressMeasurementFiles() (int, error)
+
+	// ReadMeasurementFile reads a measurement file, decrypting it
+	// when the database is encrypted.
+	ReadMeasurementFile(path string) ([]byte, error)
+
+	// WriteMeasurementFile writes a measurement file, encrypting it
+	// when the database is encrypted.
+	WriteMeasurementFile(path string, data []byte) error
+
+	// ImportMeasurements stores measurements fetched from the OONI API.
+	ImportMeasurements(homePath string, measurements []*probeservices.MeasurementListEntry) (int, error)
+
+	// Backup writes a backup of the database and of the measurements.
+	Backup(homePath string, backupDir string) error
+
+	// Restore replaces the database and the measurements with a backup.
+	Restore(homePath string, backupDir string) error
+
+	// Close closes the database.
+	Close() error
+}
+
+// writeRequest is a request for the write worker.
+type writeRequest struct {
+	// fn is the function performing the write.
+	fn func(sess db.Session) error
+
+	// errch receives the return value of fn.
+	errch chan error
+}
+
+// Database implements Actions. We run reads directly, since WAL mode
+// allows reads to run concurrently with a write, while we run all writes
+// in a single worker goroutine, such that they never race with each other.
+type Database struct {
+	sess      db.Session
+	writes    chan *writeRequest
+	closed    chan struct{}
+	stopped   chan struct{}
+	closeOnce *sync.Once
+
+	// afterClose, if not nil, runs after we closed the session.
+	afterClose func() error
+
+	// passphrase is the passphrase of the encrypted database, if any,
+	// which we also use to encrypt and decrypt backups.
+	passphrase string
+
+	// keyring, if not nil, encrypts and decrypts the measurement
+	// files using the passphrase of the encrypted database.
+	keyring *encryption.Keyring
+}
+
+var _ Actions = &Database{}
+
+// Open connects to the database at path and starts the write worker. The
+// caller is responsible for calling Close when done. This function fails
+// with ErrEncryptedDatabase if there is an encrypted database, in which
+// case the caller should use OpenEncrypted instead.
+func Open(path string) (*Database, error) {
+	if utils.FileExists(EncryptedPath(path)) {
+		return nil, ErrEncryptedDatabase
+	}
+	sess, err := Connect(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewDatabase(sess), nil
+}
+
+// NewDatabase creates a Database using the given session and starts the
+// write worker. The Database takes ownership of the session.
+func NewDatabase(sess db.Session) *Database {
+	d := &Database{
+		sess:      sess,
+		writes:    make(chan *writeRequest),
+		closed:    make(chan struct{}),
+		stopped:   make(chan struct{}),
+		closeOnce: &sync.Once{},
+	}
+	go d.loop()
+	return d
+}
+
+// loop is the write worker.
+func (d *Database) loop() {
+	defer close(d.stopped)
+	for {
+		select {
+		case req := <-d.writes:
+			req.errch <- req.fn(d.sess)
+		case <-d.closed:
+			return
+		}
+	}
+}
+
+// write runs fn in the write worker and returns its result.
+func (d *Database) write(fn func(sess db.Session) error) error {
+	req := &writeRequest{fn: fn, errch: make(chan error, 1)}
+	select {
+	case d.writes <- req:
+		return <-req.errch
+	case <-d.closed:
+		return ErrClosed
+	}
+}
+
+// Close stops the write worker, after it has finished the write it
+// is running, if any, and closes the underlying session.
+func (d *Database) Close() (err error) {
+	err = ErrClosed
+	d.closeOnce.Do(func() {
+		close(d.closed)
+		<-d.stopped
+		if err = d.sess.Close(); err == nil && d.afterClose != nil {
+			err = d.afterClose()
+		}
+	})
+	return
+}
+
+// ListMeasurements implements Actions.ListMeasurements.
+func (d *Database) ListMeasurements(resultID int64) ([]MeasurementURLNetwork, error) {
+	return ListMeasurements(d.sess, resultID)
+}
+
+// GetMeasurementJSON implements Actions.GetMeasurementJSON.
+func (d *Database) GetMeasurementJSON(measurementID int64) (map[string]interface{}, error) {
+	return GetMeasurementJSON(d.sess, measurementID, d.keyring)
+}
+
+// GetMeasurement implements Actions.GetMeasurement.
+func (d *Database) GetMeasurement(measurementID int64) (*MeasurementURLNetwork, error) {
+	return GetMeasurement(d.sess, measurementID)
+}
+
+// ListMeasurementReruns implements Actions.ListMeasurementReruns.
+func (d *Database) ListMeasurementReruns(measurementID int64) ([]Measurement, error) {
+	return ListMeasurementReruns(d.sess, measurementID)
+}
+
+// ListResults implements Actions.ListResults.
+func (d *Database) ListResults() ([]ResultNetwork, []ResultNetwork, error) {
+	return ListResults(d.sess)
+}
+
+// ListResultsMatching implements Actions.ListResultsMatching.
+func (d *Database) ListResultsMatching(filter *ResultFilter) ([]ResultNetwork, []ResultNetwork, error) {
+	return ListResultsMatching(d.sess, filter)
+}
+
+// ListIncompleteResults implements Actions.ListIncompleteResults.
+func (d *Database) ListIncompleteResults() ([]IncompleteResult, error) {
+	return ListIncompleteResults(d.sess)
+}
+
+// ListPerformanceSummaries implements Actions.ListPerformanceSummaries.
+func (d *Database) ListPerformanceSummaries(filter *PerformanceFilter) ([]PerformanceSummary, error) {
+	return ListPerformanceSummaries(d.sess, filter)
+}
+
+// ExportResultsCSV implements Actions.ExportResultsCSV.
+func (d *Database) ExportResultsCSV(w io.Writer) error {
+	return ExportResultsCSV(d.sess, w)
+}
+
+// ExportMeasurementsCSV implements Actions.ExportMeasurementsCSV.
+func (d *Database) ExportMeasurementsCSV(w io.Writer) error {
+	return ExportMeasurementsCSV(d.sess, w)
+}
+
+// ExportMeasurementsJSONL implements Actions.ExportMeasurementsJSONL.
+func (d *Database) ExportMeasurementsJSONL(filter *MeasurementFilter, w io.Writer) (int, error) {
+	return ExportMeasurementsJSONL(d.sess, filter, w, d.keyring)
+}
+
+// ListMeasurementsMatching implements Actions.ListMeasurementsMatching.
+func (d *Database) ListMeasurementsMatching(filter *MeasurementFilter) ([]MeasurementURLNetwork, error) {
+	return ListMeasurementsMatching(d.sess, filter)
+}
+
+// ListMeasurementsPage implements Actions.ListMeasurementsPage.
+func (d *Database) ListMeasurementsPage(filter *MeasurementFilter, cursor string, limit int) (*MeasurementPage, error) {
+	return ListMeasurementsPage(d.sess, filter, cursor, limit)
+}
+
+// StatsByNetwork implements Actions.StatsByNetwork.
+func (d *Database) StatsByNetwork() ([]NetworkStats, error) {
+	return StatsByNetwork(d.sess)
+}
+
+// ListNetworkHistories implements Actions.ListNetworkHistories.
+func (d *Database) ListNetworkHistories(recentSince time.Time) ([]NetworkHistory, error) {
+	return ListNetworkHistories(d.sess, recentSince)
+}
+
+// StatsByTestGroup implements Actions.StatsByTestGroup.
+func (d *Database) StatsByTestGroup() ([]TestGroupStats, error) {
+	return StatsByTestGroup(d.sess)
+}
+
+// StatsOverTime implements Actions.StatsOverTime.
+func (d *Database) StatsOverTime(since, until time.Time, window time.Duration) ([]TimeWindowStats, error) {
+	return StatsOverTime(d.sess, since, until, window)
+}
+
+// ListMeasurementTags implements Actions.ListMeasurementTags.
+func (d *Database) ListMeasurementTags(measurementID int64) ([]string, error) {
+	return ListMeasurementTags(d.sess, measurementID)
+}
+
+// ListTaggedMeasurements implements Actions.ListTaggedMeasurements.
+func (d *Database) ListTaggedMeasurements(tag string) ([]int64, error) {
+	return ListTaggedMeasurements(d.sess, tag)
+}
+
+// ListMeasurementAnnotations implements Actions.ListMeasurementAnnotations.
+func (d *Database) ListMeasurementAnnotations(measurementID int64) (map[string]string, error) {
+	return ListMeasurementAnnotations(d.sess, measurementID)
+}
+
+// ListResultNotes implements Actions.ListResultNotes.
+func (d *Database) ListResultNotes(resultID int64) ([]ResultNote, error) {
+	return ListResultNotes(d.sess, resultID)
+}
+
+// ListPendingUploads implements Actions.ListPendingUploads.
+func (d *Database) ListPendingUploads() ([]Upload, error) {
+	return ListPendingUploads(d.sess)
+}
+
+// CountPendingUploads implements Actions.CountPendingUploads.
+func (d *Database) CountPendingUploads() (uint64, error) {
+	return CountPendingUploads(d.sess)
+}
+
+// FindOrphans implements Actions.FindOrphans.
+func (d *Database) FindOrphans(homePath string) (*Orphans, error) {
+	return FindOrphans(d.sess, homePath)
+}
+
+// CheckIntegrity implements Actions.CheckIntegrity.
+func (d *Database) CheckIntegrity() ([]string, error) {
+	return CheckIntegrity(d.sess)
+}
+
+// CreateNetwork implements Actions.CreateNetwork.
+func (d *Database) CreateNetwork(loc enginex.LocationProvider) (network *Network, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		network, err = CreateNetwork(sess, loc)
+		return
+	})
+	return
+}
+
+// CreateResult implements Actions.CreateResult.
+func (d *Database) CreateResult(homePath string, testGroupName string, networkID int64) (result *Result, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		result, err = CreateResult(sess, homePath, testGroupName, networkID)
+		return
+	})
+	return
+}
+
+// CreateMeasurement implements Actions.CreateMeasurement.
+func (d *Database) CreateMeasurement(reportID sql.NullString, testName string, measurementDir string,
+	idx int, resultID int64, urlID sql.NullInt64) (msmt *Measurement, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		msmt, err = CreateMeasurement(sess, reportID, testName, measurementDir, idx, resultID, urlID)
+		return
+	})
+	return
+}
+
+// CreateOrUpdateURL implements Actions.CreateOrUpdateURL.
+func (d *Database) CreateOrUpdateURL(urlStr string, categoryCode string, countryCode string) (urlID int64, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		urlID, err = CreateOrUpdateURL(sess, urlStr, categoryCode, countryCode)
+		return
+	})
+	return
+}
+
+// AddTestKeys implements Actions.AddTestKeys.
+func (d *Database) AddTestKeys(msmt *Measurement, tk interface{}) error {
+	return d.write(func(sess db.Session) error {
+		return AddTestKeys(sess, msmt, tk)
+	})
+}
+
+// DeleteResult implements Actions.DeleteResult.
+func (d *Database) DeleteResult(resultID int64) error {
+	return d.write(func(sess db.Session) error {
+		if err := DeleteResult(sess, resultID); err != nil {
+			return err
+		}
+		maybeVacuumAfterPrune(sess)
+		return nil
+	})
+}
+
+// DeleteResults implements Actions.DeleteResults.
+func (d *Database) DeleteResults(resultIDs []int64) error {
+	return d.write(func(sess db.Session) error {
+		if err := DeleteResults(sess, resultIDs); err != nil {
+			return err
+		}
+		maybeVacuumAfterPrune(sess)
+		return nil
+	})
+}
+
+// UpdateUploadedStatus implements Actions.UpdateUploadedStatus.
+func (d *Database) UpdateUploadedStatus(result *Result) error {
+	return d.write(func(sess db.Session) error {
+		return UpdateUploadedStatus(sess, result)
+	})
+}
+
+// ResultFinished implements Actions.ResultFinished.
+func (d *Database) ResultFinished(result *Result) error {
+	return d.write(result.Finished)
+}
+
+// UpdateResultLinkContext implements Actions.UpdateResultLinkContext.
+func (d *Database) UpdateResultLinkContext(result *Result, network *Network, linkType string) error {
+	return d.write(func(sess db.Session) error {
+		return UpdateResultLinkContext(sess, result, network, linkType)
+	})
+}
+
+// SetResultSoftware implements Actions.SetResultSoftware.
+func (d *Database) SetResultSoftware(result *Result, info *SoftwareInfo) error {
+	return d.write(func(sess db.Session) error {
+		return SetResultSoftware(sess, result, info)
+	})
+}
+
+// FailResult implements Actions.FailResult.
+func (d *Database) FailResult(resultID int64, reason string) error {
+	return d.write(func(sess db.Session) error {
+		return FailResult(sess, resultID, reason)
+	})
+}
+
+// ResumeResult implements Actions.ResumeResult.
+func (d *Database) ResumeResult(resultID int64, network *Network) (resumed *ResumedResult, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		resumed, err = ResumeResult(sess, resultID, network)
+		return
+	})
+	return
+}
+
+// MeasurementFailed implements Actions.MeasurementFailed.
+func (d *Database) MeasurementFailed(msmt *Measurement, failure string) error {
+	return d.write(func(sess db.Session) error {
+		return msmt.Failed(sess, failure)
+	})
+}
+
+// MeasurementDone implements Actions.MeasurementDone.
+func (d *Database) MeasurementDone(msmt *Measurement) error {
+	return d.write(msmt.Done)
+}
+
+// CompleteMeasurement implements Actions.CompleteMeasurement.
+func (d *Database) CompleteMeasurement(msmt *Measurement, data []byte, tk interface{}, annotations map[string]string) error {
+	return d.write(func(sess db.Session) error {
+		return CompleteMeasurement(sess, msmt, data, tk, annotations, d.keyring)
+	})
+}
+
+// SetMeasurementRerunOf implements Actions.SetMeasurementRerunOf.
+func (d *Database) SetMeasurementRerunOf(msmt *Measurement, originalID int64) error {
+	return d.write(func(sess db.Session) error {
+		return SetMeasurementRerunOf(sess, msmt, originalID)
+	})
+}
+
+// MeasurementUploadFailed implements Actions.MeasurementUploadFailed.
+func (d *Database) MeasurementUploadFailed(msmt *Measurement, failure string) error {
+	return d.write(func(sess db.Session) error {
+		return msmt.UploadFailed(sess, failure)
+	})
+}
+
+// MeasurementUploadSucceeded implements Actions.MeasurementUploadSucceeded.
+func (d *Database) MeasurementUploadSucceeded(msmt *Measurement, collectorAddress string) error {
+	return d.write(func(sess db.Session) error {
+		return msmt.UploadSucceeded(sess, collectorAddress)
+	})
+}
+
+// AddMeasurementTag implements Actions.AddMeasurementTag.
+func (d *Database) AddMeasurementTag(measurementID int64, tag string) error {
+	return d.write(func(sess db.Session) error {
+		return AddMeasurementTag(sess, measurementID, tag)
+	})
+}
+
+// RemoveMeasurementTag implements Actions.RemoveMeasurementTag.
+func (d *Database) RemoveMeasurementTag(measurementID int64, tag string) error {
+	return d.write(func(sess db.Session) error {
+		return RemoveMeasurementTag(sess, measurementID, tag)
+	})
+}
+
+// SetMeasurementAnnotations implements Actions.SetMeasurementAnnotations.
+func (d *Database) SetMeasurementAnnotations(measurementID int64, annotations map[string]string) error {
+	return d.write(func(sess db.Session) error {
+		return SetMeasurementAnnotations(sess, measurementID, annotations)
+	})
+}
+
+// AddResultNote implements Actions.AddResultNote.
+func (d *Database) AddResultNote(resultID int64, text string) (note *ResultNote, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		note, err = AddResultNote(sess, resultID, text)
+		return
+	})
+	return
+}
+
+// DeleteResultNote implements Actions.DeleteResultNote.
+func (d *Database) DeleteResultNote(noteID int64) error {
+	return d.write(func(sess db.Session) error {
+		return DeleteResultNote(sess, noteID)
+	})
+}
+
+// RecordEvent implements Actions.RecordEvent.
+func (d *Database) RecordEvent(kind string, resultID sql.NullInt64, details interface{}) error {
+	return d.write(func(sess db.Session) error {
+		_, err := RecordEvent(sess, kind, resultID, details)
+		return err
+	})
+}
+
+// RecordLocation implements Actions.RecordLocation.
+func (d *Database) RecordLocation(network *Network, resultID sql.NullInt64) (changed bool, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		changed, err = RecordLocation(sess, network, resultID)
+		return
+	})
+	return
+}
+
+// ListEvents implements Actions.ListEvents.
+func (d *Database) ListEvents(filter *EventFilter) ([]Event, error) {
+	return ListEvents(d.sess, filter)
+}
+
+// EstimateDataUsage implements Actions.EstimateDataUsage.
+func (d *Database) EstimateDataUsage(groupName string) (*DataUsageEstimate, error) {
+	return EstimateDataUsage(d.sess, groupName)
+}
+
+// ListScheduleRuns implements Actions.ListScheduleRuns.
+func (d *Database) ListScheduleRuns() ([]ScheduleRun, error) {
+	return ListScheduleRuns(d.sess)
+}
+
+// SaveScheduleRun implements Actions.SaveScheduleRun.
+func (d *Database) SaveScheduleRun(run *ScheduleRun) error {
+	return d.write(func(sess db.Session) error {
+		return SaveScheduleRun(sess, run)
+	})
+}
+
+// RemoveOrphans implements Actions.RemoveOrphans.
+func (d *Database) RemoveOrphans(orphans *Orphans) error {
+	return d.write(func(sess db.Session) error {
+		if err := RemoveOrphans(sess, orphans); err != nil {
+			return err
+		}
+		maybeVacuumAfterPrune(sess)
+		return nil
+	})
+}
+
+// Vacuum implements Actions.Vacuum.
+func (d *Database) Vacuum() (stats *VacuumStats, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		stats, err = Vacuum(sess)
+		return
+	})
+	return
+}
+
+// CompressMeasurementFiles implements Actions.CompressMeasurementFiles.
+func (d *Database) CompressMeasurementFiles() (count int, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		count, err = CompressMeasurementFiles(sess, d.keyring)
+		return
+	})
+	return
+}
+
+// ReadMeasurementFile implements Actions.ReadMeasurementFile.
+func (d *Database) ReadMeasurementFile(path string) ([]byte, error) {
+	return ReadMeasurementFile(path, d.keyring)
+}
+
+// WriteMeasurementFile implements Actions.WriteMeasurementFile.
+func (d *Database) WriteMeasurementFile(path string, data []byte) error {
+	return WriteMeasurementFile(path, data, d.keyring)
+}
+
+// ImportMeasurements implements Actions.ImportMeasurements.
+func (d *Database) ImportMeasurements(homePath string, measurements []*probeservices.MeasurementListEntry) (count int, err error) {
+	err = d.write(func(sess db.Session) (err error) {
+		count, err = ImportMeasurements(sess, homePath, measurements)
+		return
+	})
+	return
+}
+
+// Backup implements Actions.Backup. We use the worker, such that the
+// backup does not contain a partially written measurement.
+func (d *Database) Backup(homePath string, backupDir string) error {
+	return d.write(func(sess db.Session) error {
+		return Backup(sess, homePath, backupDir, d.passphrase)
+	})
+}
+
+// Restore implements Actions.Restore. When the database is encrypted, we
+// also encrypt the restored measurement files, which are not encrypted
+// when the backup was taken before enabling encryption.
+func (d *Database) Restore(homePath string, backupDir string) error {
+	return d.write(func(sess db.Session) error {
+		if err := Restore(sess, homePath, backupDir, d.passphrase); err != nil {
+			return err
+		}
+		if d.keyring == nil {
+			return nil
+		}
+		_, err := encryptMeasurementFiles(sess, d.keyring)
+		return err
+	})
+}
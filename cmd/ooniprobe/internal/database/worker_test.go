@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestDatabaseConcurrentActions(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "dbtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpdir, err := ioutil.TempDir("", "oonitest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	d, err := Open(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	network, err := d.CreateNetwork(&locationInfo{asn: 0, countryCode: "IT", networkName: "Unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.CreateResult(tmpdir, "websites", network.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Emulate several experiments writing measurements while
+	// the UI is listing the measurements of the same result.
+	const writers, writes = 4, 25
+	errch := make(chan error, 2*writers*writes)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < writers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				msmt, err := d.CreateMeasurement(sql.NullString{}, "web_connectivity",
+					tmpdir, w*writes+i, result.ID, sql.NullInt64{})
+				if err != nil {
+					errch <- err
+					continue
+				}
+				errch <- d.MeasurementDone(msmt)
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				_, err := d.ListMeasurements(result.ID)
+				errch <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errch)
+	for err := range errch {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	measurements, err := d.ListMeasurements(result.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(measurements) != writers*writes {
+		t.Fatal("unexpected number of measurements", len(measurements))
+	}
+	for _, msmt := range measurements {
+		if !msmt.Measurement.IsDone {
+			t.Fatal("expected the measurement to be done", msmt.Measurement.ID)
+		}
+	}
+
+	if err := d.ResultFinished(result); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != ErrClosed {
+		t.Fatal("not the error we expected", err)
+	}
+	if err := d.DeleteResult(result.ID); err != ErrClosed {
+		t.Fatal("not the error we expected", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package doctor
+
+// freeDiskSpace returns the free disk space of the filesystem
+// containing the given path, which we cannot get on this platform.
+func freeDiskSpace(path string) (uint64, error) {
+	return 0, errDiskSpaceUnknown
+}
@@ -0,0 +1,392 @@
+// Package doctor diagnoses the problems that may prevent ooniprobe from
+// measuring or uploading (see `ooniprobe doctor`).
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/internal/engine/geolocate"
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// These are the severities of the findings.
+const (
+	// SeverityOK indicates that the check succeeded.
+	SeverityOK = "ok"
+
+	// SeverityWarning indicates a problem that may affect some measurements.
+	SeverityWarning = "warning"
+
+	// SeverityError indicates a problem preventing us from measuring.
+	SeverityError = "error"
+)
+
+// These constants control when we report a problem.
+const (
+	// maxClockSkew is the maximum clock skew, beyond which TLS
+	// handshakes may fail and measurements have wrong times.
+	maxClockSkew = 5 * time.Minute
+
+	// minFreeDiskSpace is the free disk space below which we
+	// cannot reliably save results.
+	minFreeDiskSpace = 50 << 20
+
+	// lowFreeDiskSpace is the free disk space below which we warn.
+	lowFreeDiskSpace = 500 << 20
+
+	// maxGeoIPDataAge is the age after which we consider the geoip
+	// data stale, which may cause a wrong ASN or country.
+	maxGeoIPDataAge = 180 * 24 * time.Hour
+
+	// checkTimeout is the timeout of each network check.
+	checkTimeout = 15 * time.Second
+)
+
+// errDiskSpaceUnknown indicates that we cannot get the free disk
+// space on this platform.
+var errDiskSpaceUnknown = errors.New("doctor: cannot get the free disk space")
+
+// Finding is the outcome of a check.
+type Finding struct {
+	// Check is the name of the check (e.g., "clock").
+	Check string
+
+	// Severity is one of SeverityOK, SeverityWarning, and SeverityError.
+	Severity string
+
+	// Message describes the outcome.
+	Message string
+
+	// Advice is the OPTIONAL action the user should take.
+	Advice string
+}
+
+// Session is the subset of *engine.Session we use.
+type Session interface {
+	MaybeLookupBackendsContext(ctx context.Context) error
+	NewProbeServicesClient(ctx context.Context) (*probeservices.Client, error)
+	GetTestHelpersByName(name string) ([]model.OOAPIService, bool)
+	DefaultHTTPClient() *http.Client
+}
+
+// Resolver is a resolver we use to check the DNS.
+type Resolver struct {
+	// Name describes the resolver (e.g., "udp://8.8.8.8:53").
+	Name string
+
+	// Resolver is the resolver.
+	Resolver model.Resolver
+}
+
+// Config contains the settings of the doctor.
+type Config struct {
+	// Session is the session to check the backends with.
+	Session Session
+
+	// DB is the database to check.
+	DB database.Actions
+
+	// Home is the OONI home directory.
+	Home string
+
+	// Resolvers contains the resolvers to check, which should
+	// use different transports (e.g., UDP, DoT, and DoH).
+	Resolvers []Resolver
+
+	// Domain is the domain we resolve to check the DNS.
+	Domain string
+}
+
+// Doctor runs the checks.
+type Doctor struct {
+	config Config
+
+	// These fields allow to mock the environment in tests.
+	timeNow        func() time.Time
+	freeDiskSpace  func(path string) (uint64, error)
+	geoipBuildTime func() (time.Time, error)
+}
+
+// New creates a new doctor.
+func New(config Config) *Doctor {
+	if config.Domain == "" {
+		config.Domain = "api.ooni.io"
+	}
+	return &Doctor{
+		config:         config,
+		timeNow:        time.Now,
+		freeDiskSpace:  freeDiskSpace,
+		geoipBuildTime: geolocate.DatabaseBuildTime,
+	}
+}
+
+// Run runs all the checks and returns their findings.
+func (d *Doctor) Run(ctx context.Context) []Finding {
+	var findings []Finding
+	finding, serverTime := d.checkProbeServices(ctx)
+	findings = append(findings, finding)
+	if !serverTime.IsZero() {
+		findings = append(findings, checkClockSkew(d.timeNow(), serverTime))
+	}
+	findings = append(findings, d.checkTestHelpers(ctx))
+	findings = append(findings, d.checkDNS(ctx))
+	findings = append(findings, d.checkDiskSpace())
+	findings = append(findings, d.checkDatabase())
+	findings = append(findings, d.checkGeoIPData())
+	return findings
+}
+
+// checkProbeServices checks whether we can reach the probe services,
+// returning the time of their clock, if we could reach them.
+func (d *Doctor) checkProbeServices(ctx context.Context) (Finding, time.Time) {
+	const check = "probe_services"
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if err := d.config.Session.MaybeLookupBackendsContext(ctx); err != nil {
+		return Finding{
+			Check:    check,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("cannot reach the OONI backend: %s", err),
+			Advice:   "check your connection or use --proxy to reach the OONI backend (e.g., --proxy psiphon:///)",
+		}, time.Time{}
+	}
+	client, err := d.config.Session.NewProbeServicesClient(ctx)
+	if err != nil {
+		return Finding{Check: check, Severity: SeverityError, Message: err.Error()}, time.Time{}
+	}
+	serverTime, err := d.headDate(ctx, client.BaseURL)
+	if err != nil {
+		return Finding{
+			Check:    check,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("cannot reach %s: %s", client.BaseURL, err),
+			Advice:   "check your connection or use --proxy to reach the OONI backend",
+		}, time.Time{}
+	}
+	return Finding{
+		Check:    check,
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("using %s", client.BaseURL),
+	}, serverTime
+}
+
+// headDate sends a HEAD request to the given URL and returns the
+// value of the Date header, which is zero when it is missing.
+func (d *Doctor) headDate(ctx context.Context, URL string) (time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", URL, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := d.config.Session.DefaultHTTPClient().Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp.Body.Close()
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return date, nil
+}
+
+// checkClockSkew compares our clock with the clock of the server.
+func checkClockSkew(local, server time.Time) Finding {
+	skew := local.Sub(server)
+	if skew < 0 {
+		skew = -skew
+	}
+	// The Date header has a resolution of one second.
+	skew = skew.Truncate(time.Second)
+	if skew > maxClockSkew {
+		return Finding{
+			Check:    "clock",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("the clock differs by %s from the clock of the OONI backend", skew),
+			Advice:   "synchronize the clock (e.g., using NTP), otherwise TLS handshakes may fail",
+		}
+	}
+	return Finding{
+		Check:    "clock",
+		Severity: SeverityOK,
+		Message:  fmt.Sprintf("the clock differs by %s from the clock of the OONI backend", skew),
+	}
+}
+
+// checkTestHelpers checks whether we can reach the test helpers of
+// web_connectivity, without which we cannot measure websites.
+func (d *Doctor) checkTestHelpers(ctx context.Context) Finding {
+	const check = "test_helpers"
+	ths, _ := d.config.Session.GetTestHelpersByName("web-connectivity")
+	var reachable, total int
+	var failures []string
+	for _, th := range ths {
+		if th.Type != "https" {
+			continue
+		}
+		total++
+		thCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		_, err := d.headDate(thCtx, th.Address)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s (%s)", th.Address, err))
+			continue
+		}
+		reachable++
+	}
+	switch {
+	case total <= 0:
+		return Finding{
+			Check:    check,
+			Severity: SeverityError,
+			Message:  "the OONI backend did not return any test helper",
+			Advice:   "try again later, since we cannot measure websites without test helpers",
+		}
+	case reachable <= 0:
+		return Finding{
+			Check:    check,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("cannot reach any test helper: %s", strings.Join(failures, ", ")),
+			Advice:   "check your connection, since we cannot measure websites without test helpers",
+		}
+	case reachable < total:
+		return Finding{
+			Check:    check,
+			Severity: SeverityWarning,
+			Message: fmt.Sprintf("reached %d of %d test helpers, failed: %s",
+				reachable, total, strings.Join(failures, ", ")),
+		}
+	default:
+		return Finding{
+			Check:    check,
+			Severity: SeverityOK,
+			Message:  fmt.Sprintf("reached %d test helpers", total),
+		}
+	}
+}
+
+// checkDNS checks whether the resolvers work.
+func (d *Doctor) checkDNS(ctx context.Context) Finding {
+	const check = "dns"
+	var working, failures []string
+	for _, r := range d.config.Resolvers {
+		resolverCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		_, err := r.Resolver.LookupHost(resolverCtx, d.config.Domain)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s (%s)", r.Name, err))
+			continue
+		}
+		working = append(working, r.Name)
+	}
+	switch {
+	case len(working) <= 0:
+		return Finding{
+			Check:    check,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("cannot resolve %s: %s", d.config.Domain, strings.Join(failures, ", ")),
+			Advice:   "check your DNS settings and your connection",
+		}
+	case len(failures) > 0:
+		return Finding{
+			Check:    check,
+			Severity: SeverityWarning,
+			Message: fmt.Sprintf("resolved %s using %s, failed: %s", d.config.Domain,
+				strings.Join(working, ", "), strings.Join(failures, ", ")),
+			Advice: "some DNS transports may be blocked or misconfigured on this network",
+		}
+	default:
+		return Finding{
+			Check:    check,
+			Severity: SeverityOK,
+			Message:  fmt.Sprintf("resolved %s using %s", d.config.Domain, strings.Join(working, ", ")),
+		}
+	}
+}
+
+// checkDiskSpace checks the free disk space in the OONI home.
+func (d *Doctor) checkDiskSpace() Finding {
+	const check = "disk_space"
+	free, err := d.freeDiskSpace(d.config.Home)
+	if errors.Is(err, errDiskSpaceUnknown) {
+		return Finding{Check: check, Severity: SeverityOK, Message: "cannot check the free disk space on this platform"}
+	}
+	if err != nil {
+		return Finding{Check: check, Severity: SeverityWarning, Message: err.Error()}
+	}
+	message := fmt.Sprintf("%d MiB free in %s", free>>20, d.config.Home)
+	switch {
+	case free < minFreeDiskSpace:
+		return Finding{
+			Check:    check,
+			Severity: SeverityError,
+			Message:  message,
+			Advice:   "free some disk space or delete old results using `ooniprobe rm --all-before`",
+		}
+	case free < lowFreeDiskSpace:
+		return Finding{
+			Check:    check,
+			Severity: SeverityWarning,
+			Message:  message,
+			Advice:   "consider deleting old results using `ooniprobe rm --all-before`",
+		}
+	default:
+		return Finding{Check: check, Severity: SeverityOK, Message: message}
+	}
+}
+
+// checkDatabase checks the integrity of the database and looks
+// for the rows and files left behind by crashes.
+func (d *Doctor) checkDatabase() Finding {
+	const check = "database"
+	problems, err := d.config.DB.CheckIntegrity()
+	if err != nil {
+		return Finding{Check: check, Severity: SeverityError, Message: err.Error()}
+	}
+	if len(problems) > 0 {
+		return Finding{
+			Check:    check,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("the database is corrupted: %s", strings.Join(problems, "; ")),
+			Advice:   "restore a backup using `ooniprobe restore` or start over using `ooniprobe reset`",
+		}
+	}
+	orphans, err := d.config.DB.FindOrphans(d.config.Home)
+	if err != nil {
+		return Finding{Check: check, Severity: SeverityWarning, Message: err.Error()}
+	}
+	if !orphans.Empty() {
+		return Finding{
+			Check:    check,
+			Severity: SeverityWarning,
+			Message:  "the database contains rows or files left behind by crashes",
+			Advice:   "remove them using `ooniprobe repair`",
+		}
+	}
+	return Finding{Check: check, Severity: SeverityOK, Message: "the database is fine"}
+}
+
+// checkGeoIPData checks whether the embedded geoip data is stale.
+func (d *Doctor) checkGeoIPData() Finding {
+	const check = "geoip_data"
+	built, err := d.geoipBuildTime()
+	if err != nil {
+		return Finding{Check: check, Severity: SeverityError, Message: err.Error()}
+	}
+	message := fmt.Sprintf("the geoip data was built on %s", built.UTC().Format("2006-01-02"))
+	if d.timeNow().Sub(built) > maxGeoIPDataAge {
+		return Finding{
+			Check:    check,
+			Severity: SeverityWarning,
+			Message:  message,
+			Advice:   "update ooniprobe using `ooniprobe update`, since stale data may cause a wrong ASN or country",
+		}
+	}
+	return Finding{Check: check, Severity: SeverityOK, Message: message}
+}
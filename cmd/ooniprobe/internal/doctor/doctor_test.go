@@ -0,0 +1,170 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// fakeSession only returns the configured test helpers.
+type fakeSession struct {
+	Session
+	ths []model.OOAPIService
+}
+
+func (s *fakeSession) GetTestHelpersByName(name string) ([]model.OOAPIService, bool) {
+	return s.ths, len(s.ths) > 0
+}
+
+func (s *fakeSession) DefaultHTTPClient() *http.Client {
+	return http.DefaultClient
+}
+
+// fakeResolver returns the configured error.
+type fakeResolver struct {
+	model.Resolver
+	err error
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, domain string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return []string{"127.0.0.1"}, nil
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	server := time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC)
+	expectations := map[time.Duration]string{
+		0:                 SeverityOK,
+		-4 * time.Minute:  SeverityOK,
+		6 * time.Minute:   SeverityError,
+		-10 * time.Minute: SeverityError,
+	}
+	for skew, expected := range expectations {
+		if finding := checkClockSkew(server.Add(skew), server); finding.Severity != expected {
+			t.Fatal("unexpected finding", skew, finding)
+		}
+	}
+}
+
+func TestCheckTestHelpers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+	expectations := []struct {
+		ths      []model.OOAPIService
+		severity string
+	}{{
+		ths:      nil,
+		severity: SeverityError,
+	}, {
+		ths:      []model.OOAPIService{{Address: server.URL, Type: "https"}, {Address: "x", Type: "cloudfront"}},
+		severity: SeverityOK,
+	}, {
+		ths:      []model.OOAPIService{{Address: server.URL, Type: "https"}, {Address: closed.URL, Type: "https"}},
+		severity: SeverityWarning,
+	}, {
+		ths:      []model.OOAPIService{{Address: closed.URL, Type: "https"}},
+		severity: SeverityError,
+	}}
+	for _, expectation := range expectations {
+		d := New(Config{Session: &fakeSession{ths: expectation.ths}})
+		if finding := d.checkTestHelpers(context.Background()); finding.Severity != expectation.severity {
+			t.Fatal("unexpected finding", expectation.ths, finding)
+		}
+	}
+}
+
+func TestCheckDNS(t *testing.T) {
+	working := Resolver{Name: "working", Resolver: &fakeResolver{}}
+	broken := Resolver{Name: "broken", Resolver: &fakeResolver{err: errors.New("mocked error")}}
+	expectations := []struct {
+		resolvers []Resolver
+		severity  string
+	}{{
+		resolvers: []Resolver{working, working},
+		severity:  SeverityOK,
+	}, {
+		resolvers: []Resolver{working, broken},
+		severity:  SeverityWarning,
+	}, {
+		resolvers: []Resolver{broken},
+		severity:  SeverityError,
+	}}
+	for _, expectation := range expectations {
+		d := New(Config{Resolvers: expectation.resolvers})
+		if finding := d.checkDNS(context.Background()); finding.Severity != expectation.severity {
+			t.Fatal("unexpected finding", finding)
+		}
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	expectations := map[uint64]string{
+		10 << 20:  SeverityError,
+		100 << 20: SeverityWarning,
+		10 << 30:  SeverityOK,
+	}
+	for free, expected := range expectations {
+		free := free
+		d := New(Config{Home: "/home/ooni/.ooniprobe"})
+		d.freeDiskSpace = func(path string) (uint64, error) {
+			return free, nil
+		}
+		if finding := d.checkDiskSpace(); finding.Severity != expected {
+			t.Fatal("unexpected finding", free, finding)
+		}
+	}
+}
+
+func TestCheckDatabase(t *testing.T) {
+	expectations := []struct {
+		db       *oonitest.FakeDB
+		severity string
+	}{{
+		db:       &oonitest.FakeDB{FakeOrphans: &database.Orphans{}},
+		severity: SeverityOK,
+	}, {
+		db:       &oonitest.FakeDB{FakeOrphans: &database.Orphans{ResultIDs: []int64{1}}},
+		severity: SeverityWarning,
+	}, {
+		db:       &oonitest.FakeDB{FakeProblems: []string{"row 1 missing from index"}},
+		severity: SeverityError,
+	}}
+	for _, expectation := range expectations {
+		d := New(Config{DB: expectation.db})
+		if finding := d.checkDatabase(); finding.Severity != expectation.severity {
+			t.Fatal("unexpected finding", finding)
+		}
+	}
+}
+
+func TestCheckGeoIPData(t *testing.T) {
+	now := time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC)
+	expectations := map[time.Duration]string{
+		30 * 24 * time.Hour:  SeverityOK,
+		365 * 24 * time.Hour: SeverityWarning,
+	}
+	for age, expected := range expectations {
+		age := age
+		d := New(Config{})
+		d.timeNow = func() time.Time {
+			return now
+		}
+		d.geoipBuildTime = func() (time.Time, error) {
+			return now.Add(-age), nil
+		}
+		if finding := d.checkGeoIPData(); finding.Severity != expected {
+			t.Fatal("unexpected finding", age, finding)
+		}
+	}
+}
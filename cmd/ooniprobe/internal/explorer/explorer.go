@@ -0,0 +1,51 @@
+// Package explorer builds the OONI Explorer URLs of the measurements we
+// uploaded, which link the local results to the public dataset.
+package explorer
+
+import (
+	"errors"
+	"net/url"
+	"os/exec"
+	"runtime"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+// BaseURL is the base URL of OONI Explorer.
+const BaseURL = "https://explorer.ooni.org"
+
+// ErrNotUploaded indicates that a measurement is not in OONI
+// Explorer because we did not upload it.
+var ErrNotUploaded = errors.New("explorer: measurement not uploaded")
+
+// MeasurementURL returns the OONI Explorer URL of the measurement with
+// the given report ID and input, which is empty for tests without input.
+func MeasurementURL(reportID, input string) string {
+	URL := &url.URL{Path: "/measurement/" + reportID}
+	if input != "" {
+		URL.RawQuery = url.Values{"input": {input}}.Encode()
+	}
+	return BaseURL + URL.String()
+}
+
+// ForMeasurement returns the OONI Explorer URL of the given measurement.
+func ForMeasurement(msmt *database.MeasurementURLNetwork) (string, error) {
+	if !msmt.Measurement.IsUploaded || msmt.ReportID.String == "" {
+		return "", ErrNotUploaded
+	}
+	return MeasurementURL(msmt.ReportID.String, msmt.URL.URL.String), nil
+}
+
+// Open opens the given URL using the default browser.
+func Open(URL string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", URL)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", URL)
+	default:
+		cmd = exec.Command("xdg-open", URL)
+	}
+	return cmd.Start()
+}
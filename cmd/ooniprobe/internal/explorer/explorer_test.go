@@ -0,0 +1,39 @@
+package explorer
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+func TestMeasurementURL(t *testing.T) {
+	const reportID = "20221006T090000Z_webconnectivity_IT_30722_n1_abc"
+	expectations := map[string]string{
+		"": "https://explorer.ooni.org/measurement/" + reportID,
+		"https://www.example.com/?q=a b": "https://explorer.ooni.org/measurement/" + reportID +
+			"?input=https%3A%2F%2Fwww.example.com%2F%3Fq%3Da+b",
+	}
+	for input, expected := range expectations {
+		if URL := MeasurementURL(reportID, input); URL != expected {
+			t.Fatal("unexpected URL", URL)
+		}
+	}
+}
+
+func TestForMeasurement(t *testing.T) {
+	msmt := &database.MeasurementURLNetwork{}
+	msmt.Measurement.ReportID = sql.NullString{String: "20221006T090000Z_telegram_IT_30722_n1_abc", Valid: true}
+	if _, err := ForMeasurement(msmt); !errors.Is(err, ErrNotUploaded) {
+		t.Fatal("not the error we expected", err)
+	}
+	msmt.Measurement.IsUploaded = true
+	URL, err := ForMeasurement(msmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if URL != "https://explorer.ooni.org/measurement/20221006T090000Z_telegram_IT_30722_n1_abc" {
+		t.Fatal("unexpected URL", URL)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/apex/log"
 	"github.com/fatih/color"
 	colorable "github.com/mattn/go-colorable"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/doctor"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
 )
 
@@ -72,6 +74,36 @@ func logSectionTitle(w io.Writer, f log.Fields) error {
 	return nil
 }
 
+func logMeasureVerdict(w io.Writer, f log.Fields) error {
+	verdict := color.GreenString("%s", f.Get("verdict"))
+	if f.Get("is_anomaly").(bool) {
+		verdict = color.RedString("%s", f.Get("verdict"))
+	}
+	fmt.Fprintf(w, "  %s: %s\n", f.Get("input"), verdict)
+	fmt.Fprintf(w, "  Saved the measurement to %s\n", f.Get("path"))
+	if explorerURL, _ := f.Get("explorer_url").(string); explorerURL != "" {
+		fmt.Fprintf(w, "  %s\n", explorerURL)
+	}
+	return nil
+}
+
+func logDoctorFinding(w io.Writer, f log.Fields) error {
+	var status string
+	switch f.Get("severity") {
+	case doctor.SeverityError:
+		status = color.RedString("✗")
+	case doctor.SeverityWarning:
+		status = color.YellowString("!")
+	default:
+		status = color.GreenString("✓")
+	}
+	fmt.Fprintf(w, "  %s %s: %s\n", status, f.Get("check"), f.Get("message"))
+	if advice, _ := f.Get("advice").(string); advice != "" {
+		fmt.Fprintf(w, "      → %s\n", advice)
+	}
+	return nil
+}
+
 func logTable(w io.Writer, f log.Fields) error {
 	color := color.New(color.FgBlue)
 
@@ -83,7 +115,11 @@ func logTable(w io.Writer, f log.Fields) error {
 		if name == "type" {
 			continue
 		}
-		line := fmt.Sprintf("%s: %s", color.Sprint(name), f.Get(name))
+		value := f.Get(name)
+		if values, ok := value.([]string); ok {
+			value = strings.Join(values, ", ")
+		}
+		line := fmt.Sprintf("%s: %v", color.Sprint(name), value)
 		lineLength := utils.EscapeAwareRuneCountInString(line)
 		lines = append(lines, line)
 		if colWidth < lineLength {
@@ -128,12 +164,47 @@ func (h *Handler) TypedLog(t string, e *log.Entry) error {
 		return logMeasurementJSON(h.Writer, e.Fields)
 	case "measurement_summary":
 		return logMeasurementSummary(h.Writer, e.Fields)
+	case "measurement_page":
+		fmt.Fprintf(h.Writer, "  %s\n", e.Message)
+		if cursor, _ := e.Fields.Get("next_cursor").(string); cursor != "" {
+			fmt.Fprintf(h.Writer, "  Use --cursor %s to show the next page\n", cursor)
+		}
+		return nil
 	case "result_item":
 		return logResultItem(h.Writer, e.Fields)
 	case "result_summary":
 		return logResultSummary(h.Writer, e.Fields)
 	case "section_title":
 		return logSectionTitle(h.Writer, e.Fields)
+	case "dry_run_item":
+		fmt.Fprintf(h.Writer, "  %s\n", e.Message)
+		for _, input := range e.Fields.Get("inputs").([]string) {
+			if input != "" {
+				fmt.Fprintf(h.Writer, "    %s\n", input)
+			}
+		}
+		return nil
+	case "dry_run_summary", "upload_summary", "status_summary":
+		fmt.Fprintf(h.Writer, "  %s\n", bold.Sprint(e.Message))
+		return nil
+	case "version":
+		fmt.Fprintln(h.Writer, e.Message)
+		return nil
+	case "config_problem":
+		if e.Fields.Get("severity") == config.SeverityError {
+			fmt.Fprintf(h.Writer, "  %s\n", color.RedString(e.Message))
+		} else {
+			fmt.Fprintf(h.Writer, "  %s\n", color.YellowString(e.Message))
+		}
+		return nil
+	case "measure_verdict":
+		return logMeasureVerdict(h.Writer, e.Fields)
+	case "doctor_finding":
+		return logDoctorFinding(h.Writer, e.Fields)
+	case "stats_item", "network_history_item", "event_item", "config_value", "explorer_link",
+		"status_group":
+		fmt.Fprintf(h.Writer, "  %s\n", e.Message)
+		return nil
 	default:
 		return h.DefaultLog(e)
 	}
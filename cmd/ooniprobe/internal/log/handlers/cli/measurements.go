@@ -57,6 +57,8 @@ func logMeasurementItem(w io.Writer, f log.Fields) error {
 	isUploaded := f.Get("is_uploaded").(bool)
 	url := f.Get("url").(string)
 	urlCategoryCode := f.Get("url_category_code").(string)
+	rerunOf := f.Get("rerun_of").(int64)
+	rerunOfIsAnomaly := f.Get("rerun_of_is_anomaly").(bool)
 
 	isFirst := f.Get("is_first").(bool)
 	isLast := f.Get("is_last").(bool)
@@ -88,6 +90,12 @@ func logMeasurementItem(w io.Writer, f log.Fields) error {
 		utils.RightPad(failureStr, colWidth),
 		utils.RightPad(uploadStr, colWidth)))
 
+	if rerunOf != 0 {
+		fmt.Fprintf(w, fmt.Sprintf("│ %s %s│\n",
+			utils.RightPad(fmt.Sprintf("re-run of #%d", rerunOf), colWidth),
+			utils.RightPad(fmt.Sprintf("was ok: %s", statusIcon(!rerunOfIsAnomaly)), colWidth)))
+	}
+
 	if testKeys != "" {
 		if err := logTestKeys(w, testKeys); err != nil {
 			return err
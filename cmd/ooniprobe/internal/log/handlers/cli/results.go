@@ -108,7 +108,11 @@ func logResultItem(w io.Writer, f log.Fields) error {
 		fmt.Fprintf(w, "┢"+strings.Repeat("━", colWidth*2+2)+"┪\n")
 	}
 
-	firstRow := utils.RightPad(fmt.Sprintf("#%d - %s", rID, startTime.Format(time.RFC822)), colWidth*2)
+	header := fmt.Sprintf("#%d - %s", rID, startTime.Format(time.RFC822))
+	if isPartial, _ := f.Get("is_partial").(bool); isPartial {
+		header += " (partial)"
+	}
+	firstRow := utils.RightPad(header, colWidth*2)
 	fmt.Fprintf(w, "┃ "+firstRow+" ┃\n")
 	fmt.Fprintf(w, "┡"+strings.Repeat("━", colWidth*2+2)+"┩\n")
 
@@ -156,6 +160,9 @@ func logResultSummary(w io.Writer, f log.Fields) error {
 		utils.RightPad(fmt.Sprintf("%d nets", networks), 11),
 		utils.RightPad(fmt.Sprintf("⬆ %s  ⬇ %s", formatSize(dataUp), formatSize(dataDown)), 17))
 	fmt.Fprintf(w, " └──────────────┴─────────────┴───────────────────┘\n")
+	if pending, _ := f.Get("pending_uploads").(uint64); pending > 0 {
+		fmt.Fprintf(w, "  %d measurements pending upload\n", pending)
+	}
 
 	return nil
 }
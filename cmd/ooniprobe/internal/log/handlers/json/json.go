@@ -0,0 +1,53 @@
+package json
+
+import (
+	j "encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/apex/log"
+)
+
+// Default handler for the --json mode. We emit the typed logs, which
+// contain the output of commands (e.g., a result_item), as JSON objects
+// on the standard output, such that scripts and GUI frontends wrapping
+// the CLI can parse it. We emit all the other logs on the standard
+// error using the format of the batch handler.
+var Default = New(os.Stdout, os.Stderr)
+
+// Handler implementation.
+type Handler struct {
+	stdout *j.Encoder
+	stderr *j.Encoder
+	mu     sync.Mutex
+}
+
+// New handler.
+func New(stdout, stderr io.Writer) *Handler {
+	return &Handler{
+		stdout: j.NewEncoder(stdout),
+		stderr: j.NewEncoder(stderr),
+	}
+}
+
+// HandleLog implements log.Handler.
+func (h *Handler) HandleLog(e *log.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, _ := e.Fields.Get("type").(string)
+	switch t {
+	case "":
+		return h.stderr.Encode(e)
+	case "section_title":
+		// Section titles only make sense for humans.
+		return nil
+	default:
+		record := make(map[string]interface{}, len(e.Fields)+1)
+		record["message"] = e.Message
+		for key, value := range e.Fields {
+			record[key] = value
+		}
+		return h.stdout.Encode(record)
+	}
+}
@@ -0,0 +1,51 @@
+// Package level contains a handler filtering the logs by level, which
+// allows us to use a different level for the logs of the engine, since
+// its debug logs (e.g., each network operation) are very noisy. See
+// docs/ooniprobe-logging.md for how the -v flags map to the levels.
+package level
+
+import (
+	"github.com/apex/log"
+)
+
+// FromVerbosity returns the level of the logs and the level of the logs
+// of the engine for the given number of -v flags.
+func FromVerbosity(verbosity int) (level log.Level, engineLevel log.Level) {
+	switch {
+	case verbosity <= 0:
+		return log.InfoLevel, log.InfoLevel
+	case verbosity == 1:
+		return log.DebugLevel, log.InfoLevel
+	default:
+		return log.DebugLevel, log.DebugLevel
+	}
+}
+
+// Handler only passes to the underlying handler the logs whose level
+// is at least Level, or EngineLevel for the logs of the engine.
+type Handler struct {
+	Handler     log.Handler
+	Level       log.Level
+	EngineLevel log.Level
+}
+
+// New creates a new handler.
+func New(handler log.Handler, level, engineLevel log.Level) *Handler {
+	return &Handler{
+		Handler:     handler,
+		Level:       level,
+		EngineLevel: engineLevel,
+	}
+}
+
+// HandleLog implements log.Handler.
+func (h *Handler) HandleLog(e *log.Entry) error {
+	level := h.Level
+	if t, _ := e.Fields.Get("type").(string); t == "engine" {
+		level = h.EngineLevel
+	}
+	if e.Level < level {
+		return nil
+	}
+	return h.Handler.HandleLog(e)
+}
@@ -0,0 +1,192 @@
+// Package measure measures a single URL right away, outside of any
+// result (see `ooniprobe measure`).
+package measure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/explorer"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
+	engine "github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/webconnectivity"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Experiments contains the names of the experiments we can use,
+// the first one being the default.
+var Experiments = []string{"web_connectivity", "urlgetter"}
+
+// ErrInvalidURL indicates that the input is not an HTTP or HTTPS URL.
+var ErrInvalidURL = errors.New("measure: invalid URL")
+
+// CheckURL returns an error wrapping ErrInvalidURL unless the
+// given URL is a valid HTTP or HTTPS URL.
+func CheckURL(URL string) error {
+	parsed, err := url.Parse(URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidURL, URL)
+	}
+	return nil
+}
+
+// Verdict is the human-readable interpretation of a measurement.
+type Verdict struct {
+	// Anomaly indicates that the measurement is anomalous.
+	Anomaly bool
+
+	// Message describes the verdict (e.g., "accessible").
+	Message string
+}
+
+// blockingDescriptions describes the blocking reasons of web_connectivity.
+var blockingDescriptions = map[string]string{
+	"dns":          "DNS tampering",
+	"tcp_ip":       "TCP/IP blocking",
+	"http-failure": "the HTTP request failed",
+	"http-diff":    "the HTTP response differs from the one of the control",
+}
+
+// NewVerdict interprets the test keys of the given measurement.
+func NewVerdict(measurement *model.Measurement) Verdict {
+	switch tk := measurement.TestKeys.(type) {
+	case *webconnectivity.TestKeys:
+		if tk.BlockingReason != nil {
+			description, ok := blockingDescriptions[*tk.BlockingReason]
+			if !ok {
+				description = *tk.BlockingReason
+			}
+			return Verdict{Anomaly: true, Message: fmt.Sprintf("likely blocked: %s", description)}
+		}
+		if tk.Accessible == nil {
+			return Verdict{Message: "inconclusive: we could not compare with the control"}
+		}
+		if !*tk.Accessible {
+			return Verdict{Message: "down: the website is not accessible also from the control"}
+		}
+		return Verdict{Message: "accessible"}
+	case *urlgetter.TestKeys:
+		if tk.Failure != nil {
+			operation := "unknown"
+			if tk.FailedOperation != nil {
+				operation = *tk.FailedOperation
+			}
+			return Verdict{
+				Anomaly: true,
+				Message: fmt.Sprintf("failed: %s during %s", *tk.Failure, operation),
+			}
+		}
+		if tk.HTTPResponseStatus > 0 {
+			return Verdict{Message: fmt.Sprintf("accessible: got HTTP status %d", tk.HTTPResponseStatus)}
+		}
+		return Verdict{Message: "accessible"}
+	default:
+		return Verdict{Message: "unknown: we cannot interpret this measurement"}
+	}
+}
+
+// Config contains the settings for measuring a URL.
+type Config struct {
+	// Session is the session to measure with, which must have
+	// already looked up the location and the backends.
+	Session *engine.Session
+
+	// ExperimentName is the OPTIONAL experiment to use, which is
+	// one of Experiments and defaults to web_connectivity.
+	ExperimentName string
+
+	// Home is the OONI home in which we save the measurement.
+	Home string
+
+	// Submit indicates that we should submit the measurement.
+	Submit bool
+
+	// Annotations contains OPTIONAL annotations to add to the measurement.
+	Annotations map[string]string
+}
+
+// Report is the outcome of measuring a URL.
+type Report struct {
+	// Measurement is the measurement.
+	Measurement *model.Measurement
+
+	// Verdict is the interpretation of the measurement.
+	Verdict Verdict
+
+	// Path is the path of the file containing the measurement.
+	Path string
+
+	// ExplorerURL is the OONI Explorer URL of the measurement, which
+	// is empty unless we submitted the measurement.
+	ExplorerURL string
+}
+
+// ReportPath returns the path of the file in which we save the
+// measurement of the given experiment started at the given time.
+func ReportPath(home, experimentName string, ts time.Time) string {
+	return filepath.Join(utils.MeasureDir(home),
+		fmt.Sprintf("%s-%s.json", experimentName, ts.Format(utils.ResultTimestamp)))
+}
+
+// Run measures the given URL. When we fail to submit the measurement
+// we only warn, since we have already saved it on disk.
+func Run(ctx context.Context, config Config, URL string) (*Report, error) {
+	if err := CheckURL(URL); err != nil {
+		return nil, err
+	}
+	if config.ExperimentName == "" {
+		config.ExperimentName = Experiments[0]
+	}
+	builder, err := config.Session.NewExperimentBuilder(config.ExperimentName)
+	if err != nil {
+		return nil, err
+	}
+	builder.SetCallbacks(model.NewPrinterCallbacks(log.Log))
+	exp := builder.NewExperiment()
+	start := time.Now()
+	measurement, err := exp.MeasureWithContext(ctx, URL)
+	if err != nil {
+		return nil, err
+	}
+	measurement.AddAnnotations(config.Annotations)
+	report := &Report{
+		Measurement: measurement,
+		Verdict:     NewVerdict(measurement),
+		Path:        ReportPath(config.Home, config.ExperimentName, start),
+	}
+	if config.Submit {
+		if err := submit(ctx, exp, measurement); err != nil {
+			log.WithError(err).Warn("failed to submit the measurement")
+		} else {
+			report.ExplorerURL = explorer.MeasurementURL(measurement.ReportID, URL)
+		}
+	}
+	// We save after submitting, such that the file contains the report ID.
+	data, err := json.Marshal(measurement)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(report.Path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(report.Path, append(data, '\n'), 0600); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// submit opens a report and submits the measurement.
+func submit(ctx context.Context, exp *engine.Experiment, measurement *model.Measurement) error {
+	if err := exp.OpenReportContext(ctx); err != nil {
+		return err
+	}
+	return exp.SubmitAndUpdateMeasurementContext(ctx, measurement)
+}
@@ -0,0 +1,78 @@
+package measure
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/urlgetter"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/webconnectivity"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestCheckURL(t *testing.T) {
+	expectations := map[string]bool{
+		"https://www.example.com/": true,
+		"http://example.com":       true,
+		"ftp://example.com/":       false,
+		"www.example.com":          false,
+		"https://":                 false,
+	}
+	for URL, valid := range expectations {
+		err := CheckURL(URL)
+		if valid != (err == nil) {
+			t.Fatal("unexpected result", URL, err)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidURL) {
+			t.Fatal("unexpected error", URL, err)
+		}
+	}
+}
+
+func TestNewVerdict(t *testing.T) {
+	str := func(s string) *string { return &s }
+	boolean := func(b bool) *bool { return &b }
+	expectations := []struct {
+		testKeys interface{}
+		verdict  Verdict
+	}{{
+		testKeys: &webconnectivity.TestKeys{Summary: webconnectivity.Summary{Accessible: boolean(true)}},
+		verdict:  Verdict{Message: "accessible"},
+	}, {
+		testKeys: &webconnectivity.TestKeys{Summary: webconnectivity.Summary{
+			Accessible: boolean(false), BlockingReason: str("dns"),
+		}},
+		verdict: Verdict{Anomaly: true, Message: "likely blocked: DNS tampering"},
+	}, {
+		testKeys: &webconnectivity.TestKeys{Summary: webconnectivity.Summary{Accessible: boolean(false)}},
+		verdict:  Verdict{Message: "down: the website is not accessible also from the control"},
+	}, {
+		testKeys: &webconnectivity.TestKeys{},
+		verdict:  Verdict{Message: "inconclusive: we could not compare with the control"},
+	}, {
+		testKeys: &urlgetter.TestKeys{HTTPResponseStatus: 200},
+		verdict:  Verdict{Message: "accessible: got HTTP status 200"},
+	}, {
+		testKeys: &urlgetter.TestKeys{Failure: str("connection_reset"), FailedOperation: str("tls_handshake")},
+		verdict:  Verdict{Anomaly: true, Message: "failed: connection_reset during tls_handshake"},
+	}, {
+		testKeys: map[string]interface{}{},
+		verdict:  Verdict{Message: "unknown: we cannot interpret this measurement"},
+	}}
+	for _, expectation := range expectations {
+		verdict := NewVerdict(&model.Measurement{TestKeys: expectation.testKeys})
+		if verdict != expectation.verdict {
+			t.Fatal("unexpected verdict", verdict)
+		}
+	}
+}
+
+func TestReportPath(t *testing.T) {
+	ts := time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC)
+	path := ReportPath("/home/ooni/.ooniprobe", "web_connectivity", ts)
+	expected := filepath.Join("/home/ooni/.ooniprobe", "measure", "web_connectivity-2022-10-06T090000Z.json")
+	if path != expected {
+		t.Fatal("unexpected path", path)
+	}
+}
@@ -0,0 +1,59 @@
+package nettests
+
+import (
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	engine "github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// dryRunGroup loads the inputs of the nettests of the given group, which
+// may involve a check-in, and prints what we would measure along with an
+// estimate of the data usage, without measuring and without writing
+// results into the database.
+func dryRunGroup(config RunGroupConfig, group Group, sess *engine.Session) error {
+	// We never save this result, which only exists because the
+	// nettests expect the controller to have a result.
+	result := &database.Result{TestGroupName: config.GroupName}
+	summary := output.DryRunSummaryData{
+		GroupName:    config.GroupName,
+		Label:        group.Label,
+		EstimatedKiB: -1,
+	}
+	for i, nt := range group.Nettests {
+		if config.RunType != model.RunTypeTimed {
+			if _, background := nt.(onlyBackground); background {
+				log.Debugf("we would not run %T, since we only run it in background mode", nt)
+				continue
+			}
+		}
+		ctl := NewController(nt, config.Probe, result, sess)
+		ctl.InputFiles = config.InputFiles
+		ctl.Inputs = config.Inputs
+		ctl.RunType = config.RunType
+		ctl.Reruns = config.Reruns
+		ctl.CategoryCodes = config.CategoryCodes
+		ctl.ExcludeCategoryCodes = config.ExcludeCategoryCodes
+		ctl.MergeWithTestList = config.MergeWithTestList
+		ctl.Features = config.Features
+		ctl.DryRun = true
+		ctl.SetNettestIndex(i, len(group.Nettests))
+		if err := nt.Run(ctl); err != nil {
+			log.WithError(err).Errorf("Failed to load the inputs of %T", nt)
+			return err
+		}
+		if ctl.plannedInputs > 0 {
+			summary.ExperimentCount++
+			summary.MeasurementCount += ctl.plannedInputs
+		}
+	}
+	estimate, err := config.Probe.DB().EstimateDataUsage(config.GroupName)
+	if err != nil {
+		log.WithError(err).Warn("Failed to estimate the data usage")
+	} else if estimate.ResultCount > 0 {
+		summary.EstimatedKiB = estimate.KibiBytesPerMeasurement * float64(summary.MeasurementCount)
+	}
+	output.DryRunSummary(summary)
+	return nil
+}
@@ -0,0 +1,55 @@
+package nettests
+
+import (
+	"database/sql"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// runEventDetails contains the details of the run_started
+// and run_stopped events.
+type runEventDetails struct {
+	GroupName   string        `json:"group_name"`
+	RunType     model.RunType `json:"run_type"`
+	Resumed     bool          `json:"resumed,omitempty"`
+	Interrupted bool          `json:"interrupted,omitempty"` // run_stopped only
+	Partial     bool          `json:"partial,omitempty"`     // run_stopped only
+}
+
+// checkInEventDetails contains the details of the check_in event.
+type checkInEventDetails struct {
+	TestName string `json:"test_name"`
+	URLCount int    `json:"url_count"`
+	Failure  string `json:"failure,omitempty"`
+}
+
+// uploadBatchEventDetails contains the details of the upload_batch event.
+type uploadBatchEventDetails struct {
+	TestName string `json:"test_name"`
+	Uploaded int    `json:"uploaded"`
+	Failed   int    `json:"failed"`
+}
+
+// recordEvent records a lifecycle event. We only warn in case of
+// failure, since the timeline is not worth stopping a run.
+func recordEvent(probe *ooni.Probe, kind string, resultID int64, details interface{}) {
+	err := probe.DB().RecordEvent(kind, sql.NullInt64{Int64: resultID, Valid: true}, details)
+	if err != nil {
+		log.WithError(err).Warnf("Failed to record the %s event", kind)
+	}
+}
+
+// recordLocation records the location of the probe if it changed.
+func recordLocation(probe *ooni.Probe, network *database.Network, resultID int64) {
+	changed, err := probe.DB().RecordLocation(network, sql.NullInt64{Int64: resultID, Valid: true})
+	if err != nil {
+		log.WithError(err).Warn("Failed to record the location")
+		return
+	}
+	if changed {
+		log.Debugf("Location changed to AS%d (%s)", network.ASN, network.CountryCode)
+	}
+}
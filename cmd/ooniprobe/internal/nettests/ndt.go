@@ -11,5 +11,10 @@ func (n NDT) Run(ctl *Controller) error {
 	if err != nil {
 		return err
 	}
+	if server := ctl.Probe.Config().Nettests.Experiments.NDT.Server; server != "" {
+		if err := builder.SetOptionString("Server", server); err != nil {
+			return err
+		}
+	}
 	return ctl.Run(builder, []string{""})
 }
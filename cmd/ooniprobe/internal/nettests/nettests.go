@@ -2,18 +2,18 @@ package nettests
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/apex/log"
-	"github.com/fatih/color"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/atomicx"
 	engine "github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/pkg/errors"
-	"github.com/upper/db/v4"
 )
 
 // Nettest interface. Every Nettest should implement this.
@@ -58,11 +58,55 @@ type Controller struct {
 	// not set, the underlying code defaults to model.RunTypeTimed.
 	RunType model.RunType
 
+	// Annotations contains OPTIONAL annotations to add to each
+	// measurement, which we also save into the database.
+	Annotations map[string]string
+
+	// CompletedTests contains the names of the tests to skip because
+	// the result we are resuming already contains them.
+	CompletedTests map[string]bool
+
+	// Reruns OPTIONALLY contains the measurements we are re-running, in
+	// which case we only measure their tests and inputs and we link the
+	// new measurements to them.
+	Reruns []Rerun
+
+	// reruns indexes Reruns while we run.
+	reruns rerunIndex
+
+	// DryRun indicates that we should not measure (see dryRunGroup).
+	DryRun bool
+
+	// CategoryCodes and ExcludeCategoryCodes OPTIONALLY select the
+	// URLs from the check-in API by category code.
+	CategoryCodes        []string
+	ExcludeCategoryCodes []string
+
+	// MergeWithTestList indicates that we should test the URLs of
+	// the test list in addition to Inputs and InputFiles.
+	MergeWithTestList bool
+
+	// Deadline is the OPTIONAL time after which we stop measuring
+	// and mark the result as partial (see RunGroupConfig.MaxRuntime).
+	Deadline time.Time
+
+	// Parallelism is the OPTIONAL number of inputs to measure
+	// concurrently, which defaults to one.
+	Parallelism int
+
+	// Features OPTIONALLY contains the feature flags returned by
+	// the check-in API (see RunGroupConfig.Features).
+	Features map[string]bool
+
+	// plannedInputs is the number of inputs we would measure in DryRun mode.
+	plannedInputs int
+
 	// numInputs is the total number of inputs
 	numInputs int
 
-	// curInputIdx is the current input index
-	curInputIdx int
+	// curInputIdx is the index of the current input, i.e., the
+	// number of inputs we have already measured
+	curInputIdx atomicx.Int64
 }
 
 // BuildAndSetInputIdxMap takes in input a list of URLs in the format
@@ -78,7 +122,7 @@ type Controller struct {
 //
 // Arguments:
 //
-// - sess is the database in which to register the URL;
+// - db is the database in which to register the URL;
 //
 // - testlist is the result from the check-in API (or possibly
 // a manually constructed list when applicable, e.g., for dnscheck
@@ -90,13 +134,18 @@ type Controller struct {
 //
 // - on failure, an error.
 func (c *Controller) BuildAndSetInputIdxMap(
-	sess db.Session, testlist []model.OOAPIURLInfo) ([]string, error) {
+	db database.Actions, testlist []model.OOAPIURLInfo) ([]string, error) {
 	var urls []string
 	urlIDMap := make(map[int64]int64)
 	for idx, url := range testlist {
+		if c.DryRun {
+			// We don't need the URL IDs, since we are not measuring.
+			urls = append(urls, url.URL)
+			continue
+		}
 		log.Debugf("Going over URL %d", idx)
-		urlID, err := database.CreateOrUpdateURL(
-			sess, url.URL, url.CategoryCode, url.CountryCode,
+		urlID, err := db.CreateOrUpdateURL(
+			url.URL, url.CategoryCode, url.CountryCode,
 		)
 		if err != nil {
 			log.Error("failed to add to the URL table")
@@ -128,10 +177,36 @@ func (c *Controller) Run(builder *engine.ExperimentBuilder, inputs []string) err
 	builder.SetCallbacks(model.ExperimentCallbacks(c))
 	c.numInputs = len(inputs)
 	exp := builder.NewExperiment()
-	defer func() {
-		c.res.DataUsageDown += exp.KibiBytesReceived()
-		c.res.DataUsageUp += exp.KibiBytesSent()
-	}()
+	if !c.Probe.Config().Nettests.Experiments.IsEnabled(exp.Name()) {
+		log.Infof("Skipping %s: disabled in the config file", exp.Name())
+		return nil
+	}
+	if c.CompletedTests[exp.Name()] {
+		log.Infof("Skipping %s: the resumed result already contains it", exp.Name())
+		return nil
+	}
+	if c.Features != nil && !isEnabledByBackend(c.Features, exp.Name()) {
+		log.Infof("Skipping %s: disabled by the check-in API", exp.Name())
+		return nil
+	}
+	if c.reruns = newRerunIndex(c.Reruns); c.reruns != nil {
+		if !c.reruns.hasTest(exp.Name()) {
+			log.Debugf("Skipping %s: we are not re-running it", exp.Name())
+			return nil
+		}
+		inputs = c.selectRerunInputs(exp.Name(), inputs)
+		c.numInputs = len(inputs)
+		if len(inputs) <= 0 {
+			log.Infof("Skipping %s: no inputs to re-run", exp.Name())
+			return nil
+		}
+	}
+	if c.DryRun {
+		c.plannedInputs = len(inputs)
+		output.DryRunItem(exp.Name(), inputs, c.maxRuntime())
+		return nil
+	}
+	output.ExperimentStarted(exp.Name(), c.ntIndex, c.ntCount, len(inputs))
 
 	c.msmts = make(map[int64]*database.Measurement)
 
@@ -139,128 +214,274 @@ func (c *Controller) Run(builder *engine.ExperimentBuilder, inputs []string) err
 	var reportID sql.NullString
 	resultID := c.res.ID
 
-	log.Debug(color.RedString("status.queued"))
-	log.Debug(color.RedString("status.started"))
+	logger := c.logger(exp.Name())
+	logger.Debug("status.queued")
+	logger.Debug("status.started")
 
 	if c.Probe.Config().Sharing.UploadResults {
 		if err := exp.OpenReport(); err != nil {
-			log.Debugf(
-				"%s: %s", color.RedString("failure.report_create"), err.Error(),
-			)
+			logger.WithError(err).Debug("failure.report_create")
 		} else {
-			log.Debugf(color.RedString("status.report_create"))
 			reportID = sql.NullString{String: exp.ReportID(), Valid: true}
+			logger.WithField("report_id", reportID.String).Debug("status.report_create")
 		}
 	}
 
-	maxRuntime := time.Duration(c.Probe.Config().Nettests.WebsitesMaxRuntime) * time.Second
-	if c.RunType == model.RunTypeTimed && maxRuntime > 0 {
-		log.Debug("disabling maxRuntime when running in the background")
-		maxRuntime = 0
+	maxRuntime := c.maxRuntime()
+	start := time.Now()
+	c.ntStartTime = start
+	// Because an experiment is not safe for concurrent use, each
+	// worker measures using its own experiment. We submit all the
+	// measurements using the report opened by the first experiment.
+	exps := []*engine.Experiment{exp}
+	for len(exps) < c.Parallelism && len(exps) < len(inputs) {
+		exps = append(exps, builder.NewExperiment())
 	}
-	_, isWebConnectivity := c.nt.(WebConnectivity)
-	if !isWebConnectivity {
-		log.Debug("disabling maxRuntime without Web Connectivity")
-		maxRuntime = 0
+	batch := uploadBatchEventDetails{TestName: exp.Name()}
+	runner := &inputRunner{
+		numInputs:   len(inputs),
+		parallelism: len(exps),
+		shouldStop: func(idx int) bool {
+			return c.shouldStop(start, maxRuntime, idx, len(inputs))
+		},
+		create: func(idx int) (*inputMeasurement, error) {
+			return c.createMeasurement(exp, reportID, idx, inputs[idx])
+		},
+		measure: func(worker int, im *inputMeasurement) {
+			wexp := exps[worker]
+			// A panicking experiment fails the measurement with a
+			// crash dump rather than crashing the whole run.
+			im.err = c.Probe.CrashReporter().Guard(wexp.Name(), func() (err error) {
+				im.measurement, err = wexp.Measure(im.input)
+				return
+			})
+			if im.measurement != nil {
+				im.measurement.ReportID = exp.ReportID()
+			}
+		},
+		save: func(im *inputMeasurement) error {
+			if err := c.saveMeasurement(exp, im, &batch); err != nil {
+				return err
+			}
+			c.curInputIdx.Add(1) // allow for precise progress
+			return nil
+		},
 	}
-	if len(c.Inputs) > 0 || len(c.InputFiles) > 0 {
-		log.Debug("disabling maxRuntime with user-provided input")
-		maxRuntime = 0
+	if err := runner.run(); err != nil {
+		return err
+	}
+	if batch.Uploaded+batch.Failed > 0 {
+		recordEvent(c.Probe, database.EventUploadBatch, resultID, batch)
+	}
+	c.Probe.DB().UpdateUploadedStatus(c.res)
+	logger.WithFields(log.Fields{
+		"uploaded": batch.Uploaded,
+		"failed":   batch.Failed,
+	}).Debug("status.end")
+	return nil
+}
+
+// logger returns a logger whose entries carry the name of the given nettest
+// and the ID of the result, which --log-format json emits as fields.
+func (c *Controller) logger(testName string) log.Interface {
+	return log.WithFields(log.Fields{
+		"nettest":   testName,
+		"result_id": c.res.ID,
+	})
+}
+
+// selectRerunInputs returns the inputs of the given test we are re-running,
+// which matters because all the tests of a group share the inputs, and
+// updates the mapping between the inputs and their URL IDs accordingly.
+func (c *Controller) selectRerunInputs(testName string, inputs []string) []string {
+	var selected []string
+	var inputIdxMap map[int64]int64
+	if c.inputIdxMap != nil {
+		inputIdxMap = make(map[int64]int64)
 	}
-	start := time.Now()
-	c.ntStartTime = start
 	for idx, input := range inputs {
-		if c.Probe.IsTerminated() {
-			log.Info("user requested us to terminate using Ctrl-C")
-			break
-		}
-		if maxRuntime > 0 && time.Since(start) > maxRuntime {
-			log.Info("exceeded maximum runtime")
-			break
+		if _, found := c.reruns.originalID(testName, input); !found {
+			continue
 		}
-		c.curInputIdx = idx // allow for precise progress
-		idx64 := int64(idx)
-		log.Debug(color.RedString("status.measurement_start"))
-		var urlID sql.NullInt64
-		if c.inputIdxMap != nil {
-			urlID = sql.NullInt64{Int64: c.inputIdxMap[idx64], Valid: true}
+		if inputIdxMap != nil {
+			inputIdxMap[int64(len(selected))] = c.inputIdxMap[int64(idx)]
 		}
+		selected = append(selected, input)
+	}
+	c.inputIdxMap = inputIdxMap
+	return selected
+}
 
-		msmt, err := database.CreateMeasurement(
-			c.Probe.DB(), reportID, exp.Name(), c.res.MeasurementDir, idx, resultID, urlID,
-		)
-		if err != nil {
-			return errors.Wrap(err, "failed to create measurement")
-		}
-		c.msmts[idx64] = msmt
+// inputMeasurement is the measurement of an input in progress.
+type inputMeasurement struct {
+	idx         int
+	input       string
+	msmt        *database.Measurement
+	measurement *model.Measurement
+	err         error
+}
 
-		if input != "" {
-			c.OnProgress(0, fmt.Sprintf("processing input: %s", input))
-		}
-		measurement, err := exp.Measure(input)
-		if err != nil {
-			log.WithError(err).Debug(color.RedString("failure.measurement"))
-			if err := c.msmts[idx64].Failed(c.Probe.DB(), err.Error()); err != nil {
-				return errors.Wrap(err, "failed to mark measurement as failed")
-			}
-			// Since https://github.com/ooni/probe-cli/pull/527, the Measure
-			// function returns EITHER a valid measurement OR an error. Before
-			// that, instead, the measurement was valid EVEN in case of an
-			// error, which is quite not the <value> OR <error> semantics that
-			// is so typical and widespread in the Go ecosystem. So, we must
-			// jump to the next iteration of the loop here rather than falling
-			// through and attempting to do something with the measurement.
-			continue
-		}
+// shouldStop returns whether we should stop measuring before
+// measuring the input with the given index.
+func (c *Controller) shouldStop(start time.Time, maxRuntime time.Duration, idx, numInputs int) bool {
+	if c.Probe.IsTerminated() {
+		log.Info("user requested us to terminate using Ctrl-C")
+		return true
+	}
+	if maxRuntime > 0 && time.Since(start) > maxRuntime {
+		log.Info("exceeded maximum runtime")
+		return true
+	}
+	if !c.Deadline.IsZero() && time.Now().After(c.Deadline) {
+		log.Infof("reached the maximum runtime after measuring %d of %d inputs", idx, numInputs)
+		c.res.IsPartial = true
+		return true
+	}
+	return false
+}
 
-		saveToDisk := true
-		if c.Probe.Config().Sharing.UploadResults {
-			// Implementation note: SubmitMeasurement will fail here if we did fail
-			// to open the report but we still want to continue. There will be a
-			// bit of a spew in the logs, perhaps, but stopping seems less efficient.
-			if err := exp.SubmitAndUpdateMeasurement(measurement); err != nil {
-				log.Debug(color.RedString("failure.measurement_submission"))
-				if err := c.msmts[idx64].UploadFailed(c.Probe.DB(), err.Error()); err != nil {
-					return errors.Wrap(err, "failed to mark upload as failed")
-				}
-			} else if err := c.msmts[idx64].UploadSucceeded(c.Probe.DB()); err != nil {
-				return errors.Wrap(err, "failed to mark upload as succeeded")
-			} else {
-				// Everything went OK, don't save to disk
-				saveToDisk = false
-			}
-		}
-		// We only save the measurement to disk if we failed to upload the measurement
-		if saveToDisk {
-			if err := exp.SaveMeasurement(measurement, msmt.MeasurementFilePath.String); err != nil {
-				return errors.Wrap(err, "failed to save measurement on disk")
-			}
+// createMeasurement creates the database measurement of the input with
+// the given index, which we are about to measure.
+func (c *Controller) createMeasurement(
+	exp *engine.Experiment, reportID sql.NullString, idx int, input string) (*inputMeasurement, error) {
+	idx64 := int64(idx)
+	c.logger(exp.Name()).WithField("idx", idx).Debug("status.measurement_start")
+	var urlID sql.NullInt64
+	if c.inputIdxMap != nil {
+		urlID = sql.NullInt64{Int64: c.inputIdxMap[idx64], Valid: true}
+	}
+	msmt, err := c.Probe.DB().CreateMeasurement(
+		reportID, exp.Name(), c.res.MeasurementDir, idx, c.res.ID, urlID,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create measurement")
+	}
+	c.msmts[idx64] = msmt
+	if originalID, found := c.reruns.originalID(exp.Name(), input); found {
+		if err := c.Probe.DB().SetMeasurementRerunOf(msmt, originalID); err != nil {
+			return nil, errors.Wrap(err, "failed to link re-run measurement")
 		}
+	}
+	if input != "" {
+		c.OnProgress(0, fmt.Sprintf("processing input: %s", input))
+	}
+	return &inputMeasurement{idx: idx, input: input, msmt: msmt}, nil
+}
 
-		if err := c.msmts[idx64].Done(c.Probe.DB()); err != nil {
-			return errors.Wrap(err, "failed to mark measurement as done")
+// saveMeasurement submits the given measurement, when we should upload
+// it, and saves it, counting the uploads into batch.
+func (c *Controller) saveMeasurement(
+	exp *engine.Experiment, im *inputMeasurement, batch *uploadBatchEventDetails) error {
+	idx, input, msmt, measurement := im.idx, im.input, im.msmt, im.measurement
+	logger := c.logger(exp.Name()).WithFields(log.Fields{
+		"idx":            idx,
+		"measurement_id": msmt.ID,
+	})
+	if err := im.err; err != nil {
+		logger.WithError(err).Debug("failure.measurement")
+		output.InputMeasured(exp.Name(), idx, input, msmt.ID, err.Error())
+		if err := c.Probe.DB().MeasurementFailed(msmt, err.Error()); err != nil {
+			return errors.Wrap(err, "failed to mark measurement as failed")
 		}
+		// Since https://github.com/ooni/probe-cli/pull/527, the Measure
+		// function returns EITHER a valid measurement OR an error. Before
+		// that, instead, the measurement was valid EVEN in case of an
+		// error, which is quite not the <value> OR <error> semantics that
+		// is so typical and widespread in the Go ecosystem. So, we must
+		// return here rather than falling through and attempting to do
+		// something with the measurement.
+		return nil
+	}
 
-		// We're not sure whether it's enough to log the error or we should
-		// instead also mark the measurement as failed. Strictly speaking this
-		// is an inconsistency between the code that generate the measurement
-		// and the code that process the measurement. We do have some data
-		// but we're not gonna have a summary. To be reconsidered.
-		tk, err := exp.GetSummaryKeys(measurement)
-		if err != nil {
-			log.WithError(err).Error("failed to obtain testKeys")
-			continue
+	measurement.AddAnnotations(c.Annotations)
+
+	saveToDisk := true
+	if c.Probe.Config().Sharing.UploadResults {
+		// Implementation note: SubmitMeasurement will fail here if we did fail
+		// to open the report but we still want to continue. There will be a
+		// bit of a spew in the logs, perhaps, but stopping seems less efficient.
+		if err := exp.SubmitAndUpdateMeasurement(measurement); err != nil {
+			logger.WithError(err).Debug("failure.measurement_submission")
+			output.UploadStatus(exp.Name(), idx, msmt.ID, err.Error())
+			batch.Failed++
+			if err := c.Probe.DB().MeasurementUploadFailed(msmt, err.Error()); err != nil {
+				return errors.Wrap(err, "failed to mark upload as failed")
+			}
+		} else if err := c.Probe.DB().MeasurementUploadSucceeded(msmt, exp.CollectorAddress()); err != nil {
+			return errors.Wrap(err, "failed to mark upload as succeeded")
+		} else {
+			// Everything went OK, don't save to disk
+			saveToDisk = false
+			output.UploadStatus(exp.Name(), idx, msmt.ID, "")
+			batch.Uploaded++
 		}
-		log.Debugf("Fetching: %d %v", idx, c.msmts[idx64])
-		if err := database.AddTestKeys(c.Probe.DB(), c.msmts[idx64], tk); err != nil {
-			return errors.Wrap(err, "failed to add test keys to summary")
+	}
+	// We only save the measurement to disk if we failed to upload the measurement
+	var data []byte
+	if saveToDisk {
+		var err error
+		if data, err = marshalMeasurement(measurement); err != nil {
+			return errors.Wrap(err, "failed to serialize measurement")
 		}
 	}
-	database.UpdateUploadedStatus(c.Probe.DB(), c.res)
-	log.Debugf("status.end")
+
+	// We're not sure whether it's enough to log the error or we should
+	// instead also mark the measurement as failed. Strictly speaking this
+	// is an inconsistency between the code that generate the measurement
+	// and the code that process the measurement. We do have some data
+	// but we're not gonna have a summary. To be reconsidered.
+	tk, err := exp.GetSummaryKeys(measurement)
+	if err != nil {
+		logger.WithError(err).Error("failed to obtain testKeys")
+		tk = nil
+	}
+	logger.Debug("status.measurement_done")
+	err = c.Probe.DB().CompleteMeasurement(msmt, data, tk, measurement.Annotations)
+	if err != nil {
+		return errors.Wrap(err, "failed to complete measurement")
+	}
+	output.InputMeasured(exp.Name(), idx, input, msmt.ID, "")
 	return nil
 }
 
+// maxRuntime returns the maximum runtime of the nettest, which is zero
+// when there is no maximum runtime.
+func (c *Controller) maxRuntime() time.Duration {
+	maxRuntime := time.Duration(c.Probe.Config().Nettests.WebsitesMaxRuntime) * time.Second
+	if c.RunType == model.RunTypeTimed && maxRuntime > 0 {
+		log.Debug("disabling maxRuntime when running in the background")
+		maxRuntime = 0
+	}
+	_, isWebConnectivity := c.nt.(WebConnectivity)
+	if !isWebConnectivity {
+		log.Debug("disabling maxRuntime without Web Connectivity")
+		maxRuntime = 0
+	}
+	if len(c.Inputs) > 0 || len(c.InputFiles) > 0 {
+		log.Debug("disabling maxRuntime with user-provided input")
+		maxRuntime = 0
+	}
+	return maxRuntime
+}
+
+// marshalMeasurement serializes the measurement for saving it into
+// its measurement file, using the format of Experiment.SaveMeasurement.
+func marshalMeasurement(measurement *model.Measurement) ([]byte, error) {
+	data, err := json.Marshal(measurement)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// logProgress logs a progress event emitted by the experiment.
+func (c *Controller) logProgress(perc float64, msg string) {
+	log.WithFields(log.Fields{
+		"percentage": perc,
+		"message":    msg,
+	}).Debug("status.progress")
+}
+
 // OnProgress should be called when a new progress event is available.
 func (c *Controller) OnProgress(perc float64, msg string) {
 	// when we have maxRuntime, honor it
@@ -271,22 +492,23 @@ func (c *Controller) OnProgress(perc float64, msg string) {
 		elapsed := time.Since(c.ntStartTime)
 		perc = float64(elapsed) / float64(maxRuntime)
 		eta := maxRuntime.Seconds() - elapsed.Seconds()
-		log.Debugf("OnProgress: %f - %s", perc, msg)
+		c.logProgress(perc, msg)
 		key := fmt.Sprintf("%T", c.nt)
 		output.Progress(key, perc, eta, msg)
 		return
 	}
 	// otherwise estimate the ETA
-	log.Debugf("OnProgress: %f - %s", perc, msg)
+	c.logProgress(perc, msg)
 	var eta float64
 	eta = -1.0
 	if c.numInputs > 1 {
 		// make the percentage relative to the current input over all inputs
-		floor := (float64(c.curInputIdx) / float64(c.numInputs))
+		curInputIdx := int(c.curInputIdx.Load())
+		floor := (float64(curInputIdx) / float64(c.numInputs))
 		step := 1.0 / float64(c.numInputs)
 		perc = floor + perc*step
-		if c.curInputIdx > 0 {
-			eta = (time.Since(c.ntStartTime).Seconds() / float64(c.curInputIdx)) * float64(c.numInputs-c.curInputIdx)
+		if curInputIdx > 0 {
+			eta = (time.Since(c.ntStartTime).Seconds() / float64(curInputIdx)) * float64(c.numInputs-curInputIdx)
 		}
 	}
 	if c.ntCount > 0 {
@@ -2,10 +2,12 @@ package nettests
 
 import (
 	"context"
+	"database/sql"
 	"io/ioutil"
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
@@ -50,11 +52,11 @@ func TestRun(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	network, err := database.CreateNetwork(probe.DB(), sess)
+	network, err := probe.DB().CreateNetwork(sess)
 	if err != nil {
 		t.Fatal(err)
 	}
-	res, err := database.CreateResult(probe.DB(), probe.Home(), "middlebox", network.ID)
+	res, err := probe.DB().CreateResult(probe.Home(), "middlebox", network.ID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -62,3 +64,84 @@ func TestRun(t *testing.T) {
 	ctl := NewController(nt, probe, res, sess)
 	nt.Run(ctl)
 }
+
+func TestGroupDeadline(t *testing.T) {
+	probe := newOONIProbe(t)
+	start := time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC)
+	if deadline := groupDeadline(RunGroupConfig{Probe: probe}, start); !deadline.IsZero() {
+		t.Fatal("expected no deadline", deadline)
+	}
+	probe.Config().Nettests.MaxRuntime = 60
+	if deadline := groupDeadline(RunGroupConfig{Probe: probe}, start); !deadline.Equal(start.Add(time.Minute)) {
+		t.Fatal("unexpected deadline", deadline)
+	}
+	config := RunGroupConfig{Probe: probe, MaxRuntime: 10 * time.Second}
+	if deadline := groupDeadline(config, start); !deadline.Equal(start.Add(10 * time.Second)) {
+		t.Fatal("expected the flag to override the config", deadline)
+	}
+}
+
+func TestIsEnabledByBackend(t *testing.T) {
+	features := map[string]bool{"torsf_enabled": true, "dnscheck_enabled": false}
+	expectations := map[string]bool{
+		"torsf":            true,
+		"vanilla_tor":      false,
+		"dnscheck":         false,
+		"web_connectivity": true,
+	}
+	for name, expected := range expectations {
+		if enabled := isEnabledByBackend(features, name); enabled != expected {
+			t.Fatal("unexpected result for", name, enabled)
+		}
+	}
+}
+
+func TestReruns(t *testing.T) {
+	newMeasurement := func(id int64, groupName, testName, URL string) database.MeasurementURLNetwork {
+		var msmt database.MeasurementURLNetwork
+		msmt.Measurement.ID = id
+		msmt.Measurement.TestName = testName
+		msmt.Result.TestGroupName = groupName
+		msmt.URL.URL = sql.NullString{String: URL, Valid: URL != ""}
+		return msmt
+	}
+	byGroup := RerunsByGroup([]database.MeasurementURLNetwork{
+		newMeasurement(1, "experimental", "dnscheck", "dot://1.1.1.1"),
+		newMeasurement(2, "experimental", "stunreachability", "stun://stun.l.google.com:19302"),
+		newMeasurement(3, "im", "telegram", ""),
+		newMeasurement(4, "experimental", "dnscheck", "dot://1.1.1.1"),
+		newMeasurement(5, "im", "telegram", ""),
+	})
+	if len(byGroup) != 2 || len(byGroup["experimental"]) != 3 || len(byGroup["im"]) != 2 {
+		t.Fatal("unexpected reruns", byGroup)
+	}
+	inputs := rerunInputs(byGroup["experimental"])
+	if len(inputs) != 2 || inputs[0] != "dot://1.1.1.1" || inputs[1] != "stun://stun.l.google.com:19302" {
+		t.Fatal("unexpected inputs", inputs)
+	}
+
+	ctl := &Controller{
+		reruns:      newRerunIndex(byGroup["experimental"]),
+		inputIdxMap: map[int64]int64{0: 10, 1: 11},
+	}
+	if !ctl.reruns.hasTest("dnscheck") || ctl.reruns.hasTest("telegram") {
+		t.Fatal("unexpected tests")
+	}
+	selected := ctl.selectRerunInputs("stunreachability", inputs)
+	if len(selected) != 1 || selected[0] != inputs[1] || ctl.inputIdxMap[0] != 11 {
+		t.Fatal("unexpected selection", selected, ctl.inputIdxMap)
+	}
+	if id, found := ctl.reruns.originalID("dnscheck", "dot://1.1.1.1"); !found || id != 4 {
+		t.Fatal("expected to link to the last original", id)
+	}
+	imReruns := newRerunIndex(byGroup["im"])
+	if id, found := imReruns.originalID("telegram", ""); !found || id != 5 {
+		t.Fatal("unexpected original", id)
+	}
+	if newRerunIndex(nil) != nil {
+		t.Fatal("expected a nil index without reruns")
+	}
+	if _, found := rerunIndex(nil).originalID("telegram", ""); found {
+		t.Fatal("expected no original without reruns")
+	}
+}
@@ -0,0 +1,85 @@
+package nettests
+
+// inputRunner measures the inputs of a nettest using a pool of workers
+// and saves the measurements in the same order as the inputs. We only
+// measure in the background goroutines and we otherwise create and save
+// the measurements in the goroutine calling run, such that we never
+// access the database concurrently. Each worker has its own index, such
+// that the caller can give each worker its own experiment.
+type inputRunner struct {
+	// numInputs is the number of inputs to measure.
+	numInputs int
+
+	// parallelism is the number of workers, which defaults to one.
+	parallelism int
+
+	// shouldStop returns whether we should stop before measuring
+	// the input with the given index (e.g., after Ctrl-C).
+	shouldStop func(idx int) bool
+
+	// create creates the measurement of the input with the given index.
+	create func(idx int) (*inputMeasurement, error)
+
+	// measure measures the given input using the given worker.
+	measure func(worker int, im *inputMeasurement)
+
+	// save saves the given measurement.
+	save func(im *inputMeasurement) error
+}
+
+// inputRunnerResult is the result of measuring an input.
+type inputRunnerResult struct {
+	worker int
+	im     *inputMeasurement
+}
+
+// run measures the inputs. When shouldStop returns true, we stop
+// measuring new inputs but we save the ones we are measuring.
+func (r *inputRunner) run() error {
+	parallelism := r.parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	var idle []int
+	for worker := parallelism - 1; worker >= 0; worker-- {
+		idle = append(idle, worker)
+	}
+	measured := make(chan *inputRunnerResult, parallelism)
+	// We keep the measurements we cannot save yet because we are still
+	// measuring a previous input and we bound their number, such that a
+	// slow input does not cause us to accumulate measurements.
+	pending := make(map[int]*inputMeasurement)
+	var next, saved int
+	stopped := false
+	for {
+		if !stopped && next < r.numInputs && len(idle) > 0 && len(pending) < parallelism {
+			if stopped = r.shouldStop(next); !stopped {
+				im, err := r.create(next)
+				if err != nil {
+					return err
+				}
+				worker := idle[len(idle)-1]
+				idle = idle[:len(idle)-1]
+				go func() {
+					r.measure(worker, im)
+					measured <- &inputRunnerResult{worker: worker, im: im}
+				}()
+				next++
+				continue
+			}
+		}
+		if saved >= next {
+			return nil
+		}
+		result := <-measured
+		idle = append(idle, result.worker)
+		pending[result.im.idx] = result.im
+		for im, found := pending[saved]; found; im, found = pending[saved] {
+			delete(pending, saved)
+			if err := r.save(im); err != nil {
+				return err
+			}
+			saved++
+		}
+	}
+}
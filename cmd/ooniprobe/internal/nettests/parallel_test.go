@@ -0,0 +1,109 @@
+package nettests
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestInputRunner returns an inputRunner whose measure function takes
+// longer for the first inputs, such that they complete out of order, and
+// fails if two inputs concurrently use the same worker.
+func newTestInputRunner(t *testing.T, numInputs, parallelism int) (*inputRunner, *[]int) {
+	var (
+		busy  = make(map[int]bool)
+		mu    sync.Mutex
+		saved []int
+	)
+	runner := &inputRunner{
+		numInputs:   numInputs,
+		parallelism: parallelism,
+		shouldStop: func(idx int) bool {
+			return false
+		},
+		create: func(idx int) (*inputMeasurement, error) {
+			return &inputMeasurement{idx: idx}, nil
+		},
+		measure: func(worker int, im *inputMeasurement) {
+			mu.Lock()
+			if busy[worker] {
+				t.Error("worker used concurrently", worker)
+			}
+			busy[worker] = true
+			mu.Unlock()
+			time.Sleep(time.Duration(numInputs-im.idx) * time.Millisecond)
+			mu.Lock()
+			busy[worker] = false
+			mu.Unlock()
+		},
+		save: func(im *inputMeasurement) error {
+			saved = append(saved, im.idx)
+			return nil
+		},
+	}
+	return runner, &saved
+}
+
+func TestInputRunner(t *testing.T) {
+	t.Run("saves the measurements in order", func(t *testing.T) {
+		for _, parallelism := range []int{0, 1, 3, 8} {
+			runner, saved := newTestInputRunner(t, 20, parallelism)
+			if err := runner.run(); err != nil {
+				t.Fatal(err)
+			}
+			if len(*saved) != 20 {
+				t.Fatal("unexpected number of saved measurements", len(*saved))
+			}
+			for idx, value := range *saved {
+				if idx != value {
+					t.Fatal("unexpected order", *saved)
+				}
+			}
+		}
+	})
+
+	t.Run("stops measuring but saves the running measurements", func(t *testing.T) {
+		runner, saved := newTestInputRunner(t, 20, 4)
+		var created int
+		runner.shouldStop = func(idx int) bool {
+			return idx >= 6
+		}
+		create := runner.create
+		runner.create = func(idx int) (*inputMeasurement, error) {
+			created++
+			return create(idx)
+		}
+		if err := runner.run(); err != nil {
+			t.Fatal(err)
+		}
+		if created != 6 || len(*saved) != 6 {
+			t.Fatal("unexpected counters", created, len(*saved))
+		}
+	})
+
+	t.Run("with a create error", func(t *testing.T) {
+		runner, _ := newTestInputRunner(t, 20, 4)
+		expected := errors.New("mocked error")
+		runner.create = func(idx int) (*inputMeasurement, error) {
+			if idx == 5 {
+				return nil, expected
+			}
+			return &inputMeasurement{idx: idx}, nil
+		}
+		if err := runner.run(); !errors.Is(err, expected) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with a save error", func(t *testing.T) {
+		runner, _ := newTestInputRunner(t, 20, 4)
+		expected := errors.New("mocked error")
+		runner.save = func(im *inputMeasurement) error {
+			return expected
+		}
+		if err := runner.run(); !errors.Is(err, expected) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package nettests
+
+import (
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+// Rerun is a measurement to re-run.
+type Rerun struct {
+	// MeasurementID is the ID of the original measurement.
+	MeasurementID int64
+
+	// TestName is the name of the test of the original measurement.
+	TestName string
+
+	// Input is the input of the original measurement, which is
+	// empty for the tests without input.
+	Input string
+}
+
+// RerunsByGroup returns the reruns of the given measurements
+// indexed by the test group of their result.
+func RerunsByGroup(measurements []database.MeasurementURLNetwork) map[string][]Rerun {
+	out := make(map[string][]Rerun)
+	for _, msmt := range measurements {
+		rerun := Rerun{MeasurementID: msmt.Measurement.ID, TestName: msmt.Measurement.TestName}
+		if msmt.URL.URL.Valid {
+			rerun.Input = msmt.URL.URL.String
+		}
+		groupName := msmt.Result.TestGroupName
+		out[groupName] = append(out[groupName], rerun)
+	}
+	return out
+}
+
+// rerunInputs returns the inputs of the given reruns without
+// duplicates, which we use as the inputs of the group.
+func rerunInputs(reruns []Rerun) []string {
+	var inputs []string
+	seen := make(map[string]bool)
+	for _, rerun := range reruns {
+		if rerun.Input == "" || seen[rerun.Input] {
+			continue
+		}
+		seen[rerun.Input] = true
+		inputs = append(inputs, rerun.Input)
+	}
+	return inputs
+}
+
+// rerunKey identifies the measurement of a test with an input.
+type rerunKey struct {
+	testName string
+	input    string
+}
+
+// rerunIndex maps the test name and the input of the measurements
+// we are re-running to their IDs. When we re-run several measurements
+// with the same key, e.g., of a test without input, we link the new
+// measurement to the last one.
+type rerunIndex map[rerunKey]int64
+
+// newRerunIndex creates a new rerunIndex, returning nil when
+// we are not re-running any measurement.
+func newRerunIndex(reruns []Rerun) rerunIndex {
+	if len(reruns) <= 0 {
+		return nil
+	}
+	index := make(rerunIndex)
+	for _, rerun := range reruns {
+		index[rerunKey{rerun.TestName, rerun.Input}] = rerun.MeasurementID
+	}
+	return index
+}
+
+// hasTest returns whether we are re-running the given test.
+func (ri rerunIndex) hasTest(testName string) bool {
+	for key := range ri {
+		if key.testName == testName {
+			return true
+		}
+	}
+	return false
+}
+
+// originalID returns the ID of the measurement we are re-running
+// with the given test name and input, if any.
+func (ri rerunIndex) originalID(testName, input string) (int64, bool) {
+	id, found := ri[rerunKey{testName, input}]
+	return id, found
+}
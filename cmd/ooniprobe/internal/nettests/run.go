@@ -2,13 +2,16 @@ package nettests
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/notify"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	engine "github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/pkg/errors"
 )
@@ -20,8 +23,53 @@ type RunGroupConfig struct {
 	Inputs     []string
 	Probe      *ooni.Probe
 	RunType    model.RunType // hint for check-in API
+
+	// Annotations contains OPTIONAL annotations to add to each measurement.
+	Annotations map[string]string
+
+	// ResumeResultID is the OPTIONAL ID of an incomplete result of
+	// the same group to resume rather than creating a new result.
+	ResumeResultID int64
+
+	// Reruns OPTIONALLY contains the measurements of the group to re-run,
+	// in which case we only measure their tests and inputs, ignoring
+	// Inputs and InputFiles, and we link the new measurements to them.
+	Reruns []Rerun
+
+	// DryRun OPTIONALLY indicates that we should only load the inputs
+	// and print what we would measure, without measuring.
+	DryRun bool
+
+	// CategoryCodes OPTIONALLY restricts the URLs we get from the
+	// check-in API to the given category codes, overriding the config.
+	CategoryCodes []string
+
+	// ExcludeCategoryCodes OPTIONALLY contains the category codes of
+	// the URLs from the check-in API that we should not measure.
+	ExcludeCategoryCodes []string
+
+	// MergeWithTestList OPTIONALLY indicates that we should also
+	// measure the URLs from the check-in API besides the Inputs
+	// and the InputFiles, rather than only measuring them.
+	MergeWithTestList bool
+
+	// MaxRuntime OPTIONALLY caps the runtime of the group, overriding
+	// the max_runtime setting. When we reach it, we finish the current
+	// measurement and mark the result as done but partial.
+	MaxRuntime time.Duration
+
+	// Parallelism is the OPTIONAL number of inputs to measure concurrently,
+	// which must not exceed MaxParallelism and defaults to one.
+	Parallelism int
+
+	// Features OPTIONALLY contains the feature flags returned by the
+	// check-in API, in which case we skip the nettests they disable.
+	Features map[string]bool
 }
 
+// MaxParallelism is the maximum number of inputs we measure concurrently.
+const MaxParallelism = 8
+
 const websitesURLLimitRemoved = `WARNING: CONFIGURATION CHANGE REQUIRED:
 
 * Since ooniprobe 3.9.0, websites_url_limit has been replaced
@@ -59,6 +107,11 @@ func RunGroup(config RunGroupConfig) error {
 		log.Debugf("context is terminated, stopping runNettestGroup early")
 		return nil
 	}
+	deadline := groupDeadline(config, time.Now())
+	if len(config.Reruns) > 0 {
+		config.Inputs, config.InputFiles = rerunInputs(config.Reruns), nil
+		config.MergeWithTestList = false
+	}
 
 	sess, err := config.Probe.NewSession(context.Background(), config.RunType)
 	if err != nil {
@@ -72,11 +125,6 @@ func RunGroup(config RunGroupConfig) error {
 		log.WithError(err).Error("Failed to lookup the location of the probe")
 		return err
 	}
-	network, err := database.CreateNetwork(config.Probe.DB(), sess)
-	if err != nil {
-		log.WithError(err).Error("Failed to create the network row")
-		return err
-	}
 	if err := sess.MaybeLookupBackends(); err != nil {
 		log.WithError(err).Warn("Failed to discover OONI backends")
 		return err
@@ -87,15 +135,28 @@ func RunGroup(config RunGroupConfig) error {
 		log.Errorf("No test group named %s", config.GroupName)
 		return errors.New("invalid test group name")
 	}
+	if config.DryRun {
+		return dryRunGroup(config, group, sess)
+	}
 	log.Debugf("Running test group %s", group.Label)
 
-	result, err := database.CreateResult(
-		config.Probe.DB(), config.Probe.Home(), config.GroupName, network.ID)
+	network, err := config.Probe.DB().CreateNetwork(sess)
 	if err != nil {
-		log.Errorf("DB result error: %s", err)
+		log.WithError(err).Error("Failed to create the network row")
 		return err
 	}
 
+	result, completedTests, err := createOrResumeResult(config, network)
+	if err != nil {
+		return err
+	}
+	recordLocation(config.Probe, network, result.ID)
+	recordEvent(config.Probe, database.EventRunStarted, result.ID, runEventDetails{
+		GroupName: config.GroupName,
+		RunType:   config.RunType,
+		Resumed:   config.ResumeResultID > 0,
+	})
+
 	config.Probe.ListenForSignals()
 	config.Probe.MaybeListenForStdinClosed()
 	for i, nt := range group.Nettests {
@@ -103,6 +164,11 @@ func RunGroup(config RunGroupConfig) error {
 			log.Debugf("context is terminated, stopping group.Nettests early")
 			break
 		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			log.Infof("Skipping the remaining tests: reached the maximum runtime")
+			result.IsPartial = true
+			break
+		}
 		if config.RunType != model.RunTypeTimed {
 			if _, background := nt.(onlyBackground); background {
 				log.Debug("we only run this nettest in background mode")
@@ -114,11 +180,33 @@ func RunGroup(config RunGroupConfig) error {
 		ctl.InputFiles = config.InputFiles
 		ctl.Inputs = config.Inputs
 		ctl.RunType = config.RunType
+		ctl.Annotations = config.Annotations
+		ctl.CompletedTests = completedTests
+		ctl.Reruns = config.Reruns
+		ctl.CategoryCodes = config.CategoryCodes
+		ctl.ExcludeCategoryCodes = config.ExcludeCategoryCodes
+		ctl.MergeWithTestList = config.MergeWithTestList
+		ctl.Deadline = deadline
+		ctl.Parallelism = config.Parallelism
+		ctl.Features = config.Features
 		ctl.SetNettestIndex(i, len(group.Nettests))
-		if err = nt.Run(ctl); err != nil {
+		err = config.Probe.CrashReporter().Guard(fmt.Sprintf("%T", nt), func() error {
+			return nt.Run(ctl)
+		})
+		if err != nil {
 			log.WithError(err).Errorf("Failed to run %s", group.Label)
 		}
 	}
+	recordEvent(config.Probe, database.EventRunStopped, result.ID, runEventDetails{
+		GroupName:   config.GroupName,
+		RunType:     config.RunType,
+		Resumed:     config.ResumeResultID > 0,
+		Interrupted: config.Probe.IsTerminated(),
+		Partial:     result.IsPartial,
+	})
+	if result.IsPartial {
+		log.Warnf("The result #%d is partial: the run stopped at its maximum runtime", result.ID)
+	}
 
 	// Remove the directory if it's emtpy, which happens when the corresponding
 	// measurements have been submitted (see https://github.com/ooni/probe/issues/2090)
@@ -132,12 +220,108 @@ func RunGroup(config RunGroupConfig) error {
 		os.Remove(result.MeasurementDir)
 	}
 
-	if err = result.Finished(config.Probe.DB()); err != nil {
+	// The session counts the bytes of every conn it creates, hence its data
+	// usage also includes the geolocation, the backend discovery, and the
+	// fetching of the test lists, which the experiments do not account for.
+	usage := sess.DataUsage()
+	result.DataUsageUp += usage.KibiBytesSent
+	result.DataUsageDown += usage.KibiBytesReceived
+
+	if err = config.Probe.DB().ResultFinished(result); err != nil {
 		return err
 	}
+	notifyAnomalies(config.Probe, sess, result, network)
 	return nil
 }
 
+// notifyTimeout is the maximum time we spend notifying the webhook,
+// including the time spent waiting before retrying.
+const notifyTimeout = 60 * time.Second
+
+// notifyAnomalies POSTs the summary of the result to the webhook in the
+// config file, if any, when the result contains anomalies, using the HTTP
+// client of the session. We only warn in case of failure, since the
+// result is already saved.
+func notifyAnomalies(probe *ooni.Probe, sess *engine.Session,
+	result *database.Result, network *database.Network) {
+	settings := probe.Config().Notifications
+	if settings.WebhookURL == "" {
+		return
+	}
+	measurements, err := probe.DB().ListMeasurements(result.ID)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list the measurements to notify")
+		return
+	}
+	summary := notify.NewSummary(result, network, measurements)
+	if summary.AnomalyCount <= 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	err = notify.New(notify.Config{
+		HTTPClient: sess.DefaultHTTPClient(),
+		URL:        settings.WebhookURL,
+		Secret:     settings.WebhookSecret,
+	}).Notify(ctx, summary)
+	if err != nil {
+		log.WithError(err).Warn("Failed to notify the anomalies to the webhook")
+		return
+	}
+	log.Infof("Notified %d anomalies of result #%d to the webhook", summary.AnomalyCount, result.ID)
+}
+
+// groupDeadline returns when the group started at the given time should
+// stop, which is the zero time when there is no maximum runtime.
+func groupDeadline(config RunGroupConfig, start time.Time) time.Time {
+	maxRuntime := config.MaxRuntime
+	if maxRuntime <= 0 {
+		maxRuntime = time.Duration(config.Probe.Config().Nettests.MaxRuntime) * time.Second
+	}
+	if maxRuntime <= 0 {
+		return time.Time{}
+	}
+	return start.Add(maxRuntime)
+}
+
+// createOrResumeResult returns the result in which to save the measurements
+// of the group along with the names of the tests we should skip because the
+// result already contains them, which only happens when resuming.
+func createOrResumeResult(
+	config RunGroupConfig, network *database.Network) (*database.Result, map[string]bool, error) {
+	if config.ResumeResultID > 0 {
+		resumed, err := config.Probe.DB().ResumeResult(config.ResumeResultID, network)
+		if err != nil {
+			log.WithError(err).Errorf("Failed to resume result #%d", config.ResumeResultID)
+			return nil, nil, err
+		}
+		if resumed.TestGroupName != config.GroupName {
+			return nil, nil, errors.New("the result belongs to another test group")
+		}
+		// The measurement dir may be missing if the run stopped
+		// before writing any measurement into it.
+		if err := os.MkdirAll(resumed.MeasurementDir, 0700); err != nil {
+			return nil, nil, err
+		}
+		return resumed.Result, resumed.CompletedTests, nil
+	}
+	result, err := config.Probe.DB().CreateResult(
+		config.Probe.Home(), config.GroupName, network.ID)
+	if err != nil {
+		log.Errorf("DB result error: %s", err)
+		return nil, nil, err
+	}
+	err = config.Probe.DB().UpdateResultLinkContext(result, network, config.Probe.LinkType())
+	if err != nil {
+		log.WithError(err).Warn("Failed to save the link context")
+	}
+	err = config.Probe.DB().SetResultSoftware(result, config.Probe.SoftwareInfo())
+	if err != nil {
+		log.WithError(err).Warn("Failed to save the software info")
+	}
+	return result, nil, nil
+}
+
 // onlyBackground is the interface implements by nettests that we don't
 // want to run in manual mode because they take too much runtime
 //
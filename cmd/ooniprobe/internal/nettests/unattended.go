@@ -0,0 +1,185 @@
+package nettests
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/conditions"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// These constants are the annotation with which we mark the measurements
+// of unattended runs, which is the same one used by the mobile apps.
+const (
+	unattendedAnnotationKey   = "origin"
+	unattendedAnnotationValue = "autorun"
+)
+
+// backendGatedExperiments contains the experiments that we only run
+// unattended when the check-in API enables them (e.g., using the
+// "torsf_enabled" feature flag), like the mobile apps do.
+var backendGatedExperiments = map[string]bool{
+	"torsf":       true,
+	"vanilla_tor": true,
+}
+
+// isEnabledByBackend returns whether the feature flags returned by
+// the check-in API allow us to run the given experiment.
+func isEnabledByBackend(features map[string]bool, name string) bool {
+	if enabled, found := features[name+"_enabled"]; found {
+		return enabled
+	}
+	return !backendGatedExperiments[name]
+}
+
+// UnattendedConfig contains the settings for RunUnattended.
+type UnattendedConfig struct {
+	Probe *ooni.Probe
+
+	// Annotations contains OPTIONAL annotations to add to each measurement.
+	Annotations map[string]string
+
+	// MaxJitter is the OPTIONAL maximum random delay before running,
+	// which avoids many probes started at the same time (e.g., by cron)
+	// hitting the backend all at once.
+	MaxJitter time.Duration
+
+	// DryRun and MaxRuntime are like the fields of RunGroupConfig.
+	DryRun     bool
+	MaxRuntime time.Duration
+}
+
+// RunUnattended runs the test groups that we can run unattended, after
+// waiting for a random delay, skipping the nettests that the check-in
+// API disables, and marking the measurements as unattended.
+func RunUnattended(config UnattendedConfig) error {
+	config.Probe.ListenForSignals()
+	if config.MaxJitter > 0 && !config.DryRun {
+		rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+		delay := time.Duration(rnd.Int63n(int64(config.MaxJitter)))
+		log.Infof("Waiting %s before running", delay.Round(time.Second))
+		sleepUnlessTerminated(config.Probe, delay)
+	}
+	if config.Probe.IsTerminated() {
+		return nil
+	}
+	features, err := checkInFeatures(config.Probe)
+	if err != nil {
+		log.WithError(err).Error("Failed to ask the check-in API what to run")
+		return err
+	}
+	annotations := map[string]string{unattendedAnnotationKey: unattendedAnnotationValue}
+	for key, value := range config.Annotations {
+		annotations[key] = value
+	}
+	var names []string
+	for name, group := range All {
+		if group.UnattendedOK {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if config.Probe.IsTerminated() {
+			break
+		}
+		log.Infof("Running %s tests", name)
+		err := RunGroup(RunGroupConfig{
+			GroupName:   name,
+			Probe:       config.Probe,
+			RunType:     model.RunTypeTimed,
+			Annotations: annotations,
+			Features:    features,
+			DryRun:      config.DryRun,
+			MaxRuntime:  config.MaxRuntime,
+		})
+		if err != nil {
+			log.WithError(err).Errorf("failed to run %s", name)
+		}
+	}
+	return nil
+}
+
+// checkInFeatures returns the feature flags returned by the check-in API.
+func checkInFeatures(probe *ooni.Probe) (map[string]bool, error) {
+	sess, err := probe.NewSession(context.Background(), model.RunTypeTimed)
+	if err != nil {
+		return nil, err
+	}
+	defer sess.Close()
+	if err := sess.MaybeLookupBackends(); err != nil {
+		return nil, err
+	}
+	info, err := sess.CheckIn(context.Background(), &model.OOAPICheckInConfig{
+		Charging: isCharging(),
+		OnWiFi:   isOnWiFi(probe.LinkType()),
+		RunType:  model.RunTypeTimed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	features := make(map[string]bool)
+	for key, value := range info.Conf.Features {
+		features[key] = value
+	}
+	log.Debugf("check-in feature flags: %v", features)
+	return features, nil
+}
+
+// These variables allow to mock the conditions in tests.
+var (
+	conditionsOnBattery        = conditions.OnBattery
+	conditionsOnMeteredNetwork = conditions.OnMeteredNetwork
+)
+
+// isCharging returns whether we are charging, which we tell the check-in
+// API. When we cannot check, we assume we are charging, which is the case
+// of desktops and servers, where we typically run unattended.
+func isCharging() bool {
+	battery, err := conditionsOnBattery()
+	if err != nil {
+		log.WithError(err).Debug("cannot check whether we're on battery")
+		return true
+	}
+	return !battery
+}
+
+// isOnWiFi returns whether we are using an unmetered network, which we
+// tell the check-in API as being on WiFi. We trust the link type set by
+// the user, if any, and otherwise check whether the network is metered,
+// assuming it is not when we cannot check.
+func isOnWiFi(linkType string) bool {
+	switch linkType {
+	case database.LinkTypeMobile:
+		return false
+	case database.LinkTypeWifi, database.LinkTypeWired:
+		return true
+	}
+	metered, err := conditionsOnMeteredNetwork()
+	if err != nil {
+		log.WithError(err).Debug("cannot check whether the network is metered")
+		return true
+	}
+	return !metered
+}
+
+// sleepUnlessTerminated sleeps for the given delay, returning
+// early if the user asks us to terminate.
+func sleepUnlessTerminated(probe *ooni.Probe, delay time.Duration) {
+	const pollInterval = time.Second
+	for deadline := time.Now().Add(delay); !probe.IsTerminated(); {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return
+		}
+		if remaining > pollInterval {
+			remaining = pollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
@@ -0,0 +1,84 @@
+package nettests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+func TestCheckInConditions(t *testing.T) {
+	onBattery, onMeteredNetwork := conditionsOnBattery, conditionsOnMeteredNetwork
+	defer func() {
+		conditionsOnBattery, conditionsOnMeteredNetwork = onBattery, onMeteredNetwork
+	}()
+	unsupported := errors.New("mocked error")
+
+	var chargingCases = []struct {
+		name     string
+		battery  bool
+		err      error
+		expected bool
+	}{{
+		name:     "on battery",
+		battery:  true,
+		expected: false,
+	}, {
+		name:     "not on battery",
+		expected: true,
+	}, {
+		name:     "when we cannot check",
+		battery:  true,
+		err:      unsupported,
+		expected: true,
+	}}
+	for _, tc := range chargingCases {
+		t.Run("isCharging "+tc.name, func(t *testing.T) {
+			conditionsOnBattery = func() (bool, error) {
+				return tc.battery, tc.err
+			}
+			if isCharging() != tc.expected {
+				t.Fatal("unexpected result")
+			}
+		})
+	}
+
+	var wifiCases = []struct {
+		name     string
+		linkType string
+		metered  bool
+		err      error
+		expected bool
+	}{{
+		name:     "with mobile link",
+		linkType: database.LinkTypeMobile,
+		expected: false,
+	}, {
+		name:     "with wired link",
+		linkType: database.LinkTypeWired,
+		metered:  true,
+		expected: true,
+	}, {
+		name:     "with metered network",
+		metered:  true,
+		expected: false,
+	}, {
+		name:     "with unmetered network",
+		expected: true,
+	}, {
+		name:     "when we cannot check",
+		metered:  true,
+		err:      unsupported,
+		expected: true,
+	}}
+	for _, tc := range wifiCases {
+		t.Run("isOnWiFi "+tc.name, func(t *testing.T) {
+			conditionsOnMeteredNetwork = func() (bool, error) {
+				return tc.metered, tc.err
+			}
+			if isOnWiFi(tc.linkType) != tc.expected {
+				t.Fatal("unexpected result")
+			}
+		})
+	}
+}
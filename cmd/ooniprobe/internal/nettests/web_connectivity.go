@@ -2,8 +2,12 @@ package nettests
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
 
 	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
 	engine "github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
@@ -21,26 +25,105 @@ func (n WebConnectivity) lookupURLs(ctl *Controller, categories []string) ([]str
 				CategoryCodes: categories,
 			},
 		},
-		ExperimentName: "web_connectivity",
-		InputPolicy:    engine.InputOrQueryBackend,
-		Session:        ctl.Session,
-		SourceFiles:    ctl.InputFiles,
-		StaticInputs:   ctl.Inputs,
+		ExperimentName:   "web_connectivity",
+		InputPolicy:      engine.InputOrQueryBackend,
+		Session:          ctl.Session,
+		SourceFiles:      ctl.InputFiles,
+		StaticInputs:     ctl.Inputs,
+		MergeWithBackend: ctl.MergeWithTestList,
 	}
 	testlist, err := inputloader.Load(context.Background())
+	userProvided := len(ctl.Inputs) > 0 || len(ctl.InputFiles) > 0
+	if !ctl.DryRun && (!userProvided || ctl.MergeWithTestList) {
+		// Without user-provided input, or when merging with the
+		// test list, the loader uses the check-in API.
+		details := checkInEventDetails{TestName: "web_connectivity", URLCount: len(testlist)}
+		if err != nil {
+			details.Failure = err.Error()
+		}
+		recordEvent(ctl.Probe, database.EventCheckIn, ctl.res.ID, details)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if !userProvided {
+		testlist = filterCategoryCodes(testlist, categories, ctl.ExcludeCategoryCodes)
+	} else if ctl.MergeWithTestList {
+		// The check-in API already selects the URLs by category and the
+		// user-provided URLs have no category, so we only exclude.
+		testlist = filterCategoryCodes(testlist, nil, ctl.ExcludeCategoryCodes)
+	}
+	if testlist, err = checkURLs(testlist); err != nil {
+		return nil, err
+	}
 	return ctl.BuildAndSetInputIdxMap(ctl.Probe.DB(), testlist)
 }
 
+// errInvalidURL indicates that an input of web_connectivity is not a URL.
+var errInvalidURL = errors.New("nettests: invalid URL")
+
+// checkURLs checks that all the URLs are valid HTTP or HTTPS URLs and
+// returns the list without duplicate URLs, which may happen when the user
+// provides overlapping input files or merges them with the test list.
+func checkURLs(testlist []model.OOAPIURLInfo) ([]model.OOAPIURLInfo, error) {
+	var out []model.OOAPIURLInfo
+	seen := make(map[string]bool)
+	for _, entry := range testlist {
+		parsed, err := url.Parse(entry.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidURL, entry.URL)
+		}
+		if seen[entry.URL] {
+			log.Debugf("Skipping duplicate URL %s", entry.URL)
+			continue
+		}
+		seen[entry.URL] = true
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+// filterCategoryCodes returns the URLs whose category code is among the
+// included ones, unless include is empty, and is not among the excluded ones.
+func filterCategoryCodes(testlist []model.OOAPIURLInfo, include, exclude []string) []model.OOAPIURLInfo {
+	contains := func(codes []string, code string) bool {
+		for _, c := range codes {
+			if c == code {
+				return true
+			}
+		}
+		return false
+	}
+	var out []model.OOAPIURLInfo
+	for _, entry := range testlist {
+		if len(include) > 0 && !contains(include, entry.CategoryCode) {
+			continue
+		}
+		if contains(exclude, entry.CategoryCode) {
+			continue
+		}
+		out = append(out, entry)
+	}
+	if len(out) < len(testlist) {
+		log.Infof("Skipping %d URLs because of their category code", len(testlist)-len(out))
+	}
+	return out
+}
+
 // WebConnectivity test implementation
 type WebConnectivity struct{}
 
 // Run starts the test
 func (n WebConnectivity) Run(ctl *Controller) error {
-	log.Debugf("Enabled category codes are the following %v", ctl.Probe.Config().Nettests.WebsitesEnabledCategoryCodes)
-	urls, err := n.lookupURLs(ctl, ctl.Probe.Config().Nettests.WebsitesEnabledCategoryCodes)
+	categories := ctl.Probe.Config().Nettests.WebsitesEnabledCategoryCodes
+	if options := ctl.Probe.Config().Nettests.Experiments.WebConnectivity; len(options.CategoryCodes) > 0 {
+		categories = options.CategoryCodes
+	}
+	if len(ctl.CategoryCodes) > 0 {
+		categories = ctl.CategoryCodes
+	}
+	log.Debugf("Enabled category codes are the following %v", categories)
+	urls, err := n.lookupURLs(ctl, categories)
 	if err != nil {
 		return err
 	}
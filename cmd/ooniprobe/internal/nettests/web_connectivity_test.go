@@ -0,0 +1,77 @@
+package nettests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestFilterCategoryCodes(t *testing.T) {
+	testlist := []model.OOAPIURLInfo{
+		{CategoryCode: "NEWS", URL: "https://news.example.com/"},
+		{CategoryCode: "HUMR", URL: "https://humr.example.com/"},
+		{CategoryCode: "GAME", URL: "https://game.example.com/"},
+	}
+	urls := func(testlist []model.OOAPIURLInfo) (out []string) {
+		for _, entry := range testlist {
+			out = append(out, entry.URL)
+		}
+		return
+	}
+	for _, tc := range []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+	}{{
+		name:     "without filters",
+		expected: urls(testlist),
+	}, {
+		name:     "with included category codes",
+		include:  []string{"NEWS", "HUMR"},
+		expected: []string{"https://news.example.com/", "https://humr.example.com/"},
+	}, {
+		name:     "with excluded category codes",
+		exclude:  []string{"GAME"},
+		expected: []string{"https://news.example.com/", "https://humr.example.com/"},
+	}, {
+		name:     "with both",
+		include:  []string{"NEWS", "HUMR"},
+		exclude:  []string{"NEWS"},
+		expected: []string{"https://humr.example.com/"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			out := urls(filterCategoryCodes(testlist, tc.include, tc.exclude))
+			if diff := cmp.Diff(tc.expected, out); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestCheckURLs(t *testing.T) {
+	t.Run("with valid URLs", func(t *testing.T) {
+		out, err := checkURLs([]model.OOAPIURLInfo{
+			{URL: "https://example.com/"},
+			{URL: "http://example.org/"},
+			{URL: "https://example.com/"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) != 2 {
+			t.Fatal("expected to skip the duplicate URL", out)
+		}
+	})
+
+	t.Run("with invalid URLs", func(t *testing.T) {
+		for _, input := range []string{"example.com", "ftp://example.com/", "https://", "\t"} {
+			_, err := checkURLs([]model.OOAPIURLInfo{{URL: input}})
+			if !errors.Is(err, errInvalidURL) {
+				t.Fatalf("unexpected error for %q: %v", input, err)
+			}
+		}
+	})
+}
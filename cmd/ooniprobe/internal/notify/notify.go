@@ -0,0 +1,194 @@
+// Package notify notifies a webhook about the results containing
+// anomalies, which allows to integrate ooniprobe with alerting systems
+// when monitoring a network (see the notifications settings).
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/explorer"
+)
+
+// SignatureHeader is the header containing the HMAC-SHA256 signature of
+// the body using the webhook secret, formatted as "sha256=<hex>".
+const SignatureHeader = "X-OONI-Signature"
+
+// ErrHTTPStatus indicates that the webhook returned an error.
+var ErrHTTPStatus = errors.New("notify: unexpected HTTP status")
+
+// Anomaly is an anomalous measurement.
+type Anomaly struct {
+	MeasurementID int64  `json:"measurement_id"`
+	TestName      string `json:"test_name"`
+	Input         string `json:"input,omitempty"`
+
+	// ExplorerURL is the OONI Explorer URL of the measurement,
+	// which is empty when we did not upload it.
+	ExplorerURL string `json:"explorer_url,omitempty"`
+}
+
+// Summary is the summary of a result we POST to the webhook.
+type Summary struct {
+	ResultID         int64     `json:"result_id"`
+	TestGroupName    string    `json:"test_group_name"`
+	StartTime        time.Time `json:"start_time"`
+	Runtime          float64   `json:"runtime"`
+	IsPartial        bool      `json:"is_partial"`
+	ProbeASN         string    `json:"probe_asn"`
+	ProbeCC          string    `json:"probe_cc"`
+	NetworkName      string    `json:"network_name"`
+	MeasurementCount int       `json:"measurement_count"`
+	AnomalyCount     int       `json:"anomaly_count"`
+	Anomalies        []Anomaly `json:"anomalies"`
+}
+
+// NewSummary returns the summary of the given result.
+func NewSummary(result *database.Result, network *database.Network,
+	measurements []database.MeasurementURLNetwork) *Summary {
+	summary := &Summary{
+		ResultID:         result.ID,
+		TestGroupName:    result.TestGroupName,
+		StartTime:        result.StartTime,
+		Runtime:          result.Runtime,
+		IsPartial:        result.IsPartial,
+		ProbeASN:         fmt.Sprintf("AS%d", network.ASN),
+		ProbeCC:          network.CountryCode,
+		NetworkName:      network.NetworkName,
+		MeasurementCount: len(measurements),
+		Anomalies:        []Anomaly{},
+	}
+	for idx := range measurements {
+		msmt := &measurements[idx]
+		if !msmt.IsAnomaly.Bool {
+			continue
+		}
+		URL, _ := explorer.ForMeasurement(msmt)
+		summary.Anomalies = append(summary.Anomalies, Anomaly{
+			MeasurementID: msmt.Measurement.ID,
+			TestName:      msmt.Measurement.TestName,
+			Input:         msmt.URL.URL.String,
+			ExplorerURL:   URL,
+		})
+	}
+	summary.AnomalyCount = len(summary.Anomalies)
+	return summary
+}
+
+// Config contains the settings of the notifier.
+type Config struct {
+	// HTTPClient is the OPTIONAL HTTP client to use.
+	HTTPClient *http.Client
+
+	// URL is the URL of the webhook.
+	URL string
+
+	// Secret is the OPTIONAL secret for signing the body.
+	Secret string
+
+	// MaxAttempts is the OPTIONAL maximum number of attempts.
+	MaxAttempts int
+
+	// RetryDelay is the OPTIONAL delay before the second attempt,
+	// which we double before each further attempt.
+	RetryDelay time.Duration
+}
+
+// Notifier notifies the webhook.
+type Notifier struct {
+	config Config
+
+	// sleep allows to mock sleepContext in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New creates a new notifier.
+func New(config Config) *Notifier {
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 5 * time.Second
+	}
+	return &Notifier{config: config, sleep: sleepContext}
+}
+
+// sleepContext sleeps for the given duration unless the context
+// is done first, in which case it returns the context error.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Sign returns the value of SignatureHeader for the given body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify POSTs the summary to the webhook, retrying in case of network
+// errors and server errors, but not in case of client errors. We stop
+// retrying as soon as the context is done, hence the caller should use
+// a context with a deadline to bound the time spent notifying.
+func (n *Notifier) Notify(ctx context.Context, summary *Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	delay := n.config.RetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, body)
+		if err == nil || !retry || attempt >= n.config.MaxAttempts {
+			return err
+		}
+		log.WithError(err).Debugf("notify: retrying in %s", delay)
+		if n.sleep(ctx, delay) != nil {
+			return err
+		}
+		delay *= 2
+	}
+}
+
+// post POSTs the body to the webhook, returning whether to retry on failure.
+func (n *Notifier) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", n.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.config.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.config.Secret, body))
+	}
+	resp, err := n.config.HTTPClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("%w: %s", ErrHTTPStatus, resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+func TestNewSummary(t *testing.T) {
+	result := &database.Result{ID: 7, TestGroupName: "websites"}
+	network := &database.Network{ASN: 30722, CountryCode: "IT", NetworkName: "Vodafone Italia S.p.A."}
+	measurements := make([]database.MeasurementURLNetwork, 3)
+	measurements[0].Measurement.ID = 1
+	measurements[1].Measurement.ID = 2
+	measurements[1].Measurement.TestName = "web_connectivity"
+	measurements[1].IsAnomaly = sql.NullBool{Bool: true, Valid: true}
+	measurements[1].Measurement.IsUploaded = true
+	measurements[1].ReportID = sql.NullString{String: "20221006T090000Z_webconnectivity_IT_30722_n1_abc", Valid: true}
+	measurements[1].URL.URL = sql.NullString{String: "https://example.com/", Valid: true}
+	measurements[2].Measurement.ID = 3
+	measurements[2].IsAnomaly = sql.NullBool{Bool: false, Valid: true}
+	summary := NewSummary(result, network, measurements)
+	if summary.ResultID != 7 || summary.ProbeASN != "AS30722" || summary.MeasurementCount != 3 {
+		t.Fatal("unexpected summary", summary)
+	}
+	if summary.AnomalyCount != 1 || summary.Anomalies[0].MeasurementID != 2 {
+		t.Fatal("unexpected anomalies", summary.Anomalies)
+	}
+	expected := "https://explorer.ooni.org/measurement/20221006T090000Z_webconnectivity_IT_30722_n1_abc" +
+		"?input=https%3A%2F%2Fexample.com%2F"
+	if summary.Anomalies[0].ExplorerURL != expected {
+		t.Fatal("unexpected explorer URL", summary.Anomalies[0].ExplorerURL)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Header.Get(SignatureHeader) != Sign("secret", body) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var summary Summary
+		if err := json.Unmarshal(body, &summary); err != nil || summary.ResultID != 7 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if attempts < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer server.Close()
+	n := New(Config{URL: server.URL, Secret: "secret"})
+	var slept []time.Duration
+	n.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	if err := n.Notify(context.Background(), &Summary{ResultID: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || len(slept) != 1 {
+		t.Fatal("expected to retry once", attempts, slept)
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.Header.Get(SignatureHeader) != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	t.Run("we stop after the maximum number of attempts", func(t *testing.T) {
+		attempts = 0
+		n := New(Config{URL: server.URL, MaxAttempts: 3})
+		var slept []time.Duration
+		n.sleep = func(ctx context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		}
+		if err := n.Notify(context.Background(), &Summary{}); !errors.Is(err, ErrHTTPStatus) {
+			t.Fatal("not the error we expected", err)
+		}
+		if attempts != 3 || len(slept) != 2 || slept[1] != 2*slept[0] {
+			t.Fatal("unexpected attempts", attempts, slept)
+		}
+	})
+	t.Run("we do not retry on client errors", func(t *testing.T) {
+		attempts = 0
+		n := New(Config{URL: server.URL, Secret: "secret"})
+		n.sleep = func(ctx context.Context, d time.Duration) error {
+			t.Fatal("should not retry")
+			return nil
+		}
+		if err := n.Notify(context.Background(), &Summary{}); !errors.Is(err, ErrHTTPStatus) {
+			t.Fatal("not the error we expected", err)
+		}
+		if attempts != 1 {
+			t.Fatal("unexpected attempts", attempts)
+		}
+	})
+	t.Run("we stop retrying when the context is done", func(t *testing.T) {
+		attempts = 0
+		n := New(Config{URL: server.URL, RetryDelay: time.Hour})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := n.Notify(ctx, &Summary{}); !errors.Is(err, ErrHTTPStatus) {
+			t.Fatal("not the error we expected", err)
+		}
+		if attempts != 1 {
+			t.Fatal("unexpected attempts", attempts)
+		}
+	})
+}
@@ -3,23 +3,32 @@ package ooni
 import (
 	"context"
 	_ "embed" // because we embed a file
+	"io/fs"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/crash"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/enginex"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils"
 	"github.com/ooni/probe-cli/v3/internal/atomicx"
 	"github.com/ooni/probe-cli/v3/internal/engine"
+	"github.com/ooni/probe-cli/v3/internal/engine/geolocate"
 	"github.com/ooni/probe-cli/v3/internal/engine/legacy/assetsdir"
+	"github.com/ooni/probe-cli/v3/internal/httpx"
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/platform"
+	"github.com/ooni/probe-cli/v3/internal/scrubber"
+	"github.com/ooni/probe-cli/v3/internal/version"
 	"github.com/pkg/errors"
-	"github.com/upper/db/v4"
 )
 
 // DefaultSoftwareName is the default software name.
@@ -28,7 +37,7 @@ const DefaultSoftwareName = "ooniprobe-cli"
 // ProbeCLI is the OONI Probe CLI context.
 type ProbeCLI interface {
 	Config() *config.Config
-	DB() db.Session
+	DB() database.Actions
 	IsBatch() bool
 	Home() string
 	TempDir() string
@@ -38,17 +47,23 @@ type ProbeCLI interface {
 // ProbeEngine is an instance of the OONI Probe engine.
 type ProbeEngine interface {
 	Close() error
+	CrossCheckLocation() error
 	MaybeLookupLocation() error
 	ProbeASNString() string
 	ProbeCC() string
 	ProbeIP() string
+	ProbeIPLookups() []geolocate.IPLookup
+	ProbeIPLookupsDisagree() bool
 	ProbeNetworkName() string
+	ResolverASNString() string
+	ResolverIP() string
+	ResolverNetworkName() string
 }
 
 // Probe contains the ooniprobe CLI context.
 type Probe struct {
 	config  *config.Config
-	db      db.Session
+	db      *database.Database
 	isBatch bool
 
 	home      string
@@ -60,8 +75,94 @@ type Probe struct {
 
 	isTerminated *atomicx.Int64
 
+	// listenSignalsOnce and listenStdinOnce ensure that we install the
+	// handlers terminating the probe just once, even when we run several
+	// test groups with the same probe (e.g., `ooniprobe daemon`).
+	listenSignalsOnce sync.Once
+	listenStdinOnce   sync.Once
+
 	softwareName    string
 	softwareVersion string
+
+	collectors              []string
+	replaceDefaultCollector bool
+
+	// linkType is the OPTIONAL type of the link we're using.
+	linkType string
+
+	// proxyURL is the OPTIONAL proxy we use to talk to the backend.
+	proxyURL *url.URL
+
+	// limiter is shared by all the sessions we create, such that
+	// running several nettests or re-submitting many measurements
+	// does not hammer the probe services.
+	limiter *httpx.RateLimiter
+
+	// askDatabasePassphrase, if not nil, asks the user for the
+	// passphrase of the encrypted database.
+	askDatabasePassphrase func() (string, error)
+
+	// crashReporter writes the crash dumps into the home.
+	crashReporter *crash.Reporter
+}
+
+// DatabasePassphraseEnv is the environment variable containing the
+// passphrase of the database when the database is encrypted.
+const DatabasePassphraseEnv = "OONI_DATABASE_PASSPHRASE"
+
+// SetAskDatabasePassphrase configures the function we use to ask the
+// user for the database passphrase when the database is encrypted and
+// the DatabasePassphraseEnv environment variable is not set.
+func (p *Probe) SetAskDatabasePassphrase(fn func() (string, error)) {
+	p.askDatabasePassphrase = fn
+}
+
+// SetCrashRecorder configures the recorder of the last log events,
+// which we include into the crash dumps.
+func (p *Probe) SetCrashRecorder(recorder *crash.Recorder) {
+	p.crashReporter.Events = recorder
+}
+
+// CrashReporter returns the reporter that writes the crash dumps.
+func (p *Probe) CrashReporter() *crash.Reporter {
+	return p.crashReporter
+}
+
+// SetCollectors configures alternative collectors for the measurements
+// run using this probe. When replace is true, we only use these
+// collectors, otherwise we use them if the default one fails.
+func (p *Probe) SetCollectors(collectors []string, replace bool) {
+	p.collectors = collectors
+	p.replaceDefaultCollector = replace
+}
+
+// SetLinkType sets the type of the link we're using (e.g., "wired"),
+// which we save along with the results to provide context.
+func (p *Probe) SetLinkType(linkType string) {
+	p.linkType = linkType
+}
+
+// SetProxyURL configures the proxy used by the sessions we create to
+// communicate with the OONI backend. The psiphon and tor schemes start
+// a tunnel, while socks5 and http use an already running proxy.
+func (p *Probe) SetProxyURL(proxyURL *url.URL) {
+	p.proxyURL = proxyURL
+}
+
+// LinkType returns the type of the link we're using, if known.
+func (p *Probe) LinkType() string {
+	return p.linkType
+}
+
+// SoftwareInfo returns the software that produces the results, which
+// we save along with the results to correlate them with upgrades.
+func (p *Probe) SoftwareInfo() *database.SoftwareInfo {
+	return &database.SoftwareInfo{
+		Name:          p.softwareName,
+		Version:       p.softwareVersion,
+		EngineVersion: version.Version,
+		Platform:      platform.Name(),
+	}
 }
 
 // SetIsBatch sets the value of isBatch.
@@ -80,7 +181,7 @@ func (p *Probe) Config() *config.Config {
 }
 
 // DB returns the database we're using
-func (p *Probe) DB() db.Session {
+func (p *Probe) DB() database.Actions {
 	return p.db
 }
 
@@ -111,7 +212,14 @@ func (p *Probe) Terminate() {
 //
 // TODO refactor this to use a cancellable context.Context instead of a bool
 // flag, probably as part of: https://github.com/ooni/probe-cli/issues/45
+//
+// This method is idempotent.
 func (p *Probe) ListenForSignals() {
+	p.listenSignalsOnce.Do(p.listenForSignals)
+}
+
+// listenForSignals implements ListenForSignals.
+func (p *Probe) listenForSignals() {
 	s := make(chan os.Signal, 1)
 	signal.Notify(s, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
@@ -134,7 +242,14 @@ func (p *Probe) ListenForSignals() {
 //
 // TODO refactor this to use a cancellable context.Context instead of a bool
 // flag, probably as part of: https://github.com/ooni/probe-cli/issues/45
+//
+// This method is idempotent.
 func (p *Probe) MaybeListenForStdinClosed() {
+	p.listenStdinOnce.Do(p.maybeListenForStdinClosed)
+}
+
+// maybeListenForStdinClosed implements MaybeListenForStdinClosed.
+func (p *Probe) maybeListenForStdinClosed() {
 	if os.Getenv("OONI_STDIN_EOF_IMPLIES_SIGTERM") != "true" {
 		return
 	}
@@ -173,8 +288,7 @@ func (p *Probe) Init(softwareName, softwareVersion string) error {
 	}
 
 	p.dbPath = utils.DBDir(p.home, "main")
-	log.Debugf("Connecting to database sqlite3://%s", p.dbPath)
-	db, err := database.Connect(p.dbPath)
+	db, err := p.openDatabase()
 	if err != nil {
 		return err
 	}
@@ -197,18 +311,130 @@ func (p *Probe) Init(softwareName, softwareVersion string) error {
 
 	p.softwareName = softwareName
 	p.softwareVersion = softwareVersion
+	p.crashReporter.SoftwareName = softwareName
+	p.crashReporter.SoftwareVersion = softwareVersion
+	return nil
+}
+
+// openDatabase opens the database, which is the PostgreSQL database at
+// advanced.database_url, when set, or the SQLite database inside the
+// home, which is encrypted when advanced.encrypt_database is true.
+func (p *Probe) openDatabase() (*database.Database, error) {
+	if p.config.Advanced.DatabaseURL != "" {
+		if p.config.Advanced.EncryptDatabase {
+			return nil, errors.New("advanced.encrypt_database requires the SQLite database, " +
+				"please unset advanced.database_url")
+		}
+		// We do not log the URL, which may contain a password.
+		log.Debug("Connecting to the PostgreSQL database at advanced.database_url")
+		return database.OpenPostgreSQL(p.config.Advanced.DatabaseURL)
+	}
+	log.Debugf("Connecting to database sqlite3://%s", p.dbPath)
+	if !p.config.Advanced.EncryptDatabase {
+		db, err := database.Open(p.dbPath)
+		if errors.Is(err, database.ErrEncryptedDatabase) {
+			return nil, errors.Wrap(err, "please set advanced.encrypt_database to true")
+		}
+		return db, err
+	}
+	passphrase := os.Getenv(DatabasePassphraseEnv)
+	if passphrase == "" && p.askDatabasePassphrase != nil {
+		var err error
+		if passphrase, err = p.askDatabasePassphrase(); err != nil {
+			return nil, err
+		}
+	}
+	return database.OpenEncrypted(p.dbPath, passphrase)
+}
+
+// Close closes the database. When the database is encrypted, closing
+// it encrypts it again, so we must call this function before exiting.
+func (p *Probe) Close() error {
+	if p.db == nil {
+		return nil
+	}
+	if err := p.db.Close(); err != nil && !errors.Is(err, database.ErrClosed) {
+		return err
+	}
 	return nil
 }
 
+// importLegacyKVStore moves the keys of the file-system kvstore used
+// by older releases, which stored each key into its own file inside
+// dirpath, into the given kvstore. When a key exists in both, we keep
+// the value inside kvs. This function does nothing when dirpath
+// does not exist, i.e., on new installs or after the first run.
+func importLegacyKVStore(dirpath string, kvs model.KeyValueStore) error {
+	if _, err := os.Stat(dirpath); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	legacy, err := kvstore.NewCompactFS(dirpath, 0)
+	if err != nil {
+		return err
+	}
+	keys, err := legacy.ListKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, err := kvs.Get(key); !errors.Is(err, kvstore.ErrNoSuchKey) {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := legacy.Get(key)
+		if err != nil {
+			return err
+		}
+		if err := kvs.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(dirpath)
+}
+
+// unattendedWatchdogInterval is the default interval after which we
+// interrupt a measurement making no progress when running in unattended
+// mode, where nobody would otherwise notice that the run is stuck.
+const unattendedWatchdogInterval = 5 * time.Minute
+
+// watchdogInterval returns the interval after which we interrupt a
+// measurement making no progress, or zero to disable the watchdog.
+func (p *Probe) watchdogInterval(runType model.RunType) time.Duration {
+	if timeout := p.config.Nettests.WatchdogTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	if runType == model.RunTypeTimed {
+		return unattendedWatchdogInterval
+	}
+	return 0
+}
+
+// KVStore returns the engine's kvstore, which lives inside the
+// database, such that all the probe's state is inside a single file and
+// expired entries are removed when the session compacts the kvstore.
+// When we use a PostgreSQL database, which many probes may share, we
+// keep using the file-system kvstore inside the home, because the
+// kvstore contains the probe's own state, including its credentials.
+func (p *Probe) KVStore() (model.KeyValueStore, error) {
+	if p.config.Advanced.DatabaseURL != "" {
+		return kvstore.NewCompactFS(utils.EngineDir(p.home), 0)
+	}
+	kvs := p.db.KVStore()
+	if err := importLegacyKVStore(utils.EngineDir(p.home), kvs); err != nil {
+		return nil, errors.Wrap(err, "importing engine's kvstore")
+	}
+	return kvs, nil
+}
+
 // NewSession creates a new ooni/probe-engine session using the
 // current configuration inside the context. The caller must close
 // the session when done using it, by calling sess.Close().
 func (p *Probe) NewSession(ctx context.Context, runType model.RunType) (*engine.Session, error) {
-	kvstore, err := kvstore.NewFS(
-		utils.EngineDir(p.home),
-	)
+	kvstore, err := p.KVStore()
 	if err != nil {
-		return nil, errors.Wrap(err, "creating engine's kvstore")
+		return nil, err
 	}
 	if err := os.MkdirAll(p.tunnelDir, 0700); err != nil {
 		return nil, errors.Wrap(err, "creating tunnel dir")
@@ -221,13 +447,29 @@ func (p *Probe) NewSession(ctx context.Context, runType model.RunType) (*engine.
 	if runType == model.RunTypeTimed && softwareName == DefaultSoftwareName {
 		softwareName = DefaultSoftwareName + "-unattended"
 	}
+	var collectors []model.OOAPIService
+	for _, address := range p.collectors {
+		collectors = append(collectors, model.OOAPIService{
+			Address: address,
+			Type:    "https",
+		})
+	}
 	return engine.NewSession(ctx, engine.SessionConfig{
-		KVStore:         kvstore,
-		Logger:          enginex.Logger,
-		SoftwareName:    softwareName,
-		SoftwareVersion: p.softwareVersion,
-		TempDir:         p.tempDir,
-		TunnelDir:       p.tunnelDir,
+		Collectors:              collectors,
+		KVStore:                 kvstore,
+		Limiter:                 p.limiter,
+		Logger:                  enginex.Logger,
+		ProxyURL:                p.proxyURL,
+		ReplaceDefaultCollector: p.replaceDefaultCollector,
+		Scrubbing: scrubber.Rules{
+			MACHostnames: p.config.Sharing.ScrubMACHostnames,
+			Strings:      p.config.Sharing.ScrubStrings,
+		},
+		SoftwareName:     softwareName,
+		SoftwareVersion:  p.softwareVersion,
+		TempDir:          p.tempDir,
+		TunnelDir:        p.tunnelDir,
+		WatchdogInterval: p.watchdogInterval(runType),
 	})
 }
 
@@ -247,6 +489,10 @@ func NewProbe(configPath string, homePath string) *Probe {
 		config:       &config.Config{},
 		configPath:   configPath,
 		isTerminated: &atomicx.Int64{},
+		limiter:      &httpx.RateLimiter{},
+		crashReporter: &crash.Reporter{
+			Dir: utils.CrashDir(homePath),
+		},
 	}
 }
 
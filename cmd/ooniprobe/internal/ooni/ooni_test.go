@@ -4,7 +4,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
 func TestInit(t *testing.T) {
@@ -27,3 +33,93 @@ func TestInit(t *testing.T) {
 		t.Fatal("config file was not created")
 	}
 }
+
+func TestWatchdogInterval(t *testing.T) {
+	var cases = []struct {
+		name     string
+		timeout  int64
+		runType  model.RunType
+		expected time.Duration
+	}{{
+		name:     "with manual run and default timeout",
+		runType:  model.RunTypeManual,
+		expected: 0,
+	}, {
+		name:     "with unattended run and default timeout",
+		runType:  model.RunTypeTimed,
+		expected: unattendedWatchdogInterval,
+	}, {
+		name:     "with manual run and custom timeout",
+		timeout:  30,
+		runType:  model.RunTypeManual,
+		expected: 30 * time.Second,
+	}, {
+		name:     "with unattended run and custom timeout",
+		timeout:  600,
+		runType:  model.RunTypeTimed,
+		expected: 10 * time.Minute,
+	}}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			probe := &Probe{config: &config.Config{}}
+			probe.config.Nettests.WatchdogTimeout = tc.timeout
+			if interval := probe.watchdogInterval(tc.runType); interval != tc.expected {
+				t.Fatal("unexpected interval", interval)
+			}
+		})
+	}
+}
+
+func TestImportLegacyKVStore(t *testing.T) {
+	t.Run("without a legacy kvstore", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		if err := importLegacyKVStore(filepath.Join(t.TempDir(), "engine"), kvs); err != nil {
+			t.Fatal(err)
+		}
+		keys, err := kvs.ListKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 0 {
+			t.Fatal("unexpected keys", keys)
+		}
+	})
+
+	t.Run("with a legacy kvstore", func(t *testing.T) {
+		dirpath := filepath.Join(t.TempDir(), "engine")
+		legacy, err := kvstore.NewFS(dirpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range map[string]string{
+			"orchestra.state":       "credentials",
+			"sessionresolver.state": "old",
+		} {
+			if err := legacy.Set(key, []byte(value)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		kvs := &kvstore.Memory{}
+		if err := kvs.Set("sessionresolver.state", []byte("new")); err != nil {
+			t.Fatal(err)
+		}
+		if err := importLegacyKVStore(dirpath, kvs); err != nil {
+			t.Fatal(err)
+		}
+		for key, expected := range map[string]string{
+			"orchestra.state":       "credentials",
+			"sessionresolver.state": "new",
+		} {
+			value, err := kvs.Get(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(value) != expected {
+				t.Fatal("unexpected value", key, string(value))
+			}
+		}
+		if _, err := os.Stat(dirpath); !os.IsNotExist(err) {
+			t.Fatal("expected the legacy kvstore to be removed", err)
+		}
+	})
+}
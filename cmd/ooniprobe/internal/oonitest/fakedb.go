@@ -0,0 +1,169 @@
+package oonitest
+
+import (
+	"sort"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+// FakeDB fakes database.Actions using in-memory data. It only implements
+// the methods used by the tests and calling other methods panics.
+type FakeDB struct {
+	database.Actions
+
+	// FakeDeletedResults contains the IDs passed to DeleteResult.
+	FakeDeletedResults []int64
+
+	// FakeEvents contains the events returned by ListEvents.
+	FakeEvents []database.Event
+
+	// FakeFailedUploads maps the ID of the measurements passed to
+	// MeasurementUploadFailed to the failure.
+	FakeFailedUploads map[int64]string
+
+	// FakeFilters contains the filters passed to ListResultsMatching.
+	FakeFilters []*database.ResultFilter
+
+	// FakeMeasurements maps an ID to the measurement returned by
+	// GetMeasurement. ListPendingUploads returns all of them.
+	FakeMeasurements map[int64]*database.MeasurementURLNetwork
+
+	// FakeOrphans is returned by FindOrphans.
+	FakeOrphans *database.Orphans
+
+	// FakePendingUploads is returned by CountPendingUploads.
+	FakePendingUploads uint64
+
+	// FakeProblems is returned by CheckIntegrity.
+	FakeProblems []string
+
+	// FakeResults contains the done results returned by ListResults
+	// and, filtered by test group, by ListResultsMatching.
+	FakeResults []database.ResultNetwork
+
+	// FakeScheduleRuns maps a group name to its schedule run.
+	FakeScheduleRuns map[string]database.ScheduleRun
+
+	// FakeSucceededUploads contains the ID of the measurements
+	// passed to MeasurementUploadSucceeded.
+	FakeSucceededUploads []int64
+
+	// FakeUpdatedUploadedStatus contains the ID of the results
+	// passed to UpdateUploadedStatus.
+	FakeUpdatedUploadedStatus []int64
+}
+
+// CheckIntegrity implements database.Actions.CheckIntegrity.
+func (db *FakeDB) CheckIntegrity() ([]string, error) {
+	return db.FakeProblems, nil
+}
+
+// CountPendingUploads implements database.Actions.CountPendingUploads.
+func (db *FakeDB) CountPendingUploads() (uint64, error) {
+	return db.FakePendingUploads, nil
+}
+
+// DeleteResult implements database.Actions.DeleteResult.
+func (db *FakeDB) DeleteResult(resultID int64) error {
+	db.FakeDeletedResults = append(db.FakeDeletedResults, resultID)
+	return nil
+}
+
+// FindOrphans implements database.Actions.FindOrphans.
+func (db *FakeDB) FindOrphans(homePath string) (*database.Orphans, error) {
+	return db.FakeOrphans, nil
+}
+
+// GetMeasurement implements database.Actions.GetMeasurement.
+func (db *FakeDB) GetMeasurement(measurementID int64) (*database.MeasurementURLNetwork, error) {
+	return db.FakeMeasurements[measurementID], nil
+}
+
+// ListEvents implements database.Actions.ListEvents.
+func (db *FakeDB) ListEvents(filter *database.EventFilter) ([]database.Event, error) {
+	return db.FakeEvents, nil
+}
+
+// ListPendingUploads implements database.Actions.ListPendingUploads.
+func (db *FakeDB) ListPendingUploads() ([]database.Upload, error) {
+	var uploads []database.Upload
+	for id := range db.FakeMeasurements {
+		uploads = append(uploads, database.Upload{MeasurementID: id})
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		return uploads[i].MeasurementID < uploads[j].MeasurementID
+	})
+	return uploads, nil
+}
+
+// ListResults implements database.Actions.ListResults.
+func (db *FakeDB) ListResults() ([]database.ResultNetwork, []database.ResultNetwork, error) {
+	return db.FakeResults, nil, nil
+}
+
+// ListResultsMatching implements database.Actions.ListResultsMatching.
+func (db *FakeDB) ListResultsMatching(
+	filter *database.ResultFilter) ([]database.ResultNetwork, []database.ResultNetwork, error) {
+	db.FakeFilters = append(db.FakeFilters, filter)
+	var results []database.ResultNetwork
+	for _, result := range db.FakeResults {
+		if filter.TestGroupName == "" || result.TestGroupName == filter.TestGroupName {
+			results = append(results, result)
+		}
+	}
+	return results, nil, nil
+}
+
+// ListScheduleRuns implements database.Actions.ListScheduleRuns.
+func (db *FakeDB) ListScheduleRuns() ([]database.ScheduleRun, error) {
+	var runs []database.ScheduleRun
+	for _, run := range db.FakeScheduleRuns {
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].GroupName < runs[j].GroupName
+	})
+	return runs, nil
+}
+
+// MeasurementUploadFailed implements database.Actions.MeasurementUploadFailed.
+func (db *FakeDB) MeasurementUploadFailed(msmt *database.Measurement, failure string) error {
+	if db.FakeFailedUploads == nil {
+		db.FakeFailedUploads = make(map[int64]string)
+	}
+	db.FakeFailedUploads[msmt.ID] = failure
+	return nil
+}
+
+// MeasurementUploadSucceeded implements database.Actions.MeasurementUploadSucceeded.
+func (db *FakeDB) MeasurementUploadSucceeded(msmt *database.Measurement, collectorAddress string) error {
+	db.FakeSucceededUploads = append(db.FakeSucceededUploads, msmt.ID)
+	return nil
+}
+
+// ReadMeasurementFile implements database.Actions.ReadMeasurementFile.
+func (db *FakeDB) ReadMeasurementFile(path string) ([]byte, error) {
+	return database.ReadMeasurementFile(path, nil)
+}
+
+// SaveScheduleRun implements database.Actions.SaveScheduleRun.
+func (db *FakeDB) SaveScheduleRun(run *database.ScheduleRun) error {
+	if db.FakeScheduleRuns == nil {
+		db.FakeScheduleRuns = make(map[string]database.ScheduleRun)
+	}
+	db.FakeScheduleRuns[run.GroupName] = *run
+	return nil
+}
+
+// UpdateUploadedStatus implements database.Actions.UpdateUploadedStatus.
+func (db *FakeDB) UpdateUploadedStatus(result *database.Result) error {
+	db.FakeUpdatedUploadedStatus = append(db.FakeUpdatedUploadedStatus, result.ID)
+	return nil
+}
+
+// WriteMeasurementFile implements database.Actions.WriteMeasurementFile.
+func (db *FakeDB) WriteMeasurementFile(path string, data []byte) error {
+	return database.WriteMeasurementFile(path, data, nil)
+}
+
+var _ database.Actions = &FakeDB{}
@@ -7,9 +7,10 @@ import (
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/ooni"
+	"github.com/ooni/probe-cli/v3/internal/engine/geolocate"
 	"github.com/ooni/probe-cli/v3/internal/model"
-	"github.com/upper/db/v4"
 )
 
 // FakeOutput allows to fake the output package.
@@ -28,7 +29,7 @@ func (fo *FakeOutput) SectionTitle(s string) {
 // FakeProbeCLI fakes ooni.ProbeCLI
 type FakeProbeCLI struct {
 	FakeConfig         *config.Config
-	FakeDB             db.Session
+	FakeDB             database.Actions
 	FakeIsBatch        bool
 	FakeHome           string
 	FakeTempDir        string
@@ -42,7 +43,7 @@ func (cli *FakeProbeCLI) Config() *config.Config {
 }
 
 // DB implements ProbeCLI.DB
-func (cli *FakeProbeCLI) DB() db.Session {
+func (cli *FakeProbeCLI) DB() database.Actions {
 	return cli.FakeDB
 }
 
@@ -70,12 +71,18 @@ var _ ooni.ProbeCLI = &FakeProbeCLI{}
 
 // FakeProbeEngine fakes ooni.ProbeEngine
 type FakeProbeEngine struct {
-	FakeClose               error
-	FakeMaybeLookupLocation error
-	FakeProbeASNString      string
-	FakeProbeCC             string
-	FakeProbeIP             string
-	FakeProbeNetworkName    string
+	FakeClose                  error
+	FakeCrossCheckLocation     error
+	FakeMaybeLookupLocation    error
+	FakeProbeASNString         string
+	FakeProbeCC                string
+	FakeProbeIP                string
+	FakeProbeIPLookups         []geolocate.IPLookup
+	FakeProbeIPLookupsDisagree bool
+	FakeProbeNetworkName       string
+	FakeResolverASNString      string
+	FakeResolverIP             string
+	FakeResolverNetworkName    string
 }
 
 // Close implements ProbeEngine.Close
@@ -83,6 +90,11 @@ func (eng *FakeProbeEngine) Close() error {
 	return eng.FakeClose
 }
 
+// CrossCheckLocation implements ProbeEngine.CrossCheckLocation
+func (eng *FakeProbeEngine) CrossCheckLocation() error {
+	return eng.FakeCrossCheckLocation
+}
+
 // MaybeLookupLocation implements ProbeEngine.MaybeLookupLocation
 func (eng *FakeProbeEngine) MaybeLookupLocation() error {
 	return eng.FakeMaybeLookupLocation
@@ -103,11 +115,36 @@ func (eng *FakeProbeEngine) ProbeIP() string {
 	return eng.FakeProbeIP
 }
 
+// ProbeIPLookups implements ProbeEngine.ProbeIPLookups
+func (eng *FakeProbeEngine) ProbeIPLookups() []geolocate.IPLookup {
+	return eng.FakeProbeIPLookups
+}
+
+// ProbeIPLookupsDisagree implements ProbeEngine.ProbeIPLookupsDisagree
+func (eng *FakeProbeEngine) ProbeIPLookupsDisagree() bool {
+	return eng.FakeProbeIPLookupsDisagree
+}
+
 // ProbeNetworkName implements ProbeEngine.ProbeNetworkName
 func (eng *FakeProbeEngine) ProbeNetworkName() string {
 	return eng.FakeProbeNetworkName
 }
 
+// ResolverASNString implements ProbeEngine.ResolverASNString
+func (eng *FakeProbeEngine) ResolverASNString() string {
+	return eng.FakeResolverASNString
+}
+
+// ResolverIP implements ProbeEngine.ResolverIP
+func (eng *FakeProbeEngine) ResolverIP() string {
+	return eng.FakeResolverIP
+}
+
+// ResolverNetworkName implements ProbeEngine.ResolverNetworkName
+func (eng *FakeProbeEngine) ResolverNetworkName() string {
+	return eng.FakeResolverNetworkName
+}
+
 var _ ooni.ProbeEngine = &FakeProbeEngine{}
 
 // FakeLoggerHandler fakes apex.log.Handler.
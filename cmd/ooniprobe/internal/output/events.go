@@ -0,0 +1,78 @@
+package output
+
+//
+// Progress events.
+//
+// With --progress=json, the runner emits newline-delimited JSON events on
+// the standard output, which allows other programs to monitor long runs.
+// Each event has an "event" field containing its kind and a "time" field.
+//
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressEvents emits the progress events, if enabled.
+var progressEvents struct {
+	encoder *json.Encoder
+	mu      sync.Mutex
+}
+
+// EnableProgressEvents enables emitting the progress events on w.
+func EnableProgressEvents(w io.Writer) {
+	progressEvents.mu.Lock()
+	defer progressEvents.mu.Unlock()
+	progressEvents.encoder = json.NewEncoder(w)
+}
+
+// emitProgressEvent emits a progress event of the given kind, if
+// the progress events are enabled.
+func emitProgressEvent(kind string, fields map[string]interface{}) {
+	progressEvents.mu.Lock()
+	defer progressEvents.mu.Unlock()
+	if progressEvents.encoder == nil {
+		return
+	}
+	fields["event"] = kind
+	fields["time"] = time.Now().UTC()
+	// There's not much we can do if we cannot write the event.
+	_ = progressEvents.encoder.Encode(fields)
+}
+
+// ExperimentStarted emits the event of starting the experiment with the
+// given index among the count experiments of a group.
+func ExperimentStarted(testName string, index, count, numInputs int) {
+	emitProgressEvent("experiment_started", map[string]interface{}{
+		"test_name":  testName,
+		"index":      index,
+		"count":      count,
+		"num_inputs": numInputs,
+	})
+}
+
+// InputMeasured emits the event of measuring the input with the given
+// index, where failure is empty if the measurement succeeded.
+func InputMeasured(testName string, idx int, input string, measurementID int64, failure string) {
+	emitProgressEvent("input_measured", map[string]interface{}{
+		"test_name":      testName,
+		"idx":            idx,
+		"input":          input,
+		"measurement_id": measurementID,
+		"failure":        failure,
+	})
+}
+
+// UploadStatus emits the event of uploading the measurement of the
+// input with the given index, where failure is empty on success.
+func UploadStatus(testName string, idx int, measurementID int64, failure string) {
+	emitProgressEvent("upload_status", map[string]interface{}{
+		"test_name":      testName,
+		"idx":            idx,
+		"measurement_id": measurementID,
+		"uploaded":       failure == "",
+		"failure":        failure,
+	})
+}
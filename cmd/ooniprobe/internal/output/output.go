@@ -3,14 +3,23 @@ package output
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
 	"github.com/apex/log"
 	"github.com/mitchellh/go-wordwrap"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/doctor"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/status"
 )
 
+// Stdout is where we write the text for humans that does not go through
+// the logger (e.g., Paragraph). The --json mode sets it to the standard
+// error, such that the standard output only contains JSON.
+var Stdout io.Writer = os.Stdout
+
 // MeasurementJSON prints the JSON of a measurement
 func MeasurementJSON(j map[string]interface{}) {
 	log.WithFields(log.Fields{
@@ -27,6 +36,12 @@ func Progress(key string, perc float64, eta float64, msg string) {
 		"percentage": perc,
 		"eta":        eta,
 	}).Info(msg)
+	emitProgressEvent("progress", map[string]interface{}{
+		"key":        key,
+		"percentage": perc,
+		"eta":        eta,
+		"message":    msg,
+	})
 }
 
 // MeasurementSummaryData contains summary information on the measurement
@@ -60,6 +75,10 @@ func MeasurementSummary(msmt MeasurementSummaryData) {
 
 // MeasurementItem logs a progress type event
 func MeasurementItem(msmt database.MeasurementURLNetwork, isFirst bool, isLast bool) {
+	var rerunOfIsAnomaly bool
+	if msmt.RerunOfMeasurement != nil {
+		rerunOfIsAnomaly = msmt.RerunOfMeasurement.IsAnomaly.Bool
+	}
 	log.WithFields(log.Fields{
 		"type":     "measurement_item",
 		"is_first": isFirst,
@@ -81,14 +100,36 @@ func MeasurementItem(msmt database.MeasurementURLNetwork, isFirst bool, isLast b
 		"is_uploaded":           msmt.Measurement.IsUploaded,
 		"is_upload_failed":      msmt.IsUploadFailed,
 		"upload_failure_msg":    msmt.UploadFailureMsg.String,
-		"is_failed":             msmt.IsFailed,
-		"failure_msg":           msmt.FailureMsg.String,
+		"is_failed":             msmt.Measurement.IsFailed,
+		"failure_msg":           msmt.Measurement.FailureMsg.String,
 		"is_done":               msmt.Measurement.IsDone,
 		"report_file_path":      msmt.ReportFilePath.String,
 		"measurement_file_path": msmt.MeasurementFilePath.String,
+		"collector_address":     msmt.CollectorAddress.String,
+		"annotations":           msmt.Annotations,
+		"is_rerun":              msmt.IsRerun,
+		"rerun_of":              msmt.Measurement.RerunOf.Int64,
+		"rerun_of_is_anomaly":   rerunOfIsAnomaly,
 	}).Info("measurement")
 }
 
+// MeasurementPageData contains the metadata of a page of measurements
+type MeasurementPageData struct {
+	Count      int
+	TotalCount uint64
+	NextCursor string
+}
+
+// MeasurementPage emits the metadata of a page of measurements
+func MeasurementPage(page MeasurementPageData) {
+	log.WithFields(log.Fields{
+		"type":        "measurement_page",
+		"count":       page.Count,
+		"total_count": page.TotalCount,
+		"next_cursor": page.NextCursor,
+	}).Infof("%d of %d measurements", page.Count, page.TotalCount)
+}
+
 // ResultItemData is the metadata about a result
 type ResultItemData struct {
 	ID                      int64
@@ -103,6 +144,7 @@ type ResultItemData struct {
 	ASN                     uint
 	Done                    bool
 	IsUploaded              bool
+	IsPartial               bool
 	DataUsageDown           float64
 	DataUsageUp             float64
 	Index                   int
@@ -125,6 +167,7 @@ func ResultItem(result ResultItemData) {
 		"runtime":                   result.Runtime,
 		"is_done":                   result.Done,
 		"is_uploaded":               result.IsUploaded,
+		"is_partial":                result.IsPartial,
 		"data_usage_down":           result.DataUsageDown,
 		"data_usage_up":             result.DataUsageUp,
 		"index":                     result.Index,
@@ -138,6 +181,7 @@ type ResultSummaryData struct {
 	TotalDataUsageUp   float64
 	TotalDataUsageDown float64
 	TotalNetworks      int64
+	PendingUploads     uint64
 }
 
 // ResultSummary emits the result summary
@@ -148,9 +192,260 @@ func ResultSummary(result ResultSummaryData) {
 		"total_data_usage_up":   result.TotalDataUsageUp,
 		"total_data_usage_down": result.TotalDataUsageDown,
 		"total_networks":        result.TotalNetworks,
+		"pending_uploads":       result.PendingUploads,
 	}).Info("result summary")
 }
 
+// StatsItemData contains the anomaly stats of a group of measurements
+type StatsItemData struct {
+	GroupBy      string
+	Key          string
+	TotalCount   uint64
+	AnomalyCount uint64
+	AnomalyRate  float64
+}
+
+// StatsItem emits the anomaly stats of a group of measurements
+func StatsItem(item StatsItemData) {
+	log.WithFields(log.Fields{
+		"type":          "stats_item",
+		"group_by":      item.GroupBy,
+		"key":           item.Key,
+		"total_count":   item.TotalCount,
+		"anomaly_count": item.AnomalyCount,
+		"anomaly_rate":  item.AnomalyRate,
+	}).Infof("%s: %d/%d anomalies (%.1f%%)", item.Key,
+		item.AnomalyCount, item.TotalCount, item.AnomalyRate*100)
+}
+
+// NetworkHistoryItemData contains the history of a network
+type NetworkHistoryItemData struct {
+	ASN          uint
+	CountryCode  string
+	NetworkName  string
+	FirstSeen    time.Time
+	LastSeen     time.Time
+	RunCount     uint64
+	TotalCount   uint64
+	AnomalyCount uint64
+	AnomalyRate  float64
+	Trend        float64
+}
+
+// NetworkHistoryItem emits the history of a network
+func NetworkHistoryItem(item NetworkHistoryItemData) {
+	log.WithFields(log.Fields{
+		"type":                 "network_history_item",
+		"asn":                  item.ASN,
+		"network_country_code": item.CountryCode,
+		"network_name":         item.NetworkName,
+		"first_seen":           item.FirstSeen,
+		"last_seen":            item.LastSeen,
+		"run_count":            item.RunCount,
+		"total_count":          item.TotalCount,
+		"anomaly_count":        item.AnomalyCount,
+		"anomaly_rate":         item.AnomalyRate,
+		"trend":                item.Trend,
+	}).Infof("AS%d (%s, %s): %d runs between %s and %s, %d/%d anomalies (%.1f%%, trend %+.1f%%)",
+		item.ASN, item.NetworkName, item.CountryCode, item.RunCount,
+		item.FirstSeen.Format("2006-01-02"), item.LastSeen.Format("2006-01-02"),
+		item.AnomalyCount, item.TotalCount, item.AnomalyRate*100, item.Trend*100)
+}
+
+// EventItem emits a lifecycle event
+func EventItem(event database.Event) {
+	resultStr := ""
+	if event.ResultID.Valid {
+		resultStr = fmt.Sprintf(" #%d", event.ResultID.Int64)
+	}
+	log.WithFields(log.Fields{
+		"type":      "event_item",
+		"id":        event.ID,
+		"time":      event.Time,
+		"kind":      event.Kind,
+		"result_id": event.ResultID.Int64,
+		"details":   event.Details,
+	}).Infof("%s %s%s %s", event.Time.Local().Format("2006-01-02 15:04:05"),
+		event.Kind, resultStr, event.Details)
+}
+
+// ConfigProblem emits a problem of the config file
+func ConfigProblem(problem config.Problem) {
+	log.WithFields(log.Fields{
+		"type":     "config_problem",
+		"line":     problem.Line,
+		"column":   problem.Column,
+		"key":      problem.Key,
+		"severity": problem.Severity,
+		"message":  problem.Message,
+	}).Infof("%s: %s", problem.Severity, problem)
+}
+
+// DoctorFinding emits the outcome of a check of `ooniprobe doctor`
+func DoctorFinding(finding doctor.Finding) {
+	log.WithFields(log.Fields{
+		"type":     "doctor_finding",
+		"check":    finding.Check,
+		"severity": finding.Severity,
+		"message":  finding.Message,
+		"advice":   finding.Advice,
+	}).Infof("%s: %s", finding.Check, finding.Message)
+}
+
+// StatusGroup emits the status of a test group for `ooniprobe status`
+func StatusGroup(gs status.GroupStatus) {
+	fields := log.Fields{
+		"type":            "status_group",
+		"test_group_name": gs.GroupName,
+		"schedule":        gs.Schedule,
+	}
+	lastRun := "never run"
+	if !gs.LastRunTime.IsZero() {
+		fields["last_result_id"] = gs.LastResultID
+		fields["last_run_time"] = gs.LastRunTime
+		lastRun = fmt.Sprintf("last run %s (#%d)",
+			gs.LastRunTime.Local().Format("2006-01-02 15:04"), gs.LastResultID)
+	}
+	nextRun := ""
+	if !gs.NextRunTime.IsZero() {
+		fields["next_run_time"] = gs.NextRunTime
+		nextRun = fmt.Sprintf(", next run %s", gs.NextRunTime.Local().Format("2006-01-02 15:04"))
+	} else if gs.Schedule != "" {
+		nextRun = ", not scheduled yet"
+	}
+	log.WithFields(fields).Infof("%s: %s%s", gs.GroupName, lastRun, nextRun)
+}
+
+// StatusSummary emits the status of the probe for `ooniprobe status`
+func StatusSummary(s *status.Status, daemonEnabled bool) {
+	backend := "not checked"
+	if s.BackendChecked {
+		backend = "reachable"
+		if s.BackendFailure != "" {
+			backend = "unreachable: " + s.BackendFailure
+		}
+	}
+	log.WithFields(log.Fields{
+		"type":            "status_summary",
+		"pending_uploads": s.PendingUploads,
+		"home_size":       s.HomeSize,
+		"daemon_enabled":  daemonEnabled,
+		"backend_checked": s.BackendChecked,
+		"backend_failure": s.BackendFailure,
+		"crash_dumps":     len(s.CrashDumps),
+	}).Infof("%d pending uploads, %.1f MiB in the OONI home, backend %s",
+		s.PendingUploads, float64(s.HomeSize)/(1<<20), backend)
+	if len(s.CrashDumps) > 0 {
+		log.WithFields(log.Fields{
+			"type":        "status_crash_dumps",
+			"crash_dumps": s.CrashDumps,
+		}).Warnf("ooniprobe crashed %d times, most recently: %s (use --crash-report to attach it to a bug report)",
+			len(s.CrashDumps), s.CrashDumps[0])
+	}
+}
+
+// ConfigValue emits the effective value of a setting
+func ConfigValue(value config.Value) {
+	defaultStr := ""
+	if value.IsDefault {
+		defaultStr = " (default)"
+	}
+	log.WithFields(log.Fields{
+		"type":       "config_value",
+		"key":        value.Key,
+		"value":      value.Value,
+		"is_default": value.IsDefault,
+	}).Infof("%s = %s%s", value.Key, value.Value, defaultStr)
+}
+
+// DryRunItem emits the inputs an experiment would measure
+func DryRunItem(testName string, inputs []string, maxRuntime time.Duration) {
+	message := fmt.Sprintf("%s: %d measurements", testName, len(inputs))
+	if maxRuntime > 0 {
+		message += fmt.Sprintf(" (stopping after %s)", maxRuntime)
+	}
+	log.WithFields(log.Fields{
+		"type":        "dry_run_item",
+		"test_name":   testName,
+		"inputs":      inputs,
+		"max_runtime": maxRuntime.Seconds(),
+	}).Info(message)
+}
+
+// DryRunSummaryData contains what we would measure running a group
+type DryRunSummaryData struct {
+	GroupName        string
+	Label            string
+	ExperimentCount  int
+	MeasurementCount int
+	EstimatedKiB     float64 // negative when unknown
+}
+
+// DryRunSummary emits what we would measure running a group
+func DryRunSummary(summary DryRunSummaryData) {
+	usage := "unknown data usage"
+	if summary.EstimatedKiB >= 0 {
+		usage = fmt.Sprintf("about %.1f MiB of data", summary.EstimatedKiB/1024)
+	}
+	log.WithFields(log.Fields{
+		"type":              "dry_run_summary",
+		"test_group_name":   summary.GroupName,
+		"experiment_count":  summary.ExperimentCount,
+		"measurement_count": summary.MeasurementCount,
+		"estimated_kib":     summary.EstimatedKiB,
+	}).Infof("%s: %d experiments, %d measurements, %s", summary.Label,
+		summary.ExperimentCount, summary.MeasurementCount, usage)
+}
+
+// UploadSummaryData contains the outcome of uploading the pending measurements
+type UploadSummaryData struct {
+	Uploaded  int
+	Failed    int
+	Remaining int
+}
+
+// UploadSummary emits the outcome of uploading the pending measurements
+func UploadSummary(summary UploadSummaryData) {
+	log.WithFields(log.Fields{
+		"type":      "upload_summary",
+		"uploaded":  summary.Uploaded,
+		"failed":    summary.Failed,
+		"remaining": summary.Remaining,
+	}).Infof("Uploaded %d measurements, failed to upload %d measurements, %d measurements left",
+		summary.Uploaded, summary.Failed, summary.Remaining)
+}
+
+// MeasureVerdict emits the verdict of `ooniprobe measure`
+func MeasureVerdict(testName string, input string, anomaly bool, verdict string, path string, explorerURL string) {
+	log.WithFields(log.Fields{
+		"type":         "measure_verdict",
+		"test_name":    testName,
+		"input":        input,
+		"is_anomaly":   anomaly,
+		"verdict":      verdict,
+		"path":         path,
+		"explorer_url": explorerURL,
+	}).Infof("%s %s: %s", testName, input, verdict)
+}
+
+// ExplorerLink emits the OONI Explorer URL of a measurement
+func ExplorerLink(measurementID int64, testName string, URL string) {
+	log.WithFields(log.Fields{
+		"type":           "explorer_link",
+		"measurement_id": measurementID,
+		"test_name":      testName,
+		"url":            URL,
+	}).Infof("#%d %s: %s", measurementID, testName, URL)
+}
+
+// Version emits the version of ooniprobe
+func Version(version string) {
+	log.WithFields(log.Fields{
+		"type":    "version",
+		"version": version,
+	}).Info(version)
+}
+
 // SectionTitle is the title of a section
 func SectionTitle(text string) {
 	log.WithFields(log.Fields{
@@ -162,13 +457,13 @@ func SectionTitle(text string) {
 // Paragraph makes a word-wrapped paragraph out of text
 func Paragraph(text string) {
 	const width = 80
-	fmt.Println(wordwrap.WrapString(text, width))
+	fmt.Fprintln(Stdout, wordwrap.WrapString(text, width))
 }
 
 // Bullet is like paragraph but with a bullet point in front
 func Bullet(text string) {
 	const width = 80
-	fmt.Printf("• %s\n", wordwrap.WrapString(text, width))
+	fmt.Fprintf(Stdout, "• %s\n", wordwrap.WrapString(text, width))
 }
 
 // PressAnyKeyToContinue blocks until the user presses any key
@@ -0,0 +1,24 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed report.html
+var reportHTML string
+
+// reportTemplate is the template of the HTML report, which embeds
+// the styles, such that the report is a standalone file.
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+}).Parse(reportHTML))
+
+// WriteHTML writes the report as a standalone HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
@@ -0,0 +1,67 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTML(t *testing.T) {
+	startTime := time.Date(2022, 3, 7, 10, 17, 30, 0, time.UTC)
+	r := &Report{
+		ResultID:      17,
+		TestGroupName: "performance",
+		StartTime:     startTime,
+		IsDone:        true,
+		NetworkName:   "Vodafone Italia",
+		ASN:           30722,
+		CountryCode:   "IT",
+		Measurements: []Measurement{{
+			ID:        1,
+			TestName:  "ndt",
+			StartTime: startTime,
+			Status:    "ok",
+			Details:   "download 10.00 Mbit/s",
+		}, {
+			ID:        2,
+			TestName:  "web_connectivity",
+			URL:       "https://example.com/?a=<script>",
+			StartTime: startTime,
+			Status:    "anomaly",
+		}},
+		Charts: []Chart{{
+			Title: "Download",
+			Bars: []Bar{
+				{Label: "2022-03-06", Value: 20000, Text: "20.00 Mbit/s"},
+				{Label: "2022-03-07", Value: 10000, Text: "10.00 Mbit/s", IsCurrent: true},
+			},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := r.WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, expected := range []string{
+		"<title>OONI Probe result #17 (performance)</title>",
+		"Vodafone Italia (AS30722, IT)",
+		"2022-03-07 10:17:30 UTC",
+		`<td class="anomaly">anomaly</td>`,
+		"https://example.com/?a=&lt;script&gt;",
+		`<div class="bar current">`,
+		`style="width: 100.0%"`,
+		`style="width: 50.0%"`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Fatalf("cannot find %q in the report", expected)
+		}
+	}
+}
+
+func TestChartWidth(t *testing.T) {
+	chart := Chart{Bars: []Bar{{Value: 0}, {Value: 0}}}
+	if width := chart.Width(chart.Bars[0]); width != 0 {
+		t.Fatal("unexpected width", width)
+	}
+}
@@ -0,0 +1,162 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/dash"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/ndt7"
+	"github.com/ooni/probe-cli/v3/internal/engine/experiment/webconnectivity"
+)
+
+// ErrEmptyResult indicates that a result has no measurements.
+var ErrEmptyResult = errors.New("report: result without measurements")
+
+// maxChartBars is the maximum number of results of a chart.
+const maxChartBars = 10
+
+// formatSpeed formats a speed in kbit/s.
+func formatSpeed(speed float64) string {
+	if speed < 1000 {
+		return fmt.Sprintf("%.2f Kbit/s", speed)
+	}
+	return fmt.Sprintf("%.2f Mbit/s", speed/1000)
+}
+
+// New loads the report of the given result from the database.
+func New(actions database.Actions, resultID int64) (*Report, error) {
+	measurements, err := actions.ListMeasurements(resultID)
+	if err != nil {
+		return nil, err
+	}
+	if len(measurements) <= 0 {
+		return nil, fmt.Errorf("%w: #%d", ErrEmptyResult, resultID)
+	}
+	first := measurements[0]
+	r := &Report{
+		ResultID:      resultID,
+		TestGroupName: first.Result.TestGroupName,
+		StartTime:     first.Result.StartTime,
+		Runtime:       first.Result.Runtime,
+		IsDone:        first.Result.IsDone,
+		NetworkName:   first.Network.NetworkName,
+		ASN:           first.Network.ASN,
+		CountryCode:   first.Network.CountryCode,
+		DataUsageUp:   first.DataUsageUp,
+		DataUsageDown: first.DataUsageDown,
+		GeneratedAt:   time.Now(),
+	}
+	if first.SoftwareName.Valid {
+		r.Software = first.SoftwareName.String + " " + first.SoftwareVersion.String
+	}
+	for _, msmt := range measurements {
+		m := Measurement{
+			ID:           msmt.Measurement.ID,
+			TestName:     msmt.TestName,
+			URL:          msmt.URL.URL.String,
+			CategoryCode: msmt.URL.CategoryCode.String,
+			StartTime:    msmt.Measurement.StartTime,
+			Runtime:      msmt.Measurement.Runtime,
+			Status:       "ok",
+			Details:      summarize(&msmt.Measurement),
+			IsUploaded:   msmt.Measurement.IsUploaded,
+		}
+		switch {
+		case msmt.Measurement.IsFailed:
+			m.Status = "failed"
+			m.Details = msmt.Measurement.FailureMsg.String
+		case msmt.IsAnomaly.Bool:
+			m.Status = "anomaly"
+			r.AnomalyCount++
+		}
+		r.Measurements = append(r.Measurements, m)
+	}
+	if r.TestGroupName == "performance" {
+		if r.Charts, err = performanceCharts(actions, r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// summarize returns the summary of the test keys of a measurement,
+// or an empty string when there is nothing worth showing.
+func summarize(msmt *database.Measurement) string {
+	tk, err := msmt.DecodeTestKeys()
+	if err != nil {
+		return ""
+	}
+	switch v := tk.(type) {
+	case *ndt7.SummaryKeys:
+		return fmt.Sprintf("download %s, upload %s, ping %.0f ms",
+			formatSpeed(v.Download), formatSpeed(v.Upload), v.Ping)
+	case *dash.SummaryKeys:
+		return fmt.Sprintf("median bitrate %s, playout delay %.2f s",
+			formatSpeed(v.Bitrate), v.Delay)
+	case *webconnectivity.SummaryKeys:
+		if v.Blocking != "" {
+			return "blocking: " + v.Blocking
+		}
+	}
+	return ""
+}
+
+// performanceCharts returns the charts comparing the given result with
+// the previous results of the performance group on the same network.
+func performanceCharts(actions database.Actions, r *Report) ([]Chart, error) {
+	results, _, err := actions.ListResultsMatching(&database.ResultFilter{
+		TestGroupName: r.TestGroupName,
+		ASN:           r.ASN,
+		CountryCode:   r.CountryCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for idx, result := range results {
+		if result.Result.ID == r.ResultID {
+			results = results[:idx+1] // ignore the following results
+			break
+		}
+	}
+	if len(results) > maxChartBars {
+		results = results[len(results)-maxChartBars:]
+	}
+	charts := []Chart{{Title: "Download"}, {Title: "Upload"}, {Title: "Ping"}, {Title: "Video streaming"}}
+	for _, result := range results {
+		label := result.StartTime.UTC().Format("2006-01-02 15:04")
+		isCurrent := result.Result.ID == r.ResultID
+		if result.Download.Valid {
+			charts[0].Bars = append(charts[0].Bars, Bar{
+				Label: label, Value: result.Download.Float64,
+				Text: formatSpeed(result.Download.Float64), IsCurrent: isCurrent,
+			})
+		}
+		if result.Upload.Valid {
+			charts[1].Bars = append(charts[1].Bars, Bar{
+				Label: label, Value: result.Upload.Float64,
+				Text: formatSpeed(result.Upload.Float64), IsCurrent: isCurrent,
+			})
+		}
+		if result.Ping.Valid {
+			charts[2].Bars = append(charts[2].Bars, Bar{
+				Label: label, Value: result.Ping.Float64,
+				Text: fmt.Sprintf("%.0f ms", result.Ping.Float64), IsCurrent: isCurrent,
+			})
+		}
+		if result.MedianBitrate.Valid {
+			charts[3].Bars = append(charts[3].Bars, Bar{
+				Label: label, Value: result.MedianBitrate.Float64,
+				Text: formatSpeed(result.MedianBitrate.Float64), IsCurrent: isCurrent,
+			})
+		}
+	}
+	var out []Chart
+	for _, chart := range charts {
+		if len(chart.Bars) > 0 {
+			out = append(out, chart)
+		}
+	}
+	return out, nil
+}
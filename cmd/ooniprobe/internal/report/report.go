@@ -0,0 +1,123 @@
+// Package report renders a result as a standalone HTML report, which
+// users can share without using the OONI Explorer (see `ooniprobe show`).
+package report
+
+import "time"
+
+// Report contains the data of the report of a result.
+type Report struct {
+	// ResultID is the ID of the result.
+	ResultID int64
+
+	// TestGroupName is the name of the test group of the result.
+	TestGroupName string
+
+	// StartTime is when the result started.
+	StartTime time.Time
+
+	// Runtime is the runtime of the result in seconds.
+	Runtime float64
+
+	// IsDone indicates whether the result is done.
+	IsDone bool
+
+	// NetworkName is the name of the network.
+	NetworkName string
+
+	// ASN is the ASN of the network.
+	ASN uint
+
+	// CountryCode is the country code of the network.
+	CountryCode string
+
+	// DataUsageUp and DataUsageDown are the data usage of
+	// the result in KiB.
+	DataUsageUp, DataUsageDown float64
+
+	// Software is the OPTIONAL name and version of the
+	// software that produced the result.
+	Software string
+
+	// AnomalyCount is the number of anomalous measurements.
+	AnomalyCount int
+
+	// Measurements contains the measurements of the result.
+	Measurements []Measurement
+
+	// Charts contains the OPTIONAL charts of the report, which
+	// we only produce for the results of the performance group.
+	Charts []Chart
+
+	// GeneratedAt is when we generated the report.
+	GeneratedAt time.Time
+}
+
+// Measurement is a measurement of the report.
+type Measurement struct {
+	// ID is the ID of the measurement.
+	ID int64
+
+	// TestName is the name of the test.
+	TestName string
+
+	// URL is the OPTIONAL URL of the measurement.
+	URL string
+
+	// CategoryCode is the OPTIONAL category of the URL.
+	CategoryCode string
+
+	// StartTime is when the measurement started.
+	StartTime time.Time
+
+	// Runtime is the runtime of the measurement in seconds.
+	Runtime float64
+
+	// Status is one of "ok", "anomaly", and "failed".
+	Status string
+
+	// Details is the OPTIONAL summary of the test keys.
+	Details string
+
+	// IsUploaded indicates whether we uploaded the measurement.
+	IsUploaded bool
+}
+
+// Chart is a bar chart comparing the results of a network.
+type Chart struct {
+	// Title is the title of the chart.
+	Title string
+
+	// Bars contains the bars of the chart in chronological order.
+	Bars []Bar
+}
+
+// Bar is a bar of a chart.
+type Bar struct {
+	// Label is the label of the bar.
+	Label string
+
+	// Value is the value of the bar.
+	Value float64
+
+	// Text is the value of the bar formatted for humans.
+	Text string
+
+	// IsCurrent indicates whether the bar refers to the
+	// result of the report.
+	IsCurrent bool
+}
+
+// Width returns the width of the given bar as a percentage of the
+// width of the longest bar of the chart.
+func (c Chart) Width(bar Bar) float64 {
+	var max float64
+	for _, b := range c.Bars {
+		if b.Value > max {
+			max = b.Value
+		}
+	}
+	if max <= 0 {
+		return 0
+	}
+	return 100 * bar.Value / max
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package selfupdate
+
+import "os"
+
+// replaceExecutable replaces the executable with the new file. On Unix
+// renaming is atomic and the running process keeps using the old inode.
+func replaceExecutable(executable, newfile string) error {
+	return os.Rename(newfile, executable)
+}
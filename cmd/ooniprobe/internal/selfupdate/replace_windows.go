@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package selfupdate
+
+import "os"
+
+// replaceExecutable replaces the executable with the new file. On Windows
+// we cannot overwrite a running executable but we can rename it, so we
+// move it aside, and we put it back if we cannot install the new file.
+func replaceExecutable(executable, newfile string) error {
+	old := executable + ".old"
+	os.Remove(old) // left behind by a previous update
+	if err := os.Rename(executable, old); err != nil {
+		return err
+	}
+	if err := os.Rename(newfile, executable); err != nil {
+		os.Rename(old, executable)
+		return err
+	}
+	// We cannot remove the running executable, hence the
+	// next update will remove it (see above).
+	return nil
+}
@@ -0,0 +1,356 @@
+// Package selfupdate updates ooniprobe to its latest release (see
+// `ooniprobe update`). We only install binaries having a valid GPG
+// signature made using the release signing key.
+//
+// The signature covers the binary prefixed by a line containing the
+// version and the name of the binary, such that one cannot serve the
+// signed binary of a previous release or of another platform:
+//
+//	(printf "ooniprobe %s %s\n" 3.16.0 ooniprobe-linux-amd64;
+//	 cat ooniprobe-linux-amd64) | gpg --armor --detach-sign \
+//	 > ooniprobe-linux-amd64.asc
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// DefaultReleaseURL is the URL of the GitHub API returning the latest release.
+const DefaultReleaseURL = "https://api.github.com/repos/ooni/probe-cli/releases/latest"
+
+// maxBinarySize is the maximum size of the binary we download.
+const maxBinarySize = 128 << 20
+
+// maxResponseSize is the maximum size of the other responses.
+const maxResponseSize = 1 << 20
+
+var (
+	// ErrNoSigningKey indicates that this build cannot verify the
+	// releases, hence it cannot update itself.
+	ErrNoSigningKey = errors.New("selfupdate: no release signing key")
+
+	// ErrNoBinary indicates that the release does not contain a
+	// binary for the platform we are running on.
+	ErrNoBinary = errors.New("selfupdate: no binary for this platform")
+
+	// ErrInvalidSignature indicates that the signature of the
+	// binary we downloaded is missing or invalid.
+	ErrInvalidSignature = errors.New("selfupdate: invalid signature")
+
+	// ErrHTTPStatus indicates that a request failed.
+	ErrHTTPStatus = errors.New("selfupdate: unexpected HTTP status")
+
+	// ErrTooLarge indicates that a response exceeds the maximum size.
+	ErrTooLarge = errors.New("selfupdate: response too large")
+)
+
+// Config contains the settings of the updater.
+type Config struct {
+	// HTTPClient is the OPTIONAL HTTP client to use.
+	HTTPClient *http.Client
+
+	// ReleaseURL is the OPTIONAL URL returning the latest release.
+	ReleaseURL string
+
+	// SigningKey is the armored GPG public key signing the
+	// releases. When empty, we can only check for updates.
+	SigningKey string
+
+	// Executable is the OPTIONAL path of the executable to replace.
+	Executable string
+
+	// GOOS and GOARCH OPTIONALLY override the platform.
+	GOOS   string
+	GOARCH string
+}
+
+// Release is a release of ooniprobe.
+type Release struct {
+	// Version is the version (e.g., "3.16.0").
+	Version string
+
+	// URL is the URL of the release notes.
+	URL string
+
+	// BinaryURL and SignatureURL are the URLs of the binary for
+	// the platform we are running on and of its signature, which
+	// are empty when the release does not contain them.
+	BinaryURL    string
+	SignatureURL string
+}
+
+// IsNewerThan returns whether the release is newer than the given version.
+func (r *Release) IsNewerThan(version string) bool {
+	return compareVersions(r.Version, version) > 0
+}
+
+// Updater updates ooniprobe.
+type Updater struct {
+	config Config
+}
+
+// New creates a new updater.
+func New(config Config) *Updater {
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.ReleaseURL == "" {
+		config.ReleaseURL = DefaultReleaseURL
+	}
+	if config.GOOS == "" {
+		config.GOOS = runtime.GOOS
+	}
+	if config.GOARCH == "" {
+		config.GOARCH = runtime.GOARCH
+	}
+	return &Updater{config: config}
+}
+
+// CanUpdate returns whether this build can update itself.
+func (u *Updater) CanUpdate() bool {
+	return u.config.SigningKey != ""
+}
+
+// githubRelease is the subset of a GitHub release we use.
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// Check returns the latest release.
+func (u *Updater) Check(ctx context.Context) (*Release, error) {
+	data, err := u.get(ctx, u.config.ReleaseURL, maxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+	var latest githubRelease
+	if err := json.Unmarshal(data, &latest); err != nil {
+		return nil, err
+	}
+	release := &Release{
+		Version: strings.TrimPrefix(latest.TagName, "v"),
+		URL:     latest.HTMLURL,
+	}
+	name := binaryName(u.config.GOOS, u.config.GOARCH)
+	for _, asset := range latest.Assets {
+		switch asset.Name {
+		case name:
+			release.BinaryURL = asset.BrowserDownloadURL
+		case name + ".asc":
+			release.SignatureURL = asset.BrowserDownloadURL
+		}
+	}
+	return release, nil
+}
+
+// Update downloads the binary of the given release, verifies its
+// signature, and replaces the executable with it.
+func (u *Updater) Update(ctx context.Context, release *Release) error {
+	if !u.CanUpdate() {
+		return ErrNoSigningKey
+	}
+	if release.BinaryURL == "" {
+		return ErrNoBinary
+	}
+	if release.SignatureURL == "" {
+		return ErrInvalidSignature
+	}
+	binary, err := u.get(ctx, release.BinaryURL, maxBinarySize)
+	if err != nil {
+		return err
+	}
+	signature, err := u.get(ctx, release.SignatureURL, maxResponseSize)
+	if err != nil {
+		return err
+	}
+	if err := u.verify(release.Version, binary, signature); err != nil {
+		return err
+	}
+	executable := u.config.Executable
+	if executable == "" {
+		if executable, err = os.Executable(); err != nil {
+			return err
+		}
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return err
+	}
+	return install(executable, binary)
+}
+
+// signedHeader returns the line preceding the binary of the given
+// version and platform in the signed message.
+func signedHeader(version, goos, goarch string) string {
+	return fmt.Sprintf("ooniprobe %s %s\n", version, binaryName(goos, goarch))
+}
+
+// verify verifies the detached signature of the binary of the given
+// version, which also covers the version and the binary name.
+func (u *Updater) verify(version string, binary, signature []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(u.config.SigningKey))
+	if err != nil {
+		return err
+	}
+	signed := io.MultiReader(
+		strings.NewReader(signedHeader(version, u.config.GOOS, u.config.GOARCH)),
+		bytes.NewReader(binary),
+	)
+	_, err = openpgp.CheckArmoredDetachedSignature(keyring, signed, bytes.NewReader(signature), nil)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, err.Error())
+	}
+	return nil
+}
+
+// get fetches the given URL, failing if the body is larger than maxSize.
+func (u *Updater) get(ctx context.Context, URL string, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrHTTPStatus, resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, resp.ContentLength)
+	}
+	// We read one more byte to detect bodies larger than maxSize.
+	data, err := netxlite.ReadAllContext(ctx, io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxSize)
+	}
+	return data, nil
+}
+
+// install atomically replaces the executable with the binary by
+// writing the binary next to the executable and renaming it.
+func install(executable string, binary []byte) error {
+	dir, name := filepath.Split(executable)
+	tmp, err := os.CreateTemp(dir, "."+name+".new-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails once we have renamed it
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	return replaceExecutable(executable, tmp.Name())
+}
+
+// binaryName returns the name of the release asset containing
+// the binary for the given platform (see the ./mk file).
+func binaryName(goos, goarch string) string {
+	if goarch == "arm" {
+		goarch = "armv7"
+	}
+	name := fmt.Sprintf("ooniprobe-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// compareVersions compares versions such as "3.16.0" and "3.16.0-alpha",
+// where a prerelease precedes the corresponding release, returning a
+// negative number, zero, or a positive number like strings.Compare. We
+// compare prereleases using the semver rules (see comparePrereleases).
+func compareVersions(a, b string) int {
+	coreA, preA := splitVersion(a)
+	coreB, preB := splitVersion(b)
+	for idx := 0; idx < len(coreA) || idx < len(coreB); idx++ {
+		var numA, numB int
+		if idx < len(coreA) {
+			numA, _ = strconv.Atoi(coreA[idx])
+		}
+		if idx < len(coreB) {
+			numB, _ = strconv.Atoi(coreB[idx])
+		}
+		if numA != numB {
+			return numA - numB
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	default:
+		return comparePrereleases(preA, preB)
+	}
+}
+
+// comparePrereleases compares prereleases such as "rc.9" and "rc.10" by
+// comparing their dot-separated identifiers from left to right, where we
+// compare numeric identifiers numerically, numeric identifiers precede the
+// other identifiers, and a prefix precedes the longer prerelease.
+func comparePrereleases(a, b string) int {
+	idsA, idsB := strings.Split(a, "."), strings.Split(b, ".")
+	for idx := 0; idx < len(idsA) && idx < len(idsB); idx++ {
+		numA, errA := strconv.ParseUint(idsA[idx], 10, 64)
+		numB, errB := strconv.ParseUint(idsB[idx], 10, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA < numB {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			return -1
+		case errB == nil:
+			return 1
+		default:
+			if result := strings.Compare(idsA[idx], idsB[idx]); result != 0 {
+				return result
+			}
+		}
+	}
+	return len(idsA) - len(idsB)
+}
+
+// splitVersion splits a version into its numeric components
+// and its prerelease suffix, if any.
+func splitVersion(version string) ([]string, string) {
+	version = strings.TrimPrefix(version, "v")
+	core, pre := version, ""
+	if idx := strings.Index(version, "-"); idx >= 0 {
+		core, pre = version[:idx], version[idx+1:]
+	}
+	return strings.Split(core, "."), pre
+}
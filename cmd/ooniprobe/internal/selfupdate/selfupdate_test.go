@@ -0,0 +1,250 @@
+package selfupdate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func TestCompareVersions(t *testing.T) {
+	expectations := []struct {
+		a, b     string
+		expected int
+	}{
+		{"3.16.0", "3.16.0", 0},
+		{"v3.16.0", "3.16.0", 0},
+		{"3.16.1", "3.16.0", 1},
+		{"3.16.0", "3.9.2", 1},
+		{"3.16.0", "3.16.0-alpha", 1},
+		{"3.16.0-alpha", "3.16.0-beta", -1},
+		{"3.15.3", "3.16.0-alpha", -1},
+		{"4", "3.16.0", 1},
+		{"3.16.0-rc.10", "3.16.0-rc.9", 1},
+		{"3.16.0-rc.2", "3.16.0-rc.10", -1},
+		{"3.16.0-rc.1", "3.16.0-rc.1", 0},
+		{"3.16.0-alpha", "3.16.0-alpha.1", -1},
+		{"3.16.0-alpha.1", "3.16.0-alpha.beta", -1},
+		{"3.16.0-beta.11", "3.16.0-rc.1", -1},
+		{"3.16.0-1", "3.16.0-alpha", -1},
+	}
+	for _, e := range expectations {
+		result := compareVersions(e.a, e.b)
+		if (result > 0) != (e.expected > 0) || (result < 0) != (e.expected < 0) {
+			t.Fatal("unexpected result", e.a, e.b, result)
+		}
+	}
+}
+
+func TestBinaryName(t *testing.T) {
+	expectations := map[[2]string]string{
+		{"linux", "amd64"}:   "ooniprobe-linux-amd64",
+		{"linux", "arm"}:     "ooniprobe-linux-armv7",
+		{"darwin", "arm64"}:  "ooniprobe-darwin-arm64",
+		{"windows", "amd64"}: "ooniprobe-windows-amd64.exe",
+	}
+	for platform, expected := range expectations {
+		if name := binaryName(platform[0], platform[1]); name != expected {
+			t.Fatal("unexpected name", platform, name)
+		}
+	}
+}
+
+// newSigningKey returns a new signing entity and its armored public key.
+func newSigningKey(t *testing.T) (*openpgp.Entity, string) {
+	entity, err := openpgp.NewEntity("OONI", "testing", "contact@openobservatory.org", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return entity, buf.String()
+}
+
+// newReleaseServer serves the v3.16.0 release containing the given
+// binary, which signer signed claiming it is the given version.
+func newReleaseServer(t *testing.T, binary []byte, signer *openpgp.Entity, version string) *httptest.Server {
+	var signature bytes.Buffer
+	signed := io.MultiReader(
+		strings.NewReader(signedHeader(version, "linux", "amd64")), bytes.NewReader(binary))
+	if err := openpgp.ArmoredDetachSign(&signature, signer, signed, nil); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name": "v3.16.0", "html_url": "%[1]s/v3.16.0", "assets": [
+			{"name": "ooniprobe-linux-amd64", "browser_download_url": "%[1]s/binary"},
+			{"name": "ooniprobe-linux-amd64.asc", "browser_download_url": "%[1]s/binary.asc"}
+		]}`, server.URL)
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	})
+	mux.HandleFunc("/binary.asc", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(signature.Bytes())
+	})
+	return server
+}
+
+func TestUpdate(t *testing.T) {
+	signer, key := newSigningKey(t)
+	server := newReleaseServer(t, []byte("new ooniprobe"), signer, "3.16.0")
+	defer server.Close()
+	executable := filepath.Join(t.TempDir(), "ooniprobe")
+	if err := os.WriteFile(executable, []byte("old ooniprobe"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	updater := New(Config{
+		ReleaseURL: server.URL + "/latest",
+		SigningKey: key,
+		Executable: executable,
+		GOOS:       "linux",
+		GOARCH:     "amd64",
+	})
+	release, err := updater.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version != "3.16.0" || release.BinaryURL != server.URL+"/binary" {
+		t.Fatal("unexpected release", release)
+	}
+	if !release.IsNewerThan("3.15.3") || release.IsNewerThan("3.16.0") {
+		t.Fatal("unexpected version comparison")
+	}
+	if err := updater.Update(context.Background(), release); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new ooniprobe" {
+		t.Fatal("we did not replace the executable")
+	}
+	entries, err := os.ReadDir(filepath.Dir(executable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatal("we left behind some files", entries)
+	}
+}
+
+func TestUpdateInvalidSignature(t *testing.T) {
+	t.Run("with another signing key", func(t *testing.T) {
+		signer, _ := newSigningKey(t)
+		_, key := newSigningKey(t)
+		testUpdateInvalidSignature(t, signer, key, "3.16.0")
+	})
+	t.Run("with the signature of another version", func(t *testing.T) {
+		signer, key := newSigningKey(t)
+		testUpdateInvalidSignature(t, signer, key, "3.15.0")
+	})
+}
+
+// testUpdateInvalidSignature checks that we do not install a binary
+// that signer signed claiming that it is the given version.
+func testUpdateInvalidSignature(t *testing.T, signer *openpgp.Entity, key, version string) {
+	server := newReleaseServer(t, []byte("malicious ooniprobe"), signer, version)
+	defer server.Close()
+	executable := filepath.Join(t.TempDir(), "ooniprobe")
+	if err := os.WriteFile(executable, []byte("old ooniprobe"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	updater := New(Config{
+		ReleaseURL: server.URL + "/latest",
+		SigningKey: key,
+		Executable: executable,
+		GOOS:       "linux",
+		GOARCH:     "amd64",
+	})
+	release, err := updater.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := updater.Update(context.Background(), release); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatal("not the error we expected", err)
+	}
+	data, err := os.ReadFile(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "old ooniprobe" {
+		t.Fatal("we should not replace the executable")
+	}
+}
+
+func TestUpdateWithoutSigningKeyOrBinary(t *testing.T) {
+	signer, key := newSigningKey(t)
+	server := newReleaseServer(t, []byte("new ooniprobe"), signer, "3.16.0")
+	defer server.Close()
+	updater := New(Config{ReleaseURL: server.URL + "/latest", GOOS: "windows", GOARCH: "amd64"})
+	release, err := updater.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updater.CanUpdate() {
+		t.Fatal("we cannot update without the signing key")
+	}
+	if err := updater.Update(context.Background(), release); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatal("not the error we expected", err)
+	}
+	updater = New(Config{ReleaseURL: server.URL + "/latest", SigningKey: key, GOOS: "windows", GOARCH: "amd64"})
+	if err := updater.Update(context.Background(), release); !errors.Is(err, ErrNoBinary) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestCheckHTTPFailure(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	_, err := New(Config{ReleaseURL: server.URL}).Check(context.Background())
+	if !errors.Is(err, ErrHTTPStatus) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestGetTooLarge(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 1025)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush() // omits the Content-Length
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+	updater := New(Config{})
+	for _, path := range []string{"/", "/chunked"} {
+		_, err := updater.get(context.Background(), server.URL+path, 1024)
+		if !errors.Is(err, ErrTooLarge) {
+			t.Fatal("not the error we expected", path, err)
+		}
+	}
+	data, err := updater.get(context.Background(), server.URL, 1025)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, body) {
+		t.Fatal("unexpected body")
+	}
+}
@@ -0,0 +1,160 @@
+// Package settings exports the settings of the probe into an encrypted
+// bundle and imports them on another machine, which eases provisioning
+// many probes (see `ooniprobe settings export`).
+//
+// The bundle contains the config file, including its secrets (e.g., the
+// webhook secret), and, when the user asks for them, the orchestra
+// credentials. Because the orchestra credentials identify a probe, the
+// user should only export them when moving a probe to another machine.
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/utils/encryption"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+var (
+	// ErrWrongPassphrase indicates that we could not decrypt the
+	// bundle, most likely because the passphrase is wrong.
+	ErrWrongPassphrase = errors.New("settings: wrong passphrase or corrupt bundle")
+
+	// ErrEmptyPassphrase indicates that the passphrase is empty.
+	ErrEmptyPassphrase = errors.New("settings: empty passphrase")
+
+	// ErrUnsupportedVersion indicates that the bundle was created by
+	// a newer version of ooniprobe.
+	ErrUnsupportedVersion = errors.New("settings: unsupported bundle version")
+)
+
+// PassphraseEnv is the environment variable containing the passphrase
+// of the bundle, which allows to export and import unattended.
+const PassphraseEnv = "OONI_SETTINGS_PASSPHRASE"
+
+// bundleMagic is the header of the encrypted bundles.
+var bundleMagic = []byte("OONISET1")
+
+// bundleVersion is the current version of the bundle format.
+const bundleVersion = 1
+
+// orchestraStateKey is the key of the engine's kvstore containing
+// the orchestra credentials (see probeservices.NewStateFile).
+const orchestraStateKey = "orchestra.state"
+
+// Bundle contains the settings of a probe.
+type Bundle struct {
+	// Version is the version of the bundle format.
+	Version int64 `json:"version"`
+
+	// Config is the content of the config file, which also
+	// contains whether the user gave their informed consent.
+	Config json.RawMessage `json:"config"`
+
+	// OrchestraState contains the OPTIONAL orchestra credentials.
+	OrchestraState json.RawMessage `json:"orchestra_state,omitempty"`
+}
+
+// Paths contains the paths of the files containing the settings.
+type Paths struct {
+	// ConfigPath is the path of the config file.
+	ConfigPath string
+}
+
+// Export returns the bundle containing the settings, encrypted using
+// a key derived from the given passphrase. When kvs is not nil, the
+// bundle also contains the orchestra credentials stored into kvs.
+func Export(paths Paths, passphrase string, kvs model.KeyValueStore) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	configData, err := os.ReadFile(paths.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &Bundle{Version: bundleVersion, Config: configData}
+	if kvs != nil {
+		state, err := kvs.Get(orchestraStateKey)
+		// We may not have registered with orchestra yet.
+		if err != nil && !errors.Is(err, kvstore.ErrNoSuchKey) {
+			return nil, err
+		}
+		bundle.OrchestraState = state
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.Encrypt(bundleMagic, passphrase, data)
+}
+
+// Decrypt decrypts and validates the given bundle.
+func Decrypt(passphrase string, data []byte) (*Bundle, error) {
+	plaintext, err := encryption.Decrypt(bundleMagic, passphrase, data)
+	if errors.Is(err, encryption.ErrDecrypt) {
+		return nil, ErrWrongPassphrase
+	}
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, err
+	}
+	if bundle.Version > bundleVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, bundle.Version)
+	}
+	if _, err := config.ParseConfig(bundle.Config); err != nil {
+		return nil, fmt.Errorf("settings: invalid config in bundle: %w", err)
+	}
+	return &bundle, nil
+}
+
+// Import writes the config contained by the given bundle, replacing the
+// current config file, which only the user can read and write, since it
+// may contain secrets. Use ImportCredentials for the orchestra credentials.
+func Import(paths Paths, bundle *Bundle) error {
+	// Indent like config.Config.Write, since the bundle contains compact JSON.
+	var configData bytes.Buffer
+	if err := json.Indent(&configData, bundle.Config, "", "  "); err != nil {
+		return err
+	}
+	// We may be provisioning a probe that has never run.
+	if err := os.MkdirAll(filepath.Dir(paths.ConfigPath), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(paths.ConfigPath, configData.Bytes(), 0600); err != nil {
+		return err
+	}
+	// WriteFile does not change the permissions of an existing file.
+	return os.Chmod(paths.ConfigPath, 0600)
+}
+
+// ImportCredentials writes the orchestra credentials contained by the
+// given bundle into kvs, replacing the current ones. This function does
+// nothing when the bundle does not contain the orchestra credentials.
+func ImportCredentials(kvs model.KeyValueStore, bundle *Bundle) error {
+	if !bundle.HasCredentials() {
+		return nil
+	}
+	return kvs.Set(orchestraStateKey, bundle.OrchestraState)
+}
+
+// HasCredentials returns whether the bundle contains the orchestra credentials.
+func (b *Bundle) HasCredentials() bool {
+	return len(b.OrchestraState) > 0
+}
+
+// InformedConsent returns whether the user gave their informed consent
+// according to the config contained by the bundle.
+func (b *Bundle) InformedConsent() bool {
+	c, err := config.ParseConfig(b.Config)
+	return err == nil && c.InformedConsent
+}
@@ -0,0 +1,190 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/config"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+)
+
+// newPaths returns the paths of the settings inside a temporary dir.
+func newPaths(t *testing.T) Paths {
+	return Paths{ConfigPath: filepath.Join(t.TempDir(), "config.json")}
+}
+
+// readConfig reads the config at the given path.
+func readConfig(t *testing.T, path string) *config.Config {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c config.Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatal(err)
+	}
+	return &c
+}
+
+func TestExportImport(t *testing.T) {
+	configData, err := os.ReadFile("../config/testdata/valid-config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configData = bytes.Replace(configData,
+		[]byte(`"_informed_consent": false`), []byte(`"_informed_consent": true`), 1)
+	source := newPaths(t)
+	if err := os.WriteFile(source.ConfigPath, configData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := readConfig(t, source.ConfigPath)
+	c.Notifications.WebhookURL = "https://example.com/hook"
+	c.Notifications.WebhookSecret = "mascetti"
+	configData, err = json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source.ConfigPath, configData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Export(source, "", nil); !errors.Is(err, ErrEmptyPassphrase) {
+		t.Fatal("not the error we expected", err)
+	}
+	data, err := Export(source, "antani", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("example.com")) {
+		t.Fatal("expected the bundle to be encrypted")
+	}
+	if _, err := Decrypt("mascetti", data); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatal("not the error we expected", err)
+	}
+	bundle, err := Decrypt("antani", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bundle.InformedConsent() {
+		t.Fatal("expected the informed consent")
+	}
+	if bundle.HasCredentials() {
+		t.Fatal("expected no orchestra credentials")
+	}
+
+	destination := newPaths(t)
+	if err := os.WriteFile(destination.ConfigPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Import(destination, bundle); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(destination.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode().Perm() != 0600 {
+		t.Fatal("unexpected permissions", stat.Mode().Perm())
+	}
+	imported := readConfig(t, destination.ConfigPath)
+	if !imported.InformedConsent || imported.Notifications.WebhookURL != c.Notifications.WebhookURL {
+		t.Fatal("unexpected config", imported)
+	}
+	if imported.Notifications.WebhookSecret != c.Notifications.WebhookSecret {
+		t.Fatal("expected the webhook secret", imported.Notifications.WebhookSecret)
+	}
+}
+
+func TestExportImportCredentials(t *testing.T) {
+	source := newPaths(t)
+	configData, err := os.ReadFile("../config/testdata/valid-config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source.ConfigPath, configData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := []byte(`{"client_id":"antani","password":"mascetti"}`)
+
+	t.Run("before registering with orchestra", func(t *testing.T) {
+		data, err := Export(source, "antani", &kvstore.Memory{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundle, err := Decrypt("antani", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bundle.HasCredentials() {
+			t.Fatal("expected no orchestra credentials")
+		}
+		kvs := &kvstore.Memory{}
+		if err := ImportCredentials(kvs, bundle); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kvs.Get(orchestraStateKey); !errors.Is(err, kvstore.ErrNoSuchKey) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("after registering with orchestra", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		if err := kvs.Set(orchestraStateKey, state); err != nil {
+			t.Fatal(err)
+		}
+		data, err := Export(source, "antani", kvs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("mascetti")) {
+			t.Fatal("expected the bundle to be encrypted")
+		}
+		bundle, err := Decrypt("antani", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bundle.HasCredentials() {
+			t.Fatal("expected the orchestra credentials")
+		}
+		destination := &kvstore.Memory{}
+		if err := destination.Set(orchestraStateKey, []byte(`{}`)); err != nil {
+			t.Fatal(err)
+		}
+		if err := ImportCredentials(destination, bundle); err != nil {
+			t.Fatal(err)
+		}
+		imported, err := destination.Get(orchestraStateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(imported, state) {
+			t.Fatal("unexpected orchestra credentials", string(imported))
+		}
+	})
+}
+
+func TestExportWithoutInformedConsent(t *testing.T) {
+	source := newPaths(t)
+	configData, err := os.ReadFile("../config/testdata/valid-config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source.ConfigPath, configData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Export(source, "antani", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := Decrypt("antani", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.InformedConsent() {
+		t.Fatal("unexpected bundle", bundle)
+	}
+}
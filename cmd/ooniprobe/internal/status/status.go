@@ -0,0 +1,184 @@
+// Package status summarizes the state of the probe (see `ooniprobe status`).
+package status
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/crash"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+)
+
+// backendTimeout is the timeout for checking whether we can reach the backend.
+const backendTimeout = 15 * time.Second
+
+// Session is the subset of *engine.Session we use.
+type Session interface {
+	MaybeLookupBackendsContext(ctx context.Context) error
+}
+
+// Config contains the settings for collecting the status.
+type Config struct {
+	// DB is the database of the probe.
+	DB database.Actions
+
+	// Home is the OONI home directory.
+	Home string
+
+	// CrashDir is the OPTIONAL directory containing the crash
+	// dumps, which we don't check when it is empty.
+	CrashDir string
+
+	// GroupNames OPTIONALLY contains the test groups to report
+	// even if we have never run them.
+	GroupNames []string
+
+	// Schedules OPTIONALLY maps the test groups run by the daemon
+	// to their schedule (see config.Schedule).
+	Schedules map[string]string
+
+	// Session is the OPTIONAL session to check whether we can
+	// reach the backend, which we don't check when it is nil.
+	Session Session
+}
+
+// GroupStatus is the status of a test group.
+type GroupStatus struct {
+	// GroupName is the name of the test group.
+	GroupName string
+
+	// LastResultID is the ID of the last successful result, if any.
+	LastResultID int64
+
+	// LastRunTime is when the last successful run started, which
+	// is the zero time when there is no successful run.
+	LastRunTime time.Time
+
+	// Schedule is the schedule of the daemon, if any.
+	Schedule string
+
+	// NextRunTime is when the daemon will run the group next, which
+	// is the zero time when the daemon has not scheduled it yet.
+	NextRunTime time.Time
+}
+
+// Status is the status of the probe.
+type Status struct {
+	// Groups contains the status of the test groups sorted by name.
+	Groups []GroupStatus
+
+	// PendingUploads is the number of measurements we failed
+	// to upload and will upload again.
+	PendingUploads uint64
+
+	// HomeSize is the disk usage of the OONI home in bytes.
+	HomeSize int64
+
+	// BackendChecked indicates whether we checked the backend.
+	BackendChecked bool
+
+	// BackendFailure is why we cannot reach the backend, which
+	// is empty when we can reach it.
+	BackendFailure string
+
+	// CrashDumps contains the paths of the crash dumps, the
+	// most recent first.
+	CrashDumps []string
+}
+
+// Collect collects the status of the probe.
+func Collect(ctx context.Context, config Config) (*Status, error) {
+	groups := make(map[string]*GroupStatus)
+	group := func(groupName string) *GroupStatus {
+		if _, found := groups[groupName]; !found {
+			groups[groupName] = &GroupStatus{GroupName: groupName}
+		}
+		return groups[groupName]
+	}
+	for _, groupName := range config.GroupNames {
+		group(groupName)
+	}
+	doneResults, _, err := config.DB.ListResults()
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range doneResults {
+		if result.IsFailed {
+			continue
+		}
+		gs := group(result.TestGroupName)
+		if result.StartTime.After(gs.LastRunTime) {
+			gs.LastRunTime = result.StartTime
+			gs.LastResultID = result.Result.ID
+		}
+	}
+	for groupName, spec := range config.Schedules {
+		group(groupName).Schedule = spec
+	}
+	runs, err := config.DB.ListScheduleRuns()
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		// The daemon keeps the runs of the groups that are not
+		// scheduled anymore, which we ignore.
+		if gs, found := groups[run.GroupName]; found && gs.Schedule == run.ScheduleSpec {
+			gs.NextRunTime = run.NextRunTime
+		}
+	}
+	status := &Status{}
+	for _, gs := range groups {
+		status.Groups = append(status.Groups, *gs)
+	}
+	sort.Slice(status.Groups, func(i, j int) bool {
+		return status.Groups[i].GroupName < status.Groups[j].GroupName
+	})
+	if status.PendingUploads, err = config.DB.CountPendingUploads(); err != nil {
+		return nil, err
+	}
+	if status.HomeSize, err = diskUsage(config.Home); err != nil {
+		return nil, err
+	}
+	if config.CrashDir != "" {
+		if status.CrashDumps, err = crash.List(config.CrashDir); err != nil {
+			return nil, err
+		}
+	}
+	if config.Session != nil {
+		status.BackendChecked = true
+		ctx, cancel := context.WithTimeout(ctx, backendTimeout)
+		defer cancel()
+		if err := config.Session.MaybeLookupBackendsContext(ctx); err != nil {
+			status.BackendFailure = err.Error()
+		}
+	}
+	return status, nil
+}
+
+// diskUsage returns the total size of the files inside dir.
+func diskUsage(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Files may disappear while we walk, e.g., when
+			// a run is in progress, so we skip them.
+			if path != dir {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
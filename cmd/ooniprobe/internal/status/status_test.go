@@ -0,0 +1,129 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+)
+
+// fakeSession fails to reach the backend with the given error.
+type fakeSession struct {
+	err error
+}
+
+func (s *fakeSession) MaybeLookupBackendsContext(ctx context.Context) error {
+	return s.err
+}
+
+func newResult(id int64, groupName string, start time.Time, failed bool) database.ResultNetwork {
+	var result database.ResultNetwork
+	result.Result.ID = id
+	result.TestGroupName = groupName
+	result.StartTime = start
+	result.IsDone = true
+	result.IsFailed = failed
+	return result
+}
+
+func TestCollect(t *testing.T) {
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, "db"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "db", "main.sqlite3"), make([]byte, 1000), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "config.json"), make([]byte, 24), 0600); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC)
+	db := &oonitest.FakeDB{
+		FakeResults: []database.ResultNetwork{
+			newResult(1, "websites", start, false),
+			newResult(2, "websites", start.Add(time.Hour), false),
+			newResult(3, "websites", start.Add(2*time.Hour), true),
+			newResult(4, "im", start, false),
+		},
+		FakeScheduleRuns: map[string]database.ScheduleRun{
+			"websites": {
+				GroupName:    "websites",
+				ScheduleSpec: "6h",
+				NextRunTime:  start.Add(6 * time.Hour),
+			},
+			"im": {
+				GroupName:    "im",
+				ScheduleSpec: "1h",
+				NextRunTime:  start.Add(time.Hour),
+			},
+		},
+		FakePendingUploads: 7,
+	}
+	status, err := Collect(context.Background(), Config{
+		DB:         db,
+		Home:       home,
+		GroupNames: []string{"performance"},
+		Schedules:  map[string]string{"websites": "6h"},
+		Session:    &fakeSession{err: errors.New("mocked error")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Groups) != 3 {
+		t.Fatal("unexpected groups", status.Groups)
+	}
+	im, performance, websites := status.Groups[0], status.Groups[1], status.Groups[2]
+	if im.GroupName != "im" || im.LastResultID != 4 || im.Schedule != "" || !im.NextRunTime.IsZero() {
+		t.Fatal("unexpected im status", im)
+	}
+	if performance.GroupName != "performance" || performance.LastResultID != 0 || !performance.LastRunTime.IsZero() {
+		t.Fatal("unexpected performance status", performance)
+	}
+	if websites.LastResultID != 2 || !websites.LastRunTime.Equal(start.Add(time.Hour)) {
+		t.Fatal("expected to ignore the failed result", websites)
+	}
+	if websites.Schedule != "6h" || !websites.NextRunTime.Equal(start.Add(6*time.Hour)) {
+		t.Fatal("unexpected schedule", websites)
+	}
+	if status.PendingUploads != 7 || status.HomeSize != 1024 {
+		t.Fatal("unexpected status", status)
+	}
+	if !status.BackendChecked || status.BackendFailure != "mocked error" {
+		t.Fatal("unexpected backend status", status)
+	}
+}
+
+func TestCollectOffline(t *testing.T) {
+	status, err := Collect(context.Background(), Config{DB: &oonitest.FakeDB{}, Home: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.BackendChecked || len(status.Groups) != 0 || status.HomeSize != 0 {
+		t.Fatal("unexpected status", status)
+	}
+}
+
+func TestCollectCrashDumps(t *testing.T) {
+	home := t.TempDir()
+	crashDir := filepath.Join(home, "crashes")
+	if err := os.MkdirAll(crashDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"crash-2022-10-06T090000Z.json", "crash-2022-10-07T090000Z.json"} {
+		if err := os.WriteFile(filepath.Join(crashDir, name), []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	status, err := Collect(context.Background(), Config{DB: &oonitest.FakeDB{}, Home: home, CrashDir: crashDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.CrashDumps) != 2 || filepath.Base(status.CrashDumps[0]) != "crash-2022-10-07T090000Z.json" {
+		t.Fatal("unexpected crash dumps", status.CrashDumps)
+	}
+}
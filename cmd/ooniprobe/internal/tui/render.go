@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// clearScreen moves the cursor to the top left corner
+// of the terminal and clears the screen.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// title formats the title of a section.
+var title = color.New(color.Bold, color.FgBlue).SprintFunc()
+
+// render draws the dashboard on w.
+func (d *Dashboard) render(w io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fmt.Fprint(w, clearScreen)
+	fmt.Fprintf(w, "%s  %s\n\n", title("OONI Probe"), time.Now().Format("2006-01-02 15:04:05"))
+
+	fmt.Fprintln(w, title("Current run"))
+	if d.job == nil {
+		fmt.Fprintln(w, "  None")
+	} else {
+		fmt.Fprintf(w, "  %s", d.job.name)
+		if d.job.testName != "" {
+			fmt.Fprintf(w, ": %s (%d/%d) %.0f%%", d.job.testName,
+				d.job.testIndex+1, d.job.testCount, d.job.percentage*100)
+		}
+		fmt.Fprintln(w)
+		if d.job.message != "" {
+			fmt.Fprintf(w, "  %s\n", d.job.message)
+		}
+		fmt.Fprintf(w, "  %d measured (%d failed), %d uploaded (%d failed)\n",
+			d.job.measured, d.job.failed, d.job.uploaded, d.job.uploadFailed)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, title("Recent results"))
+	if len(d.results) <= 0 {
+		fmt.Fprintln(w, "  None")
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for idx := len(d.results) - 1; idx >= 0; idx-- {
+		result := &d.results[idx]
+		anomalies := fmt.Sprintf("%d anomalies", result.AnomalyCount)
+		if result.AnomalyCount > 0 {
+			anomalies = color.RedString(anomalies)
+		}
+		fmt.Fprintf(tw, "  #%d\t%s\t%s\tAS%d (%s)\t%d measurements\t%s\n", result.Result.ID,
+			result.TestGroupName, result.StartTime.Local().Format("2006-01-02 15:04"),
+			result.Network.ASN, result.Network.CountryCode, result.TotalCount, anomalies)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%s %d\n\n", title("Pending uploads:"), d.pendingUploads)
+
+	fmt.Fprintln(w, title("Recent events"))
+	for _, event := range d.events {
+		fmt.Fprintf(w, "  %s %s\n", event.Time.Local().Format("2006-01-02 15:04:05"), event.Kind)
+	}
+	if len(d.activity) > 0 {
+		fmt.Fprintf(w, "\n%s\n", title("Activity"))
+		for _, message := range d.activity {
+			fmt.Fprintf(w, "  %s\n", message)
+		}
+	}
+	fmt.Fprintf(w, "\n%s\n> ", d.status)
+}
@@ -0,0 +1,334 @@
+// Package tui implements the terminal dashboard of `ooniprobe tui`, which
+// shows the progress of the current run, the recent results, and the pending
+// uploads, and allows users to re-run measurements and delete results.
+package tui
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+)
+
+// These constants control what the dashboard shows.
+const (
+	// refreshInterval is how often we reload the data from the
+	// database, which allows us to notice runs performed by other
+	// processes (e.g., `ooniprobe daemon`).
+	refreshInterval = 5 * time.Second
+
+	// maxResults is the maximum number of recent results.
+	maxResults = 8
+
+	// maxEvents is the maximum number of recent events.
+	maxEvents = 5
+
+	// maxActivity is the maximum number of recent log messages.
+	maxActivity = 5
+)
+
+// Config contains the settings of the dashboard.
+type Config struct {
+	// DB is the database from which we read the results.
+	DB database.Actions
+
+	// RunGroup runs the given test group.
+	RunGroup func(groupName string) error
+
+	// Rerun re-runs the given measurement.
+	Rerun func(measurementID int64) error
+
+	// UploadPending uploads the measurements we failed to upload.
+	UploadPending func() error
+
+	// Input is where we read the commands from.
+	Input io.Reader
+
+	// Output is the terminal where we draw the dashboard.
+	Output io.Writer
+
+	// IsTerminated returns whether we should stop.
+	IsTerminated func() bool
+}
+
+// job is a run in progress, whose state we update using the
+// progress events emitted by the runner (see output.EnableProgressEvents).
+type job struct {
+	name         string
+	testName     string
+	testIndex    int
+	testCount    int
+	percentage   float64
+	message      string
+	measured     int
+	failed       int
+	uploaded     int
+	uploadFailed int
+}
+
+// Dashboard is the terminal dashboard. It also implements log.Handler,
+// such that it can show the log messages without breaking the layout.
+type Dashboard struct {
+	config  Config
+	updates chan struct{}
+
+	// mu protects the following fields, which we update from the
+	// goroutines running jobs and reading the progress events.
+	mu             sync.Mutex
+	job            *job
+	activity       []string
+	status         string
+	confirmDelete  int64
+	results        []database.ResultNetwork
+	events         []database.Event
+	pendingUploads uint64
+}
+
+// New creates a new dashboard.
+func New(config Config) *Dashboard {
+	return &Dashboard{
+		config:  config,
+		updates: make(chan struct{}, 1),
+		status:  "Type `help` to list the commands.",
+	}
+}
+
+// notify asks the main loop to redraw the dashboard.
+func (d *Dashboard) notify() {
+	select {
+	case d.updates <- struct{}{}:
+	default:
+	}
+}
+
+// HandleLog implements log.Handler.
+func (d *Dashboard) HandleLog(e *log.Entry) error {
+	if _, typed := e.Fields["type"]; typed || e.Level < log.InfoLevel {
+		return nil // the dashboard already shows the typed entries
+	}
+	message := e.Message
+	if err, found := e.Fields["error"]; found {
+		message = fmt.Sprintf("%s: %v", message, err)
+	}
+	d.mu.Lock()
+	d.activity = append(d.activity, message)
+	if len(d.activity) > maxActivity {
+		d.activity = d.activity[len(d.activity)-maxActivity:]
+	}
+	d.mu.Unlock()
+	d.notify()
+	return nil
+}
+
+// progressEvent is a progress event emitted by the runner.
+type progressEvent struct {
+	Event      string  `json:"event"`
+	TestName   string  `json:"test_name"`
+	Index      int     `json:"index"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+	Message    string  `json:"message"`
+	Failure    string  `json:"failure"`
+	Uploaded   bool    `json:"uploaded"`
+}
+
+// handleEvent updates the state of the current job.
+func (d *Dashboard) handleEvent(ev *progressEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.job == nil {
+		return
+	}
+	switch ev.Event {
+	case "experiment_started":
+		d.job.testName = ev.TestName
+		d.job.testIndex, d.job.testCount = ev.Index, ev.Count
+	case "input_measured":
+		d.job.measured++
+		if ev.Failure != "" {
+			d.job.failed++
+		}
+	case "upload_status":
+		if ev.Uploaded {
+			d.job.uploaded++
+		} else {
+			d.job.uploadFailed++
+		}
+	case "progress":
+		d.job.percentage, d.job.message = ev.Percentage, ev.Message
+	}
+}
+
+// readEvents reads the progress events until r is closed.
+func (d *Dashboard) readEvents(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var ev progressEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue // not much we can do
+		}
+		d.handleEvent(&ev)
+		d.notify()
+	}
+}
+
+// refresh reloads the data from the database.
+func (d *Dashboard) refresh() error {
+	results, _, err := d.config.DB.ListResults()
+	if err != nil {
+		return err
+	}
+	if len(results) > maxResults {
+		results = results[len(results)-maxResults:]
+	}
+	events, err := d.config.DB.ListEvents(&database.EventFilter{Limit: maxEvents})
+	if err != nil {
+		return err
+	}
+	pendingUploads, err := d.config.DB.CountPendingUploads()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.results, d.events, d.pendingUploads = results, events, pendingUploads
+	d.mu.Unlock()
+	return nil
+}
+
+// setStatus sets the message telling the user the outcome of a command.
+func (d *Dashboard) setStatus(format string, v ...interface{}) {
+	d.mu.Lock()
+	d.status = fmt.Sprintf(format, v...)
+	d.mu.Unlock()
+}
+
+// startJob runs fn in the background, unless a job is already running.
+func (d *Dashboard) startJob(name string, fn func() error, done chan<- error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.job != nil {
+		d.status = fmt.Sprintf("Please wait for `%s` to finish.", d.job.name)
+		return
+	}
+	d.job = &job{name: name}
+	d.status = fmt.Sprintf("Started `%s`.", name)
+	go func() {
+		done <- fn()
+	}()
+}
+
+// errQuit indicates that the user wants to quit.
+var errQuit = errors.New("tui: quit")
+
+// execute executes a command typed by the user.
+func (d *Dashboard) execute(line string, done chan<- error) error {
+	fields := strings.Fields(line)
+	d.mu.Lock()
+	confirmDelete := d.confirmDelete
+	d.confirmDelete = 0
+	d.mu.Unlock()
+	if confirmDelete > 0 {
+		if len(fields) != 1 || fields[0] != "y" {
+			d.setStatus("Not deleting result #%d.", confirmDelete)
+			return nil
+		}
+		if err := d.config.DB.DeleteResult(confirmDelete); err != nil {
+			d.setStatus("Cannot delete result #%d: %s.", confirmDelete, err)
+			return nil
+		}
+		d.setStatus("Deleted result #%d.", confirmDelete)
+		return nil
+	}
+	if len(fields) <= 0 {
+		return nil // just refresh
+	}
+	var id int64
+	if len(fields) == 2 {
+		id, _ = strconv.ParseInt(fields[1], 10, 64)
+	}
+	switch {
+	case fields[0] == "q" || fields[0] == "quit":
+		return errQuit
+	case fields[0] == "run" && len(fields) == 2:
+		groupName := fields[1]
+		d.startJob(line, func() error { return d.config.RunGroup(groupName) }, done)
+	case fields[0] == "rerun" && id > 0:
+		d.startJob(line, func() error { return d.config.Rerun(id) }, done)
+	case fields[0] == "rm" && id > 0:
+		d.mu.Lock()
+		d.confirmDelete = id
+		d.status = fmt.Sprintf("Type `y` to delete result #%d and its measurements.", id)
+		d.mu.Unlock()
+	case fields[0] == "upload":
+		d.startJob(line, d.config.UploadPending, done)
+	default:
+		d.setStatus("Commands: run <group>, rerun <measurement-id>, rm <result-id>, upload, quit.")
+	}
+	return nil
+}
+
+// Run runs the dashboard until the user quits or IsTerminated returns
+// true. When the user quits, we wait for the current job to finish.
+func (d *Dashboard) Run() error {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	output.EnableProgressEvents(writer)
+	defer output.EnableProgressEvents(io.Discard)
+	go d.readEvents(reader)
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(d.config.Input)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	done := make(chan error, 1)
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	quitting := false
+	for {
+		if err := d.refresh(); err != nil {
+			return err
+		}
+		d.render(d.config.Output)
+		d.mu.Lock()
+		running := d.job != nil
+		d.mu.Unlock()
+		if (quitting || d.config.IsTerminated()) && !running {
+			return nil
+		}
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				lines = nil // stdin closed, hence we quit
+				quitting = true
+				continue
+			}
+			if err := d.execute(line, done); errors.Is(err, errQuit) {
+				quitting = true
+				d.setStatus("Quitting when the current run finishes...")
+			}
+		case err := <-done:
+			d.mu.Lock()
+			if err != nil {
+				d.status = fmt.Sprintf("`%s` failed: %s.", d.job.name, err)
+			} else {
+				d.status = fmt.Sprintf("`%s` done.", d.job.name)
+			}
+			d.job = nil
+			d.mu.Unlock()
+		case <-d.updates:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+)
+
+func newFakeDB() *oonitest.FakeDB {
+	db := &oonitest.FakeDB{
+		FakeEvents:         []database.Event{{Kind: database.EventRunStarted}},
+		FakePendingUploads: 3,
+	}
+	for idx := int64(1); idx <= 10; idx++ {
+		var result database.ResultNetwork
+		result.Result.ID = idx
+		result.TestGroupName = "websites"
+		result.Network.ASN = 30722
+		result.Network.CountryCode = "IT"
+		db.FakeResults = append(db.FakeResults, result)
+	}
+	return db
+}
+
+func TestDashboard(t *testing.T) {
+	t.Run("runs the commands", func(t *testing.T) {
+		db := newFakeDB()
+		var ran []string
+		var out bytes.Buffer
+		d := New(Config{
+			DB: db,
+			RunGroup: func(groupName string) error {
+				ran = append(ran, groupName)
+				output.ExperimentStarted("web_connectivity", 0, 1, 2)
+				output.InputMeasured("web_connectivity", 0, "https://example.com/", 1, "")
+				return nil
+			},
+			Rerun: func(measurementID int64) error {
+				return errors.New("mocked error")
+			},
+			Input:        strings.NewReader("run websites\nrm 7\ny\nrm 8\nn\n"),
+			Output:       &out,
+			IsTerminated: func() bool { return false },
+		})
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if len(ran) != 1 || ran[0] != "websites" {
+			t.Fatal("unexpected runs", ran)
+		}
+		if len(db.FakeDeletedResults) != 1 || db.FakeDeletedResults[0] != 7 {
+			t.Fatal("unexpected deleted results", db.FakeDeletedResults)
+		}
+		screen := out.String()
+		for _, expected := range []string{"#10", "Pending uploads:", "run_started", "Not deleting result #8."} {
+			if !strings.Contains(screen, expected) {
+				t.Fatalf("cannot find %q in the dashboard", expected)
+			}
+		}
+		if strings.Contains(screen, "#2 ") {
+			t.Fatal("the dashboard should only show the recent results")
+		}
+	})
+
+	t.Run("tracks the progress of the current job", func(t *testing.T) {
+		d := New(Config{DB: newFakeDB()})
+		d.handleEvent(&progressEvent{Event: "input_measured"}) // no job, ignored
+		d.job = &job{name: "run websites"}
+		d.handleEvent(&progressEvent{Event: "experiment_started", TestName: "web_connectivity", Count: 1})
+		d.handleEvent(&progressEvent{Event: "input_measured"})
+		d.handleEvent(&progressEvent{Event: "input_measured", Failure: "generic_timeout_error"})
+		d.handleEvent(&progressEvent{Event: "upload_status", Uploaded: true})
+		d.handleEvent(&progressEvent{Event: "progress", Percentage: 0.5, Message: "processing"})
+		var out bytes.Buffer
+		d.render(&out)
+		for _, expected := range []string{
+			"run websites: web_connectivity (1/1) 50%",
+			"2 measured (1 failed), 1 uploaded (0 failed)",
+		} {
+			if !strings.Contains(out.String(), expected) {
+				t.Fatalf("cannot find %q in the dashboard", expected)
+			}
+		}
+	})
+
+	t.Run("reports failed jobs", func(t *testing.T) {
+		var out bytes.Buffer
+		d := New(Config{
+			DB:           newFakeDB(),
+			Rerun:        func(measurementID int64) error { return errors.New("mocked error") },
+			Input:        strings.NewReader("rerun 17\n"),
+			Output:       &out,
+			IsTerminated: func() bool { return false },
+		})
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "`rerun 17` failed: mocked error.") {
+			t.Fatal("expected to see the failure")
+		}
+	})
+}
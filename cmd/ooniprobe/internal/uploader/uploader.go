@@ -0,0 +1,158 @@
+// Package uploader uploads the measurements we previously failed to
+// upload (see `ooniprobe upload --pending`).
+package uploader
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/output"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// ErrMissingMeasurementFile indicates that we cannot upload a measurement
+// because we have not saved its JSON, which happens with measurements
+// created by very old versions of ooniprobe.
+var ErrMissingMeasurementFile = errors.New("uploader: missing measurement file")
+
+// Submitter submits measurements to the OONI collector.
+type Submitter interface {
+	// Submit submits the measurement and updates its
+	// report ID field in case of success.
+	Submit(ctx context.Context, m *model.Measurement) error
+}
+
+// Config contains the settings of the uploader.
+type Config struct {
+	// DB is the database containing the pending uploads.
+	DB database.Actions
+
+	// Submitter submits the measurements.
+	Submitter Submitter
+
+	// Interval is the OPTIONAL minimum time between two
+	// uploads, which limits the rate of the uploads.
+	Interval time.Duration
+
+	// IsTerminated returns whether we should stop.
+	IsTerminated func() bool
+}
+
+// Summary summarizes the outcome of uploading the pending measurements.
+type Summary struct {
+	// Uploaded is the number of measurements we uploaded.
+	Uploaded int
+
+	// Failed is the number of measurements we failed to upload, which
+	// remain pending, such that we will try again later.
+	Failed int
+
+	// Remaining is the number of measurements we did not try to
+	// upload because we were interrupted.
+	Remaining int
+}
+
+// Uploader uploads the pending measurements.
+type Uploader struct {
+	config Config
+
+	// These fields allow to mock the environment in tests.
+	timeNow func() time.Time
+	sleep   func(time.Duration)
+}
+
+// New creates a new uploader.
+func New(config Config) *Uploader {
+	return &Uploader{
+		config:  config,
+		timeNow: time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+// Run uploads all the pending measurements, emitting the progress and
+// the outcome of each upload, regardless of when their next attempt is due.
+func (u *Uploader) Run(ctx context.Context) (*Summary, error) {
+	uploads, err := u.config.DB.ListPendingUploads()
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	results := make(map[int64]*database.Result)
+	start := u.timeNow()
+	var last time.Time
+	for idx, upload := range uploads {
+		if u.config.IsTerminated() {
+			log.Info("user requested us to terminate using Ctrl-C")
+			summary.Remaining = len(uploads) - idx
+			break
+		}
+		if wait := u.config.Interval - u.timeNow().Sub(last); !last.IsZero() && wait > 0 {
+			u.sleep(wait)
+		}
+		last = u.timeNow()
+		msmt, err := u.config.DB.GetMeasurement(upload.MeasurementID)
+		if err != nil {
+			return nil, err
+		}
+		failure := ""
+		if err := u.submit(ctx, &msmt.Measurement); err != nil {
+			log.WithError(err).Warnf("failed to upload measurement #%d", msmt.Measurement.ID)
+			failure = err.Error()
+			summary.Failed++
+			if err := u.config.DB.MeasurementUploadFailed(&msmt.Measurement, failure); err != nil {
+				return nil, fmt.Errorf("failed to mark upload as failed: %w", err)
+			}
+		} else {
+			if err := u.config.DB.MeasurementUploadSucceeded(&msmt.Measurement, ""); err != nil {
+				return nil, fmt.Errorf("failed to mark upload as succeeded: %w", err)
+			}
+			summary.Uploaded++
+			results[msmt.Result.ID] = &msmt.Result
+		}
+		output.UploadStatus(msmt.Measurement.TestName, idx, msmt.Measurement.ID, failure)
+		done := idx + 1
+		eta := u.timeNow().Sub(start).Seconds() / float64(done) * float64(len(uploads)-done)
+		output.Progress("upload", float64(done)/float64(len(uploads)), eta,
+			fmt.Sprintf("uploaded %d/%d pending measurements", done, len(uploads)))
+	}
+	for _, result := range results {
+		if err := u.config.DB.UpdateUploadedStatus(result); err != nil {
+			log.WithError(err).Warnf("failed to update the uploaded status of result #%d", result.ID)
+		}
+	}
+	return summary, nil
+}
+
+// submit submits the measurement saved on disk and updates its report ID.
+func (u *Uploader) submit(ctx context.Context, msmt *database.Measurement) error {
+	path := msmt.MeasurementFilePath
+	if !path.Valid || path.String == "" {
+		return ErrMissingMeasurementFile
+	}
+	data, err := u.config.DB.ReadMeasurementFile(path.String)
+	if err != nil {
+		return err
+	}
+	var measurement model.Measurement
+	if err := json.Unmarshal(data, &measurement); err != nil {
+		return err
+	}
+	if err := u.config.Submitter.Submit(ctx, &measurement); err != nil {
+		return err
+	}
+	// Keep the report ID we got from the collector also on disk.
+	if data, err := json.Marshal(&measurement); err == nil {
+		if err := u.config.DB.WriteMeasurementFile(path.String, append(data, '\n')); err != nil {
+			log.WithError(err).Warnf("failed to update %s", path.String)
+		}
+	}
+	msmt.ReportID = sql.NullString{String: measurement.ReportID, Valid: measurement.ReportID != ""}
+	return nil
+}
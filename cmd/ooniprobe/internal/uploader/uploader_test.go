@@ -0,0 +1,121 @@
+package uploader
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/database"
+	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/oonitest"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// fakeSubmitter assigns a report ID to the measurements
+// unless their input is "fail".
+type fakeSubmitter struct{}
+
+func (fakeSubmitter) Submit(ctx context.Context, m *model.Measurement) error {
+	if m.Input == "fail" {
+		return errors.New("mocked error")
+	}
+	m.ReportID = "20221006T090000Z_webconnectivity_IT_30722_n1_abc"
+	return nil
+}
+
+func newMeasurement(t *testing.T, id int64, path, input string) *database.MeasurementURLNetwork {
+	msmt := &database.MeasurementURLNetwork{}
+	msmt.Measurement.ID = id
+	msmt.Measurement.TestName = "web_connectivity"
+	msmt.Result.ID = 7
+	if path == "" {
+		return msmt
+	}
+	data, err := json.Marshal(&model.Measurement{TestName: "web_connectivity", Input: model.MeasurementTarget(input)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.WriteMeasurementFile(path, data, nil); err != nil {
+		t.Fatal(err)
+	}
+	msmt.MeasurementFilePath = sql.NullString{String: path, Valid: true}
+	return msmt
+}
+
+func TestUploader(t *testing.T) {
+	dir := t.TempDir()
+	uploaded := filepath.Join(dir, "msmt-1.json")
+	db := &oonitest.FakeDB{
+		FakeMeasurements: map[int64]*database.MeasurementURLNetwork{
+			1: newMeasurement(t, 1, uploaded, "https://example.com/"),
+			2: newMeasurement(t, 2, filepath.Join(dir, "msmt-2.json"), "fail"),
+			3: newMeasurement(t, 3, "", ""),
+		},
+	}
+	u := New(Config{
+		DB:           db,
+		Submitter:    fakeSubmitter{},
+		Interval:     time.Minute,
+		IsTerminated: func() bool { return false },
+	})
+	var slept []time.Duration
+	u.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+	}
+	summary, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Uploaded != 1 || summary.Failed != 2 || summary.Remaining != 0 {
+		t.Fatal("unexpected summary", summary)
+	}
+	if len(db.FakeSucceededUploads) != 1 || db.FakeSucceededUploads[0] != 1 {
+		t.Fatal("unexpected successful uploads", db.FakeSucceededUploads)
+	}
+	if db.FakeFailedUploads[2] != "mocked error" || db.FakeFailedUploads[3] != ErrMissingMeasurementFile.Error() {
+		t.Fatal("unexpected failed uploads", db.FakeFailedUploads)
+	}
+	if len(db.FakeUpdatedUploadedStatus) != 1 || db.FakeUpdatedUploadedStatus[0] != 7 {
+		t.Fatal("unexpected updated results", db.FakeUpdatedUploadedStatus)
+	}
+	if len(slept) != 2 {
+		t.Fatal("expected to wait between the uploads", slept)
+	}
+	if db.FakeMeasurements[1].ReportID.String != "20221006T090000Z_webconnectivity_IT_30722_n1_abc" {
+		t.Fatal("unexpected report ID", db.FakeMeasurements[1].ReportID)
+	}
+	data, err := database.ReadMeasurementFile(uploaded, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var measurement model.Measurement
+	if err := json.Unmarshal(data, &measurement); err != nil {
+		t.Fatal(err)
+	}
+	if measurement.ReportID != db.FakeMeasurements[1].ReportID.String {
+		t.Fatal("expected to save the report ID on disk")
+	}
+}
+
+func TestUploaderInterrupted(t *testing.T) {
+	db := &oonitest.FakeDB{
+		FakeMeasurements: map[int64]*database.MeasurementURLNetwork{
+			1: newMeasurement(t, 1, "", ""),
+			2: newMeasurement(t, 2, "", ""),
+		},
+	}
+	summary, err := New(Config{
+		DB:           db,
+		Submitter:    fakeSubmitter{},
+		IsTerminated: func() bool { return true },
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Remaining != 2 || len(db.FakeFailedUploads) != 0 {
+		t.Fatal("unexpected summary", summary)
+	}
+}
@@ -0,0 +1,148 @@
+// Package encryption encrypts data using AES-256-GCM with a key
+// derived from a passphrase using scrypt.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrDecrypt indicates that we could not decrypt the data, most
+// likely because the passphrase is wrong.
+var ErrDecrypt = errors.New("encryption: wrong passphrase or corrupt data")
+
+// These constants control how we derive the key from the passphrase.
+const (
+	saltSize = 16
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+	keySize  = 32
+)
+
+// newAEAD derives the key from passphrase and salt and
+// returns the corresponding AES-256-GCM AEAD.
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt returns the encrypted data, which consists of the magic, the
+// salt, the nonce, and the sealed data, which also authenticates the
+// magic and the salt. The magic identifies the kind of data.
+func Encrypt(magic []byte, passphrase string, data []byte) ([]byte, error) {
+	return NewKeyring(passphrase).Encrypt(magic, data)
+}
+
+// Decrypt is the inverse of Encrypt. It fails with ErrDecrypt when
+// the data does not start with magic or we cannot decrypt it.
+func Decrypt(magic []byte, passphrase string, data []byte) ([]byte, error) {
+	return NewKeyring(passphrase).Decrypt(magic, data)
+}
+
+// Keyring encrypts and decrypts like Encrypt and Decrypt but caches the
+// keys it derives from the passphrase, because deriving a key is slow on
+// purpose. Use it when encrypting or decrypting many files, e.g., the
+// measurement files. All the data encrypted by a Keyring uses the same
+// salt, and each piece of data uses a random nonce.
+type Keyring struct {
+	// aeads maps a salt to the corresponding AEAD.
+	aeads map[string]cipher.AEAD
+
+	// mu protects aeads and salt.
+	mu sync.Mutex
+
+	// passphrase is the passphrase.
+	passphrase string
+
+	// salt is the salt used for encrypting.
+	salt []byte
+}
+
+// NewKeyring creates a new Keyring using the given passphrase.
+func NewKeyring(passphrase string) *Keyring {
+	return &Keyring{aeads: make(map[string]cipher.AEAD), passphrase: passphrase}
+}
+
+// aead returns the AEAD for the given salt.
+func (k *Keyring) aead(salt []byte) (cipher.AEAD, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if aead, found := k.aeads[string(salt)]; found {
+		return aead, nil
+	}
+	aead, err := newAEAD(k.passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	k.aeads[string(salt)] = aead
+	return aead, nil
+}
+
+// encryptionSalt returns the salt used for encrypting.
+func (k *Keyring) encryptionSalt() ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		k.salt = salt
+	}
+	return k.salt, nil
+}
+
+// Encrypt is like the Encrypt function.
+func (k *Keyring) Encrypt(magic []byte, data []byte) ([]byte, error) {
+	salt, err := k.encryptionSalt()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := k.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header := append(append([]byte{}, magic...), salt...)
+	out := append(append([]byte{}, header...), nonce...)
+	return aead.Seal(out, nonce, data, header), nil
+}
+
+// Decrypt is like the Decrypt function.
+func (k *Keyring) Decrypt(magic []byte, data []byte) ([]byte, error) {
+	headerSize := len(magic) + saltSize
+	if len(data) < headerSize || !bytes.Equal(data[:len(magic)], magic) {
+		return nil, ErrDecrypt
+	}
+	header, salt := data[:headerSize], data[len(magic):headerSize]
+	aead, err := k.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	data = data[headerSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], header)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
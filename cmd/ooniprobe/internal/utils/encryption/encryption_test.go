@@ -0,0 +1,68 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	magic := []byte("OONITEST")
+	plaintext := []byte("SQLite format 3")
+	data, err := Encrypt(magic, "antani", plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, magic) || bytes.Contains(data, plaintext) {
+		t.Fatal("unexpected encrypted data")
+	}
+	decrypted, err := Decrypt(magic, "antani", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatal("unexpected plaintext", decrypted)
+	}
+	if _, err := Decrypt(magic, "mascetti", data); !errors.Is(err, ErrDecrypt) {
+		t.Fatal("not the error we expected", err)
+	}
+	if _, err := Decrypt([]byte("OONIELSE"), "antani", data); !errors.Is(err, ErrDecrypt) {
+		t.Fatal("not the error we expected", err)
+	}
+	if _, err := Decrypt(magic, "antani", data[:len(magic)+4]); !errors.Is(err, ErrDecrypt) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	magic := []byte("OONITEST")
+	keyring := NewKeyring("antani")
+	var encrypted [][]byte
+	for _, plaintext := range []string{"first file", "second file"} {
+		data, err := keyring.Encrypt(magic, []byte(plaintext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted = append(encrypted, data)
+	}
+	if bytes.Equal(encrypted[0][len(magic):], encrypted[1][len(magic):]) {
+		t.Fatal("expected a different nonce for each file")
+	}
+	// The Decrypt function must be able to decrypt what the keyring encrypts and
+	// a new keyring must be able to decrypt what the Encrypt function encrypts.
+	decrypted, err := Decrypt(magic, "antani", encrypted[1])
+	if err != nil || string(decrypted) != "second file" {
+		t.Fatal("unexpected result", err, decrypted)
+	}
+	data, err := Encrypt(magic, "antani", []byte("third file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err = keyring.Decrypt(magic, data)
+	if err != nil || string(decrypted) != "third file" {
+		t.Fatal("unexpected result", err, decrypted)
+	}
+	if _, err := NewKeyring("mascetti").Decrypt(magic, data); !errors.Is(err, ErrDecrypt) {
+		t.Fatal("not the error we expected", err)
+	}
+}
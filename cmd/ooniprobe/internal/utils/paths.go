@@ -35,8 +35,21 @@ func TunnelDir(home string) string {
 	return filepath.Join(home, "tunnel")
 }
 
-// EngineDir returns the directory where ooni/probe-engine should
-// store its private data given a specific OONI Home.
+// MeasureDir returns the directory where `ooniprobe measure` saves
+// its measurements, which do not belong to any result.
+func MeasureDir(home string) string {
+	return filepath.Join(home, "measure")
+}
+
+// CrashDir returns the directory where we write the crash dumps.
+func CrashDir(home string) string {
+	return filepath.Join(home, "crashes")
+}
+
+// EngineDir returns the directory where older releases stored the
+// ooni/probe-engine private data given a specific OONI Home. We now
+// store this data inside the database (see database.KVStore), unless
+// we use a PostgreSQL database shared with other probes.
 func EngineDir(home string) string {
 	return filepath.Join(home, "engine")
 }
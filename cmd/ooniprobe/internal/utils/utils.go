@@ -1,26 +1,11 @@
 package utils
 
 import (
-	"fmt"
-	"os"
 	"regexp"
 	"strings"
 	"unicode/utf8"
-
-	"github.com/fatih/color"
 )
 
-// Log outputs a log message.
-func Log(msg string, v ...interface{}) {
-	fmt.Printf("     %s\n", color.CyanString(msg, v...))
-}
-
-// Fatal error
-func Fatal(err error) {
-	fmt.Fprintf(os.Stderr, "\n     %s %s\n\n", color.RedString("Error:"), err)
-	os.Exit(1)
-}
-
 // Finds the ansi escape sequences (like colors)
 // Taken from: https://github.com/chalk/ansi-regex/blob/d9d806ecb45d899cf43408906a4440060c5c50e5/index.js
 var ansiEscapes = regexp.MustCompile(`[\x1B\x9B][[\]()#;?]*` +
@@ -3,15 +3,39 @@ package main
 import (
 	"github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/app"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/autorun"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/backup"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/completion"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/config"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/daemon"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/doctor"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/events"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/explorer"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/export"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/geoip"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/importer"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/info"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/list"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/measure"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/measurements"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/networks"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/note"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/onboard"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/repair"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/rerun"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/reset"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/restore"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/resume"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/rm"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/run"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/settings"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/show"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/stats"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/status"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/tag"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/tui"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/update"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/upload"
+	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/vacuum"
 	_ "github.com/ooni/probe-cli/v3/cmd/ooniprobe/internal/cli/version"
 )
 
@@ -31,9 +31,24 @@ func WithExperimentByteCounter(ctx context.Context, counter *Counter) context.Co
 	return context.WithValue(ctx, byteCounterExperimentKey{}, counter)
 }
 
+type byteCounterMeasurementKey struct{}
+
+// ContextMeasurementByteCounter retrieves the measurement byte counter from the context
+func ContextMeasurementByteCounter(ctx context.Context) *Counter {
+	counter, _ := ctx.Value(byteCounterMeasurementKey{}).(*Counter)
+	return counter
+}
+
+// WithMeasurementByteCounter assigns the byte counter of a single
+// measurement (i.e., of a single input) to the context.
+func WithMeasurementByteCounter(ctx context.Context, counter *Counter) context.Context {
+	return context.WithValue(ctx, byteCounterMeasurementKey{}, counter)
+}
+
 // MaybeWrapWithContextByteCounters wraps a conn with the byte counters
 // that have previosuly been configured into a context.
 func MaybeWrapWithContextByteCounters(ctx context.Context, conn net.Conn) net.Conn {
+	conn = MaybeWrap(conn, ContextMeasurementByteCounter(ctx))
 	conn = MaybeWrap(conn, ContextExperimentByteCounter(ctx))
 	conn = MaybeWrap(conn, ContextSessionByteCounter(ctx))
 	return conn
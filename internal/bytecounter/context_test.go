@@ -28,6 +28,16 @@ func TestExperimentByteCounter(t *testing.T) {
 	}
 }
 
+func TestMeasurementByteCounter(t *testing.T) {
+	counter := New()
+	ctx := context.Background()
+	ctx = WithMeasurementByteCounter(ctx, counter)
+	outer := ContextMeasurementByteCounter(ctx)
+	if outer != counter {
+		t.Fatal("unexpected result")
+	}
+}
+
 func TestMaybeWrapWithContextByteCounters(t *testing.T) {
 	var conn net.Conn = &mocks.Conn{
 		MockRead: func(b []byte) (int, error) {
@@ -39,9 +49,11 @@ func TestMaybeWrapWithContextByteCounters(t *testing.T) {
 	}
 	sessCounter := New()
 	expCounter := New()
+	measCounter := New()
 	ctx := context.Background()
 	ctx = WithSessionByteCounter(ctx, sessCounter)
 	ctx = WithExperimentByteCounter(ctx, expCounter)
+	ctx = WithMeasurementByteCounter(ctx, measCounter)
 	conn = MaybeWrapWithContextByteCounters(ctx, conn)
 	buf := make([]byte, 128)
 	conn.Read(buf)
@@ -58,4 +70,10 @@ func TestMaybeWrapWithContextByteCounters(t *testing.T) {
 	if expCounter.Sent.Load() != 128 {
 		t.Fatal("invalid value")
 	}
+	if measCounter.Received.Load() != 128 {
+		t.Fatal("invalid value")
+	}
+	if measCounter.Sent.Load() != 128 {
+		t.Fatal("invalid value")
+	}
 }
@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/debugserver"
 	"github.com/ooni/probe-cli/v3/internal/engine"
 	"github.com/ooni/probe-cli/v3/internal/engine/legacy/assetsdir"
 	"github.com/ooni/probe-cli/v3/internal/humanize"
@@ -31,6 +32,7 @@ import (
 type Options struct {
 	Annotations      []string
 	Censor           string
+	DebugAddress     string
 	ExtraOptions     []string
 	HomeDir          string
 	Inputs           []string
@@ -39,6 +41,7 @@ type Options struct {
 	MaxRuntime       int64
 	NoJSON           bool
 	NoCollector      bool
+	Offline          bool
 	ProbeServicesURL string
 	Proxy            string
 	Random           bool
@@ -61,6 +64,18 @@ var (
 	startTime     = time.Now()
 )
 
+// kvstoreMaxBytes is the maximum size of miniooni's kvstore. The
+// session periodically compacts the kvstore to stay below this limit.
+const kvstoreMaxBytes = 32 << 20
+
+// kvstorePinnedKeys contains the keys of miniooni's kvstore that we
+// must not evict when compacting, because they contain the probe
+// credentials (orchestra.state) or state rather than cached data.
+var kvstorePinnedKeys = []string{
+	"orchestra.state",
+	"sessionresolver.state",
+}
+
 func init() {
 	getopt.FlagLong(
 		&globalOptions.Annotations, "annotation", 'A', "Add annotaton", "KEY=VALUE",
@@ -69,6 +84,10 @@ func init() {
 		&globalOptions.Censor, "censor", 0,
 		"Specifies censorship rules to apply for QA purposes", "FILE",
 	)
+	getopt.FlagLong(
+		&globalOptions.DebugAddress, "debug-address", 0,
+		"Serve pprof profiles and runtime metrics at this address (e.g., 127.0.0.1:6060)", "ADDRESS",
+	)
 	getopt.FlagLong(
 		&globalOptions.ExtraOptions, "option", 'O',
 		"Pass an option to the experiment", "KEY=VALUE",
@@ -99,6 +118,10 @@ func init() {
 	getopt.FlagLong(
 		&globalOptions.NoCollector, "no-collector", 'n', "Don't use a collector",
 	)
+	getopt.FlagLong(
+		&globalOptions.Offline, "offline", 0,
+		"Only use cached backend data and do not submit measurements",
+	)
 	getopt.FlagLong(
 		&globalOptions.ProbeServicesURL, "probe-services", 0,
 		"Set the URL of the probe-services instance you want to use", "URL",
@@ -316,6 +339,12 @@ func MainWithConfiguration(experimentName string, currentOptions Options) {
 	}
 	log.Log = logger
 
+	if currentOptions.DebugAddress != "" {
+		server, err := debugserver.Start(currentOptions.DebugAddress, log.Log)
+		runtimex.PanicOnError(err, "cannot start the debug server")
+		defer server.Close()
+	}
+
 	if currentOptions.Censor != "" {
 		config, err := filtering.NewTProxyConfig(currentOptions.Censor)
 		runtimex.PanicOnError(err, "cannot parse --censor file as JSON")
@@ -327,6 +356,11 @@ func MainWithConfiguration(experimentName string, currentOptions Options) {
 		currentOptions.NoCollector = true
 	}
 
+	if currentOptions.Offline {
+		log.Infof("miniooni: disabling submission with --offline")
+		currentOptions.NoCollector = true
+	}
+
 	//Mon Jan 2 15:04:05 -0700 MST 2006
 	log.Infof("Current time: %s", time.Now().Format("2006-01-02 15:04:05 MST"))
 
@@ -359,8 +393,9 @@ func MainWithConfiguration(experimentName string, currentOptions Options) {
 	}
 
 	kvstore2dir := filepath.Join(miniooniDir, "kvstore2")
-	kvstore, err := kvstore.NewFS(kvstore2dir)
+	kvstore, err := kvstore.NewCompactFS(kvstore2dir, kvstoreMaxBytes)
 	fatalOnError(err, "cannot create kvstore2 directory")
+	kvstore.Pinned = kvstorePinnedKeys
 
 	tunnelDir := filepath.Join(miniooniDir, "tunnel")
 	err = os.MkdirAll(tunnelDir, 0700)
@@ -369,6 +404,7 @@ func MainWithConfiguration(experimentName string, currentOptions Options) {
 	config := engine.SessionConfig{
 		KVStore:         kvstore,
 		Logger:          logger,
+		Offline:         currentOptions.Offline,
 		ProxyURL:        proxyURL,
 		SoftwareName:    softwareName,
 		SoftwareVersion: softwareVersion,
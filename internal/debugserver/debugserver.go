@@ -0,0 +1,131 @@
+// Package debugserver implements an opt-in HTTP server exposing the pprof
+// profiles and the runtime metrics of the running process, which helps to
+// diagnose leaks occurring during long runs.
+//
+// The server exposes the profiles at /debug/pprof/ and the metrics, as
+// JSON, at /debug/metrics. Because the profiles reveal information about
+// the process, you should only listen on a loopback address.
+package debugserver
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Metrics contains the runtime metrics of the process.
+type Metrics struct {
+	// Goroutines is the number of goroutines.
+	Goroutines int `json:"goroutines"`
+
+	// HeapAlloc is the number of bytes of allocated heap objects.
+	HeapAlloc uint64 `json:"heap_alloc_bytes"`
+
+	// HeapInuse is the number of bytes in in-use heap spans.
+	HeapInuse uint64 `json:"heap_inuse_bytes"`
+
+	// HeapObjects is the number of allocated heap objects.
+	HeapObjects uint64 `json:"heap_objects"`
+
+	// Sys is the number of bytes obtained from the OS.
+	Sys uint64 `json:"sys_bytes"`
+
+	// NumGC is the number of completed GC cycles.
+	NumGC uint32 `json:"num_gc"`
+
+	// OpenFDs is the number of open file descriptors, which
+	// is negative when we cannot count them on this system.
+	OpenFDs int `json:"open_fds"`
+}
+
+// fdDirs contains the directories listing the open file
+// descriptors of the current process on the systems we know.
+var fdDirs = []string{"/proc/self/fd", "/dev/fd"}
+
+// countOpenFDs returns the number of open file descriptors or -1.
+func countOpenFDs() int {
+	for _, dir := range fdDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		// Discount the descriptor used for reading the directory.
+		return len(entries) - 1
+	}
+	return -1
+}
+
+// ReadMetrics reads the runtime metrics of the process.
+func ReadMetrics() Metrics {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return Metrics{
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   stats.HeapAlloc,
+		HeapInuse:   stats.HeapInuse,
+		HeapObjects: stats.HeapObjects,
+		Sys:         stats.Sys,
+		NumGC:       stats.NumGC,
+		OpenFDs:     countOpenFDs(),
+	}
+}
+
+// NewHandler returns the handler serving the profiles and the metrics.
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/metrics", func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(ReadMetrics())
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	return mux
+}
+
+// Server is a running debug server.
+type Server struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+// Start starts a debug server listening on the given address (e.g.,
+// "127.0.0.1:6060"). Use Close to stop the server.
+func Start(address string, logger model.Logger) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{
+		Handler: NewHandler(),
+		// Profiles and traces last for as many seconds as
+		// the user requests, so we only bound reading.
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go server.Serve(listener)
+	logger.Infof("debugserver: serving pprof and metrics at http://%s/debug/", listener.Addr())
+	return &Server{listener: listener, server: server}, nil
+}
+
+// Addr returns the address on which the server listens.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
@@ -0,0 +1,101 @@
+package debugserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestReadMetrics(t *testing.T) {
+	metrics := ReadMetrics()
+	if metrics.Goroutines <= 0 || metrics.HeapAlloc == 0 || metrics.Sys == 0 {
+		t.Fatal("unexpected metrics", metrics)
+	}
+}
+
+func TestCountOpenFDs(t *testing.T) {
+	t.Run("when we cannot count them", func(t *testing.T) {
+		saved := fdDirs
+		defer func() { fdDirs = saved }()
+		fdDirs = []string{"/nonexistent"}
+		if count := countOpenFDs(); count != -1 {
+			t.Fatal("unexpected count", count)
+		}
+	})
+
+	t.Run("when we can count them", func(t *testing.T) {
+		saved := fdDirs
+		defer func() { fdDirs = saved }()
+		dir := t.TempDir()
+		for _, name := range []string{"0", "1", "2"} {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fdDirs = []string{"/nonexistent", dir}
+		if count := countOpenFDs(); count != 2 {
+			t.Fatal("unexpected count", count)
+		}
+	})
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	t.Run("for the metrics", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/debug/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/json" {
+			t.Fatal("unexpected response", resp.StatusCode)
+		}
+		var metrics Metrics
+		if err := json.NewDecoder(resp.Body).Decode(&metrics); err != nil {
+			t.Fatal(err)
+		}
+		if metrics.Goroutines <= 0 {
+			t.Fatal("unexpected metrics", metrics)
+		}
+	})
+
+	t.Run("for the profiles", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/debug/pprof/goroutine?debug=1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 200 || !strings.HasPrefix(string(data), "goroutine profile:") {
+			t.Fatal("unexpected response", resp.StatusCode, string(data))
+		}
+	})
+}
+
+func TestStart(t *testing.T) {
+	server, err := Start("127.0.0.1:0", model.DiscardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	resp, err := http.Get("http://" + server.Addr().String() + "/debug/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatal("unexpected status code", resp.StatusCode)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"os"
+	"reflect"
 	"runtime"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/httptransport"
 	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/scrubber"
 	"github.com/ooni/probe-cli/v3/internal/version"
 )
 
@@ -27,6 +29,7 @@ func formatTimeNowUTC() string {
 type Experiment struct {
 	byteCounter   *bytecounter.Counter
 	callbacks     model.ExperimentCallbacks
+	collector     string
 	measurer      model.ExperimentMeasurer
 	report        probeservices.ReportChannel
 	session       *Session
@@ -39,7 +42,7 @@ type Experiment struct {
 // way to create an experiment is the ExperimentBuilder. Though this function
 // allows the programmer to create a custom, external experiment.
 func NewExperiment(sess *Session, measurer model.ExperimentMeasurer) *Experiment {
-	return &Experiment{
+	e := &Experiment{
 		byteCounter:   bytecounter.New(),
 		callbacks:     model.NewPrinterCallbacks(sess.Logger()),
 		measurer:      measurer,
@@ -48,6 +51,8 @@ func NewExperiment(sess *Session, measurer model.ExperimentMeasurer) *Experiment
 		testStartTime: formatTimeNowUTC(),
 		testVersion:   measurer.ExperimentVersion(),
 	}
+	sess.registerExperimentByteCounter(e.testName, e.byteCounter)
+	return e
 }
 
 // KibiBytesReceived accounts for the KibiBytes received by the HTTP clients
@@ -79,6 +84,13 @@ func (e *Experiment) OpenReport() (err error) {
 	return e.OpenReportContext(context.Background())
 }
 
+// CollectorAddress returns the address of the collector that accepted
+// the report, if we have opened a report successfully before, or an
+// empty string, otherwise.
+func (e *Experiment) CollectorAddress() string {
+	return e.collector
+}
+
 // ReportID returns the open reportID, if we have opened a report
 // successfully before, or an empty string, otherwise.
 func (e *Experiment) ReportID() string {
@@ -119,7 +131,7 @@ func (eaw *experimentAsyncWrapper) RunAsync(
 	out := make(chan *model.ExperimentAsyncTestKeys)
 	measurement := eaw.Experiment.newMeasurement(input)
 	start := time.Now()
-	err := eaw.Experiment.measurer.Run(ctx, eaw.session, measurement, eaw.callbacks)
+	err := eaw.Experiment.measurer.Run(ctx, eaw.session, measurement, callbacks)
 	stop := time.Now()
 	if err != nil {
 		return nil, err
@@ -169,21 +181,37 @@ func (e *Experiment) MeasureAsync(
 	} else {
 		async = &experimentAsyncWrapper{e}
 	}
-	in, err := async.RunAsync(ctx, e.session, input, e.callbacks)
+	// The watchdog interrupts the measurement if it stops making progress,
+	// so a single stuck input cannot stall a whole run.
+	measurementByteCounter := bytecounter.New()
+	ctx = bytecounter.WithMeasurementByteCounter(ctx, measurementByteCounter)
+	ctx, cancel := context.WithCancel(ctx)
+	wd := newWatchdog(e.session.watchdogInterval, e.session.watchdogClock,
+		measurementByteCounter, e.callbacks, e.session.Logger())
+	wd.start(cancel)
+	in, err := async.RunAsync(ctx, e.session, input, wd)
 	if err != nil {
+		wd.stop()
+		cancel()
 		return nil, err
 	}
 	out := make(chan *model.Measurement)
 	go func() {
 		defer close(out) // we need to signal the consumer we're done
+		defer cancel()
+		defer wd.stop()
 		for tk := range in {
+			wd.touch()
 			measurement := e.newMeasurement(input)
+			if wd.hasFired() {
+				measurement.AddAnnotation(watchdogAnnotation, wd.interval.String())
+			}
 			measurement.Extensions = tk.Extensions
 			measurement.Input = tk.Input
 			measurement.MeasurementRuntime = tk.MeasurementRuntime
 			measurement.TestHelpers = tk.TestHelpers
 			measurement.TestKeys = tk.TestKeys
-			if err := measurement.Scrub(e.session.ProbeIP()); err != nil {
+			if err := e.scrub(measurement); err != nil {
 				// If we fail to scrub the measurement then we are not going to
 				// submit it. Most likely causes of error here are unlikely,
 				// e.g., the TestKeys being not serializable.
@@ -249,6 +277,58 @@ func (e *Experiment) SubmitAndUpdateMeasurementContext(
 	return e.report.SubmitMeasurement(ctx, measurement)
 }
 
+// scrub scrubs the probe IP out of the measurement and redacts from its test
+// keys the private data matching the scrubbing rules of the session, unless
+// the measurer requires raw data (see model.ExperimentMeasurerWithRawData).
+func (e *Experiment) scrub(measurement *model.Measurement) error {
+	probeIP := e.session.ProbeIP()
+	if err := measurement.Scrub(probeIP); err != nil {
+		return err
+	}
+	if v, okay := e.measurer.(model.ExperimentMeasurerWithRawData); okay && v.RequiresRawData() {
+		return nil
+	}
+	rules := e.session.scrubbing
+	rules.ProbeIPs = append([]string{probeIP}, rules.ProbeIPs...)
+	// We scrub the string leaves of the decoded test keys rather than the
+	// serialized JSON, so we never rewrite keys or JSON syntax.
+	data, err := json.Marshal(measurement.TestKeys)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	value, changed := scrubber.New(rules).ScrubValue(value)
+	if !changed {
+		return nil
+	}
+	if data, err = json.Marshal(value); err != nil {
+		return err
+	}
+	measurement.TestKeys, err = experimentUnmarshalTestKeys(measurement.TestKeys, data)
+	return err
+}
+
+// experimentUnmarshalTestKeys unmarshals data into a new value having the same
+// type of the given test keys, such that the measurer can still use them (e.g.,
+// in GetSummaryKeys). When that is not possible, we unmarshal into a generic
+// value, like model.Measurement.MaybeRewriteTestKeys does.
+func experimentUnmarshalTestKeys(tk interface{}, data []byte) (interface{}, error) {
+	if t := reflect.TypeOf(tk); t != nil && t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem()).Interface()
+		if err := json.Unmarshal(data, v); err == nil {
+			return v, nil
+		}
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // newMeasurement creates a new measurement for this experiment with the given input.
 func (e *Experiment) newMeasurement(input string) *model.Measurement {
 	utctimenow := time.Now().UTC()
@@ -291,14 +371,17 @@ func (e *Experiment) OpenReportContext(ctx context.Context) error {
 			Counter:       e.byteCounter,
 		},
 	}
-	client, err := e.session.NewProbeServicesClient(ctx)
+	clients, err := e.session.newCollectorClients(ctx)
 	if err != nil {
 		e.session.logger.Debugf("%+v", err)
 		return err
 	}
-	client.HTTPClient = httpClient // patch HTTP client to use
+	for _, cc := range clients {
+		cc.client.HTTPClient = httpClient // patch HTTP client to use
+	}
+	opener := &sessionReportOpener{clients: clients, logger: e.session.logger}
 	template := e.newReportTemplate()
-	e.report, err = client.OpenReport(ctx, template)
+	e.report, e.collector, err = opener.openReport(ctx, template)
 	if err != nil {
 		e.session.logger.Debugf("experiment: probe services error: %s", err.Error())
 		return err
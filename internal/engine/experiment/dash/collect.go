@@ -6,8 +6,8 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
-	"net/url"
 
+	"github.com/ooni/probe-cli/v3/internal/mlablocate"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -21,17 +21,14 @@ type collectDeps interface {
 	UserAgent() string
 }
 
-func collect(ctx context.Context, fqdn, authorization string,
+func collect(ctx context.Context, server mlablocate.Result, authorization string,
 	results []clientResults, deps collectDeps) error {
 	data, err := deps.JSONMarshal(results)
 	if err != nil {
 		return err
 	}
 	deps.Logger().Debugf("dash: body: %s", string(data))
-	var URL url.URL
-	URL.Scheme = deps.Scheme()
-	URL.Host = fqdn
-	URL.Path = collectPath
+	URL := newServerURL(deps.Scheme(), server, collectPath)
 	req, err := deps.NewHTTPRequest("POST", URL.String(), bytes.NewReader(data))
 	if err != nil {
 		return err
@@ -9,12 +9,14 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/mlablocate"
 )
 
 func TestCollectJSONMarshalError(t *testing.T) {
 	expected := errors.New("mocked error")
 	deps := FakeDeps{jsonMarshalErr: expected}
-	err := collect(context.Background(), "", "", nil, deps)
+	err := collect(context.Background(), mlablocate.Result{}, "", nil, deps)
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
@@ -23,7 +25,7 @@ func TestCollectJSONMarshalError(t *testing.T) {
 func TestCollectNewHTTPRequestFailure(t *testing.T) {
 	expected := errors.New("mocked error")
 	deps := FakeDeps{newHTTPRequestErr: expected}
-	err := collect(context.Background(), "", "", nil, deps)
+	err := collect(context.Background(), mlablocate.Result{}, "", nil, deps)
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
@@ -36,7 +38,7 @@ func TestCollectHTTPClientDoFailure(t *testing.T) {
 		Header: http.Header{},
 		URL:    &url.URL{},
 	}}
-	err := collect(context.Background(), "", "", nil, deps)
+	err := collect(context.Background(), mlablocate.Result{}, "", nil, deps)
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
@@ -48,7 +50,7 @@ func TestCollectInternalError(t *testing.T) {
 		Header: http.Header{},
 		URL:    &url.URL{},
 	}}
-	err := collect(context.Background(), "", "", nil, deps)
+	err := collect(context.Background(), mlablocate.Result{}, "", nil, deps)
 	if !errors.Is(err, errHTTPRequestFailed) {
 		t.Fatal("not the error we expected")
 	}
@@ -68,7 +70,7 @@ func TestCollectReadAllFailure(t *testing.T) {
 		},
 		readAllErr: expected,
 	}
-	err := collect(context.Background(), "", "", nil, deps)
+	err := collect(context.Background(), mlablocate.Result{}, "", nil, deps)
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
@@ -87,7 +89,7 @@ func TestCollectInvalidJSON(t *testing.T) {
 		},
 		readAllResult: []byte("["),
 	}
-	err := collect(context.Background(), "", "", nil, deps)
+	err := collect(context.Background(), mlablocate.Result{}, "", nil, deps)
 	if err == nil || !strings.HasSuffix(err.Error(), "unexpected end of JSON input") {
 		t.Fatal("not the error we expected")
 	}
@@ -106,7 +108,7 @@ func TestCollectSuccess(t *testing.T) {
 		},
 		readAllResult: []byte("[]"),
 	}
-	err := collect(context.Background(), "", "", nil, deps)
+	err := collect(context.Background(), mlablocate.Result{}, "", nil, deps)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -17,6 +17,7 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/engine/netx"
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
 	"github.com/ooni/probe-cli/v3/internal/humanize"
+	"github.com/ooni/probe-cli/v3/internal/mlablocate"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
@@ -25,7 +26,7 @@ const (
 	defaultTimeout = 120 * time.Second
 	magicVersion   = "0.008000000"
 	testName       = "dash"
-	testVersion    = "0.13.0"
+	testVersion    = "0.14.0"
 	totalStep      = 15
 )
 
@@ -35,7 +36,22 @@ var (
 )
 
 // Config contains the experiment config.
-type Config struct{}
+type Config struct {
+	Server string `ooni:"use the given dash server rather than asking m-lab locate"`
+	Site   string `ooni:"only use dash servers at the given m-lab site (e.g., mil04)"`
+}
+
+// pinned returns the config option pinning the server, if any.
+func (c Config) pinned() string {
+	switch {
+	case c.Server != "":
+		return "server"
+	case c.Site != "":
+		return "site"
+	default:
+		return ""
+	}
+}
 
 // Simple contains the experiment total summary
 type Simple struct {
@@ -51,6 +67,15 @@ type Simple struct {
 type ServerInfo struct {
 	Hostname string `json:"hostname"`
 	Site     string `json:"site,omitempty"`
+
+	// Pinned is the config option the user set to pin the
+	// server ("server" or "site"), if any. Pinning allows to
+	// compare measurements performed using the same server.
+	Pinned string `json:"pinned,omitempty"`
+
+	// Candidates contains the servers whose latency we probed
+	// to select the fastest server, if any.
+	Candidates []mlablocate.Candidate `json:"candidates,omitempty"`
 }
 
 // TestKeys contains the test keys
@@ -63,6 +88,7 @@ type TestKeys struct {
 
 type runner struct {
 	callbacks  model.ExperimentCallbacks
+	config     Config
 	httpClient *http.Client
 	saver      *trace.Saver
 	sess       model.ExperimentSession
@@ -73,6 +99,10 @@ func (r runner) HTTPClient() *http.Client {
 	return r.httpClient
 }
 
+func (r runner) KeyValueStore() model.KeyValueStore {
+	return r.sess.KeyValueStore()
+}
+
 func (r runner) JSONMarshal(v interface{}) ([]byte, error) {
 	return json.Marshal(v)
 }
@@ -98,25 +128,26 @@ func (r runner) UserAgent() string {
 }
 
 func (r runner) loop(ctx context.Context, numIterations int64) error {
-	locateResult, err := locate(ctx, r)
+	locateResult, candidates, err := locate(ctx, r.config, r)
 	if err != nil {
 		return err
 	}
 	r.tk.Server = ServerInfo{
-		Hostname: locateResult.FQDN,
-		Site:     locateResult.Site,
+		Hostname:   locateResult.FQDN,
+		Site:       locateResult.Site,
+		Pinned:     r.config.pinned(),
+		Candidates: candidates,
 	}
-	fqdn := locateResult.FQDN
-	r.callbacks.OnProgress(0.0, fmt.Sprintf("streaming: server: %s", fqdn))
-	negotiateResp, err := negotiate(ctx, fqdn, r)
+	r.callbacks.OnProgress(0.0, fmt.Sprintf("streaming: server: %s", locateResult.FQDN))
+	negotiateResp, err := negotiate(ctx, locateResult, r)
 	if err != nil {
 		return err
 	}
-	if err := r.measure(ctx, fqdn, negotiateResp, numIterations); err != nil {
+	if err := r.measure(ctx, locateResult, negotiateResp, numIterations); err != nil {
 		return err
 	}
 	// TODO(bassosimone): it seems we're not saving the server data?
-	err = collect(ctx, fqdn, negotiateResp.Authorization, r.tk.ReceiverData, r)
+	err = collect(ctx, locateResult, negotiateResp.Authorization, r.tk.ReceiverData, r)
 	if err != nil {
 		return err
 	}
@@ -124,7 +155,7 @@ func (r runner) loop(ctx context.Context, numIterations int64) error {
 }
 
 func (r runner) measure(
-	ctx context.Context, fqdn string, negotiateResp negotiateResponse,
+	ctx context.Context, server mlablocate.Result, negotiateResp negotiateResponse,
 	numIterations int64) error {
 	// Note: according to a comment in MK sources 3000 kbit/s was the
 	// minimum speed recommended by Netflix for SD quality in 2017.
@@ -150,7 +181,7 @@ func (r runner) measure(
 			currentRate:   current.Rate,
 			deps:          r,
 			elapsedTarget: current.ElapsedTarget,
-			fqdn:          fqdn,
+			server:        server,
 		})
 		if err != nil {
 			// Implementation note: ndt7 controls the connection much
@@ -255,7 +286,7 @@ func (m Measurer) Run(
 ) error {
 	tk := new(TestKeys)
 	measurement.TestKeys = tk
-	saver := &trace.Saver{}
+	saver := trace.NewSaver(sess.MemoryBudget())
 	httpClient := &http.Client{
 		Transport: netx.NewHTTPTransport(netx.Config{
 			ContextByteCounting: true,
@@ -266,6 +297,7 @@ func (m Measurer) Run(
 	defer httpClient.CloseIdleConnections()
 	r := runner{
 		callbacks:  callbacks,
+		config:     m.config,
 		httpClient: httpClient,
 		saver:      saver,
 		sess:       sess,
@@ -150,6 +150,55 @@ func TestRunnerLoopCollectFailure(t *testing.T) {
 	}
 }
 
+func TestRunnerLoopWithPinnedServer(t *testing.T) {
+	saver := new(trace.Saver)
+	saver.Write(trace.Event{Name: netxlite.ConnectOperation, Duration: 150 * time.Millisecond})
+	tk := new(TestKeys)
+	r := runner{
+		callbacks: model.NewPrinterCallbacks(log.Log),
+		config:    Config{Server: "dash.example.com"},
+		httpClient: &http.Client{
+			Transport: &FakeHTTPTransportStack{
+				all: []FakeHTTPTransport{
+					{
+						resp: &http.Response{
+							Body: io.NopCloser(strings.NewReader(
+								`{"authorization": "xx", "unchoked": 1}`)),
+							StatusCode: 200,
+						},
+					},
+					{
+						resp: &http.Response{
+							Body:       io.NopCloser(strings.NewReader(`1234567`)),
+							StatusCode: 200,
+						},
+					},
+					{
+						resp: &http.Response{
+							Body:       io.NopCloser(strings.NewReader(`[]`)),
+							StatusCode: 200,
+						},
+					},
+				},
+			},
+		},
+		saver: saver,
+		sess: &mockable.Session{
+			MockableLogger: log.Log,
+		},
+		tk: tk,
+	}
+	if err := r.loop(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if tk.Server.Hostname != "dash.example.com" {
+		t.Fatal("not the Hostname we expected")
+	}
+	if tk.Server.Pinned != "server" {
+		t.Fatal("not the Pinned we expected")
+	}
+}
+
 func TestRunnerLoopSuccess(t *testing.T) {
 	saver := new(trace.Saver)
 	saver.Write(trace.Event{Name: netxlite.ConnectOperation, Duration: 150 * time.Millisecond})
@@ -261,7 +310,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "dash" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.13.0" {
+	if measurer.ExperimentVersion() != "0.14.0" {
 		t.Fatal("unexpected version")
 	}
 }
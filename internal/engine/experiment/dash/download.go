@@ -5,8 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/mlablocate"
 )
 
 type downloadDeps interface {
@@ -23,7 +24,7 @@ type downloadConfig struct {
 	currentRate   int64
 	deps          downloadDeps
 	elapsedTarget int64
-	fqdn          string
+	server        mlablocate.Result
 }
 
 type downloadResult struct {
@@ -36,10 +37,7 @@ type downloadResult struct {
 
 func download(ctx context.Context, config downloadConfig) (downloadResult, error) {
 	nbytes := (config.currentRate * 1000 * config.elapsedTarget) >> 3
-	var URL url.URL
-	URL.Scheme = config.deps.Scheme()
-	URL.Host = config.fqdn
-	URL.Path = fmt.Sprintf("%s%d", downloadPath, nbytes)
+	URL := newServerURL(config.deps.Scheme(), config.server, fmt.Sprintf("%s%d", downloadPath, nbytes))
 	req, err := config.deps.NewHTTPRequest("GET", URL.String(), nil)
 	var result downloadResult
 	if err != nil {
@@ -3,6 +3,9 @@ package dash
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/ooni/probe-cli/v3/internal/mlablocate"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -10,11 +13,70 @@ import (
 
 type locateDeps interface {
 	HTTPClient() *http.Client
+	KeyValueStore() model.KeyValueStore
 	Logger() model.Logger
 	UserAgent() string
 }
 
-func locate(ctx context.Context, deps locateDeps) (mlablocate.Result, error) {
-	return mlablocate.NewClient(
-		deps.HTTPClient(), deps.Logger(), deps.UserAgent()).Query(ctx, "neubot")
+// locate returns the fastest among the nearest dash servers along with
+// the candidates whose latency we probed to select it. When the
+// config pins a server, we use it without asking m-lab locate. When the
+// config pins a site, we only use the servers at that site, which requires
+// using v2 of the locate API, because v1 does not support sites. Because v2
+// returns URLs containing access tokens, you MUST use newServerURL to
+// build the URLs of the server returned by this function.
+func locate(ctx context.Context, config Config,
+	deps locateDeps) (mlablocate.Result, []mlablocate.Candidate, error) {
+	if config.Server != "" {
+		return mlablocate.Result{FQDN: config.Server}, nil, nil
+	}
+	client := mlablocate.NewClient(deps.HTTPClient(), deps.Logger(), deps.UserAgent())
+	client.KVStore = deps.KeyValueStore() // allows using cached servers
+	tool := "neubot"
+	if config.Site != "" {
+		client.Version = mlablocate.V2
+		client.Params.Site = config.Site
+		tool = "neubot/dash"
+	}
+	results, err := client.Query(ctx, tool)
+	if err != nil {
+		return mlablocate.Result{}, nil, err
+	}
+	var fqdns []string
+	for _, result := range results {
+		fqdns = append(fqdns, result.FQDN)
+	}
+	idx, candidates := client.Fastest(ctx, fqdns, mlablocate.DefaultCandidates)
+	return results[idx], candidates, nil
+}
+
+// newServerURL returns the URL with the given scheme and path of the
+// given server returned by locate. When locate used v2, the server's
+// URLs contain access tokens, hence we use the host and the query of
+// the URL whose path is a prefix of path or, if none, of any URL.
+func newServerURL(scheme string, server mlablocate.Result, path string) *url.URL {
+	URL := &url.URL{Scheme: scheme, Host: server.FQDN, Path: path}
+	var keys []string
+	for key := range server.URLs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // be deterministic
+	var chosen *url.URL
+	for _, key := range keys {
+		parsed, err := url.Parse(server.URLs[key])
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		if strings.HasPrefix(path, parsed.Path) {
+			chosen = parsed
+			break
+		}
+		if chosen == nil {
+			chosen = parsed
+		}
+	}
+	if chosen != nil {
+		URL.Host, URL.RawQuery = chosen.Host, chosen.RawQuery
+	}
+	return URL
 }
@@ -0,0 +1,39 @@
+package dash
+
+import (
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/mlablocate"
+)
+
+func TestNewServerURL(t *testing.T) {
+	t.Run("without URLs", func(t *testing.T) {
+		server := mlablocate.Result{FQDN: "neubot.mlab.mil04.measurement-lab.org"}
+		URL := newServerURL("https", server, negotiatePath)
+		if URL.String() != "https://neubot.mlab.mil04.measurement-lab.org/negotiate/dash" {
+			t.Fatal("unexpected URL", URL.String())
+		}
+	})
+
+	t.Run("with URLs", func(t *testing.T) {
+		server := mlablocate.Result{
+			FQDN: "dash-mlab1-mil04.mlab-oti.measurement-lab.org",
+			URLs: map[string]string{
+				"https:///collect/dash":   "https://dash-mlab1-mil04.mlab-oti.measurement-lab.org/collect/dash?access_token=aa",
+				"https:///dash/download":  "https://dash-mlab1-mil04.mlab-oti.measurement-lab.org/dash/download?access_token=bb",
+				"https:///negotiate/dash": "https://dash-mlab1-mil04.mlab-oti.measurement-lab.org/negotiate/dash?access_token=cc",
+			},
+		}
+		expectations := map[string]string{
+			negotiatePath:          "https://dash-mlab1-mil04.mlab-oti.measurement-lab.org/negotiate/dash?access_token=cc",
+			downloadPath + "12345": "https://dash-mlab1-mil04.mlab-oti.measurement-lab.org/dash/download/12345?access_token=bb",
+			collectPath:            "https://dash-mlab1-mil04.mlab-oti.measurement-lab.org/collect/dash?access_token=aa",
+			"/other":               "https://dash-mlab1-mil04.mlab-oti.measurement-lab.org/other?access_token=aa",
+		}
+		for path, expected := range expectations {
+			if URL := newServerURL("https", server, path); URL.String() != expected {
+				t.Fatal("unexpected URL", URL.String())
+			}
+		}
+	})
+}
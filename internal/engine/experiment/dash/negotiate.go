@@ -6,8 +6,8 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
-	"net/url"
 
+	"github.com/ooni/probe-cli/v3/internal/mlablocate"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -22,17 +22,14 @@ type negotiateDeps interface {
 }
 
 func negotiate(
-	ctx context.Context, fqdn string, deps negotiateDeps) (negotiateResponse, error) {
+	ctx context.Context, server mlablocate.Result, deps negotiateDeps) (negotiateResponse, error) {
 	var negotiateResp negotiateResponse
 	data, err := deps.JSONMarshal(negotiateRequest{DASHRates: defaultRates})
 	if err != nil {
 		return negotiateResp, err
 	}
 	deps.Logger().Debugf("dash: body: %s", string(data))
-	var URL url.URL
-	URL.Scheme = deps.Scheme()
-	URL.Host = fqdn
-	URL.Path = negotiatePath
+	URL := newServerURL(deps.Scheme(), server, negotiatePath)
 	req, err := deps.NewHTTPRequest("POST", URL.String(), bytes.NewReader(data))
 	if err != nil {
 		return negotiateResp, err
@@ -9,12 +9,14 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/mlablocate"
 )
 
 func TestNegotiateJSONMarshalError(t *testing.T) {
 	expected := errors.New("mocked error")
 	deps := FakeDeps{jsonMarshalErr: expected}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
@@ -26,7 +28,7 @@ func TestNegotiateJSONMarshalError(t *testing.T) {
 func TestNegotiateNewHTTPRequestFailure(t *testing.T) {
 	expected := errors.New("mocked error")
 	deps := FakeDeps{newHTTPRequestErr: expected}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
@@ -42,7 +44,7 @@ func TestNegotiateHTTPClientDoFailure(t *testing.T) {
 		Header: http.Header{},
 		URL:    &url.URL{},
 	}}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
@@ -57,7 +59,7 @@ func TestNegotiateInternalError(t *testing.T) {
 		Header: http.Header{},
 		URL:    &url.URL{},
 	}}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if !errors.Is(err, errHTTPRequestFailed) {
 		t.Fatal("not the error we expected")
 	}
@@ -80,7 +82,7 @@ func TestNegotiateReadAllFailure(t *testing.T) {
 		},
 		readAllErr: expected,
 	}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
@@ -102,7 +104,7 @@ func TestNegotiateInvalidJSON(t *testing.T) {
 		},
 		readAllResult: []byte("["),
 	}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if err == nil || !strings.HasSuffix(err.Error(), "unexpected end of JSON input") {
 		t.Fatal("not the error we expected")
 	}
@@ -124,7 +126,7 @@ func TestNegotiateServerBusyFirstCase(t *testing.T) {
 		},
 		readAllResult: []byte(`{"authorization": ""}`),
 	}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if !errors.Is(err, errServerBusy) {
 		t.Fatal("not the error we expected")
 	}
@@ -146,7 +148,7 @@ func TestNegotiateServerBusyThirdCase(t *testing.T) {
 		},
 		readAllResult: []byte(`{}`),
 	}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if !errors.Is(err, errServerBusy) {
 		t.Fatal("not the error we expected")
 	}
@@ -168,7 +170,7 @@ func TestNegotiateSuccess(t *testing.T) {
 		},
 		readAllResult: []byte(`{"authorization": "xx", "unchoked": 1}`),
 	}
-	result, err := negotiate(context.Background(), "", deps)
+	result, err := negotiate(context.Background(), mlablocate.Result{}, deps)
 	if err != nil {
 		t.Fatal(err)
 	}
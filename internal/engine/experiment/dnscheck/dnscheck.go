@@ -110,6 +110,13 @@ func (m *Measurer) ExperimentVersion() string {
 	return testVersion
 }
 
+// RequiresRawData implements model.ExperimentMeasurerWithRawData.RequiresRawData. We
+// check resolvers configured by the user, hence we must not redact the domains
+// and the resolver URLs we measure.
+func (m *Measurer) RequiresRawData() bool {
+	return true
+}
+
 // The following errors may be returned by this experiment. Of course these
 // errors are in addition to any other errors returned by the low level packages
 // that are used by this experiment to implement its functionality.
@@ -168,7 +175,7 @@ func (m *Measurer) Run(
 	// with IP addresses successfully, we just get back the IPs when we are
 	// passing as input an IP address rather than a domain name.
 	begin := measurement.MeasurementStartTimeSaved
-	evsaver := new(trace.Saver)
+	evsaver := trace.NewSaver(sess.MemoryBudget())
 	resolver := netx.NewResolver(netx.Config{
 		BogonIsError: true,
 		Logger:       sess.Logger(),
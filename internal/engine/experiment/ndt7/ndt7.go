@@ -8,22 +8,36 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/humanize"
-	"github.com/ooni/probe-cli/v3/internal/mlablocatev2"
+	"github.com/ooni/probe-cli/v3/internal/mlablocate"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 const (
 	testName    = "ndt"
-	testVersion = "0.10.0"
+	testVersion = "0.11.0"
 )
 
 // Config contains the experiment settings
 type Config struct {
+	Server     string `ooni:"use the given ndt7 server rather than asking m-lab locate"`
+	Site       string `ooni:"only use ndt7 servers at the given m-lab site (e.g., mil04)"`
 	noDownload bool
 	noUpload   bool
 }
 
+// pinned returns the config option pinning the server, if any.
+func (c Config) pinned() string {
+	switch {
+	case c.Server != "":
+		return "server"
+	case c.Site != "":
+		return "site"
+	default:
+		return ""
+	}
+}
+
 // Summary is the measurement summary
 type Summary struct {
 	AvgRTT         float64 `json:"avg_rtt"`         // Average RTT [ms]
@@ -43,6 +57,15 @@ type Summary struct {
 type ServerInfo struct {
 	Hostname string `json:"hostname"`
 	Site     string `json:"site,omitempty"`
+
+	// Pinned is the config option the user set to pin the
+	// server ("server" or "site"), if any. Pinning allows to
+	// compare measurements performed using the same server.
+	Pinned string `json:"pinned,omitempty"`
+
+	// Candidates contains the servers whose latency we probed
+	// to select the fastest server, if any.
+	Candidates []mlablocate.Candidate `json:"candidates,omitempty"`
 }
 
 // TestKeys contains the test keys
@@ -74,16 +97,34 @@ type Measurer struct {
 	preUploadHook   func()
 }
 
-func (m *Measurer) discover(
-	ctx context.Context, sess model.ExperimentSession) (mlablocatev2.NDT7Result, error) {
+func (m *Measurer) discover(ctx context.Context,
+	sess model.ExperimentSession) (mlablocate.NDT7Result, []mlablocate.Candidate, error) {
+	if m.config.Server != "" {
+		// A custom server (e.g., a self hosted ndt-server) does
+		// not require the access tokens given by m-lab locate.
+		return mlablocate.NDT7Result{
+			Hostname:       m.config.Server,
+			WSSDownloadURL: fmt.Sprintf("wss://%s/ndt/v7/download", m.config.Server),
+			WSSUploadURL:   fmt.Sprintf("wss://%s/ndt/v7/upload", m.config.Server),
+		}, nil, nil
+	}
 	httpClient := netxlite.NewHTTPClientStdlib(sess.Logger())
 	defer httpClient.CloseIdleConnections()
-	client := mlablocatev2.NewClient(httpClient, sess.Logger(), sess.UserAgent())
+	client := mlablocate.NewClient(httpClient, sess.Logger(), sess.UserAgent())
+	client.Params.Site = m.config.Site // only use servers at this site, if set
+	// Note that we cannot fall back to cached servers when the locate
+	// service is unreachable, because QueryNDT7 uses v2, whose access
+	// tokens expire quickly (see mlablocate.Client.KVStore).
 	out, err := client.QueryNDT7(ctx)
 	if err != nil {
-		return mlablocatev2.NDT7Result{}, err
+		return mlablocate.NDT7Result{}, nil, err
+	}
+	var hostnames []string
+	for _, result := range out {
+		hostnames = append(hostnames, result.Hostname)
 	}
-	return out[0], nil // same as with locate services v1
+	idx, candidates := client.Fastest(ctx, hostnames, mlablocate.DefaultCandidates)
+	return out[idx], candidates, nil
 }
 
 // ExperimentName implements ExperimentMeasurer.ExperiExperimentName.
@@ -217,14 +258,16 @@ func (m *Measurer) Run(
 	tk := new(TestKeys)
 	tk.Protocol = 7
 	measurement.TestKeys = tk
-	locateResult, err := m.discover(ctx, sess)
+	locateResult, candidates, err := m.discover(ctx, sess)
 	if err != nil {
 		tk.Failure = failureFromError(err)
 		return nil // we still want to submit this measurement
 	}
 	tk.Server = ServerInfo{
-		Hostname: locateResult.Hostname,
-		Site:     locateResult.Site,
+		Hostname:   locateResult.Hostname,
+		Site:       locateResult.Site,
+		Pinned:     m.config.pinned(),
+		Candidates: candidates,
 	}
 	callbacks.OnProgress(0, fmt.Sprintf(" download: url: %s", locateResult.WSSDownloadURL))
 	if m.preDownloadHook != nil {
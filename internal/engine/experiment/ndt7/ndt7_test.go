@@ -17,7 +17,7 @@ func TestNewExperimentMeasurer(t *testing.T) {
 	if measurer.ExperimentName() != "ndt" {
 		t.Fatal("unexpected name")
 	}
-	if measurer.ExperimentVersion() != "0.10.0" {
+	if measurer.ExperimentVersion() != "0.11.0" {
 		t.Fatal("unexpected version")
 	}
 }
@@ -31,7 +31,7 @@ func TestDiscoverCancelledContext(t *testing.T) {
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // immediately cancel
-	locateResult, err := m.discover(ctx, sess)
+	locateResult, _, err := m.discover(ctx, sess)
 	if !errors.Is(err, context.Canceled) {
 		t.Fatal("not the error we expected")
 	}
@@ -40,6 +40,62 @@ func TestDiscoverCancelledContext(t *testing.T) {
 	}
 }
 
+func TestDiscoverWithCustomServer(t *testing.T) {
+	m := &Measurer{config: Config{Server: "ndt.example.com"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // we should not use the network
+	locateResult, _, err := m.discover(ctx, &mockable.Session{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locateResult.Hostname != "ndt.example.com" {
+		t.Fatal("not the Hostname we expected")
+	}
+	if locateResult.WSSDownloadURL != "wss://ndt.example.com/ndt/v7/download" {
+		t.Fatal("not the WSSDownloadURL we expected")
+	}
+	if locateResult.WSSUploadURL != "wss://ndt.example.com/ndt/v7/upload" {
+		t.Fatal("not the WSSUploadURL we expected")
+	}
+}
+
+func TestRunWithPinnedServer(t *testing.T) {
+	measurer := NewExperimentMeasurer(Config{
+		Server:     "ndt.example.com",
+		noDownload: true,
+		noUpload:   true,
+	})
+	meas := &model.Measurement{}
+	err := measurer.Run(context.Background(), &mockable.Session{
+		MockableLogger: log.Log,
+	}, meas, model.NewPrinterCallbacks(log.Log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tk := meas.TestKeys.(*TestKeys)
+	if tk.Failure != nil {
+		t.Fatal("unexpected failure", *tk.Failure)
+	}
+	if tk.Server.Hostname != "ndt.example.com" {
+		t.Fatal("not the Hostname we expected")
+	}
+	if tk.Server.Pinned != "server" {
+		t.Fatal("not the Pinned we expected")
+	}
+}
+
+func TestConfigPinned(t *testing.T) {
+	if v := (Config{}).pinned(); v != "" {
+		t.Fatal("unexpected value", v)
+	}
+	if v := (Config{Site: "mil04"}).pinned(); v != "site" {
+		t.Fatal("unexpected value", v)
+	}
+	if v := (Config{Server: "ndt.example.com", Site: "mil04"}).pinned(); v != "server" {
+		t.Fatal("unexpected value", v)
+	}
+}
+
 func TestDoDownloadWithCancelledContext(t *testing.T) {
 	m := new(Measurer)
 	sess := &mockable.Session{
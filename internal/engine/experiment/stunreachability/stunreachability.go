@@ -113,7 +113,7 @@ func (tk *TestKeys) run(
 	defer callbacks.OnProgress(
 		1, fmt.Sprintf("stunreachability: measuring: %s... done", endpoint))
 	tk.Endpoint = endpoint
-	saver := new(trace.Saver)
+	saver := trace.NewSaver(sess.MemoryBudget())
 	begin := time.Now()
 	err := tk.do(ctx, config, netx.NewDialer(netx.Config{
 		ContextByteCounting: true,
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/archival"
+	"github.com/ooni/probe-cli/v3/internal/engine/netx/otlp"
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -50,7 +51,7 @@ func (g Getter) Get(ctx context.Context) (TestKeys, error) {
 	if g.Begin.IsZero() {
 		g.Begin = time.Now()
 	}
-	saver := new(trace.Saver)
+	saver := trace.NewSaver(g.Session.MemoryBudget())
 	tk, err := g.get(ctx, saver)
 	// Make sure we have an operation in cases where we fail before
 	// hitting our httptransport that does error wrapping.
@@ -59,29 +60,74 @@ func (g Getter) Get(ctx context.Context) (TestKeys, error) {
 	}
 	tk.FailedOperation = archival.NewFailedOperation(err)
 	tk.Failure = archival.NewFailure(err)
-	events := saver.Read()
-	tk.Queries = append(tk.Queries, archival.NewDNSQueriesList(g.Begin, events)...)
+	collector := &trace.Collector{}
+	collector.Add(saver.Read()...)
+	events := collector.Events()
+	g.exportSpans(events)
+	tk.Queries = append(
+		tk.Queries, archival.NewDNSQueriesList(g.Begin, collector.ByName("resolve_done"))...,
+	)
 	tk.NetworkEvents = append(
 		tk.NetworkEvents, archival.NewNetworkEventsList(g.Begin, events)...,
 	)
-	tk.Requests = append(
-		tk.Requests, archival.NewRequestList(g.Begin, events)...,
-	)
+	tk.Requests = append(tk.Requests, archival.NewRequestList(g.Begin, collector.ByName(
+		"http_transaction_start", "http_request_body_snapshot", "http_request_metadata",
+		"http_response_metadata", "http_response_body_snapshot", "http_transaction_done",
+	))...)
 	if len(tk.Requests) > 0 {
 		// OONI's convention is that the last request appears first
 		tk.HTTPResponseStatus = tk.Requests[0].Response.Code
 		tk.HTTPResponseBody = tk.Requests[0].Response.Body.Value
 		tk.HTTPResponseLocations = tk.Requests[0].Response.Locations
 	}
-	tk.TCPConnect = append(
-		tk.TCPConnect, archival.NewTCPConnectList(g.Begin, events)...,
-	)
-	tk.TLSHandshakes = append(
-		tk.TLSHandshakes, archival.NewTLSHandshakesList(g.Begin, events)...,
-	)
+	tk.TCPConnect = append(tk.TCPConnect, archival.NewTCPConnectList(
+		g.Begin, collector.ByName(netxlite.ConnectOperation))...)
+	tk.TLSHandshakes = append(tk.TLSHandshakes, archival.NewTLSHandshakesList(
+		g.Begin, collector.ByName("tls_handshake_done", "quic_handshake_done"))...)
 	return tk, err
 }
 
+// spanExporterProvider is a session providing the exporter of
+// OpenTelemetry spans (e.g., engine.Session).
+type spanExporterProvider interface {
+	SpanExporter() *otlp.Exporter
+}
+
+// exportSpans exports the events as OpenTelemetry spans when the
+// session provides an exporter, i.e., when the user has configured
+// an OTLP collector (see the otlp package).
+func (g Getter) exportSpans(events []trace.Event) {
+	provider, okay := g.Session.(spanExporterProvider)
+	if !okay {
+		return
+	}
+	exporter := provider.SpanExporter()
+	if exporter == nil {
+		return
+	}
+	attributes := map[string]string{"ooni.input": g.Target}
+	err := exporter.Export(context.Background(), "urlgetter", attributes, g.Begin, events)
+	if err != nil {
+		g.Session.Logger().Warnf("urlgetter: cannot export spans: %s", err.Error())
+	}
+}
+
+// tunnelStarter is a session starting tunnels on behalf of the
+// experiments (e.g., mockable.Session for testing offline).
+type tunnelStarter interface {
+	StartTunnel(ctx context.Context, config *tunnel.Config) (tunnel.Tunnel, tunnel.DebugInfo, error)
+}
+
+// startTunnel starts a tunnel using either the session, when it
+// implements tunnelStarter, or tunnel.Start.
+func (g Getter) startTunnel(
+	ctx context.Context, config *tunnel.Config) (tunnel.Tunnel, tunnel.DebugInfo, error) {
+	if ts, okay := g.Session.(tunnelStarter); okay {
+		return ts.StartTunnel(ctx, config)
+	}
+	return tunnel.Start(ctx, config)
+}
+
 // ioutilTempDir calls either g.testIOUtilTempDir or ioutil.TempDir
 func (g Getter) ioutilTempDir(dir, pattern string) (string, error) {
 	if g.testIOUtilTempDir != nil {
@@ -111,7 +157,7 @@ func (g Getter) get(ctx context.Context, saver *trace.Saver) (TestKeys, error) {
 		if err != nil {
 			return tk, err
 		}
-		tun, _, err := tunnel.Start(ctx, &tunnel.Config{
+		tun, _, err := g.startTunnel(ctx, &tunnel.Config{
 			Name:      g.Config.Tunnel,
 			Session:   g.Session,
 			TorArgs:   g.Session.TorArgs(),
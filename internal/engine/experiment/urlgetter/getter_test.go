@@ -4,12 +4,46 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 
 	"github.com/apex/log"
 	"github.com/ooni/probe-cli/v3/internal/engine/mockable"
+	"github.com/ooni/probe-cli/v3/internal/engine/netx/otlp"
 )
 
+func TestGetterStartsTheTunnelUsingTheSession(t *testing.T) {
+	expected := errors.New("mocked error")
+	var names []string
+	g := Getter{
+		Config: Config{
+			NoFollowRedirects: true, // reduce number of events
+			Tunnel:            "psiphon",
+		},
+		Session: &mockable.Session{
+			MockableHTTPClient: http.DefaultClient,
+			MockableLogger:     log.Log,
+			MockableTempDir:    t.TempDir(),
+			MockableInjectError: func(method string) error {
+				names = append(names, method)
+				return expected
+			},
+		},
+		Target: "https://www.google.com",
+	}
+	tk, err := g.Get(context.Background())
+	if !errors.Is(err, expected) {
+		t.Fatal("not the error we expected", err)
+	}
+	if len(names) != 1 || names[0] != "StartTunnel" {
+		t.Fatal("unexpected calls", names)
+	}
+	if tk.Failure == nil || *tk.Failure != "unknown_failure: mocked error" {
+		t.Fatal("not the Failure we expected")
+	}
+}
+
 func TestGetterHTTPSWithTunnelCannotCreateTempDir(t *testing.T) {
 	expected := errors.New("mocked error")
 	ctx := context.Background()
@@ -71,3 +105,41 @@ func TestGetterHTTPSWithTunnelCannotCreateTempDir(t *testing.T) {
 		t.Fatal("not the HTTPResponseBody we expected")
 	}
 }
+
+// sessionWithSpanExporter is a mockable.Session providing a span exporter.
+type sessionWithSpanExporter struct {
+	*mockable.Session
+	exporter *otlp.Exporter
+}
+
+func (s *sessionWithSpanExporter) SpanExporter() *otlp.Exporter {
+	return s.exporter
+}
+
+func TestGetterExportsSpansUsingTheSessionExporter(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer target.Close()
+	var exported int64
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&exported, 1)
+	}))
+	defer collector.Close()
+	g := Getter{
+		Session: &sessionWithSpanExporter{
+			Session: &mockable.Session{
+				MockableHTTPClient: http.DefaultClient,
+				MockableLogger:     log.Log,
+			},
+			exporter: otlp.NewExporter(collector.URL, http.DefaultClient),
+		},
+		Target: target.URL,
+	}
+	if _, err := g.Get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt64(&exported) != 1 {
+		t.Fatal("unexpected number of exports", exported)
+	}
+}
@@ -2,8 +2,11 @@ package engine
 
 import (
 	"os"
+	"testing"
 
+	"github.com/ooni/probe-cli/v3/internal/engine/geolocate"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/scrubber"
 )
 
 func (e *Experiment) SaveMeasurementEx(
@@ -14,3 +17,96 @@ func (e *Experiment) SaveMeasurementEx(
 ) error {
 	return e.saveMeasurement(measurement, filePath, marshal, openFile, write)
 }
+
+// scrubTestMeasurer is a measurer that may require raw data.
+type scrubTestMeasurer struct {
+	model.ExperimentMeasurer
+	raw bool
+}
+
+func (m *scrubTestMeasurer) RequiresRawData() bool {
+	return m.raw
+}
+
+// scrubTestKeys are the test keys used for testing scrubbing.
+type scrubTestKeys struct {
+	Hostname string `json:"hostname"`
+	ProbeIP  string `json:"probe_ip"`
+}
+
+func TestExperimentScrub(t *testing.T) {
+	newExperiment := func(raw bool) *Experiment {
+		return &Experiment{
+			measurer: &scrubTestMeasurer{raw: raw},
+			session: &Session{
+				location: &geolocate.Results{ProbeIP: "2001:db8::1"},
+				scrubbing: scrubber.Rules{
+					MACHostnames: true,
+					Strings:      []string{"laptop"},
+				},
+			},
+		}
+	}
+	newMeasurement := func() *model.Measurement {
+		return &model.Measurement{TestKeys: &scrubTestKeys{
+			Hostname: "00-1a-2b-3c-4d-5e.laptop.example.com",
+			ProbeIP:  "2001:0db8:0000:0000:0000:0000:0000:0001",
+		}}
+	}
+
+	t.Run("with the scrubbing rules", func(t *testing.T) {
+		measurement := newMeasurement()
+		if err := newExperiment(false).scrub(measurement); err != nil {
+			t.Fatal(err)
+		}
+		tk, okay := measurement.TestKeys.(*scrubTestKeys)
+		if !okay {
+			t.Fatalf("unexpected test keys type %T", measurement.TestKeys)
+		}
+		if tk.Hostname != "[scrubbed].[scrubbed].example.com" || tk.ProbeIP != "[scrubbed]" {
+			t.Fatal("unexpected test keys", tk)
+		}
+		if measurement.ProbeIP != model.DefaultProbeIP {
+			t.Fatal("unexpected probe IP", measurement.ProbeIP)
+		}
+	})
+
+	t.Run("when the measurer requires raw data", func(t *testing.T) {
+		measurement := newMeasurement()
+		if err := newExperiment(true).scrub(measurement); err != nil {
+			t.Fatal(err)
+		}
+		tk := measurement.TestKeys.(*scrubTestKeys)
+		if tk.Hostname != "00-1a-2b-3c-4d-5e.laptop.example.com" {
+			t.Fatal("unexpected test keys", tk)
+		}
+	})
+}
+
+func TestExperimentUnmarshalTestKeys(t *testing.T) {
+	t.Run("with a pointer to struct", func(t *testing.T) {
+		tk, err := experimentUnmarshalTestKeys(&scrubTestKeys{}, []byte(`{"hostname":"x"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tk.(*scrubTestKeys).Hostname != "x" {
+			t.Fatal("unexpected test keys", tk)
+		}
+	})
+
+	t.Run("when we cannot use the original type", func(t *testing.T) {
+		tk, err := experimentUnmarshalTestKeys(&scrubTestKeys{}, []byte(`{"hostname":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tk.(map[string]interface{})["hostname"] != 1.0 {
+			t.Fatal("unexpected test keys", tk)
+		}
+	})
+
+	t.Run("with invalid JSON", func(t *testing.T) {
+		if _, err := experimentUnmarshalTestKeys(nil, []byte(`{`)); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -52,6 +52,10 @@ type Results struct {
 	// didResolverLookup indicates whether we did a resolver lookup.
 	didResolverLookup bool
 
+	// IPLookups contains the outcome of each probe IP lookup we
+	// performed, in the order in which we performed them.
+	IPLookups []IPLookup
+
 	// NetworkName is the network name.
 	NetworkName string
 
@@ -73,8 +77,36 @@ func (r *Results) ASNString() string {
 	return fmt.Sprintf("AS%d", r.ASN)
 }
 
+// IPLookupsDisagree returns whether the services we used to
+// look up the probe IP returned different IPs.
+func (r *Results) IPLookupsDisagree() bool {
+	ip := ""
+	for _, lookup := range r.IPLookups {
+		if lookup.Failure != "" {
+			continue
+		}
+		if ip != "" && ip != lookup.IP {
+			return true
+		}
+		ip = lookup.IP
+	}
+	return false
+}
+
+// IPLookup is the outcome of looking up the probe IP using a service.
+type IPLookup struct {
+	// Service is the name of the service (e.g., "ipinfo").
+	Service string
+
+	// IP is the IP returned by the service, if any.
+	IP string
+
+	// Failure is the error that occurred, if any.
+	Failure string
+}
+
 type probeIPLookupper interface {
-	LookupProbeIP(ctx context.Context) (addr string, err error)
+	LookupProbeIP(ctx context.Context) (addr string, lookups []IPLookup, err error)
 }
 
 type asnLookupper interface {
@@ -103,6 +135,10 @@ type Config struct {
 	// UserAgent is the user agent to use. If not set, then
 	// we will use a default user agent.
 	UserAgent string
+
+	// CrossCheck indicates that we should look up the probe IP
+	// using two services, to notice whether they disagree.
+	CrossCheck bool
 }
 
 // NewTask creates a new instance of Task from config.
@@ -148,7 +184,8 @@ func (op Task) Run(ctx context.Context) (*Results, error) {
 		ResolverIP:          DefaultResolverIP,
 		ResolverNetworkName: DefaultResolverNetworkName,
 	}
-	ip, err := op.probeIPLookupper.LookupProbeIP(ctx)
+	ip, lookups, err := op.probeIPLookupper.LookupProbeIP(ctx)
+	out.IPLookups = lookups
 	if err != nil {
 		return out, fmt.Errorf("lookupProbeIP failed: %w", err)
 	}
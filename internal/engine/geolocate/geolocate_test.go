@@ -11,8 +11,8 @@ type taskProbeIPLookupper struct {
 	err error
 }
 
-func (c taskProbeIPLookupper) LookupProbeIP(ctx context.Context) (string, error) {
-	return c.ip, c.err
+func (c taskProbeIPLookupper) LookupProbeIP(ctx context.Context) (string, []IPLookup, error) {
+	return c.ip, []IPLookup{{Service: "fake", IP: c.ip}}, c.err
 }
 
 func TestLocationLookupCannotLookupProbeIP(t *testing.T) {
@@ -283,3 +283,18 @@ func TestASNStringWorks(t *testing.T) {
 		t.Fatal("unexpected result")
 	}
 }
+
+func TestIPLookupsDisagree(t *testing.T) {
+	r := Results{IPLookups: []IPLookup{
+		{Service: "avast", IP: "1.1.1.1"},
+		{Service: "ipinfo", Failure: "generic_timeout_error"},
+		{Service: "ubuntu", IP: "1.1.1.1"},
+	}}
+	if r.IPLookupsDisagree() {
+		t.Fatal("the lookups should agree")
+	}
+	r.IPLookups = append(r.IPLookups, IPLookup{Service: "cloudflare", IP: "8.8.8.8"})
+	if !r.IPLookupsDisagree() {
+		t.Fatal("the lookups should disagree")
+	}
+}
@@ -76,6 +76,9 @@ type ipLookupClient struct {
 
 	// UserAgent is the user agent to use
 	UserAgent string
+
+	// CrossCheck indicates whether to use two services
+	CrossCheck bool
 }
 
 func makeSlice() []method {
@@ -114,15 +117,29 @@ func (c ipLookupClient) doWithCustomFunc(
 	return ip, nil
 }
 
-func (c ipLookupClient) LookupProbeIP(ctx context.Context) (string, error) {
+func (c ipLookupClient) LookupProbeIP(ctx context.Context) (string, []IPLookup, error) {
 	union := multierror.New(ErrAllIPLookuppersFailed)
+	var lookups []IPLookup
+	probeIP := ""
 	for _, method := range makeSlice() {
 		c.Logger.Infof("iplookup: using %s", method.name)
 		ip, err := c.doWithCustomFunc(ctx, method.fn)
-		if err == nil {
-			return ip, nil
+		if err != nil {
+			lookups = append(lookups, IPLookup{Service: method.name, Failure: err.Error()})
+			union.Add(err)
+			continue
+		}
+		lookups = append(lookups, IPLookup{Service: method.name, IP: ip})
+		if probeIP != "" {
+			break // we have cross checked the IP
 		}
-		union.Add(err)
+		probeIP = ip
+		if !c.CrossCheck {
+			break
+		}
+	}
+	if probeIP == "" {
+		return DefaultProbeIP, lookups, union
 	}
-	return DefaultProbeIP, union
+	return probeIP, lookups, nil
 }
@@ -4,13 +4,15 @@ import (
 	"context"
 	"errors"
 	"net"
+	"net/http"
 	"testing"
 
 	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
 func TestIPLookupGood(t *testing.T) {
-	ip, err := (ipLookupClient{
+	ip, lookups, err := (ipLookupClient{
 		Logger:    log.Log,
 		UserAgent: "ooniprobe-engine/0.1.0",
 	}).LookupProbeIP(context.Background())
@@ -20,12 +22,15 @@ func TestIPLookupGood(t *testing.T) {
 	if net.ParseIP(ip) == nil {
 		t.Fatal("not an IP address")
 	}
+	if last := lookups[len(lookups)-1]; last.IP != ip || last.Service == "" {
+		t.Fatal("unexpected lookups", lookups)
+	}
 }
 
 func TestIPLookupAllFailed(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // immediately cancel to cause Do() to fail
-	ip, err := (ipLookupClient{
+	ip, lookups, err := (ipLookupClient{
 		Logger:    log.Log,
 		UserAgent: "ooniprobe-engine/0.1.0",
 	}).LookupProbeIP(ctx)
@@ -35,6 +40,9 @@ func TestIPLookupAllFailed(t *testing.T) {
 	if ip != DefaultProbeIP {
 		t.Fatal("expected the default IP here")
 	}
+	if len(lookups) != len(methods) || lookups[0].Failure == "" {
+		t.Fatal("expected to record the failed lookups", lookups)
+	}
 }
 
 func TestIPLookupInvalidIP(t *testing.T) {
@@ -50,3 +58,37 @@ func TestIPLookupInvalidIP(t *testing.T) {
 		t.Fatal("expected the default IP here")
 	}
 }
+
+func TestIPLookupCrossCheck(t *testing.T) {
+	fakeLookup := func(ip string) lookupFunc {
+		return func(ctx context.Context, client *http.Client,
+			logger model.Logger, userAgent string) (string, error) {
+			return ip, nil
+		}
+	}
+	saved := methods
+	defer func() {
+		methods = saved
+	}()
+	methods = []method{
+		{name: "first", fn: fakeLookup("1.1.1.1")},
+		{name: "second", fn: fakeLookup("1.1.1.1")},
+		{name: "third", fn: fakeLookup("1.1.1.1")},
+	}
+	client := ipLookupClient{Logger: log.Log, UserAgent: "ooniprobe-engine/0.1.0"}
+	_, lookups, err := client.LookupProbeIP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lookups) != 1 {
+		t.Fatal("expected a single lookup", lookups)
+	}
+	client.CrossCheck = true
+	ip, lookups, err := client.LookupProbeIP(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip != "1.1.1.1" || len(lookups) != 2 || lookups[0].Service == lookups[1].Service {
+		t.Fatal("expected to cross check the IP", ip, lookups)
+	}
+}
@@ -2,6 +2,7 @@ package geolocate
 
 import (
 	"net"
+	"time"
 
 	"github.com/ooni/probe-assets/assets"
 	"github.com/oschwald/geoip2-golang"
@@ -52,3 +53,21 @@ func (mmdbLookupper) LookupCC(ip string) (cc string, err error) {
 	}
 	return
 }
+
+// DatabaseBuildTime returns when the oldest of the embedded ASN and
+// country databases was built, which tells us how stale they are.
+func DatabaseBuildTime() (time.Time, error) {
+	var oldest time.Time
+	for _, data := range [][]byte{assets.ASNDatabaseData(), assets.CountryDatabaseData()} {
+		db, err := geoip2.FromBytes(data)
+		if err != nil {
+			return time.Time{}, err
+		}
+		built := time.Unix(int64(db.Metadata().BuildEpoch), 0)
+		db.Close()
+		if oldest.IsZero() || built.Before(oldest) {
+			oldest = built
+		}
+	}
+	return oldest, nil
+}
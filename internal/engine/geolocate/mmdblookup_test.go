@@ -1,6 +1,9 @@
 package geolocate
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 const ipAddr = "8.8.8.8"
 
@@ -49,3 +52,13 @@ func TestLookupCCInvalidIP(t *testing.T) {
 		t.Fatal("expected an empty cc")
 	}
 }
+
+func TestDatabaseBuildTime(t *testing.T) {
+	built, err := DatabaseBuildTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built.Before(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) || built.After(time.Now()) {
+		t.Fatal("unexpected build time", built)
+	}
+}
@@ -59,7 +59,9 @@ type InputLoaderLogger interface {
 //
 // We gather input from StaticInput and SourceFiles. If there is
 // input, we return it. Otherwise, we use OONI's probe services
-// to gather input using the best API for the task.
+// to gather input using the best API for the task. When
+// MergeWithBackend is true, we also use OONI's probe services
+// when there is input and we append their input to it.
 //
 // InputOrStaticDefault
 //
@@ -106,6 +108,12 @@ type InputLoader struct {
 	// per line. We will fail if any file is unreadable
 	// as well as if any file is empty.
 	SourceFiles []string
+
+	// MergeWithBackend is optional and only used together with
+	// the InputOrQueryBackend policy. When true, we append the input
+	// from OONI's probe services to the StaticInputs and SourceFiles
+	// input, skipping the URLs we already have.
+	MergeWithBackend bool
 }
 
 // Load attempts to load input using the specified input loader. We will
@@ -156,10 +164,32 @@ func (il *InputLoader) loadStrictlyRequired(ctx context.Context) ([]model.OOAPIU
 // loadOrQueryBackend implements the InputOrQueryBackend policy.
 func (il *InputLoader) loadOrQueryBackend(ctx context.Context) ([]model.OOAPIURLInfo, error) {
 	inputs, err := il.loadLocal()
-	if err != nil || len(inputs) > 0 {
+	if err != nil || (len(inputs) > 0 && !il.MergeWithBackend) {
 		return inputs, err
 	}
-	return il.loadRemote(ctx)
+	if len(inputs) <= 0 {
+		return il.loadRemote(ctx)
+	}
+	remote, err := il.loadRemote(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeInputs(inputs, remote), nil
+}
+
+// mergeInputs appends to inputs the extra inputs with new URLs.
+func mergeInputs(inputs, extra []model.OOAPIURLInfo) []model.OOAPIURLInfo {
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		seen[input.URL] = true
+	}
+	for _, input := range extra {
+		if !seen[input.URL] {
+			seen[input.URL] = true
+			inputs = append(inputs, input)
+		}
+	}
+	return inputs
 }
 
 // TODO(https://github.com/ooni/probe/issues/1390): we need to
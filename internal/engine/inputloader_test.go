@@ -364,6 +364,41 @@ func TestInputLoaderInputOrQueryBackendWithInput(t *testing.T) {
 	}
 }
 
+func TestInputLoaderInputOrQueryBackendMergeWithBackend(t *testing.T) {
+	il := &InputLoader{
+		StaticInputs: []string{"https://www.google.com/", "https://corriere.it"},
+		InputPolicy:  InputOrQueryBackend,
+		Session: &InputLoaderMockableSession{
+			Output: &model.OOAPICheckInInfo{
+				WebConnectivity: &model.OOAPICheckInInfoWebConnectivity{
+					URLs: []model.OOAPIURLInfo{{
+						CategoryCode: "NEWS",
+						CountryCode:  "IT",
+						URL:          "https://repubblica.it",
+					}, {
+						CategoryCode: "NEWS",
+						CountryCode:  "IT",
+						URL:          "https://corriere.it",
+					}},
+				},
+			},
+		},
+		MergeWithBackend: true,
+	}
+	out, err := il.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []model.OOAPIURLInfo{
+		{URL: "https://www.google.com/"},
+		{URL: "https://corriere.it"},
+		{CategoryCode: "NEWS", CountryCode: "IT", URL: "https://repubblica.it"},
+	}
+	if diff := cmp.Diff(expect, out); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
 func TestInputLoaderInputOrQueryBackendWithNoInputAndCancelledContext(t *testing.T) {
 	sess, err := NewSession(context.Background(), SessionConfig{
 		KVStore:         &kvstore.Memory{},
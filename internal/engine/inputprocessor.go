@@ -57,6 +57,10 @@ type InputProcessor struct {
 	// there will be no MaxRuntime limit.
 	MaxRuntime time.Duration
 
+	// Clock is the OPTIONAL clock used to enforce MaxRuntime. If
+	// not set, we will use model.SystemClock.
+	Clock model.Clock
+
 	// Options contains command line options for this experiment.
 	Options []string
 
@@ -136,9 +140,10 @@ const (
 // run is like Run but, in addition to returning an error, it
 // also returns the reason why we stopped.
 func (ip *InputProcessor) run(ctx context.Context) (int, error) {
-	start := time.Now()
+	clock := model.ClockOrSystemClock(ip.Clock)
+	start := clock.Now()
 	for idx, url := range ip.Inputs {
-		if ip.MaxRuntime > 0 && time.Since(start) > ip.MaxRuntime {
+		if ip.MaxRuntime > 0 && clock.Now().Sub(start) > ip.MaxRuntime {
 			return stopMaxRuntime, nil
 		}
 		input := url.URL
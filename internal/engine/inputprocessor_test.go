@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
 )
 
 type FakeInputProcessorExperiment struct {
@@ -206,3 +207,49 @@ func TestInputProcessorMaxRuntime(t *testing.T) {
 		t.Fatal("not terminated by max runtime")
 	}
 }
+
+// clockAdvancingExperiment advances the clock by step for each measurement.
+type clockAdvancingExperiment struct {
+	InputProcessorExperimentWrapper
+	clock *mocks.FakeClock
+	step  time.Duration
+}
+
+func (e *clockAdvancingExperiment) MeasureAsync(
+	ctx context.Context, input string, idx int) (<-chan *model.Measurement, error) {
+	e.clock.Advance(e.step)
+	return e.InputProcessorExperimentWrapper.MeasureAsync(ctx, input, idx)
+}
+
+func TestInputProcessorMaxRuntimeWithFakeClock(t *testing.T) {
+	clock := mocks.NewFakeClock(time.Now())
+	fipe := &FakeInputProcessorExperiment{}
+	ip := &InputProcessor{
+		Clock: clock,
+		Experiment: &clockAdvancingExperiment{
+			InputProcessorExperimentWrapper: NewInputProcessorExperimentWrapper(fipe),
+			clock:                           clock,
+			step:                            time.Minute,
+		},
+		Inputs: []model.OOAPIURLInfo{{
+			URL: "https://www.kernel.org/",
+		}, {
+			URL: "https://www.slashdot.org/",
+		}, {
+			URL: "https://www.example.com/",
+		}},
+		MaxRuntime: 90 * time.Second,
+		Saver:      NewInputProcessorSaverWrapper(&FakeInputProcessorSaver{}),
+		Submitter:  NewInputProcessorSubmitterWrapper(&FakeInputProcessorSubmitter{}),
+	}
+	reason, err := ip.run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason != stopMaxRuntime {
+		t.Fatal("not terminated by max runtime")
+	}
+	if len(fipe.M) != 2 {
+		t.Fatal("unexpected number of measurements", len(fipe.M))
+	}
+}
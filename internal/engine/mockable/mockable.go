@@ -3,12 +3,14 @@ package mockable
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/url"
 
 	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/tunnel"
 )
 
 // Session allows to mock sessions.
@@ -16,6 +18,7 @@ type Session struct {
 	MockableTestHelpers              map[string][]model.OOAPIService
 	MockableHTTPClient               *http.Client
 	MockableLogger                   model.Logger
+	MockableMemoryBudget             model.MemoryBudget
 	MockableMaybeResolverIP          string
 	MockableProbeASNString           string
 	MockableProbeCC                  string
@@ -36,6 +39,70 @@ type Session struct {
 	MockableTorBinary                string
 	MockableTunnelDir                string
 	MockableUserAgent                string
+
+	// MockableCheckInInfo and MockableCheckInErr are the
+	// results returned by CheckIn.
+	MockableCheckInInfo *model.OOAPICheckInInfo
+	MockableCheckInErr  error
+
+	// MockableNewProbeServicesClient OPTIONALLY creates the client returned
+	// by NewProbeServicesClient, which otherwise fails.
+	MockableNewProbeServicesClient func(ctx context.Context) (*probeservices.Client, error)
+
+	// MockableStartTunnel OPTIONALLY starts the tunnel returned by
+	// StartTunnel, which otherwise calls tunnel.Start. Use the "fake"
+	// tunnel name to start a tunnel without network access.
+	MockableStartTunnel func(ctx context.Context, config *tunnel.Config) (
+		tunnel.Tunnel, tunnel.DebugInfo, error)
+
+	// MockableInjectError OPTIONALLY returns the error that the method
+	// with the given name (e.g., "FetchURLList") should return instead of
+	// its mocked result. Counting the calls allows, e.g., to only fail
+	// the first call and check whether the code retries.
+	MockableInjectError func(method string) error
+}
+
+// ErrNoProbeServicesClient indicates that MockableNewProbeServicesClient is not set.
+var ErrNoProbeServicesClient = errors.New("mockable: no probe services client")
+
+// injectError returns the error to inject into the given method, if any.
+func (sess *Session) injectError(method string) error {
+	if sess.MockableInjectError != nil {
+		return sess.MockableInjectError(method)
+	}
+	return nil
+}
+
+// CheckIn implements engine.InputLoaderSession.CheckIn
+func (sess *Session) CheckIn(
+	ctx context.Context, config *model.OOAPICheckInConfig) (*model.OOAPICheckInInfo, error) {
+	if err := sess.injectError("CheckIn"); err != nil {
+		return nil, err
+	}
+	return sess.MockableCheckInInfo, sess.MockableCheckInErr
+}
+
+// NewProbeServicesClient creates a client using MockableNewProbeServicesClient.
+func (sess *Session) NewProbeServicesClient(ctx context.Context) (*probeservices.Client, error) {
+	if err := sess.injectError("NewProbeServicesClient"); err != nil {
+		return nil, err
+	}
+	if sess.MockableNewProbeServicesClient == nil {
+		return nil, ErrNoProbeServicesClient
+	}
+	return sess.MockableNewProbeServicesClient(ctx)
+}
+
+// StartTunnel starts a tunnel using MockableStartTunnel or tunnel.Start.
+func (sess *Session) StartTunnel(
+	ctx context.Context, config *tunnel.Config) (tunnel.Tunnel, tunnel.DebugInfo, error) {
+	if err := sess.injectError("StartTunnel"); err != nil {
+		return nil, tunnel.DebugInfo{}, err
+	}
+	if sess.MockableStartTunnel != nil {
+		return sess.MockableStartTunnel(ctx, config)
+	}
+	return tunnel.Start(ctx, config)
 }
 
 // GetTestHelpersByName implements ExperimentSession.GetTestHelpersByName
@@ -51,18 +118,27 @@ func (sess *Session) DefaultHTTPClient() *http.Client {
 
 // FetchPsiphonConfig implements ExperimentSession.FetchPsiphonConfig
 func (sess *Session) FetchPsiphonConfig(ctx context.Context) ([]byte, error) {
+	if err := sess.injectError("FetchPsiphonConfig"); err != nil {
+		return nil, err
+	}
 	return sess.MockableFetchPsiphonConfigResult, sess.MockableFetchPsiphonConfigErr
 }
 
 // FetchTorTargets implements ExperimentSession.TorTargets
 func (sess *Session) FetchTorTargets(
 	ctx context.Context, cc string) (map[string]model.OOAPITorTarget, error) {
+	if err := sess.injectError("FetchTorTargets"); err != nil {
+		return nil, err
+	}
 	return sess.MockableFetchTorTargetsResult, sess.MockableFetchTorTargetsErr
 }
 
 // FetchURLList implements ExperimentSession.FetchURLList.
 func (sess *Session) FetchURLList(
 	ctx context.Context, config model.OOAPIURLListConfig) ([]model.OOAPIURLInfo, error) {
+	if err := sess.injectError("FetchURLList"); err != nil {
+		return nil, err
+	}
 	return sess.MockableFetchURLListResult, sess.MockableFetchURLListErr
 }
 
@@ -121,6 +197,11 @@ func (sess *Session) SoftwareVersion() string {
 	return sess.MockableSoftwareVersion
 }
 
+// MemoryBudget implements ExperimentSession.MemoryBudget
+func (sess *Session) MemoryBudget() model.MemoryBudget {
+	return sess.MockableMemoryBudget
+}
+
 // TempDir implements ExperimentSession.TempDir
 func (sess *Session) TempDir() string {
 	return sess.MockableTempDir
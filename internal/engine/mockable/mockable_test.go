@@ -0,0 +1,95 @@
+package mockable
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/tunnel"
+)
+
+func TestSessionCheckIn(t *testing.T) {
+	info := &model.OOAPICheckInInfo{}
+	sess := &Session{MockableCheckInInfo: info}
+	out, err := sess.CheckIn(context.Background(), &model.OOAPICheckInConfig{})
+	if err != nil || out != info {
+		t.Fatal("unexpected result", out, err)
+	}
+}
+
+func TestSessionNewProbeServicesClient(t *testing.T) {
+	t.Run("without a hook", func(t *testing.T) {
+		sess := &Session{}
+		_, err := sess.NewProbeServicesClient(context.Background())
+		if !errors.Is(err, ErrNoProbeServicesClient) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("with a hook", func(t *testing.T) {
+		client := &probeservices.Client{}
+		sess := &Session{
+			MockableNewProbeServicesClient: func(ctx context.Context) (*probeservices.Client, error) {
+				return client, nil
+			},
+		}
+		out, err := sess.NewProbeServicesClient(context.Background())
+		if err != nil || out != client {
+			t.Fatal("unexpected result", out, err)
+		}
+	})
+}
+
+func TestSessionStartTunnel(t *testing.T) {
+	expected := errors.New("mocked error")
+	sess := &Session{
+		MockableStartTunnel: func(ctx context.Context, config *tunnel.Config) (
+			tunnel.Tunnel, tunnel.DebugInfo, error) {
+			return nil, tunnel.DebugInfo{Name: config.Name}, expected
+		},
+	}
+	_, info, err := sess.StartTunnel(context.Background(), &tunnel.Config{Name: "fake"})
+	if !errors.Is(err, expected) || info.Name != "fake" {
+		t.Fatal("unexpected result", info, err)
+	}
+}
+
+func TestSessionInjectError(t *testing.T) {
+	expected := errors.New("mocked error")
+	var calls int
+	sess := &Session{
+		MockableFetchURLListResult: []model.OOAPIURLInfo{{URL: "https://example.com/"}},
+		MockableInjectError: func(method string) error {
+			calls++
+			if method == "FetchURLList" && calls == 1 {
+				return expected
+			}
+			return nil
+		},
+	}
+	ctx := context.Background()
+	if _, err := sess.FetchURLList(ctx, model.OOAPIURLListConfig{}); !errors.Is(err, expected) {
+		t.Fatal("unexpected error", err)
+	}
+	list, err := sess.FetchURLList(ctx, model.OOAPIURLListConfig{})
+	if err != nil || len(list) != 1 {
+		t.Fatal("unexpected result", list, err)
+	}
+	if _, err := sess.FetchPsiphonConfig(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.FetchTorTargets(ctx, "IT"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sess.CheckIn(ctx, &model.OOAPICheckInConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sess.StartTunnel(ctx, &tunnel.Config{Name: "nonexistent"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls != 6 {
+		t.Fatal("unexpected number of calls", calls)
+	}
+}
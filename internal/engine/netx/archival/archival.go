@@ -297,6 +297,14 @@ func NewNetworkEventsList(begin time.Time, events []trace.Event) []NetworkEvent
 			})
 			continue
 		}
+		if ev.Name == trace.OverflowEventName {
+			out = append(out, NetworkEvent{
+				DroppedEvents: int64(ev.DroppedEvents),
+				Operation:     ev.Name,
+				T:             ev.Time.Sub(begin).Seconds(),
+			})
+			continue
+		}
 		out = append(out, NetworkEvent{
 			Failure:   NewFailure(ev.Err),
 			Operation: ev.Name,
@@ -490,6 +490,21 @@ func TestNewNetworkEventsList(t *testing.T) {
 			Operation: netxlite.CloseOperation,
 			T:         0.017,
 		}},
+	}, {
+		name: "overflowing run",
+		args: args{
+			begin: begin,
+			events: []trace.Event{{
+				DroppedEvents: 3,
+				Name:          trace.OverflowEventName,
+				Time:          begin.Add(19 * time.Millisecond),
+			}},
+		},
+		want: []archival.NetworkEvent{{
+			DroppedEvents: 3,
+			Operation:     trace.OverflowEventName,
+			T:             0.019,
+		}},
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
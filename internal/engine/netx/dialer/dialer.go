@@ -42,6 +42,11 @@ type Config struct {
 
 	// ReadWriteSaver is like DialSaver but for I/O events.
 	ReadWriteSaver *trace.Saver
+
+	// Clock is the OPTIONAL clock used to timestamp the events
+	// saved by DialSaver and ReadWriteSaver. If not set, we will
+	// use model.SystemClock.
+	Clock model.Clock
 }
 
 // New creates a new Dialer from the specified config and resolver.
@@ -54,10 +59,10 @@ func New(config *Config, resolver model.Resolver) model.Dialer {
 		}
 	}
 	if config.DialSaver != nil {
-		d = &saverDialer{Dialer: d, Saver: config.DialSaver}
+		d = &saverDialer{Dialer: d, Saver: config.DialSaver, Clock: config.Clock}
 	}
 	if config.ReadWriteSaver != nil {
-		d = &saverConnDialer{Dialer: d, Saver: config.ReadWriteSaver}
+		d = &saverConnDialer{Dialer: d, Saver: config.ReadWriteSaver, Clock: config.Clock}
 	}
 	d = &netxlite.DialerResolver{
 		Resolver: resolver,
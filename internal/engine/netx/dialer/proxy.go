@@ -1,10 +1,15 @@
 package dialer
 
 import (
+	"bufio"
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"net"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"golang.org/x/net/proxy"
@@ -12,7 +17,8 @@ import (
 
 // proxyDialer is a dialer that uses a proxy. If the ProxyURL is not configured, this
 // dialer is a passthrough for the next Dialer in chain. Otherwise, it will internally
-// create a SOCKS5 dialer that will connect to the proxy using the underlying Dialer.
+// create a SOCKS5 dialer, or an HTTP CONNECT dialer when the scheme is "http", that
+// will connect to the proxy using the underlying Dialer.
 type proxyDialer struct {
 	model.Dialer
 	ProxyURL *url.URL
@@ -27,6 +33,10 @@ func (d *proxyDialer) DialContext(ctx context.Context, network, address string)
 	if url == nil {
 		return d.Dialer.DialContext(ctx, network, address)
 	}
+	if url.Scheme == "http" {
+		child := &httpConnectDialer{Dialer: d.Dialer, ProxyURL: url}
+		return child.DialContext(ctx, network, address)
+	}
 	if url.Scheme != "socks5" {
 		return nil, ErrProxyUnsupportedScheme
 	}
@@ -54,3 +64,78 @@ type proxyDialerWrapper struct {
 func (d *proxyDialerWrapper) Dial(network, address string) (net.Conn, error) {
 	panic(errors.New("proxyDialerWrapper.Dial should not be called directly"))
 }
+
+// ErrProxyConnectFailed indicates that the HTTP proxy refused our CONNECT request.
+var ErrProxyConnectFailed = errors.New("proxy: CONNECT failed")
+
+// httpConnectDialer tunnels connections through an HTTP proxy using the
+// CONNECT method. It connects to the proxy using the underlying Dialer.
+type httpConnectDialer struct {
+	model.Dialer
+	ProxyURL *url.URL
+}
+
+// DialContext implements Dialer.DialContext
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	proxyAddress := d.ProxyURL.Host
+	if d.ProxyURL.Port() == "" {
+		proxyAddress = net.JoinHostPort(d.ProxyURL.Hostname(), "80")
+	}
+	conn, err := d.Dialer.DialContext(ctx, network, proxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	reader, err := d.connect(conn, address)
+	conn.SetDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &httpConnectConn{Conn: conn, reader: reader}, nil
+}
+
+// connect sends the CONNECT request and reads the proxy response.
+func (d *httpConnectDialer) connect(conn net.Conn, address string) (*bufio.Reader, error) {
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if user := d.ProxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := user.Username() + ":" + password
+		req.Header.Set("Proxy-Authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, err
+	}
+	// Like net/http, we don't close the body: for a successful CONNECT
+	// the body is the tunneled stream, which the caller owns.
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrProxyConnectFailed, resp.Status)
+	}
+	return reader, nil
+}
+
+// httpConnectConn is a connection tunneled through an HTTP proxy. It reads
+// from the buffered reader we used to parse the proxy response, which may
+// already contain data sent by the server.
+type httpConnectConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read implements net.Conn.Read
+func (c *httpConnectConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -1,10 +1,12 @@
 package dialer
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"io"
 	"net"
+	"net/http"
 	"net/url"
 	"testing"
 
@@ -79,3 +81,108 @@ func TestProxyDialWrapperPanics(t *testing.T) {
 		t.Fatal("unexpected result", err)
 	}
 }
+
+// serveHTTPProxyOnce accepts a single connection on the given listener, reads
+// the CONNECT request, passes it to check, and replies with the given status
+// followed by the given data, which emulates the server speaking first.
+func serveHTTPProxyOnce(t *testing.T, listener net.Listener, status int,
+	data string, check func(*http.Request)) {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	check(req)
+	resp := &http.Response{StatusCode: status, ProtoMajor: 1, ProtoMinor: 1}
+	if status == http.StatusOK {
+		resp.ContentLength = -1
+	}
+	resp.Write(conn)
+	io.WriteString(conn, data)
+}
+
+func TestProxyDialerDialContextHTTPConnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go serveHTTPProxyOnce(t, listener, http.StatusOK, "hello", func(req *http.Request) {
+		if req.Method != "CONNECT" || req.Host != "www.google.com:443" {
+			t.Error("unexpected request", req.Method, req.Host)
+		}
+		user, password, ok := (&http.Request{Header: http.Header{
+			"Authorization": req.Header["Proxy-Authorization"],
+		}}).BasicAuth()
+		if !ok || user != "user" || password != "pass" {
+			t.Error("unexpected credentials", user, password)
+		}
+	})
+	d := &proxyDialer{
+		Dialer: &mocks.Dialer{MockDialContext: (&net.Dialer{}).DialContext},
+		ProxyURL: &url.URL{
+			Scheme: "http",
+			Host:   listener.Addr().String(),
+			User:   url.UserPassword("user", "pass"),
+		},
+	}
+	conn, err := d.DialContext(context.Background(), "tcp", "www.google.com:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Fatal("unexpected data", string(data))
+	}
+}
+
+func TestProxyDialerDialContextHTTPConnectRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go serveHTTPProxyOnce(t, listener, http.StatusForbidden, "", func(req *http.Request) {})
+	d := &proxyDialer{
+		Dialer:   &mocks.Dialer{MockDialContext: (&net.Dialer{}).DialContext},
+		ProxyURL: &url.URL{Scheme: "http", Host: listener.Addr().String()},
+	}
+	conn, err := d.DialContext(context.Background(), "tcp", "www.google.com:443")
+	if !errors.Is(err, ErrProxyConnectFailed) {
+		t.Fatal("not the error we expected", err)
+	}
+	if conn != nil {
+		t.Fatal("conn is not nil")
+	}
+}
+
+func TestProxyDialerDialContextHTTPDefaultPort(t *testing.T) {
+	d := &proxyDialer{
+		Dialer: &mocks.Dialer{
+			MockDialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+				if address != "10.0.0.1:80" {
+					return nil, errors.New("unexpected address")
+				}
+				return nil, io.EOF
+			},
+		},
+		ProxyURL: &url.URL{Scheme: "http", Host: "10.0.0.1"},
+	}
+	conn, err := d.DialContext(context.Background(), "tcp", "www.google.com:443")
+	if !errors.Is(err, io.EOF) {
+		t.Fatal("not the error we expected", err)
+	}
+	if conn != nil {
+		t.Fatal("conn is not nil")
+	}
+}
@@ -3,7 +3,6 @@ package dialer
 import (
 	"context"
 	"net"
-	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -14,13 +13,15 @@ import (
 type saverDialer struct {
 	model.Dialer
 	Saver *trace.Saver
+	Clock model.Clock
 }
 
 // DialContext implements Dialer.DialContext
 func (d *saverDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
-	start := time.Now()
+	clock := model.ClockOrSystemClock(d.Clock)
+	start := clock.Now()
 	conn, err := d.Dialer.DialContext(ctx, network, address)
-	stop := time.Now()
+	stop := clock.Now()
 	d.Saver.Write(trace.Event{
 		Address:  address,
 		Duration: stop.Sub(start),
@@ -37,6 +38,7 @@ func (d *saverDialer) DialContext(ctx context.Context, network, address string)
 type saverConnDialer struct {
 	model.Dialer
 	Saver *trace.Saver
+	Clock model.Clock
 }
 
 // DialContext implements Dialer.DialContext
@@ -45,18 +47,19 @@ func (d *saverConnDialer) DialContext(ctx context.Context, network, address stri
 	if err != nil {
 		return nil, err
 	}
-	return &saverConn{saver: d.Saver, Conn: conn}, nil
+	return &saverConn{saver: d.Saver, Conn: conn, clock: model.ClockOrSystemClock(d.Clock)}, nil
 }
 
 type saverConn struct {
 	net.Conn
 	saver *trace.Saver
+	clock model.Clock
 }
 
 func (c *saverConn) Read(p []byte) (int, error) {
-	start := time.Now()
+	start := c.clock.Now()
 	count, err := c.Conn.Read(p)
-	stop := time.Now()
+	stop := c.clock.Now()
 	c.saver.Write(trace.Event{
 		Data:     p[:count],
 		Duration: stop.Sub(start),
@@ -69,9 +72,9 @@ func (c *saverConn) Read(p []byte) (int, error) {
 }
 
 func (c *saverConn) Write(p []byte) (int, error) {
-	start := time.Now()
+	start := c.clock.Now()
 	count, err := c.Conn.Write(p)
-	stop := time.Now()
+	stop := c.clock.Now()
 	c.saver.Write(trace.Event{
 		Data:     p[:count],
 		Duration: stop.Sub(start),
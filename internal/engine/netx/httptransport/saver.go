@@ -22,12 +22,13 @@ type SaverMetadataHTTPTransport struct {
 // RoundTrip implements RoundTripper.RoundTrip
 func (txp SaverMetadataHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	txp.Saver.Write(trace.Event{
-		HTTPHeaders: txp.CloneHeaders(req),
-		HTTPMethod:  req.Method,
-		HTTPURL:     req.URL.String(),
-		Transport:   txp.HTTPTransport.Network(),
-		Name:        "http_request_metadata",
-		Time:        time.Now(),
+		HTTPHeaders:   txp.CloneHeaders(req),
+		HTTPMethod:    req.Method,
+		HTTPURL:       req.URL.String(),
+		Transport:     txp.HTTPTransport.Network(),
+		Name:          "http_request_metadata",
+		Time:          time.Now(),
+		TransactionID: trace.ContextTransactionID(req.Context()),
 	})
 	resp, err := txp.HTTPTransport.RoundTrip(req)
 	if err != nil {
@@ -38,6 +39,7 @@ func (txp SaverMetadataHTTPTransport) RoundTrip(req *http.Request) (*http.Respon
 		HTTPStatusCode: resp.StatusCode,
 		Name:           "http_response_metadata",
 		Time:           time.Now(),
+		TransactionID:  trace.ContextTransactionID(req.Context()),
 	})
 	return resp, err
 }
@@ -64,15 +66,19 @@ type SaverTransactionHTTPTransport struct {
 
 // RoundTrip implements RoundTripper.RoundTrip
 func (txp SaverTransactionHTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	txID := txp.Saver.NewTransactionID()
+	req = req.WithContext(trace.WithTransactionID(req.Context(), txID))
 	txp.Saver.Write(trace.Event{
-		Name: "http_transaction_start",
-		Time: time.Now(),
+		Name:          "http_transaction_start",
+		Time:          time.Now(),
+		TransactionID: txID,
 	})
 	resp, err := txp.HTTPTransport.RoundTrip(req)
 	txp.Saver.Write(trace.Event{
-		Err:  err,
-		Name: "http_transaction_done",
-		Time: time.Now(),
+		Err:           err,
+		Name:          "http_transaction_done",
+		Time:          time.Now(),
+		TransactionID: txID,
 	})
 	return resp, err
 }
@@ -103,6 +109,7 @@ func (txp SaverBodyHTTPTransport) RoundTrip(req *http.Request) (*http.Response,
 			Data:            data,
 			Name:            "http_request_body_snapshot",
 			Time:            time.Now(),
+			TransactionID:   trace.ContextTransactionID(req.Context()),
 		})
 	}
 	resp, err := txp.HTTPTransport.RoundTrip(req)
@@ -120,6 +127,7 @@ func (txp SaverBodyHTTPTransport) RoundTrip(req *http.Request) (*http.Response,
 		Data:            data,
 		Name:            "http_response_body_snapshot",
 		Time:            time.Now(),
+		TransactionID:   trace.ContextTransactionID(req.Context()),
 	})
 	return resp, nil
 }
@@ -198,6 +198,43 @@ func TestSaverTransactionFailure(t *testing.T) {
 	}
 }
 
+func TestSaverTransactionMarksNestedEvents(t *testing.T) {
+	saver := &trace.Saver{}
+	txp := httptransport.SaverTransactionHTTPTransport{
+		HTTPTransport: httptransport.SaverBodyHTTPTransport{
+			HTTPTransport: httptransport.FakeTransport{
+				Resp: &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(strings.NewReader("abad1dea")),
+				},
+			},
+			Saver: saver,
+		},
+		Saver: saver,
+	}
+	for idx := 0; idx < 2; idx++ {
+		req, err := http.NewRequest("GET", "http://x.org/y", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := txp.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ev := saver.Read()
+	if len(ev) != 6 {
+		t.Fatal("unexpected number of events", len(ev))
+	}
+	for idx := range ev {
+		if ev[idx].TransactionID != ev[idx/3*3].TransactionID || ev[idx].TransactionID == 0 {
+			t.Fatal("unexpected TransactionID", idx, ev[idx].TransactionID)
+		}
+	}
+	if ev[0].TransactionID == ev[3].TransactionID {
+		t.Fatal("expected distinct transactions")
+	}
+}
+
 func TestSaverBodySuccess(t *testing.T) {
 	saver := new(trace.Saver)
 	txp := httptransport.SaverBodyHTTPTransport{
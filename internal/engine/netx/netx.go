@@ -52,6 +52,7 @@ type Config struct {
 	ByteCounter         *bytecounter.Counter // default: no explicit byte counting
 	CacheResolutions    bool                 // default: no caching
 	CertPool            *x509.CertPool       // default: use vendored gocertifi
+	Clock               model.Clock          // default: model.SystemClock
 	ContextByteCounting bool                 // default: no implicit byte counting
 	DNSCache            map[string][]string  // default: cache is empty
 	DialSaver           *trace.Saver         // default: not saving dials
@@ -107,7 +108,11 @@ func NewResolver(config Config) model.Resolver {
 		}
 	}
 	if config.ResolveSaver != nil {
-		r = resolver.SaverResolver{Resolver: r, Saver: config.ResolveSaver}
+		r = resolver.SaverResolver{
+			Resolver: r,
+			Saver:    config.ResolveSaver,
+			Clock:    config.Clock,
+		}
 	}
 	return &netxlite.ResolverIDNA{Resolver: r}
 }
@@ -118,6 +123,7 @@ func NewDialer(config Config) model.Dialer {
 		config.FullResolver = NewResolver(config)
 	}
 	return dialer.New(&dialer.Config{
+		Clock:               config.Clock,
 		ContextByteCounting: config.ContextByteCounting,
 		DialSaver:           config.DialSaver,
 		Logger:              config.Logger,
@@ -292,6 +298,7 @@ func NewDNSClientWithOverrides(config Config, URL, hostOverride, SNIOverride,
 			txp = resolver.SaverDNSTransport{
 				DNSTransport: txp,
 				Saver:        config.ResolveSaver,
+				Clock:        config.Clock,
 			}
 		}
 		return netxlite.NewSerialResolver(txp), nil
@@ -307,6 +314,7 @@ func NewDNSClientWithOverrides(config Config, URL, hostOverride, SNIOverride,
 			txp = resolver.SaverDNSTransport{
 				DNSTransport: txp,
 				Saver:        config.ResolveSaver,
+				Clock:        config.Clock,
 			}
 		}
 		return netxlite.NewSerialResolver(txp), nil
@@ -323,6 +331,7 @@ func NewDNSClientWithOverrides(config Config, URL, hostOverride, SNIOverride,
 			txp = resolver.SaverDNSTransport{
 				DNSTransport: txp,
 				Saver:        config.ResolveSaver,
+				Clock:        config.Clock,
 			}
 		}
 		return netxlite.NewSerialResolver(txp), nil
@@ -338,6 +347,7 @@ func NewDNSClientWithOverrides(config Config, URL, hostOverride, SNIOverride,
 			txp = resolver.SaverDNSTransport{
 				DNSTransport: txp,
 				Saver:        config.ResolveSaver,
+				Clock:        config.Clock,
 			}
 		}
 		return netxlite.NewSerialResolver(txp), nil
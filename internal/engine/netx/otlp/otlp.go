@@ -0,0 +1,346 @@
+// Package otlp exports trace events as OpenTelemetry spans using the
+// OTLP/HTTP JSON encoding, such that one can analyze the behavior of
+// the probe using an existing observability stack.
+//
+// Each measurement becomes a root span and the DNS lookups, connects,
+// TLS and QUIC handshakes, and HTTP transactions become its children.
+//
+// The exporter is disabled unless the user sets the standard
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT
+// environment variables (see NewExporterFromEnv).
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/version"
+)
+
+const (
+	// TracesEndpointEnv is the environment variable containing
+	// the full URL to which we should POST the spans.
+	TracesEndpointEnv = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+
+	// EndpointEnv is the environment variable containing the base
+	// URL of the collector, to which we append "/v1/traces".
+	EndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+	// exportTimeout is the timeout for exporting the spans.
+	exportTimeout = 10 * time.Second
+
+	// serviceName is the name of the service emitting the spans.
+	serviceName = "ooniprobe"
+
+	// scopeName is the name of the instrumentation scope.
+	scopeName = "github.com/ooni/probe-cli/v3/internal/engine/netx/otlp"
+)
+
+// Span kinds and status codes (see the OTLP trace protobuf).
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusCodeOK     = 1
+	statusCodeError  = 2
+)
+
+// Exporter exports trace events as OpenTelemetry spans.
+type Exporter struct {
+	// Endpoint is the URL to which we POST the spans.
+	Endpoint string
+
+	// HTTPClient is the HTTP client to use.
+	HTTPClient model.HTTPClient
+
+	// randRead allows to mock crypto/rand.Read.
+	randRead func(b []byte) (int, error)
+}
+
+// NewExporter creates a new Exporter posting spans to the given endpoint.
+func NewExporter(endpoint string, client model.HTTPClient) *Exporter {
+	return &Exporter{
+		Endpoint:   endpoint,
+		HTTPClient: client,
+		randRead:   rand.Read,
+	}
+}
+
+// NewExporterFromEnv creates a new Exporter using the standard OpenTelemetry
+// environment variables. It returns nil if exporting is not enabled.
+func NewExporterFromEnv(logger model.DebugLogger) *Exporter {
+	endpoint := os.Getenv(TracesEndpointEnv)
+	if endpoint == "" {
+		base := os.Getenv(EndpointEnv)
+		if base == "" {
+			return nil
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	return NewExporter(endpoint, netxlite.NewHTTPClientStdlib(logger))
+}
+
+// Export exports the events of the measurement with the given name that
+// begun at the given time. The attributes annotate the root span.
+func (e *Exporter) Export(ctx context.Context, name string,
+	attributes map[string]string, begin time.Time, events []trace.Event) error {
+	request, err := e.newRequest(name, attributes, begin, events)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", e.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("otlp: collector returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// exportRequest is the JSON encoding of ExportTraceServiceRequest.
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// newKeyValues converts the given attributes sorted by key.
+func newKeyValues(attributes map[string]string) (out []keyValue) {
+	for key, value := range attributes {
+		if value != "" {
+			out = append(out, keyValue{Key: key, Value: anyValue{StringValue: value}})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+	return
+}
+
+// newStatus returns the status corresponding to the given error.
+func newStatus(err error) status {
+	if err != nil {
+		return status{Code: statusCodeError, Message: err.Error()}
+	}
+	return status{Code: statusCodeOK}
+}
+
+// unixNano formats t as OTLP does for 64 bit integers.
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// newID returns a random hex encoded ID of the given size.
+func (e *Exporter) newID(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := e.randRead(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newRequest converts the events into an export request.
+func (e *Exporter) newRequest(name string, attributes map[string]string,
+	begin time.Time, events []trace.Event) (*exportRequest, error) {
+	traceID, err := e.newID(16)
+	if err != nil {
+		return nil, err
+	}
+	rootID, err := e.newID(8)
+	if err != nil {
+		return nil, err
+	}
+	children := newChildSpans(events)
+	end := begin
+	for _, child := range children {
+		if child.end.After(end) {
+			end = child.end
+		}
+	}
+	spans := []span{{
+		TraceID:           traceID,
+		SpanID:            rootID,
+		Name:              name,
+		Kind:              spanKindInternal,
+		StartTimeUnixNano: unixNano(begin),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        newKeyValues(attributes),
+		Status:            status{Code: statusCodeOK},
+	}}
+	for _, child := range children {
+		spanID, err := e.newID(8)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, span{
+			TraceID:           traceID,
+			SpanID:            spanID,
+			ParentSpanID:      rootID,
+			Name:              child.name,
+			Kind:              spanKindClient,
+			StartTimeUnixNano: unixNano(child.start),
+			EndTimeUnixNano:   unixNano(child.end),
+			Attributes:        newKeyValues(child.attributes),
+			Status:            newStatus(child.err),
+		})
+	}
+	return &exportRequest{ResourceSpans: []resourceSpans{{
+		Resource: resource{Attributes: newKeyValues(map[string]string{
+			"service.name":    serviceName,
+			"service.version": version.Version,
+		})},
+		ScopeSpans: []scopeSpans{{
+			Scope: scope{Name: scopeName, Version: version.Version},
+			Spans: spans,
+		}},
+	}}}, nil
+}
+
+// childSpan is a span we still need to assign IDs to.
+type childSpan struct {
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        error
+}
+
+// newChildSpans converts the events into child spans. The events carrying
+// a duration become spans on their own, while we pair the start and the
+// end of each HTTP transaction, which has no duration.
+func newChildSpans(events []trace.Event) (out []childSpan) {
+	var transaction *childSpan
+	for _, ev := range events {
+		switch ev.Name {
+		case "resolve_done":
+			out = append(out, childSpan{
+				name:  "dns.resolve",
+				start: ev.Time.Add(-ev.Duration),
+				end:   ev.Time,
+				attributes: map[string]string{
+					"dns.hostname":  ev.Hostname,
+					"dns.addresses": strings.Join(ev.Addresses, " "),
+					"dns.resolver":  ev.Address,
+					"net.transport": ev.Proto,
+				},
+				err: ev.Err,
+			})
+		case netxlite.ConnectOperation:
+			out = append(out, childSpan{
+				name:  "net.connect",
+				start: ev.Time.Add(-ev.Duration),
+				end:   ev.Time,
+				attributes: map[string]string{
+					"net.peer.address": ev.Address,
+					"net.transport":    ev.Proto,
+				},
+				err: ev.Err,
+			})
+		case "tls_handshake_done", "quic_handshake_done":
+			out = append(out, childSpan{
+				name:  strings.Replace(strings.TrimSuffix(ev.Name, "_done"), "_", ".", 1),
+				start: ev.Time.Add(-ev.Duration),
+				end:   ev.Time,
+				attributes: map[string]string{
+					"net.peer.address": ev.Address,
+					"tls.server_name":  ev.TLSServerName,
+					"tls.version":      ev.TLSVersion,
+					"tls.cipher_suite": ev.TLSCipherSuite,
+					"tls.alpn":         ev.TLSNegotiatedProto,
+				},
+				err: ev.Err,
+			})
+		case "http_transaction_start":
+			transaction = &childSpan{
+				name:       "http.request",
+				start:      ev.Time,
+				attributes: make(map[string]string),
+			}
+		case "http_request_metadata":
+			if transaction != nil {
+				transaction.attributes["http.method"] = ev.HTTPMethod
+				transaction.attributes["http.url"] = ev.HTTPURL
+			}
+		case "http_response_metadata":
+			if transaction != nil {
+				transaction.attributes["http.status_code"] = strconv.Itoa(ev.HTTPStatusCode)
+			}
+		case "http_transaction_done":
+			if transaction != nil {
+				transaction.end = ev.Time
+				transaction.err = ev.Err
+				out = append(out, *transaction)
+				transaction = nil
+			}
+		}
+	}
+	return
+}
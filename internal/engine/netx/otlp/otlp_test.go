@@ -0,0 +1,179 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+func TestNewExporterFromEnv(t *testing.T) {
+	t.Run("when disabled", func(t *testing.T) {
+		t.Setenv(TracesEndpointEnv, "")
+		t.Setenv(EndpointEnv, "")
+		if NewExporterFromEnv(model.DiscardLogger) != nil {
+			t.Fatal("expected nil exporter")
+		}
+	})
+
+	t.Run("with the base endpoint", func(t *testing.T) {
+		t.Setenv(TracesEndpointEnv, "")
+		t.Setenv(EndpointEnv, "http://127.0.0.1:4318/")
+		exporter := NewExporterFromEnv(model.DiscardLogger)
+		if exporter == nil || exporter.Endpoint != "http://127.0.0.1:4318/v1/traces" {
+			t.Fatal("unexpected exporter", exporter)
+		}
+	})
+
+	t.Run("with the traces endpoint", func(t *testing.T) {
+		t.Setenv(TracesEndpointEnv, "http://127.0.0.1:4318/traces")
+		t.Setenv(EndpointEnv, "http://127.0.0.1:4318/")
+		exporter := NewExporterFromEnv(model.DiscardLogger)
+		if exporter == nil || exporter.Endpoint != "http://127.0.0.1:4318/traces" {
+			t.Fatal("unexpected exporter", exporter)
+		}
+	})
+}
+
+func TestNewChildSpans(t *testing.T) {
+	begin := time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC)
+	failure := errors.New("connection_refused")
+	events := []trace.Event{{
+		Name:      "resolve_done",
+		Hostname:  "example.com",
+		Addresses: []string{"93.184.216.34"},
+		Duration:  10 * time.Millisecond,
+		Time:      begin.Add(10 * time.Millisecond),
+	}, {
+		Name:     netxlite.ConnectOperation,
+		Address:  "93.184.216.34:443",
+		Proto:    "tcp",
+		Duration: 20 * time.Millisecond,
+		Err:      failure,
+		Time:     begin.Add(30 * time.Millisecond),
+	}, {
+		Name:          "tls_handshake_done",
+		TLSServerName: "example.com",
+		Duration:      30 * time.Millisecond,
+		Time:          begin.Add(60 * time.Millisecond),
+	}, {
+		Name: "http_transaction_start",
+		Time: begin.Add(60 * time.Millisecond),
+	}, {
+		Name:       "http_request_metadata",
+		HTTPMethod: "GET",
+		HTTPURL:    "https://example.com/",
+	}, {
+		Name:           "http_response_metadata",
+		HTTPStatusCode: 200,
+	}, {
+		Name: "http_transaction_done",
+		Time: begin.Add(100 * time.Millisecond),
+	}, {
+		Name: netxlite.ReadOperation,
+	}}
+	spans := newChildSpans(events)
+	if len(spans) != 4 {
+		t.Fatal("unexpected number of spans", len(spans))
+	}
+	names := []string{"dns.resolve", "net.connect", "tls.handshake", "http.request"}
+	for idx, name := range names {
+		if spans[idx].name != name {
+			t.Fatal("unexpected span name", spans[idx].name, "expected", name)
+		}
+	}
+	if !spans[1].start.Equal(begin.Add(10*time.Millisecond)) || spans[1].err != failure {
+		t.Fatal("unexpected connect span", spans[1])
+	}
+	txn := spans[3]
+	if !txn.start.Equal(begin.Add(60*time.Millisecond)) || !txn.end.Equal(begin.Add(100*time.Millisecond)) {
+		t.Fatal("unexpected HTTP span times", txn)
+	}
+	if txn.attributes["http.method"] != "GET" || txn.attributes["http.status_code"] != "200" {
+		t.Fatal("unexpected HTTP span attributes", txn.attributes)
+	}
+}
+
+func TestExport(t *testing.T) {
+	begin := time.Date(2022, 10, 6, 9, 0, 0, 0, time.UTC)
+	events := []trace.Event{{
+		Name:     netxlite.ConnectOperation,
+		Address:  "93.184.216.34:443",
+		Proto:    "tcp",
+		Duration: 20 * time.Millisecond,
+		Time:     begin.Add(30 * time.Millisecond),
+	}}
+
+	t.Run("on success", func(t *testing.T) {
+		var request exportRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(400)
+				return
+			}
+			data, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(data, &request); err != nil {
+				w.WriteHeader(400)
+			}
+		}))
+		defer server.Close()
+		exporter := NewExporter(server.URL, http.DefaultClient)
+		exporter.randRead = func(b []byte) (int, error) {
+			for idx := range b {
+				b[idx] = 0xab
+			}
+			return len(b), nil
+		}
+		err := exporter.Export(context.Background(), "urlgetter",
+			map[string]string{"ooni.input": "https://example.com/"}, begin, events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+		if len(spans) != 2 {
+			t.Fatal("unexpected number of spans", len(spans))
+		}
+		root, child := spans[0], spans[1]
+		if root.Name != "urlgetter" || root.ParentSpanID != "" || root.Attributes[0].Value.StringValue != "https://example.com/" {
+			t.Fatal("unexpected root span", root)
+		}
+		if root.EndTimeUnixNano != unixNano(begin.Add(30*time.Millisecond)) {
+			t.Fatal("expected the root span to end with the last child", root)
+		}
+		if child.ParentSpanID != root.SpanID || child.TraceID != root.TraceID || len(child.TraceID) != 32 {
+			t.Fatal("unexpected child span", child)
+		}
+	})
+
+	t.Run("when the collector fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(500)
+		}))
+		defer server.Close()
+		exporter := NewExporter(server.URL, http.DefaultClient)
+		err := exporter.Export(context.Background(), "urlgetter", nil, begin, events)
+		if err == nil || err.Error() != "otlp: collector returned 500" {
+			t.Fatal("unexpected error", err)
+		}
+	})
+
+	t.Run("when we cannot generate IDs", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		exporter := NewExporter("http://127.0.0.1/", http.DefaultClient)
+		exporter.randRead = func(b []byte) (int, error) {
+			return 0, expected
+		}
+		err := exporter.Export(context.Background(), "urlgetter", nil, begin, events)
+		if !errors.Is(err, expected) {
+			t.Fatal("unexpected error", err)
+		}
+	})
+}
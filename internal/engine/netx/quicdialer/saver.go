@@ -23,6 +23,7 @@ func (h HandshakeSaver) DialContext(ctx context.Context, network string,
 	start := time.Now()
 	// TODO(bassosimone): in the future we probably want to also save
 	// information about what versions we're willing to accept.
+	txID := h.Saver.NewTransactionID()
 	h.Saver.Write(trace.Event{
 		Address:       host,
 		Name:          "quic_handshake_start",
@@ -31,6 +32,7 @@ func (h HandshakeSaver) DialContext(ctx context.Context, network string,
 		TLSNextProtos: tlsCfg.NextProtos,
 		TLSServerName: tlsCfg.ServerName,
 		Time:          start,
+		TransactionID: txID,
 	})
 	sess, err := h.QUICDialer.DialContext(ctx, network, host, tlsCfg, cfg)
 	stop := time.Now()
@@ -43,6 +45,7 @@ func (h HandshakeSaver) DialContext(ctx context.Context, network string,
 			TLSNextProtos: tlsCfg.NextProtos,
 			TLSServerName: tlsCfg.ServerName,
 			Time:          stop,
+			TransactionID: txID,
 		})
 		return nil, err
 	}
@@ -58,6 +61,7 @@ func (h HandshakeSaver) DialContext(ctx context.Context, network string,
 		TLSServerName:      tlsCfg.ServerName,
 		TLSVersion:         netxlite.TLSVersionString(state.Version),
 		Time:               stop,
+		TransactionID:      txID,
 	})
 	return sess, nil
 }
@@ -2,7 +2,6 @@ package resolver
 
 import (
 	"context"
-	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -12,29 +11,36 @@ import (
 type SaverResolver struct {
 	model.Resolver
 	Saver *trace.Saver
+
+	// Clock is the OPTIONAL clock (default: model.SystemClock).
+	Clock model.Clock
 }
 
 // LookupHost implements Resolver.LookupHost
 func (r SaverResolver) LookupHost(ctx context.Context, hostname string) ([]string, error) {
-	start := time.Now()
+	clock := model.ClockOrSystemClock(r.Clock)
+	start := clock.Now()
+	txID := r.Saver.NewTransactionID()
 	r.Saver.Write(trace.Event{
-		Address:  r.Resolver.Address(),
-		Hostname: hostname,
-		Name:     "resolve_start",
-		Proto:    r.Resolver.Network(),
-		Time:     start,
+		Address:       r.Resolver.Address(),
+		Hostname:      hostname,
+		Name:          "resolve_start",
+		Proto:         r.Resolver.Network(),
+		Time:          start,
+		TransactionID: txID,
 	})
 	addrs, err := r.Resolver.LookupHost(ctx, hostname)
-	stop := time.Now()
+	stop := clock.Now()
 	r.Saver.Write(trace.Event{
-		Addresses: addrs,
-		Address:   r.Resolver.Address(),
-		Duration:  stop.Sub(start),
-		Err:       err,
-		Hostname:  hostname,
-		Name:      "resolve_done",
-		Proto:     r.Resolver.Network(),
-		Time:      stop,
+		Addresses:     addrs,
+		Address:       r.Resolver.Address(),
+		Duration:      stop.Sub(start),
+		Err:           err,
+		Hostname:      hostname,
+		Name:          "resolve_done",
+		Proto:         r.Resolver.Network(),
+		Time:          stop,
+		TransactionID: txID,
 	})
 	return addrs, err
 }
@@ -43,29 +49,36 @@ func (r SaverResolver) LookupHost(ctx context.Context, hostname string) ([]strin
 type SaverDNSTransport struct {
 	model.DNSTransport
 	Saver *trace.Saver
+
+	// Clock is the OPTIONAL clock (default: model.SystemClock).
+	Clock model.Clock
 }
 
 // RoundTrip implements RoundTripper.RoundTrip
 func (txp SaverDNSTransport) RoundTrip(ctx context.Context, query []byte) ([]byte, error) {
-	start := time.Now()
+	clock := model.ClockOrSystemClock(txp.Clock)
+	start := clock.Now()
+	txID := txp.Saver.NewTransactionID()
 	txp.Saver.Write(trace.Event{
-		Address:  txp.Address(),
-		DNSQuery: query,
-		Name:     "dns_round_trip_start",
-		Proto:    txp.Network(),
-		Time:     start,
+		Address:       txp.Address(),
+		DNSQuery:      query,
+		Name:          "dns_round_trip_start",
+		Proto:         txp.Network(),
+		Time:          start,
+		TransactionID: txID,
 	})
 	reply, err := txp.DNSTransport.RoundTrip(ctx, query)
-	stop := time.Now()
+	stop := clock.Now()
 	txp.Saver.Write(trace.Event{
-		Address:  txp.Address(),
-		DNSQuery: query,
-		DNSReply: reply,
-		Duration: stop.Sub(start),
-		Err:      err,
-		Name:     "dns_round_trip_done",
-		Proto:    txp.Network(),
-		Time:     stop,
+		Address:       txp.Address(),
+		DNSQuery:      query,
+		DNSReply:      reply,
+		Duration:      stop.Sub(start),
+		Err:           err,
+		Name:          "dns_round_trip_done",
+		Proto:         txp.Network(),
+		Time:          stop,
+		TransactionID: txID,
 	})
 	return reply, err
 }
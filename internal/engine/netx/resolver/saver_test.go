@@ -10,6 +10,7 @@ import (
 
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/resolver"
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
 )
 
 func TestSaverResolverFailure(t *testing.T) {
@@ -205,3 +206,38 @@ func TestSaverDNSTransportSuccess(t *testing.T) {
 		t.Fatal("the saved time is wrong")
 	}
 }
+
+func TestSaverResolverWithFakeClock(t *testing.T) {
+	begin := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := mocks.NewFakeClock(begin)
+	saver := &trace.Saver{}
+	reso := resolver.SaverResolver{
+		Resolver: &mocks.Resolver{
+			MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+				clock.Advance(3 * time.Second) // simulate a slow lookup
+				return []string{"8.8.8.8"}, nil
+			},
+			MockAddress: func() string {
+				return ""
+			},
+			MockNetwork: func() string {
+				return "system"
+			},
+		},
+		Saver: saver,
+		Clock: clock,
+	}
+	if _, err := reso.LookupHost(context.Background(), "dns.google"); err != nil {
+		t.Fatal(err)
+	}
+	ev := saver.Read()
+	if len(ev) != 2 {
+		t.Fatal("expected number of events")
+	}
+	if !ev[0].Time.Equal(begin) {
+		t.Fatal("unexpected start time", ev[0].Time)
+	}
+	if ev[1].Duration != 3*time.Second || !ev[1].Time.Equal(begin.Add(3*time.Second)) {
+		t.Fatal("unexpected duration or stop time", ev[1].Duration, ev[1].Time)
+	}
+}
@@ -22,12 +22,14 @@ func (h SaverTLSHandshaker) Handshake(
 	ctx context.Context, conn net.Conn, config *tls.Config,
 ) (net.Conn, tls.ConnectionState, error) {
 	start := time.Now()
+	txID := h.Saver.NewTransactionID()
 	h.Saver.Write(trace.Event{
 		Name:          "tls_handshake_start",
 		NoTLSVerify:   config.InsecureSkipVerify,
 		TLSNextProtos: config.NextProtos,
 		TLSServerName: config.ServerName,
 		Time:          start,
+		TransactionID: txID,
 	})
 	remoteAddr := conn.RemoteAddr().String()
 	tlsconn, state, err := h.TLSHandshaker.Handshake(ctx, conn, config)
@@ -45,6 +47,7 @@ func (h SaverTLSHandshaker) Handshake(
 		TLSServerName:      config.ServerName,
 		TLSVersion:         netxlite.TLSVersionString(state.Version),
 		Time:               stop,
+		TransactionID:      txID,
 	})
 	return tlsconn, state, err
 }
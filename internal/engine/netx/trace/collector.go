@@ -0,0 +1,113 @@
+package trace
+
+import (
+	"sort"
+	"strings"
+)
+
+// Collector stores the events read from a Saver. It assigns transaction
+// IDs to the events, deduplicates identical DNS answers, and indexes the
+// events by name and by transaction ID, so that extracting the events of
+// a given operation does not require scanning the whole trace.
+//
+// The code saving events marks the events belonging to the same operation
+// (e.g., the start and done events of a TLS handshake or the events of an
+// HTTP transaction) with the same transaction ID (see NewTransactionID),
+// hence we match them even when operations of the same kind run
+// concurrently. Any other event forms a transaction on its own. We
+// renumber the transaction IDs in the order in which we see them.
+//
+// The zero value is ready to use. This struct is not goroutine safe: you
+// typically fill it with the result of Saver.Read after measuring.
+type Collector struct {
+	answers  map[string][]string
+	byName   map[string][]int
+	byTxID   map[int64][]int
+	events   []Event
+	lastTxID int64
+	txIDs    map[int64]int64
+}
+
+// Add adds the given events, read from a single Saver, to the collector.
+// The collector owns the events after this call. To avoid copying a large
+// trace, we reuse the storage of events when the collector is empty.
+func (c *Collector) Add(events ...Event) {
+	if c.byName == nil {
+		c.answers = make(map[string][]string)
+		c.byName = make(map[string][]int)
+		c.byTxID = make(map[int64][]int)
+		c.txIDs = make(map[int64]int64)
+	}
+	if len(c.events) <= 0 {
+		c.events = events[:0]
+	}
+	for _, ev := range events {
+		ev.TransactionID = c.transactionID(ev.TransactionID)
+		if ev.Name == "resolve_done" && len(ev.Addresses) > 0 {
+			ev.Addresses = c.dedupAnswers(ev.Addresses)
+		}
+		idx := len(c.events)
+		c.events = append(c.events, ev)
+		c.byName[ev.Name] = append(c.byName[ev.Name], idx)
+		c.byTxID[ev.TransactionID] = append(c.byTxID[ev.TransactionID], idx)
+	}
+}
+
+// transactionID returns the transaction ID of an event that the code
+// saving events marked with the given transaction ID.
+func (c *Collector) transactionID(saved int64) int64 {
+	if id, found := c.txIDs[saved]; found && saved != 0 {
+		return id
+	}
+	c.lastTxID++
+	if saved != 0 {
+		c.txIDs[saved] = c.lastTxID
+	}
+	return c.lastTxID
+}
+
+// dedupAnswers returns a previously seen slice equal to addrs, if
+// any, so that identical DNS answers share the same memory.
+func (c *Collector) dedupAnswers(addrs []string) []string {
+	key := strings.Join(addrs, "\n")
+	if v, found := c.answers[key]; found {
+		return v
+	}
+	c.answers[key] = addrs
+	return addrs
+}
+
+// Events returns all the events in the order in which they were added.
+// To avoid copying the trace, we return the events owned by the
+// collector, which the caller MUST NOT modify.
+func (c *Collector) Events() []Event {
+	return c.events
+}
+
+// ByName returns the events with any of the given names in the
+// order in which they were added.
+func (c *Collector) ByName(names ...string) []Event {
+	var indexes []int
+	for _, name := range names {
+		indexes = append(indexes, c.byName[name]...)
+	}
+	if len(names) > 1 {
+		sort.Ints(indexes)
+	}
+	return c.collect(indexes)
+}
+
+// ByTransactionID returns the events belonging to the given
+// transaction in the order in which they were added.
+func (c *Collector) ByTransactionID(id int64) []Event {
+	return c.collect(c.byTxID[id])
+}
+
+// collect returns the events with the given indexes.
+func (c *Collector) collect(indexes []int) []Event {
+	out := make([]Event, 0, len(indexes))
+	for _, idx := range indexes {
+		out = append(out, c.events[idx])
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package trace_test
+
+import (
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
+)
+
+func names(events []trace.Event) (out []string) {
+	for _, ev := range events {
+		out = append(out, ev.Name)
+	}
+	return
+}
+
+func TestCollector(t *testing.T) {
+	t.Run("the zero value is ready to use", func(t *testing.T) {
+		c := &trace.Collector{}
+		if events := c.Events(); len(events) != 0 {
+			t.Fatal("expected no events")
+		}
+		if events := c.ByName("resolve_done"); len(events) != 0 {
+			t.Fatal("expected no events")
+		}
+		if events := c.ByTransactionID(1); len(events) != 0 {
+			t.Fatal("expected no events")
+		}
+	})
+
+	t.Run("we assign transaction IDs and index events", func(t *testing.T) {
+		c := &trace.Collector{}
+		c.Add(
+			trace.Event{Name: "resolve_start", TransactionID: 10},               // 0: tx 1
+			trace.Event{Name: "resolve_done", TransactionID: 10},                // 1: tx 1
+			trace.Event{Name: "connect"},                                        // 2: tx 2
+			trace.Event{Name: "tls_handshake_start", TransactionID: 11},         // 3: tx 3
+			trace.Event{Name: "tls_handshake_start", TransactionID: 12},         // 4: tx 4
+			trace.Event{Name: "write"},                                          // 5: tx 5
+			trace.Event{Name: "tls_handshake_done", TransactionID: 12},          // 6: tx 4
+			trace.Event{Name: "tls_handshake_done", TransactionID: 11},          // 7: tx 3
+			trace.Event{Name: "http_transaction_start", TransactionID: 13},      // 8: tx 6
+			trace.Event{Name: "http_request_metadata", TransactionID: 13},       // 9: tx 6
+			trace.Event{Name: "http_response_metadata", TransactionID: 13},      // 10: tx 6
+			trace.Event{Name: "http_response_body_snapshot", TransactionID: 13}, // 11: tx 6
+			trace.Event{Name: "http_transaction_done", TransactionID: 13},       // 12: tx 6
+			trace.Event{Name: "quic_handshake_done", TransactionID: 14},         // 13: tx 7
+		)
+		expectIDs := []int64{1, 1, 2, 3, 4, 5, 4, 3, 6, 6, 6, 6, 6, 7}
+		events := c.Events()
+		if len(events) != len(expectIDs) {
+			t.Fatal("unexpected number of events", len(events))
+		}
+		for idx, ev := range events {
+			if ev.TransactionID != expectIDs[idx] {
+				t.Fatal("unexpected transaction ID for", idx, ev.Name, ev.TransactionID)
+			}
+		}
+		got := names(c.ByName("quic_handshake_done", "tls_handshake_done"))
+		if len(got) != 3 || got[0] != "tls_handshake_done" || got[2] != "quic_handshake_done" {
+			t.Fatal("unexpected events", got)
+		}
+		got = names(c.ByTransactionID(6))
+		if len(got) != 5 || got[0] != "http_transaction_start" || got[4] != "http_transaction_done" {
+			t.Fatal("unexpected events", got)
+		}
+	})
+
+	t.Run("we match events saved by concurrent operations", func(t *testing.T) {
+		saver := &trace.Saver{}
+		first, second := saver.NewTransactionID(), saver.NewTransactionID()
+		saver.Write(trace.Event{Name: "tls_handshake_start", Address: "first", TransactionID: first})
+		saver.Write(trace.Event{Name: "tls_handshake_start", Address: "second", TransactionID: second})
+		saver.Write(trace.Event{Name: "tls_handshake_done", Address: "second", TransactionID: second})
+		saver.Write(trace.Event{Name: "tls_handshake_done", Address: "first", TransactionID: first})
+		c := &trace.Collector{}
+		c.Add(saver.Read()...)
+		for _, ev := range c.ByName("tls_handshake_done") {
+			events := c.ByTransactionID(ev.TransactionID)
+			if len(events) != 2 || events[0].Address != ev.Address {
+				t.Fatal("unexpected transaction", events)
+			}
+		}
+	})
+
+	t.Run("we deduplicate identical DNS answers", func(t *testing.T) {
+		c := &trace.Collector{}
+		c.Add(
+			trace.Event{Name: "resolve_done", Addresses: []string{"8.8.8.8", "8.8.4.4"}},
+			trace.Event{Name: "resolve_done", Addresses: []string{"8.8.8.8", "8.8.4.4"}},
+			trace.Event{Name: "resolve_done", Addresses: []string{"8.8.8.8"}},
+		)
+		events := c.ByName("resolve_done")
+		if len(events) != 3 {
+			t.Fatal("unexpected number of events", len(events))
+		}
+		if &events[0].Addresses[0] != &events[1].Addresses[0] {
+			t.Fatal("expected identical answers to share memory")
+		}
+		if len(events[2].Addresses) != 1 || events[2].Addresses[0] != "8.8.8.8" {
+			t.Fatal("unexpected addresses", events[2].Addresses)
+		}
+	})
+
+	t.Run("Add is incremental", func(t *testing.T) {
+		c := &trace.Collector{}
+		c.Add(trace.Event{Name: "http_transaction_start", TransactionID: 1})
+		c.Add(trace.Event{Name: "http_transaction_done", TransactionID: 1})
+		events := c.Events()
+		if len(events) != 2 || events[0].TransactionID != events[1].TransactionID {
+			t.Fatal("unexpected events", events)
+		}
+	})
+}
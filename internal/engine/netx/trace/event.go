@@ -16,6 +16,7 @@ type Event struct {
 	DNSReply           []byte              `json:",omitempty"`
 	DataIsTruncated    bool                `json:",omitempty"`
 	Data               []byte              `json:",omitempty"`
+	DroppedEvents      int                 `json:",omitempty"`
 	Duration           time.Duration       `json:",omitempty"`
 	Err                error               `json:",omitempty"`
 	HTTPHeaders        http.Header         `json:",omitempty"`
@@ -34,6 +35,7 @@ type Event struct {
 	TLSPeerCerts       []*x509.Certificate `json:",omitempty"`
 	TLSVersion         string              `json:",omitempty"`
 	Time               time.Time           `json:",omitempty"`
+	TransactionID      int64               `json:",omitempty"`
 	Transport          string              `json:",omitempty"`
 }
 
@@ -1,11 +1,45 @@
 package trace
 
-import "sync"
+import (
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// OverflowEventName is the name of the event replacing the events
+// we drop because the trace contains too many events.
+const OverflowEventName = "trace_overflow"
 
 // The Saver saves a trace
 type Saver struct {
-	ops []Event
-	mu  sync.Mutex
+	// MaxEvents is the OPTIONAL maximum number of events to save. Past
+	// this limit, we first evict the low-value I/O events (read, write,
+	// read_from, write_to) and only then drop incoming events. We account
+	// for evicted and dropped events in a single event named
+	// OverflowEventName, whose DroppedEvents field counts them.
+	MaxEvents int
+
+	// MaxDataBytes is the OPTIONAL maximum number of bytes of data (e.g.,
+	// body snapshots) to save. Past this limit, we truncate the data of
+	// events and set their DataIsTruncated field.
+	MaxDataBytes int64
+
+	dataBytes   int64
+	dropped     int
+	droppedTime time.Time
+	lastTxID    int64
+	ops         []Event
+	mu          sync.Mutex
+}
+
+// NewSaver creates a new Saver honouring the given memory budget.
+func NewSaver(budget model.MemoryBudget) *Saver {
+	return &Saver{
+		MaxEvents:    budget.MaxTraceEvents,
+		MaxDataBytes: budget.MaxTraceDataBytes,
+	}
 }
 
 // Read reads and returns events inside the trace. It advances
@@ -14,7 +48,17 @@ func (s *Saver) Read() []Event {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	v := s.ops
+	if s.dropped > 0 {
+		v = append(v, Event{
+			DroppedEvents: s.dropped,
+			Name:          OverflowEventName,
+			Time:          s.droppedTime,
+		})
+	}
 	s.ops = nil
+	s.dataBytes = 0
+	s.dropped = 0
+	s.droppedTime = time.Time{}
 	return v
 }
 
@@ -23,5 +67,56 @@ func (s *Saver) Read() []Event {
 func (s *Saver) Write(ev Event) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.MaxEvents > 0 && len(s.ops) >= s.MaxEvents {
+		if isLowValue(ev) || !s.evictLowValue() {
+			s.drop(ev)
+			return
+		}
+	}
+	if s.MaxDataBytes > 0 && len(ev.Data) > 0 {
+		if avail := s.MaxDataBytes - s.dataBytes; int64(len(ev.Data)) > avail {
+			ev.Data = ev.Data[:avail]
+			ev.DataIsTruncated = true
+		}
+		s.dataBytes += int64(len(ev.Data))
+	}
 	s.ops = append(s.ops, ev)
 }
+
+// isLowValue returns whether the given event is a read or write
+// event, which we sacrifice first when the trace is full.
+func isLowValue(ev Event) bool {
+	switch ev.Name {
+	case netxlite.ReadOperation, netxlite.WriteOperation,
+		netxlite.ReadFromOperation, netxlite.WriteToOperation:
+		return true
+	default:
+		return false
+	}
+}
+
+// evictLowValue evicts the oldest low-value event to make room
+// for a new event. It returns false if there is none.
+func (s *Saver) evictLowValue() bool {
+	for idx, ev := range s.ops {
+		if isLowValue(ev) {
+			s.ops = append(s.ops[:idx], s.ops[idx+1:]...)
+			s.dataBytes -= int64(len(ev.Data))
+			s.drop(ev)
+			return true
+		}
+	}
+	return false
+}
+
+// drop accounts for the given event, which we are not saving. Read
+// reports dropped events using a single OverflowEventName event.
+func (s *Saver) drop(ev Event) {
+	if s.dropped == 0 {
+		s.droppedTime = ev.Time
+		if s.droppedTime.IsZero() {
+			s.droppedTime = time.Now()
+		}
+	}
+	s.dropped++
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/ooni/probe-cli/v3/internal/engine/netx/trace"
+	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
 func TestGood(t *testing.T) {
@@ -24,3 +25,68 @@ func TestGood(t *testing.T) {
 		t.Fatal("unexpected number of events read")
 	}
 }
+
+func TestMaxEvents(t *testing.T) {
+	saver := trace.NewSaver(model.MemoryBudget{MaxTraceEvents: 2})
+	for idx := 0; idx < 5; idx++ {
+		saver.Write(trace.Event{Name: "connect"})
+	}
+	ev := saver.Read()
+	if len(ev) != 3 {
+		t.Fatal("unexpected number of events read", len(ev))
+	}
+	overflow := ev[2]
+	if overflow.Name != trace.OverflowEventName || overflow.DroppedEvents != 3 || overflow.Time.IsZero() {
+		t.Fatal("unexpected overflow event", overflow)
+	}
+	saver.Write(trace.Event{Name: "connect"})
+	if ev := saver.Read(); len(ev) != 1 || ev[0].Name != "connect" {
+		t.Fatal("expected Read to reset the budget", ev)
+	}
+}
+
+func TestMaxEventsEvictsReadWriteFirst(t *testing.T) {
+	saver := trace.NewSaver(model.MemoryBudget{MaxTraceEvents: 3})
+	saver.Write(trace.Event{Name: "read", Data: []byte("abc")})
+	saver.Write(trace.Event{Name: "tls_handshake_start"})
+	saver.Write(trace.Event{Name: "write"})
+	saver.Write(trace.Event{Name: "tls_handshake_done"})
+	saver.Write(trace.Event{Name: "read"})
+	saver.Write(trace.Event{Name: "http_transaction_done"})
+	saver.Write(trace.Event{Name: "connect"})
+	ev := saver.Read()
+	if len(ev) != 4 {
+		t.Fatal("unexpected number of events read", len(ev))
+	}
+	for idx, name := range []string{
+		"tls_handshake_start", "tls_handshake_done", "http_transaction_done",
+	} {
+		if ev[idx].Name != name {
+			t.Fatal("unexpected event", idx, ev[idx])
+		}
+	}
+	overflow := ev[3]
+	if overflow.Name != trace.OverflowEventName || overflow.DroppedEvents != 4 {
+		t.Fatal("unexpected overflow event", overflow)
+	}
+}
+
+func TestMaxDataBytes(t *testing.T) {
+	saver := trace.NewSaver(model.MemoryBudget{MaxTraceDataBytes: 10})
+	saver.Write(trace.Event{Data: []byte("abcdef")})
+	saver.Write(trace.Event{Data: []byte("ghijkl")})
+	saver.Write(trace.Event{Data: []byte("mnopqr")})
+	ev := saver.Read()
+	if len(ev) != 3 {
+		t.Fatal("unexpected number of events read", len(ev))
+	}
+	if string(ev[0].Data) != "abcdef" || ev[0].DataIsTruncated {
+		t.Fatal("unexpected first event", ev[0])
+	}
+	if string(ev[1].Data) != "ghij" || !ev[1].DataIsTruncated {
+		t.Fatal("unexpected second event", ev[1])
+	}
+	if len(ev[2].Data) != 0 || !ev[2].DataIsTruncated {
+		t.Fatal("unexpected third event", ev[2])
+	}
+}
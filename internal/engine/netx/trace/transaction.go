@@ -0,0 +1,31 @@
+package trace
+
+import "context"
+
+// NewTransactionID returns a new transaction ID. The code saving events
+// sets the same transaction ID on all the events of an operation (e.g.,
+// the start and done events of a TLS handshake), such that a Collector
+// matches them even when operations of the same kind run concurrently.
+func (s *Saver) NewTransactionID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastTxID++
+	return s.lastTxID
+}
+
+// transactionIDKey is the context key for the transaction ID.
+type transactionIDKey struct{}
+
+// WithTransactionID returns a copy of ctx carrying the given transaction
+// ID, which allows nested code (e.g., the HTTP transports wrapped by
+// the one saving the HTTP transaction) to mark its events.
+func WithTransactionID(ctx context.Context, id int64) context.Context {
+	return context.WithValue(ctx, transactionIDKey{}, id)
+}
+
+// ContextTransactionID returns the transaction ID carried by ctx
+// or zero, if ctx carries no transaction ID.
+func ContextTransactionID(ctx context.Context) int64 {
+	id, _ := ctx.Value(transactionIDKey{}).(int64)
+	return id
+}
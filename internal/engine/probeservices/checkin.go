@@ -7,8 +7,9 @@ import (
 )
 
 type checkInResult struct {
-	Tests model.OOAPICheckInInfo `json:"tests"`
-	V     int                    `json:"v"`
+	Conf  model.OOAPICheckInInfoConfig `json:"conf"`
+	Tests model.OOAPICheckInInfo       `json:"tests"`
+	V     int                          `json:"v"`
 }
 
 // CheckIn function is called by probes asking if there are tests to be run
@@ -19,5 +20,6 @@ func (c Client) CheckIn(ctx context.Context, config model.OOAPICheckInConfig) (*
 	if err := c.APIClientTemplate.Build().PostJSON(ctx, "/api/v1/check-in", config, &response); err != nil {
 		return nil, err
 	}
+	response.Tests.Conf = response.Conf
 	return &response.Tests, nil
 }
@@ -2,12 +2,18 @@ package probeservices
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"reflect"
 	"sync"
+	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/httpx"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 const (
@@ -18,6 +24,14 @@ const (
 
 	// DefaultFormat is the default format
 	DefaultFormat = "json"
+
+	// DefaultSubmitRetries is the default number of times we retry
+	// submitting a measurement after a transient failure.
+	DefaultSubmitRetries = 3
+
+	// DefaultSubmitRetryDelay is the default delay before the first
+	// retry of a failed submission.
+	DefaultSubmitRetryDelay = 2 * time.Second
 )
 
 var (
@@ -81,8 +95,10 @@ func NewReportTemplate(m *model.Measurement) ReportTemplate {
 }
 
 type collectorOpenResponse struct {
-	ID               string   `json:"report_id"`
-	SupportedFormats []string `json:"supported_formats"`
+	ID                        string   `json:"report_id"`
+	SupportedFormats          []string `json:"supported_formats"`
+	ResumableUpload           bool     `json:"resumable_upload"`
+	SupportedContentEncodings []string `json:"supported_content_encodings"`
 }
 
 type reportChan struct {
@@ -94,6 +110,13 @@ type reportChan struct {
 
 	// tmpl is the template used when opening this report.
 	tmpl ReportTemplate
+
+	// resumable indicates whether the collector supports resumable uploads.
+	resumable bool
+
+	// contentEncoding is the OPTIONAL encoding we use for compressing
+	// the submitted measurements, negotiated when opening the report.
+	contentEncoding string
 }
 
 // OpenReport opens a new report.
@@ -110,12 +133,33 @@ func (c Client) OpenReport(ctx context.Context, rt ReportTemplate) (ReportChanne
 	}
 	for _, format := range cor.SupportedFormats {
 		if format == "json" {
-			return &reportChan{ID: cor.ID, client: c, tmpl: rt}, nil
+			return &reportChan{
+				ID:              cor.ID,
+				client:          c,
+				tmpl:            rt,
+				resumable:       cor.ResumableUpload,
+				contentEncoding: c.negotiateContentEncoding(cor.SupportedContentEncodings),
+			}, nil
 		}
 	}
 	return nil, ErrJSONFormatNotSupported
 }
 
+// negotiateContentEncoding returns the encoding to use for compressing
+// the submitted measurements given the encodings supported by the
+// collector, or the empty string if we should not compress.
+func (c Client) negotiateContentEncoding(supported []string) string {
+	if !c.CompressSubmissions {
+		return ""
+	}
+	for _, encoding := range supported {
+		if encoding == httpx.ContentEncodingGzip {
+			return encoding
+		}
+	}
+	return ""
+}
+
 type collectorUpdateRequest struct {
 	// Format is the data format
 	Format string `json:"format"`
@@ -141,20 +185,134 @@ func (r reportChan) CanSubmit(m *model.Measurement) bool {
 // such that it contains the report ID for which it has been
 // submitted. Otherwise, we'll set the report ID to the empty
 // string, so that you know which measurements weren't submitted.
+//
+// When the collector supports resumable uploads, we upload measurements
+// larger than a chunk in chunks (see upload.go) and we retry after any
+// transient failure, since retrying continues the same upload. Otherwise,
+// we only retry when the collector cannot have stored the measurement,
+// so that retrying does not cause duplicate measurements.
 func (r reportChan) SubmitMeasurement(ctx context.Context, m *model.Measurement) error {
-	var updateResponse collectorUpdateResponse
 	m.ReportID = r.ID
-	err := r.client.APIClientTemplate.WithBodyLogging().Build().PostJSON(
-		ctx, fmt.Sprintf("/report/%s", r.ID), collectorUpdateRequest{
-			Format:  "json",
-			Content: m,
-		}, &updateResponse,
-	)
-	if err != nil {
-		m.ReportID = ""
-		return err
-	}
-	return nil
+	submit, isRetryable := r.submitterFor(m), isSafeToResubmit
+	if r.resumable {
+		data, err := json.Marshal(m)
+		if err != nil {
+			m.ReportID = ""
+			return err
+		}
+		if int64(len(data)) > r.client.uploadChunkSize() {
+			submit = func(ctx context.Context) (string, error) {
+				return r.submitResumable(ctx, data)
+			}
+			isRetryable = isTransientUploadFailure
+		}
+	}
+	delay := r.client.submitRetryDelay()
+	for attempt := 0; ; attempt++ {
+		measurementID, err := submit(ctx)
+		if err == nil {
+			r.client.Logger.Debugf("probeservices: measurement ID: %s", measurementID)
+			return nil
+		}
+		if attempt >= r.client.submitRetries() || ctx.Err() != nil || !isRetryable(err) {
+			m.ReportID = ""
+			return err
+		}
+		r.client.Logger.Debugf("probeservices: submission failed: %s; retrying in %s", err, delay)
+		timer := model.ClockOrSystemClock(r.client.Clock).NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			m.ReportID = ""
+			return ctx.Err()
+		case <-timer.C():
+		}
+		delay *= 2
+	}
+}
+
+// submitterFor returns a function that submits the given measurement
+// using a single request and returns the measurement ID.
+func (r reportChan) submitterFor(m *model.Measurement) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		var updateResponse collectorUpdateResponse
+		err := r.apiClientTemplate().WithBodyLogging().Build().PostJSON(
+			ctx, fmt.Sprintf("/report/%s", r.ID), collectorUpdateRequest{
+				Format:  "json",
+				Content: m,
+			}, &updateResponse,
+		)
+		return updateResponse.ID, err
+	}
+}
+
+// isSafeToResubmit returns whether err indicates that the submission
+// did not reach the collector or that the collector refused it before
+// storing the measurement. After other failures (e.g., a 500 status or
+// a connection reset while waiting for the response), the collector
+// may have stored the measurement, so resubmitting could duplicate it.
+func isSafeToResubmit(err error) bool {
+	var statusErr *httpx.StatusCodeError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode == http.StatusServiceUnavailable
+	}
+	var wrapper *netxlite.ErrWrapper
+	if errors.As(err, &wrapper) {
+		switch wrapper.Operation {
+		case netxlite.ResolveOperation, netxlite.ConnectOperation, netxlite.TLSHandshakeOperation:
+			return true
+		default:
+			return false
+		}
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isTransientUploadFailure returns whether err is a failure after
+// which we should retry a resumable upload, i.e., a network error,
+// a 429 status, or a 5xx status.
+func isTransientUploadFailure(err error) bool {
+	var statusErr *httpx.StatusCodeError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode >= 500
+	}
+	var wrapper *netxlite.ErrWrapper
+	if errors.As(err, &wrapper) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// submitRetries returns the number of times we retry submitting.
+func (c Client) submitRetries() int {
+	if c.SubmitRetries > 0 {
+		return c.SubmitRetries
+	}
+	return DefaultSubmitRetries
+}
+
+// submitRetryDelay returns the delay before the first retry.
+func (c Client) submitRetryDelay() time.Duration {
+	if c.SubmitRetryDelay > 0 {
+		return c.SubmitRetryDelay
+	}
+	return DefaultSubmitRetryDelay
+}
+
+// apiClientTemplate returns the template for the API clients
+// we use for submitting measurements belonging to this report.
+func (r reportChan) apiClientTemplate() *httpx.APIClientTemplate {
+	tmpl := r.client.APIClientTemplate
+	tmpl.ContentEncoding = r.contentEncoding
+	return &tmpl
 }
 
 // ReportID returns the report ID.
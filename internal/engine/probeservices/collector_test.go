@@ -3,18 +3,24 @@ package probeservices_test
 import (
 	"context"
 	"errors"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"reflect"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/google/go-cmp/cmp"
 	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/ooni/probe-cli/v3/internal/httpx"
 	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
@@ -458,3 +464,170 @@ func TestSubmitterCannotOpenNewChannel(t *testing.T) {
 		t.Fatal("unexpected number of channels")
 	}
 }
+
+// flakyCollector is a collector failing the first submissions.
+type flakyCollector struct {
+	// failures is the number of submissions that should fail.
+	failures int
+
+	// status is the status code of the failing submissions.
+	status int
+
+	// submissions counts the submissions.
+	submissions int
+
+	mu sync.Mutex
+}
+
+func (fc *flakyCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	switch r.URL.Path {
+	case "/report":
+		w.Write([]byte(`{"report_id":"_id","supported_formats":["json"]}`))
+	case "/report/_id":
+		fc.submissions++
+		if fc.submissions <= fc.failures {
+			w.WriteHeader(fc.status)
+			return
+		}
+		w.Write([]byte(`{"measurement_id":"e00c584e6e9e5326"}`))
+	default:
+		w.WriteHeader(404)
+	}
+}
+
+// instantClock is a clock whose timers fire immediately and which
+// records the delays of the timers, such that tests don't sleep.
+type instantClock struct {
+	model.Clock
+	delays []time.Duration
+}
+
+func (c *instantClock) NewTimer(d time.Duration) model.Timer {
+	c.delays = append(c.delays, d)
+	return model.SystemClock.NewTimer(0)
+}
+
+func submitToFlakyCollector(t *testing.T, fc *flakyCollector) (*model.Measurement, error) {
+	measurement, _, err := submitToFlakyCollectorWithClock(t, fc)
+	return measurement, err
+}
+
+func submitToFlakyCollectorWithClock(
+	t *testing.T, fc *flakyCollector) (*model.Measurement, *instantClock, error) {
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	client := newclient()
+	client.BaseURL = server.URL
+	clock := &instantClock{}
+	client.Clock = clock
+	template := probeservices.ReportTemplate{
+		DataFormatVersion: probeservices.DefaultDataFormatVersion,
+		Format:            probeservices.DefaultFormat,
+		ProbeASN:          "AS0",
+		ProbeCC:           "ZZ",
+		SoftwareName:      "ooniprobe-engine",
+		SoftwareVersion:   "0.1.0",
+		TestName:          "dummy",
+		TestStartTime:     "2018-11-01 15:33:17",
+		TestVersion:       "0.1.0",
+	}
+	report, err := client.OpenReport(context.Background(), template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	measurement := makeMeasurement(template, report.ReportID())
+	err = report.SubmitMeasurement(context.Background(), &measurement)
+	return &measurement, clock, err
+}
+
+func TestSubmitMeasurementRetries(t *testing.T) {
+	t.Run("after a transient failure", func(t *testing.T) {
+		fc := &flakyCollector{failures: 2, status: 503}
+		measurement, clock, err := submitToFlakyCollectorWithClock(t, fc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fc.submissions != 3 || measurement.ReportID != "_id" {
+			t.Fatal("unexpected state", fc.submissions, measurement.ReportID)
+		}
+		expected := []time.Duration{
+			probeservices.DefaultSubmitRetryDelay,
+			2 * probeservices.DefaultSubmitRetryDelay,
+		}
+		if diff := cmp.Diff(expected, clock.delays); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("until we run out of retries", func(t *testing.T) {
+		fc := &flakyCollector{failures: 10, status: 503}
+		measurement, err := submitToFlakyCollector(t, fc)
+		if !errors.Is(err, httpx.ErrRequestFailed) {
+			t.Fatal("not the error we expected", err)
+		}
+		if fc.submissions != probeservices.DefaultSubmitRetries+1 || measurement.ReportID != "" {
+			t.Fatal("unexpected state", fc.submissions, measurement.ReportID)
+		}
+	})
+
+	t.Run("after a connection failure", func(t *testing.T) {
+		var submissions int
+		client := newclient()
+		client.SubmitRetryDelay = time.Millisecond
+		client.HTTPClient = &mocks.HTTPClient{
+			MockDo: func(req *http.Request) (*http.Response, error) {
+				body := `{"report_id":"_id","supported_formats":["json"]}`
+				if req.URL.Path == "/report/_id" {
+					submissions++
+					if submissions <= 2 {
+						return nil, &url.Error{Op: "Post", URL: req.URL.String(), Err: &net.OpError{
+							Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+					}
+					body = `{"measurement_id":"e00c584e6e9e5326"}`
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(strings.NewReader(body)),
+				}, nil
+			},
+		}
+		template := probeservices.NewReportTemplate(&model.Measurement{})
+		report, err := client.OpenReport(context.Background(), template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		measurement := makeMeasurement(template, report.ReportID())
+		if err := report.SubmitMeasurement(context.Background(), &measurement); err != nil {
+			t.Fatal(err)
+		}
+		if submissions != 3 || measurement.ReportID != "_id" {
+			t.Fatal("unexpected state", submissions, measurement.ReportID)
+		}
+	})
+
+	t.Run("not after a server error", func(t *testing.T) {
+		// the collector may have stored the measurement
+		fc := &flakyCollector{failures: 1, status: 500}
+		measurement, err := submitToFlakyCollector(t, fc)
+		var statusErr *httpx.StatusCodeError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != 500 {
+			t.Fatal("not the error we expected", err)
+		}
+		if fc.submissions != 1 || measurement.ReportID != "" {
+			t.Fatal("unexpected state", fc.submissions, measurement.ReportID)
+		}
+	})
+
+	t.Run("not after a client error", func(t *testing.T) {
+		fc := &flakyCollector{failures: 1, status: 400}
+		measurement, err := submitToFlakyCollector(t, fc)
+		if !errors.Is(err, httpx.ErrRequestFailed) {
+			t.Fatal("not the error we expected", err)
+		}
+		if fc.submissions != 1 || measurement.ReportID != "" {
+			t.Fatal("unexpected state", fc.submissions, measurement.ReportID)
+		}
+	})
+}
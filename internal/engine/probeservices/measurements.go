@@ -0,0 +1,127 @@
+package probeservices
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// defaultMeasurementListLimit is the default maximum number of
+// measurements returned by ListMeasurements.
+const defaultMeasurementListLimit = 1000
+
+// ErrEmptyMeasurementListConfig indicates that the config passed to
+// ListMeasurements does not select the measurements of a single
+// report or network.
+var ErrEmptyMeasurementListConfig = errors.New(
+	"probeservices: listing measurements requires a report ID or a country code and ASN")
+
+// MeasurementListConfig contains configuration for ListMeasurements.
+type MeasurementListConfig struct {
+	// ReportID is the OPTIONAL report ID.
+	ReportID string
+
+	// ProbeCC is the OPTIONAL country code. When the ReportID is
+	// empty, both ProbeCC and ProbeASN are required.
+	ProbeCC string
+
+	// ProbeASN is the OPTIONAL ASN (e.g., "AS30722").
+	ProbeASN string
+
+	// Since is the OPTIONAL minimum measurement start time.
+	Since time.Time
+
+	// Limit is the OPTIONAL maximum number of measurements. If
+	// zero or negative, we return at most 1000 measurements.
+	Limit int
+}
+
+// query returns the query string selecting the measurements.
+func (config *MeasurementListConfig) query() (url.Values, error) {
+	if config.ReportID == "" && (config.ProbeCC == "" || config.ProbeASN == "") {
+		return nil, ErrEmptyMeasurementListConfig
+	}
+	query := url.Values{}
+	if config.ReportID != "" {
+		query.Add("report_id", config.ReportID)
+	}
+	if config.ProbeCC != "" {
+		query.Add("probe_cc", config.ProbeCC)
+	}
+	if config.ProbeASN != "" {
+		query.Add("probe_asn", config.ProbeASN)
+	}
+	if !config.Since.IsZero() {
+		query.Add("since", config.Since.UTC().Format("2006-01-02T15:04:05"))
+	}
+	query.Add("limit", strconv.Itoa(config.limit()))
+	return query, nil
+}
+
+// limit returns the maximum number of measurements to list.
+func (config *MeasurementListConfig) limit() int {
+	if config.Limit <= 0 {
+		return defaultMeasurementListLimit
+	}
+	return config.Limit
+}
+
+// MeasurementListEntry contains the metadata of a measurement
+// returned by the /api/v1/measurements API.
+type MeasurementListEntry struct {
+	Anomaly              bool   `json:"anomaly"`
+	Confirmed            bool   `json:"confirmed"`
+	Failure              bool   `json:"failure"`
+	Input                string `json:"input"`
+	MeasurementStartTime string `json:"measurement_start_time"`
+	ProbeASN             string `json:"probe_asn"`
+	ProbeCC              string `json:"probe_cc"`
+	ReportID             string `json:"report_id"`
+	TestName             string `json:"test_name"`
+}
+
+// measurementListResult is the response of the /api/v1/measurements API.
+type measurementListResult struct {
+	Metadata struct {
+		NextURL string `json:"next_url"`
+	} `json:"metadata"`
+	Results []*MeasurementListEntry `json:"results"`
+}
+
+// ListMeasurements returns the metadata of the measurements selected
+// by the given config, following the pages returned by the API.
+func (c Client) ListMeasurements(
+	ctx context.Context, config MeasurementListConfig) ([]*MeasurementListEntry, error) {
+	query, err := config.query()
+	if err != nil {
+		return nil, err
+	}
+	limit := config.limit()
+	resourcePath := "/api/v1/measurements"
+	var out []*MeasurementListEntry
+	for len(out) < limit {
+		var response measurementListResult
+		err := c.APIClientTemplate.WithBodyLogging().Build().GetJSONWithQuery(
+			ctx, resourcePath, query, &response)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, response.Results...)
+		if response.Metadata.NextURL == "" {
+			break
+		}
+		// We only use the path and the query of the next page, such
+		// that we keep using the same backend and HTTP settings.
+		next, err := url.Parse(response.Metadata.NextURL)
+		if err != nil {
+			return nil, err
+		}
+		resourcePath, query = next.Path, next.Query()
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package probeservices_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+)
+
+func TestListMeasurements(t *testing.T) {
+	pages := [][]*probeservices.MeasurementListEntry{{{
+		ReportID: "20220510T120000Z_webconnectivity_IT_30722_n1_antani",
+		TestName: "web_connectivity",
+	}, {
+		ReportID: "20220510T120000Z_webconnectivity_IT_30722_n1_antani",
+		TestName: "web_connectivity",
+	}}, {{
+		ReportID: "20220510T130000Z_telegram_IT_30722_n1_antani",
+		TestName: "telegram",
+	}}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/measurements" || r.URL.Query().Get("probe_asn") != "AS30722" {
+			w.WriteHeader(400)
+			return
+		}
+		var response struct {
+			Metadata struct {
+				NextURL string `json:"next_url"`
+			} `json:"metadata"`
+			Results []*probeservices.MeasurementListEntry `json:"results"`
+		}
+		if r.URL.Query().Get("page") == "" {
+			response.Results = pages[0]
+			response.Metadata.NextURL = "https://api.ooni.io" + r.URL.Path + "?probe_asn=AS30722&page=2"
+		} else {
+			response.Results = pages[1]
+		}
+		json.NewEncoder(w).Encode(&response)
+	}))
+	defer server.Close()
+	client := newclient()
+	client.BaseURL = server.URL
+
+	t.Run("with an empty config", func(t *testing.T) {
+		_, err := client.ListMeasurements(context.Background(), probeservices.MeasurementListConfig{ProbeCC: "IT"})
+		if !errors.Is(err, probeservices.ErrEmptyMeasurementListConfig) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("following the pages", func(t *testing.T) {
+		config := probeservices.MeasurementListConfig{ProbeCC: "IT", ProbeASN: "AS30722"}
+		measurements, err := client.ListMeasurements(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(measurements) != 3 || measurements[2].TestName != "telegram" {
+			t.Fatal("unexpected measurements", measurements)
+		}
+	})
+
+	t.Run("with a limit", func(t *testing.T) {
+		config := probeservices.MeasurementListConfig{ProbeCC: "IT", ProbeASN: "AS30722", Limit: 1}
+		measurements, err := client.ListMeasurements(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(measurements) != 1 {
+			t.Fatal("unexpected number of measurements", len(measurements))
+		}
+	})
+
+	t.Run("with a server error", func(t *testing.T) {
+		config := probeservices.MeasurementListConfig{ProbeCC: "IT", ProbeASN: "AS12345"}
+		if _, err := client.ListMeasurements(context.Background(), config); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
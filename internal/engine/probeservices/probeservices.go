@@ -27,6 +27,7 @@ import (
 	"errors"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/atomicx"
 	"github.com/ooni/probe-cli/v3/internal/httpx"
@@ -69,6 +70,31 @@ type Client struct {
 	LoginCalls    *atomicx.Int64
 	RegisterCalls *atomicx.Int64
 	StateFile     StateFile
+
+	// CompressSubmissions OPTIONALLY enables compressing the submitted
+	// measurements, provided that the collector advertises support for
+	// a content encoding we support when we open a report.
+	CompressSubmissions bool
+
+	// UploadChunkSize is the OPTIONAL size of the chunks used when
+	// the collector supports resumable uploads. Measurements larger
+	// than a chunk are submitted in chunks. If zero or negative, we
+	// use DefaultUploadChunkSize.
+	UploadChunkSize int64
+
+	// SubmitRetries is the OPTIONAL number of times we retry submitting
+	// a measurement after a transient failure (e.g., a network error). If
+	// zero or negative, we use DefaultSubmitRetries.
+	SubmitRetries int
+
+	// SubmitRetryDelay is the OPTIONAL delay before the first retry, which
+	// we double after each retry. If zero or negative, we use
+	// DefaultSubmitRetryDelay.
+	SubmitRetryDelay time.Duration
+
+	// Clock is the OPTIONAL clock used to wait before retrying. If
+	// not set, we will use model.SystemClock.
+	Clock model.Clock
 }
 
 // GetCredsAndAuth is an utility function that returns the credentials with
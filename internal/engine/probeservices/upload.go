@@ -0,0 +1,214 @@
+package probeservices
+
+//
+// Resumable upload of large measurements.
+//
+// When the collector advertises support for resumable uploads, we
+// submit measurements larger than a single chunk using this protocol:
+//
+// 1. POST /report/{report_id}/upload with the size and the SHA256 of
+// the serialized measurement creates an upload and returns its ID;
+//
+// 2. GET /report/{report_id}/upload/{upload_id} returns the number
+// of bytes the collector has already received;
+//
+// 3. POST /report/{report_id}/upload/{upload_id}/chunk appends a chunk
+// at the given offset, along with the chunk's SHA256;
+//
+// 4. POST /report/{report_id}/upload/{upload_id}/commit assembles the
+// measurement and returns its ID and the SHA256 the collector computed.
+//
+// After each chunk, we save the upload progress in the key-value store,
+// so that a subsequent submission of the same measurement (e.g., after
+// a network failure or a restart) continues from where we stopped.
+//
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// DefaultUploadChunkSize is the default size of the chunks used when
+// performing resumable uploads. Measurements whose serialized size is
+// smaller than a chunk are submitted using a single request.
+const DefaultUploadChunkSize = 512 << 10
+
+// ErrUploadIntegrity indicates that the measurement assembled by
+// the collector differs from the one we have uploaded.
+var ErrUploadIntegrity = errors.New("probe services: upload integrity check failed")
+
+type collectorUploadCreateRequest struct {
+	Format string `json:"format"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type collectorUploadCreateResponse struct {
+	ID string `json:"upload_id"`
+}
+
+type collectorUploadStatusResponse struct {
+	Offset int64 `json:"offset"`
+}
+
+type collectorUploadChunkRequest struct {
+	Offset int64  `json:"offset"`
+	Data   []byte `json:"data"`
+	SHA256 string `json:"sha256"`
+}
+
+type collectorUploadCommitResponse struct {
+	ID     string `json:"measurement_id"`
+	SHA256 string `json:"sha256"`
+}
+
+// uploadState is the upload progress we save into the key-value store.
+type uploadState struct {
+	ReportID string
+	UploadID string
+	Offset   int64
+}
+
+// uploadChunkSize returns the chunk size to use for resumable uploads.
+func (c Client) uploadChunkSize() int64 {
+	if c.UploadChunkSize > 0 {
+		return c.UploadChunkSize
+	}
+	return DefaultUploadChunkSize
+}
+
+// uploadStore returns the key-value store where we save the
+// upload progress or nil if there is no key-value store.
+func (c Client) uploadStore() model.KeyValueStore {
+	if c.StateFile.Store == nil {
+		return nil
+	}
+	return kvstore.NewNamespace(c.StateFile.Store, "upload")
+}
+
+// loadUploadState returns the saved progress for the upload of the
+// measurement with the given digest, if any, and nil otherwise.
+func (r reportChan) loadUploadState(digest string) *uploadState {
+	store := r.client.uploadStore()
+	if store == nil {
+		return nil
+	}
+	data, err := store.Get(digest)
+	if err != nil {
+		return nil
+	}
+	var state uploadState
+	if err := json.Unmarshal(data, &state); err != nil || state.ReportID != r.ID {
+		return nil
+	}
+	return &state
+}
+
+// saveUploadState saves the progress for the upload of the
+// measurement with the given digest.
+func (r reportChan) saveUploadState(digest string, state *uploadState) {
+	store := r.client.uploadStore()
+	if store == nil {
+		return
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	_ = store.Set(digest, data) // saving progress is best effort
+}
+
+// clearUploadState removes the progress for the upload of the
+// measurement with the given digest.
+func (r reportChan) clearUploadState(digest string) {
+	if store := r.client.uploadStore(); store != nil {
+		_ = store.Delete(digest)
+	}
+}
+
+// resumeUpload returns the state of the upload of the measurement
+// with the given digest, resuming a previous upload if possible.
+func (r reportChan) resumeUpload(
+	ctx context.Context, digest string, size int64) (*uploadState, error) {
+	apiClient := r.apiClientTemplate().Build()
+	if state := r.loadUploadState(digest); state != nil {
+		var status collectorUploadStatusResponse
+		err := apiClient.GetJSON(ctx, fmt.Sprintf(
+			"/report/%s/upload/%s", r.ID, state.UploadID), &status)
+		if err == nil && status.Offset >= 0 && status.Offset <= size {
+			r.client.Logger.Debugf("resuming upload from byte %d of %d", status.Offset, size)
+			state.Offset = status.Offset
+			return state, nil
+		}
+		// The collector most likely forgot about the upload.
+	}
+	var created collectorUploadCreateResponse
+	err := apiClient.PostJSON(ctx, fmt.Sprintf("/report/%s/upload", r.ID),
+		collectorUploadCreateRequest{
+			Format: "json",
+			Size:   size,
+			SHA256: digest,
+		}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &uploadState{ReportID: r.ID, UploadID: created.ID}, nil
+}
+
+// submitResumable submits the serialized measurement using
+// the resumable upload protocol and returns its ID.
+func (r reportChan) submitResumable(ctx context.Context, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	state, err := r.resumeUpload(ctx, digest, int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	r.saveUploadState(digest, state)
+	apiClient := r.apiClientTemplate().Build()
+	chunkSize := r.client.uploadChunkSize()
+	for state.Offset < int64(len(data)) {
+		end := state.Offset + chunkSize
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		chunk := data[state.Offset:end]
+		chunkSum := sha256.Sum256(chunk)
+		var status collectorUploadStatusResponse
+		err := apiClient.PostJSON(ctx, fmt.Sprintf(
+			"/report/%s/upload/%s/chunk", r.ID, state.UploadID),
+			collectorUploadChunkRequest{
+				Offset: state.Offset,
+				Data:   chunk,
+				SHA256: hex.EncodeToString(chunkSum[:]),
+			}, &status)
+		if err != nil {
+			return "", err
+		}
+		if status.Offset <= state.Offset || status.Offset > int64(len(data)) {
+			return "", fmt.Errorf("%w: unexpected offset %d", ErrUploadIntegrity, status.Offset)
+		}
+		state.Offset = status.Offset
+		r.saveUploadState(digest, state)
+	}
+	var committed collectorUploadCommitResponse
+	err = apiClient.PostJSON(ctx, fmt.Sprintf(
+		"/report/%s/upload/%s/commit", r.ID, state.UploadID),
+		struct{}{}, &committed)
+	if err != nil {
+		return "", err
+	}
+	// Whatever the outcome, we need to start from scratch next time.
+	r.clearUploadState(digest)
+	if committed.SHA256 != digest {
+		return "", ErrUploadIntegrity
+	}
+	return committed.ID, nil
+}
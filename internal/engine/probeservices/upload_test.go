@@ -0,0 +1,336 @@
+package probeservices_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/ooni/probe-cli/v3/internal/httpx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// fakeResumableCollector is a collector supporting resumable uploads.
+type fakeResumableCollector struct {
+	// chunks counts the number of chunk requests.
+	chunks int
+
+	// commits counts the number of commit requests.
+	commits int
+
+	// data contains the data received so far.
+	data []byte
+
+	// failAtChunk is the OPTIONAL 1-based index of the
+	// chunk request that should fail.
+	failAtChunk int
+
+	// failCommits is the OPTIONAL number of commit requests that
+	// should fail after the collector has stored the measurement.
+	failCommits int
+
+	// failStatus is the OPTIONAL status code of the failing
+	// chunk request. If zero, we use 500.
+	failStatus int
+
+	// measurements counts the measurements stored by the collector,
+	// which only stores a measurement once for each upload.
+	measurements int
+
+	// singleRequests counts the non-resumable submissions.
+	singleRequests int
+
+	// uploads counts the number of uploads created.
+	uploads int
+
+	// wrongDigest causes the commit to return a wrong SHA256.
+	wrongDigest bool
+
+	mu sync.Mutex
+}
+
+func (fc *fakeResumableCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	switch {
+	case r.URL.Path == "/report":
+		w.Write([]byte(`{"report_id":"_id","supported_formats":["json"],"resumable_upload":true}`))
+	case r.URL.Path == "/report/_id":
+		fc.singleRequests++
+		w.Write([]byte(`{"measurement_id":"e00c584e6e9e5326"}`))
+	case r.URL.Path == "/report/_id/upload":
+		fc.uploads++
+		fc.data = nil
+		w.Write([]byte(`{"upload_id":"_upload"}`))
+	case r.URL.Path == "/report/_id/upload/_upload" && r.Method == "GET":
+		json.NewEncoder(w).Encode(map[string]int{"offset": len(fc.data)})
+	case r.URL.Path == "/report/_id/upload/_upload/chunk":
+		fc.chunks++
+		if fc.chunks == fc.failAtChunk {
+			status := fc.failStatus
+			if status == 0 {
+				status = 500
+			}
+			w.WriteHeader(status)
+			return
+		}
+		var req struct {
+			Offset int    `json:"offset"`
+			Data   []byte `json:"data"`
+			SHA256 string `json:"sha256"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			panic(err)
+		}
+		sum := sha256.Sum256(req.Data)
+		if req.Offset != len(fc.data) || hex.EncodeToString(sum[:]) != req.SHA256 {
+			w.WriteHeader(400)
+			return
+		}
+		fc.data = append(fc.data, req.Data...)
+		json.NewEncoder(w).Encode(map[string]int{"offset": len(fc.data)})
+	case r.URL.Path == "/report/_id/upload/_upload/commit":
+		fc.commits++
+		if fc.commits == 1 {
+			fc.measurements++
+		}
+		if fc.commits <= fc.failCommits {
+			w.WriteHeader(500)
+			return
+		}
+		sum := sha256.Sum256(fc.data)
+		digest := hex.EncodeToString(sum[:])
+		if fc.wrongDigest {
+			digest = strings.Repeat("0", len(digest))
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"measurement_id": "e00c584e6e9e5326",
+			"sha256":         digest,
+		})
+	default:
+		panic(r.URL.Path)
+	}
+}
+
+func newResumableClient(URL string) *probeservices.Client {
+	client := newclient()
+	client.BaseURL = URL
+	client.StateFile = probeservices.NewStateFile(&kvstore.Memory{})
+	client.UploadChunkSize = 128
+	client.SubmitRetryDelay = time.Millisecond
+	return client
+}
+
+func openResumableReport(t *testing.T, client *probeservices.Client) (
+	probeservices.ReportChannel, *model.Measurement) {
+	template := probeservices.ReportTemplate{
+		DataFormatVersion: probeservices.DefaultDataFormatVersion,
+		Format:            probeservices.DefaultFormat,
+		ProbeASN:          "AS0",
+		ProbeCC:           "ZZ",
+		SoftwareName:      "ooniprobe-engine",
+		SoftwareVersion:   "0.1.0",
+		TestName:          "dummy",
+		TestStartTime:     "2018-11-01 15:33:17",
+		TestVersion:       "0.1.0",
+	}
+	report, err := client.OpenReport(context.Background(), template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	measurement := makeMeasurement(template, report.ReportID())
+	return report, &measurement
+}
+
+func TestResumableUploadSuccess(t *testing.T) {
+	fc := &fakeResumableCollector{}
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	report, measurement := openResumableReport(t, newResumableClient(server.URL))
+	if err := report.SubmitMeasurement(context.Background(), measurement); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := json.Marshal(measurement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fc.data, expected) {
+		t.Fatal("the collector received unexpected data")
+	}
+	if fc.uploads != 1 || fc.chunks < 2 || fc.singleRequests != 0 {
+		t.Fatal("unexpected counters", fc.uploads, fc.chunks, fc.singleRequests)
+	}
+}
+
+func TestResumableUploadResumesAfterFailure(t *testing.T) {
+	fc := &fakeResumableCollector{failAtChunk: 2}
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	client := newResumableClient(server.URL)
+	report, measurement := openResumableReport(t, client)
+	if err := report.SubmitMeasurement(context.Background(), measurement); err != nil {
+		t.Fatal(err)
+	}
+	if fc.uploads != 1 || fc.measurements != 1 {
+		t.Fatal("expected to resume the previous upload", fc.uploads, fc.measurements)
+	}
+	keys, err := client.StateFile.Store.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatal("expected to have cleared the upload progress", keys)
+	}
+}
+
+func TestResumableUploadResumesAcrossSubmissions(t *testing.T) {
+	fc := &fakeResumableCollector{failAtChunk: 2, failStatus: 400}
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	client := newResumableClient(server.URL)
+	report, measurement := openResumableReport(t, client)
+	ctx := context.Background()
+	err := report.SubmitMeasurement(ctx, measurement)
+	if !errors.Is(err, httpx.ErrRequestFailed) {
+		t.Fatal("not the error we expected", err)
+	}
+	if measurement.ReportID != "" {
+		t.Fatal("expected empty report ID")
+	}
+	keys, err := client.StateFile.Store.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Fatal("expected to have saved the upload progress", keys)
+	}
+	if err := report.SubmitMeasurement(ctx, measurement); err != nil {
+		t.Fatal(err)
+	}
+	if fc.uploads != 1 {
+		t.Fatal("expected to resume the previous upload", fc.uploads)
+	}
+	keys, err = client.StateFile.Store.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Fatal("expected to have cleared the upload progress", keys)
+	}
+}
+
+func TestResumableUploadDoesNotDuplicateAfterCommitFailure(t *testing.T) {
+	fc := &fakeResumableCollector{failCommits: 1}
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	report, measurement := openResumableReport(t, newResumableClient(server.URL))
+	if err := report.SubmitMeasurement(context.Background(), measurement); err != nil {
+		t.Fatal(err)
+	}
+	if fc.uploads != 1 || fc.commits != 2 || fc.measurements != 1 {
+		t.Fatal("unexpected counters", fc.uploads, fc.commits, fc.measurements)
+	}
+}
+
+func TestResumableUploadIntegrityFailure(t *testing.T) {
+	fc := &fakeResumableCollector{wrongDigest: true}
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	report, measurement := openResumableReport(t, newResumableClient(server.URL))
+	err := report.SubmitMeasurement(context.Background(), measurement)
+	if !errors.Is(err, probeservices.ErrUploadIntegrity) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestResumableUploadSmallMeasurement(t *testing.T) {
+	fc := &fakeResumableCollector{}
+	server := httptest.NewServer(fc)
+	defer server.Close()
+	client := newResumableClient(server.URL)
+	client.UploadChunkSize = 1 << 20
+	report, measurement := openResumableReport(t, client)
+	if err := report.SubmitMeasurement(context.Background(), measurement); err != nil {
+		t.Fatal(err)
+	}
+	if fc.uploads != 0 || fc.singleRequests != 1 {
+		t.Fatal("unexpected counters", fc.uploads, fc.singleRequests)
+	}
+}
+
+// fakeCompressingCollector is a collector supporting gzip.
+type fakeCompressingCollector struct {
+	// encodings contains the content encoding of each submission.
+	encodings []string
+
+	// measurement is the last measurement we received.
+	measurement json.RawMessage
+}
+
+func (fc *fakeCompressingCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/report":
+		w.Write([]byte(`{"report_id":"_id","supported_formats":["json"],"supported_content_encodings":["gzip"]}`))
+	case "/report/_id":
+		fc.encodings = append(fc.encodings, r.Header.Get("Content-Encoding"))
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				panic(err)
+			}
+			reader = zr
+		}
+		var req struct {
+			Content json.RawMessage `json:"content"`
+		}
+		if err := json.NewDecoder(reader).Decode(&req); err != nil {
+			panic(err)
+		}
+		fc.measurement = req.Content
+		w.Write([]byte(`{"measurement_id":"e00c584e6e9e5326"}`))
+	default:
+		panic(r.URL.Path)
+	}
+}
+
+func TestSubmitCompressedMeasurement(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		fc := &fakeCompressingCollector{}
+		server := httptest.NewServer(fc)
+		client := newclient()
+		client.BaseURL = server.URL
+		client.CompressSubmissions = compress
+		report, measurement := openResumableReport(t, client)
+		if err := report.SubmitMeasurement(context.Background(), measurement); err != nil {
+			t.Fatal(err)
+		}
+		server.Close()
+		expected := ""
+		if compress {
+			expected = "gzip"
+		}
+		if len(fc.encodings) != 1 || fc.encodings[0] != expected {
+			t.Fatal("unexpected content encodings", fc.encodings)
+		}
+		var received model.Measurement
+		if err := json.Unmarshal(fc.measurement, &received); err != nil {
+			t.Fatal(err)
+		}
+		if received.ReportID != "_id" {
+			t.Fatal("the collector received an unexpected measurement")
+		}
+	}
+}
@@ -9,16 +9,20 @@ import (
 	"net/url"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/atomicx"
 	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	"github.com/ooni/probe-cli/v3/internal/engine/geolocate"
 	"github.com/ooni/probe-cli/v3/internal/engine/internal/sessionresolver"
 	"github.com/ooni/probe-cli/v3/internal/engine/netx"
+	"github.com/ooni/probe-cli/v3/internal/engine/netx/otlp"
 	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/ooni/probe-cli/v3/internal/httpx"
 	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/platform"
+	"github.com/ooni/probe-cli/v3/internal/scrubber"
 	"github.com/ooni/probe-cli/v3/internal/tunnel"
 	"github.com/ooni/probe-cli/v3/internal/version"
 )
@@ -35,6 +39,54 @@ type SessionConfig struct {
 	TorArgs                []string
 	TorBinary              string
 
+	// Collectors contains OPTIONAL alternative collectors (e.g., a
+	// self-hosted or onion collector). When opening a report, we try
+	// the probe services' collector first and then these collectors
+	// in order, unless ReplaceDefaultCollector is true, in which case
+	// we only use these collectors.
+	Collectors []model.OOAPIService
+
+	// ReplaceDefaultCollector indicates that we should only
+	// submit measurements to the configured Collectors.
+	ReplaceDefaultCollector bool
+
+	// Offline OPTIONALLY forbids accessing the probe services. In
+	// offline mode, we serve URL lists, tor targets, the psiphon config
+	// and the check-in response exclusively from the data we cached
+	// when we were last online, and we cannot submit measurements.
+	Offline bool
+
+	// CompressSubmissions OPTIONALLY enables compressing the measurements
+	// we submit to collectors advertising support for compression.
+	CompressSubmissions bool
+
+	// Limiter OPTIONALLY delays the requests we send to the probe
+	// services and to the collectors (see httpx.RateLimiter).
+	Limiter httpx.Limiter
+
+	// SigningSecret is the OPTIONAL secret shared with a self-hosted
+	// backend, which we use to sign requests to the probe services
+	// and to the collectors (see httpx.NewSigningHTTPClient).
+	SigningSecret string
+
+	// Scrubbing OPTIONALLY contains the rules for redacting private data
+	// from the test keys, in addition to the probe IP we always redact.
+	Scrubbing scrubber.Rules
+
+	// MemoryBudget OPTIONALLY bounds the memory used by the trace of
+	// each measurement. We use model.DefaultMemoryBudget for each
+	// field that is zero.
+	MemoryBudget model.MemoryBudget
+
+	// WatchdogInterval OPTIONALLY enables interrupting a measurement
+	// that neither sends nor receives bytes nor reports progress for
+	// longer than this interval. A zero value disables the watchdog.
+	WatchdogInterval time.Duration
+
+	// WatchdogClock is the OPTIONAL clock used by the watchdog. If
+	// nil, we use model.SystemClock.
+	WatchdogClock model.Clock
+
 	// TunnelDir is the directory where we should store
 	// the state of persistent tunnels. This field is
 	// optional _unless_ you want to use tunnels. In such
@@ -51,15 +103,25 @@ type Session struct {
 	availableProbeServices   []model.OOAPIService
 	availableTestHelpers     map[string][]model.OOAPIService
 	byteCounter              *bytecounter.Counter
+	collectors               []model.OOAPIService
+	compressSubmissions      bool
+	experimentByteCounters   map[string][]*bytecounter.Counter
 	httpDefaultTransport     model.HTTPTransport
 	kvStore                  model.KeyValueStore
+	limiter                  httpx.Limiter
 	location                 *geolocate.Results
 	logger                   model.Logger
+	memoryBudget             model.MemoryBudget
+	offline                  bool
 	proxyURL                 *url.URL
+	spanExporter             *otlp.Exporter
 	queryProbeServicesCount  *atomicx.Int64
+	replaceDefaultCollector  bool
 	resolver                 *sessionresolver.Resolver
+	scrubbing                scrubber.Rules
 	selectedProbeServiceHook func(*model.OOAPIService)
 	selectedProbeService     *model.OOAPIService
+	signingSecret            string
 	softwareName             string
 	softwareVersion          string
 	tempDir                  string
@@ -67,6 +129,13 @@ type Session struct {
 	// closeOnce allows us to call Close just once.
 	closeOnce sync.Once
 
+	// compactCancel stops the background goroutine compacting the
+	// kvstore, if any. It is set by NewSession and used by Close.
+	compactCancel context.CancelFunc
+
+	// compactWg allows Close to wait for the compacting goroutine.
+	compactWg sync.WaitGroup
+
 	// mu provides mutual exclusion.
 	mu sync.Mutex
 
@@ -101,6 +170,13 @@ type Session struct {
 	// tunnel is the optional tunnel that we may be using. It is created
 	// by NewSession and it is cleaned up by Close.
 	tunnel tunnel.Tunnel
+
+	// watchdogClock is the clock used by the watchdog.
+	watchdogClock model.Clock
+
+	// watchdogInterval is the interval after which the watchdog
+	// interrupts a measurement that makes no progress.
+	watchdogInterval time.Duration
 }
 
 // sessionProbeServicesClientForCheckIn returns the probe services
@@ -155,18 +231,31 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 	if err != nil {
 		return nil, err
 	}
+	if config.ReplaceDefaultCollector && len(config.Collectors) <= 0 {
+		return nil, errors.New("ReplaceDefaultCollector without Collectors")
+	}
 	sess := &Session{
 		availableProbeServices:  config.AvailableProbeServices,
 		byteCounter:             bytecounter.New(),
+		collectors:              config.Collectors,
+		compressSubmissions:     config.CompressSubmissions,
 		kvStore:                 config.KVStore,
+		limiter:                 config.Limiter,
 		logger:                  config.Logger,
+		memoryBudget:            newMemoryBudget(config.MemoryBudget),
+		offline:                 config.Offline,
 		queryProbeServicesCount: &atomicx.Int64{},
+		replaceDefaultCollector: config.ReplaceDefaultCollector,
+		scrubbing:               config.Scrubbing,
+		signingSecret:           config.SigningSecret,
 		softwareName:            config.SoftwareName,
 		softwareVersion:         config.SoftwareVersion,
 		tempDir:                 tempDir,
 		torArgs:                 config.TorArgs,
 		torBinary:               config.TorBinary,
 		tunnelDir:               config.TunnelDir,
+		watchdogClock:           config.WatchdogClock,
+		watchdogInterval:        config.WatchdogInterval,
 	}
 	proxyURL := config.ProxyURL
 	if proxyURL != nil {
@@ -205,9 +294,49 @@ func NewSession(ctx context.Context, config SessionConfig) (*Session, error) {
 	}
 	httpConfig.FullResolver = sess.resolver
 	sess.httpDefaultTransport = netx.NewHTTPTransport(httpConfig)
+	sess.spanExporter = otlp.NewExporterFromEnv(sess.logger)
+	sess.maybeStartCompacting()
 	return sess, nil
 }
 
+// sessionKVStoreCompacter is a model.KeyValueStore that we
+// can periodically compact (e.g., kvstore.CompactFS).
+type sessionKVStoreCompacter interface {
+	Compact() error
+}
+
+// sessionCompactInterval is the interval between two
+// consecutive compactions of the kvstore.
+const sessionCompactInterval = time.Hour
+
+// maybeStartCompacting starts a background goroutine that periodically
+// compacts the kvstore, if the kvstore supports compaction. We compact
+// once immediately, such that short-lived sessions also benefit.
+func (s *Session) maybeStartCompacting() {
+	compacter, ok := s.kvStore.(sessionKVStoreCompacter)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.compactCancel = cancel
+	s.compactWg.Add(1)
+	go func() {
+		defer s.compactWg.Done()
+		ticker := time.NewTicker(sessionCompactInterval)
+		defer ticker.Stop()
+		for {
+			if err := compacter.Compact(); err != nil {
+				s.logger.Warnf("cannot compact kvstore: %s", err.Error())
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
 // TunnelDir returns the persistent directory used by tunnels.
 func (s *Session) TunnelDir() string {
 	return s.tunnelDir
@@ -224,6 +353,61 @@ func (s *Session) KibiBytesSent() float64 {
 	return s.byteCounter.KibiBytesSent()
 }
 
+// DataUsage is the data used by a session or by an experiment.
+type DataUsage struct {
+	// KibiBytesReceived is the number of KiB received.
+	KibiBytesReceived float64
+
+	// KibiBytesSent is the number of KiB sent.
+	KibiBytesSent float64
+}
+
+// SessionDataUsage is the data used by a session.
+type SessionDataUsage struct {
+	// DataUsage accounts for all the traffic of the session, including
+	// the traffic of its experiments, the geolocation, the backend
+	// discovery, and the traffic with the probe services.
+	DataUsage
+
+	// Experiments maps the name of each experiment to the traffic of
+	// all the experiments with such a name created by this session.
+	Experiments map[string]DataUsage
+}
+
+// DataUsage returns the data used by this session so far. We measure
+// the data usage by counting the bytes read and written by each conn.
+func (s *Session) DataUsage() *SessionDataUsage {
+	out := &SessionDataUsage{
+		DataUsage: DataUsage{
+			KibiBytesReceived: s.byteCounter.KibiBytesReceived(),
+			KibiBytesSent:     s.byteCounter.KibiBytesSent(),
+		},
+		Experiments: make(map[string]DataUsage),
+	}
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	for name, counters := range s.experimentByteCounters {
+		var usage DataUsage
+		for _, counter := range counters {
+			usage.KibiBytesReceived += counter.KibiBytesReceived()
+			usage.KibiBytesSent += counter.KibiBytesSent()
+		}
+		out.Experiments[name] = usage
+	}
+	return out
+}
+
+// registerExperimentByteCounter registers the byte counter of an
+// experiment such that DataUsage accounts for its data usage.
+func (s *Session) registerExperimentByteCounter(name string, counter *bytecounter.Counter) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	if s.experimentByteCounters == nil {
+		s.experimentByteCounters = make(map[string][]*bytecounter.Counter)
+	}
+	s.experimentByteCounters[name] = append(s.experimentByteCounters[name], counter)
+}
+
 // CheckIn calls the check-in API. The input arguments MUST NOT
 // be nil. Before querying the API, this function will ensure
 // that the config structure does not contain any field that
@@ -256,10 +440,6 @@ func (s *Session) CheckIn(
 	if err := s.maybeLookupLocationContext(ctx); err != nil {
 		return nil, err
 	}
-	client, err := s.newProbeServicesClientForCheckIn(ctx)
-	if err != nil {
-		return nil, err
-	}
 	if config.Platform == "" {
 		config.Platform = s.Platform()
 	}
@@ -281,7 +461,22 @@ func (s *Session) CheckIn(
 	if config.WebConnectivity.CategoryCodes == nil {
 		config.WebConnectivity.CategoryCodes = []string{}
 	}
-	return client.CheckIn(ctx, *config)
+	key, err := offlineCacheKey("checkin", config)
+	if err != nil {
+		return nil, err
+	}
+	var out model.OOAPICheckInInfo
+	err = s.withOfflineCache(key, &out, func() (interface{}, error) {
+		client, err := s.newProbeServicesClientForCheckIn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return client.CheckIn(ctx, *config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
 }
 
 // maybeLookupLocationContext is a wrapper for MaybeLookupLocationContext that calls
@@ -321,8 +516,15 @@ func (s *Session) Close() error {
 
 // doClose implements Close. This function is called just once.
 func (s *Session) doClose() {
+	if s.compactCancel != nil {
+		s.compactCancel()
+		s.compactWg.Wait()
+	}
 	s.httpDefaultTransport.CloseIdleConnections()
 	s.resolver.CloseIdleConnections()
+	if s.spanExporter != nil {
+		s.spanExporter.HTTPClient.CloseIdleConnections()
+	}
 	s.logger.Infof("%s", s.resolver.Stats())
 	if s.tunnel != nil {
 		s.tunnel.Stop()
@@ -344,24 +546,53 @@ func (s *Session) DefaultHTTPClient() *http.Client {
 	return &http.Client{Transport: s.httpDefaultTransport}
 }
 
+// SpanExporter returns the exporter that the experiments should use to
+// export OpenTelemetry spans, or nil if the user has not configured an
+// OTLP collector (see the otlp package).
+func (s *Session) SpanExporter() *otlp.Exporter {
+	return s.spanExporter
+}
+
 // FetchTorTargets fetches tor targets from the API.
 func (s *Session) FetchTorTargets(
 	ctx context.Context, cc string) (map[string]model.OOAPITorTarget, error) {
-	clnt, err := s.NewOrchestraClient(ctx)
+	key, err := offlineCacheKey("tortargets", cc)
 	if err != nil {
 		return nil, err
 	}
-	return clnt.FetchTorTargets(ctx, cc)
+	var out map[string]model.OOAPITorTarget
+	err = s.withOfflineCache(key, &out, func() (interface{}, error) {
+		clnt, err := s.NewOrchestraClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return clnt.FetchTorTargets(ctx, cc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 // FetchURLList fetches the URL list from the API.
 func (s *Session) FetchURLList(
 	ctx context.Context, config model.OOAPIURLListConfig) ([]model.OOAPIURLInfo, error) {
-	clnt, err := s.NewOrchestraClient(ctx)
+	key, err := offlineCacheKey("urllist", config)
+	if err != nil {
+		return nil, err
+	}
+	var out []model.OOAPIURLInfo
+	err = s.withOfflineCache(key, &out, func() (interface{}, error) {
+		clnt, err := s.NewOrchestraClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return clnt.FetchURLList(ctx, config)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return clnt.FetchURLList(ctx, config)
+	return out, nil
 }
 
 // KeyValueStore returns the configured key-value store.
@@ -374,6 +605,23 @@ func (s *Session) Logger() model.Logger {
 	return s.logger
 }
 
+// newMemoryBudget fills the zero fields of budget using the default budget.
+func newMemoryBudget(budget model.MemoryBudget) model.MemoryBudget {
+	if budget.MaxTraceEvents <= 0 {
+		budget.MaxTraceEvents = model.DefaultMemoryBudget.MaxTraceEvents
+	}
+	if budget.MaxTraceDataBytes <= 0 {
+		budget.MaxTraceDataBytes = model.DefaultMemoryBudget.MaxTraceDataBytes
+	}
+	return budget
+}
+
+// MemoryBudget returns the budget bounding the memory used by the
+// trace of each measurement.
+func (s *Session) MemoryBudget() model.MemoryBudget {
+	return s.memoryBudget
+}
+
 // MaybeLookupLocation is a caching location lookup call.
 func (s *Session) MaybeLookupLocation() error {
 	return s.MaybeLookupLocationContext(context.Background())
@@ -406,6 +654,9 @@ func (s *Session) NewProbeServicesClient(ctx context.Context) (*probeservices.Cl
 	if ctx.Err() != nil {
 		return nil, ctx.Err() // helps with testing
 	}
+	if s.offline {
+		return nil, ErrOffline
+	}
 	if err := s.maybeLookupBackendsContext(ctx); err != nil {
 		return nil, err
 	}
@@ -415,16 +666,108 @@ func (s *Session) NewProbeServicesClient(ctx context.Context) (*probeservices.Cl
 	if s.selectedProbeServiceHook != nil {
 		s.selectedProbeServiceHook(s.selectedProbeService)
 	}
-	return probeservices.NewClient(s, *s.selectedProbeService)
+	return s.newProbeServicesClientForEndpoint(*s.selectedProbeService)
+}
+
+// newProbeServicesClientForEndpoint creates a client for the given
+// probe services endpoint honouring the session's HTTP settings.
+func (s *Session) newProbeServicesClientForEndpoint(
+	endpoint model.OOAPIService) (*probeservices.Client, error) {
+	client, err := probeservices.NewClient(s, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	client.SigningSecret = s.signingSecret
+	client.CompressSubmissions = s.compressSubmissions
+	client.Limiter = s.limiter
+	return client, nil
 }
 
 // NewSubmitter creates a new submitter instance.
 func (s *Session) NewSubmitter(ctx context.Context) (Submitter, error) {
-	psc, err := s.NewProbeServicesClient(ctx)
+	clients, err := s.newCollectorClients(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return probeservices.NewSubmitter(psc, s.Logger()), nil
+	opener := &sessionReportOpener{clients: clients, logger: s.logger}
+	return probeservices.NewSubmitter(opener, s.Logger()), nil
+}
+
+// sessionCollectorClient is a client for a specific collector.
+type sessionCollectorClient struct {
+	// address is the collector's address.
+	address string
+
+	// client is the probe services client for the collector.
+	client *probeservices.Client
+}
+
+// newCollectorClients returns the clients for the collectors we
+// should use, in order of preference (see SessionConfig.Collectors).
+func (s *Session) newCollectorClients(ctx context.Context) ([]*sessionCollectorClient, error) {
+	if s.offline {
+		return nil, ErrOffline
+	}
+	var out []*sessionCollectorClient
+	if s.replaceDefaultCollector {
+		// We still need the location to fill the report template.
+		if err := s.maybeLookupLocationContext(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		client, err := s.NewProbeServicesClient(ctx)
+		if err != nil && len(s.collectors) <= 0 {
+			return nil, err
+		}
+		if err != nil {
+			s.logger.Warnf("cannot use the default collector: %s", err.Error())
+		} else {
+			out = append(out, &sessionCollectorClient{
+				address: s.selectedProbeService.Address,
+				client:  client,
+			})
+		}
+	}
+	for _, svc := range s.collectors {
+		client, err := s.newProbeServicesClientForEndpoint(svc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &sessionCollectorClient{address: svc.Address, client: client})
+	}
+	return out, nil
+}
+
+// sessionReportOpener is a probeservices.ReportOpener that opens
+// the report with the first collector accepting it.
+type sessionReportOpener struct {
+	clients []*sessionCollectorClient
+	logger  model.Logger
+}
+
+var _ probeservices.ReportOpener = &sessionReportOpener{}
+
+// OpenReport implements probeservices.ReportOpener.
+func (o *sessionReportOpener) OpenReport(
+	ctx context.Context, rt probeservices.ReportTemplate) (probeservices.ReportChannel, error) {
+	report, _, err := o.openReport(ctx, rt)
+	return report, err
+}
+
+// openReport is like OpenReport but also returns the address
+// of the collector that accepted the report.
+func (o *sessionReportOpener) openReport(ctx context.Context,
+	rt probeservices.ReportTemplate) (probeservices.ReportChannel, string, error) {
+	err := errors.New("no available collectors")
+	for _, cc := range o.clients {
+		var report probeservices.ReportChannel
+		report, err = cc.client.OpenReport(ctx, rt)
+		if err == nil {
+			return report, cc.address, nil
+		}
+		o.logger.Debugf("collector %s rejected the report: %s", cc.address, err.Error())
+	}
+	return nil, "", err
 }
 
 // NewOrchestraClient creates a new orchestra client. This client is registered
@@ -505,6 +848,24 @@ func (s *Session) ProbeIP() string {
 	return ip
 }
 
+// ProbeIPLookups returns the outcome of the lookups of the probe IP.
+func (s *Session) ProbeIPLookups() []geolocate.IPLookup {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	if s.location == nil {
+		return nil
+	}
+	return s.location.IPLookups
+}
+
+// ProbeIPLookupsDisagree returns whether the services we used to
+// look up the probe IP returned different IPs.
+func (s *Session) ProbeIPLookupsDisagree() bool {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	return s.location != nil && s.location.IPLookupsDisagree()
+}
+
 // ProxyURL returns the Proxy URL, or nil if not set
 func (s *Session) ProxyURL() *url.URL {
 	return s.proxyURL
@@ -639,6 +1000,12 @@ func (s *Session) MaybeLookupBackendsContext(ctx context.Context) error {
 	if s.selectedProbeService != nil {
 		return nil
 	}
+	if s.offline {
+		if s.availableTestHelpers == nil {
+			s.loadOfflineBackendsUnlocked()
+		}
+		return nil
+	}
 	s.queryProbeServicesCount.Add(1)
 	candidates := probeservices.TryAll(ctx, s, s.getAvailableProbeServicesUnlocked())
 	selected := probeservices.SelectBest(candidates)
@@ -648,18 +1015,38 @@ func (s *Session) MaybeLookupBackendsContext(ctx context.Context) error {
 	s.logger.Infof("session: using probe services: %+v", selected.Endpoint)
 	s.selectedProbeService = &selected.Endpoint
 	s.availableTestHelpers = selected.TestHelpers
+	s.saveOfflineBackendsUnlocked()
 	return nil
 }
 
 // LookupLocationContext performs a location lookup. If you want memoisation
 // of the results, you should use MaybeLookupLocationContext.
 func (s *Session) LookupLocationContext(ctx context.Context) (*geolocate.Results, error) {
-	task := geolocate.NewTask(geolocate.Config{
-		Logger:    s.Logger(),
-		Resolver:  s.resolver,
-		UserAgent: s.UserAgent(),
+	return s.newLocationTask(false).Run(ctx)
+}
+
+// CrossCheckLocation performs a new location lookup where we look up the
+// probe IP using two services, to notice whether they disagree, and saves
+// its results, replacing the ones of any previous lookup.
+func (s *Session) CrossCheckLocation() error {
+	location, err := s.newLocationTask(true).Run(context.Background())
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+	s.mu.Lock()
+	s.location = location
+	return nil
+}
+
+// newLocationTask creates a new geolocate task.
+func (s *Session) newLocationTask(crossCheck bool) *geolocate.Task {
+	return geolocate.NewTask(geolocate.Config{
+		Logger:     s.Logger(),
+		Resolver:   s.resolver,
+		UserAgent:  s.UserAgent(),
+		CrossCheck: crossCheck,
 	})
-	return task.Run(ctx)
 }
 
 // lookupLocationContext calls testLookupLocationContext if set and
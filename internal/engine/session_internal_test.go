@@ -3,13 +3,21 @@ package engine
 import (
 	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/apex/log"
 	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/atomicx"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
 	"github.com/ooni/probe-cli/v3/internal/engine/geolocate"
+	"github.com/ooni/probe-cli/v3/internal/engine/probeservices"
+	"github.com/ooni/probe-cli/v3/internal/httpx"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -291,3 +299,274 @@ func TestNewSessionWithFakeTunnelAndCancelledContext(t *testing.T) {
 		t.Fatal("expected nil session here")
 	}
 }
+
+// compactableKVStore is a kvstore that counts compactions.
+type compactableKVStore struct {
+	kvstore.Memory
+	count *atomicx.Int64
+}
+
+func (kvs *compactableKVStore) Compact() error {
+	kvs.count.Add(1)
+	return nil
+}
+
+func TestNewSessionCompactsTheKVStore(t *testing.T) {
+	kvs := &compactableKVStore{count: &atomicx.Int64{}}
+	sess, err := NewSession(context.Background(), SessionConfig{
+		KVStore:         kvs,
+		Logger:          log.Log,
+		SoftwareName:    "miniooni",
+		SoftwareVersion: "0.1.0-dev",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.Close() // waits for the compacting goroutine to terminate
+	if kvs.count.Load() != 1 {
+		t.Fatal("unexpected number of compactions", kvs.count.Load())
+	}
+}
+
+func TestNewSessionReplaceDefaultCollectorWithoutCollectors(t *testing.T) {
+	sess, err := NewSession(context.Background(), SessionConfig{
+		Logger:                  log.Log,
+		ReplaceDefaultCollector: true,
+		SoftwareName:            "miniooni",
+		SoftwareVersion:         "0.1.0-dev",
+	})
+	if err == nil {
+		t.Fatal("expected an error here")
+	}
+	if sess != nil {
+		t.Fatal("expected nil session here")
+	}
+}
+
+func TestSessionReportOpenerFallsBack(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"report_id":"_id","supported_formats":["json"]}`))
+	}))
+	defer working.Close()
+	newclient := func(URL string) *sessionCollectorClient {
+		return &sessionCollectorClient{
+			address: URL,
+			client: &probeservices.Client{
+				APIClientTemplate: httpx.APIClientTemplate{
+					BaseURL:    URL,
+					HTTPClient: http.DefaultClient,
+					Logger:     log.Log,
+				},
+			},
+		}
+	}
+	opener := &sessionReportOpener{
+		clients: []*sessionCollectorClient{newclient(failing.URL), newclient(working.URL)},
+		logger:  log.Log,
+	}
+	report, address, err := opener.openReport(context.Background(), probeservices.ReportTemplate{
+		DataFormatVersion: probeservices.DefaultDataFormatVersion,
+		Format:            probeservices.DefaultFormat,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ReportID() != "_id" {
+		t.Fatal("unexpected report ID", report.ReportID())
+	}
+	if address != working.URL {
+		t.Fatal("unexpected collector", address)
+	}
+}
+
+func TestSessionReportOpenerAllFailing(t *testing.T) {
+	opener := &sessionReportOpener{logger: log.Log}
+	report, err := opener.OpenReport(context.Background(), probeservices.ReportTemplate{})
+	if err == nil {
+		t.Fatal("expected an error here")
+	}
+	if report != nil {
+		t.Fatal("expected nil report here")
+	}
+}
+
+func TestSessionOfflineCheckInUsesCachedResults(t *testing.T) {
+	results := &model.OOAPICheckInInfo{
+		WebConnectivity: &model.OOAPICheckInInfoWebConnectivity{
+			ReportID: "xxx-x-xx",
+			URLs: []model.OOAPIURLInfo{{
+				CategoryCode: "NEWS",
+				CountryCode:  "IT",
+				URL:          "https://www.repubblica.it/",
+			}},
+		},
+	}
+	mockedClnt := &mockableProbeServicesClientForCheckIn{
+		Results: results,
+	}
+	s := &Session{
+		kvStore: &kvstore.Memory{},
+		location: &geolocate.Results{
+			ASN:         137,
+			CountryCode: "IT",
+		},
+		softwareName:    "miniooni",
+		softwareVersion: "0.1.0-dev",
+		testMaybeLookupLocationContext: func(ctx context.Context) error {
+			return nil
+		},
+		testNewProbeServicesClientForCheckIn: func(
+			ctx context.Context) (sessionProbeServicesClientForCheckIn, error) {
+			return mockedClnt, nil
+		},
+	}
+	ctx := context.Background()
+	if _, err := s.CheckIn(ctx, &model.OOAPICheckInConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	s.offline = true // the mocked client fails if called again
+	out, err := s.CheckIn(ctx, &model.OOAPICheckInConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(results, out); diff != "" {
+		t.Fatal(diff)
+	}
+	out, err = s.CheckIn(ctx, &model.OOAPICheckInConfig{ProbeCC: "DE"})
+	if !errors.Is(err, ErrOfflineNoCache) {
+		t.Fatal("not the error we expected", err)
+	}
+	if out != nil {
+		t.Fatal("expected nil response here")
+	}
+}
+
+func TestSessionOfflineFetchURLListWithoutCache(t *testing.T) {
+	sess := &Session{offline: true}
+	resp, err := sess.FetchURLList(context.Background(), model.OOAPIURLListConfig{})
+	if !errors.Is(err, ErrOfflineNoCache) {
+		t.Fatal("not the error we expected", err)
+	}
+	if resp != nil {
+		t.Fatal("expected nil response here")
+	}
+}
+
+func TestSessionOfflineNewProbeServicesClient(t *testing.T) {
+	sess := &Session{offline: true}
+	clnt, err := sess.NewProbeServicesClient(context.Background())
+	if !errors.Is(err, ErrOffline) {
+		t.Fatal("not the error we expected", err)
+	}
+	if clnt != nil {
+		t.Fatal("expected nil client here")
+	}
+}
+
+func TestSessionOfflineBackendsRoundTrip(t *testing.T) {
+	kvs := &kvstore.Memory{}
+	helpers := map[string][]model.OOAPIService{
+		"web-connectivity": {{
+			Address: "https://0.th.ooni.org",
+			Type:    "https",
+		}},
+	}
+	online := &Session{kvStore: kvs, logger: log.Log, availableTestHelpers: helpers}
+	online.saveOfflineBackendsUnlocked()
+	offline := &Session{kvStore: kvs, logger: log.Log, offline: true}
+	if err := offline.MaybeLookupBackendsContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(helpers, offline.availableTestHelpers); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestSessionSignsCollectorRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := httpx.VerifyRequestSignature(r, "antani", time.Minute); err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{"report_id":"_id","supported_formats":["json"]}`))
+	}))
+	defer server.Close()
+	s := &Session{
+		collectors: []model.OOAPIService{{
+			Address: server.URL,
+			Type:    "https",
+		}},
+		logger:                  log.Log,
+		replaceDefaultCollector: true,
+		signingSecret:           "antani",
+		testMaybeLookupLocationContext: func(ctx context.Context) error {
+			return nil
+		},
+	}
+	ctx := context.Background()
+	clients, err := s.newCollectorClients(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opener := &sessionReportOpener{clients: clients, logger: log.Log}
+	report, err := opener.OpenReport(ctx, probeservices.ReportTemplate{
+		DataFormatVersion: probeservices.DefaultDataFormatVersion,
+		Format:            probeservices.DefaultFormat,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ReportID() != "_id" {
+		t.Fatal("unexpected report ID", report.ReportID())
+	}
+}
+
+func TestNewMemoryBudget(t *testing.T) {
+	t.Run("with the zero budget", func(t *testing.T) {
+		budget := newMemoryBudget(model.MemoryBudget{})
+		if budget != model.DefaultMemoryBudget {
+			t.Fatal("unexpected budget", budget)
+		}
+	})
+
+	t.Run("with a custom budget", func(t *testing.T) {
+		budget := newMemoryBudget(model.MemoryBudget{MaxTraceEvents: 10})
+		expected := model.MemoryBudget{
+			MaxTraceEvents:    10,
+			MaxTraceDataBytes: model.DefaultMemoryBudget.MaxTraceDataBytes,
+		}
+		if budget != expected {
+			t.Fatal("unexpected budget", budget)
+		}
+	})
+}
+
+func TestSessionDataUsage(t *testing.T) {
+	sess := &Session{byteCounter: bytecounter.New()}
+	sess.byteCounter.CountKibiBytesReceived(100)
+	sess.byteCounter.CountKibiBytesSent(10)
+	first, second, other := bytecounter.New(), bytecounter.New(), bytecounter.New()
+	first.CountKibiBytesReceived(20)
+	first.CountKibiBytesSent(2)
+	second.CountKibiBytesReceived(30)
+	second.CountKibiBytesSent(3)
+	other.CountKibiBytesReceived(40)
+	sess.registerExperimentByteCounter("web_connectivity", first)
+	sess.registerExperimentByteCounter("web_connectivity", second)
+	sess.registerExperimentByteCounter("ndt", other)
+	usage := sess.DataUsage()
+	expected := &SessionDataUsage{
+		DataUsage: DataUsage{KibiBytesReceived: 100, KibiBytesSent: 10},
+		Experiments: map[string]DataUsage{
+			"web_connectivity": {KibiBytesReceived: 50, KibiBytesSent: 5},
+			"ndt":              {KibiBytesReceived: 40},
+		},
+	}
+	if diff := cmp.Diff(expected, usage); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -10,11 +10,18 @@ import (
 
 // FetchPsiphonConfig fetches psiphon config from the API.
 func (s *Session) FetchPsiphonConfig(ctx context.Context) ([]byte, error) {
-	clnt, err := s.NewOrchestraClient(ctx)
+	var out []byte
+	err := s.withOfflineCache("psiphonconfig", &out, func() (interface{}, error) {
+		clnt, err := s.NewOrchestraClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return clnt.FetchPsiphonConfig(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return clnt.FetchPsiphonConfig(ctx)
+	return out, nil
 }
 
 // sessionTunnelEarlySession is the early session that we pass
@@ -0,0 +1,126 @@
+package engine
+
+//
+// Offline mode: the session does not access the probe services and
+// serves backend data exclusively from the cache we populate while
+// we are online.
+//
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+var (
+	// ErrOffline indicates that we cannot access the probe
+	// services because the session is in offline mode.
+	ErrOffline = errors.New("session: cannot access probe services in offline mode")
+
+	// ErrOfflineNoCache indicates that the session is in offline
+	// mode and we do not have cached data for a given request.
+	ErrOfflineNoCache = errors.New("session: offline mode and no cached data")
+)
+
+// sessionOfflineNamespace is the kvstore namespace containing
+// the backend data we serve when we're in offline mode.
+const sessionOfflineNamespace = "offline"
+
+// Offline returns whether the session is in offline mode.
+func (s *Session) Offline() bool {
+	return s.offline
+}
+
+// offlineCache returns the kvstore containing the offline data.
+func (s *Session) offlineCache() model.KeyValueStore {
+	kvs := s.kvStore
+	if kvs == nil {
+		kvs = &kvstore.Memory{} // happens when testing
+	}
+	return kvstore.NewNamespace(kvs, sessionOfflineNamespace)
+}
+
+// offlineCacheKey returns the cache key for the given kind of
+// data and request, which must be JSON serializable.
+func offlineCacheKey(kind string, request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return kind + "-" + hex.EncodeToString(sum[:]), nil
+}
+
+// withOfflineCache unmarshals into output the cached value for the given
+// key when we're in offline mode. Otherwise, it calls fetch to obtain a
+// fresh value, caches it, and unmarshals it into output.
+func (s *Session) withOfflineCache(
+	key string, output interface{}, fetch func() (interface{}, error)) error {
+	cache := s.offlineCache()
+	if s.offline {
+		data, err := cache.Get(key)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrOfflineNoCache, key)
+		}
+		return json.Unmarshal(data, output)
+	}
+	value, err := fetch()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := cache.Set(key, data); err != nil {
+		// Failing to cache is not fatal, we're online after all.
+		s.logger.Warnf("session: cannot cache %s: %s", key, err.Error())
+	}
+	return json.Unmarshal(data, output)
+}
+
+// sessionOfflineBackends is the cached result of MaybeLookupBackends.
+type sessionOfflineBackends struct {
+	TestHelpers map[string][]model.OOAPIService
+}
+
+// sessionOfflineBackendsKey is the key for sessionOfflineBackends.
+const sessionOfflineBackendsKey = "backends"
+
+// loadOfflineBackendsUnlocked loads the test helpers we discovered the last time
+// we looked up the backends. This function assumes that the caller is
+// holding the mutex. Not having cached test helpers is not an error: the
+// experiments that need them will fail gracefully.
+func (s *Session) loadOfflineBackendsUnlocked() {
+	cache := s.offlineCache()
+	data, err := cache.Get(sessionOfflineBackendsKey)
+	if err != nil {
+		s.logger.Warnf("session: offline mode and no cached test helpers")
+		return
+	}
+	var backends sessionOfflineBackends
+	if err := json.Unmarshal(data, &backends); err != nil {
+		s.logger.Warnf("session: cannot parse cached test helpers: %s", err.Error())
+		return
+	}
+	s.availableTestHelpers = backends.TestHelpers
+}
+
+// saveOfflineBackendsUnlocked saves the test helpers we have discovered
+// such that we can use them in offline mode. This function assumes that
+// the caller is holding the mutex.
+func (s *Session) saveOfflineBackendsUnlocked() {
+	data, err := json.Marshal(&sessionOfflineBackends{TestHelpers: s.availableTestHelpers})
+	if err != nil {
+		return
+	}
+	cache := s.offlineCache()
+	if err := cache.Set(sessionOfflineBackendsKey, data); err != nil {
+		s.logger.Warnf("session: cannot cache test helpers: %s", err.Error())
+	}
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/atomicx"
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// watchdogAnnotation is the annotation we add to the measurements that
+// the watchdog interrupted. Its value is the watchdog interval.
+const watchdogAnnotation = "watchdog_timeout"
+
+// watchdogChecksPerInterval is the number of times per interval
+// at which the watchdog checks whether there has been progress.
+const watchdogChecksPerInterval = 4
+
+// watchdog cancels a measurement that makes no progress for longer than
+// its interval. We have progress when the measurement sends or receives
+// bytes, reports progress, or emits test keys. We use a byte counter for
+// each measurement, such that a stuck input is not hidden by the bytes
+// that other inputs of the same experiment are exchanging.
+//
+// The watchdog wraps the experiment callbacks to see progress reports.
+type watchdog struct {
+	callbacks model.ExperimentCallbacks
+	clock     model.Clock
+	counter   *bytecounter.Counter
+	fired     *atomicx.Int64
+	interval  time.Duration
+	logger    model.Logger
+	progress  *atomicx.Int64
+	stopch    chan struct{}
+	stoponce  sync.Once
+}
+
+var _ model.ExperimentCallbacks = &watchdog{}
+
+// newWatchdog creates a new watchdog for a single measurement whose
+// bytes we count using counter. A zero or negative interval creates a
+// watchdog that never fires. A nil clock means model.SystemClock.
+func newWatchdog(interval time.Duration, clock model.Clock, counter *bytecounter.Counter,
+	callbacks model.ExperimentCallbacks, logger model.Logger) *watchdog {
+	return &watchdog{
+		callbacks: callbacks,
+		clock:     model.ClockOrSystemClock(clock),
+		counter:   counter,
+		fired:     &atomicx.Int64{},
+		interval:  interval,
+		logger:    logger,
+		progress:  &atomicx.Int64{},
+		stopch:    make(chan struct{}),
+	}
+}
+
+// watchdogState is a snapshot of the measurement progress.
+type watchdogState struct {
+	progress int64
+	received int64
+	sent     int64
+}
+
+// state returns the current watchdogState.
+func (w *watchdog) state() watchdogState {
+	return watchdogState{
+		progress: w.progress.Load(),
+		received: w.counter.BytesReceived(),
+		sent:     w.counter.BytesSent(),
+	}
+}
+
+// start starts the watchdog in a background goroutine, which
+// calls cancel when the watchdog fires. You MUST call stop when
+// the measurement is done to release the goroutine.
+func (w *watchdog) start(cancel context.CancelFunc) {
+	if w.interval <= 0 {
+		return
+	}
+	go w.loop(cancel)
+}
+
+// loop is the watchdog's main loop.
+func (w *watchdog) loop(cancel context.CancelFunc) {
+	period := w.interval / watchdogChecksPerInterval
+	timer := w.clock.NewTimer(period)
+	defer timer.Stop()
+	last, lastChange := w.state(), w.clock.Now()
+	for {
+		select {
+		case <-w.stopch:
+			return
+		case <-timer.C():
+			timer.Reset(period)
+			now := w.clock.Now()
+			if current := w.state(); current != last {
+				last, lastChange = current, now
+				continue
+			}
+			if now.Sub(lastChange) < w.interval {
+				continue
+			}
+			w.logger.Warnf("watchdog: no progress for %s; interrupting the measurement", w.interval)
+			w.fired.Add(1)
+			cancel()
+			return
+		}
+	}
+}
+
+// stop stops the watchdog. This method is idempotent.
+func (w *watchdog) stop() {
+	w.stoponce.Do(func() { close(w.stopch) })
+}
+
+// touch signals that the measurement made progress.
+func (w *watchdog) touch() {
+	w.progress.Add(1)
+}
+
+// hasFired returns whether the watchdog has fired.
+func (w *watchdog) hasFired() bool {
+	return w.fired.Load() > 0
+}
+
+// OnProgress implements model.ExperimentCallbacks.OnProgress.
+func (w *watchdog) OnProgress(percentage float64, message string) {
+	w.touch()
+	w.callbacks.OnProgress(percentage, message)
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/bytecounter"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestWatchdog(t *testing.T) {
+	newWatchdogForTesting := func(interval time.Duration) (*watchdog, *bytecounter.Counter) {
+		counter := bytecounter.New()
+		callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
+		return newWatchdog(interval, nil, counter, callbacks, model.DiscardLogger), counter
+	}
+
+	t.Run("with zero interval it never fires", func(t *testing.T) {
+		wd, _ := newWatchdogForTesting(0)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		wd.start(cancel)
+		time.Sleep(50 * time.Millisecond)
+		wd.stop()
+		if wd.hasFired() || ctx.Err() != nil {
+			t.Fatal("the watchdog should not have fired")
+		}
+	})
+
+	t.Run("it fires without progress", func(t *testing.T) {
+		wd, _ := newWatchdogForTesting(40 * time.Millisecond)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		wd.start(cancel)
+		defer wd.stop()
+		<-ctx.Done()
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			t.Fatal("unexpected error", ctx.Err())
+		}
+		if !wd.hasFired() {
+			t.Fatal("the watchdog should have fired")
+		}
+	})
+
+	t.Run("it does not fire while we make progress", func(t *testing.T) {
+		wd, counter := newWatchdogForTesting(80 * time.Millisecond)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		wd.start(cancel)
+		for i := 0; i < 20; i++ {
+			switch i % 3 {
+			case 0:
+				counter.CountBytesSent(1)
+			case 1:
+				counter.CountBytesReceived(1)
+			default:
+				wd.OnProgress(float64(i)/20, "making progress")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		wd.stop()
+		wd.stop() // idempotent
+		if wd.hasFired() || ctx.Err() != nil {
+			t.Fatal("the watchdog should not have fired")
+		}
+	})
+
+	t.Run("it uses the clock", func(t *testing.T) {
+		clock := &watchdogSteppingClock{
+			now:  time.Now(),
+			step: 15 * time.Minute,
+		}
+		counter := bytecounter.New()
+		callbacks := model.NewPrinterCallbacks(model.DiscardLogger)
+		wd := newWatchdog(time.Hour, clock, counter, callbacks, model.DiscardLogger)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		wd.start(cancel)
+		defer wd.stop()
+		<-ctx.Done()
+		if !wd.hasFired() {
+			t.Fatal("the watchdog should have fired")
+		}
+	})
+}
+
+// watchdogSteppingClock is a model.Clock whose timers expire at once
+// and whose time moves forward by step every time we read it.
+type watchdogSteppingClock struct {
+	model.Clock
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+func (c *watchdogSteppingClock) Now() time.Time {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func (c *watchdogSteppingClock) NewTimer(d time.Duration) model.Timer {
+	return &watchdogExpiredTimer{}
+}
+
+// watchdogExpiredTimer is a model.Timer that is always expired.
+type watchdogExpiredTimer struct {
+	model.Timer
+}
+
+func (t *watchdogExpiredTimer) C() <-chan time.Time {
+	ch := make(chan time.Time)
+	close(ch)
+	return ch
+}
+
+func (t *watchdogExpiredTimer) Reset(d time.Duration) bool {
+	return false
+}
+
+func (t *watchdogExpiredTimer) Stop() bool {
+	return false
+}
@@ -24,7 +24,8 @@ import (
 //
 // Caveat: this kind of fillter does not support filling interfaces
 // and channels and other complex types. The current behavior when this
-// kind of data types is encountered is to just ignore them.
+// kind of data types is encountered is to just ignore them. You can
+// teach the filler how to fill these types using Strategies.
 type Filler struct {
 	// mu provides mutual exclusion
 	mu sync.Mutex
@@ -32,22 +33,42 @@ type Filler struct {
 	// Now is OPTIONAL and allows to mock the current time
 	Now func() time.Time
 
+	// Seed is the OPTIONAL seed of the random number generator. When
+	// it is zero, we seed the generator using the current time. Use a
+	// nonzero seed and a mocked Now to always generate the same data.
+	Seed int64
+
+	// Strategies OPTIONALLY maps a type to the Strategy for generating
+	// its values, which overrides the default behavior for such a type.
+	Strategies map[reflect.Type]Strategy
+
 	// rnd is the random number generator and is
 	// automatically initialized on first use
 	rnd *rand.Rand
 }
 
+// Strategy generates a random value for a type using the given random
+// number generator. The returned value MUST be assignable to the type.
+type Strategy func(rnd *rand.Rand) interface{}
+
 func (ff *Filler) getRandLocked() *rand.Rand {
 	if ff.rnd == nil {
-		now := time.Now
-		if ff.Now != nil {
-			now = ff.Now
+		seed := ff.Seed
+		if seed == 0 {
+			seed = ff.now().UnixNano()
 		}
-		ff.rnd = rand.New(rand.NewSource(now().UnixNano()))
+		ff.rnd = rand.New(rand.NewSource(seed))
 	}
 	return ff.rnd
 }
 
+func (ff *Filler) now() time.Time {
+	if ff.Now != nil {
+		return ff.Now()
+	}
+	return time.Now()
+}
+
 func (ff *Filler) getRandomString() string {
 	defer ff.mu.Unlock()
 	ff.mu.Lock()
@@ -69,6 +90,13 @@ func (ff *Filler) getRandomInt64() int64 {
 	return rnd.Int63()
 }
 
+func (ff *Filler) getRandomFloat64() float64 {
+	defer ff.mu.Unlock()
+	ff.mu.Lock()
+	rnd := ff.getRandLocked()
+	return rnd.Float64()
+}
+
 func (ff *Filler) getRandomBool() bool {
 	defer ff.mu.Unlock()
 	ff.mu.Lock()
@@ -83,6 +111,13 @@ func (ff *Filler) getRandomSmallPositiveInt() int {
 	return int(rnd.Int63n(8)) + 1 // safe cast
 }
 
+func (ff *Filler) runStrategy(strategy Strategy) interface{} {
+	defer ff.mu.Unlock()
+	ff.mu.Lock()
+	rnd := ff.getRandLocked()
+	return strategy(rnd)
+}
+
 func (ff *Filler) doFill(v reflect.Value) {
 	for v.Type().Kind() == reflect.Ptr {
 		if v.IsNil() {
@@ -92,22 +127,33 @@ func (ff *Filler) doFill(v reflect.Value) {
 		// switch to the element
 		v = v.Elem()
 	}
+	if strategy, found := ff.Strategies[v.Type()]; found {
+		v.Set(reflect.ValueOf(ff.runStrategy(strategy)))
+		return
+	}
 	switch v.Type().Kind() {
 	case reflect.String:
 		v.SetString(ff.getRandomString())
-	case reflect.Int64:
-		v.SetInt(ff.getRandomInt64())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(ff.getRandomInt64()) // truncated to the type's size
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(ff.getRandomInt64())) // truncated to the type's size
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(ff.getRandomFloat64())
 	case reflect.Bool:
 		v.SetBool(ff.getRandomBool())
 	case reflect.Struct:
 		if v.Type().String() == "time.Time" {
 			// Implementation note: we treat the time specially
 			// and we avoid attempting to set its fields.
-			v.Set(reflect.ValueOf(time.Now().Add(
+			v.Set(reflect.ValueOf(ff.now().Add(
 				time.Duration(ff.getRandomSmallPositiveInt()) * time.Second)))
 			return
 		}
 		for idx := 0; idx < v.NumField(); idx++ {
+			if !v.Field(idx).CanSet() {
+				continue // skip unexported fields
+			}
 			ff.doFill(v.Field(idx)) // visit all fields
 		}
 	case reflect.Slice:
@@ -119,16 +165,14 @@ func (ff *Filler) doFill(v reflect.Value) {
 			v.Set(reflect.Append(v, value.Elem())) // append to slice
 		}
 	case reflect.Map:
-		if v.Type().Key().Kind() != reflect.String {
-			panic("fakefill: we only support string key types")
-		}
 		v.Set(reflect.MakeMap(v.Type())) // we need to init the map
 		total := ff.getRandomSmallPositiveInt()
-		kind := v.Type().Elem()
 		for idx := 0; idx < total; idx++ {
-			value := reflect.New(kind)
+			key := reflect.New(v.Type().Key())
+			ff.doFill(key)
+			value := reflect.New(v.Type().Elem())
 			ff.doFill(value)
-			v.SetMapIndex(reflect.ValueOf(ff.getRandomString()), value.Elem())
+			v.SetMapIndex(key.Elem(), value.Elem())
 		}
 	}
 }
@@ -1,8 +1,12 @@
 package fakefill
 
 import (
+	"math/rand"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // exampleStructure is an example structure we fill.
@@ -54,22 +58,19 @@ func TestFakeFillAllocatesIntoAMapLikeWithStringKeys(t *testing.T) {
 }
 
 func TestFakeFillAllocatesIntoAMapLikeWithNonStringKeys(t *testing.T) {
-	var panicmsg string
-	func() {
-		defer func() {
-			if v := recover(); v != nil {
-				panicmsg = v.(string)
-			}
-		}()
-		var resp map[int64]*exampleStructure
-		ff := &Filler{}
-		ff.Fill(&resp)
-		if resp != nil {
-			t.Fatal("we expected nil here")
+	var resp map[int64]*exampleStructure
+	ff := &Filler{}
+	ff.Fill(&resp)
+	if resp == nil {
+		t.Fatal("we expected non nil here")
+	}
+	if len(resp) < 1 {
+		t.Fatal("we expected some data here")
+	}
+	for _, value := range resp {
+		if value == nil {
+			t.Fatal("expected non-nil here")
 		}
-	}()
-	if panicmsg != "fakefill: we only support string key types" {
-		t.Fatal("unexpected panic message", panicmsg)
 	}
 }
 
@@ -89,3 +90,91 @@ func TestFakeFillAllocatesIntoASlice(t *testing.T) {
 		}
 	}
 }
+
+// richStructure is a structure using more data types.
+type richStructure struct {
+	Counters map[string]int
+	Nested   **exampleStructure
+	Ratio    float64
+	Sizes    map[uint32][]string
+	Status   uint8
+	private  string
+}
+
+func TestFakeFillWithRicherStructures(t *testing.T) {
+	var resp richStructure
+	ff := &Filler{}
+	ff.Fill(&resp)
+	if len(resp.Counters) < 1 {
+		t.Fatal("we expected some counters here")
+	}
+	if resp.Nested == nil || *resp.Nested == nil {
+		t.Fatal("we expected non nil here")
+	}
+	if len(resp.Sizes) < 1 {
+		t.Fatal("we expected some sizes here")
+	}
+	if resp.private != "" {
+		t.Fatal("we expected unexported fields to be ignored")
+	}
+}
+
+func TestFakeFillUsesNowForTime(t *testing.T) {
+	now := time.Date(1992, time.January, 24, 17, 53, 0, 0, time.UTC)
+	var req exampleStructure
+	ff := &Filler{
+		Now: func() time.Time {
+			return now
+		},
+	}
+	ff.Fill(&req)
+	if req.Now.Before(now) || req.Now.After(now.Add(time.Hour)) {
+		t.Fatal("unexpected time", req.Now)
+	}
+}
+
+func TestFakeFillWithSeedIsDeterministic(t *testing.T) {
+	fill := func() (out map[string]*richStructure) {
+		ff := &Filler{
+			Now: func() time.Time {
+				return time.Date(1992, time.January, 24, 17, 53, 0, 0, time.UTC)
+			},
+			Seed: 17,
+		}
+		ff.Fill(&out)
+		return
+	}
+	first, second := fill(), fill()
+	if diff := cmp.Diff(first, second, cmp.AllowUnexported(richStructure{})); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestFakeFillWithStrategies(t *testing.T) {
+	ff := &Filler{
+		Strategies: map[reflect.Type]Strategy{
+			reflect.TypeOf(""): func(rnd *rand.Rand) interface{} {
+				return "antani"
+			},
+			reflect.TypeOf(time.Duration(0)): func(rnd *rand.Rand) interface{} {
+				return time.Duration(rnd.Intn(10)) * time.Second
+			},
+		},
+	}
+	var resp struct {
+		Names   []string
+		Timeout time.Duration
+	}
+	ff.Fill(&resp)
+	if len(resp.Names) < 1 {
+		t.Fatal("we expected some names here")
+	}
+	for _, name := range resp.Names {
+		if name != "antani" {
+			t.Fatal("unexpected name", name)
+		}
+	}
+	if resp.Timeout < 0 || resp.Timeout >= 10*time.Second || resp.Timeout%time.Second != 0 {
+		t.Fatal("unexpected timeout", resp.Timeout)
+	}
+}
@@ -0,0 +1,34 @@
+package httpx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+)
+
+// ContentEncodingGzip is the gzip content encoding.
+const ContentEncodingGzip = "gzip"
+
+// ErrUnsupportedContentEncoding indicates that we do not
+// support compressing bodies with a given content encoding.
+var ErrUnsupportedContentEncoding = errors.New("httpx: unsupported content encoding")
+
+// CompressBody compresses the body using the given content encoding. The
+// only content encoding we currently support is ContentEncodingGzip.
+func CompressBody(encoding string, body []byte) ([]byte, error) {
+	switch encoding {
+	case ContentEncodingGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentEncoding, encoding)
+	}
+}
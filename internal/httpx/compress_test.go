@@ -0,0 +1,85 @@
+package httpx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCompressBody(t *testing.T) {
+	t.Run("with gzip", func(t *testing.T) {
+		body := bytes.Repeat([]byte("antani"), 1024)
+		data, err := CompressBody(ContentEncodingGzip, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) >= len(body) {
+			t.Fatal("expected the body to be smaller")
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(body, out) {
+			t.Fatal("unexpected decompressed body")
+		}
+	})
+
+	t.Run("with unsupported encoding", func(t *testing.T) {
+		data, err := CompressBody("br", []byte("antani"))
+		if !errors.Is(err, ErrUnsupportedContentEncoding) {
+			t.Fatal("not the error we expected", err)
+		}
+		if data != nil {
+			t.Fatal("expected nil data here")
+		}
+	})
+}
+
+func TestAPIClientContentEncoding(t *testing.T) {
+	t.Run("with gzip", func(t *testing.T) {
+		client := newAPIClient()
+		client.ContentEncoding = ContentEncodingGzip
+		req, err := client.newRequestWithJSONBody(
+			context.Background(), "POST", "/", nil, []string{"antani"},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if req.Header.Get("Content-Encoding") != ContentEncodingGzip {
+			t.Fatal("did not set content-encoding properly")
+		}
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != `["antani"]` {
+			t.Fatal("unexpected body", string(out))
+		}
+	})
+
+	t.Run("with unsupported encoding", func(t *testing.T) {
+		client := newAPIClient()
+		client.ContentEncoding = "br"
+		req, err := client.newRequestWithJSONBody(
+			context.Background(), "POST", "/", nil, []string{"antani"},
+		)
+		if !errors.Is(err, ErrUnsupportedContentEncoding) {
+			t.Fatal("not the error we expected", err)
+		}
+		if req != nil {
+			t.Fatal("expected nil request here")
+		}
+	})
+}
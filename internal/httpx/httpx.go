@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
@@ -27,6 +28,10 @@ type APIClientTemplate struct {
 	// BaseURL is the MANDATORY base URL of the API.
 	BaseURL string
 
+	// ContentEncoding is the OPTIONAL encoding with which we compress
+	// the bodies of the requests we send (see CompressBody).
+	ContentEncoding string
+
 	// HTTPClient is the MANDATORY underlying http client to use.
 	HTTPClient model.HTTPClient
 
@@ -37,9 +42,17 @@ type APIClientTemplate struct {
 	// LogBody is the OPTIONAL flag to force logging the bodies.
 	LogBody bool
 
+	// Limiter is the OPTIONAL limiter delaying requests
+	// (see RateLimiter). If nil, we don't delay requests.
+	Limiter Limiter
+
 	// Logger is MANDATORY the logger to use.
 	Logger model.DebugLogger
 
+	// SigningSecret is the OPTIONAL shared secret for signing
+	// requests (see NewSigningHTTPClient). If empty, we don't sign.
+	SigningSecret string
+
 	// UserAgent is the OPTIONAL user agent to use.
 	UserAgent string
 }
@@ -108,6 +121,10 @@ type apiClient struct {
 	// BaseURL is the MANDATORY base URL of the API.
 	BaseURL string
 
+	// ContentEncoding is the OPTIONAL encoding with which we compress
+	// the bodies of the requests we send (see CompressBody).
+	ContentEncoding string
+
 	// HTTPClient is the MANDATORY underlying http client to use.
 	HTTPClient model.HTTPClient
 
@@ -118,9 +135,17 @@ type apiClient struct {
 	// LogBody is the OPTIONAL flag to force logging the bodies.
 	LogBody bool
 
+	// Limiter is the OPTIONAL limiter delaying requests
+	// (see RateLimiter). If nil, we don't delay requests.
+	Limiter Limiter
+
 	// Logger is MANDATORY the logger to use.
 	Logger model.DebugLogger
 
+	// SigningSecret is the OPTIONAL shared secret for signing
+	// requests (see NewSigningHTTPClient). If empty, we don't sign.
+	SigningSecret string
+
 	// UserAgent is the OPTIONAL user agent to use.
 	UserAgent string
 }
@@ -137,6 +162,13 @@ func (c *apiClient) newRequestWithJSONBody(
 	if c.LogBody {
 		c.Logger.Debugf("httpx: request body: %s", string(data))
 	}
+	if c.ContentEncoding != "" {
+		data, err = CompressBody(c.ContentEncoding, data)
+		if err != nil {
+			return nil, err
+		}
+		c.Logger.Debugf("httpx: %s request body length: %d bytes", c.ContentEncoding, len(data))
+	}
 	request, err := c.newRequest(
 		ctx, method, resourcePath, query, bytes.NewReader(data))
 	if err != nil {
@@ -145,6 +177,9 @@ func (c *apiClient) newRequestWithJSONBody(
 	if body != nil {
 		request.Header.Set("Content-Type", "application/json")
 	}
+	if c.ContentEncoding != "" {
+		request.Header.Set("Content-Encoding", c.ContentEncoding)
+	}
 	return request, nil
 }
 
@@ -192,12 +227,52 @@ func (c *apiClient) newRequest(ctx context.Context, method, resourcePath string,
 	return request, nil
 }
 
+// httpClient returns the HTTP client to use, which delays requests
+// when we have a Limiter and signs them when we have a SigningSecret.
+func (c *apiClient) httpClient() model.HTTPClient {
+	clnt := c.HTTPClient
+	if c.SigningSecret != "" {
+		clnt = &signingHTTPClient{
+			HTTPClient: clnt,
+			Secret:     c.SigningSecret,
+			timeNow:    time.Now,
+		}
+	}
+	if c.Limiter != nil {
+		// Note: we wait before signing, so that the signature's
+		// timestamp does not include the waiting time.
+		clnt = &rateLimitedHTTPClient{HTTPClient: clnt, Limiter: c.Limiter}
+	}
+	return clnt
+}
+
 // ErrRequestFailed indicates that the server returned >= 400.
 var ErrRequestFailed = errors.New("httpx: request failed")
 
+// StatusCodeError is the error returned when the server returns >= 400. You
+// can use errors.As to access the status code and errors.Is to check whether
+// an error is an ErrRequestFailed error.
+type StatusCodeError struct {
+	// StatusCode is the response status code.
+	StatusCode int
+
+	// Status is the response status (e.g., "404 Not Found").
+	Status string
+}
+
+// Error implements error.
+func (e *StatusCodeError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrRequestFailed.Error(), e.Status)
+}
+
+// Is returns true when target is ErrRequestFailed.
+func (e *StatusCodeError) Is(target error) bool {
+	return target == ErrRequestFailed
+}
+
 // do performs the provided request and returns the response body or an error.
 func (c *apiClient) do(request *http.Request) ([]byte, error) {
-	response, err := c.HTTPClient.Do(request)
+	response, err := c.httpClient().Do(request)
 	if err != nil {
 		return nil, err
 	}
@@ -214,7 +289,7 @@ func (c *apiClient) do(request *http.Request) ([]byte, error) {
 		c.Logger.Debugf("httpx: response body: %s", string(data))
 	}
 	if response.StatusCode >= 400 {
-		return nil, fmt.Errorf("%w: %s", ErrRequestFailed, response.Status)
+		return nil, &StatusCodeError{StatusCode: response.StatusCode, Status: response.Status}
 	}
 	return data, nil
 }
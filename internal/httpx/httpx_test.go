@@ -321,6 +321,10 @@ func TestAPIClient(t *testing.T) {
 			if !errors.Is(err, ErrRequestFailed) {
 				t.Fatal("not the error we expected", err)
 			}
+			var statusErr *StatusCodeError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != 401 {
+				t.Fatal("not the error we expected", err)
+			}
 		})
 
 		t.Run("cannot read body", func(t *testing.T) {
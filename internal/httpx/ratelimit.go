@@ -0,0 +1,176 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Limiter delays requests to avoid hammering backend endpoints. The
+// RateLimiter type implements this interface.
+type Limiter interface {
+	// Wait blocks until we can send a request to the given endpoint or
+	// the context is done, in which case it returns the context's error.
+	Wait(ctx context.Context, endpoint string) error
+}
+
+// RateLimit configures a token bucket.
+type RateLimit struct {
+	// Burst is the maximum number of requests we can send
+	// back to back after a period of inactivity.
+	Burst int
+
+	// Rate is the steady number of requests per second.
+	Rate float64
+}
+
+// These are the default values used by RateLimiter.
+const (
+	defaultRateLimitBurst = 10
+	defaultRateLimitRate  = 1.0
+)
+
+// RateLimiter prevents us from hammering backend endpoints. It uses a
+// token bucket for each endpoint (i.e., scheme and host of the URL) and
+// delays requests exceeding the configured rates until either a token
+// becomes available or the request context is done.
+//
+// A RateLimiter is meant to be shared by all the clients using the same
+// backend. The zero value is a valid rate limiter using sensible defaults.
+type RateLimiter struct {
+	// Default is the OPTIONAL rate limit for endpoints not listed in
+	// PerEndpoint. When its fields are zero or negative, we use
+	// default values.
+	Default RateLimit
+
+	// PerEndpoint OPTIONALLY maps endpoints (e.g., "https://api.ooni.io")
+	// to specific rate limits overriding Default.
+	PerEndpoint map[string]RateLimit
+
+	// TimeNow is the OPTIONAL function returning the current time. When
+	// nil, we use time.Now. This field is mainly useful for testing.
+	TimeNow func() time.Time
+
+	// buckets maps each endpoint to its bucket.
+	buckets map[string]*tokenBucket
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+}
+
+// tokenBucket is the token bucket of a specific endpoint.
+type tokenBucket struct {
+	// tokens is the number of available tokens, which is negative
+	// when there are requests waiting for a token.
+	tokens float64
+
+	// updated is the last time we refilled the bucket.
+	updated time.Time
+}
+
+func (rl *RateLimiter) timeNow() time.Time {
+	if rl.TimeNow != nil {
+		return rl.TimeNow()
+	}
+	return time.Now()
+}
+
+// limit returns the rate limit of the given endpoint.
+func (rl *RateLimiter) limit(endpoint string) RateLimit {
+	limit, found := rl.PerEndpoint[endpoint]
+	if !found {
+		limit = rl.Default
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = defaultRateLimitBurst
+	}
+	if limit.Rate <= 0 {
+		limit.Rate = defaultRateLimitRate
+	}
+	return limit
+}
+
+// reserve takes a token from the bucket of the given endpoint and
+// returns how long we should wait before the token is valid.
+func (rl *RateLimiter) reserve(endpoint string) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	limit, now := rl.limit(endpoint), rl.timeNow()
+	if rl.buckets == nil {
+		rl.buckets = make(map[string]*tokenBucket)
+	}
+	tb, found := rl.buckets[endpoint]
+	if !found {
+		tb = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		rl.buckets[endpoint] = tb
+	}
+	tb.tokens += now.Sub(tb.updated).Seconds() * limit.Rate
+	if tb.tokens > float64(limit.Burst) {
+		tb.tokens = float64(limit.Burst)
+	}
+	tb.updated = now
+	tb.tokens--
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / limit.Rate * float64(time.Second))
+}
+
+// cancel returns a token we didn't use to the given endpoint's bucket.
+func (rl *RateLimiter) cancel(endpoint string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if tb, found := rl.buckets[endpoint]; found {
+		tb.tokens++
+	}
+}
+
+// Wait implements Limiter.Wait.
+func (rl *RateLimiter) Wait(ctx context.Context, endpoint string) error {
+	delay := rl.reserve(endpoint)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		rl.cancel(endpoint)
+		return ctx.Err()
+	}
+}
+
+// rateLimitedHTTPClient is a model.HTTPClient that waits
+// for the RateLimiter before sending each request.
+type rateLimitedHTTPClient struct {
+	// HTTPClient is the MANDATORY underlying HTTP client.
+	HTTPClient model.HTTPClient
+
+	// Limiter is the MANDATORY limiter.
+	Limiter Limiter
+}
+
+// NewRateLimitedHTTPClient returns a model.HTTPClient that uses the given
+// limiter to delay requests and then sends them using clnt.
+func NewRateLimitedHTTPClient(clnt model.HTTPClient, limiter Limiter) model.HTTPClient {
+	return &rateLimitedHTTPClient{HTTPClient: clnt, Limiter: limiter}
+}
+
+// Do implements model.HTTPClient.Do.
+func (c *rateLimitedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	endpoint := req.URL.Scheme + "://" + req.URL.Host
+	if err := c.Limiter.Wait(req.Context(), endpoint); err != nil {
+		return nil, err
+	}
+	return c.HTTPClient.Do(req)
+}
+
+// CloseIdleConnections implements model.HTTPClient.CloseIdleConnections.
+func (c *rateLimitedHTTPClient) CloseIdleConnections() {
+	c.HTTPClient.CloseIdleConnections()
+}
@@ -0,0 +1,126 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	now := time.Now()
+	rl := &RateLimiter{
+		Default: RateLimit{Burst: 2, Rate: 1},
+		PerEndpoint: map[string]RateLimit{
+			"https://api.ooni.io": {Burst: 1, Rate: 4},
+		},
+		TimeNow: func() time.Time {
+			return now
+		},
+	}
+	const endpoint = "https://ams-pg.ooni.org"
+	for idx := 0; idx < 2; idx++ {
+		if delay := rl.reserve(endpoint); delay != 0 {
+			t.Fatal("expected no delay within the burst", delay)
+		}
+	}
+	if delay := rl.reserve(endpoint); delay != time.Second {
+		t.Fatal("unexpected delay", delay)
+	}
+	if delay := rl.reserve(endpoint); delay != 2*time.Second {
+		t.Fatal("unexpected delay", delay)
+	}
+	now = now.Add(3 * time.Second)
+	if delay := rl.reserve(endpoint); delay != 0 {
+		t.Fatal("expected no delay after refilling", delay)
+	}
+	// the other endpoint has its own bucket and rates
+	if delay := rl.reserve("https://api.ooni.io"); delay != 0 {
+		t.Fatal("expected no delay within the burst", delay)
+	}
+	if delay := rl.reserve("https://api.ooni.io"); delay != 250*time.Millisecond {
+		t.Fatal("unexpected delay", delay)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	t.Run("with cancelled context", func(t *testing.T) {
+		rl := &RateLimiter{Default: RateLimit{Burst: 1, Rate: 0.001}}
+		ctx := context.Background()
+		if err := rl.Wait(ctx, "https://x.org"); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if err := rl.Wait(ctx, "https://x.org"); !errors.Is(err, context.Canceled) {
+			t.Fatal("not the error we expected", err)
+		}
+		if tokens := rl.buckets["https://x.org"].tokens; tokens < -0.01 || tokens > 0.01 {
+			t.Fatal("expected to have returned the token", tokens)
+		}
+	})
+
+	t.Run("with delay", func(t *testing.T) {
+		rl := &RateLimiter{Default: RateLimit{Burst: 1, Rate: 20}}
+		ctx := context.Background()
+		t0 := time.Now()
+		for idx := 0; idx < 3; idx++ {
+			if err := rl.Wait(ctx, "https://x.org"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if elapsed := time.Since(t0); elapsed < 90*time.Millisecond {
+			t.Fatal("expected to wait for the tokens", elapsed)
+		}
+	})
+}
+
+// fakeLimiter is a Limiter recording the endpoints.
+type fakeLimiter struct {
+	endpoints []string
+	err       error
+	mu        sync.Mutex
+}
+
+func (fl *fakeLimiter) Wait(ctx context.Context, endpoint string) error {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+	fl.endpoints = append(fl.endpoints, endpoint)
+	return fl.err
+}
+
+func TestAPIClientLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	t.Run("when the limiter lets us through", func(t *testing.T) {
+		fl := &fakeLimiter{}
+		client := newAPIClient()
+		client.BaseURL = server.URL
+		client.Limiter = fl
+		var output struct{}
+		if err := client.GetJSON(context.Background(), "/api/v1/test-helpers", &output); err != nil {
+			t.Fatal(err)
+		}
+		if len(fl.endpoints) != 1 || fl.endpoints[0] != server.URL {
+			t.Fatal("unexpected endpoints", fl.endpoints)
+		}
+	})
+
+	t.Run("when the limiter fails", func(t *testing.T) {
+		fl := &fakeLimiter{err: context.DeadlineExceeded}
+		client := newAPIClient()
+		client.BaseURL = server.URL
+		client.Limiter = fl
+		var output struct{}
+		err := client.GetJSON(context.Background(), "/api/v1/test-helpers", &output)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model/mocks"
+)
+
+// newSigningTestServer returns a server that verifies the signature of
+// requests using the given secret and saves the verification result.
+func newSigningTestServer(secret string, result *error) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if *result = VerifyRequestSignature(r, secret, time.Minute); *result != nil {
+			w.WriteHeader(401)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestAPIClientSigning(t *testing.T) {
+	t.Run("with the same secret", func(t *testing.T) {
+		var result error
+		server := newSigningTestServer("antani", &result)
+		defer server.Close()
+		client := newAPIClient()
+		client.BaseURL = server.URL
+		client.SigningSecret = "antani"
+		var output struct{}
+		ctx := context.Background()
+		if err := client.PostJSON(ctx, "/api/v1/check-in", []int{1, 2, 3}, &output); err != nil {
+			t.Fatal(err, result)
+		}
+		if err := client.GetJSON(ctx, "/api/v1/test-list/urls?limit=1", &output); err != nil {
+			t.Fatal(err, result)
+		}
+	})
+
+	t.Run("with a different secret", func(t *testing.T) {
+		var result error
+		server := newSigningTestServer("antani", &result)
+		defer server.Close()
+		client := newAPIClient()
+		client.BaseURL = server.URL
+		client.SigningSecret = "mascetti"
+		var output struct{}
+		err := client.PostJSON(context.Background(), "/", []int{1, 2, 3}, &output)
+		if !errors.Is(err, ErrRequestFailed) {
+			t.Fatal("not the error we expected", err)
+		}
+		if !errors.Is(result, ErrInvalidSignature) {
+			t.Fatal("not the error we expected", result)
+		}
+	})
+
+	t.Run("without a secret", func(t *testing.T) {
+		var result error
+		server := newSigningTestServer("antani", &result)
+		defer server.Close()
+		client := newAPIClient()
+		client.BaseURL = server.URL
+		var output struct{}
+		err := client.GetJSON(context.Background(), "/", &output)
+		if !errors.Is(err, ErrRequestFailed) {
+			t.Fatal("not the error we expected", err)
+		}
+		if !errors.Is(result, ErrInvalidSignature) {
+			t.Fatal("not the error we expected", result)
+		}
+	})
+}
+
+func TestVerifyRequestSignature(t *testing.T) {
+	// sign returns a request signed at the given time.
+	sign := func(t *testing.T, now time.Time, body string) *http.Request {
+		var signed *http.Request
+		clnt := &signingHTTPClient{
+			HTTPClient: &mocks.HTTPClient{
+				MockDo: func(req *http.Request) (*http.Response, error) {
+					signed = req
+					return nil, errors.New("mocked error")
+				},
+			},
+			Secret: "antani",
+			timeNow: func() time.Time {
+				return now
+			},
+		}
+		req, err := http.NewRequest("POST", "https://example.com/x", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		clnt.Do(req)
+		return signed
+	}
+
+	t.Run("success", func(t *testing.T) {
+		req := sign(t, time.Now(), "deadbeef")
+		if err := VerifyRequestSignature(req, "antani", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("with tampered body", func(t *testing.T) {
+		req := sign(t, time.Now(), "deadbeef")
+		req.Body = http.NoBody
+		err := VerifyRequestSignature(req, "antani", time.Minute)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with expired timestamp", func(t *testing.T) {
+		req := sign(t, time.Now().Add(-time.Hour), "deadbeef")
+		err := VerifyRequestSignature(req, "antani", time.Minute)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with request whose body cannot be reread", func(t *testing.T) {
+		clnt := NewSigningHTTPClient(http.DefaultClient, "antani")
+		req, err := http.NewRequest("POST", "https://example.com/x", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Body = io.NopCloser(strings.NewReader("deadbeef"))
+		resp, err := clnt.Do(req)
+		if err == nil || resp != nil {
+			t.Fatal("expected an error here")
+		}
+	})
+}
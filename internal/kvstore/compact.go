@@ -0,0 +1,316 @@
+package kvstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/rogpeppe/go-internal/lockedfile"
+)
+
+// CompactFS is a file-system based KVStore that keeps an index of the
+// keys it contains along with their size and last access time. Calling
+// Compact removes stale index entries, adopts untracked files and, when
+// MaxBytes is positive, evicts the least recently used keys, except for the
+// Pinned ones, until the total size is below MaxBytes.
+//
+// Several processes may share the same base directory. Each of them merges
+// its view of the index with the one on disk while holding a lock on the
+// index file, such that they do not overwrite each other's entries.
+//
+// CompactFS uses the same on-disk layout as FS for the keys, so it is
+// possible to open with CompactFS a directory previously used by FS.
+type CompactFS struct {
+	// MaxBytes is the OPTIONAL maximum size of the store. When zero
+	// or negative, Compact does not evict any key.
+	MaxBytes int64
+
+	// Pinned contains the OPTIONAL keys that Compact never evicts, e.g.,
+	// the ones containing credentials and state rather than cached data.
+	// A pinned name also pins the keys inside the namespace having such
+	// a name (see Namespace).
+	Pinned []string
+
+	// basedir is the base directory.
+	basedir string
+
+	// index maps each key to its metadata.
+	index map[string]*compactEntry
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// timeNow allows to mock time.Now in tests.
+	timeNow func() time.Time
+}
+
+// compactEntry contains metadata about a key.
+type compactEntry struct {
+	// Size is the size of the value in bytes.
+	Size int64
+
+	// LastAccess is the last time we read or wrote the key.
+	LastAccess time.Time
+}
+
+// compactIndexName is the name of the file containing the index. We use
+// a leading dot to avoid clashing with the keys we actually use.
+const compactIndexName = ".kvstore-index.json"
+
+var _ model.KeyValueStore = &CompactFS{}
+
+// NewCompactFS creates a new kvstore.CompactFS using the given base
+// directory and limiting the total size of the store to maxBytes.
+func NewCompactFS(basedir string, maxBytes int64) (*CompactFS, error) {
+	return newCompactFS(basedir, maxBytes, os.MkdirAll)
+}
+
+// newCompactFS is like NewCompactFS with a customizable
+// osMkdirAll function for creating the kvstore dir.
+func newCompactFS(basedir string, maxBytes int64, mkdir osMkdirAll) (*CompactFS, error) {
+	if err := mkdir(basedir, 0700); err != nil {
+		return nil, err
+	}
+	kvs := &CompactFS{
+		MaxBytes: maxBytes,
+		basedir:  basedir,
+		index:    make(map[string]*compactEntry),
+		timeNow:  time.Now,
+	}
+	kvs.readIndex()
+	return kvs, nil
+}
+
+// filename returns the filename for a given key.
+func (kvs *CompactFS) filename(key string) string {
+	return filepath.Join(kvs.basedir, key)
+}
+
+// readIndex loads the index from disk. We ignore errors because
+// a missing or corrupt index is rebuilt by Compact.
+func (kvs *CompactFS) readIndex() {
+	data, err := lockedfile.Read(kvs.filename(compactIndexName))
+	if err != nil {
+		return
+	}
+	var index map[string]*compactEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		return
+	}
+	for key, entry := range index {
+		if entry != nil {
+			kvs.index[key] = entry
+		}
+	}
+}
+
+// writeIndex merges the index with the one on disk, which other processes
+// may have modified, and writes the result on disk. We hold a lock on the
+// index file while doing that. This function assumes that the caller is
+// holding the mutex.
+func (kvs *CompactFS) writeIndex() error {
+	filep, err := lockedfile.OpenFile(
+		kvs.filename(compactIndexName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer filep.Close()
+	data, err := io.ReadAll(filep)
+	if err != nil {
+		return err
+	}
+	kvs.mergeIndex(data)
+	if data, err = json.Marshal(kvs.index); err != nil {
+		return err
+	}
+	if err := filep.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := filep.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return filep.Close()
+}
+
+// mergeIndex merges the given serialized index into the index. For the keys
+// we both know, we keep the most recent entry. For the other keys, we check
+// whether they still exist, such that we neither resurrect the keys deleted
+// or evicted by us nor the ones deleted or evicted by other processes. This
+// function assumes that the caller is holding the mutex. We ignore errors
+// like readIndex does.
+func (kvs *CompactFS) mergeIndex(data []byte) {
+	var index map[string]*compactEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		return
+	}
+	for key := range kvs.index {
+		if _, found := index[key]; found {
+			continue
+		}
+		if _, err := os.Stat(kvs.filename(key)); err != nil {
+			delete(kvs.index, key)
+		}
+	}
+	for key, entry := range index {
+		if entry == nil {
+			continue
+		}
+		if current, found := kvs.index[key]; found {
+			if entry.LastAccess.After(current.LastAccess) {
+				kvs.index[key] = entry
+			}
+			continue
+		}
+		if _, err := os.Stat(kvs.filename(key)); err == nil {
+			kvs.index[key] = entry
+		}
+	}
+}
+
+// isPinned returns whether Compact must not evict the given key.
+func (kvs *CompactFS) isPinned(key string) bool {
+	for _, pinned := range kvs.Pinned {
+		if key == pinned || strings.HasPrefix(key, pinned+NamespaceSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the specified key's value. In case of error, the
+// error type is such that errors.Is(err, ErrNoSuchKey).
+func (kvs *CompactFS) Get(key string) ([]byte, error) {
+	if key == compactIndexName {
+		return nil, fmt.Errorf("%w: %s is reserved", ErrNoSuchKey, key)
+	}
+	data, err := lockedfile.Read(kvs.filename(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchKey, err.Error())
+	}
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	// Implementation note: we don't write the index here to avoid
+	// doing I/O on every Get. The access time will be saved by the
+	// next Set or Compact, which is good enough for evicting keys.
+	kvs.index[key] = &compactEntry{Size: int64(len(data)), LastAccess: kvs.timeNow()}
+	return data, nil
+}
+
+// Set sets the value of a specific key.
+func (kvs *CompactFS) Set(key string, value []byte) error {
+	if key == compactIndexName {
+		return fmt.Errorf("kvstore: %s is reserved", key)
+	}
+	filename := kvs.filename(key)
+	// Namespaced keys live in subdirectories (see Namespace).
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	if err := lockedfile.Write(filename, bytes.NewReader(value), 0600); err != nil {
+		return err
+	}
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	kvs.index[key] = &compactEntry{Size: int64(len(value)), LastAccess: kvs.timeNow()}
+	return kvs.writeIndex()
+}
+
+// Delete removes a specific key.
+func (kvs *CompactFS) Delete(key string) error {
+	if key == compactIndexName {
+		return fmt.Errorf("kvstore: %s is reserved", key)
+	}
+	err := os.Remove(kvs.filename(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	delete(kvs.index, key)
+	return kvs.writeIndex()
+}
+
+// ListKeys returns the keys in the key-value store.
+func (kvs *CompactFS) ListKeys() ([]string, error) {
+	return listKeys(kvs.basedir, func(key string) bool {
+		return key != compactIndexName
+	})
+}
+
+// Size returns the total size in bytes of the values in the store
+// according to the index. Call Compact to resync the index.
+func (kvs *CompactFS) Size() (total int64) {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	for _, entry := range kvs.index {
+		total += entry.Size
+	}
+	return
+}
+
+// Compact resyncs the index with the content of the base directory
+// and evicts the least recently used keys until the size of the store
+// is below MaxBytes. This function is safe to call periodically from
+// a background goroutine.
+func (kvs *CompactFS) Compact() error {
+	keys, err := kvs.ListKeys()
+	if err != nil {
+		return err
+	}
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	ondisk := make(map[string]bool)
+	for _, key := range keys {
+		info, err := os.Stat(kvs.filename(key))
+		if err != nil {
+			continue // the file has most likely been removed meanwhile
+		}
+		ondisk[key] = true
+		entry, found := kvs.index[key]
+		if !found {
+			// Adopt files written by FS or by a previous process that
+			// did not manage to save the index.
+			kvs.index[key] = &compactEntry{Size: info.Size(), LastAccess: info.ModTime()}
+			continue
+		}
+		entry.Size = info.Size()
+	}
+	var total int64
+	keys = nil
+	for key, entry := range kvs.index {
+		if !ondisk[key] {
+			delete(kvs.index, key)
+			continue
+		}
+		total += entry.Size
+		if !kvs.isPinned(key) {
+			keys = append(keys, key)
+		}
+	}
+	if kvs.MaxBytes > 0 && total > kvs.MaxBytes {
+		sort.SliceStable(keys, func(i, j int) bool {
+			return kvs.index[keys[i]].LastAccess.Before(kvs.index[keys[j]].LastAccess)
+		})
+		for _, key := range keys {
+			if total <= kvs.MaxBytes {
+				break
+			}
+			if err := os.Remove(kvs.filename(key)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			total -= kvs.index[key].Size
+			delete(kvs.index, key)
+		}
+	}
+	return kvs.writeIndex()
+}
@@ -0,0 +1,234 @@
+package kvstore
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newCompactFSForTesting(t *testing.T, maxBytes int64) *CompactFS {
+	kvstore, err := NewCompactFS(t.TempDir(), maxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kvstore
+}
+
+func TestCompactFSGood(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 0)
+	value := []byte("foobar")
+	if err := kvstore.Set("antani", value); err != nil {
+		t.Fatal(err)
+	}
+	ovalue, err := kvstore.Get("antani")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(ovalue, value) {
+		t.Fatal("invalid value")
+	}
+	if kvstore.Size() != int64(len(value)) {
+		t.Fatal("invalid size", kvstore.Size())
+	}
+}
+
+func TestCompactFSNoSuchKey(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 0)
+	value, err := kvstore.Get("antani")
+	if !errors.Is(err, ErrNoSuchKey) {
+		t.Fatal("not the error we expected", err)
+	}
+	if value != nil {
+		t.Fatal("expected nil value")
+	}
+}
+
+func TestCompactFSIndexIsReserved(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 0)
+	if err := kvstore.Set(compactIndexName, []byte("{}")); err == nil {
+		t.Fatal("expected an error here")
+	}
+	if _, err := kvstore.Get(compactIndexName); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestCompactFSIndexIsPersisted(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 0)
+	if err := kvstore.Set("antani", []byte("foobar")); err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewCompactFS(kvstore.basedir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Size() != 6 {
+		t.Fatal("invalid size", other.Size())
+	}
+}
+
+func TestCompactFSCompactResyncsIndex(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 0)
+	if err := kvstore.Set("antani", []byte("foobar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(kvstore.filename("antani")); err != nil {
+		t.Fatal(err)
+	}
+	// simulate a file written by kvstore.FS
+	if err := os.WriteFile(kvstore.filename("mascetti"), []byte("xo"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if kvstore.Size() != 2 {
+		t.Fatal("invalid size", kvstore.Size())
+	}
+	if _, found := kvstore.index["antani"]; found {
+		t.Fatal("expected stale entry to be removed")
+	}
+}
+
+func TestCompactFSCompactEvictsLeastRecentlyUsed(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 10)
+	now := time.Now()
+	kvstore.timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if err := kvstore.Set(key, []byte("01234")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := kvstore.Get("a"); err != nil { // "b" is now the LRU key
+		t.Fatal(err)
+	}
+	if err := kvstore.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if kvstore.Size() != 10 {
+		t.Fatal("invalid size", kvstore.Size())
+	}
+	if _, err := kvstore.Get("b"); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatal("not the error we expected", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := kvstore.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompactFSWithFailure(t *testing.T) {
+	expect := errors.New("mocked error")
+	mkdir := func(path string, perm fs.FileMode) error {
+		return expect
+	}
+	kvstore, err := newCompactFS(
+		filepath.Join(t.TempDir(), "kvstore3"), 0,
+		mkdir,
+	)
+	if !errors.Is(err, expect) {
+		t.Fatal("not the error we expected", err)
+	}
+	if kvstore != nil {
+		t.Fatal("expected nil here")
+	}
+}
+
+func TestCompactFSDeleteAndListKeys(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 0)
+	for _, key := range []string{"b", "a/b", "c"} {
+		if err := kvstore.Set(key, []byte("foobar")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kvstore.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Delete(compactIndexName); err == nil {
+		t.Fatal("expected an error here")
+	}
+	keys, err := kvstore.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a/b", "b"}, keys); diff != "" {
+		t.Fatal(diff)
+	}
+	if err := kvstore.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if kvstore.Size() != 12 {
+		t.Fatal("invalid size", kvstore.Size())
+	}
+}
+
+func TestCompactFSCompactDoesNotEvictPinnedKeys(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 5)
+	kvstore.Pinned = []string{"state", "ns"}
+	now := time.Now()
+	kvstore.timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	for _, key := range []string{"state", "ns/b", "c", "d"} {
+		if err := kvstore.Set(key, []byte("01234")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kvstore.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	// The pinned keys alone exceed MaxBytes, so we evict all the other
+	// keys, even though "state" is the least recently used key.
+	if kvstore.Size() != 10 {
+		t.Fatal("invalid size", kvstore.Size())
+	}
+	for _, key := range []string{"c", "d"} {
+		if _, err := kvstore.Get(key); !errors.Is(err, ErrNoSuchKey) {
+			t.Fatal("not the error we expected", err)
+		}
+	}
+	for _, key := range []string{"state", "ns/b"} {
+		if _, err := kvstore.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompactFSMergesTheIndexOfOtherProcesses(t *testing.T) {
+	kvstore := newCompactFSForTesting(t, 0)
+	other, err := NewCompactFS(kvstore.basedir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Set("a", []byte("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Set("b", []byte("foobar")); err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	// The other process must not resurrect the key we deleted.
+	if err := other.Set("c", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	third, err := NewCompactFS(kvstore.basedir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Size() != 7 {
+		t.Fatal("invalid size", third.Size())
+	}
+}
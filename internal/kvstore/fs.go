@@ -2,10 +2,12 @@ package kvstore
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/rogpeppe/go-internal/lockedfile"
@@ -52,5 +54,51 @@ func (kvs *FS) Get(key string) ([]byte, error) {
 
 // Set sets the value of a specific key.
 func (kvs *FS) Set(key string, value []byte) error {
-	return lockedfile.Write(kvs.filename(key), bytes.NewReader(value), 0600)
+	filename := kvs.filename(key)
+	// Namespaced keys live in subdirectories (see Namespace).
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return lockedfile.Write(filename, bytes.NewReader(value), 0600)
+}
+
+// Delete removes a specific key.
+func (kvs *FS) Delete(key string) error {
+	err := os.Remove(kvs.filename(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ListKeys returns the keys in the key-value store.
+func (kvs *FS) ListKeys() ([]string, error) {
+	return listKeys(kvs.basedir, func(string) bool { return true })
+}
+
+// listKeys walks basedir and returns the sorted list of keys
+// for which the filter function returns true.
+func listKeys(basedir string, filter func(key string) bool) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(basedir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		key, err := filepath.Rel(basedir, path)
+		if err != nil {
+			return err
+		}
+		if key = filepath.ToSlash(key); filter(key) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
 }
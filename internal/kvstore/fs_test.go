@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestFileSystemGood(t *testing.T) {
@@ -65,3 +67,28 @@ func TestFileSystemWithFailure(t *testing.T) {
 		t.Fatal("expected nil here")
 	}
 }
+
+func TestFileSystemDeleteAndListKeys(t *testing.T) {
+	kvstore, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kvstore.Delete("nonexistent"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"b", "a/b", "a.b", "c"} {
+		if err := kvstore.Set(key, []byte("foobar")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kvstore.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := kvstore.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a.b", "a/b", "b"}, keys); diff != "" {
+		t.Fatal(diff)
+	}
+}
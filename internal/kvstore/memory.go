@@ -2,6 +2,7 @@ package kvstore
 
 import (
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
@@ -43,3 +44,23 @@ func (kvs *Memory) Set(key string, value []byte) error {
 	kvs.m[key] = value
 	return nil
 }
+
+// Delete removes a key from the key-value store.
+func (kvs *Memory) Delete(key string) error {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	delete(kvs.m, key)
+	return nil
+}
+
+// ListKeys returns the keys in the key-value store.
+func (kvs *Memory) ListKeys() ([]string, error) {
+	kvs.mu.Lock()
+	defer kvs.mu.Unlock()
+	var keys []string
+	for key := range kvs.m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -3,6 +3,8 @@ package kvstore
 import (
 	"errors"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestNoSuchKey(t *testing.T) {
@@ -29,3 +31,25 @@ func TestExistingKey(t *testing.T) {
 		t.Fatal("not the result we expected")
 	}
 }
+
+func TestMemoryDeleteAndListKeys(t *testing.T) {
+	kvs := &Memory{}
+	if err := kvs.Delete("nonexistent"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"b", "a", "c"} {
+		if err := kvs.Set(key, []byte("mascetti")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kvs.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := kvs.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, keys); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,79 @@
+package kvstore
+
+import (
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// NamespaceSeparator separates a namespace from the keys it contains.
+const NamespaceSeparator = "/"
+
+// Namespace is a view of a model.KeyValueStore containing only the keys
+// belonging to a given namespace. Components sharing the same underlying
+// key-value store (e.g., the ooapi cache and the engine state) should each
+// use their own Namespace, such that their keys do not collide and each
+// component can list and clean up its own keys.
+//
+// Namespaces nest, so Namespace(Namespace(kvs, "a"), "b") is equivalent to
+// Namespace(kvs, "a/b"). With FS, each namespace is a subdirectory.
+type Namespace struct {
+	// kvs is the underlying key-value store.
+	kvs model.KeyValueStore
+
+	// prefix is the namespace followed by NamespaceSeparator.
+	prefix string
+}
+
+var _ model.KeyValueStore = &Namespace{}
+
+// NewNamespace creates a new Namespace named name inside kvs.
+func NewNamespace(kvs model.KeyValueStore, name string) *Namespace {
+	return &Namespace{kvs: kvs, prefix: name + NamespaceSeparator}
+}
+
+// Get returns the specified key's value. In case of error, the
+// error type is such that errors.Is(err, ErrNoSuchKey), provided
+// that the underlying key-value store behaves like this.
+func (ns *Namespace) Get(key string) ([]byte, error) {
+	return ns.kvs.Get(ns.prefix + key)
+}
+
+// Set sets the value of a specific key.
+func (ns *Namespace) Set(key string, value []byte) error {
+	return ns.kvs.Set(ns.prefix+key, value)
+}
+
+// Delete removes a specific key.
+func (ns *Namespace) Delete(key string) error {
+	return ns.kvs.Delete(ns.prefix + key)
+}
+
+// ListKeys returns the keys in the namespace without the namespace prefix.
+func (ns *Namespace) ListKeys() ([]string, error) {
+	keys, err := ns.kvs.ListKeys()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, ns.prefix) {
+			out = append(out, strings.TrimPrefix(key, ns.prefix))
+		}
+	}
+	return out, nil
+}
+
+// Clear removes all the keys in the namespace.
+func (ns *Namespace) Clear() error {
+	keys, err := ns.ListKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := ns.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
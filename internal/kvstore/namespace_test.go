@@ -0,0 +1,77 @@
+package kvstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNamespace(t *testing.T) {
+	kvs := &Memory{}
+	if err := kvs.Set("antani", []byte("toplevel")); err != nil {
+		t.Fatal(err)
+	}
+	ooapi := NewNamespace(kvs, "ooapi")
+	engine := NewNamespace(kvs, "engine")
+	if err := ooapi.Set("antani", []byte("ooapi")); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Set("antani", []byte("engine")); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewNamespace(ooapi, "cache").Set("mascetti", []byte("nested")); err != nil {
+		t.Fatal(err)
+	}
+	for _, kvs := range []*Namespace{ooapi, engine} {
+		value, err := kvs.Get("antani")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != kvs.prefix[:len(kvs.prefix)-1] {
+			t.Fatal("keys are colliding", string(value))
+		}
+	}
+	keys, err := ooapi.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"antani", "cache/mascetti"}, keys); diff != "" {
+		t.Fatal(diff)
+	}
+	if err := ooapi.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = kvs.ListKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"antani", "engine/antani"}, keys); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestNamespaceListKeysFailure(t *testing.T) {
+	expected := errors.New("mocked error")
+	ns := NewNamespace(&failingListKeysKVStore{err: expected}, "ooapi")
+	keys, err := ns.ListKeys()
+	if !errors.Is(err, expected) {
+		t.Fatal("not the error we expected", err)
+	}
+	if keys != nil {
+		t.Fatal("expected nil keys")
+	}
+	if err := ns.Clear(); !errors.Is(err, expected) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+// failingListKeysKVStore is a Memory where ListKeys fails.
+type failingListKeysKVStore struct {
+	Memory
+	err error
+}
+
+func (kvs *failingListKeysKVStore) ListKeys() ([]string, error) {
+	return nil, kvs.err
+}
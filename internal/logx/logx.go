@@ -0,0 +1,34 @@
+// Package logx contains adapters from logging libraries to model.FieldLogger.
+//
+// We do not provide a log/slog adapter because this module targets a
+// version of Go predating log/slog. Use LoggerWithFields to attach fields
+// to any model.Logger, including the ones not listed here.
+package logx
+
+import (
+	"github.com/apex/log"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// NewApexLogger returns a model.FieldLogger using apex/log, whose
+// handlers emit the fields along with each message.
+func NewApexLogger(logger log.Interface) model.FieldLogger {
+	return &apexLogger{logger}
+}
+
+// apexLogger adapts apex/log to model.FieldLogger.
+type apexLogger struct {
+	log.Interface
+}
+
+// WithField implements model.FieldLogger.WithField
+func (al *apexLogger) WithField(key string, value interface{}) model.FieldLogger {
+	return &apexLogger{al.Interface.WithField(key, value)}
+}
+
+// WithFields implements model.FieldLogger.WithFields
+func (al *apexLogger) WithFields(fields model.LogFields) model.FieldLogger {
+	return &apexLogger{al.Interface.WithFields(log.Fields(fields))}
+}
+
+var _ model.FieldLogger = &apexLogger{}
@@ -0,0 +1,32 @@
+package logx
+
+import (
+	"testing"
+
+	"github.com/apex/log"
+	"github.com/apex/log/handlers/memory"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+func TestApexLogger(t *testing.T) {
+	handler := memory.New()
+	logger := NewApexLogger(&log.Logger{Handler: handler, Level: log.DebugLevel})
+	logger = model.LoggerWithFields(logger, model.LogFields{"experiment": "dnscheck"})
+	logger.WithField("input", "dot://1.1.1.1:853").Infof("resolved %d addresses", 2)
+	logger.Debug("done")
+	if len(handler.Entries) != 2 {
+		t.Fatal("unexpected number of entries", len(handler.Entries))
+	}
+	first, second := handler.Entries[0], handler.Entries[1]
+	if first.Message != "resolved 2 addresses" || first.Level != log.InfoLevel {
+		t.Fatal("unexpected first entry", first)
+	}
+	expected := log.Fields{"experiment": "dnscheck", "input": "dot://1.1.1.1:853"}
+	if diff := cmp.Diff(expected, first.Fields); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff(log.Fields{"experiment": "dnscheck"}, second.Fields); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,61 @@
+package mlablocate
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// cacheMaxAge is the maximum age of the cached servers we use when
+// we cannot reach the locate service. M-Lab servers change rarely,
+// hence a week-old list most likely contains working servers.
+const cacheMaxAge = 7 * 24 * time.Hour
+
+// cacheEntry is the entry we cache for a given tool.
+type cacheEntry struct {
+	// Time is when we saved the entry.
+	Time time.Time `json:"time"`
+
+	// Results contains the servers returned by the locate service.
+	Results []Result `json:"results"`
+}
+
+// cacheKey returns the key where we cache the servers of the given
+// tool for the given query, which depends on the query parameters.
+func cacheKey(tool string, query url.Values) string {
+	return "mlablocate.v1." + url.PathEscape(tool) + "_" + url.PathEscape(query.Encode()) + ".cache"
+}
+
+// writeCache caches the servers of the given tool and query, if the client has
+// a KVStore. We ignore errors because the cache is just an optimization.
+func (c *Client) writeCache(tool string, query url.Values, results []Result) {
+	if c.KVStore == nil {
+		return
+	}
+	data, err := json.Marshal(&cacheEntry{Time: time.Now(), Results: results})
+	if err != nil {
+		return
+	}
+	if err := c.KVStore.Set(cacheKey(tool, query), data); err != nil {
+		c.Logger.Debugf("mlablocate: cannot cache servers: %s", err.Error())
+	}
+}
+
+// readCache returns the cached servers of the given tool and query, if any.
+func (c *Client) readCache(tool string, query url.Values) ([]Result, bool) {
+	if c.KVStore == nil {
+		return nil, false
+	}
+	data, err := c.KVStore.Get(cacheKey(tool, query))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if len(entry.Results) <= 0 || time.Since(entry.Time) > cacheMaxAge {
+		return nil, false
+	}
+	return entry.Results, true
+}
@@ -11,9 +11,20 @@ import (
 
 func Example_usage() {
 	clnt := mlablocate.NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
-	result, err := clnt.Query(context.Background(), "neubot/dash")
+	results, err := clnt.Query(context.Background(), "neubot/dash")
 	if err != nil {
 		log.WithError(err).Fatal("clnt.Query failed")
 	}
-	fmt.Printf("%s\n", result.FQDN)
+	for _, result := range results {
+		fmt.Printf("%s\n", result.FQDN)
+	}
+}
+
+func Example_ndt7() {
+	clnt := mlablocate.NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+	results, err := clnt.QueryNDT7(context.Background())
+	if err != nil {
+		log.WithError(err).Fatal("clnt.QueryNDT7 failed")
+	}
+	fmt.Printf("%+v\n", results)
 }
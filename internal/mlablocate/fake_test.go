@@ -1,9 +1,10 @@
-package mlablocatev2
+package mlablocate
 
 import (
 	"net/http"
 	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
@@ -44,3 +45,15 @@ func (fb FakeBody) Read(p []byte) (int, error) {
 func (fb FakeBody) Close() error {
 	return nil
 }
+
+// instantClock is a clock whose timers fire immediately and which
+// records the delays of the timers, such that tests don't sleep.
+type instantClock struct {
+	model.Clock
+	delays []time.Duration
+}
+
+func (c *instantClock) NewTimer(d time.Duration) model.Timer {
+	c.delays = append(c.delays, d)
+	return model.SystemClock.NewTimer(0)
+}
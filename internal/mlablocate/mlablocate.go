@@ -1,21 +1,66 @@
 // Package mlablocate contains a locate.measurementlab.net client
-// implementing v1 of the locate API. This version of the API isn't
-// suitable for requesting servers for ndt7. You should use the
-// mlablocatev2 package for that.
+// implementing v1 and v2 of the locate API. The v1 API isn't suitable
+// for requesting servers for ndt7, which requires v2.
 package mlablocate
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/ooni/probe-cli/v3/internal/model"
 	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
+// Version is a version of the locate API.
+type Version int
+
+const (
+	// V1 is v1 of the locate API, which cannot serve ndt7.
+	V1 = Version(1)
+
+	// V2 is v2 of the locate API, which returns URLs
+	// containing the access tokens for each server.
+	V2 = Version(2)
+)
+
+const (
+	// ndt7Service is the v2 service name for ndt7.
+	ndt7Service = "ndt/ndt7"
+
+	// v1Policy is the v1 policy returning several servers.
+	v1Policy = "geo_options"
+
+	// probeTimeout is the timeout for probing the latency to a server.
+	probeTimeout = 5 * time.Second
+)
+
+// DefaultCandidates is the default number of candidate
+// servers whose latency we probe with Fastest.
+const DefaultCandidates = 3
+
+var (
+	// ErrRequestFailed indicates that the response is not "200 Ok"
+	ErrRequestFailed = errors.New("mlablocate: non-200 status code")
+
+	// ErrEmptyFQDN indicates that v1 returned an empty FQDN
+	ErrEmptyFQDN = errors.New("mlablocate: returned empty FQDN")
+
+	// ErrEmptyResponse indicates that v2 returned no servers
+	ErrEmptyResponse = errors.New("mlablocate: empty response")
+
+	// ErrUnsupportedVersion indicates that the client's version is not supported
+	ErrUnsupportedVersion = errors.New("mlablocate: unsupported API version")
+)
+
 // Client is a locate.measurementlab.net client. Please use the
 // NewClient factory to construct a new instance of client, otherwise
 // you MUST fill all the fields marked as MANDATORY.
@@ -34,16 +79,39 @@ type Client struct {
 
 	// UserAgent is the MANDATORY user-agent to use.
 	UserAgent string
+
+	// Version is the OPTIONAL version of the locate API used
+	// by Query. When it is zero, Query uses V1.
+	Version Version
+
+	// Params contains the OPTIONAL query parameters.
+	Params Params
+
+	// KVStore is the OPTIONAL key-value store where Query caches the
+	// last servers returned by V1 for each tool and Params, which it returns when
+	// it cannot reach the locate service. We don't cache the servers
+	// returned by V2, because their access tokens expire quickly, hence
+	// V2 queries, including QueryNDT7, have no cached fallback.
+	KVStore model.KeyValueStore
+
+	// RetryPolicy is the OPTIONAL policy for retrying queries
+	// failing with transient errors. When nil, we don't retry.
+	RetryPolicy *RetryPolicy
+
+	// Clock is the OPTIONAL clock used to wait before retrying. When
+	// nil, we use model.SystemClock.
+	Clock model.Clock
 }
 
 // NewClient creates a new locate.measurementlab.net client.
 func NewClient(httpClient model.HTTPClient, logger model.DebugLogger, userAgent string) *Client {
 	return &Client{
-		HTTPClient: httpClient,
-		Hostname:   "locate.measurementlab.net",
-		Logger:     logger,
-		Scheme:     "https",
-		UserAgent:  userAgent,
+		HTTPClient:  httpClient,
+		Hostname:    "locate.measurementlab.net",
+		Logger:      logger,
+		Scheme:      "https",
+		UserAgent:   userAgent,
+		RetryPolicy: &RetryPolicy{},
 	}
 }
 
@@ -54,45 +122,367 @@ type Result struct {
 
 	// Site is the ID of the site where the server is.
 	Site string `json:"site"`
+
+	// City is the city where the server is.
+	City string `json:"city"`
+
+	// Country is the country where the server is.
+	Country string `json:"country"`
+
+	// Metro is the metro area where the server is, which consists
+	// of the first three letters of the site (e.g., "mil").
+	Metro string `json:"metro,omitempty"`
+
+	// URLs maps the tool's URL templates (e.g., "wss:///ndt/v7/download")
+	// to the URLs to use, which contain the access tokens. Only v2
+	// returns URLs: you MUST use them rather than the FQDN.
+	URLs map[string]string `json:"urls,omitempty"`
+}
+
+// Query performs a locate.measurementlab.net query for the given tool
+// using the client's Version. With V1, the tool is the path of the
+// query (e.g., "neubot/dash"). With V2, the tool is the service name
+// (e.g., "neubot/dash" or "ndt/ndt7"). This function returns either
+// at least one result ranked by the locate service, which puts the
+// nearest servers first, on success, or an error, on failure.
+func (c *Client) Query(ctx context.Context, tool string) ([]Result, error) {
+	switch c.Version {
+	case 0, V1:
+		return c.queryV1(ctx, tool)
+	case V2:
+		return c.queryV2(ctx, tool)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, c.Version)
+	}
+}
+
+// queryV1 performs a query using v1 of the locate protocol and returns
+// at least one result on success. When the query fails, we return the
+// cached results of the last successful query, if any.
+func (c *Client) queryV1(ctx context.Context, tool string) ([]Result, error) {
+	query, err := c.Params.v1Query()
+	if err != nil {
+		return nil, err
+	}
+	results, err := c.doQueryV1(ctx, tool, query)
+	if err != nil {
+		if cached, found := c.readCache(tool, query); found {
+			c.Logger.Debugf("mlablocate: %s; using cached servers", err.Error())
+			return cached, nil
+		}
+		return nil, err
+	}
+	c.writeCache(tool, query, results)
+	return results, nil
+}
+
+// doQueryV1 implements queryV1.
+func (c *Client) doQueryV1(ctx context.Context, tool string, query url.Values) ([]Result, error) {
+	var raw json.RawMessage
+	if err := c.get(ctx, tool, query, &raw); err != nil {
+		return nil, err
+	}
+	// With the "geo_options" policy, v1 returns a list of servers, while
+	// it returns a single server with all the other policies.
+	var response []Result
+	if data := bytes.TrimSpace(raw); len(data) > 0 && data[0] == '{' {
+		response = append(response, Result{})
+		if err := json.Unmarshal(data, &response[0]); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, result := range response {
+		if result.FQDN == "" {
+			continue
+		}
+		result.Metro = metro(result.Site)
+		results = append(results, result)
+	}
+	if len(results) <= 0 {
+		return nil, ErrEmptyFQDN
+	}
+	return results, nil
+}
+
+// metro returns the metro area of the given site.
+func metro(site string) string {
+	if len(site) < 3 {
+		return ""
+	}
+	return site[:3]
+}
+
+// v2Location is the location of a server returned by v2.
+type v2Location struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+// v2Entry describes one of the boxes returned by v2 of
+// the locate service. It gives you the FQDN of the specific
+// box along with URLs for each experiment phase. You MUST
+// use the URLs directly because they contain access tokens.
+type v2Entry struct {
+	Machine  string            `json:"machine"`
+	Location v2Location        `json:"location"`
+	URLs     map[string]string `json:"urls"`
+}
+
+var (
+	// siteRegexp is the regexp to extract the site from the
+	// machine name when the domain is a v2 domain.
+	//
+	// Example: mlab3-mil04.mlab-oti.measurement-lab.org.
+	siteRegexp = regexp.MustCompile(
+		`^(mlab[1-4]d?)-([a-z]{3}[0-9tc]{2})\.([a-z0-9-]{1,16})\.(measurement-lab\.org)$`)
+)
+
+// Site returns the site name. If it is not possible to determine
+// the site name, we return the empty string.
+func (e v2Entry) Site() string {
+	m := siteRegexp.FindAllStringSubmatch(e.Machine, -1)
+	if len(m) != 1 || len(m[0]) != 5 {
+		return ""
+	}
+	return m[0][2]
+}
+
+// FQDN returns the FQDN of the server. We extract it from the URLs,
+// because the service's FQDN (e.g., ndt-mlab3-mil04.mlab-oti.measurement-lab.org)
+// differs from the machine name, and fall back to the machine name.
+func (e v2Entry) FQDN() string {
+	var keys []string
+	for key := range e.URLs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // be deterministic
+	for _, key := range keys {
+		if URL, err := url.Parse(e.URLs[key]); err == nil && URL.Hostname() != "" {
+			return URL.Hostname()
+		}
+	}
+	return e.Machine
+}
+
+// v2Response is a result of a v2 query to locate.measurementlab.net.
+type v2Response struct {
+	Results []v2Entry `json:"results"`
+}
+
+// queryV2 performs a query for the given service using v2 of
+// the locate protocol and returns at least one result on success.
+func (c *Client) queryV2(ctx context.Context, service string) ([]Result, error) {
+	query, err := c.Params.v2Query()
+	if err != nil {
+		return nil, err
+	}
+	var response v2Response
+	if err := c.get(ctx, "v2/nearest/"+service, query, &response); err != nil {
+		return nil, err
+	}
+	var results []Result
+	for _, entry := range response.Results {
+		site := entry.Site()
+		if c.Params.Site != "" && site != c.Params.Site {
+			continue // the user asked for a specific site
+		}
+		results = append(results, Result{
+			FQDN:    entry.FQDN(),
+			Site:    site,
+			City:    entry.Location.City,
+			Country: entry.Location.Country,
+			Metro:   metro(site),
+			URLs:    entry.URLs,
+		})
+	}
+	if len(results) <= 0 {
+		return nil, ErrEmptyResponse
+	}
+	return results, nil
+}
+
+// get GETs the given path with the OPTIONAL query and unmarshals
+// the JSON response body into v. We retry transient failures
+// according to the client's RetryPolicy, if any.
+func (c *Client) get(ctx context.Context, path string, query url.Values, v interface{}) error {
+	for attempt := 1; ; attempt++ {
+		err := c.doGet(ctx, path, query, v)
+		if err == nil || c.RetryPolicy == nil || !c.RetryPolicy.shouldRetry(attempt, err) {
+			return err
+		}
+		delay := c.RetryPolicy.delay(attempt)
+		c.Logger.Debugf("mlablocate: %s; retrying in %s", err.Error(), delay)
+		if err := retrySleep(ctx, model.ClockOrSystemClock(c.Clock), delay); err != nil {
+			return err
+		}
+	}
 }
 
-// Query performs a locate.measurementlab.net query. This function returns
-// either valid result, on success, or an error, on failure.
-// (Note thay you cannot use this API to query for ndt7 servers. You should
-// use the mlablocatev2 API to obtain such servers.)
-func (c *Client) Query(ctx context.Context, tool string) (Result, error) {
+// doGet implements get.
+func (c *Client) doGet(ctx context.Context, path string, query url.Values, v interface{}) error {
 	// TODO(bassosimone): this code should probably be
 	// refactored to use the httpx package.
 	URL := &url.URL{
-		Scheme: c.Scheme,
-		Host:   c.Hostname,
-		Path:   tool,
+		Scheme:   c.Scheme,
+		Host:     c.Hostname,
+		Path:     path,
+		RawQuery: query.Encode(),
 	}
 	req, err := http.NewRequestWithContext(ctx, "GET", URL.String(), nil)
 	if err != nil {
-		return Result{}, err
+		return err
 	}
 	req.Header.Add("User-Agent", c.UserAgent)
 	c.Logger.Debugf("mlablocate: GET %s", URL.String())
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return Result{}, err
+		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
-		return Result{}, fmt.Errorf("mlablocate: non-200 status code: %d", resp.StatusCode)
+		return &statusCodeError{StatusCode: resp.StatusCode}
 	}
 	data, err := netxlite.ReadAllContext(ctx, resp.Body)
 	if err != nil {
-		return Result{}, err
+		return err
 	}
 	c.Logger.Debugf("mlablocate: %s", string(data))
-	var result Result
-	if err := json.Unmarshal(data, &result); err != nil {
-		return Result{}, err
+	return json.Unmarshal(data, v)
+}
+
+// NDT7Result is the result of a locate services query for ndt7.
+type NDT7Result struct {
+	// Hostname is an informative field containing the hostname
+	// to which you're connected. Because there are access tokens,
+	// you cannot use this field directly.
+	Hostname string
+
+	// Site is an informative field containing the site
+	// to which the server belongs to.
+	Site string
+
+	// WSSDownloadURL is the WebSocket URL to be used for
+	// performing a download over HTTPS. Note that the URL
+	// typically includes the required access token.
+	WSSDownloadURL string
+
+	// WSSUploadURL is like WSSDownloadURL but for the upload.
+	WSSUploadURL string
+}
+
+// QueryNDT7 performs a locate services query for ndt7. Because v1
+// cannot serve ndt7, this function always uses V2 regardless of
+// the client's Version. Consequently, this function never falls
+// back to cached servers (see KVStore) when it fails.
+func (c *Client) QueryNDT7(ctx context.Context) ([]NDT7Result, error) {
+	results, err := c.queryV2(ctx, ndt7Service)
+	if err != nil {
+		return nil, err
+	}
+	var out []NDT7Result
+	for _, entry := range results {
+		r := NDT7Result{
+			Site:           entry.Site,
+			WSSDownloadURL: entry.URLs["wss:///ndt/v7/download"],
+			WSSUploadURL:   entry.URLs["wss:///ndt/v7/upload"],
+		}
+		if r.WSSDownloadURL == "" || r.WSSUploadURL == "" {
+			continue
+		}
+		// Implementation note: we extract the hostname from the
+		// download URL, under the assumption that the download and
+		// the upload URLs have the same hostname.
+		URL, err := url.Parse(r.WSSDownloadURL)
+		if err != nil {
+			continue
+		}
+		r.Hostname = URL.Hostname()
+		out = append(out, r)
+	}
+	if len(out) <= 0 {
+		return nil, ErrEmptyResponse
+	}
+	return out, nil
+}
+
+// Candidate is a server whose latency we probed using Fastest. Because
+// probing sends requests to the servers, experiments using Fastest
+// SHOULD include the candidates in their test keys.
+type Candidate struct {
+	// FQDN is the server's FQDN.
+	FQDN string `json:"fqdn"`
+
+	// Latency is the latency in seconds, if the probe succeeded.
+	Latency float64 `json:"latency,omitempty"`
+
+	// Failure is the error occurred when probing, if any.
+	Failure *string `json:"failure"`
+}
+
+// Fastest probes in parallel the latency to the first n of the given
+// servers, which are ranked by the locate service, and returns the index
+// of the server with the lowest latency along with the probed candidates.
+// We measure the latency as the time to receive the response headers of a
+// GET for the server's root using the client's HTTPClient, such that we
+// honour its proxy, if any. When all the probes fail, we return zero, i.e.,
+// the nearest server. When n is less than two, we do not probe.
+func (c *Client) Fastest(ctx context.Context, fqdns []string, n int) (int, []Candidate) {
+	if n > len(fqdns) {
+		n = len(fqdns)
+	}
+	if n <= 1 {
+		return 0, nil
+	}
+	candidates := make([]Candidate, n)
+	wg := &sync.WaitGroup{}
+	for idx := 0; idx < n; idx++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			candidates[idx].FQDN = fqdns[idx]
+			latency, err := c.probe(ctx, fqdns[idx])
+			if err != nil {
+				c.Logger.Debugf("mlablocate: probe %s: %s", fqdns[idx], err.Error())
+				failure := err.Error()
+				candidates[idx].Failure = &failure
+				return
+			}
+			candidates[idx].Latency = latency.Seconds()
+		}(idx)
+	}
+	wg.Wait()
+	best := 0
+	for idx, candidate := range candidates {
+		if candidate.Failure != nil {
+			continue
+		}
+		if candidates[best].Failure != nil || candidate.Latency < candidates[best].Latency {
+			best = idx
+		}
+	}
+	c.Logger.Debugf("mlablocate: fastest server: %s", fqdns[best])
+	return best, candidates
+}
+
+// probe returns the latency to the server with the given FQDN.
+func (c *Client) probe(ctx context.Context, fqdn string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	URL := &url.URL{Scheme: c.Scheme, Host: fqdn, Path: "/"}
+	req, err := http.NewRequestWithContext(ctx, "GET", URL.String(), nil)
+	if err != nil {
+		return 0, err
 	}
-	if result.FQDN == "" {
-		return Result{}, errors.New("mlablocate: returned empty FQDN")
+	req.Header.Add("User-Agent", c.UserAgent)
+	start := time.Now()
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
 	}
-	return result, nil
+	latency := time.Since(start)
+	resp.Body.Close() // we only care about the headers
+	return latency, nil
 }
@@ -2,13 +2,23 @@ package mlablocate
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/apex/log"
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
 )
 
 func TestSuccess(t *testing.T) {
@@ -21,8 +31,13 @@ func TestSuccess(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result.FQDN == "" {
-		t.Fatal("unexpected empty fqdn")
+	if len(result) <= 0 {
+		t.Fatal("unexpected empty result")
+	}
+	for _, entry := range result {
+		if entry.FQDN == "" {
+			t.Fatal("unexpected empty fqdn")
+		}
 	}
 }
 
@@ -36,8 +51,8 @@ func Test404Response(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "mlablocate: non-200 status code") {
 		t.Fatal("not the error we expected")
 	}
-	if result.FQDN != "" {
-		t.Fatal("expected empty fqdn")
+	if result != nil {
+		t.Fatal("expected nil result")
 	}
 }
 
@@ -52,8 +67,8 @@ func TestNewRequestFailure(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "invalid URL escape") {
 		t.Fatal("not the error we expected")
 	}
-	if result.FQDN != "" {
-		t.Fatal("expected empty fqdn")
+	if result != nil {
+		t.Fatal("expected nil result")
 	}
 }
 
@@ -71,8 +86,8 @@ func TestHTTPClientDoFailure(t *testing.T) {
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
-	if result.FQDN != "" {
-		t.Fatal("expected empty fqdn")
+	if result != nil {
+		t.Fatal("expected nil result")
 	}
 }
 
@@ -98,8 +113,8 @@ func TestCannotReadBody(t *testing.T) {
 	if !errors.Is(err, expected) {
 		t.Fatal("not the error we expected")
 	}
-	if result.FQDN != "" {
-		t.Fatal("expected empty fqdn")
+	if result != nil {
+		t.Fatal("expected nil result")
 	}
 }
 
@@ -139,8 +154,8 @@ func TestInvalidJSON(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "unexpected end of JSON input") {
 		t.Fatal("not the error we expected")
 	}
-	if result.FQDN != "" {
-		t.Fatal("expected empty fqdn")
+	if result != nil {
+		t.Fatal("expected nil result")
 	}
 }
 
@@ -177,11 +192,11 @@ func TestEmptyFQDN(t *testing.T) {
 		Transport: &emptyFQDN{},
 	}
 	result, err := client.Query(context.Background(), "nonexistent")
-	if err == nil || !strings.HasSuffix(err.Error(), "returned empty FQDN") {
+	if !errors.Is(err, ErrEmptyFQDN) {
 		t.Fatal("not the error we expected")
 	}
-	if result.FQDN != "" {
-		t.Fatal("expected empty fqdn")
+	if result != nil {
+		t.Fatal("expected nil result")
 	}
 }
 
@@ -197,9 +212,729 @@ func (txp *emptyFQDN) RoundTrip(*http.Request) (*http.Response, error) {
 type emptyFQDNBody struct{}
 
 func (b *emptyFQDNBody) Read(p []byte) (int, error) {
-	return copy(p, []byte(`{"fqdn":""}`)), io.EOF
+	return copy(p, []byte(`[{"fqdn":""}]`)), io.EOF
 }
 
 func (b *emptyFQDNBody) Close() error {
 	return nil
 }
+
+func TestQueryV1(t *testing.T) {
+	client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+	client.HTTPClient = &http.Client{
+		Transport: FakeTransport{
+			Func: func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path != "/neubot" || req.URL.Query().Get("policy") != "geo_options" {
+					return nil, errors.New("unexpected URL")
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Body: FakeBody{
+						Data: []byte(`[{"fqdn":"neubot.mlab.mlab1.mil04.measurement-lab.org","site":"mil04","city":"Milan","country":"IT"},` +
+							`{"fqdn":""},` +
+							`{"fqdn":"neubot.mlab.mlab1.trn01.measurement-lab.org","site":"trn01","city":"Turin","country":"IT"}]`),
+						Err: io.EOF,
+					},
+				}, nil
+			},
+		},
+	}
+	result, err := client.Query(context.Background(), "neubot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 {
+		t.Fatal("unexpected result", result)
+	}
+	if result[0].FQDN != "neubot.mlab.mlab1.mil04.measurement-lab.org" || result[0].Metro != "mil" {
+		t.Fatal("unexpected first result", result[0])
+	}
+	if result[1].FQDN != "neubot.mlab.mlab1.trn01.measurement-lab.org" || result[1].City != "Turin" {
+		t.Fatal("unexpected second result", result[1])
+	}
+}
+
+func TestQueryV2(t *testing.T) {
+	t.Run("with success", func(t *testing.T) {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.Version = V2
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Func: func(req *http.Request) (*http.Response, error) {
+					if req.URL.Path != "/v2/nearest/neubot/dash" {
+						return nil, errors.New("unexpected path")
+					}
+					return &http.Response{
+						StatusCode: 200,
+						Body: FakeBody{
+							Data: []byte(`{"results":[{"machine":"mlab3-mil04.mlab-oti.measurement-lab.org",` +
+								`"location":{"city":"Milan","country":"IT"},` +
+								`"urls":{"https:///negotiate/dash":"https://neubot-mlab3-mil04.mlab-oti.measurement-lab.org/negotiate/dash?access_token=x"}}]}`),
+							Err: io.EOF,
+						},
+					}, nil
+				},
+			},
+		}
+		result, err := client.Query(context.Background(), "neubot/dash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 1 {
+			t.Fatal("unexpected result", result)
+		}
+		if result[0].FQDN != "neubot-mlab3-mil04.mlab-oti.measurement-lab.org" {
+			t.Fatal("unexpected FQDN", result[0].FQDN)
+		}
+		if result[0].Site != "mil04" || result[0].Metro != "mil" || result[0].City != "Milan" || result[0].Country != "IT" {
+			t.Fatal("unexpected result", result[0])
+		}
+		if len(result[0].URLs) != 1 {
+			t.Fatal("unexpected URLs", result[0].URLs)
+		}
+	})
+
+	t.Run("with empty response", func(t *testing.T) {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.Version = V2
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Resp: &http.Response{
+					StatusCode: 200,
+					Body: FakeBody{
+						Err:  io.EOF,
+						Data: []byte(`{}`),
+					},
+				},
+			},
+		}
+		result, err := client.Query(context.Background(), "neubot/dash")
+		if !errors.Is(err, ErrEmptyResponse) {
+			t.Fatal("not the error we expected")
+		}
+		if result != nil {
+			t.Fatal("expected nil result")
+		}
+	})
+
+	t.Run("with unsupported version", func(t *testing.T) {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.Version = 3
+		result, err := client.Query(context.Background(), "neubot/dash")
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatal("not the error we expected")
+		}
+		if result != nil {
+			t.Fatal("expected nil result")
+		}
+	})
+}
+
+func TestQueryNDT7(t *testing.T) {
+	t.Run("with success", func(t *testing.T) {
+		// this test is ~0.5 s, so we can always run it
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		result, err := client.QueryNDT7(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) <= 0 {
+			t.Fatal("unexpected empty result")
+		}
+		for _, entry := range result {
+			if entry.Hostname == "" {
+				t.Fatal("expected non empty Hostname here")
+			}
+			if entry.Site == "" {
+				t.Fatal("expected non-empty Site here")
+			}
+			if entry.WSSDownloadURL == "" {
+				t.Fatal("expected non-empty WSSDownloadURL here")
+			}
+			if _, err := url.Parse(entry.WSSDownloadURL); err != nil {
+				t.Fatal(err)
+			}
+			if entry.WSSUploadURL == "" {
+				t.Fatal("expected non-empty WSSUploadURL here")
+			}
+			if _, err := url.Parse(entry.WSSUploadURL); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("with empty response", func(t *testing.T) {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Resp: &http.Response{
+					StatusCode: 200,
+					Body: FakeBody{
+						Err:  io.EOF,
+						Data: []byte(`{}`),
+					},
+				},
+			},
+		}
+		result, err := client.QueryNDT7(context.Background())
+		if !errors.Is(err, ErrEmptyResponse) {
+			t.Fatal("not the error we expected")
+		}
+		if result != nil {
+			t.Fatal("expected nil results")
+		}
+	})
+
+	t.Run("when the query fails", func(t *testing.T) {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Resp: &http.Response{
+					StatusCode: 404,
+					Body:       FakeBody{Err: io.EOF},
+				},
+			},
+		}
+		result, err := client.QueryNDT7(context.Background())
+		if !errors.Is(err, ErrRequestFailed) {
+			t.Fatal("not the error we expected")
+		}
+		if result != nil {
+			t.Fatal("expected nil results")
+		}
+	})
+
+	t.Run("with invalid URLs", func(t *testing.T) {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Resp: &http.Response{
+					StatusCode: 200,
+					Body: FakeBody{
+						Data: []byte(
+							`{"results":[{"machine":"mlab3-mil04.mlab-oti.measurement-lab.org","urls":{"wss:///ndt/v7/download":":","wss:///ndt/v7/upload":":"}}]}`),
+						Err: io.EOF,
+					},
+				},
+			},
+		}
+		result, err := client.QueryNDT7(context.Background())
+		if !errors.Is(err, ErrEmptyResponse) {
+			t.Fatal("not the error we expected")
+		}
+		if result != nil {
+			t.Fatal("expected nil results")
+		}
+	})
+
+	t.Run("with empty URLs", func(t *testing.T) {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Resp: &http.Response{
+					StatusCode: 200,
+					Body: FakeBody{
+						Data: []byte(
+							`{"results":[{"machine":"mlab3-mil04.mlab-oti.measurement-lab.org","urls":{"wss:///ndt/v7/download":"","wss:///ndt/v7/upload":""}}]}`),
+						Err: io.EOF,
+					},
+				},
+			},
+		}
+		result, err := client.QueryNDT7(context.Background())
+		if !errors.Is(err, ErrEmptyResponse) {
+			t.Fatal("not the error we expected")
+		}
+		if result != nil {
+			t.Fatal("expected nil results")
+		}
+	})
+}
+
+func TestV2EntrySite(t *testing.T) {
+	tests := []struct {
+		name    string
+		machine string
+		want    string
+	}{{
+		name:    "with invalid machine name",
+		machine: "ndt-iupui-mlab3-mil02.mlab-oti.measurement-lab.org",
+		want:    "",
+	}, {
+		name:    "with valid machine name",
+		machine: "mlab3-mil04.mlab-oti.measurement-lab.org",
+		want:    "mil04",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := v2Entry{Machine: tt.machine}
+			if got := e.Site(); got != tt.want {
+				t.Errorf("v2Entry.Site() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestV2EntryFQDN(t *testing.T) {
+	t.Run("with URLs", func(t *testing.T) {
+		e := v2Entry{
+			Machine: "mlab3-mil04.mlab-oti.measurement-lab.org",
+			URLs: map[string]string{
+				"wss:///ndt/v7/download": "wss://ndt-mlab3-mil04.mlab-oti.measurement-lab.org/ndt/v7/download?access_token=x",
+			},
+		}
+		if got := e.FQDN(); got != "ndt-mlab3-mil04.mlab-oti.measurement-lab.org" {
+			t.Fatal("unexpected FQDN", got)
+		}
+	})
+
+	t.Run("without URLs", func(t *testing.T) {
+		e := v2Entry{Machine: "mlab3-mil04.mlab-oti.measurement-lab.org"}
+		if got := e.FQDN(); got != e.Machine {
+			t.Fatal("unexpected FQDN", got)
+		}
+	})
+}
+
+func TestFastest(t *testing.T) {
+	newServer := func(delay time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.WriteHeader(404)
+		}))
+	}
+	slow := newServer(300 * time.Millisecond)
+	defer slow.Close()
+	fast := newServer(0)
+	defer fast.Close()
+	hostname := func(server *httptest.Server) string {
+		URL, err := url.Parse(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return URL.Host
+	}
+	newClient := func() *Client {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.Scheme = "http"
+		return client
+	}
+
+	t.Run("we select the server with the lowest latency", func(t *testing.T) {
+		fqdns := []string{hostname(slow), hostname(fast)}
+		idx, candidates := newClient().Fastest(context.Background(), fqdns, DefaultCandidates)
+		if idx != 1 {
+			t.Fatal("unexpected index", idx)
+		}
+		if len(candidates) != 2 || candidates[0].FQDN != fqdns[0] || candidates[1].FQDN != fqdns[1] {
+			t.Fatal("unexpected candidates", candidates)
+		}
+		for _, candidate := range candidates {
+			if candidate.Failure != nil || candidate.Latency <= 0 {
+				t.Fatal("unexpected candidate", candidate)
+			}
+		}
+		if candidates[0].Latency < candidates[1].Latency {
+			t.Fatal("expected the slow server to have a higher latency")
+		}
+	})
+
+	t.Run("we only probe the first n servers", func(t *testing.T) {
+		fqdns := []string{hostname(slow), "\t", hostname(fast)}
+		idx, candidates := newClient().Fastest(context.Background(), fqdns, 2)
+		if idx != 0 {
+			t.Fatal("unexpected index", idx)
+		}
+		if len(candidates) != 2 || candidates[1].Failure == nil {
+			t.Fatal("unexpected candidates", candidates)
+		}
+	})
+
+	t.Run("we select the nearest server when all probes fail", func(t *testing.T) {
+		client := newClient()
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{Err: errors.New("mocked error")},
+		}
+		fqdns := []string{hostname(slow), hostname(fast)}
+		idx, candidates := client.Fastest(context.Background(), fqdns, DefaultCandidates)
+		if idx != 0 {
+			t.Fatal("unexpected index", idx)
+		}
+		for _, candidate := range candidates {
+			if candidate.Failure == nil || candidate.Latency != 0 {
+				t.Fatal("unexpected candidate", candidate)
+			}
+		}
+	})
+
+	t.Run("we do not probe a single server", func(t *testing.T) {
+		idx, candidates := newClient().Fastest(context.Background(), []string{"\t"}, DefaultCandidates)
+		if idx != 0 || candidates != nil {
+			t.Fatal("unexpected result", idx, candidates)
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	newClient := func(statusCodes ...int) (*Client, *int) {
+		var attempts int
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.RetryPolicy = &RetryPolicy{InitialDelay: time.Millisecond}
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Func: func(req *http.Request) (*http.Response, error) {
+					code := statusCodes[attempts]
+					attempts++
+					return &http.Response{
+						StatusCode: code,
+						Body: FakeBody{
+							Data: []byte(`[{"fqdn":"neubot.mlab.mlab1.mil04.measurement-lab.org","site":"mil04"}]`),
+							Err:  io.EOF,
+						},
+					}, nil
+				},
+			},
+		}
+		return client, &attempts
+	}
+
+	t.Run("we retry 5xx responses", func(t *testing.T) {
+		client, attempts := newClient(503, 502, 200)
+		result, err := client.Query(context.Background(), "neubot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 1 || *attempts != 3 {
+			t.Fatal("unexpected result", result, *attempts)
+		}
+	})
+
+	t.Run("we wait using the clock", func(t *testing.T) {
+		client, attempts := newClient(503, 200)
+		client.RetryPolicy = &RetryPolicy{InitialDelay: time.Hour, MaxDelay: 2 * time.Hour}
+		clock := &instantClock{}
+		client.Clock = clock
+		if _, err := client.Query(context.Background(), "neubot"); err != nil {
+			t.Fatal(err)
+		}
+		if *attempts != 2 || len(clock.delays) != 1 || clock.delays[0] < 30*time.Minute {
+			t.Fatal("unexpected state", *attempts, clock.delays)
+		}
+	})
+
+	t.Run("we stop after the maximum number of attempts", func(t *testing.T) {
+		client, attempts := newClient(503, 503, 503, 200)
+		_, err := client.Query(context.Background(), "neubot")
+		if !errors.Is(err, ErrRequestFailed) {
+			t.Fatal("not the error we expected", err)
+		}
+		if *attempts != defaultRetryMaxAttempts {
+			t.Fatal("unexpected number of attempts", *attempts)
+		}
+	})
+
+	t.Run("we don't retry 4xx responses", func(t *testing.T) {
+		client, attempts := newClient(404, 200)
+		_, err := client.Query(context.Background(), "neubot")
+		if !errors.Is(err, ErrRequestFailed) {
+			t.Fatal("not the error we expected", err)
+		}
+		if *attempts != 1 {
+			t.Fatal("unexpected number of attempts", *attempts)
+		}
+	})
+
+	t.Run("we retry network errors", func(t *testing.T) {
+		for _, failure := range []error{
+			&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED},
+			&net.OpError{Op: "read", Err: syscall.ECONNRESET},
+			netxlite.NewTopLevelGenericErrWrapper(io.ErrUnexpectedEOF),
+		} {
+			client, attempts := newClient(503, 200)
+			client.HTTPClient = &http.Client{
+				Transport: FakeTransport{
+					Func: func(req *http.Request) (*http.Response, error) {
+						*attempts++
+						if *attempts == 1 {
+							return nil, failure
+						}
+						return &http.Response{
+							StatusCode: 200,
+							Body: FakeBody{
+								Data: []byte(`[{"fqdn":"neubot.mlab.mlab1.mil04.measurement-lab.org","site":"mil04"}]`),
+								Err:  io.EOF,
+							},
+						}, nil
+					},
+				},
+			}
+			result, err := client.Query(context.Background(), "neubot")
+			if err != nil {
+				t.Fatal(failure, err)
+			}
+			if len(result) != 1 || *attempts != 2 {
+				t.Fatal("unexpected result", failure, result, *attempts)
+			}
+		}
+	})
+
+	t.Run("we don't retry invalid responses", func(t *testing.T) {
+		if isTransientFailure(&json.SyntaxError{}) || isTransientFailure(&url.Error{Err: errors.New("x")}) {
+			t.Fatal("expected a non transient failure")
+		}
+	})
+
+	t.Run("we don't retry without a policy", func(t *testing.T) {
+		client, attempts := newClient(503, 200)
+		client.RetryPolicy = nil
+		_, err := client.Query(context.Background(), "neubot")
+		if !errors.Is(err, ErrRequestFailed) {
+			t.Fatal("not the error we expected", err)
+		}
+		if *attempts != 1 {
+			t.Fatal("unexpected number of attempts", *attempts)
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	newClient := func(kvs model.KeyValueStore, err error) *Client {
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.KVStore = kvs
+		client.RetryPolicy = nil
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Err: err,
+				Resp: &http.Response{
+					StatusCode: 200,
+					Body: FakeBody{
+						Data: []byte(`[{"fqdn":"neubot.mlab.mlab1.mil04.measurement-lab.org","site":"mil04"}]`),
+						Err:  io.EOF,
+					},
+				},
+			},
+		}
+		return client
+	}
+	expected := errors.New("mocked error")
+
+	t.Run("we use the cached servers when the query fails", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		if _, err := newClient(kvs, nil).Query(context.Background(), "neubot"); err != nil {
+			t.Fatal(err)
+		}
+		result, err := newClient(kvs, expected).Query(context.Background(), "neubot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 1 || result[0].FQDN != "neubot.mlab.mlab1.mil04.measurement-lab.org" || result[0].Metro != "mil" {
+			t.Fatal("unexpected result", result)
+		}
+	})
+
+	t.Run("we don't use stale cached servers", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		data, err := json.Marshal(&cacheEntry{
+			Time:    time.Now().Add(-2 * cacheMaxAge),
+			Results: []Result{{FQDN: "neubot.mlab.mlab1.mil04.measurement-lab.org"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		query, err := (&Params{}).v1Query()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := kvs.Set(cacheKey("neubot", query), data); err != nil {
+			t.Fatal(err)
+		}
+		result, err := newClient(kvs, expected).Query(context.Background(), "neubot")
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected", err)
+		}
+		if result != nil {
+			t.Fatal("expected nil result")
+		}
+	})
+
+	t.Run("we don't cache the servers returned by v2", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		client := newClient(kvs, nil)
+		client.Version = V2
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Resp: &http.Response{
+					StatusCode: 200,
+					Body: FakeBody{
+						Data: []byte(`{"results":[{"machine":"mlab3-mil04.mlab-oti.measurement-lab.org"}]}`),
+						Err:  io.EOF,
+					},
+				},
+			},
+		}
+		if _, err := client.Query(context.Background(), "neubot/dash"); err != nil {
+			t.Fatal(err)
+		}
+		keys, err := kvs.ListKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(keys) != 0 {
+			t.Fatal("expected no cached servers", keys)
+		}
+	})
+
+	t.Run("we cache the servers for each set of query parameters", func(t *testing.T) {
+		kvs := &kvstore.Memory{}
+		if _, err := newClient(kvs, nil).Query(context.Background(), "neubot"); err != nil {
+			t.Fatal(err)
+		}
+		client := newClient(kvs, expected)
+		client.Params.Metro = "trn"
+		result, err := client.Query(context.Background(), "neubot")
+		if !errors.Is(err, expected) {
+			t.Fatal("not the error we expected", err)
+		}
+		if result != nil {
+			t.Fatal("expected nil result")
+		}
+	})
+}
+
+func TestParams(t *testing.T) {
+	newClient := func(version Version, params Params, body string) (*Client, *url.Values) {
+		query := &url.Values{}
+		client := NewClient(http.DefaultClient, log.Log, "miniooni/0.1.0-dev")
+		client.Version = version
+		client.Params = params
+		client.HTTPClient = &http.Client{
+			Transport: FakeTransport{
+				Func: func(req *http.Request) (*http.Response, error) {
+					*query = req.URL.Query()
+					return &http.Response{
+						StatusCode: 200,
+						Body:       FakeBody{Data: []byte(body), Err: io.EOF},
+					}, nil
+				},
+			},
+		}
+		return client, query
+	}
+	const (
+		v1Single = `{"fqdn":"neubot.mlab.mlab1.trn01.measurement-lab.org","site":"trn01"}`
+		v2Body   = `{"results":[{"machine":"mlab1-trn01.mlab-oti.measurement-lab.org"}]}`
+	)
+
+	t.Run("with v1 and metro", func(t *testing.T) {
+		client, query := newClient(V1, Params{Metro: "trn", AddressFamily: "ipv6"}, v1Single)
+		result, err := client.Query(context.Background(), "neubot")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 1 || result[0].Metro != "trn" {
+			t.Fatal("unexpected result", result)
+		}
+		expect := url.Values{"policy": {"metro"}, "metro": {"trn"}, "address_family": {"ipv6"}}
+		if diff := cmp.Diff(expect, *query); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with v1 and country", func(t *testing.T) {
+		client, query := newClient(V1, Params{Country: "IT"}, v1Single)
+		if _, err := client.Query(context.Background(), "neubot"); err != nil {
+			t.Fatal(err)
+		}
+		expect := url.Values{"policy": {"country"}, "country": {"IT"}}
+		if diff := cmp.Diff(expect, *query); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with v1 and explicit policy", func(t *testing.T) {
+		client, query := newClient(V1, Params{Policy: "random"}, v1Single)
+		if _, err := client.Query(context.Background(), "neubot"); err != nil {
+			t.Fatal(err)
+		}
+		expect := url.Values{"policy": {"random"}}
+		if diff := cmp.Diff(expect, *query); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with v1 and region", func(t *testing.T) {
+		client, _ := newClient(V1, Params{Region: "US-NY"}, v1Single)
+		if _, err := client.Query(context.Background(), "neubot"); !errors.Is(err, ErrUnsupportedParam) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with v2 and region", func(t *testing.T) {
+		client, query := newClient(V2, Params{Country: "US", Region: "US-NY"}, v2Body)
+		if _, err := client.Query(context.Background(), "neubot/dash"); err != nil {
+			t.Fatal(err)
+		}
+		expect := url.Values{"country": {"US"}, "region": {"US-NY"}}
+		if diff := cmp.Diff(expect, *query); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with v2 and metro", func(t *testing.T) {
+		client, query := newClient(V2, Params{Metro: "trn"}, v2Body)
+		if _, err := client.Query(context.Background(), "neubot/dash"); err != nil {
+			t.Fatal(err)
+		}
+		expect := url.Values{"metro": {"trn"}}
+		if diff := cmp.Diff(expect, *query); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with v2 and policy", func(t *testing.T) {
+		client, _ := newClient(V2, Params{Policy: "random"}, v2Body)
+		if _, err := client.QueryNDT7(context.Background()); !errors.Is(err, ErrUnsupportedParam) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with v2 and address family", func(t *testing.T) {
+		client, _ := newClient(V2, Params{AddressFamily: "ipv4"}, v2Body)
+		if _, err := client.Query(context.Background(), "neubot/dash"); !errors.Is(err, ErrUnsupportedParam) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with v1 and site", func(t *testing.T) {
+		client, _ := newClient(V1, Params{Site: "trn01"}, v1Single)
+		if _, err := client.Query(context.Background(), "neubot"); !errors.Is(err, ErrUnsupportedParam) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+
+	t.Run("with v2 and site", func(t *testing.T) {
+		const body = `{"results":[
+			{"machine":"mlab1-mil04.mlab-oti.measurement-lab.org"},
+			{"machine":"mlab2-trn01.mlab-oti.measurement-lab.org"}
+		]}`
+		client, query := newClient(V2, Params{Site: "trn01"}, body)
+		result, err := client.Query(context.Background(), "neubot/dash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result) != 1 || result[0].FQDN != "mlab2-trn01.mlab-oti.measurement-lab.org" {
+			t.Fatal("unexpected result", result)
+		}
+		expect := url.Values{"site": {"trn01"}}
+		if diff := cmp.Diff(expect, *query); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with v2 and site without servers", func(t *testing.T) {
+		client, _ := newClient(V2, Params{Site: "mil04"}, v2Body)
+		if _, err := client.Query(context.Background(), "neubot/dash"); !errors.Is(err, ErrEmptyResponse) {
+			t.Fatal("not the error we expected", err)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package mlablocate
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrUnsupportedParam indicates that the client's version of the
+// locate API does not support one of the query parameters.
+var ErrUnsupportedParam = errors.New("mlablocate: unsupported query parameter")
+
+// Params contains the OPTIONAL parameters of the locate queries, which
+// allow to deliberately request servers in a specific location rather
+// than the servers nearest to the probe. The zero value requests the
+// nearest servers. Not all the versions of the locate API support all
+// the parameters: queries using an unsupported parameter fail with
+// ErrUnsupportedParam rather than silently ignoring it.
+type Params struct {
+	// AddressFamily is the OPTIONAL address family of the servers
+	// (i.e., "ipv4" or "ipv6"). Only V1 supports this parameter.
+	AddressFamily string
+
+	// Country is the OPTIONAL ISO 3166-1 alpha-2 country
+	// code of the servers (e.g., "IT").
+	Country string
+
+	// Metro is the OPTIONAL metro area of the servers, which
+	// consists of three letters (e.g., "mil").
+	Metro string
+
+	// Policy is the OPTIONAL V1 policy (e.g., "random"). When
+	// empty, we use "metro" if Metro is set, "country" if Country
+	// is set, and "geo_options", which returns several servers,
+	// otherwise. Only V1 supports this parameter.
+	Policy string
+
+	// Region is the OPTIONAL ISO 3166-2 region code of the
+	// servers (e.g., "US-NY"). Only V2 supports this parameter.
+	Region string
+
+	// Site is the OPTIONAL site of the servers (e.g., "mil04"). We
+	// discard the servers at other sites returned by the locate service,
+	// such that a query either returns servers at this site or fails
+	// with ErrEmptyResponse. Only V2 supports this parameter.
+	Site string
+}
+
+// v1Query returns the query to use with V1.
+func (p *Params) v1Query() (url.Values, error) {
+	if p.Region != "" {
+		return nil, fmt.Errorf("%w: region", ErrUnsupportedParam)
+	}
+	if p.Site != "" {
+		return nil, fmt.Errorf("%w: site", ErrUnsupportedParam)
+	}
+	query := url.Values{}
+	policy := p.Policy
+	if policy == "" {
+		switch {
+		case p.Metro != "":
+			policy = "metro"
+		case p.Country != "":
+			policy = "country"
+		default:
+			policy = v1Policy
+		}
+	}
+	query.Set("policy", policy)
+	setIfNotEmpty(query, "address_family", p.AddressFamily)
+	setIfNotEmpty(query, "country", p.Country)
+	setIfNotEmpty(query, "metro", p.Metro)
+	return query, nil
+}
+
+// v2Query returns the query to use with V2.
+func (p *Params) v2Query() (url.Values, error) {
+	if p.AddressFamily != "" {
+		return nil, fmt.Errorf("%w: address family", ErrUnsupportedParam)
+	}
+	if p.Policy != "" {
+		return nil, fmt.Errorf("%w: policy", ErrUnsupportedParam)
+	}
+	query := url.Values{}
+	setIfNotEmpty(query, "country", p.Country)
+	setIfNotEmpty(query, "metro", p.Metro)
+	setIfNotEmpty(query, "region", p.Region)
+	setIfNotEmpty(query, "site", p.Site)
+	return query, nil
+}
+
+// setIfNotEmpty sets the given key of query when value is not empty.
+func setIfNotEmpty(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
@@ -0,0 +1,139 @@
+package mlablocate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// RetryPolicy controls how we retry queries failing with transient
+// errors (i.e., network errors and 5xx responses). The zero value is
+// a valid policy using sensible defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the OPTIONAL maximum number of attempts. When
+	// zero or negative, we use a default value.
+	MaxAttempts int
+
+	// InitialDelay is the OPTIONAL delay before the first retry. When
+	// zero or negative, we use a default value. We double the delay
+	// after each failed attempt and we add some random jitter.
+	InitialDelay time.Duration
+
+	// MaxDelay is the OPTIONAL maximum delay between attempts. When
+	// zero or negative, we use a default value.
+	MaxDelay time.Duration
+}
+
+// These are the default values used by RetryPolicy.
+const (
+	defaultRetryMaxAttempts  = 3
+	defaultRetryInitialDelay = time.Second
+	defaultRetryMaxDelay     = 8 * time.Second
+)
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return defaultRetryMaxAttempts
+}
+
+func (p *RetryPolicy) initialDelay() time.Duration {
+	if p.InitialDelay > 0 {
+		return p.InitialDelay
+	}
+	return defaultRetryInitialDelay
+}
+
+func (p *RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay > 0 {
+		return p.MaxDelay
+	}
+	return defaultRetryMaxDelay
+}
+
+// delay returns the delay to wait after the given failed
+// attempt. The returned delay includes random jitter.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.initialDelay()
+	for idx := 1; idx < attempt && d < p.maxDelay(); idx++ {
+		d *= 2
+	}
+	if d > p.maxDelay() {
+		d = p.maxDelay()
+	}
+	// Use "equal jitter": wait at least half of the delay and
+	// randomize the other half to avoid synchronized retries.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// shouldRetry returns whether we should retry after the given
+// failed attempt, which failed with the given error.
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	return attempt < p.maxAttempts() && isTransientFailure(err)
+}
+
+// statusCodeError is the error returned when the
+// response status code is not "200 Ok".
+type statusCodeError struct {
+	StatusCode int
+}
+
+// Error implements error.Error.
+func (e *statusCodeError) Error() string {
+	return fmt.Sprintf("%s: %d", ErrRequestFailed.Error(), e.StatusCode)
+}
+
+// Is allows errors.Is(err, ErrRequestFailed) to work.
+func (e *statusCodeError) Is(target error) bool {
+	return target == ErrRequestFailed
+}
+
+// isTransientFailure returns whether err is a transient failure that is
+// worth retrying, i.e., a 5xx status or a network error (e.g., a timeout,
+// a connection reset or refused, or a connection closed while reading the
+// response). We don't retry 4xx statuses and invalid responses.
+func isTransientFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err // a *url.Error is a net.Error regardless of the cause
+	}
+	var statusErr *statusCodeError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 && statusErr.StatusCode <= 599
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var errWrapper *netxlite.ErrWrapper
+	return errors.As(err, &errWrapper)
+}
+
+// retrySleep sleeps for the given delay according to the given
+// clock or until the context is done, whichever happens first.
+func retrySleep(ctx context.Context, clock model.Clock, delay time.Duration) error {
+	timer := clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
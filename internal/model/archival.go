@@ -302,11 +302,12 @@ type ArchivalHTTPTor struct {
 //
 // See https://github.com/ooni/spec/blob/master/data-formats/df-008-netevents.md.
 type ArchivalNetworkEvent struct {
-	Address   string   `json:"address,omitempty"`
-	Failure   *string  `json:"failure"`
-	NumBytes  int64    `json:"num_bytes,omitempty"`
-	Operation string   `json:"operation"`
-	Proto     string   `json:"proto,omitempty"`
-	T         float64  `json:"t"`
-	Tags      []string `json:"tags,omitempty"`
+	Address       string   `json:"address,omitempty"`
+	DroppedEvents int64    `json:"dropped_events,omitempty"`
+	Failure       *string  `json:"failure"`
+	NumBytes      int64    `json:"num_bytes,omitempty"`
+	Operation     string   `json:"operation"`
+	Proto         string   `json:"proto,omitempty"`
+	T             float64  `json:"t"`
+	Tags          []string `json:"tags,omitempty"`
 }
@@ -0,0 +1,82 @@
+package model
+
+import "time"
+
+// Clock abstracts the passing of time, so that unit tests can
+// simulate timeouts and long runs without sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends
+	// the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer creates a new Timer that will send the current
+	// time on its channel after at least d has elapsed.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a time.Timer-like interface returned by Clock.
+type Timer interface {
+	// C returns the channel on which the timer sends the time.
+	C() <-chan time.Time
+
+	// Reset changes the timer to expire after d. It returns
+	// true if the timer had been active.
+	Reset(d time.Duration) bool
+
+	// Stop prevents the timer from firing. It returns true
+	// if the timer had been active.
+	Stop() bool
+}
+
+// SystemClock is the Clock using the time package.
+var SystemClock Clock = &systemClock{}
+
+// ClockOrSystemClock returns clock if it is not nil and
+// otherwise returns SystemClock.
+func ClockOrSystemClock(clock Clock) Clock {
+	if clock != nil {
+		return clock
+	}
+	return SystemClock
+}
+
+// systemClock implements Clock using the time package.
+type systemClock struct{}
+
+// Now implements Clock.Now.
+func (*systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements Clock.After.
+func (*systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// NewTimer implements Clock.NewTimer.
+func (*systemClock) NewTimer(d time.Duration) Timer {
+	return &systemTimer{time.NewTimer(d)}
+}
+
+// systemTimer implements Timer using a time.Timer.
+type systemTimer struct {
+	t *time.Timer
+}
+
+// C implements Timer.C.
+func (t *systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Reset implements Timer.Reset.
+func (t *systemTimer) Reset(d time.Duration) bool {
+	return t.t.Reset(d)
+}
+
+// Stop implements Timer.Stop.
+func (t *systemTimer) Stop() bool {
+	return t.t.Stop()
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSystemClock(t *testing.T) {
+	t.Run("Now", func(t *testing.T) {
+		before := time.Now()
+		now := SystemClock.Now()
+		if now.Before(before) || now.After(time.Now()) {
+			t.Fatal("unexpected time", now)
+		}
+	})
+
+	t.Run("After", func(t *testing.T) {
+		<-SystemClock.After(time.Millisecond)
+	})
+
+	t.Run("NewTimer", func(t *testing.T) {
+		timer := SystemClock.NewTimer(time.Hour)
+		if !timer.Stop() {
+			t.Fatal("expected the timer to be active")
+		}
+		if timer.Reset(time.Millisecond) {
+			t.Fatal("expected the timer to be stopped")
+		}
+		<-timer.C()
+	})
+}
+
+func TestClockOrSystemClock(t *testing.T) {
+	if ClockOrSystemClock(nil) != SystemClock {
+		t.Fatal("expected the system clock")
+	}
+	clock := &systemClock{}
+	if ClockOrSystemClock(clock) != clock {
+		t.Fatal("expected the given clock")
+	}
+}
@@ -17,7 +17,9 @@ type ExperimentSession interface {
 	FetchPsiphonConfig(ctx context.Context) ([]byte, error)
 	FetchTorTargets(ctx context.Context, cc string) (map[string]OOAPITorTarget, error)
 	FetchURLList(ctx context.Context, config OOAPIURLListConfig) ([]OOAPIURLInfo, error)
+	KeyValueStore() KeyValueStore
 	Logger() Logger
+	MemoryBudget() MemoryBudget
 	ProbeCC() string
 	ResolverIP() string
 	TempDir() string
@@ -27,6 +29,24 @@ type ExperimentSession interface {
 	UserAgent() string
 }
 
+// MemoryBudget bounds the memory used by the trace of a measurement, which
+// prevents long or large measurements from exhausting the memory of small
+// devices. A zero field means that there is no limit.
+type MemoryBudget struct {
+	// MaxTraceEvents is the maximum number of events in a trace.
+	MaxTraceEvents int
+
+	// MaxTraceDataBytes is the maximum number of bytes of data (e.g.,
+	// the HTTP body snapshots) carried by the events in a trace.
+	MaxTraceDataBytes int64
+}
+
+// DefaultMemoryBudget is the default memory budget of a session.
+var DefaultMemoryBudget = MemoryBudget{
+	MaxTraceEvents:    1 << 14,
+	MaxTraceDataBytes: 1 << 23,
+}
+
 // ExperimentAsyncTestKeys is the type of test keys returned by an experiment
 // when running in async fashion rather than in sync fashion.
 type ExperimentAsyncTestKeys struct {
@@ -74,6 +94,16 @@ type ExperimentMeasurerAsync interface {
 		callbacks ExperimentCallbacks) (<-chan *ExperimentAsyncTestKeys, error)
 }
 
+// ExperimentMeasurerWithRawData is a measurer whose spec requires raw
+// data. We only scrub the probe IP out of its measurements, ignoring the
+// other scrubbing rules (e.g., the user-configured strings).
+//
+// This functionality is optional.
+type ExperimentMeasurerWithRawData interface {
+	// RequiresRawData returns whether the measurer requires raw data.
+	RequiresRawData() bool
+}
+
 // ExperimentCallbacks contains experiment event-handling callbacks
 type ExperimentCallbacks interface {
 	// OnProgress provides information about an experiment progress.
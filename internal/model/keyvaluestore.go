@@ -14,4 +14,12 @@ type KeyValueStore interface {
 	// Set sets the value of the given key and returns
 	// whether the operation was successful or not.
 	Set(key string, value []byte) (err error)
+
+	// Delete removes the given key. Deleting a key that does
+	// not exist is not an error.
+	Delete(key string) (err error)
+
+	// ListKeys returns all the keys in the key-value store
+	// in lexicographic order.
+	ListKeys() (keys []string, err error)
 }
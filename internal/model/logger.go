@@ -1,5 +1,11 @@
 package model
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 //
 // Logger
 //
@@ -38,6 +44,102 @@ type Logger interface {
 	Warnf(format string, v ...interface{})
 }
 
+// LogFields contains the fields to attach to log lines (e.g., the
+// name of the experiment and the input being measured).
+type LogFields map[string]interface{}
+
+// FieldLogger is a Logger attaching fields to every log line, which
+// allows components to log their context without formatting it into
+// each message. See also LoggerWithFields.
+type FieldLogger interface {
+	// A FieldLogger is also a Logger.
+	Logger
+
+	// WithField returns a logger that also attaches the given field.
+	WithField(key string, value interface{}) FieldLogger
+
+	// WithFields returns a logger that also attaches the given fields.
+	WithFields(fields LogFields) FieldLogger
+}
+
+// LoggerWithFields returns a FieldLogger attaching the given fields to
+// every log line emitted using logger. When logger is not a FieldLogger,
+// the returned logger appends the fields to each message.
+func LoggerWithFields(logger Logger, fields LogFields) FieldLogger {
+	if fl, okay := logger.(FieldLogger); okay {
+		return fl.WithFields(fields)
+	}
+	return (&fieldsLogger{Logger: logger}).WithFields(fields)
+}
+
+// fieldsLogger is a FieldLogger appending the fields to each message.
+type fieldsLogger struct {
+	Logger
+	fields LogFields
+}
+
+// WithField implements FieldLogger.WithField
+func (fl *fieldsLogger) WithField(key string, value interface{}) FieldLogger {
+	return fl.WithFields(LogFields{key: value})
+}
+
+// WithFields implements FieldLogger.WithFields
+func (fl *fieldsLogger) WithFields(fields LogFields) FieldLogger {
+	merged := make(LogFields)
+	for key, value := range fl.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &fieldsLogger{Logger: fl.Logger, fields: merged}
+}
+
+// format appends the fields sorted by key to msg.
+func (fl *fieldsLogger) format(msg string) string {
+	var keys []string
+	for key := range fl.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var builder strings.Builder
+	builder.WriteString(msg)
+	for _, key := range keys {
+		fmt.Fprintf(&builder, " %s=%v", key, fl.fields[key])
+	}
+	return builder.String()
+}
+
+// Debug implements DebugLogger.Debug
+func (fl *fieldsLogger) Debug(msg string) {
+	fl.Logger.Debug(fl.format(msg))
+}
+
+// Debugf implements DebugLogger.Debugf
+func (fl *fieldsLogger) Debugf(format string, v ...interface{}) {
+	fl.Logger.Debug(fl.format(fmt.Sprintf(format, v...)))
+}
+
+// Info implements InfoLogger.Info
+func (fl *fieldsLogger) Info(msg string) {
+	fl.Logger.Info(fl.format(msg))
+}
+
+// Infof implements InfoLogger.Infof
+func (fl *fieldsLogger) Infof(format string, v ...interface{}) {
+	fl.Logger.Info(fl.format(fmt.Sprintf(format, v...)))
+}
+
+// Warn implements Logger.Warn
+func (fl *fieldsLogger) Warn(msg string) {
+	fl.Logger.Warn(fl.format(msg))
+}
+
+// Warnf implements Logger.Warnf
+func (fl *fieldsLogger) Warnf(format string, v ...interface{}) {
+	fl.Logger.Warn(fl.format(fmt.Sprintf(format, v...)))
+}
+
 // DiscardLogger is the default logger that discards its input
 var DiscardLogger Logger = logDiscarder{}
 
@@ -1,8 +1,11 @@
 package model
 
 import (
+	"fmt"
 	"io"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestDiscardLoggerWorksAsIntended(t *testing.T) {
@@ -31,3 +34,94 @@ func TestErrorToStringOrOK(t *testing.T) {
 		}
 	})
 }
+
+// recordingLogger is a Logger recording the emitted lines.
+type recordingLogger struct {
+	lines []string
+}
+
+func (rl *recordingLogger) Debug(msg string) {
+	rl.lines = append(rl.lines, "debug: "+msg)
+}
+
+func (rl *recordingLogger) Debugf(format string, v ...interface{}) {
+	rl.Debug(fmt.Sprintf(format, v...))
+}
+
+func (rl *recordingLogger) Info(msg string) {
+	rl.lines = append(rl.lines, "info: "+msg)
+}
+
+func (rl *recordingLogger) Infof(format string, v ...interface{}) {
+	rl.Info(fmt.Sprintf(format, v...))
+}
+
+func (rl *recordingLogger) Warn(msg string) {
+	rl.lines = append(rl.lines, "warn: "+msg)
+}
+
+func (rl *recordingLogger) Warnf(format string, v ...interface{}) {
+	rl.Warn(fmt.Sprintf(format, v...))
+}
+
+// recordingFieldLogger is a FieldLogger recording the fields.
+type recordingFieldLogger struct {
+	Logger
+	fields LogFields
+}
+
+func (rfl *recordingFieldLogger) WithField(key string, value interface{}) FieldLogger {
+	return rfl.WithFields(LogFields{key: value})
+}
+
+func (rfl *recordingFieldLogger) WithFields(fields LogFields) FieldLogger {
+	return &recordingFieldLogger{Logger: rfl.Logger, fields: fields}
+}
+
+func TestLoggerWithFields(t *testing.T) {
+	t.Run("with a logger without fields", func(t *testing.T) {
+		rl := &recordingLogger{}
+		logger := LoggerWithFields(rl, LogFields{"input": "https://example.com/"})
+		logger = logger.WithField("experiment", "web_connectivity")
+		logger.Debug("a")
+		logger.Debugf("%s", "b")
+		logger.Info("c")
+		logger.Infof("%s", "d")
+		logger.Warn("e")
+		logger.Warnf("%s", "f")
+		const suffix = " experiment=web_connectivity input=https://example.com/"
+		expected := []string{
+			"debug: a" + suffix,
+			"debug: b" + suffix,
+			"info: c" + suffix,
+			"info: d" + suffix,
+			"warn: e" + suffix,
+			"warn: f" + suffix,
+		}
+		if diff := cmp.Diff(expected, rl.lines); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a logger without fields does not change the parent", func(t *testing.T) {
+		rl := &recordingLogger{}
+		parent := LoggerWithFields(rl, LogFields{"a": 1})
+		parent.WithField("b", 2)
+		parent.Info("x")
+		if diff := cmp.Diff([]string{"info: x a=1"}, rl.lines); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
+	t.Run("with a FieldLogger", func(t *testing.T) {
+		fields := LogFields{"experiment": "dnscheck"}
+		logger := LoggerWithFields(&recordingFieldLogger{Logger: &recordingLogger{}}, fields)
+		rfl, okay := logger.(*recordingFieldLogger)
+		if !okay {
+			t.Fatalf("unexpected logger type %T", logger)
+		}
+		if diff := cmp.Diff(fields, rfl.fields); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// FakeClock is a model.Clock whose time only moves when you call
+// Advance, which allows tests to simulate timeouts and long runs
+// deterministically and without sleeping.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+var _ model.Clock = &FakeClock{}
+
+// NewFakeClock creates a new FakeClock whose current time is now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now implements model.Clock.Now.
+func (c *FakeClock) Now() time.Time {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	return c.now
+}
+
+// After implements model.Clock.After.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer implements model.Clock.NewTimer.
+func (c *FakeClock) NewTimer(d time.Duration) model.Timer {
+	t := &fakeTimer{c: make(chan time.Time, 1), clock: c}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the current time forward by d and fires
+// all the timers expiring no later than the new time.
+func (c *FakeClock) Advance(d time.Duration) {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.fireLocked()
+}
+
+// fireLocked fires the expired timers. The caller MUST hold the mutex.
+func (c *FakeClock) fireLocked() {
+	var pending []*fakeTimer
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default: // the channel already contains an unread time
+		}
+	}
+	c.timers = pending
+}
+
+// removeLocked removes the given timer and returns whether it
+// was active. The caller MUST hold the mutex.
+func (c *FakeClock) removeLocked(timer *fakeTimer) bool {
+	for idx, t := range c.timers {
+		if t == timer {
+			c.timers = append(c.timers[:idx], c.timers[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeTimer is the model.Timer returned by FakeClock.
+type fakeTimer struct {
+	c        chan time.Time
+	clock    *FakeClock
+	deadline time.Time
+}
+
+// C implements model.Timer.C.
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Reset implements model.Timer.Reset.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	defer t.clock.mu.Unlock()
+	t.clock.mu.Lock()
+	active := t.clock.removeLocked(t)
+	t.deadline = t.clock.now.Add(d)
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.fireLocked()
+	return active
+}
+
+// Stop implements model.Timer.Stop.
+func (t *fakeTimer) Stop() bool {
+	defer t.clock.mu.Unlock()
+	t.clock.mu.Lock()
+	return t.clock.removeLocked(t)
+}
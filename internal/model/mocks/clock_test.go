@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	begin := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Now only changes with Advance", func(t *testing.T) {
+		clock := NewFakeClock(begin)
+		if !clock.Now().Equal(begin) {
+			t.Fatal("unexpected time", clock.Now())
+		}
+		clock.Advance(time.Hour)
+		if !clock.Now().Equal(begin.Add(time.Hour)) {
+			t.Fatal("unexpected time", clock.Now())
+		}
+	})
+
+	t.Run("After fires when we reach the deadline", func(t *testing.T) {
+		clock := NewFakeClock(begin)
+		ch := clock.After(10 * time.Second)
+		clock.Advance(9 * time.Second)
+		select {
+		case <-ch:
+			t.Fatal("fired too early")
+		default:
+		}
+		clock.Advance(time.Second)
+		select {
+		case now := <-ch:
+			if !now.Equal(begin.Add(10 * time.Second)) {
+				t.Fatal("unexpected time", now)
+			}
+		default:
+			t.Fatal("did not fire")
+		}
+	})
+
+	t.Run("a timer with zero duration fires immediately", func(t *testing.T) {
+		clock := NewFakeClock(begin)
+		select {
+		case <-clock.After(0):
+		default:
+			t.Fatal("did not fire")
+		}
+	})
+
+	t.Run("Stop and Reset", func(t *testing.T) {
+		clock := NewFakeClock(begin)
+		timer := clock.NewTimer(time.Second)
+		if !timer.Stop() {
+			t.Fatal("expected the timer to be active")
+		}
+		if timer.Stop() {
+			t.Fatal("expected the timer to be stopped")
+		}
+		clock.Advance(time.Minute)
+		select {
+		case <-timer.C():
+			t.Fatal("a stopped timer fired")
+		default:
+		}
+		if timer.Reset(time.Second) {
+			t.Fatal("expected the timer to be stopped")
+		}
+		clock.Advance(time.Second)
+		select {
+		case <-timer.C():
+		default:
+			t.Fatal("did not fire")
+		}
+	})
+}
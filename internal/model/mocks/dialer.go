@@ -1,27 +1,10 @@
 package mocks
 
 import (
-	"context"
 	"net"
 	"time"
 )
 
-// Dialer is a mockable Dialer.
-type Dialer struct {
-	MockDialContext          func(ctx context.Context, network, address string) (net.Conn, error)
-	MockCloseIdleConnections func()
-}
-
-// DialContext calls MockDialContext.
-func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
-	return d.MockDialContext(ctx, network, address)
-}
-
-// CloseIdleConnections calls MockCloseIdleConnections.
-func (d *Dialer) CloseIdleConnections() {
-	d.MockCloseIdleConnections()
-}
-
 // Conn is a mockable net.Conn.
 type Conn struct {
 	MockRead             func(b []byte) (int, error)
@@ -1,2 +1,8 @@
 // Package mocks contains mocks for internal/model interfaces.
+//
+// The mocks of the interfaces declared by internal/model/netx.go are
+// generated by ./internal/genmocks into netx.go. Every generated mock
+// embeds a CallRecorder recording the calls and their arguments.
 package mocks
+
+//go:generate go run ./internal/genmocks/
@@ -0,0 +1,356 @@
+// Command genmocks generates the mocks of the network extensions
+// interfaces defined by internal/model/netx.go.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Spec describes a mock to generate.
+type Spec struct {
+	// Interface is the name of the interface inside the model
+	// package, which is also the name of the generated mock.
+	Interface string
+
+	// Receiver is the receiver name to use for the mock's methods.
+	Receiver string
+}
+
+// Specs contains all the mocks to generate.
+//
+// Implementation note: we only support interfaces that embed other
+// interfaces defined by the same file. This is why UDPLikeConn, which
+// embeds net.PacketConn, is still written by hand.
+var Specs = []Spec{
+	{Interface: "DNSDecoder", Receiver: "e"},
+	{Interface: "DNSEncoder", Receiver: "e"},
+	{Interface: "DNSTransport", Receiver: "txp"},
+	{Interface: "Dialer", Receiver: "d"},
+	{Interface: "HTTPClient", Receiver: "c"},
+	{Interface: "HTTPTransport", Receiver: "txp"},
+	{Interface: "QUICListener", Receiver: "ql"},
+	{Interface: "QUICDialer", Receiver: "qcd"},
+	{Interface: "Resolver", Receiver: "r"},
+	{Interface: "TLSDialer", Receiver: "d"},
+	{Interface: "TLSHandshaker", Receiver: "th"},
+}
+
+const (
+	// modelFile is the file declaring the interfaces.
+	modelFile = "../netx.go"
+
+	// modelImportPath is the import path of the model package.
+	modelImportPath = "github.com/ooni/probe-cli/v3/internal/model"
+
+	// outputFile is the file we generate.
+	outputFile = "netx.go"
+)
+
+// packageNames maps import paths whose package name differs
+// from the last path element to the package name.
+var packageNames = map[string]string{
+	"github.com/lucas-clemente/quic-go": "quic",
+}
+
+// Param is a method parameter.
+type Param struct {
+	// Name is the parameter name.
+	Name string
+
+	// Type is the parameter type.
+	Type string
+
+	// Variadic indicates this is a variadic parameter.
+	Variadic bool
+}
+
+// Method is a method of an interface.
+type Method struct {
+	// Name is the method name.
+	Name string
+
+	// Params contains the parameters.
+	Params []Param
+
+	// Results contains the types of the results.
+	Results []string
+}
+
+// Signature returns the method signature without the func keyword.
+func (m *Method) Signature() string {
+	var params []string
+	for _, p := range m.Params {
+		params = append(params, p.Name+" "+p.Type)
+	}
+	out := "(" + strings.Join(params, ", ") + ")"
+	switch len(m.Results) {
+	case 0:
+	case 1:
+		out += " " + m.Results[0]
+	default:
+		out += " (" + strings.Join(m.Results, ", ") + ")"
+	}
+	return out
+}
+
+// Args returns the arguments to use when forwarding the call.
+func (m *Method) Args() string {
+	var args []string
+	for _, p := range m.Params {
+		if p.Variadic {
+			args = append(args, p.Name+"...")
+			continue
+		}
+		args = append(args, p.Name)
+	}
+	return strings.Join(args, ", ")
+}
+
+// RecordArgs returns the arguments to pass to CallRecorder.record.
+func (m *Method) RecordArgs() string {
+	out := strconv.Quote(m.Name)
+	for _, p := range m.Params {
+		out += ", " + p.Name
+	}
+	return out
+}
+
+// Generator generates the mocks.
+type Generator struct {
+	// interfaces maps names to interfaces declared by modelFile.
+	interfaces map[string]*ast.InterfaceType
+
+	// imports maps package names to import paths.
+	imports map[string]string
+
+	// used contains the package names used by the generated code.
+	used map[string]bool
+}
+
+// NewGenerator parses the given file and returns a new Generator.
+func NewGenerator(filename string) *Generator {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g := &Generator{
+		interfaces: map[string]*ast.InterfaceType{},
+		imports:    map[string]string{"model": modelImportPath},
+		used:       map[string]bool{},
+	}
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			log.Fatal(err)
+		}
+		name := path.Base(importPath)
+		if v, found := packageNames[importPath]; found {
+			name = v
+		}
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		g.imports[name] = importPath
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if ts, ok := node.(*ast.TypeSpec); ok {
+			if it, ok := ts.Type.(*ast.InterfaceType); ok {
+				g.interfaces[ts.Name.Name] = it
+			}
+		}
+		return true
+	})
+	return g
+}
+
+// Methods returns the methods of the given interface including
+// the ones declared by the embedded interfaces.
+func (g *Generator) Methods(name string) (out []*Method) {
+	it, found := g.interfaces[name]
+	if !found {
+		log.Fatalf("genmocks: cannot find interface %s", name)
+	}
+	for _, field := range it.Methods.List {
+		switch ft := field.Type.(type) {
+		case *ast.Ident:
+			out = append(out, g.Methods(ft.Name)...)
+		case *ast.FuncType:
+			for _, ident := range field.Names {
+				out = append(out, g.newMethod(ident.Name, ft))
+			}
+		default:
+			log.Fatalf("genmocks: %s: unsupported embedded interface", name)
+		}
+	}
+	return
+}
+
+// newMethod creates a new method from its name and type.
+func (g *Generator) newMethod(name string, ft *ast.FuncType) *Method {
+	m := &Method{Name: name}
+	for _, field := range ft.Params.List {
+		_, variadic := field.Type.(*ast.Ellipsis)
+		typ := g.typeString(field.Type)
+		if len(field.Names) <= 0 {
+			m.Params = append(m.Params, Param{
+				Name:     fmt.Sprintf("arg%d", len(m.Params)),
+				Type:     typ,
+				Variadic: variadic,
+			})
+			continue
+		}
+		for _, ident := range field.Names {
+			m.Params = append(m.Params, Param{
+				Name:     ident.Name,
+				Type:     typ,
+				Variadic: variadic,
+			})
+		}
+	}
+	if ft.Results != nil {
+		for _, field := range ft.Results.List {
+			count := len(field.Names)
+			if count <= 0 {
+				count = 1
+			}
+			for idx := 0; idx < count; idx++ {
+				m.Results = append(m.Results, g.typeString(field.Type))
+			}
+		}
+	}
+	return m
+}
+
+// typeString converts a type expression declared by the model
+// package to a type expression valid inside the mocks package.
+func (g *Generator) typeString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if !token.IsExported(e.Name) {
+			return e.Name // predeclared type
+		}
+		g.used["model"] = true
+		return "model." + e.Name
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		if !ok {
+			log.Fatal("genmocks: unsupported selector expression")
+		}
+		if _, found := g.imports[pkg.Name]; !found {
+			log.Fatalf("genmocks: unknown package: %s", pkg.Name)
+		}
+		g.used[pkg.Name] = true
+		return pkg.Name + "." + e.Sel.Name
+	case *ast.StarExpr:
+		return "*" + g.typeString(e.X)
+	case *ast.ArrayType:
+		if e.Len != nil {
+			log.Fatal("genmocks: arrays are not supported")
+		}
+		return "[]" + g.typeString(e.Elt)
+	case *ast.MapType:
+		return "map[" + g.typeString(e.Key) + "]" + g.typeString(e.Value)
+	case *ast.Ellipsis:
+		return "..." + g.typeString(e.Elt)
+	case *ast.InterfaceType:
+		if len(e.Methods.List) > 0 {
+			log.Fatal("genmocks: non-empty interface literals are not supported")
+		}
+		return "interface{}"
+	default:
+		log.Fatalf("genmocks: unsupported type expression: %T", expr)
+		return ""
+	}
+}
+
+// writeMock writes the mock described by spec.
+func (g *Generator) writeMock(w *bytes.Buffer, spec Spec) {
+	methods := g.Methods(spec.Interface)
+	fmt.Fprintf(w, "// %s is a mockable model.%s.\n", spec.Interface, spec.Interface)
+	fmt.Fprintf(w, "type %s struct {\n", spec.Interface)
+	fmt.Fprint(w, "\t// CallRecorder records the calls to the mock's methods.\n")
+	fmt.Fprint(w, "\tCallRecorder\n")
+	for _, m := range methods {
+		fmt.Fprint(w, "\n")
+		fmt.Fprintf(w, "\t// Mock%s allows mocking %s.\n", m.Name, m.Name)
+		fmt.Fprintf(w, "\tMock%s func%s\n", m.Name, m.Signature())
+	}
+	fmt.Fprint(w, "}\n\n")
+	g.used["model"] = true
+	fmt.Fprintf(w, "var _ model.%s = &%s{}\n\n", spec.Interface, spec.Interface)
+	for _, m := range methods {
+		for _, p := range m.Params {
+			if p.Name == spec.Receiver {
+				log.Fatalf("genmocks: %s.%s: parameter %s shadows the receiver",
+					spec.Interface, m.Name, p.Name)
+			}
+		}
+		fmt.Fprintf(w, "// %s records the call and calls Mock%s.\n", m.Name, m.Name)
+		fmt.Fprintf(w, "func (%s *%s) %s%s {\n", spec.Receiver, spec.Interface, m.Name, m.Signature())
+		fmt.Fprintf(w, "\t%s.record(%s)\n", spec.Receiver, m.RecordArgs())
+		if len(m.Results) > 0 {
+			fmt.Fprintf(w, "\treturn %s.Mock%s(%s)\n", spec.Receiver, m.Name, m.Args())
+		} else {
+			fmt.Fprintf(w, "\t%s.Mock%s(%s)\n", spec.Receiver, m.Name, m.Args())
+		}
+		fmt.Fprint(w, "}\n\n")
+	}
+}
+
+// writeImports writes the import declaration.
+func (g *Generator) writeImports(w *bytes.Buffer) {
+	var stdlib, others []string
+	for name := range g.used {
+		importPath := g.imports[name]
+		if !strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			stdlib = append(stdlib, importPath)
+			continue
+		}
+		others = append(others, importPath)
+	}
+	sort.Strings(stdlib)
+	sort.Strings(others)
+	fmt.Fprint(w, "import (\n")
+	for _, importPath := range stdlib {
+		fmt.Fprintf(w, "\t%s\n", strconv.Quote(importPath))
+	}
+	if len(stdlib) > 0 && len(others) > 0 {
+		fmt.Fprint(w, "\n")
+	}
+	for _, importPath := range others {
+		fmt.Fprintf(w, "\t%s\n", strconv.Quote(importPath))
+	}
+	fmt.Fprint(w, ")\n\n")
+}
+
+func main() {
+	g := NewGenerator(modelFile)
+	body := &bytes.Buffer{}
+	for _, spec := range Specs {
+		g.writeMock(body, spec)
+	}
+	w := &bytes.Buffer{}
+	fmt.Fprint(w, "// Code generated by go generate; DO NOT EDIT.\n\n")
+	fmt.Fprint(w, "package mocks\n\n")
+	g.writeImports(w)
+	w.Write(body.Bytes())
+	data, err := format.Source(w.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputFile, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -2,7 +2,6 @@ package mocks
 
 import (
 	"context"
-	"crypto/tls"
 	"net"
 	"syscall"
 	"time"
@@ -11,37 +10,6 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
-// QUICListener is a mockable netxlite.QUICListener.
-type QUICListener struct {
-	MockListen func(addr *net.UDPAddr) (model.UDPLikeConn, error)
-}
-
-// Listen calls MockListen.
-func (ql *QUICListener) Listen(addr *net.UDPAddr) (model.UDPLikeConn, error) {
-	return ql.MockListen(addr)
-}
-
-// QUICDialer is a mockable netxlite.QUICDialer.
-type QUICDialer struct {
-	// MockDialContext allows mocking DialContext.
-	MockDialContext func(ctx context.Context, network, address string,
-		tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error)
-
-	// MockCloseIdleConnections allows mocking CloseIdleConnections.
-	MockCloseIdleConnections func()
-}
-
-// DialContext calls MockDialContext.
-func (qcd *QUICDialer) DialContext(ctx context.Context, network, address string,
-	tlsConfig *tls.Config, quicConfig *quic.Config) (quic.EarlyConnection, error) {
-	return qcd.MockDialContext(ctx, network, address, tlsConfig, quicConfig)
-}
-
-// CloseIdleConnections calls MockCloseIdleConnections.
-func (qcd *QUICDialer) CloseIdleConnections() {
-	qcd.MockCloseIdleConnections()
-}
-
 // QUICEarlyConnection is a mockable quic.EarlyConnection.
 type QUICEarlyConnection struct {
 	MockAcceptStream      func(context.Context) (quic.Stream, error)
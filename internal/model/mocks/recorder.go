@@ -0,0 +1,47 @@
+package mocks
+
+import "sync"
+
+// Call is a method call recorded by a mock.
+type Call struct {
+	// Method is the name of the method (e.g., "LookupHost").
+	Method string
+
+	// Args contains the arguments passed to the method.
+	Args []interface{}
+}
+
+// CallRecorder records the method calls of a mock. The zero
+// value is ready to use. This struct is goroutine safe.
+type CallRecorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+// record records a call to the given method.
+func (cr *CallRecorder) record(method string, args ...interface{}) {
+	cr.mu.Lock()
+	cr.calls = append(cr.calls, Call{Method: method, Args: args})
+	cr.mu.Unlock()
+}
+
+// Calls returns all the recorded calls in the order in which they occurred.
+func (cr *CallRecorder) Calls() []Call {
+	defer cr.mu.Unlock()
+	cr.mu.Lock()
+	out := make([]Call, len(cr.calls))
+	copy(out, cr.calls)
+	return out
+}
+
+// CallsTo is like Calls but only returns the calls to the given method.
+func (cr *CallRecorder) CallsTo(method string) (out []Call) {
+	defer cr.mu.Unlock()
+	cr.mu.Lock()
+	for _, call := range cr.calls {
+		if call.Method == method {
+			out = append(out, call)
+		}
+	}
+	return
+}
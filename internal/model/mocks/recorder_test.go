@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCallRecorder(t *testing.T) {
+	t.Run("the zero value is ready to use", func(t *testing.T) {
+		cr := &CallRecorder{}
+		if calls := cr.Calls(); len(calls) != 0 {
+			t.Fatal("expected no calls", calls)
+		}
+		if calls := cr.CallsTo("LookupHost"); len(calls) != 0 {
+			t.Fatal("expected no calls", calls)
+		}
+	})
+
+	t.Run("mocks record calls and arguments", func(t *testing.T) {
+		ctx := context.Background()
+		r := &Resolver{
+			MockLookupHost: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"8.8.8.8"}, nil
+			},
+			MockCloseIdleConnections: func() {},
+		}
+		r.LookupHost(ctx, "dns.google")
+		r.CloseIdleConnections()
+		r.LookupHost(ctx, "example.com")
+		expectCalls := []Call{{
+			Method: "LookupHost",
+			Args:   []interface{}{ctx, "dns.google"},
+		}, {
+			Method: "CloseIdleConnections",
+			Args:   nil,
+		}, {
+			Method: "LookupHost",
+			Args:   []interface{}{ctx, "example.com"},
+		}}
+		if calls := r.Calls(); !reflect.DeepEqual(expectCalls, calls) {
+			t.Fatal("unexpected calls", calls)
+		}
+		lookups := r.CallsTo("LookupHost")
+		if len(lookups) != 2 || lookups[1].Args[1] != "example.com" {
+			t.Fatal("unexpected calls", lookups)
+		}
+	})
+
+	t.Run("Calls returns a copy", func(t *testing.T) {
+		d := &Dialer{MockCloseIdleConnections: func() {}}
+		d.CloseIdleConnections()
+		calls := d.Calls()
+		calls[0].Method = "antani"
+		if d.Calls()[0].Method != "CloseIdleConnections" {
+			t.Fatal("Calls did not return a copy")
+		}
+	})
+
+	t.Run("recording is goroutine safe", func(t *testing.T) {
+		d := &Dialer{MockCloseIdleConnections: func() {}}
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.CloseIdleConnections()
+			}()
+		}
+		wg.Wait()
+		if n := len(d.CallsTo("CloseIdleConnections")); n != 10 {
+			t.Fatal("unexpected number of calls", n)
+		}
+	})
+}
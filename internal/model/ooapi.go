@@ -28,8 +28,18 @@ type OOAPICheckInInfoWebConnectivity struct {
 	URLs     []OOAPIURLInfo `json:"urls"`
 }
 
+// OOAPICheckInInfoConfig contains the configuration returned by the checkin API
+type OOAPICheckInInfoConfig struct {
+	// Features contains feature flags (e.g., "torsf_enabled").
+	Features map[string]bool `json:"features"`
+}
+
 // OOAPICheckInInfo contains the return test objects from the checkin API
 type OOAPICheckInInfo struct {
+	// Conf contains the configuration, which the checkin API returns
+	// along with, rather than inside, the test objects.
+	Conf OOAPICheckInInfoConfig `json:"conf"`
+
 	WebConnectivity *OOAPICheckInInfoWebConnectivity `json:"web_connectivity"`
 }
 
@@ -5,6 +5,7 @@ type URLsRequest struct {
 	CategoryCodes string `query:"category_codes"`
 	CountryCode   string `query:"country_code"`
 	Limit         int64  `query:"limit"`
+	Offset        int64  `query:"offset"`
 }
 
 // URLsResponse is the URLs response.
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// 2022-05-19 20:30:46.817279 +0200 CEST m=+0.001015835
+// 2026-10-16 08:06:35.121564412 +0000 UTC m=+0.000148068
 
 package ooapi
 
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// 2022-05-19 20:30:47.186361 +0200 CEST m=+0.000721751
+// 2026-10-16 08:08:37.361911166 +0000 UTC m=+0.000138636
 
 package ooapi
 
@@ -12,11 +12,141 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
 )
 
+// withCacheCheckInAPI implements caching for simpleCheckInAPI.
+type withCacheCheckInAPI struct {
+	API             callerForCheckInAPI // mandatory
+	GobCodec        GobCodec            // optional
+	Instrumentation Instrumentation     // optional
+	KVStore         KVStore             // mandatory
+}
+
+type cacheEntryForCheckInAPI struct {
+	Req  *apimodel.CheckInRequest
+	Resp *apimodel.CheckInResponse
+}
+
+// Call calls the API and implements caching.
+func (c *withCacheCheckInAPI) Call(ctx context.Context, req *apimodel.CheckInRequest) (*apimodel.CheckInResponse, error) {
+	resp, err := c.API.Call(ctx, req)
+	if err != nil {
+		if resp, _ := c.lookupcache(req); resp != nil {
+			return resp, nil
+		}
+		return nil, err
+	}
+	if err := c.writecache(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *withCacheCheckInAPI) gobCodec() GobCodec {
+	if c.GobCodec != nil {
+		return c.GobCodec
+	}
+	return &defaultGobCodec{}
+}
+
+func (c *withCacheCheckInAPI) instrumentation() Instrumentation {
+	if c.Instrumentation != nil {
+		return c.Instrumentation
+	}
+	return &defaultInstrumentation{}
+}
+
+func (c *withCacheCheckInAPI) getcache() ([]cacheEntryForCheckInAPI, error) {
+	data, err := c.KVStore.Get("CheckIn.cache")
+	if err != nil {
+		return nil, err
+	}
+	var out []cacheEntryForCheckInAPI
+	if err := c.gobCodec().Decode(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *withCacheCheckInAPI) setcache(in []cacheEntryForCheckInAPI) error {
+	data, err := c.gobCodec().Encode(in)
+	if err != nil {
+		return err
+	}
+	return c.KVStore.Set("CheckIn.cache", data)
+}
+
+func (c *withCacheCheckInAPI) readcache(req *apimodel.CheckInRequest) (*apimodel.CheckInResponse, error) {
+	cache, err := c.getcache()
+	if err != nil {
+		return nil, err
+	}
+	for _, cur := range cache {
+		if reflect.DeepEqual(req, cur.Req) {
+			return cur.Resp, nil
+		}
+	}
+	return nil, errCacheNotFound
+}
+
+func (c *withCacheCheckInAPI) lookupcache(req *apimodel.CheckInRequest) (*apimodel.CheckInResponse, error) {
+	resp, err := c.readcache(req)
+	c.instrumentation().OnCacheLookup("CheckIn", resp != nil)
+	return resp, err
+}
+
+func (c *withCacheCheckInAPI) writecache(req *apimodel.CheckInRequest, resp *apimodel.CheckInResponse) error {
+	cache, _ := c.getcache()
+	out := []cacheEntryForCheckInAPI{{Req: req, Resp: resp}}
+	const toomany = 64
+	for idx, cur := range cache {
+		if reflect.DeepEqual(req, cur.Req) {
+			continue // we already updated the cache
+		}
+		if idx > toomany {
+			break
+		}
+		out = append(out, cur)
+	}
+	return c.setcache(out)
+}
+
+func (c *withCacheCheckInAPI) invalidate(req *apimodel.CheckInRequest) error {
+	// Implementation note: if we cannot read the cache, we
+	// cannot keep any entry, so we end up flushing it.
+	cache, _ := c.getcache()
+	var out []cacheEntryForCheckInAPI
+	for _, cur := range cache {
+		if reflect.DeepEqual(req, cur.Req) {
+			continue
+		}
+		out = append(out, cur)
+	}
+	return c.setcache(out)
+}
+
+func (c *withCacheCheckInAPI) flush() error {
+	return c.setcache(nil)
+}
+
+var _ callerForCheckInAPI = &withCacheCheckInAPI{}
+
+// InvalidateCheckInCache removes the cached response for req, if any.
+func (c *Client) InvalidateCheckInCache(req *apimodel.CheckInRequest) error {
+	cache := &withCacheCheckInAPI{GobCodec: c.GobCodec, KVStore: c.KVStore}
+	return cache.invalidate(req)
+}
+
+// FlushCheckInCache removes all the cached responses of the CheckIn API.
+func (c *Client) FlushCheckInCache() error {
+	cache := &withCacheCheckInAPI{GobCodec: c.GobCodec, KVStore: c.KVStore}
+	return cache.flush()
+}
+
 // withCacheMeasurementMetaAPI implements caching for simpleMeasurementMetaAPI.
 type withCacheMeasurementMetaAPI struct {
-	API      callerForMeasurementMetaAPI // mandatory
-	GobCodec GobCodec                    // optional
-	KVStore  KVStore                     // mandatory
+	API             callerForMeasurementMetaAPI // mandatory
+	GobCodec        GobCodec                    // optional
+	Instrumentation Instrumentation             // optional
+	KVStore         KVStore                     // mandatory
 }
 
 type cacheEntryForMeasurementMetaAPI struct {
@@ -26,7 +156,7 @@ type cacheEntryForMeasurementMetaAPI struct {
 
 // Call calls the API and implements caching.
 func (c *withCacheMeasurementMetaAPI) Call(ctx context.Context, req *apimodel.MeasurementMetaRequest) (*apimodel.MeasurementMetaResponse, error) {
-	if resp, _ := c.readcache(req); resp != nil {
+	if resp, _ := c.lookupcache(req); resp != nil {
 		return resp, nil
 	}
 	resp, err := c.API.Call(ctx, req)
@@ -46,6 +176,13 @@ func (c *withCacheMeasurementMetaAPI) gobCodec() GobCodec {
 	return &defaultGobCodec{}
 }
 
+func (c *withCacheMeasurementMetaAPI) instrumentation() Instrumentation {
+	if c.Instrumentation != nil {
+		return c.Instrumentation
+	}
+	return &defaultInstrumentation{}
+}
+
 func (c *withCacheMeasurementMetaAPI) getcache() ([]cacheEntryForMeasurementMetaAPI, error) {
 	data, err := c.KVStore.Get("MeasurementMeta.cache")
 	if err != nil {
@@ -79,6 +216,12 @@ func (c *withCacheMeasurementMetaAPI) readcache(req *apimodel.MeasurementMetaReq
 	return nil, errCacheNotFound
 }
 
+func (c *withCacheMeasurementMetaAPI) lookupcache(req *apimodel.MeasurementMetaRequest) (*apimodel.MeasurementMetaResponse, error) {
+	resp, err := c.readcache(req)
+	c.instrumentation().OnCacheLookup("MeasurementMeta", resp != nil)
+	return resp, err
+}
+
 func (c *withCacheMeasurementMetaAPI) writecache(req *apimodel.MeasurementMetaRequest, resp *apimodel.MeasurementMetaResponse) error {
 	cache, _ := c.getcache()
 	out := []cacheEntryForMeasurementMetaAPI{{Req: req, Resp: resp}}
@@ -95,4 +238,49 @@ func (c *withCacheMeasurementMetaAPI) writecache(req *apimodel.MeasurementMetaRe
 	return c.setcache(out)
 }
 
+func (c *withCacheMeasurementMetaAPI) invalidate(req *apimodel.MeasurementMetaRequest) error {
+	// Implementation note: if we cannot read the cache, we
+	// cannot keep any entry, so we end up flushing it.
+	cache, _ := c.getcache()
+	var out []cacheEntryForMeasurementMetaAPI
+	for _, cur := range cache {
+		if reflect.DeepEqual(req, cur.Req) {
+			continue
+		}
+		out = append(out, cur)
+	}
+	return c.setcache(out)
+}
+
+func (c *withCacheMeasurementMetaAPI) flush() error {
+	return c.setcache(nil)
+}
+
 var _ callerForMeasurementMetaAPI = &withCacheMeasurementMetaAPI{}
+
+// InvalidateMeasurementMetaCache removes the cached response for req, if any.
+func (c *Client) InvalidateMeasurementMetaCache(req *apimodel.MeasurementMetaRequest) error {
+	cache := &withCacheMeasurementMetaAPI{GobCodec: c.GobCodec, KVStore: c.KVStore}
+	return cache.invalidate(req)
+}
+
+// FlushMeasurementMetaCache removes all the cached responses of the MeasurementMeta API.
+func (c *Client) FlushMeasurementMetaCache() error {
+	cache := &withCacheMeasurementMetaAPI{GobCodec: c.GobCodec, KVStore: c.KVStore}
+	return cache.flush()
+}
+
+// FlushCaches removes all the cached responses of all the APIs. You
+// typically want to call this method when the network changes.
+func (c *Client) FlushCaches() error {
+	flushers := []func() error{
+		c.FlushCheckInCache,
+		c.FlushMeasurementMetaCache,
+	}
+	for _, flush := range flushers {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// 2022-05-19 20:30:47.604277 +0200 CEST m=+0.000796918
+// 2026-10-16 08:08:37.515809845 +0000 UTC m=+0.000121442
 
 package ooapi
 
@@ -15,6 +15,261 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
 )
 
+func TestCachesimpleCheckInAPISuccess(t *testing.T) {
+	ff := &fakeFill{}
+	var expect *apimodel.CheckInResponse
+	ff.Fill(&expect)
+	cache := &withCacheCheckInAPI{
+		API: &FakeCheckInAPI{
+			Response: expect,
+		},
+		KVStore: &kvstore.Memory{},
+	}
+	var req *apimodel.CheckInRequest
+	ff.Fill(&req)
+	ctx := context.Background()
+	resp, err := cache.Call(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil {
+		t.Fatal("expected non-nil response")
+	}
+	if diff := cmp.Diff(expect, resp); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestCachesimpleCheckInAPIWriteCacheError(t *testing.T) {
+	errMocked := errors.New("mocked error")
+	ff := &fakeFill{}
+	var expect *apimodel.CheckInResponse
+	ff.Fill(&expect)
+	cache := &withCacheCheckInAPI{
+		API: &FakeCheckInAPI{
+			Response: expect,
+		},
+		KVStore: &FakeKVStore{SetError: errMocked},
+	}
+	var req *apimodel.CheckInRequest
+	ff.Fill(&req)
+	ctx := context.Background()
+	resp, err := cache.Call(ctx, req)
+	if !errors.Is(err, errMocked) {
+		t.Fatal("not the error we expected", err)
+	}
+	if resp != nil {
+		t.Fatal("expected nil response")
+	}
+}
+
+func TestCachesimpleCheckInAPIFailureWithNoCache(t *testing.T) {
+	errMocked := errors.New("mocked error")
+	ff := &fakeFill{}
+	cache := &withCacheCheckInAPI{
+		API: &FakeCheckInAPI{
+			Err: errMocked,
+		},
+		KVStore: &kvstore.Memory{},
+	}
+	var req *apimodel.CheckInRequest
+	ff.Fill(&req)
+	ctx := context.Background()
+	resp, err := cache.Call(ctx, req)
+	if !errors.Is(err, errMocked) {
+		t.Fatal("not the error we expected", err)
+	}
+	if resp != nil {
+		t.Fatal("expected nil response")
+	}
+}
+
+func TestCachesimpleCheckInAPIFailureWithPreviousCache(t *testing.T) {
+	ff := &fakeFill{}
+	var expect *apimodel.CheckInResponse
+	ff.Fill(&expect)
+	fakeapi := &FakeCheckInAPI{
+		Response: expect,
+	}
+	cache := &withCacheCheckInAPI{
+		API:     fakeapi,
+		KVStore: &kvstore.Memory{},
+	}
+	var req *apimodel.CheckInRequest
+	ff.Fill(&req)
+	ctx := context.Background()
+	// first pass with no error at all
+	// use a separate scope to be sure we avoid mistakes
+	{
+		resp, err := cache.Call(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil {
+			t.Fatal("expected non-nil response")
+		}
+		if diff := cmp.Diff(expect, resp); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	// second pass with failure
+	errMocked := errors.New("mocked error")
+	fakeapi.Err = errMocked
+	fakeapi.Response = nil
+	resp2, err := cache.Call(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp2 == nil {
+		t.Fatal("expected non-nil response")
+	}
+	if diff := cmp.Diff(expect, resp2); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestCachesimpleCheckInAPISetcacheWithEncodeError(t *testing.T) {
+	ff := &fakeFill{}
+	errMocked := errors.New("mocked error")
+	var in []cacheEntryForCheckInAPI
+	ff.Fill(&in)
+	cache := &withCacheCheckInAPI{
+		GobCodec: &FakeCodec{EncodeErr: errMocked},
+	}
+	err := cache.setcache(in)
+	if !errors.Is(err, errMocked) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestCachesimpleCheckInAPIReadCacheNotFound(t *testing.T) {
+	ff := &fakeFill{}
+	var incache []cacheEntryForCheckInAPI
+	ff.Fill(&incache)
+	cache := &withCacheCheckInAPI{
+		KVStore: &kvstore.Memory{},
+	}
+	err := cache.setcache(incache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req *apimodel.CheckInRequest
+	ff.Fill(&req)
+	out, err := cache.readcache(req)
+	if !errors.Is(err, errCacheNotFound) {
+		t.Fatal("not the error we expected", err)
+	}
+	if out != nil {
+		t.Fatal("expected nil here")
+	}
+}
+
+func TestCachesimpleCheckInAPIWriteCacheDuplicate(t *testing.T) {
+	ff := &fakeFill{}
+	var req *apimodel.CheckInRequest
+	ff.Fill(&req)
+	var resp1 *apimodel.CheckInResponse
+	ff.Fill(&resp1)
+	var resp2 *apimodel.CheckInResponse
+	ff.Fill(&resp2)
+	cache := &withCacheCheckInAPI{
+		KVStore: &kvstore.Memory{},
+	}
+	err := cache.writecache(req, resp1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cache.writecache(req, resp2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cache.readcache(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out == nil {
+		t.Fatal("expected non-nil here")
+	}
+	if diff := cmp.Diff(resp2, out); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestCachesimpleCheckInAPICacheSizeLimited(t *testing.T) {
+	ff := &fakeFill{}
+	cache := &withCacheCheckInAPI{
+		KVStore: &kvstore.Memory{},
+	}
+	var prev int
+	for {
+		var req *apimodel.CheckInRequest
+		ff.Fill(&req)
+		var resp *apimodel.CheckInResponse
+		ff.Fill(&resp)
+		err := cache.writecache(req, resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := cache.getcache()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out) > prev {
+			prev = len(out)
+			continue
+		}
+		break
+	}
+}
+
+func TestCachesimpleCheckInAPIInvalidate(t *testing.T) {
+	ff := &fakeFill{}
+	clnt := &Client{KVStore: &kvstore.Memory{}}
+	cache := &withCacheCheckInAPI{
+		KVStore: clnt.KVStore,
+	}
+	var req1, req2 *apimodel.CheckInRequest
+	ff.Fill(&req1)
+	ff.Fill(&req2)
+	var resp *apimodel.CheckInResponse
+	ff.Fill(&resp)
+	for _, req := range []*apimodel.CheckInRequest{req1, req2} {
+		if err := cache.writecache(req, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := clnt.InvalidateCheckInCache(req1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.readcache(req1); !errors.Is(err, errCacheNotFound) {
+		t.Fatal("not the error we expected", err)
+	}
+	if _, err := cache.readcache(req2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachesimpleCheckInAPIFlush(t *testing.T) {
+	ff := &fakeFill{}
+	clnt := &Client{KVStore: &kvstore.Memory{}}
+	cache := &withCacheCheckInAPI{
+		KVStore: clnt.KVStore,
+	}
+	var req *apimodel.CheckInRequest
+	ff.Fill(&req)
+	var resp *apimodel.CheckInResponse
+	ff.Fill(&resp)
+	if err := cache.writecache(req, resp); err != nil {
+		t.Fatal(err)
+	}
+	if err := clnt.FlushCaches(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.readcache(req); !errors.Is(err, errCacheNotFound) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
 func TestCachesimpleMeasurementMetaAPISuccess(t *testing.T) {
 	ff := &fakeFill{}
 	var expect *apimodel.MeasurementMetaResponse
@@ -221,3 +476,51 @@ func TestCachesimpleMeasurementMetaAPICacheSizeLimited(t *testing.T) {
 		break
 	}
 }
+
+func TestCachesimpleMeasurementMetaAPIInvalidate(t *testing.T) {
+	ff := &fakeFill{}
+	clnt := &Client{KVStore: &kvstore.Memory{}}
+	cache := &withCacheMeasurementMetaAPI{
+		KVStore: clnt.KVStore,
+	}
+	var req1, req2 *apimodel.MeasurementMetaRequest
+	ff.Fill(&req1)
+	ff.Fill(&req2)
+	var resp *apimodel.MeasurementMetaResponse
+	ff.Fill(&resp)
+	for _, req := range []*apimodel.MeasurementMetaRequest{req1, req2} {
+		if err := cache.writecache(req, resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := clnt.InvalidateMeasurementMetaCache(req1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.readcache(req1); !errors.Is(err, errCacheNotFound) {
+		t.Fatal("not the error we expected", err)
+	}
+	if _, err := cache.readcache(req2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachesimpleMeasurementMetaAPIFlush(t *testing.T) {
+	ff := &fakeFill{}
+	clnt := &Client{KVStore: &kvstore.Memory{}}
+	cache := &withCacheMeasurementMetaAPI{
+		KVStore: clnt.KVStore,
+	}
+	var req *apimodel.MeasurementMetaRequest
+	ff.Fill(&req)
+	var resp *apimodel.MeasurementMetaResponse
+	ff.Fill(&resp)
+	if err := cache.writecache(req, resp); err != nil {
+		t.Fatal(err)
+	}
+	if err := clnt.FlushCaches(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.readcache(req); !errors.Is(err, errCacheNotFound) {
+		t.Fatal("not the error we expected", err)
+	}
+}
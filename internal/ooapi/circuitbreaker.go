@@ -0,0 +1,173 @@
+package ooapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+)
+
+// CircuitBreaker prevents us from repeatedly hanging on an unreachable
+// backend. After FailureThreshold consecutive failures suggesting that the
+// backend is unreachable (i.e., network errors, including connection refused,
+// connection reset and DNS errors, timeouts, and 5xx responses) with an
+// endpoint, the breaker opens and we
+// route calls to the next endpoint in Endpoints, if any, or fail fast with
+// ErrCircuitOpen. After OpenTimeout, we let a single "half-open" probe call
+// through: if it succeeds, the breaker closes again, otherwise it reopens.
+//
+// A CircuitBreaker is meant to be shared by all the calls of a Client. The
+// zero value is a valid breaker using sensible defaults.
+type CircuitBreaker struct {
+	// Endpoints is the OPTIONAL list of base URLs to use in order
+	// of preference. When empty, we only use the Client's BaseURL.
+	Endpoints []string
+
+	// FailureThreshold is the OPTIONAL number of consecutive failures
+	// after which the breaker opens. When zero or negative, we use
+	// a default value.
+	FailureThreshold int
+
+	// OpenTimeout is the OPTIONAL amount of time after which an open
+	// breaker lets a probe call through. When zero or negative, we
+	// use a default value.
+	OpenTimeout time.Duration
+
+	// TimeNow is the OPTIONAL function returning the current time. When
+	// nil, we use time.Now. This field is mainly useful for testing.
+	TimeNow func() time.Time
+
+	// mu provides mutual exclusion.
+	mu sync.Mutex
+
+	// state maps each endpoint to its state.
+	state map[string]*circuitState
+}
+
+// circuitState is the state of a specific endpoint.
+type circuitState struct {
+	// failures is the number of consecutive failures.
+	failures int
+
+	// openedAt is the time when we opened the breaker, if open.
+	openedAt time.Time
+
+	// open indicates whether the breaker is open.
+	open bool
+
+	// probing indicates whether a half-open probe is in flight.
+	probing bool
+}
+
+// These are the default values used by CircuitBreaker.
+const (
+	defaultCircuitFailureThreshold = 3
+	defaultCircuitOpenTimeout      = 30 * time.Second
+)
+
+func (cb *CircuitBreaker) failureThreshold() int {
+	if cb.FailureThreshold > 0 {
+		return cb.FailureThreshold
+	}
+	return defaultCircuitFailureThreshold
+}
+
+func (cb *CircuitBreaker) openTimeout() time.Duration {
+	if cb.OpenTimeout > 0 {
+		return cb.OpenTimeout
+	}
+	return defaultCircuitOpenTimeout
+}
+
+func (cb *CircuitBreaker) timeNow() time.Time {
+	if cb.TimeNow != nil {
+		return cb.TimeNow()
+	}
+	return time.Now()
+}
+
+func (cb *CircuitBreaker) endpoints(defaultBaseURL string) []string {
+	if len(cb.Endpoints) > 0 {
+		return cb.Endpoints
+	}
+	return []string{defaultBaseURL}
+}
+
+// getstate returns the state of the given endpoint. This function
+// assumes that the caller is holding the mutex.
+func (cb *CircuitBreaker) getstate(baseURL string) *circuitState {
+	if cb.state == nil {
+		cb.state = make(map[string]*circuitState)
+	}
+	st, found := cb.state[baseURL]
+	if !found {
+		st = &circuitState{}
+		cb.state[baseURL] = st
+	}
+	return st
+}
+
+// acquire returns the base URL to use for the next call or
+// ErrCircuitOpen if all the available endpoints are open. The
+// defaultBaseURL is the one to use when Endpoints is empty.
+func (cb *CircuitBreaker) acquire(defaultBaseURL string) (string, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	now := cb.timeNow()
+	for _, baseURL := range cb.endpoints(defaultBaseURL) {
+		st := cb.getstate(baseURL)
+		if !st.open {
+			return baseURL, nil
+		}
+		if !st.probing && now.Sub(st.openedAt) >= cb.openTimeout() {
+			st.probing = true // enter the half-open state
+			return baseURL, nil
+		}
+	}
+	return "", ErrCircuitOpen
+}
+
+// release updates the state of the given endpoint using
+// the result of a call that previously used acquire.
+func (cb *CircuitBreaker) release(baseURL string, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	st := cb.getstate(baseURL)
+	if errors.Is(err, context.Canceled) {
+		st.probing = false // we don't know anything new
+		return
+	}
+	if !isUnreachableFailure(err) {
+		// Either a success or a failure indicating that the
+		// backend is reachable (e.g., a 4xx response).
+		*st = circuitState{}
+		return
+	}
+	st.failures++
+	if st.probing || st.failures >= cb.failureThreshold() {
+		st.open, st.openedAt, st.probing = true, cb.timeNow(), false
+	}
+}
+
+// isUnreachableFailure returns whether err suggests that the backend is
+// unreachable. Unlike isTransientFailure, which only includes the failures
+// after which it makes sense to retry with the same endpoint, we include
+// all the network errors (e.g., connection refused), since they are the
+// reason why we have fallback endpoints.
+func isUnreachableFailure(err error) bool {
+	if isTransientFailure(err) {
+		return true
+	}
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var errWrapper *netxlite.ErrWrapper
+	return errors.As(err, &errWrapper)
+}
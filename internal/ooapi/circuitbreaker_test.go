@@ -0,0 +1,168 @@
+package ooapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/netxlite"
+	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
+)
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	cb := &CircuitBreaker{FailureThreshold: 2}
+	for idx := 0; idx < 2; idx++ {
+		baseURL, err := cb.acquire("https://a.example.org")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cb.release(baseURL, newHTTPFailure(500))
+	}
+	if _, err := cb.acquire("https://a.example.org"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("not the error we expected", err)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	cb := &CircuitBreaker{FailureThreshold: 2}
+	cb.release("", newHTTPFailure(500))
+	cb.release("", nil)
+	cb.release("", newHTTPFailure(500))
+	if _, err := cb.acquire(""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCircuitBreakerNonTransientFailuresDoNotOpen(t *testing.T) {
+	cb := &CircuitBreaker{FailureThreshold: 1}
+	cb.release("", ErrUnauthorized)
+	cb.release("", context.Canceled)
+	if _, err := cb.acquire(""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCircuitBreakerNetworkFailuresOpen(t *testing.T) {
+	failures := []error{
+		&url.Error{Op: "Get", URL: "https://a.example.org", Err: syscall.ECONNREFUSED},
+		&netxlite.ErrWrapper{Failure: netxlite.FailureConnectionReset},
+		&net.DNSError{Err: "no such host", Name: "a.example.org", IsNotFound: true},
+	}
+	for _, failure := range failures {
+		cb := &CircuitBreaker{FailureThreshold: 1}
+		cb.release("", failure)
+		if _, err := cb.acquire(""); !errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("not the error we expected", failure, err)
+		}
+	}
+}
+
+func TestCircuitBreakerRoutesToFallbackEndpoint(t *testing.T) {
+	cb := &CircuitBreaker{
+		Endpoints:        []string{"https://a.example.org", "https://b.example.org"},
+		FailureThreshold: 1,
+	}
+	cb.release("https://a.example.org", newHTTPFailure(503))
+	baseURL, err := cb.acquire("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if baseURL != "https://b.example.org" {
+		t.Fatal("unexpected base URL", baseURL)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	now := time.Now()
+	cb := &CircuitBreaker{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		TimeNow: func() time.Time {
+			return now
+		},
+	}
+	cb.release("", newHTTPFailure(500))
+	if _, err := cb.acquire(""); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("not the error we expected", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := cb.acquire(""); err != nil {
+		t.Fatal(err) // this is the half-open probe
+	}
+	if _, err := cb.acquire(""); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected only a single probe", err)
+	}
+	cb.release("", newHTTPFailure(500)) // the probe failed
+	if _, err := cb.acquire(""); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("not the error we expected", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := cb.acquire(""); err != nil {
+		t.Fatal(err) // this is another half-open probe
+	}
+	cb.release("", nil) // the probe succeeded
+	for idx := 0; idx < 3; idx++ {
+		if _, err := cb.acquire(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWithBreakerCheckInAPI(t *testing.T) {
+	ff := &fakeFill{}
+	var expect *apimodel.CheckInResponse
+	ff.Fill(&expect)
+	var used []string
+	cb := &CircuitBreaker{
+		Endpoints:        []string{"https://a.example.org", "https://b.example.org"},
+		FailureThreshold: 1,
+	}
+	api := &withBreakerCheckInAPI{
+		Breaker: cb,
+		NewCaller: func(baseURL string) callerForCheckInAPI {
+			used = append(used, baseURL)
+			if baseURL == "https://a.example.org" {
+				return &FakeCheckInAPI{Err: newHTTPFailure(502)}
+			}
+			return &FakeCheckInAPI{Response: expect}
+		},
+	}
+	ctx := context.Background()
+	req := &apimodel.CheckInRequest{}
+	if _, err := api.Call(ctx, req); !errors.Is(err, ErrHTTPFailure) {
+		t.Fatal("not the error we expected", err)
+	}
+	resp, err := api.Call(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != expect {
+		t.Fatal("unexpected response")
+	}
+	if len(used) != 2 || used[0] != "https://a.example.org" || used[1] != "https://b.example.org" {
+		t.Fatal("unexpected endpoints", used)
+	}
+}
+
+func TestWithBreakerCheckInAPIFailsFast(t *testing.T) {
+	cb := &CircuitBreaker{FailureThreshold: 1}
+	cb.release("", newHTTPFailure(500))
+	api := &withBreakerCheckInAPI{
+		Breaker: cb,
+		NewCaller: func(baseURL string) callerForCheckInAPI {
+			t.Fatal("should not be called")
+			return nil
+		},
+	}
+	resp, err := api.Call(context.Background(), &apimodel.CheckInRequest{})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("not the error we expected", err)
+	}
+	if resp != nil {
+		t.Fatal("expected nil response")
+	}
+}
@@ -1,5 +1,11 @@
 package ooapi
 
+import (
+	"net/http"
+
+	"github.com/ooni/probe-cli/v3/internal/httpx"
+)
+
 // Client is a client for speaking with the OONI API. Make sure you
 // fill in the mandatory fields.
 type Client struct {
@@ -9,10 +15,49 @@ type Client struct {
 
 	// The following fields are optional. When they are empty
 	// we will fallback to sensible defaults.
-	BaseURL      string
-	GobCodec     GobCodec
-	HTTPClient   HTTPClient
-	JSONCodec    JSONCodec
-	RequestMaker RequestMaker
-	UserAgent    string
+	BaseURL         string
+	CircuitBreaker  *CircuitBreaker
+	GobCodec        GobCodec
+	HTTPClient      HTTPClient
+	Instrumentation Instrumentation
+	JSONCodec       JSONCodec
+	Limiter         Limiter
+	RequestMaker    RequestMaker
+	RetryPolicy     *RetryPolicy
+	UserAgent       string
+
+	// SensitiveHTTPClient is the OPTIONAL HTTP client for calling
+	// the APIs that may reveal the probe's identity (i.e., register,
+	// login, and check-in), e.g., an HTTP client using Tor. When it
+	// is nil, we use HTTPClient for all APIs.
+	SensitiveHTTPClient HTTPClient
+}
+
+// These are the transports we may use for calling an API.
+const (
+	// TransportDirect indicates that we're using HTTPClient.
+	TransportDirect = "direct"
+
+	// TransportSensitive indicates that we're using SensitiveHTTPClient.
+	TransportSensitive = "sensitive"
+)
+
+// httpClientFor returns the HTTP client for calling the given API, which
+// honours the Limiter, if any, and notifies the instrumentation about
+// the transport we have chosen.
+func (c *Client) httpClientFor(api string, sensitive bool) HTTPClient {
+	clnt, transport := c.HTTPClient, TransportDirect
+	if sensitive && c.SensitiveHTTPClient != nil {
+		clnt, transport = c.SensitiveHTTPClient, TransportSensitive
+	}
+	if c.Instrumentation != nil {
+		c.Instrumentation.OnTransportSelected(api, transport)
+	}
+	if c.Limiter != nil {
+		if clnt == nil {
+			clnt = http.DefaultClient
+		}
+		clnt = httpx.NewRateLimitedHTTPClient(clnt, c.Limiter)
+	}
+	return clnt
 }
@@ -0,0 +1,83 @@
+package ooapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
+)
+
+func TestClientSensitiveHTTPClient(t *testing.T) {
+	errDirect := errors.New("mocked direct error")
+	errSensitive := errors.New("mocked sensitive error")
+	fi := &fakeInstrumentation{}
+	clnt := &Client{
+		HTTPClient:          &FakeHTTPClient{Err: errDirect},
+		Instrumentation:     fi,
+		KVStore:             &kvstore.Memory{},
+		SensitiveHTTPClient: &FakeHTTPClient{Err: errSensitive},
+	}
+	ctx := context.Background()
+	if _, err := clnt.CheckIn(ctx, &apimodel.CheckInRequest{}); !errors.Is(err, errSensitive) {
+		t.Fatal("not the error we expected", err)
+	}
+	if _, err := clnt.TestHelpers(ctx, &apimodel.TestHelpersRequest{}); !errors.Is(err, errDirect) {
+		t.Fatal("not the error we expected", err)
+	}
+	if fi.events[0] != "sensitive:CheckIn" {
+		t.Fatal("unexpected events", fi.events)
+	}
+	var found bool
+	for _, ev := range fi.events {
+		found = found || ev == "direct:TestHelpers"
+	}
+	if !found {
+		t.Fatal("unexpected events", fi.events)
+	}
+}
+
+func TestClientWithoutSensitiveHTTPClient(t *testing.T) {
+	errDirect := errors.New("mocked direct error")
+	fi := &fakeInstrumentation{}
+	clnt := &Client{
+		HTTPClient:      &FakeHTTPClient{Err: errDirect},
+		Instrumentation: fi,
+		KVStore:         &kvstore.Memory{},
+	}
+	_, err := clnt.CheckIn(context.Background(), &apimodel.CheckInRequest{})
+	if !errors.Is(err, errDirect) {
+		t.Fatal("not the error we expected", err)
+	}
+	if fi.events[0] != "direct:CheckIn" {
+		t.Fatal("unexpected events", fi.events)
+	}
+}
+
+// countingLimiter is a Limiter counting the calls to Wait.
+type countingLimiter struct {
+	count int
+}
+
+func (cl *countingLimiter) Wait(ctx context.Context, endpoint string) error {
+	cl.count++
+	return nil
+}
+
+func TestClientWithLimiter(t *testing.T) {
+	errMocked := errors.New("mocked error")
+	limiter := &countingLimiter{}
+	clnt := &Client{
+		HTTPClient: &FakeHTTPClient{Err: errMocked},
+		KVStore:    &kvstore.Memory{},
+		Limiter:    limiter,
+	}
+	_, err := clnt.TestHelpers(context.Background(), &apimodel.TestHelpersRequest{})
+	if !errors.Is(err, errMocked) {
+		t.Fatal("not the error we expected", err)
+	}
+	if limiter.count != 1 {
+		t.Fatal("expected the limiter to be called once", limiter.count)
+	}
+}
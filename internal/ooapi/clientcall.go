@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// 2022-05-19 20:30:48.430212 +0200 CEST m=+0.000888918
+// 2026-10-16 08:30:05.029474095 +0000 UTC m=+0.000098217
 
 package ooapi
 
@@ -11,10 +11,10 @@ import (
 	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
 )
 
-func (c *Client) newCheckReportIDCaller() callerForCheckReportIDAPI {
+func (c *Client) newCheckReportIDCaller(baseURL string) callerForCheckReportIDAPI {
 	return &simpleCheckReportIDAPI{
-		BaseURL:      c.BaseURL,
-		HTTPClient:   c.HTTPClient,
+		BaseURL:      baseURL,
+		HTTPClient:   c.httpClientFor("CheckReportID", false),
 		JSONCodec:    c.JSONCodec,
 		RequestMaker: c.RequestMaker,
 		UserAgent:    c.UserAgent,
@@ -25,17 +25,35 @@ func (c *Client) newCheckReportIDCaller() callerForCheckReportIDAPI {
 func (c *Client) CheckReportID(
 	ctx context.Context, req *apimodel.CheckReportIDRequest,
 ) (*apimodel.CheckReportIDResponse, error) {
-	api := c.newCheckReportIDCaller()
+	api := c.newCheckReportIDCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerCheckReportIDAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newCheckReportIDCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetryCheckReportIDAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationCheckReportIDAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
 
-func (c *Client) newCheckInCaller() callerForCheckInAPI {
-	return &simpleCheckInAPI{
-		BaseURL:      c.BaseURL,
-		HTTPClient:   c.HTTPClient,
-		JSONCodec:    c.JSONCodec,
-		RequestMaker: c.RequestMaker,
-		UserAgent:    c.UserAgent,
+func (c *Client) newCheckInCaller(baseURL string) callerForCheckInAPI {
+	return &withCacheCheckInAPI{
+		API: &simpleCheckInAPI{
+			BaseURL:      baseURL,
+			HTTPClient:   c.httpClientFor("CheckIn", true),
+			JSONCodec:    c.JSONCodec,
+			RequestMaker: c.RequestMaker,
+			UserAgent:    c.UserAgent,
+		},
+		GobCodec:        c.GobCodec,
+		Instrumentation: c.Instrumentation,
+		KVStore:         c.KVStore,
 	}
 }
 
@@ -43,21 +61,35 @@ func (c *Client) newCheckInCaller() callerForCheckInAPI {
 func (c *Client) CheckIn(
 	ctx context.Context, req *apimodel.CheckInRequest,
 ) (*apimodel.CheckInResponse, error) {
-	api := c.newCheckInCaller()
+	api := c.newCheckInCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerCheckInAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newCheckInCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetryCheckInAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationCheckInAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
 
-func (c *Client) newMeasurementMetaCaller() callerForMeasurementMetaAPI {
+func (c *Client) newMeasurementMetaCaller(baseURL string) callerForMeasurementMetaAPI {
 	return &withCacheMeasurementMetaAPI{
 		API: &simpleMeasurementMetaAPI{
-			BaseURL:      c.BaseURL,
-			HTTPClient:   c.HTTPClient,
+			BaseURL:      baseURL,
+			HTTPClient:   c.httpClientFor("MeasurementMeta", false),
 			JSONCodec:    c.JSONCodec,
 			RequestMaker: c.RequestMaker,
 			UserAgent:    c.UserAgent,
 		},
-		GobCodec: c.GobCodec,
-		KVStore:  c.KVStore,
+		GobCodec:        c.GobCodec,
+		Instrumentation: c.Instrumentation,
+		KVStore:         c.KVStore,
 	}
 }
 
@@ -65,14 +97,27 @@ func (c *Client) newMeasurementMetaCaller() callerForMeasurementMetaAPI {
 func (c *Client) MeasurementMeta(
 	ctx context.Context, req *apimodel.MeasurementMetaRequest,
 ) (*apimodel.MeasurementMetaResponse, error) {
-	api := c.newMeasurementMetaCaller()
+	api := c.newMeasurementMetaCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerMeasurementMetaAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newMeasurementMetaCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetryMeasurementMetaAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationMeasurementMetaAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
 
-func (c *Client) newTestHelpersCaller() callerForTestHelpersAPI {
+func (c *Client) newTestHelpersCaller(baseURL string) callerForTestHelpersAPI {
 	return &simpleTestHelpersAPI{
-		BaseURL:      c.BaseURL,
-		HTTPClient:   c.HTTPClient,
+		BaseURL:      baseURL,
+		HTTPClient:   c.httpClientFor("TestHelpers", false),
 		JSONCodec:    c.JSONCodec,
 		RequestMaker: c.RequestMaker,
 		UserAgent:    c.UserAgent,
@@ -83,15 +128,28 @@ func (c *Client) newTestHelpersCaller() callerForTestHelpersAPI {
 func (c *Client) TestHelpers(
 	ctx context.Context, req *apimodel.TestHelpersRequest,
 ) (apimodel.TestHelpersResponse, error) {
-	api := c.newTestHelpersCaller()
+	api := c.newTestHelpersCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerTestHelpersAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newTestHelpersCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetryTestHelpersAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationTestHelpersAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
 
-func (c *Client) newPsiphonConfigCaller() callerForPsiphonConfigAPI {
+func (c *Client) newPsiphonConfigCaller(baseURL string) callerForPsiphonConfigAPI {
 	return &withLoginPsiphonConfigAPI{
 		API: &simplePsiphonConfigAPI{
-			BaseURL:      c.BaseURL,
-			HTTPClient:   c.HTTPClient,
+			BaseURL:      baseURL,
+			HTTPClient:   c.httpClientFor("PsiphonConfig", false),
 			JSONCodec:    c.JSONCodec,
 			RequestMaker: c.RequestMaker,
 			UserAgent:    c.UserAgent,
@@ -99,15 +157,15 @@ func (c *Client) newPsiphonConfigCaller() callerForPsiphonConfigAPI {
 		JSONCodec: c.JSONCodec,
 		KVStore:   c.KVStore,
 		RegisterAPI: &simpleRegisterAPI{
-			BaseURL:      c.BaseURL,
-			HTTPClient:   c.HTTPClient,
+			BaseURL:      baseURL,
+			HTTPClient:   c.httpClientFor("Register", true),
 			JSONCodec:    c.JSONCodec,
 			RequestMaker: c.RequestMaker,
 			UserAgent:    c.UserAgent,
 		},
 		LoginAPI: &simpleLoginAPI{
-			BaseURL:      c.BaseURL,
-			HTTPClient:   c.HTTPClient,
+			BaseURL:      baseURL,
+			HTTPClient:   c.httpClientFor("Login", true),
 			JSONCodec:    c.JSONCodec,
 			RequestMaker: c.RequestMaker,
 			UserAgent:    c.UserAgent,
@@ -119,15 +177,28 @@ func (c *Client) newPsiphonConfigCaller() callerForPsiphonConfigAPI {
 func (c *Client) PsiphonConfig(
 	ctx context.Context, req *apimodel.PsiphonConfigRequest,
 ) (apimodel.PsiphonConfigResponse, error) {
-	api := c.newPsiphonConfigCaller()
+	api := c.newPsiphonConfigCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerPsiphonConfigAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newPsiphonConfigCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetryPsiphonConfigAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationPsiphonConfigAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
 
-func (c *Client) newTorTargetsCaller() callerForTorTargetsAPI {
+func (c *Client) newTorTargetsCaller(baseURL string) callerForTorTargetsAPI {
 	return &withLoginTorTargetsAPI{
 		API: &simpleTorTargetsAPI{
-			BaseURL:      c.BaseURL,
-			HTTPClient:   c.HTTPClient,
+			BaseURL:      baseURL,
+			HTTPClient:   c.httpClientFor("TorTargets", false),
 			JSONCodec:    c.JSONCodec,
 			RequestMaker: c.RequestMaker,
 			UserAgent:    c.UserAgent,
@@ -135,15 +206,15 @@ func (c *Client) newTorTargetsCaller() callerForTorTargetsAPI {
 		JSONCodec: c.JSONCodec,
 		KVStore:   c.KVStore,
 		RegisterAPI: &simpleRegisterAPI{
-			BaseURL:      c.BaseURL,
-			HTTPClient:   c.HTTPClient,
+			BaseURL:      baseURL,
+			HTTPClient:   c.httpClientFor("Register", true),
 			JSONCodec:    c.JSONCodec,
 			RequestMaker: c.RequestMaker,
 			UserAgent:    c.UserAgent,
 		},
 		LoginAPI: &simpleLoginAPI{
-			BaseURL:      c.BaseURL,
-			HTTPClient:   c.HTTPClient,
+			BaseURL:      baseURL,
+			HTTPClient:   c.httpClientFor("Login", true),
 			JSONCodec:    c.JSONCodec,
 			RequestMaker: c.RequestMaker,
 			UserAgent:    c.UserAgent,
@@ -155,14 +226,27 @@ func (c *Client) newTorTargetsCaller() callerForTorTargetsAPI {
 func (c *Client) TorTargets(
 	ctx context.Context, req *apimodel.TorTargetsRequest,
 ) (apimodel.TorTargetsResponse, error) {
-	api := c.newTorTargetsCaller()
+	api := c.newTorTargetsCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerTorTargetsAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newTorTargetsCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetryTorTargetsAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationTorTargetsAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
 
-func (c *Client) newURLsCaller() callerForURLsAPI {
+func (c *Client) newURLsCaller(baseURL string) callerForURLsAPI {
 	return &simpleURLsAPI{
-		BaseURL:      c.BaseURL,
-		HTTPClient:   c.HTTPClient,
+		BaseURL:      baseURL,
+		HTTPClient:   c.httpClientFor("URLs", false),
 		JSONCodec:    c.JSONCodec,
 		RequestMaker: c.RequestMaker,
 		UserAgent:    c.UserAgent,
@@ -173,14 +257,27 @@ func (c *Client) newURLsCaller() callerForURLsAPI {
 func (c *Client) URLs(
 	ctx context.Context, req *apimodel.URLsRequest,
 ) (*apimodel.URLsResponse, error) {
-	api := c.newURLsCaller()
+	api := c.newURLsCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerURLsAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newURLsCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetryURLsAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationURLsAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
 
-func (c *Client) newOpenReportCaller() callerForOpenReportAPI {
+func (c *Client) newOpenReportCaller(baseURL string) callerForOpenReportAPI {
 	return &simpleOpenReportAPI{
-		BaseURL:      c.BaseURL,
-		HTTPClient:   c.HTTPClient,
+		BaseURL:      baseURL,
+		HTTPClient:   c.httpClientFor("OpenReport", false),
 		JSONCodec:    c.JSONCodec,
 		RequestMaker: c.RequestMaker,
 		UserAgent:    c.UserAgent,
@@ -191,14 +288,27 @@ func (c *Client) newOpenReportCaller() callerForOpenReportAPI {
 func (c *Client) OpenReport(
 	ctx context.Context, req *apimodel.OpenReportRequest,
 ) (*apimodel.OpenReportResponse, error) {
-	api := c.newOpenReportCaller()
+	api := c.newOpenReportCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerOpenReportAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newOpenReportCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetryOpenReportAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationOpenReportAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
 
-func (c *Client) newSubmitMeasurementCaller() callerForSubmitMeasurementAPI {
+func (c *Client) newSubmitMeasurementCaller(baseURL string) callerForSubmitMeasurementAPI {
 	return &simpleSubmitMeasurementAPI{
-		BaseURL:      c.BaseURL,
-		HTTPClient:   c.HTTPClient,
+		BaseURL:      baseURL,
+		HTTPClient:   c.httpClientFor("SubmitMeasurement", false),
 		JSONCodec:    c.JSONCodec,
 		RequestMaker: c.RequestMaker,
 		UserAgent:    c.UserAgent,
@@ -209,6 +319,36 @@ func (c *Client) newSubmitMeasurementCaller() callerForSubmitMeasurementAPI {
 func (c *Client) SubmitMeasurement(
 	ctx context.Context, req *apimodel.SubmitMeasurementRequest,
 ) (*apimodel.SubmitMeasurementResponse, error) {
-	api := c.newSubmitMeasurementCaller()
+	api := c.newSubmitMeasurementCaller(c.BaseURL)
+	if c.CircuitBreaker != nil {
+		api = &withBreakerSubmitMeasurementAPI{
+			BaseURL:   c.BaseURL,
+			Breaker:   c.CircuitBreaker,
+			NewCaller: c.newSubmitMeasurementCaller,
+		}
+	}
+	if c.RetryPolicy != nil {
+		api = &withRetrySubmitMeasurementAPI{API: api, Policy: c.RetryPolicy}
+	}
+	if c.Instrumentation != nil {
+		api = &withInstrumentationSubmitMeasurementAPI{API: api, Instrumentation: c.Instrumentation}
+	}
 	return api.Call(ctx, req)
 }
+
+// API contains the Client methods calling the OONI API. Code
+// depending on API rather than on Client can be tested using
+// the fakes in the ootest package.
+type API interface {
+	CheckReportID(ctx context.Context, req *apimodel.CheckReportIDRequest) (*apimodel.CheckReportIDResponse, error)
+	CheckIn(ctx context.Context, req *apimodel.CheckInRequest) (*apimodel.CheckInResponse, error)
+	MeasurementMeta(ctx context.Context, req *apimodel.MeasurementMetaRequest) (*apimodel.MeasurementMetaResponse, error)
+	TestHelpers(ctx context.Context, req *apimodel.TestHelpersRequest) (apimodel.TestHelpersResponse, error)
+	PsiphonConfig(ctx context.Context, req *apimodel.PsiphonConfigRequest) (apimodel.PsiphonConfigResponse, error)
+	TorTargets(ctx context.Context, req *apimodel.TorTargetsRequest) (apimodel.TorTargetsResponse, error)
+	URLs(ctx context.Context, req *apimodel.URLsRequest) (*apimodel.URLsResponse, error)
+	OpenReport(ctx context.Context, req *apimodel.OpenReportRequest) (*apimodel.OpenReportResponse, error)
+	SubmitMeasurement(ctx context.Context, req *apimodel.SubmitMeasurementRequest) (*apimodel.SubmitMeasurementResponse, error)
+}
+
+var _ API = &Client{}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 	"text/template"
+	"time"
 )
 
 type defaultRequestMaker struct{}
@@ -55,3 +56,13 @@ func (*defaultGobCodec) Encode(v interface{}) ([]byte, error) {
 func (*defaultGobCodec) Decode(b []byte, v interface{}) error {
 	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
 }
+
+type defaultInstrumentation struct{}
+
+func (*defaultInstrumentation) OnCallStarted(api string) {}
+
+func (*defaultInstrumentation) OnCallFinished(api string, elapsed time.Duration, err error) {}
+
+func (*defaultInstrumentation) OnCacheLookup(api string, hit bool) {}
+
+func (*defaultInstrumentation) OnTransportSelected(api string, transport string) {}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"time"
 
+	"github.com/ooni/probe-cli/v3/internal/httpx"
 	"github.com/ooni/probe-cli/v3/internal/model"
 )
 
@@ -42,6 +44,11 @@ type templateExecutor interface {
 // with additional functionality (e.g., DoH, circumvention).
 type HTTPClient = model.HTTPClient
 
+// Limiter delays API calls to avoid hammering the backend. We use
+// no limiter by default. You can share an httpx.RateLimiter among the
+// clients using the same backend to enforce per-endpoint rates.
+type Limiter = httpx.Limiter
+
 // GobCodec is a Gob encoder and decoder. Generally, we use a
 // default GobCodec in Client. This is the interface to implement
 // if you want to override such a default.
@@ -57,3 +64,24 @@ type GobCodec interface {
 // client expect for the key-value store used to save persistent
 // state (typically on the file system).
 type KVStore = model.KeyValueStore
+
+// Instrumentation receives notifications about API calls. This is the
+// interface to implement if you want to export metrics or to log structured
+// telemetry about backend usage. By default, we use a no-op implementation.
+type Instrumentation interface {
+	// OnCallStarted is called when we start calling the given API.
+	OnCallStarted(api string)
+
+	// OnCallFinished is called when we are done calling the given API. The
+	// elapsed argument is the call duration and err is the outcome.
+	OnCallFinished(api string, elapsed time.Duration, err error)
+
+	// OnCacheLookup is called after we search the cache of the given
+	// API. The hit argument tells whether we found a response.
+	OnCacheLookup(api string, hit bool)
+
+	// OnTransportSelected is called when we choose the HTTP client for
+	// calling the given API. The transport argument is TransportDirect or
+	// TransportSensitive (see Client.SensitiveHTTPClient).
+	OnTransportSelected(api string, transport string)
+}
@@ -18,7 +18,9 @@
 //
 // If an API requires login, we will automatically
 // perform the login. If an API uses caching, we will
-// automatically use the cache.
+// automatically use the cache. If you set the Client's
+// RetryPolicy, we will retry transient failures (i.e., timeouts
+// and 5xx responses) using exponential backoff.
 //
 // Design
 //
@@ -30,6 +32,9 @@
 //
 //     go generate ./...
 //
+// We also generate openapi.json, an OpenAPI v3.0 document describing
+// the APIs implemented by this package.
+//
 // We have tests that ensure that the definition of the API
 // used here is reasonably close to the server's one.
 //
@@ -56,3 +61,5 @@
 // code in this package. In particular, the spec.go file is
 // the specification of the APIs.
 package ooapi
+
+//go:generate go run ./internal/generator -file openapi.json
@@ -5,8 +5,10 @@ import "errors"
 // Errors defined by this package.
 var (
 	ErrAPICallFailed   = errors.New("ooapi: API call failed")
+	ErrCircuitOpen     = errors.New("ooapi: circuit breaker is open")
 	ErrEmptyField      = errors.New("ooapi: empty field")
 	ErrHTTPFailure     = errors.New("ooapi: http request failed")
+	ErrInvalidPageSize = errors.New("ooapi: invalid page size")
 	ErrJSONLiteralNull = errors.New("ooapi: server returned us a literal null")
 	ErrMissingToken    = errors.New("ooapi: missing auth token")
 	ErrUnauthorized    = errors.New("ooapi: not authorized")
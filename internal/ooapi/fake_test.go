@@ -101,3 +101,11 @@ func (fs *FakeKVStore) Get(key string) ([]byte, error) {
 func (fs *FakeKVStore) Set(key string, value []byte) error {
 	return fs.SetError
 }
+
+func (fs *FakeKVStore) Delete(key string) error {
+	return fs.SetError
+}
+
+func (fs *FakeKVStore) ListKeys() ([]string, error) {
+	return nil, fs.GetError
+}
@@ -0,0 +1,100 @@
+package ooapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/kvstore"
+	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
+)
+
+// fakeInstrumentation records the events it receives.
+type fakeInstrumentation struct {
+	events []string
+	errs   []error
+	mu     sync.Mutex
+}
+
+func (fi *fakeInstrumentation) OnCallStarted(api string) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.events = append(fi.events, "started:"+api)
+}
+
+func (fi *fakeInstrumentation) OnCallFinished(api string, elapsed time.Duration, err error) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.events = append(fi.events, "finished:"+api)
+	fi.errs = append(fi.errs, err)
+}
+
+func (fi *fakeInstrumentation) OnCacheLookup(api string, hit bool) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	if hit {
+		fi.events = append(fi.events, "hit:"+api)
+		return
+	}
+	fi.events = append(fi.events, "miss:"+api)
+}
+
+func (fi *fakeInstrumentation) OnTransportSelected(api string, transport string) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.events = append(fi.events, transport+":"+api)
+}
+
+func TestWithInstrumentationCheckInAPI(t *testing.T) {
+	errMocked := errors.New("mocked error")
+	fi := &fakeInstrumentation{}
+	api := &withInstrumentationCheckInAPI{
+		API:             &FakeCheckInAPI{Err: errMocked},
+		Instrumentation: fi,
+	}
+	resp, err := api.Call(context.Background(), &apimodel.CheckInRequest{})
+	if !errors.Is(err, errMocked) {
+		t.Fatal("not the error we expected", err)
+	}
+	if resp != nil {
+		t.Fatal("expected nil response")
+	}
+	if len(fi.events) != 2 || fi.events[0] != "started:CheckIn" || fi.events[1] != "finished:CheckIn" {
+		t.Fatal("unexpected events", fi.events)
+	}
+	if !errors.Is(fi.errs[0], errMocked) {
+		t.Fatal("unexpected outcome", fi.errs[0])
+	}
+}
+
+func TestWithCacheMeasurementMetaAPIInstrumentation(t *testing.T) {
+	ff := &fakeFill{}
+	var expect *apimodel.MeasurementMetaResponse
+	ff.Fill(&expect)
+	fi := &fakeInstrumentation{}
+	cache := &withCacheMeasurementMetaAPI{
+		API:             &FakeMeasurementMetaAPI{Response: expect},
+		Instrumentation: fi,
+		KVStore:         &kvstore.Memory{},
+	}
+	var req *apimodel.MeasurementMetaRequest
+	ff.Fill(&req)
+	ctx := context.Background()
+	for idx := 0; idx < 2; idx++ {
+		if _, err := cache.Call(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fi.events) != 2 || fi.events[0] != "miss:MeasurementMeta" || fi.events[1] != "hit:MeasurementMeta" {
+		t.Fatal("unexpected events", fi.events)
+	}
+}
+
+func TestDefaultInstrumentationIsNoop(t *testing.T) {
+	var inst Instrumentation = &defaultInstrumentation{}
+	inst.OnCallStarted("CheckIn")
+	inst.OnCallFinished("CheckIn", time.Second, nil)
+	inst.OnCacheLookup("CheckIn", true)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (d *Descriptor) genNewBreaker(sb *strings.Builder) {
+	fmt.Fprintf(sb, "// %s implements circuit breaking for %s.\n",
+		d.WithBreakerAPIStructName(), d.APIStructName())
+	fmt.Fprintf(sb, "type %s struct {\n", d.WithBreakerAPIStructName())
+	fmt.Fprint(sb, "\tBaseURL string // optional\n")
+	fmt.Fprint(sb, "\tBreaker *CircuitBreaker // mandatory\n")
+	fmt.Fprintf(sb, "\tNewCaller func(baseURL string) %s // mandatory\n", d.CallerInterfaceName())
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "// Call selects an endpoint using the circuit breaker and calls the API.\n")
+	fmt.Fprintf(sb, "func (api *%s) Call(ctx context.Context, req %s) (%s, error) {\n",
+		d.WithBreakerAPIStructName(), d.RequestTypeName(), d.ResponseTypeName())
+	fmt.Fprint(sb, "\tbaseURL, err := api.Breaker.acquire(api.BaseURL)\n")
+	fmt.Fprint(sb, "\tif err != nil {\n")
+	fmt.Fprint(sb, "\t\treturn nil, err\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tresp, err := api.NewCaller(baseURL).Call(ctx, req)\n")
+	fmt.Fprint(sb, "\tapi.Breaker.release(baseURL, err)\n")
+	fmt.Fprint(sb, "\tif err != nil {\n")
+	fmt.Fprint(sb, "\t\treturn nil, err\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\treturn resp, nil\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "var _ %s = &%s{}\n\n", d.CallerInterfaceName(),
+		d.WithBreakerAPIStructName())
+}
+
+// GenBreakerGo generates breaker.go.
+func GenBreakerGo(file string) {
+	var sb strings.Builder
+	fmt.Fprint(&sb, "// Code generated by go generate; DO NOT EDIT.\n")
+	fmt.Fprintf(&sb, "// %s\n\n", time.Now())
+	fmt.Fprint(&sb, "package ooapi\n\n")
+	fmt.Fprintf(&sb, "//go:generate go run ./internal/generator -file %s\n\n", file)
+	fmt.Fprint(&sb, "import (\n")
+	fmt.Fprint(&sb, "\t\"context\"\n")
+	fmt.Fprint(&sb, "\n")
+	fmt.Fprint(&sb, "\t\"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel\"\n")
+	fmt.Fprint(&sb, ")\n")
+	for _, desc := range Descriptors {
+		switch desc.Name {
+		case "Register", "Login":
+			// These APIs are only used by the login wrappers.
+			continue
+		}
+		desc.genNewBreaker(&sb)
+	}
+	writefile(file, &sb)
+}
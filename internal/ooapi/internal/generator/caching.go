@@ -12,6 +12,7 @@ func (d *Descriptor) genNewCache(sb *strings.Builder) {
 	fmt.Fprintf(sb, "type %s struct {\n", d.WithCacheAPIStructName())
 	fmt.Fprintf(sb, "\tAPI %s // mandatory\n", d.CallerInterfaceName())
 	fmt.Fprint(sb, "\tGobCodec GobCodec // optional\n")
+	fmt.Fprint(sb, "\tInstrumentation Instrumentation // optional\n")
 	fmt.Fprint(sb, "\tKVStore KVStore // mandatory\n")
 	fmt.Fprint(sb, "}\n\n")
 
@@ -24,14 +25,14 @@ func (d *Descriptor) genNewCache(sb *strings.Builder) {
 	fmt.Fprintf(sb, "func (c *%s) Call(ctx context.Context, req %s) (%s, error) {\n",
 		d.WithCacheAPIStructName(), d.RequestTypeName(), d.ResponseTypeName())
 	if d.CachePolicy == CacheAlways {
-		fmt.Fprint(sb, "\tif resp, _ := c.readcache(req); resp != nil {\n")
+		fmt.Fprint(sb, "\tif resp, _ := c.lookupcache(req); resp != nil {\n")
 		fmt.Fprint(sb, "\t\treturn resp, nil\n")
 		fmt.Fprint(sb, "\t}\n")
 	}
 	fmt.Fprint(sb, "\tresp, err := c.API.Call(ctx, req)\n")
 	fmt.Fprint(sb, "\tif err != nil {\n")
 	if d.CachePolicy == CacheFallback {
-		fmt.Fprint(sb, "\t\tif resp, _ := c.readcache(req); resp != nil {\n")
+		fmt.Fprint(sb, "\t\tif resp, _ := c.lookupcache(req); resp != nil {\n")
 		fmt.Fprint(sb, "\t\t\treturn resp, nil\n")
 		fmt.Fprint(sb, "\t\t}\n")
 	}
@@ -50,6 +51,13 @@ func (d *Descriptor) genNewCache(sb *strings.Builder) {
 	fmt.Fprint(sb, "\treturn &defaultGobCodec{}\n")
 	fmt.Fprint(sb, "}\n\n")
 
+	fmt.Fprintf(sb, "func (c *%s) instrumentation() Instrumentation {\n", d.WithCacheAPIStructName())
+	fmt.Fprint(sb, "\tif c.Instrumentation != nil {\n")
+	fmt.Fprint(sb, "\t\treturn c.Instrumentation\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\treturn &defaultInstrumentation{}\n")
+	fmt.Fprint(sb, "}\n\n")
+
 	fmt.Fprintf(sb, "func (c *%s) getcache() ([]%s, error) {\n",
 		d.WithCacheAPIStructName(), d.CacheEntryName())
 	fmt.Fprintf(sb, "\tdata, err := c.KVStore.Get(\"%s\")\n", d.CacheKey())
@@ -86,6 +94,13 @@ func (d *Descriptor) genNewCache(sb *strings.Builder) {
 	fmt.Fprint(sb, "\treturn nil, errCacheNotFound\n")
 	fmt.Fprint(sb, "}\n\n")
 
+	fmt.Fprintf(sb, "func (c *%s) lookupcache(req %s) (%s, error) {\n",
+		d.WithCacheAPIStructName(), d.RequestTypeName(), d.ResponseTypeName())
+	fmt.Fprint(sb, "\tresp, err := c.readcache(req)\n")
+	fmt.Fprintf(sb, "\tc.instrumentation().OnCacheLookup(\"%s\", resp != nil)\n", d.Name)
+	fmt.Fprint(sb, "\treturn resp, err\n")
+	fmt.Fprint(sb, "}\n\n")
+
 	fmt.Fprintf(sb, "func (c *%s) writecache(req %s, resp %s) error {\n",
 		d.WithCacheAPIStructName(), d.RequestTypeName(), d.ResponseTypeName())
 	fmt.Fprint(sb, "\tcache, _ := c.getcache()\n")
@@ -103,8 +118,64 @@ func (d *Descriptor) genNewCache(sb *strings.Builder) {
 	fmt.Fprint(sb, "\treturn c.setcache(out)\n")
 	fmt.Fprint(sb, "}\n\n")
 
+	fmt.Fprintf(sb, "func (c *%s) invalidate(req %s) error {\n",
+		d.WithCacheAPIStructName(), d.RequestTypeName())
+	fmt.Fprint(sb, "\t// Implementation note: if we cannot read the cache, we\n")
+	fmt.Fprint(sb, "\t// cannot keep any entry, so we end up flushing it.\n")
+	fmt.Fprint(sb, "\tcache, _ := c.getcache()\n")
+	fmt.Fprintf(sb, "\tvar out []%s\n", d.CacheEntryName())
+	fmt.Fprint(sb, "\tfor _, cur := range cache {\n")
+	fmt.Fprint(sb, "\t\tif reflect.DeepEqual(req, cur.Req) {\n")
+	fmt.Fprint(sb, "\t\t\tcontinue\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t\tout = append(out, cur)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\treturn c.setcache(out)\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "func (c *%s) flush() error {\n", d.WithCacheAPIStructName())
+	fmt.Fprint(sb, "\treturn c.setcache(nil)\n")
+	fmt.Fprint(sb, "}\n\n")
+
 	fmt.Fprintf(sb, "var _ %s = &%s{}\n\n", d.CallerInterfaceName(),
 		d.WithCacheAPIStructName())
+
+	fmt.Fprintf(sb, "// Invalidate%sCache removes the cached response for req, if any.\n", d.Name)
+	fmt.Fprintf(sb, "func (c *Client) Invalidate%sCache(req %s) error {\n",
+		d.Name, d.RequestTypeName())
+	fmt.Fprintf(sb, "\tcache := &%s{GobCodec: c.GobCodec, KVStore: c.KVStore}\n",
+		d.WithCacheAPIStructName())
+	fmt.Fprint(sb, "\treturn cache.invalidate(req)\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "// Flush%sCache removes all the cached responses of the %s API.\n",
+		d.Name, d.Name)
+	fmt.Fprintf(sb, "func (c *Client) Flush%sCache() error {\n", d.Name)
+	fmt.Fprintf(sb, "\tcache := &%s{GobCodec: c.GobCodec, KVStore: c.KVStore}\n",
+		d.WithCacheAPIStructName())
+	fmt.Fprint(sb, "\treturn cache.flush()\n")
+	fmt.Fprint(sb, "}\n\n")
+}
+
+func genFlushCaches(sb *strings.Builder) {
+	fmt.Fprint(sb, "// FlushCaches removes all the cached responses of all the APIs. You\n")
+	fmt.Fprint(sb, "// typically want to call this method when the network changes.\n")
+	fmt.Fprint(sb, "func (c *Client) FlushCaches() error {\n")
+	fmt.Fprint(sb, "\tflushers := []func() error{\n")
+	for _, desc := range Descriptors {
+		if desc.CachePolicy == CacheNone {
+			continue
+		}
+		fmt.Fprintf(sb, "\t\tc.Flush%sCache,\n", desc.Name)
+	}
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tfor _, flush := range flushers {\n")
+	fmt.Fprint(sb, "\t\tif err := flush(); err != nil {\n")
+	fmt.Fprint(sb, "\t\t\treturn err\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\treturn nil\n")
+	fmt.Fprint(sb, "}\n")
 }
 
 // GenCachingGo generates caching.go.
@@ -126,5 +197,6 @@ func GenCachingGo(file string) {
 		}
 		desc.genNewCache(&sb)
 	}
+	genFlushCaches(&sb)
 	writefile(file, &sb)
 }
@@ -242,6 +242,63 @@ func (d *Descriptor) genTestCachSizeLimited(sb *strings.Builder) {
 	fmt.Fprint(sb, "}\n\n")
 }
 
+func (d *Descriptor) genTestInvalidateCache(sb *strings.Builder) {
+	if fields := d.StructFields(d.Request); len(fields) <= 0 {
+		// this test cannot work when there are no fields in the
+		// request because we will always find a match.
+		return
+	}
+	fmt.Fprintf(sb, "func TestCache%sInvalidate(t *testing.T) {\n", d.APIStructName())
+	fmt.Fprint(sb, "\tff := &fakeFill{}\n")
+	fmt.Fprint(sb, "\tclnt := &Client{KVStore: &kvstore.Memory{}}\n")
+	fmt.Fprintf(sb, "\tcache := &%s{\n", d.WithCacheAPIStructName())
+	fmt.Fprint(sb, "\t\tKVStore: clnt.KVStore,\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprintf(sb, "\tvar req1, req2 %s\n", d.RequestTypeName())
+	fmt.Fprint(sb, "\tff.Fill(&req1)\n")
+	fmt.Fprint(sb, "\tff.Fill(&req2)\n")
+	fmt.Fprintf(sb, "\tvar resp %s\n", d.ResponseTypeName())
+	fmt.Fprint(sb, "\tff.Fill(&resp)\n")
+	fmt.Fprintf(sb, "\tfor _, req := range []%s{req1, req2} {\n", d.RequestTypeName())
+	fmt.Fprint(sb, "\t\tif err := cache.writecache(req, resp); err != nil {\n")
+	fmt.Fprint(sb, "\t\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprintf(sb, "\tif err := clnt.Invalidate%sCache(req1); err != nil {\n", d.Name)
+	fmt.Fprint(sb, "\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tif _, err := cache.readcache(req1); !errors.Is(err, errCacheNotFound) {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(\"not the error we expected\", err)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tif _, err := cache.readcache(req2); err != nil {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "}\n\n")
+}
+
+func (d *Descriptor) genTestFlushCache(sb *strings.Builder) {
+	fmt.Fprintf(sb, "func TestCache%sFlush(t *testing.T) {\n", d.APIStructName())
+	fmt.Fprint(sb, "\tff := &fakeFill{}\n")
+	fmt.Fprint(sb, "\tclnt := &Client{KVStore: &kvstore.Memory{}}\n")
+	fmt.Fprintf(sb, "\tcache := &%s{\n", d.WithCacheAPIStructName())
+	fmt.Fprint(sb, "\t\tKVStore: clnt.KVStore,\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprintf(sb, "\tvar req %s\n", d.RequestTypeName())
+	fmt.Fprint(sb, "\tff.Fill(&req)\n")
+	fmt.Fprintf(sb, "\tvar resp %s\n", d.ResponseTypeName())
+	fmt.Fprint(sb, "\tff.Fill(&resp)\n")
+	fmt.Fprint(sb, "\tif err := cache.writecache(req, resp); err != nil {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tif err := clnt.FlushCaches(); err != nil {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tif _, err := cache.readcache(req); !errors.Is(err, errCacheNotFound) {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(\"not the error we expected\", err)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "}\n\n")
+}
+
 // GenCachingTestGo generates caching_test.go.
 func GenCachingTestGo(file string) {
 	var sb strings.Builder
@@ -270,6 +327,8 @@ func GenCachingTestGo(file string) {
 		desc.genTestReadCacheNotFound(&sb)
 		desc.genTestWriteCacheDuplicate(&sb)
 		desc.genTestCachSizeLimited(&sb)
+		desc.genTestInvalidateCache(&sb)
+		desc.genTestFlushCache(&sb)
 	}
 	writefile(file, &sb)
 }
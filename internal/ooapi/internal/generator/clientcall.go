@@ -6,13 +6,36 @@ import (
 	"time"
 )
 
+// clientFieldValue returns the expression we should use to
+// initialize the given field of the given API inside a newXXXCaller method.
+func clientFieldValue(d *Descriptor, name string) string {
+	switch name {
+	case "BaseURL":
+		return "baseURL"
+	case "HTTPClient":
+		return fmt.Sprintf("c.httpClientFor(\"%s\", %v)", d.Name, d.Sensitive)
+	default:
+		return "c." + name
+	}
+}
+
+// descriptorNamed returns the descriptor of the API with the given name.
+func descriptorNamed(name string) *Descriptor {
+	for idx := range Descriptors {
+		if Descriptors[idx].Name == name {
+			return &Descriptors[idx]
+		}
+	}
+	panic("no such descriptor: " + name)
+}
+
 func (d *Descriptor) clientMakeAPIBase(sb *strings.Builder) {
 	fmt.Fprintf(sb, "&%s{\n", d.APIStructName())
 	for _, field := range apiFields {
 		if field.ifLogin || field.ifTemplate {
 			continue
 		}
-		fmt.Fprintf(sb, "\t%s: c.%s,\n", field.name, field.name)
+		fmt.Fprintf(sb, "\t%s: %s,\n", field.name, clientFieldValue(d, field.name))
 	}
 	fmt.Fprint(sb, "}")
 }
@@ -33,7 +56,8 @@ func (d *Descriptor) clientMakeAPI(sb *strings.Builder) {
 			if field.ifLogin || field.ifTemplate {
 				continue
 			}
-			fmt.Fprintf(sb, "\t%s: c.%s,\n", field.name, field.name)
+			fmt.Fprintf(sb, "\t%s: %s,\n", field.name,
+				clientFieldValue(descriptorNamed("Register"), field.name))
 		}
 		fmt.Fprint(sb, "\t},\n")
 		fmt.Fprint(sb, "\tLoginAPI: &simpleLoginAPI{\n")
@@ -41,7 +65,8 @@ func (d *Descriptor) clientMakeAPI(sb *strings.Builder) {
 			if field.ifLogin || field.ifTemplate {
 				continue
 			}
-			fmt.Fprintf(sb, "\t%s: c.%s,\n", field.name, field.name)
+			fmt.Fprintf(sb, "\t%s: %s,\n", field.name,
+				clientFieldValue(descriptorNamed("Login"), field.name))
 		}
 		fmt.Fprint(sb, "\t},\n")
 		fmt.Fprint(sb, "}\n")
@@ -53,6 +78,7 @@ func (d *Descriptor) clientMakeAPI(sb *strings.Builder) {
 		d.clientMakeAPIBase(sb)
 		fmt.Fprint(sb, ",\n")
 		fmt.Fprint(sb, "\tGobCodec: c.GobCodec,\n")
+		fmt.Fprint(sb, "\tInstrumentation: c.Instrumentation,\n")
 		fmt.Fprint(sb, "\tKVStore: c.KVStore,\n")
 		fmt.Fprint(sb, "}\n")
 		return
@@ -62,7 +88,7 @@ func (d *Descriptor) clientMakeAPI(sb *strings.Builder) {
 }
 
 func (d *Descriptor) genClientNewCaller(sb *strings.Builder) {
-	fmt.Fprintf(sb, "func (c *Client) new%sCaller() ", d.Name)
+	fmt.Fprintf(sb, "func (c *Client) new%sCaller(baseURL string) ", d.Name)
 	fmt.Fprintf(sb, "%s {\n", d.CallerInterfaceName())
 	fmt.Fprint(sb, "\treturn ")
 	d.clientMakeAPI(sb)
@@ -74,7 +100,21 @@ func (d *Descriptor) genClientCall(sb *strings.Builder) {
 	fmt.Fprintf(sb, "func (c *Client) %s(\n", d.Name)
 	fmt.Fprintf(sb, "ctx context.Context, req %s,\n) ", d.RequestTypeName())
 	fmt.Fprintf(sb, "(%s, error) {\n", d.ResponseTypeName())
-	fmt.Fprintf(sb, "\tapi := c.new%sCaller()\n", d.Name)
+	fmt.Fprintf(sb, "\tapi := c.new%sCaller(c.BaseURL)\n", d.Name)
+	fmt.Fprint(sb, "\tif c.CircuitBreaker != nil {\n")
+	fmt.Fprintf(sb, "\t\tapi = &%s{\n", d.WithBreakerAPIStructName())
+	fmt.Fprint(sb, "\t\t\tBaseURL: c.BaseURL,\n")
+	fmt.Fprint(sb, "\t\t\tBreaker: c.CircuitBreaker,\n")
+	fmt.Fprintf(sb, "\t\t\tNewCaller: c.new%sCaller,\n", d.Name)
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tif c.RetryPolicy != nil {\n")
+	fmt.Fprintf(sb, "\t\tapi = &%s{API: api, Policy: c.RetryPolicy}\n", d.WithRetryAPIStructName())
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tif c.Instrumentation != nil {\n")
+	fmt.Fprintf(sb, "\t\tapi = &%s{API: api, Instrumentation: c.Instrumentation}\n",
+		d.WithInstrumentationAPIStructName())
+	fmt.Fprint(sb, "\t}\n")
 	fmt.Fprint(sb, "\treturn api.Call(ctx, req)\n")
 	fmt.Fprint(sb, "}\n\n")
 }
@@ -92,13 +132,25 @@ func GenClientCallGo(file string) {
 	fmt.Fprint(&sb, "\t\"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel\"\n")
 	fmt.Fprint(&sb, ")\n")
 	for _, desc := range Descriptors {
-		switch desc.Name {
-		case "Register", "Login":
+		if !desc.isToplevelAPI() {
 			// We don't want to generate these APIs as toplevel.
 			continue
 		}
 		desc.genClientNewCaller(&sb)
 		desc.genClientCall(&sb)
 	}
+	fmt.Fprint(&sb, "// API contains the Client methods calling the OONI API. Code\n")
+	fmt.Fprint(&sb, "// depending on API rather than on Client can be tested using\n")
+	fmt.Fprint(&sb, "// the fakes in the ootest package.\n")
+	fmt.Fprint(&sb, "type API interface {\n")
+	for _, desc := range Descriptors {
+		if !desc.isToplevelAPI() {
+			continue
+		}
+		fmt.Fprintf(&sb, "\t%s(ctx context.Context, req %s) (%s, error)\n",
+			desc.Name, desc.RequestTypeName(), desc.ResponseTypeName())
+	}
+	fmt.Fprint(&sb, "}\n\n")
+	fmt.Fprint(&sb, "var _ API = &Client{}\n")
 	writefile(file, &sb)
 }
@@ -49,8 +49,20 @@ func main() {
 		GenLoginTestGo(file)
 	case "clientcall.go":
 		GenClientCallGo(file)
+	case "retry.go":
+		GenRetryGo(file)
+	case "breaker.go":
+		GenBreakerGo(file)
+	case "instrumentation.go":
+		GenInstrumentationGo(file)
+	case "pagination.go":
+		GenPaginationGo(file)
+	case "openapi.json":
+		GenOpenAPIJSON(file)
 	case "clientcall_test.go":
 		GenClientCallTestGo(file)
+	case "fakeapi.go":
+		GenOOTestFakeAPIGo(file)
 	default:
 		panic(fmt.Sprintf("don't know how to create this file: %s", file))
 	}
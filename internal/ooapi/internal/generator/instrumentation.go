@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (d *Descriptor) genNewInstrumentation(sb *strings.Builder) {
+	fmt.Fprintf(sb, "// %s implements instrumentation for %s.\n",
+		d.WithInstrumentationAPIStructName(), d.APIStructName())
+	fmt.Fprintf(sb, "type %s struct {\n", d.WithInstrumentationAPIStructName())
+	fmt.Fprintf(sb, "\tAPI %s // mandatory\n", d.CallerInterfaceName())
+	fmt.Fprint(sb, "\tInstrumentation Instrumentation // mandatory\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "// Call calls the API and notifies the instrumentation.\n")
+	fmt.Fprintf(sb, "func (api *%s) Call(ctx context.Context, req %s) (%s, error) {\n",
+		d.WithInstrumentationAPIStructName(), d.RequestTypeName(), d.ResponseTypeName())
+	fmt.Fprintf(sb, "\tapi.Instrumentation.OnCallStarted(\"%s\")\n", d.Name)
+	fmt.Fprint(sb, "\tt0 := time.Now()\n")
+	fmt.Fprint(sb, "\tresp, err := api.API.Call(ctx, req)\n")
+	fmt.Fprintf(sb, "\tapi.Instrumentation.OnCallFinished(\"%s\", time.Since(t0), err)\n", d.Name)
+	fmt.Fprint(sb, "\tif err != nil {\n")
+	fmt.Fprint(sb, "\t\treturn nil, err\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\treturn resp, nil\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "var _ %s = &%s{}\n\n", d.CallerInterfaceName(),
+		d.WithInstrumentationAPIStructName())
+}
+
+// GenInstrumentationGo generates instrumentation.go.
+func GenInstrumentationGo(file string) {
+	var sb strings.Builder
+	fmt.Fprint(&sb, "// Code generated by go generate; DO NOT EDIT.\n")
+	fmt.Fprintf(&sb, "// %s\n\n", time.Now())
+	fmt.Fprint(&sb, "package ooapi\n\n")
+	fmt.Fprintf(&sb, "//go:generate go run ./internal/generator -file %s\n\n", file)
+	fmt.Fprint(&sb, "import (\n")
+	fmt.Fprint(&sb, "\t\"context\"\n")
+	fmt.Fprint(&sb, "\t\"time\"\n")
+	fmt.Fprint(&sb, "\n")
+	fmt.Fprint(&sb, "\t\"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel\"\n")
+	fmt.Fprint(&sb, ")\n")
+	for _, desc := range Descriptors {
+		switch desc.Name {
+		case "Register", "Login":
+			// These APIs are only used by the login wrappers.
+			continue
+		}
+		desc.genNewInstrumentation(&sb)
+	}
+	writefile(file, &sb)
+}
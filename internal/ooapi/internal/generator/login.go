@@ -29,10 +29,10 @@ func (d *Descriptor) genNewLogin(sb *strings.Builder) {
 	fmt.Fprint(sb, "\t\t// Maybe the clock is just off? Let's try to obtain\n")
 	fmt.Fprint(sb, "\t\t// a token again and see if this fixes it.\n")
 	fmt.Fprint(sb, "\t\tif token, err = api.forceLogin(ctx); err == nil {\n")
-	fmt.Fprint(sb, "\t\t\tswitch resp, err = api.API.WithToken(token).Call(ctx, req); err {\n")
-	fmt.Fprint(sb, "\t\t\tcase nil:\n")
+	fmt.Fprint(sb, "\t\t\tswitch resp, err = api.API.WithToken(token).Call(ctx, req); {\n")
+	fmt.Fprint(sb, "\t\t\tcase err == nil:\n")
 	fmt.Fprint(sb, "\t\t\t\treturn resp, nil\n")
-	fmt.Fprint(sb, "\t\t\tcase ErrUnauthorized:\n")
+	fmt.Fprint(sb, "\t\t\tcase errors.Is(err, ErrUnauthorized):\n")
 	fmt.Fprint(sb, "\t\t\t\t// fallthrough\n")
 	fmt.Fprint(sb, "\t\t\tdefault:\n")
 	fmt.Fprint(sb, "\t\t\t\treturn nil, err\n")
@@ -133,7 +133,13 @@ func (d *Descriptor) genNewLogin(sb *strings.Builder) {
 	fmt.Fprint(sb, "\t\treturn api.forceRegister(ctx)\n")
 	fmt.Fprint(sb, "\t}\n")
 	fmt.Fprint(sb, "\tif !ls.tokenValid() {\n")
-	fmt.Fprint(sb, "\t\treturn api.doLogin(ctx, ls)\n")
+	fmt.Fprint(sb, "\t\ttoken, err := api.doLogin(ctx, ls)\n")
+	fmt.Fprint(sb, "\t\tif errors.Is(err, ErrUnauthorized) {\n")
+	fmt.Fprint(sb, "\t\t\t// The server rejected our credentials, perhaps because\n")
+	fmt.Fprint(sb, "\t\t\t// its database was replaced. Let's register again.\n")
+	fmt.Fprint(sb, "\t\t\treturn api.forceRegister(ctx)\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t\treturn token, err\n")
 	fmt.Fprint(sb, "\t}\n")
 	fmt.Fprint(sb, "\treturn ls.Token, nil\n")
 	fmt.Fprint(sb, "}\n\n")
@@ -148,8 +154,7 @@ func (d *Descriptor) genNewLogin(sb *strings.Builder) {
 	fmt.Fprint(sb, "\tif err != nil {\n")
 	fmt.Fprint(sb, "\t\treturn \"\", err\n")
 	fmt.Fprint(sb, "\t}\n")
-	fmt.Fprint(sb, "\tls.Token = resp.Token\n")
-	fmt.Fprint(sb, "\tls.Expire = resp.Expire\n")
+	fmt.Fprint(sb, "\tls.setToken(resp)\n")
 	fmt.Fprint(sb, "\tif err := api.writestate(ls); err != nil {\n")
 	fmt.Fprint(sb, "\t\treturn \"\", err\n")
 	fmt.Fprint(sb, "\t}\n")
@@ -444,6 +444,93 @@ func (d *Descriptor) genTestTheDatabaseIsReplaced(sb *strings.Builder) {
 	fmt.Fprint(sb, "}\n\n")
 }
 
+func (d *Descriptor) genTestTheDatabaseIsReplacedAndTokenExpired(sb *strings.Builder) {
+	fmt.Fprintf(sb, "func Test%sTheDatabaseIsReplacedAndTokenExpired(t *testing.T) {\n", d.Name)
+	fmt.Fprint(sb, "\tff := &fakeFill{}\n")
+	fmt.Fprint(sb, "\thandler := &LoginHandler{\n")
+	fmt.Fprint(sb, "\t\tlogins: &atomicx.Int64{},\n")
+	fmt.Fprint(sb, "\t\tregisters: &atomicx.Int64{},\n")
+	fmt.Fprint(sb, "\t\tt: t,\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tsrvr := httptest.NewServer(handler)\n")
+	fmt.Fprint(sb, "\tdefer srvr.Close()\n")
+
+	fmt.Fprint(sb, "\tregisterAPI := &simpleRegisterAPI{\n")
+	fmt.Fprint(sb, "\t\tHTTPClient: &VerboseHTTPClient{T: t},\n")
+	fmt.Fprint(sb, "\t\tBaseURL: srvr.URL,\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\t\tloginAPI := &simpleLoginAPI{\n")
+	fmt.Fprint(sb, "\t\tHTTPClient: &VerboseHTTPClient{T: t},\n")
+	fmt.Fprint(sb, "\t\tBaseURL: srvr.URL,\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprintf(sb, "\tbaseAPI := &%s{\n", d.APIStructName())
+	fmt.Fprint(sb, "\t\tHTTPClient: &VerboseHTTPClient{T: t},\n")
+	fmt.Fprint(sb, "\t\tBaseURL: srvr.URL,\n")
+	fmt.Fprint(sb, "\t}\n")
+
+	fmt.Fprintf(sb, "\tlogin := &%s{\n", d.WithLoginAPIStructName())
+	fmt.Fprintf(sb, "\tAPI : baseAPI,\n")
+	fmt.Fprint(sb, "\tRegisterAPI: registerAPI,\n")
+	fmt.Fprint(sb, "\tLoginAPI: loginAPI,\n")
+	fmt.Fprint(sb, "\tKVStore: &kvstore.Memory{},\n")
+	fmt.Fprint(sb, "\t}\n")
+
+	fmt.Fprintf(sb, "\tvar req %s\n", d.RequestTypeName())
+	fmt.Fprint(sb, "\tff.Fill(&req)\n")
+	fmt.Fprint(sb, "\tctx := context.Background()\n")
+
+	fmt.Fprint(sb, "\t// step 1: we register and login and use the token\n")
+	fmt.Fprint(sb, "\t// inside a scope just to avoid mistakes\n")
+
+	fmt.Fprint(sb, "\t{\n")
+	fmt.Fprint(sb, "\t\tresp, err := login.Call(ctx, req)\n")
+	fmt.Fprint(sb, "\t\tif err != nil {\n")
+	fmt.Fprint(sb, "\t\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t\tif resp == nil {\n")
+	fmt.Fprint(sb, "\t\t\tt.Fatal(\"expected non-nil response\")\n")
+	fmt.Fprint(sb, "\t\t}\n")
+
+	fmt.Fprint(sb, "\t\tif handler.logins.Load() != 1 {\n")
+	fmt.Fprint(sb, "\t\t\tt.Fatal(\"invalid handler.logins\")\n")
+	fmt.Fprint(sb, "\t\t}\n")
+
+	fmt.Fprint(sb, "\t\tif handler.registers.Load() != 1 {\n")
+	fmt.Fprint(sb, "\t\t\tt.Fatal(\"invalid handler.registers\")\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t}\n")
+
+	fmt.Fprint(sb, "\t// step 2: we forget accounts, expire the token and try\n")
+	fmt.Fprint(sb, "\t// again, so that we're rejected when logging in.\n")
+	fmt.Fprint(sb, "\thandler.forgetLogins()\n")
+	fmt.Fprint(sb, "\tls, err := login.readstate()\n")
+	fmt.Fprint(sb, "\tif err != nil {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tls.Expire = time.Now().Add(-5 * time.Second)\n")
+	fmt.Fprint(sb, "\tif err := login.writestate(ls); err != nil {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t}\n")
+
+	fmt.Fprint(sb, "\tresp, err := login.Call(ctx, req)\n")
+	fmt.Fprint(sb, "\tif err != nil {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(err)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tif resp == nil {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(\"expected non-nil response\")\n")
+	fmt.Fprint(sb, "\t}\n")
+
+	fmt.Fprint(sb, "\tif handler.logins.Load() != 3 {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(\"invalid handler.logins\")\n")
+	fmt.Fprint(sb, "\t}\n")
+
+	fmt.Fprint(sb, "\tif handler.registers.Load() != 2 {\n")
+	fmt.Fprint(sb, "\t\tt.Fatal(\"invalid handler.registers\")\n")
+	fmt.Fprint(sb, "\t}\n")
+
+	fmt.Fprint(sb, "}\n\n")
+}
+
 func (d *Descriptor) genTestTheDatabaseIsReplacedThenFailure(sb *strings.Builder) {
 	fmt.Fprintf(sb, "func Test%sTheDatabaseIsReplacedThenFailure(t *testing.T) {\n", d.Name)
 	fmt.Fprint(sb, "\tff := &fakeFill{}\n")
@@ -888,6 +975,7 @@ func GenLoginTestGo(file string) {
 		desc.genTestWithLoginFailure(&sb)
 		desc.genTestRegisterAndLoginThenFail(&sb)
 		desc.genTestTheDatabaseIsReplaced(&sb)
+		desc.genTestTheDatabaseIsReplacedAndTokenExpired(&sb)
 		desc.genTestRegisterAndLoginCannotWriteState(&sb)
 		desc.genTestReadStateDecodeFailure(&sb)
 		desc.genTestTheDatabaseIsReplacedThenFailure(&sb)
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// isToplevelAPI returns whether Client exposes the API as a method.
+func (d *Descriptor) isToplevelAPI() bool {
+	switch d.Name {
+	case "Register", "Login":
+		return false
+	default:
+		return true
+	}
+}
+
+func (d *Descriptor) genNewOOTestFakeAPI(sb *strings.Builder) {
+	fmt.Fprintf(sb, "// %s is a fake implementation of the %s API.\n",
+		d.FakeAPIStructName(), d.Name)
+	fmt.Fprintf(sb, "type %s struct {\n", d.FakeAPIStructName())
+	fmt.Fprint(sb, "\tErr error\n")
+	fmt.Fprintf(sb, "\tResponse %s\n", d.ResponseTypeName())
+	fmt.Fprint(sb, "\tCountCall *atomicx.Int64\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "// Call returns the configured Response and Err.\n")
+	fmt.Fprintf(sb, "func (fapi *%s) Call(ctx context.Context, req %s) (%s, error) {\n",
+		d.FakeAPIStructName(), d.RequestTypeName(), d.ResponseTypeName())
+	fmt.Fprint(sb, "\tif fapi.CountCall != nil {\n")
+	fmt.Fprint(sb, "\t\tfapi.CountCall.Add(1)\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\treturn fapi.Response, fapi.Err\n")
+	fmt.Fprint(sb, "}\n\n")
+}
+
+func (d *Descriptor) genOOTestFakeClientCall(sb *strings.Builder) {
+	fmt.Fprintf(sb, "// %s calls %sAPI or fails with ErrNotConfigured.\n", d.Name, d.Name)
+	fmt.Fprintf(sb, "func (fc *FakeClient) %s(\n", d.Name)
+	fmt.Fprintf(sb, "ctx context.Context, req %s,\n) ", d.RequestTypeName())
+	fmt.Fprintf(sb, "(%s, error) {\n", d.ResponseTypeName())
+	fmt.Fprintf(sb, "\tif fc.%sAPI == nil {\n", d.Name)
+	fmt.Fprintf(sb, "\t\treturn nil, fmt.Errorf(\"%%w: %s\", ErrNotConfigured)\n", d.Name)
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprintf(sb, "\treturn fc.%sAPI.Call(ctx, req)\n", d.Name)
+	fmt.Fprint(sb, "}\n\n")
+}
+
+// GenOOTestFakeAPIGo generates ootest/fakeapi.go.
+func GenOOTestFakeAPIGo(file string) {
+	var sb strings.Builder
+	fmt.Fprint(&sb, "// Code generated by go generate; DO NOT EDIT.\n")
+	fmt.Fprintf(&sb, "// %s\n\n", time.Now())
+	fmt.Fprint(&sb, "package ootest\n\n")
+	fmt.Fprintf(&sb, "//go:generate go run ../internal/generator -file %s\n\n", file)
+	fmt.Fprint(&sb, "import (\n")
+	fmt.Fprint(&sb, "\t\"context\"\n")
+	fmt.Fprint(&sb, "\t\"fmt\"\n")
+	fmt.Fprint(&sb, "\n")
+	fmt.Fprint(&sb, "\t\"github.com/ooni/probe-cli/v3/internal/atomicx\"\n")
+	fmt.Fprint(&sb, "\t\"github.com/ooni/probe-cli/v3/internal/ooapi\"\n")
+	fmt.Fprint(&sb, "\t\"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel\"\n")
+	fmt.Fprint(&sb, ")\n\n")
+	for _, desc := range Descriptors {
+		desc.genNewOOTestFakeAPI(&sb)
+	}
+	fmt.Fprint(&sb, "// FakeClient is a fake ooapi.API. Each API method calls the\n")
+	fmt.Fprint(&sb, "// corresponding fake API, when configured, and otherwise\n")
+	fmt.Fprint(&sb, "// fails with an error wrapping ErrNotConfigured.\n")
+	fmt.Fprint(&sb, "type FakeClient struct {\n")
+	for _, desc := range Descriptors {
+		if !desc.isToplevelAPI() {
+			continue
+		}
+		fmt.Fprintf(&sb, "\t%sAPI *%s\n", desc.Name, desc.FakeAPIStructName())
+	}
+	fmt.Fprint(&sb, "}\n\n")
+	for _, desc := range Descriptors {
+		if !desc.isToplevelAPI() {
+			continue
+		}
+		desc.genOOTestFakeClientCall(&sb)
+	}
+	fmt.Fprint(&sb, "var _ ooapi.API = &FakeClient{}\n")
+	writefile(file, &sb)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// checkPagination panics if the request or the response do not
+// contain the fields mentioned by d.Pagination.
+func (d *Descriptor) checkPagination() {
+	p := d.Pagination
+	reqType := reflect.TypeOf(d.Request).Elem()
+	for _, name := range []string{p.OffsetField, p.LimitField} {
+		f, found := reqType.FieldByName(name)
+		if !found || f.Type.Kind() != reflect.Int64 {
+			panic(fmt.Sprintf("%s: missing int64 request field: %s", d.Name, name))
+		}
+	}
+	respType := reflect.TypeOf(d.Response).Elem()
+	f, found := respType.FieldByName(p.ResultsField)
+	if !found || f.Type.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("%s: missing slice response field: %s", d.Name, p.ResultsField))
+	}
+}
+
+func (d *Descriptor) genNewIterator(sb *strings.Builder) {
+	d.checkPagination()
+	p := d.Pagination
+
+	fmt.Fprintf(sb, "// %s iterates over the pages returned by the %s API.\n",
+		d.IteratorStructName(), d.Name)
+	fmt.Fprintf(sb, "type %s struct {\n", d.IteratorStructName())
+	fmt.Fprintf(sb, "\tcall func(ctx context.Context, req %s) (%s, error)\n",
+		d.RequestTypeName(), d.ResponseTypeName())
+	fmt.Fprint(sb, "\tdone bool\n")
+	fmt.Fprint(sb, "\terr error\n")
+	fmt.Fprintf(sb, "\treq %s\n", d.RequestTypeName())
+	fmt.Fprintf(sb, "\tresp %s\n", d.ResponseTypeName())
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "// New%s creates a new iterator for the %s API. We use\n",
+		d.IteratorStructName(), d.Name)
+	fmt.Fprintf(sb, "// req.%s as the starting offset and req.%s, which must be\n",
+		p.OffsetField, p.LimitField)
+	fmt.Fprint(sb, "// positive, as the page size.\n")
+	fmt.Fprintf(sb, "func (c *Client) New%s(req %s) *%s {\n",
+		d.IteratorStructName(), d.RequestTypeName(), d.IteratorStructName())
+	fmt.Fprint(sb, "\tclone := *req // we're going to modify the offset\n")
+	fmt.Fprintf(sb, "\treturn &%s{call: c.%s, req: &clone}\n", d.IteratorStructName(), d.Name)
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprint(sb, "// Next fetches the next page. It returns false when there are no\n")
+	fmt.Fprint(sb, "// more pages or on failure. Use Err to know whether it failed. We\n")
+	fmt.Fprint(sb, "// stop after a page shorter than the page size and when a page is\n")
+	fmt.Fprint(sb, "// equal to the previous one, which happens when the server ignores\n")
+	fmt.Fprint(sb, "// the offset, such that we never loop forever.\n")
+	fmt.Fprintf(sb, "func (it *%s) Next(ctx context.Context) bool {\n", d.IteratorStructName())
+	fmt.Fprint(sb, "\tif it.done {\n")
+	fmt.Fprint(sb, "\t\treturn false\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprintf(sb, "\tif it.req.%s <= 0 {\n", p.LimitField)
+	fmt.Fprint(sb, "\t\tit.done, it.err, it.resp = true, ErrInvalidPageSize, nil\n")
+	fmt.Fprint(sb, "\t\treturn false\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\treq := *it.req // the API may keep a reference to req\n")
+	fmt.Fprint(sb, "\tresp, err := it.call(ctx, &req)\n")
+	fmt.Fprint(sb, "\tif err != nil {\n")
+	fmt.Fprint(sb, "\t\tit.done, it.err, it.resp = true, err, nil\n")
+	fmt.Fprint(sb, "\t\treturn false\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprintf(sb, "\tcount := int64(len(resp.%s))\n", p.ResultsField)
+	fmt.Fprintf(sb, "\tif count <= 0 || (it.resp != nil && reflect.DeepEqual(resp.%s, it.resp.%s)) {\n",
+		p.ResultsField, p.ResultsField)
+	fmt.Fprint(sb, "\t\tit.done, it.resp = true, nil\n")
+	fmt.Fprint(sb, "\t\treturn false\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\tit.resp = resp\n")
+	fmt.Fprintf(sb, "\tit.req.%s += count\n", p.OffsetField)
+	fmt.Fprintf(sb, "\tif count < it.req.%s {\n", p.LimitField)
+	fmt.Fprint(sb, "\t\tit.done = true // this is the last page\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "\treturn true\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprint(sb, "// Page returns the current page.\n")
+	fmt.Fprintf(sb, "func (it *%s) Page() %s {\n", d.IteratorStructName(), d.ResponseTypeName())
+	fmt.Fprint(sb, "\treturn it.resp\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprint(sb, "// Err returns the error that stopped the iteration, if any.\n")
+	fmt.Fprintf(sb, "func (it *%s) Err() error {\n", d.IteratorStructName())
+	fmt.Fprint(sb, "\treturn it.err\n")
+	fmt.Fprint(sb, "}\n\n")
+}
+
+// GenPaginationGo generates pagination.go.
+func GenPaginationGo(file string) {
+	var sb strings.Builder
+	fmt.Fprint(&sb, "// Code generated by go generate; DO NOT EDIT.\n")
+	fmt.Fprintf(&sb, "// %s\n\n", time.Now())
+	fmt.Fprint(&sb, "package ooapi\n\n")
+	fmt.Fprintf(&sb, "//go:generate go run ./internal/generator -file %s\n\n", file)
+	fmt.Fprint(&sb, "import (\n")
+	fmt.Fprint(&sb, "\t\"context\"\n")
+	fmt.Fprint(&sb, "\t\"reflect\"\n")
+	fmt.Fprint(&sb, "\n")
+	fmt.Fprint(&sb, "\t\"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel\"\n")
+	fmt.Fprint(&sb, ")\n")
+	for _, desc := range Descriptors {
+		if desc.Pagination == nil {
+			continue
+		}
+		desc.genNewIterator(&sb)
+	}
+	writefile(file, &sb)
+}
@@ -56,6 +56,30 @@ func (d *Descriptor) WithCacheAPIStructName() string {
 	return fmt.Sprintf("withCache%sAPI", d.Name)
 }
 
+// WithRetryAPIStructName returns the correct struct type name for
+// the retry wrapper for the API we're currently processing.
+func (d *Descriptor) WithRetryAPIStructName() string {
+	return fmt.Sprintf("withRetry%sAPI", d.Name)
+}
+
+// WithBreakerAPIStructName returns the correct struct type name for
+// the circuit breaker wrapper for the API we're currently processing.
+func (d *Descriptor) WithBreakerAPIStructName() string {
+	return fmt.Sprintf("withBreaker%sAPI", d.Name)
+}
+
+// WithInstrumentationAPIStructName returns the correct struct type name
+// for the instrumentation wrapper for the API we're currently processing.
+func (d *Descriptor) WithInstrumentationAPIStructName() string {
+	return fmt.Sprintf("withInstrumentation%sAPI", d.Name)
+}
+
+// IteratorStructName returns the correct struct type name for
+// the pages iterator for the API we're currently processing.
+func (d *Descriptor) IteratorStructName() string {
+	return fmt.Sprintf("%sIterator", d.Name)
+}
+
 // CacheEntryName returns the correct struct type name for the
 // cache entry for the API we're currently processing.
 func (d *Descriptor) CacheEntryName() string {
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (d *Descriptor) genNewRetry(sb *strings.Builder) {
+	fmt.Fprintf(sb, "// %s implements retrying for %s.\n",
+		d.WithRetryAPIStructName(), d.APIStructName())
+	fmt.Fprintf(sb, "type %s struct {\n", d.WithRetryAPIStructName())
+	fmt.Fprintf(sb, "\tAPI %s // mandatory\n", d.CallerInterfaceName())
+	fmt.Fprint(sb, "\tPolicy *RetryPolicy // mandatory\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "// Call calls the API and retries transient failures.\n")
+	fmt.Fprintf(sb, "func (api *%s) Call(ctx context.Context, req %s) (%s, error) {\n",
+		d.WithRetryAPIStructName(), d.RequestTypeName(), d.ResponseTypeName())
+	fmt.Fprint(sb, "\tfor attempt := 1; ; attempt++ {\n")
+	fmt.Fprint(sb, "\t\tresp, err := api.API.Call(ctx, req)\n")
+	fmt.Fprintf(sb, "\t\tinfo := &CallAttempt{API: \"%s\", Attempt: attempt, Err: err}\n", d.Name)
+	fmt.Fprint(sb, "\t\tif err == nil {\n")
+	fmt.Fprint(sb, "\t\t\tapi.Policy.onAttempt(info)\n")
+	fmt.Fprint(sb, "\t\t\treturn resp, nil\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t\tif !api.Policy.shouldRetry(attempt, err) {\n")
+	fmt.Fprint(sb, "\t\t\tapi.Policy.onAttempt(info)\n")
+	fmt.Fprint(sb, "\t\t\treturn nil, err\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t\tinfo.Retry, info.Delay = true, api.Policy.delay(attempt)\n")
+	fmt.Fprint(sb, "\t\tapi.Policy.onAttempt(info)\n")
+	fmt.Fprint(sb, "\t\tif err := retrySleep(ctx, info.Delay); err != nil {\n")
+	fmt.Fprint(sb, "\t\t\treturn nil, err\n")
+	fmt.Fprint(sb, "\t\t}\n")
+	fmt.Fprint(sb, "\t}\n")
+	fmt.Fprint(sb, "}\n\n")
+
+	fmt.Fprintf(sb, "var _ %s = &%s{}\n\n", d.CallerInterfaceName(),
+		d.WithRetryAPIStructName())
+}
+
+// GenRetryGo generates retry.go.
+func GenRetryGo(file string) {
+	var sb strings.Builder
+	fmt.Fprint(&sb, "// Code generated by go generate; DO NOT EDIT.\n")
+	fmt.Fprintf(&sb, "// %s\n\n", time.Now())
+	fmt.Fprint(&sb, "package ooapi\n\n")
+	fmt.Fprintf(&sb, "//go:generate go run ./internal/generator -file %s\n\n", file)
+	fmt.Fprint(&sb, "import (\n")
+	fmt.Fprint(&sb, "\t\"context\"\n")
+	fmt.Fprint(&sb, "\n")
+	fmt.Fprint(&sb, "\t\"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel\"\n")
+	fmt.Fprint(&sb, ")\n")
+	for _, desc := range Descriptors {
+		desc.genNewRetry(&sb)
+	}
+	writefile(file, &sb)
+}
@@ -28,6 +28,11 @@ type Descriptor struct {
 	// RequiresLogin indicates whether the API requires login.
 	RequiresLogin bool
 
+	// Sensitive indicates that calling the API may reveal the identity
+	// of the probe (e.g., registration, check-in), so that we use the
+	// Client's SensitiveHTTPClient, when configured.
+	Sensitive bool
+
 	// Method is the method to use ("GET" or "POST").
 	Method string
 
@@ -39,6 +44,24 @@ type Descriptor struct {
 
 	// Response is an instance of the response type.
 	Response interface{}
+
+	// Pagination is OPTIONAL and describes how to paginate the
+	// results of APIs that return large lists.
+	Pagination *Pagination
+}
+
+// Pagination describes offset/limit pagination. The request must
+// be a pointer to struct containing int64 offset and limit fields and
+// the response must be a pointer to struct containing a slice.
+type Pagination struct {
+	// OffsetField is the name of the request's offset field.
+	OffsetField string
+
+	// LimitField is the name of the request's limit field.
+	LimitField string
+
+	// ResultsField is the name of the response's results field.
+	ResultsField string
 }
 
 // These are the caching policies.
@@ -67,17 +90,20 @@ var Descriptors = []Descriptor{{
 	Request:  &apimodel.CheckReportIDRequest{},
 	Response: &apimodel.CheckReportIDResponse{},
 }, {
-	Name:     "CheckIn",
-	Method:   "POST",
-	URLPath:  URLPath{Value: "/api/v1/check-in"},
-	Request:  &apimodel.CheckInRequest{},
-	Response: &apimodel.CheckInResponse{},
+	Name:        "CheckIn",
+	Sensitive:   true,
+	Method:      "POST",
+	URLPath:     URLPath{Value: "/api/v1/check-in"},
+	Request:     &apimodel.CheckInRequest{},
+	Response:    &apimodel.CheckInResponse{},
+	CachePolicy: CacheFallback,
 }, {
-	Name:     "Login",
-	Method:   "POST",
-	URLPath:  URLPath{Value: "/api/v1/login"},
-	Request:  &apimodel.LoginRequest{},
-	Response: &apimodel.LoginResponse{},
+	Name:      "Login",
+	Sensitive: true,
+	Method:    "POST",
+	URLPath:   URLPath{Value: "/api/v1/login"},
+	Request:   &apimodel.LoginRequest{},
+	Response:  &apimodel.LoginResponse{},
 }, {
 	Name:        "MeasurementMeta",
 	Method:      "GET",
@@ -86,11 +112,12 @@ var Descriptors = []Descriptor{{
 	Response:    &apimodel.MeasurementMetaResponse{},
 	CachePolicy: CacheAlways,
 }, {
-	Name:     "Register",
-	Method:   "POST",
-	URLPath:  URLPath{Value: "/api/v1/register"},
-	Request:  &apimodel.RegisterRequest{},
-	Response: &apimodel.RegisterResponse{},
+	Name:      "Register",
+	Sensitive: true,
+	Method:    "POST",
+	URLPath:   URLPath{Value: "/api/v1/register"},
+	Request:   &apimodel.RegisterRequest{},
+	Response:  &apimodel.RegisterResponse{},
 }, {
 	Name:     "TestHelpers",
 	Method:   "GET",
@@ -117,6 +144,11 @@ var Descriptors = []Descriptor{{
 	URLPath:  URLPath{Value: "/api/v1/test-list/urls"},
 	Request:  &apimodel.URLsRequest{},
 	Response: &apimodel.URLsResponse{},
+	Pagination: &Pagination{
+		OffsetField:  "Offset",
+		LimitField:   "Limit",
+		ResultsField: "Results",
+	},
 }, {
 	Name:     "OpenReport",
 	Method:   "POST",
@@ -163,9 +163,9 @@ func genSwaggerVersion() string {
 	return time.Now().UTC().Format("0.20060102.1150405")
 }
 
-// GenSwaggerTestGo generates swagger_test.go
-func GenSwaggerTestGo(file string) {
-	swagger := openapi.Swagger{
+// genSwaggerModel generates the Swagger v2.0 model.
+func genSwaggerModel() *openapi.Swagger {
+	swagger := &openapi.Swagger{
 		Swagger: "2.0",
 		Info: openapi.API{
 			Title:   "OONI API specification",
@@ -180,7 +180,12 @@ func GenSwaggerTestGo(file string) {
 		pathStr, pathInfo := desc.genSwaggerPath()
 		swagger.Paths[pathStr] = pathInfo
 	}
-	data, err := json.MarshalIndent(swagger, "", "    ")
+	return swagger
+}
+
+// GenSwaggerTestGo generates swagger_test.go
+func GenSwaggerTestGo(file string) {
+	data, err := json.MarshalIndent(genSwaggerModel(), "", "    ")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -192,3 +197,12 @@ func GenSwaggerTestGo(file string) {
 	fmt.Fprintf(&sb, "const swagger = `%s`\n", string(data))
 	writefile(file, &sb)
 }
+
+// GenOpenAPIJSON generates openapi.json, i.e., the OpenAPI v3.0 document.
+func GenOpenAPIJSON(file string) {
+	data, err := json.MarshalIndent(openapi.FromSwagger(genSwaggerModel()), "", "    ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	writedata(file, append(data, '\n'))
+}
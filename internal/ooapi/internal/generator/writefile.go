@@ -25,3 +25,9 @@ func writefile(name string, sb *strings.Builder) {
 		log.Fatal(err)
 	}
 }
+
+func writedata(name string, data []byte) {
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,4 +1,5 @@
-// Package openapi contains data structures for Swagger v2.0.
+// Package openapi contains data structures for Swagger v2.0
+// and for OpenAPI v3.0.
 //
 // We use these data structures to compare the API specification we
 // have here with the one of the server.
@@ -0,0 +1,126 @@
+package openapi
+
+// This file contains data structures for OpenAPI v3.0. We generate an
+// OpenAPI v3.0 document from the same descriptors we use to generate the
+// Swagger v2.0 specification, and we convert the server's Swagger v2.0
+// specification to OpenAPI v3.0 to compare them.
+
+// V3Document is the toplevel OpenAPI v3.0 structure.
+type V3Document struct {
+	OpenAPI string                 `json:"openapi"`
+	Info    API                    `json:"info"`
+	Servers []*V3Server            `json:"servers,omitempty"`
+	Paths   map[string]*V3PathItem `json:"paths"`
+}
+
+// V3Server describes a server exposing the API.
+type V3Server struct {
+	URL string `json:"url"`
+}
+
+// V3PathItem describes a path served by the API.
+type V3PathItem struct {
+	Get  *V3Operation `json:"get,omitempty"`
+	Post *V3Operation `json:"post,omitempty"`
+}
+
+// V3Operation describes an HTTP round trip with a given method and path.
+type V3Operation struct {
+	Parameters  []*V3Parameter         `json:"parameters,omitempty"`
+	RequestBody *V3RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*V3Response `json:"responses,omitempty"`
+}
+
+// V3Parameter describes a parameter in the URL path or in the query string.
+type V3Parameter struct {
+	In       string  `json:"in"`
+	Name     string  `json:"name"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+// V3RequestBody describes a request body.
+type V3RequestBody struct {
+	Required bool                    `json:"required,omitempty"`
+	Content  map[string]*V3MediaType `json:"content"`
+}
+
+// V3Response describes a response.
+type V3Response struct {
+	Description interface{}             `json:"description"`
+	Content     map[string]*V3MediaType `json:"content,omitempty"`
+}
+
+// V3MediaType describes the body for a given media type.
+type V3MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// FromSwagger converts a Swagger v2.0 specification to OpenAPI v3.0.
+func FromSwagger(in *Swagger) *V3Document {
+	out := &V3Document{
+		OpenAPI: "3.0.3",
+		Info:    in.Info,
+		Paths:   make(map[string]*V3PathItem),
+	}
+	for _, scheme := range in.Schemes {
+		if in.Host != "" {
+			out.Servers = append(out.Servers, &V3Server{
+				URL: scheme + "://" + in.Host + in.BasePath,
+			})
+		}
+	}
+	for key, path := range in.Paths {
+		out.Paths[key] = &V3PathItem{
+			Get:  fromSwaggerRoundTrip(path.Get),
+			Post: fromSwaggerRoundTrip(path.Post),
+		}
+	}
+	return out
+}
+
+func fromSwaggerRoundTrip(in *RoundTrip) *V3Operation {
+	if in == nil {
+		return nil
+	}
+	out := &V3Operation{}
+	consumes := fromSwaggerMediaTypes(in.Consumes)
+	for _, param := range in.Parameters {
+		if param.In == "body" {
+			out.RequestBody = &V3RequestBody{
+				Required: param.Required,
+				Content:  make(map[string]*V3MediaType),
+			}
+			for _, mt := range consumes {
+				out.RequestBody.Content[mt] = &V3MediaType{Schema: param.Schema}
+			}
+			continue
+		}
+		out.Parameters = append(out.Parameters, &V3Parameter{
+			In:       param.In,
+			Name:     param.Name,
+			Required: param.Required,
+			Schema:   &Schema{Type: param.Type},
+		})
+	}
+	if in.Responses != nil {
+		resp := &V3Response{Description: in.Responses.Successful.Description}
+		if in.Responses.Successful.Schema != nil {
+			resp.Content = make(map[string]*V3MediaType)
+			for _, mt := range fromSwaggerMediaTypes(in.Produces) {
+				resp.Content[mt] = &V3MediaType{Schema: in.Responses.Successful.Schema}
+			}
+		}
+		out.Responses = map[string]*V3Response{"200": resp}
+	}
+	return out
+}
+
+// fromSwaggerMediaTypes returns the media types to use. Swagger v2.0
+// allows to omit them, while OpenAPI v3.0 requires them.
+func fromSwaggerMediaTypes(in []string) []string {
+	if len(in) <= 0 {
+		return []string{"application/json"}
+	}
+	return in
+}
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// 2022-05-19 20:30:49.900013 +0200 CEST m=+0.000719959
+// 2026-10-16 08:05:21.422653273 +0000 UTC m=+0.000137276
 
 package ooapi
 
@@ -32,10 +32,10 @@ func (api *withLoginPsiphonConfigAPI) Call(ctx context.Context, req *apimodel.Ps
 		// Maybe the clock is just off? Let's try to obtain
 		// a token again and see if this fixes it.
 		if token, err = api.forceLogin(ctx); err == nil {
-			switch resp, err = api.API.WithToken(token).Call(ctx, req); err {
-			case nil:
+			switch resp, err = api.API.WithToken(token).Call(ctx, req); {
+			case err == nil:
 				return resp, nil
-			case ErrUnauthorized:
+			case errors.Is(err, ErrUnauthorized):
 				// fallthrough
 			default:
 				return nil, err
@@ -129,7 +129,13 @@ func (api *withLoginPsiphonConfigAPI) maybeLogin(ctx context.Context) (string, e
 		return api.forceRegister(ctx)
 	}
 	if !ls.tokenValid() {
-		return api.doLogin(ctx, ls)
+		token, err := api.doLogin(ctx, ls)
+		if errors.Is(err, ErrUnauthorized) {
+			// The server rejected our credentials, perhaps because
+			// its database was replaced. Let's register again.
+			return api.forceRegister(ctx)
+		}
+		return token, err
 	}
 	return ls.Token, nil
 }
@@ -143,8 +149,7 @@ func (api *withLoginPsiphonConfigAPI) doLogin(ctx context.Context, ls *loginStat
 	if err != nil {
 		return "", err
 	}
-	ls.Token = resp.Token
-	ls.Expire = resp.Expire
+	ls.setToken(resp)
 	if err := api.writestate(ls); err != nil {
 		return "", err
 	}
@@ -173,10 +178,10 @@ func (api *withLoginTorTargetsAPI) Call(ctx context.Context, req *apimodel.TorTa
 		// Maybe the clock is just off? Let's try to obtain
 		// a token again and see if this fixes it.
 		if token, err = api.forceLogin(ctx); err == nil {
-			switch resp, err = api.API.WithToken(token).Call(ctx, req); err {
-			case nil:
+			switch resp, err = api.API.WithToken(token).Call(ctx, req); {
+			case err == nil:
 				return resp, nil
-			case ErrUnauthorized:
+			case errors.Is(err, ErrUnauthorized):
 				// fallthrough
 			default:
 				return nil, err
@@ -270,7 +275,13 @@ func (api *withLoginTorTargetsAPI) maybeLogin(ctx context.Context) (string, erro
 		return api.forceRegister(ctx)
 	}
 	if !ls.tokenValid() {
-		return api.doLogin(ctx, ls)
+		token, err := api.doLogin(ctx, ls)
+		if errors.Is(err, ErrUnauthorized) {
+			// The server rejected our credentials, perhaps because
+			// its database was replaced. Let's register again.
+			return api.forceRegister(ctx)
+		}
+		return token, err
 	}
 	return ls.Token, nil
 }
@@ -284,8 +295,7 @@ func (api *withLoginTorTargetsAPI) doLogin(ctx context.Context, ls *loginState)
 	if err != nil {
 		return "", err
 	}
-	ls.Token = resp.Token
-	ls.Expire = resp.Expire
+	ls.setToken(resp)
 	if err := api.writestate(ls); err != nil {
 		return "", err
 	}
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// 2022-05-19 20:30:50.443807 +0200 CEST m=+0.000781168
+// 2026-10-16 08:05:21.572492121 +0000 UTC m=+0.000142156
 
 package ooapi
 
@@ -383,6 +383,79 @@ func TestPsiphonConfigTheDatabaseIsReplaced(t *testing.T) {
 	}
 }
 
+func TestPsiphonConfigTheDatabaseIsReplacedAndTokenExpired(t *testing.T) {
+	ff := &fakeFill{}
+	handler := &LoginHandler{
+		logins:    &atomicx.Int64{},
+		registers: &atomicx.Int64{},
+		t:         t,
+	}
+	srvr := httptest.NewServer(handler)
+	defer srvr.Close()
+	registerAPI := &simpleRegisterAPI{
+		HTTPClient: &VerboseHTTPClient{T: t},
+		BaseURL:    srvr.URL,
+	}
+	loginAPI := &simpleLoginAPI{
+		HTTPClient: &VerboseHTTPClient{T: t},
+		BaseURL:    srvr.URL,
+	}
+	baseAPI := &simplePsiphonConfigAPI{
+		HTTPClient: &VerboseHTTPClient{T: t},
+		BaseURL:    srvr.URL,
+	}
+	login := &withLoginPsiphonConfigAPI{
+		API:         baseAPI,
+		RegisterAPI: registerAPI,
+		LoginAPI:    loginAPI,
+		KVStore:     &kvstore.Memory{},
+	}
+	var req *apimodel.PsiphonConfigRequest
+	ff.Fill(&req)
+	ctx := context.Background()
+	// step 1: we register and login and use the token
+	// inside a scope just to avoid mistakes
+	{
+		resp, err := login.Call(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil {
+			t.Fatal("expected non-nil response")
+		}
+		if handler.logins.Load() != 1 {
+			t.Fatal("invalid handler.logins")
+		}
+		if handler.registers.Load() != 1 {
+			t.Fatal("invalid handler.registers")
+		}
+	}
+	// step 2: we forget accounts, expire the token and try
+	// again, so that we're rejected when logging in.
+	handler.forgetLogins()
+	ls, err := login.readstate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Expire = time.Now().Add(-5 * time.Second)
+	if err := login.writestate(ls); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := login.Call(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil {
+		t.Fatal("expected non-nil response")
+	}
+	if handler.logins.Load() != 3 {
+		t.Fatal("invalid handler.logins")
+	}
+	if handler.registers.Load() != 2 {
+		t.Fatal("invalid handler.registers")
+	}
+}
+
 func TestRegisterAndLoginPsiphonConfigCannotWriteState(t *testing.T) {
 	ff := &fakeFill{}
 	var expect apimodel.PsiphonConfigResponse
@@ -1090,6 +1163,79 @@ func TestTorTargetsTheDatabaseIsReplaced(t *testing.T) {
 	}
 }
 
+func TestTorTargetsTheDatabaseIsReplacedAndTokenExpired(t *testing.T) {
+	ff := &fakeFill{}
+	handler := &LoginHandler{
+		logins:    &atomicx.Int64{},
+		registers: &atomicx.Int64{},
+		t:         t,
+	}
+	srvr := httptest.NewServer(handler)
+	defer srvr.Close()
+	registerAPI := &simpleRegisterAPI{
+		HTTPClient: &VerboseHTTPClient{T: t},
+		BaseURL:    srvr.URL,
+	}
+	loginAPI := &simpleLoginAPI{
+		HTTPClient: &VerboseHTTPClient{T: t},
+		BaseURL:    srvr.URL,
+	}
+	baseAPI := &simpleTorTargetsAPI{
+		HTTPClient: &VerboseHTTPClient{T: t},
+		BaseURL:    srvr.URL,
+	}
+	login := &withLoginTorTargetsAPI{
+		API:         baseAPI,
+		RegisterAPI: registerAPI,
+		LoginAPI:    loginAPI,
+		KVStore:     &kvstore.Memory{},
+	}
+	var req *apimodel.TorTargetsRequest
+	ff.Fill(&req)
+	ctx := context.Background()
+	// step 1: we register and login and use the token
+	// inside a scope just to avoid mistakes
+	{
+		resp, err := login.Call(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil {
+			t.Fatal("expected non-nil response")
+		}
+		if handler.logins.Load() != 1 {
+			t.Fatal("invalid handler.logins")
+		}
+		if handler.registers.Load() != 1 {
+			t.Fatal("invalid handler.registers")
+		}
+	}
+	// step 2: we forget accounts, expire the token and try
+	// again, so that we're rejected when logging in.
+	handler.forgetLogins()
+	ls, err := login.readstate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ls.Expire = time.Now().Add(-5 * time.Second)
+	if err := login.writestate(ls); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := login.Call(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil {
+		t.Fatal("expected non-nil response")
+	}
+	if handler.logins.Load() != 3 {
+		t.Fatal("invalid handler.logins")
+	}
+	if handler.registers.Load() != 2 {
+		t.Fatal("invalid handler.registers")
+	}
+}
+
 func TestRegisterAndLoginTorTargetsCannotWriteState(t *testing.T) {
 	ff := &fakeFill{}
 	var expect apimodel.TorTargetsResponse
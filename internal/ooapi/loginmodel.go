@@ -26,6 +26,22 @@ func (ls *loginState) tokenValid() bool {
 	return ls.Token != "" && time.Now().Add(60*time.Second).Before(ls.Expire)
 }
 
+// tokenLifetimeWithClockSkew is the token lifetime we assume when the
+// server returns a token that our clock already considers expired.
+const tokenLifetimeWithClockSkew = 10 * time.Minute
+
+// setToken saves the token and the expiry time inside the login state. If
+// the token is already expired according to our clock, our clock is probably
+// off, so we assume a short lifetime. This prevents us from logging in again
+// for each call, and we anyway handle the server rejecting the token.
+func (ls *loginState) setToken(resp *apimodel.LoginResponse) {
+	ls.Token = resp.Token
+	ls.Expire = resp.Expire
+	if !ls.tokenValid() {
+		ls.Expire = time.Now().Add(tokenLifetimeWithClockSkew)
+	}
+}
+
 // loginKey is the key with which loginState is saved
 // into the key-value store used by Client.
 const loginKey = "orchestra.state"
@@ -0,0 +1,11 @@
+// Package ootest contains fakes for testing code using the ooapi package
+// without contacting the OONI backend. Code depending on the ooapi.API
+// interface rather than on *ooapi.Client can be tested by using a
+// FakeClient configured with fakes for the APIs it calls.
+package ootest
+
+import "errors"
+
+// ErrNotConfigured indicates that we called an API of a
+// FakeClient whose fake API has not been configured.
+var ErrNotConfigured = errors.New("ootest: fake API not configured")
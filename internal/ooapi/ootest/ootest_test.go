@@ -0,0 +1,56 @@
+package ootest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ooni/probe-cli/v3/internal/atomicx"
+	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
+)
+
+func TestFakeClient(t *testing.T) {
+	t.Run("with configured fake API", func(t *testing.T) {
+		expect := &apimodel.CheckInResponse{ProbeCC: "IT"}
+		count := &atomicx.Int64{}
+		clnt := &FakeClient{
+			CheckInAPI: &FakeCheckInAPI{Response: expect, CountCall: count},
+		}
+		resp, err := clnt.CheckIn(context.Background(), &apimodel.CheckInRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expect, resp); diff != "" {
+			t.Fatal(diff)
+		}
+		if count.Load() != 1 {
+			t.Fatal("unexpected number of calls", count.Load())
+		}
+	})
+
+	t.Run("with failing fake API", func(t *testing.T) {
+		errMocked := errors.New("mocked error")
+		clnt := &FakeClient{
+			URLsAPI: &FakeURLsAPI{Err: errMocked},
+		}
+		resp, err := clnt.URLs(context.Background(), &apimodel.URLsRequest{})
+		if !errors.Is(err, errMocked) {
+			t.Fatal("not the error we expected", err)
+		}
+		if resp != nil {
+			t.Fatal("expected nil response")
+		}
+	})
+
+	t.Run("without configured fake API", func(t *testing.T) {
+		clnt := &FakeClient{}
+		resp, err := clnt.TestHelpers(context.Background(), &apimodel.TestHelpersRequest{})
+		if !errors.Is(err, ErrNotConfigured) {
+			t.Fatal("not the error we expected", err)
+		}
+		if resp != nil {
+			t.Fatal("expected nil response")
+		}
+	})
+}
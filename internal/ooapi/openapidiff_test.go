@@ -0,0 +1,115 @@
+package ooapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/ooapi/internal/openapi"
+)
+
+func getOpenAPIModel() *openapi.V3Document {
+	data, err := os.ReadFile("openapi.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var out openapi.V3Document
+	if err := json.Unmarshal(data, &out); err != nil {
+		log.Fatal(err)
+	}
+	// We reduce irrelevant differences by producing a common header
+	return &openapi.V3Document{Paths: out.Paths}
+}
+
+func simplifyOperation(op *openapi.V3Operation) {
+	if op == nil {
+		return
+	}
+	// Sort parameters so the comparison does not depend on order.
+	sort.SliceStable(op.Parameters, func(i, j int) bool {
+		left, right := op.Parameters[i].Name, op.Parameters[j].Name
+		return strings.Compare(left, right) < 0
+	})
+}
+
+func simplifyPathItem(path *openapi.V3PathItem) *openapi.V3PathItem {
+	simplifyOperation(path.Get)
+	simplifyOperation(path.Post)
+	return path
+}
+
+// maybediffOpenAPI emits the diff between the server and the client and
+// returns the length of the diff itself in bytes.
+func maybediffOpenAPI(key string, server, client *openapi.V3PathItem) int {
+	diff := computediff(&diffable{
+		name:  fmt.Sprintf("server%s.json", key),
+		value: jsonify(simplifyPathItem(server)),
+	}, &diffable{
+		name:  fmt.Sprintf("client%s.json", key),
+		value: jsonify(simplifyPathItem(client)),
+	})
+	if diff != "" {
+		fmt.Printf("%s", diff)
+	}
+	return len(diff)
+}
+
+// compareOpenAPI compares the server model, which uses Swagger v2.0 and
+// which we convert to OpenAPI v3.0, with our openapi.json.
+func compareOpenAPI(server *openapi.Swagger) bool {
+	good := true
+	for _, path := range server.Paths {
+		simplifyInPlace(path)
+	}
+	serverModel, clientModel := openapi.FromSwagger(server), getOpenAPIModel()
+	// Implementation note: the server model is richer than the client
+	// model, so we ignore everything not defined by the client.
+	var count int
+	for key := range serverModel.Paths {
+		if _, found := clientModel.Paths[key]; !found {
+			continue
+		}
+		count++
+		if maybediffOpenAPI(key, serverModel.Paths[key], clientModel.Paths[key]) > 0 {
+			good = false
+		}
+	}
+	if count <= 0 {
+		panic("no element found")
+	}
+	return good
+}
+
+func TestOpenAPIIsConsistentWithSwagger(t *testing.T) {
+	// This test fails if we regenerate swagger_test.go and we
+	// forget to also regenerate openapi.json (or vice versa).
+	if !compareOpenAPI(getClientModel()) {
+		t.Fatal("model mismatch (see above)")
+	}
+}
+
+func TestOpenAPIWithProductionAPI(t *testing.T) {
+	t.Skip("skip until we use this part of the codebase")
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+	t.Log("using ", productionURL)
+	if !compareOpenAPI(getServerModel(productionURL)) {
+		t.Fatal("model mismatch (see above)")
+	}
+}
+
+func TestOpenAPIWithTestingAPI(t *testing.T) {
+	t.Skip("skip until we use this part of the codebase")
+	if testing.Short() {
+		t.Skip("skip test in short mode")
+	}
+	t.Log("using ", testingURL)
+	if !compareOpenAPI(getServerModel(testingURL)) {
+		t.Fatal("model mismatch (see above)")
+	}
+}
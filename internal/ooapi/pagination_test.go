@@ -0,0 +1,120 @@
+package ooapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
+)
+
+// newFakeURLsIterator creates an URLsIterator paging over
+// total fake URLs and recording the requests it sees.
+func newFakeURLsIterator(total int64, req *apimodel.URLsRequest, seen *[]apimodel.URLsRequest) *URLsIterator {
+	clnt := &Client{}
+	it := clnt.NewURLsIterator(req)
+	it.call = func(ctx context.Context, req *apimodel.URLsRequest) (*apimodel.URLsResponse, error) {
+		*seen = append(*seen, *req)
+		resp := &apimodel.URLsResponse{}
+		for idx := req.Offset; idx < total && idx < req.Offset+req.Limit; idx++ {
+			resp.Results = append(resp.Results, apimodel.URLsResponseURL{
+				URL: fmt.Sprintf("https://%d.example.com/", idx),
+			})
+		}
+		resp.Metadata.Count = int64(len(resp.Results))
+		return resp, nil
+	}
+	return it
+}
+
+func TestURLsIteratorWithLimit(t *testing.T) {
+	var seen []apimodel.URLsRequest
+	req := &apimodel.URLsRequest{Limit: 4}
+	it := newFakeURLsIterator(10, req, &seen)
+	var urls []string
+	ctx := context.Background()
+	for it.Next(ctx) {
+		for _, entry := range it.Page().Results {
+			urls = append(urls, entry.URL)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 10 {
+		t.Fatal("unexpected number of URLs", len(urls))
+	}
+	// we expect three pages: 4 + 4 + 2 URLs
+	if len(seen) != 3 || seen[0].Offset != 0 || seen[1].Offset != 4 || seen[2].Offset != 8 {
+		t.Fatal("unexpected requests", seen)
+	}
+	if req.Offset != 0 {
+		t.Fatal("the iterator should not modify the original request")
+	}
+	if it.Next(ctx) {
+		t.Fatal("expected no more pages")
+	}
+}
+
+func TestURLsIteratorWithoutLimit(t *testing.T) {
+	for _, limit := range []int64{0, -1} {
+		var seen []apimodel.URLsRequest
+		it := newFakeURLsIterator(6, &apimodel.URLsRequest{Limit: limit}, &seen)
+		ctx := context.Background()
+		if it.Next(ctx) {
+			t.Fatal("expected false here")
+		}
+		if !errors.Is(it.Err(), ErrInvalidPageSize) {
+			t.Fatal("not the error we expected", it.Err())
+		}
+		if len(seen) != 0 {
+			t.Fatal("expected no requests", seen)
+		}
+	}
+}
+
+func TestURLsIteratorWhenServerIgnoresOffset(t *testing.T) {
+	var seen []apimodel.URLsRequest
+	it := newFakeURLsIterator(10, &apimodel.URLsRequest{Limit: 4}, &seen)
+	call := it.call
+	it.call = func(ctx context.Context, req *apimodel.URLsRequest) (*apimodel.URLsResponse, error) {
+		clone := *req
+		clone.Offset = 0 // emulate a server that does not support the offset
+		return call(ctx, &clone)
+	}
+	var count int
+	ctx := context.Background()
+	for it.Next(ctx) {
+		count += len(it.Page().Results)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// we expect to stop as soon as we see the first page again
+	if count != 4 || len(seen) != 2 {
+		t.Fatal("unexpected result", count, len(seen))
+	}
+}
+
+func TestURLsIteratorWithFailure(t *testing.T) {
+	errMocked := errors.New("mocked error")
+	clnt := &Client{}
+	it := clnt.NewURLsIterator(&apimodel.URLsRequest{Limit: 4})
+	it.call = func(ctx context.Context, req *apimodel.URLsRequest) (*apimodel.URLsResponse, error) {
+		return nil, errMocked
+	}
+	ctx := context.Background()
+	if it.Next(ctx) {
+		t.Fatal("expected false here")
+	}
+	if !errors.Is(it.Err(), errMocked) {
+		t.Fatal("not the error we expected", it.Err())
+	}
+	if it.Page() != nil {
+		t.Fatal("expected nil page")
+	}
+	if it.Next(ctx) {
+		t.Fatal("expected the iterator to be done")
+	}
+}
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// 2022-05-19 20:30:50.891059 +0200 CEST m=+0.000799292
+// 2026-10-16 08:06:34.962123317 +0000 UTC m=+0.000161235
 
 package ooapi
 
@@ -147,6 +147,9 @@ func (api *simpleURLsAPI) newRequest(ctx context.Context, req *apimodel.URLsRequ
 	if req.Limit != 0 {
 		q.Add("limit", newQueryFieldInt64(req.Limit))
 	}
+	if req.Offset != 0 {
+		q.Add("offset", newQueryFieldInt64(req.Offset))
+	}
 	URL.RawQuery = q.Encode()
 	return api.requestMaker().NewRequest(ctx, "GET", URL.String(), nil)
 }
@@ -0,0 +1,136 @@
+package ooapi
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// RetryPolicy controls how we retry API calls failing with
+// transient errors (i.e., timeouts and 5xx responses). The zero
+// value is a valid policy using sensible defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the OPTIONAL maximum number of attempts. When
+	// zero or negative, we use a default value.
+	MaxAttempts int
+
+	// InitialDelay is the OPTIONAL delay before the first retry. When
+	// zero or negative, we use a default value. We double the delay
+	// after each failed attempt and we add some random jitter.
+	InitialDelay time.Duration
+
+	// MaxDelay is the OPTIONAL maximum delay between attempts. When
+	// zero or negative, we use a default value.
+	MaxDelay time.Duration
+
+	// OnAttempt is the OPTIONAL callback invoked after each attempt
+	// with metadata describing the attempt.
+	OnAttempt func(a *CallAttempt)
+}
+
+// CallAttempt contains metadata about an API call attempt.
+type CallAttempt struct {
+	// API is the name of the API we called.
+	API string
+
+	// Attempt is the attempt number (starting from one).
+	Attempt int
+
+	// Err is the error that occurred (nil on success).
+	Err error
+
+	// Retry indicates whether we are going to retry.
+	Retry bool
+
+	// Delay is how long we'll wait before retrying.
+	Delay time.Duration
+}
+
+// These are the default values used by RetryPolicy.
+const (
+	defaultRetryMaxAttempts  = 4
+	defaultRetryInitialDelay = 500 * time.Millisecond
+	defaultRetryMaxDelay     = 8 * time.Second
+)
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return defaultRetryMaxAttempts
+}
+
+func (p *RetryPolicy) initialDelay() time.Duration {
+	if p.InitialDelay > 0 {
+		return p.InitialDelay
+	}
+	return defaultRetryInitialDelay
+}
+
+func (p *RetryPolicy) maxDelay() time.Duration {
+	if p.MaxDelay > 0 {
+		return p.MaxDelay
+	}
+	return defaultRetryMaxDelay
+}
+
+// delay returns the delay to wait after the given failed
+// attempt. The returned delay includes random jitter.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.initialDelay()
+	for idx := 1; idx < attempt && d < p.maxDelay(); idx++ {
+		d *= 2
+	}
+	if d > p.maxDelay() {
+		d = p.maxDelay()
+	}
+	// Use "equal jitter": wait at least half of the delay and
+	// randomize the other half to avoid synchronized retries.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// onAttempt emits call-attempt metadata, if needed.
+func (p *RetryPolicy) onAttempt(a *CallAttempt) {
+	if p.OnAttempt != nil {
+		p.OnAttempt(a)
+	}
+}
+
+// shouldRetry returns whether we should retry after the given
+// failed attempt, which failed with the given error.
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	return attempt < p.maxAttempts() && isTransientFailure(err)
+}
+
+// isTransientFailure returns whether err is a transient failure
+// that is worth retrying (i.e., a timeout or a 5xx status).
+func isTransientFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var httpErr *httpFailureError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 && httpErr.StatusCode <= 599
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retrySleep sleeps for the given delay or until the
+// context is done, whichever happens first.
+func retrySleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
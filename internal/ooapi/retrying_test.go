@@ -0,0 +1,211 @@
+package ooapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ooni/probe-cli/v3/internal/atomicx"
+	"github.com/ooni/probe-cli/v3/internal/ooapi/apimodel"
+)
+
+type fakeTimeoutError struct{}
+
+func (fakeTimeoutError) Error() string   { return "i/o timeout" }
+func (fakeTimeoutError) Timeout() bool   { return true }
+func (fakeTimeoutError) Temporary() bool { return true }
+
+func TestIsTransientFailure(t *testing.T) {
+	var tests = []struct {
+		name   string
+		err    error
+		expect bool
+	}{{
+		name:   "nil error",
+		err:    nil,
+		expect: false,
+	}, {
+		name:   "context canceled",
+		err:    fmt.Errorf("wrapped: %w", context.Canceled),
+		expect: false,
+	}, {
+		name:   "deadline exceeded",
+		err:    context.DeadlineExceeded,
+		expect: true,
+	}, {
+		name:   "network timeout",
+		err:    fmt.Errorf("wrapped: %w", fakeTimeoutError{}),
+		expect: true,
+	}, {
+		name:   "500 status",
+		err:    newHTTPFailure(500),
+		expect: true,
+	}, {
+		name:   "503 status",
+		err:    newHTTPFailure(503),
+		expect: true,
+	}, {
+		name:   "404 status",
+		err:    newHTTPFailure(404),
+		expect: false,
+	}, {
+		name:   "unauthorized",
+		err:    ErrUnauthorized,
+		expect: false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientFailure(tt.err); got != tt.expect {
+				t.Fatal("expected", tt.expect, "got", got)
+			}
+		})
+	}
+}
+
+func TestHTTPFailureErrorStringAndUnwrap(t *testing.T) {
+	err := newHTTPFailure(502)
+	if err.Error() != "ooapi: http request failed: 502" {
+		t.Fatal("unexpected error string", err.Error())
+	}
+	if !errors.Is(err, ErrHTTPFailure) {
+		t.Fatal("should unwrap to ErrHTTPFailure")
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := &RetryPolicy{InitialDelay: time.Second, MaxDelay: 4 * time.Second}
+	var tests = []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{10, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		for idx := 0; idx < 16; idx++ {
+			d := p.delay(tt.attempt)
+			if d < tt.max/2 || d > tt.max {
+				t.Fatal("delay out of bounds", tt.attempt, d)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	p := &RetryPolicy{}
+	if p.maxAttempts() != defaultRetryMaxAttempts {
+		t.Fatal("invalid default max attempts")
+	}
+	if p.initialDelay() != defaultRetryInitialDelay {
+		t.Fatal("invalid default initial delay")
+	}
+	if p.maxDelay() != defaultRetryMaxDelay {
+		t.Fatal("invalid default max delay")
+	}
+}
+
+func TestWithRetryCheckInAPIRetriesTransientFailures(t *testing.T) {
+	ff := &fakeFill{}
+	var expect *apimodel.CheckInResponse
+	ff.Fill(&expect)
+	count := &atomicx.Int64{}
+	fakeapi := &FakeCheckInAPI{Err: newHTTPFailure(502), CountCall: count}
+	var attempts []*CallAttempt
+	api := &withRetryCheckInAPI{
+		API: fakeapi,
+		Policy: &RetryPolicy{
+			MaxAttempts:  3,
+			InitialDelay: time.Microsecond,
+			OnAttempt: func(a *CallAttempt) {
+				attempts = append(attempts, a)
+				if a.Attempt == 2 {
+					fakeapi.Err, fakeapi.Response = nil, expect
+				}
+			},
+		},
+	}
+	var req *apimodel.CheckInRequest
+	ff.Fill(&req)
+	resp, err := api.Call(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != expect {
+		t.Fatal("unexpected response")
+	}
+	if count.Load() != 3 {
+		t.Fatal("unexpected number of calls", count.Load())
+	}
+	if len(attempts) != 3 {
+		t.Fatal("unexpected number of attempts", len(attempts))
+	}
+	for idx, a := range attempts {
+		if a.API != "CheckIn" || a.Attempt != idx+1 {
+			t.Fatal("unexpected attempt metadata", a)
+		}
+		if retry := idx < 2; a.Retry != retry || (a.Err != nil) != retry {
+			t.Fatal("unexpected attempt metadata", a)
+		}
+	}
+}
+
+func TestWithRetryCheckInAPIStopsAfterMaxAttempts(t *testing.T) {
+	count := &atomicx.Int64{}
+	api := &withRetryCheckInAPI{
+		API: &FakeCheckInAPI{Err: newHTTPFailure(500), CountCall: count},
+		Policy: &RetryPolicy{
+			MaxAttempts:  2,
+			InitialDelay: time.Microsecond,
+		},
+	}
+	resp, err := api.Call(context.Background(), &apimodel.CheckInRequest{})
+	if !errors.Is(err, ErrHTTPFailure) {
+		t.Fatal("not the error we expected", err)
+	}
+	if resp != nil {
+		t.Fatal("expected nil response")
+	}
+	if count.Load() != 2 {
+		t.Fatal("unexpected number of calls", count.Load())
+	}
+}
+
+func TestWithRetryCheckInAPIDoesNotRetryPermanentFailures(t *testing.T) {
+	count := &atomicx.Int64{}
+	api := &withRetryCheckInAPI{
+		API:    &FakeCheckInAPI{Err: newHTTPFailure(400), CountCall: count},
+		Policy: &RetryPolicy{},
+	}
+	_, err := api.Call(context.Background(), &apimodel.CheckInRequest{})
+	if !errors.Is(err, ErrHTTPFailure) {
+		t.Fatal("not the error we expected", err)
+	}
+	if count.Load() != 1 {
+		t.Fatal("unexpected number of calls", count.Load())
+	}
+}
+
+func TestWithRetryCheckInAPIHonoursContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	count := &atomicx.Int64{}
+	api := &withRetryCheckInAPI{
+		API: &FakeCheckInAPI{Err: newHTTPFailure(500), CountCall: count},
+		Policy: &RetryPolicy{
+			InitialDelay: time.Hour,
+			OnAttempt: func(a *CallAttempt) {
+				cancel()
+			},
+		},
+	}
+	_, err := api.Call(ctx, &apimodel.CheckInRequest{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("not the error we expected", err)
+	}
+	if count.Load() != 1 {
+		t.Fatal("unexpected number of calls", count.Load())
+	}
+}
@@ -1,5 +1,5 @@
 // Code generated by go generate; DO NOT EDIT.
-// 2022-05-19 20:30:51.768205 +0200 CEST m=+0.001003793
+// 2026-10-16 08:07:53.869543515 +0000 UTC m=+0.001005723
 
 package ooapi
 
@@ -9,7 +9,7 @@ const swagger = `{
     "swagger": "2.0",
     "info": {
         "title": "OONI API specification",
-        "version": "0.20220519.5183051"
+        "version": "0.20261016.10080753"
     },
     "host": "api.ooni.io",
     "basePath": "/",
@@ -417,6 +417,11 @@ const swagger = `{
                         "in": "query",
                         "name": "limit",
                         "type": "integer"
+                    },
+                    {
+                        "in": "query",
+                        "name": "offset",
+                        "type": "integer"
                     }
                 ],
                 "responses": {
@@ -6,8 +6,22 @@ func newErrEmptyField(field string) error {
 	return fmt.Errorf("%w: %s", ErrEmptyField, field)
 }
 
+// httpFailureError is the error returned when the
+// server responds with an unexpected status code.
+type httpFailureError struct {
+	StatusCode int
+}
+
+func (e *httpFailureError) Error() string {
+	return fmt.Sprintf("%s: %d", ErrHTTPFailure.Error(), e.StatusCode)
+}
+
+func (e *httpFailureError) Unwrap() error {
+	return ErrHTTPFailure
+}
+
 func newHTTPFailure(status int) error {
-	return fmt.Errorf("%w: %d", ErrHTTPFailure, status)
+	return &httpFailureError{StatusCode: status}
 }
 
 func newQueryFieldInt64(v int64) string {
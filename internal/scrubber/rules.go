@@ -0,0 +1,134 @@
+package scrubber
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ooni/probe-cli/v3/internal/model"
+)
+
+// Rules contains the rules for redacting private data.
+type Rules struct {
+	// ProbeIPs contains the IPv4 and IPv6 addresses of the probe. We
+	// redact the IPv6 addresses in both compressed and expanded form.
+	ProbeIPs []string
+
+	// MACHostnames indicates whether to redact the MAC addresses, including
+	// the ones embedded in hostnames (e.g., 00-11-22-33-44-55.dhcp.example.com).
+	MACHostnames bool
+
+	// Strings contains user-configured strings to redact (e.g., the
+	// name of the home network or of the device).
+	Strings []string
+}
+
+// macAddressRegexp matches MAC addresses written using colons or dashes
+// (e.g., 00:11:22:33:44:55) or dots (e.g., 0011.2233.4455). We do not match
+// bare 12-digit hexadecimal strings, which are common in CDN hostnames.
+var macAddressRegexp = regexp.MustCompile(`(?i)\b(` +
+	`[0-9a-f]{2}(:[0-9a-f]{2}){5}|` +
+	`[0-9a-f]{2}(-[0-9a-f]{2}){5}|` +
+	`[0-9a-f]{4}\.[0-9a-f]{4}\.[0-9a-f]{4}` +
+	`)\b`)
+
+// Scrubber redacts private data according to its Rules.
+type Scrubber struct {
+	macHostnames bool
+	replacer     *strings.Replacer
+}
+
+// New creates a new Scrubber using the given rules.
+func New(rules Rules) *Scrubber {
+	var targets []string
+	for _, address := range rules.ProbeIPs {
+		targets = append(targets, ipForms(address)...)
+	}
+	for _, s := range rules.Strings {
+		if s != "" {
+			targets = append(targets, s)
+		}
+	}
+	// We prefer the longest match when targets overlap, e.g., an
+	// expanded IPv6 address containing a user-configured string.
+	sort.SliceStable(targets, func(i, j int) bool {
+		return len(targets[i]) > len(targets[j])
+	})
+	var oldnew []string
+	for _, target := range targets {
+		oldnew = append(oldnew, target, model.Scrubbed)
+	}
+	return &Scrubber{
+		macHostnames: rules.MACHostnames,
+		replacer:     strings.NewReplacer(oldnew...),
+	}
+}
+
+// ipForms returns the forms in which we may find the given IP address.
+func ipForms(address string) (out []string) {
+	if address == "" {
+		return
+	}
+	out = append(out, address)
+	ip := net.ParseIP(address)
+	if ip == nil || ip.To4() != nil {
+		return
+	}
+	if compressed := ip.String(); compressed != address {
+		out = append(out, compressed)
+	}
+	var groups []string
+	for idx := 0; idx < net.IPv6len; idx += 2 {
+		groups = append(groups, fmt.Sprintf("%02x%02x", ip[idx], ip[idx+1]))
+	}
+	if expanded := strings.Join(groups, ":"); expanded != address {
+		out = append(out, expanded)
+	}
+	return
+}
+
+// ScrubString redacts the private data inside s and returns whether
+// the returned string differs from s.
+func (s *Scrubber) ScrubString(input string) (string, bool) {
+	output := input
+	// We match MAC addresses first, because redacting the strings may
+	// change the surroundings of the MAC addresses.
+	if s.macHostnames {
+		output = macAddressRegexp.ReplaceAllLiteralString(output, model.Scrubbed)
+	}
+	output = s.replacer.Replace(output)
+	return output, output != input
+}
+
+// ScrubValue redacts the private data inside the string leaves of v, which
+// is a value decoded from JSON into an interface{}. We scrub in place the
+// maps and the slices of v. The return value is the scrubbed value along
+// with whether we changed anything.
+func (s *Scrubber) ScrubValue(v interface{}) (interface{}, bool) {
+	switch value := v.(type) {
+	case string:
+		return s.ScrubString(value)
+	case map[string]interface{}:
+		var changed bool
+		for key, entry := range value {
+			if scrubbed, okay := s.ScrubValue(entry); okay {
+				value[key] = scrubbed
+				changed = true
+			}
+		}
+		return value, changed
+	case []interface{}:
+		var changed bool
+		for idx, entry := range value {
+			if scrubbed, okay := s.ScrubValue(entry); okay {
+				value[idx] = scrubbed
+				changed = true
+			}
+		}
+		return value, changed
+	default:
+		return v, false
+	}
+}
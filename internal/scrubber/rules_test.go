@@ -0,0 +1,111 @@
+package scrubber
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScrubberScrubString(t *testing.T) {
+	scrubber := New(Rules{
+		ProbeIPs:     []string{"130.192.91.211", "2001:db8::1"},
+		MACHostnames: true,
+		Strings:      []string{"MyHomeNetwork", `my "laptop"`},
+	})
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{{
+		name:     "with the IPv4 probe IP",
+		input:    "connect 130.192.91.211:443",
+		expected: "connect [scrubbed]:443",
+	}, {
+		name:     "with the compressed IPv6 probe IP",
+		input:    "from [2001:db8::1]:443",
+		expected: "from [[scrubbed]]:443",
+	}, {
+		name:     "with the expanded IPv6 probe IP",
+		input:    "from 2001:0db8:0000:0000:0000:0000:0000:0001",
+		expected: "from [scrubbed]",
+	}, {
+		name:     "with a MAC address",
+		input:    "interface 00:1A:2b:3c:4d:5e is up",
+		expected: "interface [scrubbed] is up",
+	}, {
+		name:     "with a hostname embedding a MAC address with dashes",
+		input:    `"hostname":"00-1a-2b-3c-4d-5e.dhcp.example.com"`,
+		expected: `"hostname":"[scrubbed].dhcp.example.com"`,
+	}, {
+		name:     "with a MAC address using dots",
+		input:    "interface 001a.2b3c.4d5e is up",
+		expected: "interface [scrubbed] is up",
+	}, {
+		name:     "with mixed separators",
+		input:    "interface 00:1a-2b:3c-4d:5e is up",
+		expected: "interface 00:1a-2b:3c-4d:5e is up",
+	}, {
+		name:     "with a hexadecimal label of a CDN hostname",
+		input:    `"hostname":"d1a2b3c4d5e6.cloudfront.net"`,
+		expected: `"hostname":"d1a2b3c4d5e6.cloudfront.net"`,
+	}, {
+		name:     "with a user-configured string",
+		input:    "ssid=MyHomeNetwork",
+		expected: "ssid=[scrubbed]",
+	}, {
+		name:     "with a hostname embedding a MAC address and a user-configured string",
+		input:    `"hostname":"00-1a-2b-3c-4d-5e.MyHomeNetwork.example.com"`,
+		expected: `"hostname":"[scrubbed].[scrubbed].example.com"`,
+	}, {
+		name:     "with a user-configured string containing quotes",
+		input:    `device=my "laptop"`,
+		expected: "device=[scrubbed]",
+	}, {
+		name:     "with nothing to scrub",
+		input:    "connect 8.8.8.8:53",
+		expected: "connect 8.8.8.8:53",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, changed := scrubber.ScrubString(tt.input)
+			if output != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, output)
+			}
+			if changed != (tt.input != tt.expected) {
+				t.Fatal("unexpected changed", changed)
+			}
+		})
+	}
+}
+
+func TestScrubberWithoutMACHostnames(t *testing.T) {
+	scrubber := New(Rules{Strings: []string{""}})
+	input := "00-1a-2b-3c-4d-5e.dhcp.example.com"
+	if output, changed := scrubber.ScrubString(input); changed || output != input {
+		t.Fatal("unexpected output", output)
+	}
+}
+
+func TestScrubberScrubValue(t *testing.T) {
+	scrubber := New(Rules{Strings: []string{"laptop"}})
+	value := map[string]interface{}{
+		"hostname": "laptop.example.com",
+		"laptop":   []interface{}{"laptop", 1.0, true, nil},
+		"t":        0.5,
+	}
+	output, changed := scrubber.ScrubValue(value)
+	if !changed {
+		t.Fatal("expected changed")
+	}
+	expected := map[string]interface{}{
+		"hostname": "[scrubbed].example.com",
+		"laptop":   []interface{}{"[scrubbed]", 1.0, true, nil},
+		"t":        0.5,
+	}
+	if diff := cmp.Diff(expected, output); diff != "" {
+		t.Fatal(diff)
+	}
+	if _, changed := scrubber.ScrubValue(map[string]interface{}{"t": 0.5}); changed {
+		t.Fatal("expected no change")
+	}
+}
@@ -0,0 +1,40 @@
+#!/bin/bash
+#
+# Signs the ooniprobe binaries of a release, writing the detached signature
+# of each binary next to it, with the .asc extension. The signature covers
+# the "ooniprobe <version> <binary>\n" line followed by the binary, which is
+# what `ooniprobe update` verifies (see cmd/ooniprobe/internal/selfupdate).
+#
+# We sign using the secret key corresponding to the public key embedded into
+# ooniprobe, which must be inside the GPG keyring, and we check each signature
+# using only the embedded public key, so that we never publish a signature
+# that `ooniprobe update` would reject.
+#
+# Usage: ./script/sign-ooniprobe.bash <version> <binary>...
+#
+set -euo pipefail
+if [ $# -lt 2 ]; then
+	echo "usage: $0 <version> <binary>..." 1>&2
+	exit 1
+fi
+version=${1#v}
+shift
+pubkey=./cmd/ooniprobe/internal/cli/update/release-signing-key.asc
+if [ ! -s $pubkey ]; then
+	echo "$0: $pubkey is empty, please commit the release signing public key" 1>&2
+	exit 1
+fi
+fingerprint=$(gpg --batch --show-keys --with-colons $pubkey | awk -F: '$1 == "fpr" { print $10; exit }')
+if [ -z "$fingerprint" ]; then
+	echo "$0: $pubkey does not contain a public key" 1>&2
+	exit 1
+fi
+keyring=$(mktemp)
+trap 'rm -f $keyring' EXIT
+gpg --batch --yes --dearmor < $pubkey > $keyring
+for binary in "$@"; do
+	header="ooniprobe $version $(basename $binary)"
+	(echo "$header"; cat $binary) | gpg --batch --yes --armor --detach-sign \
+		--local-user $fingerprint --output $binary.asc
+	(echo "$header"; cat $binary) | gpgv --keyring $keyring $binary.asc -
+done